@@ -0,0 +1,272 @@
+// Command era is the operator CLI for the ERA API.
+//
+//	era parse <file-or-url> [--method pdf] [--config county.yaml] [--out results.json]
+//	era reparse --county X --contest "Measure B" [--snapshot <hash>|latest]
+//	era rebuild-projections [--election X] [--from <time>] [--server URL]
+//	era migrate --event-log events.jsonl [--election X] [--status]
+//
+// parse runs the parse pipeline, transforms included, on a local file or
+// a URL and prints the results the server would publish, for parser
+// development and troubleshooting a county's source without a server.
+//
+// reparse re-runs extraction of one contest from an archived source (see
+// SOURCE_ARCHIVE on the server) and prints each step, for targeted
+// debugging without reprocessing or republishing anything.
+//
+// rebuild-projections has a running server replay its event log through
+// its current projection code, reporting progress, and swap the rebuilt
+// read models in at once, so a fix to how they are derived applies to
+// results already published. Readers are served the old ones until then.
+//
+// migrate upgrades an event log written by an older server to the format
+// this one reads, keeping the original beside it. The server does the same
+// on start unless EVENT_LOG_MIGRATE=off; run it with the server stopped.
+//
+// Commands configured by the server's environment, such as serve and
+// process, are in era-server (cmd/server) instead.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/archive"
+	"github.com/many221/era_api_v1/internal/contestrules"
+	"github.com/many221/era_api_v1/internal/fetcher"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+const usage = `usage: era <command> [flags]
+
+commands:
+  parse                 parse a file or URL and print its results
+  reparse               re-extract one contest from an archived source
+  rebuild-projections   rebuild the server's read models from its event log
+  migrate               upgrade an event log to this version's format
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "parse":
+		os.Exit(parse(os.Args[2:], os.Stdout, os.Stderr))
+	case "reparse":
+		os.Exit(reparse(os.Args[2:], os.Stdout, os.Stderr))
+	case "rebuild-projections":
+		os.Exit(rebuildProjections(os.Args[2:], os.Stdout, os.Stderr))
+	case "migrate":
+		os.Exit(migrateLog(os.Args[2:], os.Stdout, os.Stderr))
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "era: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+func reparse(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("reparse", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	county := fs.String("county", "", "county name or key (required)")
+	contest := fs.String("contest", "", "contest ID or title (required)")
+	snapshot := fs.String("snapshot", "latest", "snapshot hash or unique prefix")
+	archiveDir := fs.String("archive", envOr("SOURCE_ARCHIVE", os.Getenv("SOURCE_ARCHIVE_DIR")), "source archive directory or s3://bucket/prefix")
+	contentType := fs.String("content-type", models.ContentTypeCandidate, "candidate or measure")
+	threshold := fs.String("measure-threshold", "", "measure threshold, e.g. two-thirds")
+	rulesFile := fs.String("contest-rules", os.Getenv("CONTEST_RULES"), "contest rules file")
+	registryFile := fs.String("candidate-registry", os.Getenv("CANDIDATE_REGISTRY"), "candidate registry file")
+	election := fs.String("election", envOr("ELECTION_ID", "default"), "election the contest rules belong to")
+	parserTrace := fs.Bool("trace", false, "also print every parser decision for the whole source")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *county == "" || *contest == "" || *archiveDir == "" {
+		fmt.Fprintln(stderr, "era reparse: --county, --contest and --archive (or SOURCE_ARCHIVE) are required")
+		return 2
+	}
+
+	trace := func(format string, args ...any) {
+		fmt.Fprintf(stderr, "· "+format+"\n", args...)
+	}
+
+	ctx := context.Background()
+	arch, err := archive.Open(*archiveDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "era reparse: %v\n", err)
+		return 2
+	}
+	src, err := arch.Find(ctx, *county, *snapshot)
+	if err != nil {
+		fmt.Fprintf(stderr, "era reparse: %s/%s: %v\n", *county, *snapshot, err)
+		return 1
+	}
+	data, err := arch.Read(ctx, src)
+	if err != nil {
+		fmt.Fprintf(stderr, "era reparse: %v\n", err)
+		return 1
+	}
+	trace("source %s/%s %s (%d bytes, %s, fetched %s)", arch, src.County, src.SnapshotHash, len(data), src.ParseMethod, src.FetchedAt.Format(time.RFC3339))
+
+	// raw runs the parser alone; proc adds the transforms the server runs,
+	// over rules and a registry loaded the same way it loads them.
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	st := store.New()
+	raw := processor.New(fetcher.New(), store.New(), logger)
+	proc := processor.New(fetcher.New(), st, logger)
+	if *rulesFile != "" {
+		n, err := contestrules.LoadRules(*rulesFile, st, *election)
+		if err != nil {
+			fmt.Fprintf(stderr, "era reparse: %v\n", err)
+			return 1
+		}
+		trace("loaded %d contest rules from %s", n, *rulesFile)
+	}
+	proc.AddTransform(contestrules.NewEngine(st, *election).Apply)
+	if *registryFile != "" {
+		n, err := normalize.LoadRegistry(*registryFile, st)
+		if err != nil {
+			fmt.Fprintf(stderr, "era reparse: %v\n", err)
+			return 1
+		}
+		trace("loaded %d registry candidates from %s", n, *registryFile)
+	}
+	proc.AddTransform(normalize.NewMatcher(st, normalize.DefaultThreshold).Apply)
+
+	req := models.ProcessRequest{
+		CountyName:       *county,
+		ContentType:      *contentType,
+		ParseMethod:      src.ParseMethod,
+		MeasureThreshold: *threshold,
+	}
+
+	tr := parser.NewTrace()
+	start := time.Now()
+	parsed, err := raw.Extract(parser.WithTrace(ctx, tr), req, data)
+	if *parserTrace || err != nil {
+		for _, e := range tr.Entries() {
+			trace("  %-9s %s", e.Kind, e.Message)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "era reparse: parse: %v\n", err)
+		return 1
+	}
+	trace("parsed %d contests in %s", len(parsed.Contests), time.Since(start).Round(time.Microsecond))
+
+	before, ok := findContest(parsed.Contests, *contest, trace)
+	if !ok {
+		return 1
+	}
+	trace("raw contest %q (id %s):", before.Title, before.ID)
+	for _, c := range before.Candidates {
+		trace("  %-40s %8d%s", c.Name, c.Votes, flags(c))
+	}
+
+	results, err := proc.Extract(ctx, req, data)
+	if err != nil {
+		fmt.Fprintf(stderr, "era reparse: %v\n", err)
+		return 1
+	}
+	// IDs are assigned before transforms run, so they survive retitling.
+	var after models.Contest
+	ok = false
+	for _, c := range results.Contests {
+		if c.ID == before.ID {
+			after, ok = c, true
+			break
+		}
+	}
+	if ok {
+		if after.ID != before.ID || after.Title != before.Title {
+			trace("mapped to %q (id %s)", after.Title, after.ID)
+		}
+		for i, c := range after.Candidates {
+			if i < len(before.Candidates) && before.Candidates[i].Name != c.Name {
+				trace("  %q → %q (%s)", before.Candidates[i].Name, c.Name, c.CanonicalID)
+			}
+		}
+		if m := after.Measure; m != nil {
+			trace("measure: yes %d, no %d, %.2f%% yes, %s required, passing=%t", m.Yes, m.No, m.YesPercent, m.Threshold, m.Passing)
+		}
+	}
+	if !ok {
+		trace("contest is not in the transformed output; a contest rule may drop it")
+		after = before
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(after)
+	return 0
+}
+
+// findContest matches want against contest IDs and titles: exactly first,
+// then as a substring if that is unambiguous.
+func findContest(contests []models.Contest, want string, trace func(string, ...any)) (models.Contest, bool) {
+	fold := func(s string) string { return strings.ToLower(strings.Join(strings.Fields(s), " ")) }
+	w := fold(want)
+	for _, c := range contests {
+		if c.ID == want || c.ID == models.Slug(want) || fold(c.Title) == w || (c.RawTitle != "" && fold(c.RawTitle) == w) {
+			return c, true
+		}
+	}
+
+	var matches []models.Contest
+	for _, c := range contests {
+		if strings.Contains(fold(c.Title), w) || strings.Contains(fold(c.RawTitle), w) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], true
+	case 0:
+		trace("no contest matches %q; contests in this source:", want)
+		for _, c := range contests {
+			trace("  %s  %s", c.ID, c.Title)
+		}
+	default:
+		trace("%q matches %d contests:", want, len(matches))
+		for _, c := range matches {
+			trace("  %s  %s", c.ID, c.Title)
+		}
+	}
+	return models.Contest{}, false
+}
+
+func flags(c models.Candidate) string {
+	var f []string
+	if c.Party != "" {
+		f = append(f, c.Party)
+	}
+	if c.WriteInAggregate {
+		f = append(f, "write-in total")
+	} else if c.WriteIn {
+		f = append(f, "write-in")
+	}
+	if len(f) == 0 {
+		return ""
+	}
+	return "  [" + strings.Join(f, ", ") + "]"
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/migrate"
+)
+
+func migrateLog(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	path := fs.String("event-log", os.Getenv("EVENT_LOG"), "event log file (required)")
+	election := fs.String("election", envOr("ELECTION_ID", "default"), "the server's default election, which events recorded without one belong to")
+	status := fs.Bool("status", false, "only print the log's version and the migrations it is missing")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *path == "" {
+		fmt.Fprintln(stderr, "era migrate: --event-log is required")
+		return 2
+	}
+
+	if *status {
+		s, err := migrate.Check(*path)
+		if err != nil {
+			fmt.Fprintf(stderr, "era migrate: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s: version %d of %d\n", s.Path, s.Version, s.Latest)
+		if len(s.Pending) > 0 {
+			fmt.Fprintf(stdout, "pending: %s\n", strings.Join(s.Pending, ", "))
+		}
+		return 0
+	}
+
+	// No server may have the log open while it is rewritten.
+	report, err := migrate.Run(*path, migrate.Config{DefaultElection: *election})
+	if err != nil {
+		fmt.Fprintf(stderr, "era migrate: %v\n", err)
+		return 1
+	}
+	if len(report.Applied) == 0 {
+		fmt.Fprintf(stdout, "%s is up to date at version %d\n", *path, report.To)
+		return 0
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	return 0
+}
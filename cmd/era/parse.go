@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/contestrules"
+	"github.com/many221/era_api_v1/internal/fetcher"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+func parse(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	method := fs.String("method", "", "parse method, or auto to detect it (default the config's, else auto)")
+	configFile := fs.String("config", "", "county config, as JSON or flat YAML, e.g. as registered with the server")
+	out := fs.String("out", "", "write the results to this file instead of stdout")
+	county := fs.String("county", "", "county name (default the config's)")
+	contentType := fs.String("content-type", "", "candidate or measure (default the config's, else candidate)")
+	threshold := fs.String("measure-threshold", "", "measure threshold, e.g. two-thirds")
+	rulesFile := fs.String("contest-rules", os.Getenv("CONTEST_RULES"), "contest rules file")
+	registryFile := fs.String("candidate-registry", os.Getenv("CANDIDATE_REGISTRY"), "candidate registry file")
+	election := fs.String("election", envOr("ELECTION_ID", "default"), "election the contest rules belong to")
+	parserTrace := fs.Bool("trace", false, "also print every parser decision")
+
+	// Flags may come after the source as well as before it.
+	var sources []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		sources = append(sources, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(sources) > 1 {
+		fmt.Fprintln(stderr, "era parse: give one file or URL")
+		return 2
+	}
+
+	trace := func(format string, args ...any) {
+		fmt.Fprintf(stderr, "· "+format+"\n", args...)
+	}
+
+	var cfg models.CountySource
+	if *configFile != "" {
+		var err error
+		if cfg, err = readCountyConfig(*configFile); err != nil {
+			fmt.Fprintf(stderr, "era parse: %v\n", err)
+			return 2
+		}
+		trace("county %q from %s", cfg.Name, *configFile)
+	}
+	req := cfg.ProcessRequest()
+	req.Browser = nil
+	if len(sources) == 1 {
+		req.FileLink = sources[0]
+	}
+	if req.FileLink == "" {
+		fmt.Fprintln(stderr, "era parse: give a file or URL, or a --config with a fileLink")
+		return 2
+	}
+	if *county != "" {
+		req.CountyName = *county
+	}
+	if req.CountyName == "" {
+		req.CountyName = strings.TrimSuffix(filepath.Base(req.FileLink), filepath.Ext(req.FileLink))
+	}
+	if *contentType != "" {
+		req.ContentType = *contentType
+	}
+	if req.ContentType == "" {
+		req.ContentType = models.ContentTypeCandidate
+	}
+	if *threshold != "" {
+		req.MeasureThreshold = *threshold
+	}
+	if *method != "" {
+		req.ParseMethod = *method
+	}
+	if req.ParseMethod == "" {
+		req.ParseMethod = models.ParseMethodAuto
+	}
+	if _, ok := parser.Lookup(req.ParseMethod); !ok && !strings.EqualFold(req.ParseMethod, models.ParseMethodAuto) {
+		fmt.Fprintf(stderr, "era parse: unknown parse method %q (one of %s, auto)\n", req.ParseMethod, strings.Join(parser.Methods(), ", "))
+		return 2
+	}
+
+	ctx := context.Background()
+	src, err := readSource(ctx, req.FileLink)
+	if err != nil {
+		fmt.Fprintf(stderr, "era parse: %v\n", err)
+		return 1
+	}
+	trace("source %s (%d bytes)", req.FileLink, len(src.Data))
+
+	if strings.EqualFold(req.ParseMethod, models.ParseMethodAuto) {
+		name := src.Name
+		if name == "" {
+			name = req.FileLink
+		}
+		m, reason, err := parser.Detect(src.Data, src.ContentType, name)
+		if err != nil {
+			fmt.Fprintf(stderr, "era parse: %v\n", err)
+			return 1
+		}
+		trace("detected %s, from the %s", m, reason)
+		req.ParseMethod = m
+	}
+
+	// The transforms are the server's, over rules and a registry loaded the
+	// same way it loads them.
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	st := store.New()
+	proc := processor.New(fetcher.New(), st, logger)
+	if *rulesFile != "" {
+		n, err := contestrules.LoadRules(*rulesFile, st, *election)
+		if err != nil {
+			fmt.Fprintf(stderr, "era parse: %v\n", err)
+			return 1
+		}
+		trace("loaded %d contest rules from %s", n, *rulesFile)
+	}
+	proc.AddTransform(contestrules.NewEngine(st, *election).Apply)
+	if *registryFile != "" {
+		n, err := normalize.LoadRegistry(*registryFile, st)
+		if err != nil {
+			fmt.Fprintf(stderr, "era parse: %v\n", err)
+			return 1
+		}
+		trace("loaded %d registry candidates from %s", n, *registryFile)
+	}
+	proc.AddTransform(normalize.NewMatcher(st, normalize.DefaultThreshold).Apply)
+
+	tr := parser.NewTrace()
+	start := time.Now()
+	results, err := proc.Extract(parser.WithTrace(ctx, tr), req, src.Data)
+	if *parserTrace || err != nil {
+		for _, e := range tr.Entries() {
+			trace("  %-9s %s", e.Kind, e.Message)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "era parse: %s: %v\n", req.ParseMethod, err)
+		return 1
+	}
+	trace("parsed %d contests with %s in %s", len(results.Contests), req.ParseMethod, time.Since(start).Round(time.Microsecond))
+
+	w := stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(stderr, "era parse: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(results); err != nil {
+		fmt.Fprintf(stderr, "era parse: write results: %v\n", err)
+		return 1
+	}
+	if *out != "" {
+		trace("results written to %s", *out)
+	}
+	return 0
+}
+
+// readSource reads a local file, or fetches a URL. The operator typing the
+// command picks what is fetched, so the server's fetch policy isn't applied.
+func readSource(ctx context.Context, link string) (*fetcher.Source, error) {
+	if !strings.Contains(link, "://") {
+		data, err := os.ReadFile(link)
+		if err != nil {
+			return nil, err
+		}
+		return &fetcher.Source{Data: data, Name: link}, nil
+	}
+	f := fetcher.New()
+	f.SetPolicy(nil)
+	return f.FetchSource(ctx, link)
+}
+
+// readCountyConfig reads a county's source config: the JSON the server
+// registers counties with, or the same fields as a flat YAML mapping of
+// "key: value" lines. Nested settings, such as browser or email, need
+// JSON.
+func readCountyConfig(path string) (models.CountySource, error) {
+	var c models.CountySource
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &c); err != nil {
+			return c, fmt.Errorf("decode %s: %w", path, err)
+		}
+		return c, nil
+	}
+
+	fields := map[string]any{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if t := strings.TrimSpace(line); t == "" || strings.HasPrefix(t, "#") || t == "---" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(key) != key || key == "" {
+			return c, fmt.Errorf("%s:%d: want a top-level \"key: value\"; write nested settings as JSON", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		if value == "" {
+			return c, fmt.Errorf("%s:%d: %s has no value; write nested settings as JSON", path, n, key)
+		}
+		if u, err := strconv.Unquote(value); err == nil {
+			fields[key] = u
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			fields[key] = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		} else if i, err := strconv.Atoi(value); err == nil {
+			fields[key] = i
+		} else {
+			fields[key] = value
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return c, err
+	}
+	raw, _ := json.Marshal(fields)
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return c, nil
+}
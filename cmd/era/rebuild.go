@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+func rebuildProjections(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rebuild-projections", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", envOr("ERA_SERVER", "http://localhost:8080"), "base URL of the server")
+	apiKey := fs.String("api-key", envOr("ERA_API_KEY", ""), "API key with full visibility, if the server requires keys")
+	election := fs.String("election", envOr("ELECTION_ID", "default"), "election whose projections to rebuild")
+	from := fs.String("from", "", "only rebuild counties with events since this RFC 3339 time")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *from != "" {
+		if _, err := time.Parse(time.RFC3339, *from); err != nil {
+			fmt.Fprintf(stderr, "era rebuild-projections: --from: want an RFC 3339 time such as 2026-11-03T20:00:00-08:00\n")
+			return 2
+		}
+	}
+
+	q := url.Values{"election": {*election}}
+	if *from != "" {
+		q.Set("from", *from)
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(*server, "/")+"/api/v1/projections/rebuild?"+q.Encode(), nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "era rebuild-projections: %v\n", err)
+		return 2
+	}
+	if *apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+*apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(stderr, "era rebuild-projections: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		fmt.Fprintf(stderr, "era rebuild-projections: %s: %s\n", resp.Status, body.Error)
+		return 1
+	}
+
+	// Progress is printed every tenth of the way, the server sends more.
+	lastTenth := -1
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		var u models.RebuildUpdate
+		if err := json.Unmarshal(sc.Bytes(), &u); err != nil {
+			fmt.Fprintf(stderr, "era rebuild-projections: bad progress line: %v\n", err)
+			return 1
+		}
+		switch {
+		case u.Error != "":
+			fmt.Fprintf(stderr, "era rebuild-projections: failed after %d events, projections unchanged: %s\n", u.Done, u.Error)
+			return 1
+		case u.Report != nil:
+			fmt.Fprintf(stderr, "· rebuilt %d counties from %d events in %s, swapped live\n", len(u.Report.Counties), u.Report.Events, time.Duration(u.Report.DurationMs)*time.Millisecond)
+			enc := json.NewEncoder(stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(u.Report)
+			return 0
+		case u.Total > 0:
+			if tenth := u.Done * 10 / u.Total; tenth != lastTenth {
+				lastTenth = tenth
+				fmt.Fprintf(stderr, "· replayed %d/%d events (%d%%)\n", u.Done, u.Total, u.Done*100/u.Total)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		fmt.Fprintf(stderr, "era rebuild-projections: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(stderr, "era rebuild-projections: the server closed the stream before the rebuild finished; projections are unchanged unless it completed")
+	return 1
+}
@@ -0,0 +1,71 @@
+//go:build !cgo
+
+// Command era-server is the ERA API server, and the commands that run its
+// pipeline without serving: process, export and validate-config. They are
+// configured by the same environment as the server and share its wiring,
+// which only builds without cgo.
+//
+// The era binary (cmd/era) holds the operator tools that don't: parse and
+// reparse take their settings as flags, rebuild-projections talks to a
+// running server, and migrate works on an event log file. It builds
+// anywhere, so it can be installed on a workstation without the server.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/handlers"
+)
+
+const usage = `usage: era-server [command] [flags]
+
+commands:
+  serve             run the HTTP server (the default)
+  process           parse a county's source and publish its results
+  export            write a county's published results as csv, xlsx,
+                    openelections or cdf
+  validate-config   check the environment's configuration without serving
+
+process and export work on the results EVENT_LOG holds, configured by the
+same environment as the server, so batch and offline jobs don't need one
+running. Run process with the server stopped; both append to the log.
+Operator commands such as parse and migrate are in the era binary
+(cmd/era).
+`
+
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		serve(args)
+		return
+	}
+	switch args[0] {
+	case "serve":
+		serve(args[1:])
+	case "process":
+		os.Exit(processCommand(args[1:], os.Stdout, os.Stderr))
+	case "export":
+		os.Exit(exportCommand(args[1:], os.Stdout, os.Stderr))
+	case "validate-config":
+		os.Exit(validateConfig(args[1:], os.Stdout, os.Stderr))
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "era-server: unknown command %q\n\n%s", args[0], usage)
+		os.Exit(2)
+	}
+}
+
+// commandLogger is the logger of a command other than serve: text on
+// stderr, leaving stdout to the command's output, at LOG_LEVEL or warn
+func commandLogger(stderr io.Writer) (*slog.Logger, error) {
+	level, err := handlers.ParseLogLevel(getEnvOrDefault("LOG_LEVEL", "warn"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+	return slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level})), nil
+}
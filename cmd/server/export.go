@@ -0,0 +1,78 @@
+//go:build !cgo
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/many221/era_api_v1/internal/export"
+)
+
+// exportCommand is era-server export: a county's published results, as an
+// admin downloads them from GET /api/v1/results/{county}/export
+func exportCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	county := fs.String("county", "", "county whose results to export")
+	election := fs.String("election", "", "election the results are in (default ELECTION_ID)")
+	formatName := fs.String("format", "csv", "csv, xlsx, openelections or cdf")
+	out := fs.String("out", "", "write the export to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "era-server export: unexpected argument %q\n", fs.Arg(0))
+		return 2
+	}
+	if *county == "" {
+		fmt.Fprintln(stderr, "era-server export: --county is required")
+		return 2
+	}
+	format, ok := export.Lookup(*formatName)
+	if !ok {
+		fmt.Fprintf(stderr, "era-server export: --format must be %s\n", export.FormatNames())
+		return 2
+	}
+	logger, err := commandLogger(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "era-server export: %v\n", err)
+		return 2
+	}
+
+	pipe := newPipeline(logger, getEnvOrDefault("ELECTION_ID", defaultElection))
+	if pipe.eventLog != nil {
+		defer pipe.eventLog.Close()
+	}
+	e, err := pipe.store.Election(*election)
+	if err != nil {
+		fmt.Fprintf(stderr, "era-server export: unknown election %q\n", *election)
+		return 1
+	}
+	results, err := pipe.store.ElectionResults(e.ID, *county)
+	if err != nil {
+		fmt.Fprintf(stderr, "era-server export: no results for %s in election %s\n", *county, e.ID)
+		return 1
+	}
+
+	var buf bytes.Buffer
+	if err := format.Write(&buf, results, e.ID, nil); err != nil {
+		fmt.Fprintf(stderr, "era-server export: %v\n", err)
+		return 1
+	}
+	if *out != "" {
+		if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+			fmt.Fprintf(stderr, "era-server export: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if _, err := stdout.Write(buf.Bytes()); err != nil {
+		fmt.Fprintf(stderr, "era-server export: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -6,155 +6,818 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"expvar"
+	"flag"
 	"fmt"
-	"html/template"
 	"log/slog"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/many221/era_api_v1/internal/audit"
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/broadcast"
+	"github.com/many221/era_api_v1/internal/buildinfo"
+	"github.com/many221/era_api_v1/internal/clock"
+	"github.com/many221/era_api_v1/internal/cluster"
+	"github.com/many221/era_api_v1/internal/demo"
+	"github.com/many221/era_api_v1/internal/dump"
+	"github.com/many221/era_api_v1/internal/embargo"
+	"github.com/many221/era_api_v1/internal/forecast"
+	"github.com/many221/era_api_v1/internal/grpcapi"
+	"github.com/many221/era_api_v1/internal/handlers"
+	"github.com/many221/era_api_v1/internal/jobs"
+	"github.com/many221/era_api_v1/internal/mailwatch"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/openapi"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/redis"
+	"github.com/many221/era_api_v1/internal/region"
+	"github.com/many221/era_api_v1/internal/requestid"
+	"github.com/many221/era_api_v1/internal/scheduler"
+	"github.com/many221/era_api_v1/internal/sla"
+	"github.com/many221/era_api_v1/internal/snippets"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/templates"
+	"github.com/many221/era_api_v1/internal/visibility"
+	"github.com/many221/era_api_v1/internal/workerpool"
 )
 
 const (
-	defaultPort         = "8080"
-	defaultReadTimeout  = 30 * time.Second
-	defaultWriteTimeout = 30 * time.Second
-	shutdownTimeout     = 10 * time.Second
-	templateDir         = "internal/templates" // Directory for HTML templates
-	startupBanner      = `
-╔═══════════════════════════════════════════╗
-║           ERA API v1 Server               ║
-║     Election Results Aggregator API       ║
-╚═══════════════════════════════════════════╝
-`
+	defaultPort          = "8080"
+	defaultReadTimeout   = 30 * time.Second
+	defaultWriteTimeout  = 30 * time.Second
+	shutdownTimeout      = 10 * time.Second
+	maxConcurrentJobs    = 4
+	jobTimeout           = 15 * time.Minute // async jobs aren't bound by the write timeout
+	jobRetention         = time.Hour
+	forecastTimeout      = 20 * time.Second
+	refreshInterval      = 5 * time.Minute
+	refreshTimeout       = 10 * time.Minute
+	defaultElection      = "default"
+	templateDir          = "internal/templates" // Directory for HTML templates
+	templateReload       = 5 * time.Second      // How often template files are checked for changes
+	defaultNTPServer     = "pool.ntp.org:123"
+	defaultMaxSkewMillis = 1000
+	clockCheckInterval   = 10 * time.Minute
+	defaultCacheMaxAge   = 5   // seconds browsers reuse read responses
+	defaultCacheSMaxAge  = 15  // seconds CDNs reuse anonymous read responses
+	defaultAlertCooldown = 900 // seconds before the same alert is sent again
 )
 
 type ServerConfig struct {
-	port      string
-	templates *template.Template
-	logger    *slog.Logger
+	port          string
+	templates     *templates.Registry
+	logger        *slog.Logger
+	workers       *workerpool.Pool
+	stopScheduler context.CancelFunc
+	debugServer   *http.Server
+	jobs          *jobs.Manager
+	jobsFile      string
 }
 
-func main() {
-	// Print startup banner
-	fmt.Print(startupBanner)
+// serve runs the HTTP server, until it is interrupted or terminated
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	// --demo runs on a bundled sample election instead of county sources
+	demoMode := fs.Bool("demo", false, "serve a bundled sample election with scripted updates")
+	fs.Parse(args)
 
-	// Initialize structured logger with more detailed options
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level:     slog.LevelInfo,
-		AddSource: true,
-	}))
+	// Initialize structured logger. LOG_LEVEL sets its verbosity, which
+	// PUT /admin/loglevel changes live; LOG_FORMAT is json or text
+	logLevel := new(slog.LevelVar)
+	level, err := handlers.ParseLogLevel(getEnvOrDefault("LOG_LEVEL", "info"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid LOG_LEVEL: %v\n", err)
+		os.Exit(1)
+	}
+	logLevel.Set(level)
+	logOptions := &slog.HandlerOptions{Level: logLevel, AddSource: true}
+	var logHandler slog.Handler
+	switch format := getEnvOrDefault("LOG_FORMAT", "json"); strings.ToLower(format) {
+	case "json":
+		logHandler = slog.NewJSONHandler(os.Stdout, logOptions)
+	case "text":
+		logHandler = slog.NewTextHandler(os.Stdout, logOptions)
+	default:
+		fmt.Fprintf(os.Stderr, "invalid LOG_FORMAT %q, want json or text\n", format)
+		os.Exit(1)
+	}
+	logger := slog.New(requestid.NewLogHandler(logHandler))
 	slog.SetDefault(logger)
 
 	// Add startup information
+	build := buildinfo.Read()
 	logger.Info("server initialization",
-		"go_version", runtime.Version(),
+		"version", build.Version,
+		"commit", build.Commit,
+		"go_version", build.GoVersion,
 		"os", runtime.GOOS,
 		"arch", runtime.GOARCH,
 		"cpu_cores", runtime.NumCPU(),
 	)
 
-	// Load HTML templates
-	tmpl, err := template.ParseGlob(filepath.Join(templateDir, "*.html"))
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Create templates directory if it doesn't exist
-			if err := os.MkdirAll(templateDir, 0755); err != nil {
-				logger.Error("failed to create templates directory", 
-					"path", templateDir,
-					"error", err)
-				os.Exit(1)
-			}
-			
-			// Create a default template
-			defaultTemplate := filepath.Join(templateDir, "default.html")
-			defaultContent := `
-<!DOCTYPE html>
-<html>
-<head>
-    <title>ERA API - Election Results</title>
-    <style>
-        body { font-family: Arial, sans-serif; margin: 0; padding: 20px; }
-        .container { max-width: 1200px; margin: 0 auto; }
-        .results { border: 1px solid #ddd; padding: 15px; margin: 10px 0; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <h1>{{.Title}}</h1>
-        <div class="results">
-            {{.Content}}
-        </div>
-    </div>
-</body>
-</html>`
-			
-			if err := os.WriteFile(defaultTemplate, []byte(defaultContent), 0644); err != nil {
-				logger.Error("failed to create default template",
-					"path", defaultTemplate,
-					"error", err)
-				os.Exit(1)
-			}
-			
-			// Try loading templates again
-			tmpl, err = template.ParseGlob(filepath.Join(templateDir, "*.html"))
+	// Wire up the processing pipeline. Requests that name no election are
+	// for ELECTION_ID; others are created through /api/v1/elections
+	election := getEnvOrDefault("ELECTION_ID", defaultElection)
+	if *demoMode {
+		election = demo.Election().ID
+	}
+	pipe := newPipeline(logger, election)
+	if pipe.eventLog != nil {
+		defer pipe.eventLog.Close()
+	}
+	templateRegistry, resultStore, sourceFetcher, proc := pipe.templates, pipe.store, pipe.fetcher, pipe.processor
+	sourceArchive, trustedClock, notifier := pipe.archive, pipe.clock, pipe.notifier
+	contestRules, matcher := pipe.contestRules, pipe.matcher
+	// Embargoes lift by the clock snapshots are stamped with, so a skewed
+	// host clock doesn't lift them early or late
+	var publishClock clock.Clock = clock.System{}
+	if trustedClock != nil {
+		publishClock = trustedClock
+	}
+	// Who changed what through the API is kept in the audit log, in a file
+	// when AUDIT_LOG is set
+	auditLog := audit.NewLog()
+	if path := os.Getenv("AUDIT_LOG"); path != "" {
+		var err error
+		if auditLog, err = audit.OpenLog(path); err != nil {
+			logger.Error("failed to open audit log", "path", path, "error", err)
+			os.Exit(1)
+		}
+		defer auditLog.Close()
+		logger.Info("opened audit log", "path", path, "entries", len(auditLog.Entries(audit.Query{})))
+	}
+
+	if url := os.Getenv("FORECAST_URL"); url != "" {
+		forecasts := forecast.NewService(forecast.NewHTTPProvider(url, forecastTimeout), resultStore, logger, forecastTimeout)
+		proc.OnSnapshot(forecasts.OnSnapshot)
+		logger.Info("forecast integration enabled", "url", url)
+	}
+	snippetService := snippets.NewService(resultStore, logger)
+	proc.OnSnapshot(snippetService.OnSnapshot)
+	broadcastFeeds := broadcast.NewService(resultStore, logger)
+	proc.OnSnapshot(broadcastFeeds.OnSnapshot)
+	grpcServer := grpcapi.NewServer(proc, resultStore, logger)
+	grpcServer.SetClock(publishClock)
+	proc.OnSnapshot(grpcServer.OnSnapshot)
+	// Replicas behind a load balancer share published snapshots, and cached
+	// responses, through Redis
+	var clusterNode *cluster.Node
+	if url := os.Getenv("REDIS_URL"); url != "" {
+		redisClient, err := redis.Open(url)
+		if err != nil {
+			logger.Error("invalid REDIS_URL", "error", err)
+			os.Exit(1)
+		}
+		clusterNode = cluster.NewNode(redisClient, proc, logger)
+		proc.OnSnapshot(clusterNode.OnSnapshot)
+	}
+	// Responses name the region and instance that served them, so clients
+	// behind a geo-balanced deployment can spot version skew
+	instanceID := os.Getenv("INSTANCE_ID")
+	if instanceID == "" && clusterNode != nil {
+		instanceID = clusterNode.Instance()
+	}
+	deployment := region.New(os.Getenv("REGION"), instanceID)
+	grpcServer.SetIdentity(deployment)
+	jobManager := jobs.NewManager(jobs.Config{
+		MaxConcurrent: maxConcurrentJobs,
+		Timeout:       jobTimeout,
+		Retention:     jobRetention,
+	}, logger)
+
+	// API keys are optional; without them every caller sees everything
+	profiles := visibility.DefaultProfiles()
+	if path := os.Getenv("RESPONSE_PROFILES"); path != "" {
+		if profiles, err = visibility.LoadProfiles(path); err != nil {
+			logger.Error("failed to load response profiles", "path", path, "error", err)
+			os.Exit(1)
+		}
+	}
+	// Access tiers delay what keys in them see, e.g. free keys by 5m
+	var tiers *embargo.Tiers
+	if path := os.Getenv("ACCESS_TIERS_FILE"); path != "" {
+		if tiers, err = embargo.LoadTiers(path); err != nil {
+			logger.Error("failed to load access tiers", "path", path, "error", err)
+			os.Exit(1)
+		}
+	}
+	var apiKeys *auth.Keys
+	if path := os.Getenv("API_KEYS_FILE"); path != "" {
+		apiKeys, err = auth.LoadKeys(path, getEnvOrDefault("ANONYMOUS_VISIBILITY", models.VisibilityPublic), getEnvOrDefault("ANONYMOUS_ROLE", models.RoleViewer), profiles, tiers)
+		if err != nil {
+			logger.Error("failed to load api keys", "path", path, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("api key authentication enabled", "path", path)
+	}
+	// JWTs from an OIDC issuer are accepted as bearer tokens alongside any
+	// static keys, with roles mapped from a claim. Their callers are in
+	// OIDC_TIER, the anonymous tier by default
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		roleMap := make(map[string]string)
+		for _, pair := range getEnvList("OIDC_ROLE_MAP") {
+			value, role, _ := strings.Cut(pair, "=")
+			roleMap[strings.TrimSpace(value)] = strings.TrimSpace(role)
+		}
+		oidc, err := auth.NewOIDC(auth.OIDCConfig{
+			Issuer:      issuer,
+			Audience:    os.Getenv("OIDC_AUDIENCE"),
+			RoleClaim:   os.Getenv("OIDC_ROLE_CLAIM"),
+			RoleMap:     roleMap,
+			DefaultRole: os.Getenv("OIDC_DEFAULT_ROLE"),
+			Visibility:  os.Getenv("OIDC_VISIBILITY"),
+			Profile:     os.Getenv("OIDC_PROFILE"),
+			Tier:        os.Getenv("OIDC_TIER"),
+		})
+		if err != nil {
+			logger.Error("invalid OIDC configuration", "error", err)
+			os.Exit(1)
+		}
+		if apiKeys == nil {
+			apiKeys, err = auth.NewKeys(nil, getEnvOrDefault("ANONYMOUS_VISIBILITY", models.VisibilityPublic), getEnvOrDefault("ANONYMOUS_ROLE", models.RoleViewer), profiles, tiers)
 			if err != nil {
-				logger.Error("failed to load templates after creation", "error", err)
+				logger.Error("invalid anonymous access", "error", err)
 				os.Exit(1)
 			}
-			
-			logger.Info("created default template", "path", defaultTemplate)
-		} else {
-			logger.Error("failed to load templates", "error", err)
+		}
+		if err := apiKeys.UseOIDC(oidc); err != nil {
+			logger.Error("invalid OIDC configuration", "error", err)
 			os.Exit(1)
 		}
+		// The issuer's keys are fetched again when a token needs them, so
+		// it being unreachable now isn't fatal
+		if err := oidc.Refresh(context.Background()); err != nil {
+			logger.Warn("failed to fetch OIDC signing keys", "issuer", issuer, "error", err)
+		}
+		logger.Info("oidc authentication enabled", "issuer", issuer)
 	}
 
 	// Initialize server config
 	config := &ServerConfig{
 		port:      getEnvOrDefault("PORT", defaultPort),
-		templates: tmpl,
+		templates: templateRegistry,
 		logger:    logger,
 	}
 
+	// Start the worker pool and scheduler for registered counties
+	schedCtx, stopScheduler := context.WithCancel(context.Background())
+	config.stopScheduler = stopScheduler
+	config.workers = workerpool.New(workerpool.Config{
+		Workers:        getEnvInt("REFRESH_WORKERS", 4),
+		QueueSize:      getEnvInt("REFRESH_QUEUE_SIZE", 64),
+		PerSourceLimit: getEnvInt("REFRESH_PER_SOURCE_LIMIT", 2),
+	}, logger)
+	config.workers.Start(context.Background())
+	refreshScheduler := scheduler.New(resultStore, proc, config.workers, logger, refreshInterval, refreshTimeout)
+	refreshScheduler.SetAlerter(notifier)
+	go refreshScheduler.Run(schedCtx)
+	go templateRegistry.Watch(schedCtx, templateReload)
+	slaMonitor := sla.NewMonitor(resultStore, logger)
+	slaMonitor.SetAlerter(notifier)
+	go slaMonitor.Run(schedCtx)
+	// Counties that email their results, registered with an email rule,
+	// are ingested from the mailbox at IMAP_ADDR, over TLS unless
+	// IMAP_INSECURE is on
+	if addr := os.Getenv("IMAP_ADDR"); addr != "" {
+		mailWatcher := mailwatch.New(mailwatch.Config{
+			Addr:     addr,
+			Insecure: os.Getenv("IMAP_INSECURE") == "on",
+			Username: os.Getenv("IMAP_USERNAME"),
+			Password: os.Getenv("IMAP_PASSWORD"),
+			Mailbox:  os.Getenv("IMAP_MAILBOX"),
+			Interval: time.Duration(getEnvInt("IMAP_POLL_SECONDS", 60)) * time.Second,
+		}, resultStore, proc, logger)
+		go mailWatcher.Run(schedCtx)
+	}
+	if clusterNode != nil {
+		go clusterNode.Run(schedCtx)
+	}
+	// The demo's counties report an update every DEMO_INTERVAL_SECONDS
+	// until counting ends
+	if *demoMode {
+		sampleElection := demo.New(resultStore, proc, logger, time.Duration(getEnvInt("DEMO_INTERVAL_SECONDS", 30))*time.Second)
+		sampleElection.Setup()
+		go sampleElection.Run(schedCtx)
+	}
+	if trustedClock != nil {
+		go trustedClock.Run(schedCtx, clockCheckInterval)
+	}
+
+	// What this binary is and which optional features it runs with, served
+	// at /version so deployments can be identified by tooling
+	version := models.VersionInfo{
+		Service: "era",
+		Build:   build,
+		Parsers: make(map[string]int),
+		Features: enabledFeatures(map[string]bool{
+			"event-log":          os.Getenv("EVENT_LOG") != "",
+			"audit-log":          os.Getenv("AUDIT_LOG") != "",
+			"source-archive":     sourceArchive != nil,
+			"trusted-clock":      trustedClock != nil,
+			"quarantine":         os.Getenv("QUARANTINE") != "off",
+			"anomaly-checks":     os.Getenv("ANOMALY_CHECKS") != "off",
+			"browser":            os.Getenv("BROWSER_URL") != "",
+			"slack":              os.Getenv("SLACK_WEBHOOK_URL") != "",
+			"pagerduty":          os.Getenv("PAGERDUTY_ROUTING_KEY") != "",
+			"parser-fallback":    os.Getenv("PARSER_FALLBACK") == "on",
+			"contest-rules":      os.Getenv("CONTEST_RULES") != "",
+			"candidate-registry": os.Getenv("CANDIDATE_REGISTRY") != "",
+			"election-manifest":  os.Getenv("ELECTION_MANIFEST") != "",
+			"forecast":           os.Getenv("FORECAST_URL") != "",
+			"cluster":            clusterNode != nil,
+			"api-keys":           os.Getenv("API_KEYS_FILE") != "",
+			"oidc":               os.Getenv("OIDC_ISSUER") != "",
+			"access-tiers":       tiers != nil,
+			"mailwatch":          os.Getenv("IMAP_ADDR") != "",
+			"demo":               *demoMode,
+			"debug-addr":         os.Getenv("DEBUG_ADDR") != "",
+		}),
+		Region:    deployment.Region,
+		Instance:  deployment.Instance,
+		StartedAt: time.Now().UTC(),
+	}
+	for _, info := range parser.Parsers() {
+		version.Parsers[info.Method] = info.Version
+	}
+	logger.Info("startup summary",
+		"version", build.Version,
+		"commit", build.Commit,
+		"port", config.port,
+		"election", election,
+		"region", deployment.Region,
+		"instance", deployment.Instance,
+		"features", version.Features,
+		"parsers", version.Parsers,
+	)
+
 	// Create new server mux
 	mux := http.NewServeMux()
 
 	// Register routes
-	mux.HandleFunc("POST /api/v1/process", corsMiddleware(handleProcess))
-	mux.HandleFunc("GET /health", healthCheck)
-	
+	processHandler := handlers.NewProcessHandler(proc, jobManager, logger)
+	// Jobs a shutdown cut short are saved to JOBS_FILE and run again here
+	config.jobs, config.jobsFile = jobManager, os.Getenv("JOBS_FILE")
+	if config.jobsFile != "" {
+		n, err := jobManager.Restore(config.jobsFile)
+		if err != nil {
+			logger.Error("failed to resume saved jobs", "path", config.jobsFile, "error", err)
+		} else if n > 0 {
+			logger.Info("resumed saved jobs", "jobs", n, "path", config.jobsFile)
+		}
+	}
+	// Processing responses say how loaded the server is, so clients can
+	// pace themselves during spikes; load balancers can check the same at
+	// /api/v1/capacity
+	capacity := handlers.NewCapacityHandler(jobManager, config.workers)
+	mux.HandleFunc("POST /api/v1/process", corsMiddleware(capacity.Track(processHandler.ServeHTTP)))
+	mux.HandleFunc("POST /api/v1/upload", corsMiddleware(capacity.Track(processHandler.Upload)))
+	// A batch, such as every county at poll close, runs on the refresh workers
+	mux.HandleFunc("POST /api/v1/process/batch", corsMiddleware(capacity.Track(handlers.NewBatchHandler(proc, resultStore, config.workers, logger).ServeHTTP)))
+	mux.HandleFunc("POST /api/v1/state-feeds/process", corsMiddleware(capacity.Track(handlers.NewStateFeedsHandler(proc, logger).Process)))
+	mux.HandleFunc("GET /api/v1/capacity", corsMiddleware(capacity.ServeHTTP))
+	mux.HandleFunc("GET /api/v1/conflicts", corsMiddleware(handlers.NewConflictsHandler(resultStore, proc).List))
+	mux.HandleFunc("GET /api/v1/jobs/{id}", corsMiddleware(handlers.NewJobsHandler(jobManager).ServeHTTP))
+	quarantine := handlers.NewQuarantineHandler(resultStore, proc, logger)
+	mux.HandleFunc("GET /api/v1/quarantine", corsMiddleware(quarantine.List))
+	mux.HandleFunc("GET /api/v1/quarantine/{id}", corsMiddleware(quarantine.Get))
+	mux.HandleFunc("POST /api/v1/quarantine/{id}/release", corsMiddleware(quarantine.Release))
+	mux.HandleFunc("DELETE /api/v1/quarantine/{id}", corsMiddleware(quarantine.Delete))
+	datasets := handlers.NewDatasetsHandler(resultStore, proc)
+	mux.HandleFunc("GET /api/v1/datasets", corsMiddleware(datasets.List))
+	mux.HandleFunc("GET /api/v1/datasets/{label}", corsMiddleware(datasets.Get))
+	mux.HandleFunc("GET /api/v1/datasets/{label}/results/{county}", corsMiddleware(datasets.Results))
+	mux.HandleFunc("POST /api/v1/datasets/{label}/activate", corsMiddleware(datasets.Activate))
+	mux.HandleFunc("DELETE /api/v1/datasets/{label}", corsMiddleware(datasets.Delete))
+	parseErrors := handlers.NewParseErrorsHandler(resultStore, proc, logger)
+	mux.HandleFunc("GET /api/v1/errors", corsMiddleware(parseErrors.List))
+	mux.HandleFunc("GET /api/v1/errors/{id}", corsMiddleware(parseErrors.Get))
+	mux.HandleFunc("GET /api/v1/errors/{id}/source", corsMiddleware(parseErrors.Source))
+	mux.HandleFunc("POST /api/v1/errors/{id}/retry", corsMiddleware(parseErrors.Retry))
+	mux.HandleFunc("DELETE /api/v1/errors/{id}", corsMiddleware(parseErrors.Delete))
+	mux.HandleFunc("GET /api/v1/layout-changes", corsMiddleware(handlers.NewLayoutHandler(resultStore).Changes))
+	alerts := handlers.NewAlertsHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/alerts", corsMiddleware(alerts.List))
+	slas := handlers.NewSLAHandler(resultStore, slaMonitor)
+	mux.HandleFunc("GET /api/v1/slas", corsMiddleware(slas.List))
+	mux.HandleFunc("POST /api/v1/slas", corsMiddleware(slas.Create))
+	mux.HandleFunc("GET /api/v1/slas/breaches", corsMiddleware(slas.Breaches))
+	mux.HandleFunc("GET /api/v1/slas/report", corsMiddleware(slas.Report))
+	mux.HandleFunc("GET /api/v1/slas/{id}", corsMiddleware(slas.Get))
+	mux.HandleFunc("DELETE /api/v1/slas/{id}", corsMiddleware(slas.Delete))
+	latency := handlers.NewLatencyHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/latency", corsMiddleware(latency.List))
+	mux.HandleFunc("GET /api/v1/latency/{county}", corsMiddleware(latency.Get))
+	anomalies := handlers.NewAnomaliesHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/anomalies", corsMiddleware(anomalies.List))
+	eventsHandler := handlers.NewEventsHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/events", corsMiddleware(eventsHandler.List))
+	mux.HandleFunc("POST /api/v1/projections/rebuild", corsMiddleware(eventsHandler.Rebuild))
+	mux.HandleFunc("GET /api/v1/runbooks", corsMiddleware(alerts.Runbooks))
+	mux.HandleFunc("PUT /api/v1/runbooks/{type}", corsMiddleware(alerts.PutRunbook))
+	mux.HandleFunc("PUT /api/v1/runbooks/{type}/{county}", corsMiddleware(alerts.PutRunbook))
+	mux.HandleFunc("DELETE /api/v1/runbooks/{type}", corsMiddleware(alerts.DeleteRunbook))
+	mux.HandleFunc("DELETE /api/v1/runbooks/{type}/{county}", corsMiddleware(alerts.DeleteRunbook))
+
+	// Reads of published data carry ETags from the store version and may be
+	// cached briefly, by CDNs too for anonymous callers
+	cache := handlers.NewCache(resultStore,
+		time.Duration(getEnvInt("CACHE_MAX_AGE", defaultCacheMaxAge))*time.Second,
+		time.Duration(getEnvInt("CACHE_S_MAXAGE", defaultCacheSMaxAge))*time.Second)
+	if clusterNode != nil && os.Getenv("SHARED_CACHE") != "off" {
+		cache.SetShared(clusterNode)
+	}
+
+	aggregates := handlers.NewAggregateHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/aggregate", corsMiddleware(cache.Wrap(aggregates.ServeHTTP)))
+	mux.HandleFunc("GET /api/v1/results/{contest}/geojson", corsMiddleware(cache.Wrap(aggregates.GeoJSON)))
+	mux.HandleFunc("GET /api/v1/turnout", corsMiddleware(cache.Wrap(handlers.NewTurnoutHandler(resultStore).ServeHTTP)))
+	mux.HandleFunc("GET /api/v1/search", corsMiddleware(cache.Wrap(handlers.NewSearchHandler(resultStore).ServeHTTP)))
+	dumpHandler := handlers.NewDumpHandler(resultStore, dump.NewBuilder(resultStore, getEnvInt("DUMP_PART_BYTES", dump.DefaultPartSize)))
+	mux.HandleFunc("GET /api/v1/dump.ndjson.gz", corsMiddleware(dumpHandler.ServeHTTP))
+	mux.HandleFunc("GET /api/v1/dump/manifest", corsMiddleware(dumpHandler.Manifest))
+	mux.HandleFunc("GET /api/v1/dump/{snapshot}/parts/{index}", corsMiddleware(dumpHandler.Part))
+
+	results := handlers.NewResultsHandler(resultStore, publishClock)
+	mux.HandleFunc("GET /api/v1/results/{county}", corsMiddleware(cache.Wrap(results.Get)))
+	mux.HandleFunc("GET /api/v1/results/{county}/export", corsMiddleware(cache.Wrap(results.Export)))
+	snapshotLog := handlers.NewSnapshotLogHandler(resultStore)
+	if sourceArchive != nil {
+		artifacts := handlers.NewArtifactsHandler(sourceArchive, logger)
+		mux.HandleFunc("GET /api/v1/results/{county}/artifacts", corsMiddleware(artifacts.List))
+		mux.HandleFunc("GET /api/v1/results/{county}/artifacts/{snapshot}", corsMiddleware(artifacts.Get))
+		// A parser fixed mid-count corrects published snapshots from the
+		// files they were parsed from
+		mux.HandleFunc("POST /api/v1/results/{county}/reprocess", corsMiddleware(capacity.Track(processHandler.Reprocess)))
+		// Certified results are frozen, their bundle archived for good
+		certifications := handlers.NewCertificationsHandler(proc, resultStore, sourceArchive, logger)
+		mux.HandleFunc("POST /api/v1/elections/{election}/results/{county}/certify", corsMiddleware(certifications.Certify))
+		mux.HandleFunc("GET /api/v1/elections/{election}/certifications", corsMiddleware(certifications.List))
+		mux.HandleFunc("GET /api/v1/elections/{election}/results/{county}/certification", corsMiddleware(certifications.Get))
+		mux.HandleFunc("GET /api/v1/elections/{election}/results/{county}/certification/bundle.zip", corsMiddleware(certifications.Bundle))
+	}
+	mux.HandleFunc("GET /api/v1/results/{county}/log", corsMiddleware(cache.Wrap(snapshotLog.Log)))
+	mux.HandleFunc("GET /api/v1/results/{county}/log/proof", corsMiddleware(cache.Wrap(snapshotLog.Proof)))
+
+	overlays := handlers.NewOverlaysHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/results/{county}/contests/{contest}/overlays", corsMiddleware(cache.Wrap(overlays.List)))
+	mux.HandleFunc("POST /api/v1/results/{county}/contests/{contest}/overlays", corsMiddleware(overlays.Create))
+	mux.HandleFunc("DELETE /api/v1/overlays/{id}", corsMiddleware(overlays.Delete))
+
+	mux.HandleFunc("GET /api/v1/broadcast/{county}", corsMiddleware(handlers.NewBroadcastHandler(broadcastFeeds).ServeHTTP))
+
+	snippetsHandler := handlers.NewSnippetsHandler(resultStore, snippetService, publishClock)
+	mux.HandleFunc("GET /api/v1/snippets", corsMiddleware(snippetsHandler.List))
+	mux.HandleFunc("POST /api/v1/snippets", corsMiddleware(snippetsHandler.Create))
+	mux.HandleFunc("GET /api/v1/snippets/{id}", corsMiddleware(snippetsHandler.Get))
+	mux.HandleFunc("DELETE /api/v1/snippets/{id}", corsMiddleware(snippetsHandler.Delete))
+
+	candidates := handlers.NewCandidatesHandler(resultStore, matcher)
+	mux.HandleFunc("GET /api/v1/candidates", corsMiddleware(candidates.List))
+	mux.HandleFunc("POST /api/v1/candidates", corsMiddleware(candidates.Create))
+	mux.HandleFunc("GET /api/v1/candidates/match", corsMiddleware(candidates.Match))
+	mux.HandleFunc("POST /api/v1/candidates/{id}/aliases", corsMiddleware(candidates.AddAliases))
+	mux.HandleFunc("DELETE /api/v1/candidates/{id}", corsMiddleware(candidates.Delete))
+
+	rules := handlers.NewContestRulesHandler(resultStore, contestRules, election)
+	mux.HandleFunc("GET /api/v1/contest-rules", corsMiddleware(rules.List))
+	mux.HandleFunc("POST /api/v1/contest-rules", corsMiddleware(rules.Create))
+	mux.HandleFunc("GET /api/v1/contest-rules/test", corsMiddleware(rules.Test))
+	mux.HandleFunc("DELETE /api/v1/contest-rules/{id}", corsMiddleware(rules.Delete))
+	measureLinks := handlers.NewMeasureLinksHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/measure-links", corsMiddleware(measureLinks.List))
+	mux.HandleFunc("POST /api/v1/measure-links", corsMiddleware(measureLinks.Create))
+	mux.HandleFunc("GET /api/v1/measure-links/suggestions", corsMiddleware(measureLinks.Suggestions))
+	mux.HandleFunc("DELETE /api/v1/measure-links/{id}", corsMiddleware(measureLinks.Delete))
+	jurisdictions := handlers.NewJurisdictionsHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/jurisdictions", corsMiddleware(jurisdictions.List))
+	mux.HandleFunc("POST /api/v1/jurisdictions", corsMiddleware(jurisdictions.Create))
+	mux.HandleFunc("GET /api/v1/jurisdictions/{id}", corsMiddleware(jurisdictions.Get))
+	mux.HandleFunc("DELETE /api/v1/jurisdictions/{id}", corsMiddleware(jurisdictions.Delete))
+	mux.HandleFunc("POST /api/v1/jurisdictions/{id}/contests", corsMiddleware(jurisdictions.AttachContest))
+	mux.HandleFunc("DELETE /api/v1/jurisdictions/{id}/contests/{contest}", corsMiddleware(jurisdictions.DetachContest))
+	districts := handlers.NewDistrictsHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/districts", corsMiddleware(districts.List))
+	mux.HandleFunc("POST /api/v1/districts/import", corsMiddleware(districts.Import))
+	mux.HandleFunc("GET /api/v1/districts/{id}", corsMiddleware(districts.Get))
+	mux.HandleFunc("DELETE /api/v1/districts/{id}", corsMiddleware(districts.Delete))
+	mux.HandleFunc("GET /api/v1/districts/{id}/results", corsMiddleware(cache.Wrap(districts.Results)))
+
+	manifests := handlers.NewManifestHandler(resultStore, sourceFetcher, election)
+	mux.HandleFunc("GET /api/v1/manifest", corsMiddleware(manifests.Get))
+	mux.HandleFunc("PUT /api/v1/manifest", corsMiddleware(manifests.Put))
+	mux.HandleFunc("DELETE /api/v1/manifest", corsMiddleware(manifests.Delete))
+	mux.HandleFunc("POST /api/v1/manifest/import", corsMiddleware(manifests.Import))
+	mux.HandleFunc("GET /api/v1/manifest/check", corsMiddleware(manifests.Check))
+
+	lite := handlers.NewLiteHandler(resultStore, publishClock)
+	mux.HandleFunc("GET /lite/counties", corsMiddleware(cache.Wrap(lite.Counties)))
+	mux.HandleFunc("GET /lite/aggregate/{contest}", corsMiddleware(cache.Wrap(lite.Aggregate)))
+	mux.HandleFunc("GET /lite/{county}", corsMiddleware(cache.Wrap(lite.County)))
+
+	elections := handlers.NewElectionsHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/elections", corsMiddleware(elections.List))
+	mux.HandleFunc("POST /api/v1/elections", corsMiddleware(elections.Create))
+	mux.HandleFunc("GET /api/v1/elections/{election}", corsMiddleware(elections.Get))
+	mux.HandleFunc("DELETE /api/v1/elections/{election}", corsMiddleware(elections.Delete))
+	mux.HandleFunc("POST /api/v1/elections/{election}/archive", corsMiddleware(elections.Archive))
+	mux.HandleFunc("POST /api/v1/elections/{election}/aliases/import", corsMiddleware(elections.ImportAliases))
+	// Any election's results, archived or not, by path
+	mux.HandleFunc("GET /api/v1/elections/{election}/results", corsMiddleware(cache.Wrap(elections.Results)))
+	mux.HandleFunc("GET /api/v1/elections/{election}/results/{county}", corsMiddleware(cache.Wrap(results.Get)))
+	mux.HandleFunc("GET /api/v1/elections/{election}/results/{county}/export", corsMiddleware(cache.Wrap(results.Export)))
+	mux.HandleFunc("GET /api/v1/elections/{election}/results/{county}/log", corsMiddleware(cache.Wrap(snapshotLog.Log)))
+	mux.HandleFunc("GET /api/v1/elections/{election}/results/{county}/log/proof", corsMiddleware(cache.Wrap(snapshotLog.Proof)))
+	legalHolds := handlers.NewLegalHoldsHandler(resultStore, logger)
+	mux.HandleFunc("GET /api/v1/legal-holds", corsMiddleware(legalHolds.List))
+	mux.HandleFunc("POST /api/v1/legal-holds", corsMiddleware(legalHolds.Create))
+	mux.HandleFunc("GET /api/v1/legal-holds/{id}", corsMiddleware(legalHolds.Get))
+	mux.HandleFunc("POST /api/v1/legal-holds/{id}/release", corsMiddleware(legalHolds.Release))
+	raceCalls := handlers.NewRaceCallsHandler(resultStore, logger, grpcServer.OnRaceCall)
+	mux.HandleFunc("GET /api/v1/contests/{id}/call", corsMiddleware(raceCalls.Get))
+	mux.HandleFunc("POST /api/v1/contests/{id}/call", corsMiddleware(raceCalls.Call))
+	mux.HandleFunc("DELETE /api/v1/contests/{id}/call", corsMiddleware(raceCalls.Retract))
+	mux.HandleFunc("GET /api/v1/audit", corsMiddleware(handlers.NewAuditHandler(auditLog).List))
+	counties := handlers.NewCountiesHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/counties", corsMiddleware(counties.List))
+	mux.HandleFunc("POST /api/v1/counties", corsMiddleware(counties.Register))
+	mux.HandleFunc("DELETE /api/v1/counties/{county}", corsMiddleware(counties.Delete))
+	credentials := handlers.NewCredentialsHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/counties/{county}/credentials", corsMiddleware(credentials.Get))
+	mux.HandleFunc("PUT /api/v1/counties/{county}/credentials", corsMiddleware(credentials.Put))
+	mux.HandleFunc("DELETE /api/v1/counties/{county}/credentials", corsMiddleware(credentials.Delete))
+	contacts := handlers.NewContactsHandler(resultStore)
+	mux.HandleFunc("GET /api/v1/contacts", corsMiddleware(contacts.List))
+	mux.HandleFunc("GET /api/v1/contacts/{county}", corsMiddleware(contacts.Get))
+	mux.HandleFunc("PUT /api/v1/contacts/{county}", corsMiddleware(contacts.Put))
+	mux.HandleFunc("DELETE /api/v1/contacts/{county}", corsMiddleware(contacts.Delete))
+	templatesHandler := handlers.NewTemplatesHandler(templateRegistry, resultStore)
+	mux.HandleFunc("GET /api/v1/templates", corsMiddleware(templatesHandler.List))
+	mux.HandleFunc("GET /api/v1/templates/assignments", corsMiddleware(templatesHandler.Assignments))
+	mux.HandleFunc("PUT /api/v1/templates/assignments", corsMiddleware(templatesHandler.SetAssignments))
+	renderHandler := handlers.NewRenderHandler(proc)
+	mux.HandleFunc("POST /api/v1/render", corsMiddleware(renderHandler.Render))
+	mux.HandleFunc("GET /api/v1/templates/{name}", corsMiddleware(templatesHandler.Get))
+	mux.HandleFunc("PUT /api/v1/templates/{name}", corsMiddleware(templatesHandler.Put))
+	mux.HandleFunc("DELETE /api/v1/templates/{name}", corsMiddleware(templatesHandler.Delete))
+
+	mux.HandleFunc("GET /api/v1/parsers", corsMiddleware(handlers.NewParsersHandler().ServeHTTP))
+	mux.HandleFunc("GET /.well-known/era.json", corsMiddleware(handlers.NewDiscoveryHandler(discoveryDocument(deployment), resultStore).ServeHTTP))
+	openAPI, err := handlers.NewOpenAPIHandler(openapi.API())
+	if err != nil {
+		logger.Error("failed to build openapi document", "error", err)
+		os.Exit(1)
+	}
+	mux.HandleFunc("GET /api/v1/openapi.json", corsMiddleware(openAPI.Spec))
+	mux.HandleFunc("GET /api/v1/docs", corsMiddleware(openAPI.Docs))
+	graphqlHandler := handlers.NewGraphQLHandler(resultStore)
+	mux.HandleFunc("GET /graphql", corsMiddleware(graphqlHandler.ServeHTTP))
+	mux.HandleFunc("POST /graphql", corsMiddleware(graphqlHandler.ServeHTTP))
+	mux.HandleFunc("GET /health", healthCheck(trustedClock, deployment))
+	mux.HandleFunc("GET /version", corsMiddleware(handlers.NewVersionHandler(version).ServeHTTP))
+
+	// Profiles and runtime stats, as net/http/pprof and expvar serve them
+	debug := debugEndpoints(version, resultStore, config.workers)
+
+	// The admin dashboard signs in with an API key, so it is only served
+	// when keys are configured. It is same-origin only, so no CORS
+	if apiKeys != nil {
+		admin := handlers.NewAdminHandler(resultStore, refreshScheduler, config.workers)
+		mux.HandleFunc("GET /admin", admin.Dashboard)
+		mux.HandleFunc("POST /admin/counties/{county}/refresh", admin.Refresh)
+		mux.HandleFunc("POST /admin/templates/preview", templatesHandler.Preview)
+		logLevelHandler := handlers.NewLogLevelHandler(logLevel, logger)
+		mux.HandleFunc("GET /admin/loglevel", logLevelHandler.Get)
+		mux.HandleFunc("PUT /admin/loglevel", logLevelHandler.Set)
+		// CPU profiles and traces here must be shorter than the write
+		// timeout; DEBUG_ADDR has none
+		mux.Handle("/admin/debug/", http.StripPrefix("/admin", debug))
+	} else {
+		logger.Info("admin dashboard disabled, set API_KEYS_FILE to enable it")
+	}
+
+	// Routes that change something are admin only unless listed here;
+	// reads are open to every role
+	routeRoles := auth.Routes{
+		"POST /api/v1/process":                  models.RoleIngester,
+		"POST /api/v1/process/batch":            models.RoleIngester,
+		"POST /api/v1/state-feeds/process":      models.RoleIngester,
+		"POST /api/v1/upload":                   models.RoleIngester,
+		"POST /api/v1/render":                   models.RoleViewer,
+		"POST /api/v1/errors/{id}/retry":        models.RoleIngester,
+		"POST /admin/counties/{county}/refresh": models.RoleIngester,
+
+		"POST /api/v1/quarantine/{id}/release":                      models.RoleEditor,
+		"DELETE /api/v1/quarantine/{id}":                            models.RoleEditor,
+		"POST /api/v1/datasets/{label}/activate":                    models.RoleEditor,
+		"DELETE /api/v1/datasets/{label}":                           models.RoleEditor,
+		"DELETE /api/v1/errors/{id}":                                models.RoleEditor,
+		"POST /api/v1/results/{county}/contests/{contest}/overlays": models.RoleEditor,
+		"DELETE /api/v1/overlays/{id}":                              models.RoleEditor,
+		"POST /api/v1/snippets":                                     models.RoleEditor,
+		"DELETE /api/v1/snippets/{id}":                              models.RoleEditor,
+		"POST /api/v1/candidates":                                   models.RoleEditor,
+		"POST /api/v1/candidates/{id}/aliases":                      models.RoleEditor,
+		"DELETE /api/v1/candidates/{id}":                            models.RoleEditor,
+		"POST /api/v1/contest-rules":                                models.RoleEditor,
+		"DELETE /api/v1/contest-rules/{id}":                         models.RoleEditor,
+		"POST /api/v1/measure-links":                                models.RoleEditor,
+		"DELETE /api/v1/measure-links/{id}":                         models.RoleEditor,
+		"POST /api/v1/jurisdictions/{id}/contests":                  models.RoleEditor,
+		"DELETE /api/v1/jurisdictions/{id}/contests/{contest}":      models.RoleEditor,
+		"PUT /api/v1/manifest":                                      models.RoleEditor,
+		"DELETE /api/v1/manifest":                                   models.RoleEditor,
+		"POST /api/v1/manifest/import":                              models.RoleEditor,
+		"POST /api/v1/elections/{election}/aliases/import":          models.RoleEditor,
+		"POST /api/v1/contests/{id}/call":                           models.RoleEditor,
+		"DELETE /api/v1/contests/{id}/call":                         models.RoleEditor,
+		"PUT /api/v1/contacts/{county}":                             models.RoleEditor,
+		"DELETE /api/v1/contacts/{county}":                          models.RoleEditor,
+
+		// Unpublished snapshots and raw sources are read by those who
+		// review them, not by every viewer
+		"GET /api/v1/quarantine/{id}":                       models.RoleEditor,
+		"GET /api/v1/datasets/{label}/results/{county}":     models.RoleEditor,
+		"GET /api/v1/results/{county}/artifacts/{snapshot}": models.RoleEditor,
+		"GET /api/v1/errors/{id}/source":                    models.RoleIngester,
+
+		// Queries are posted, but only read
+		"POST /graphql": models.RoleViewer,
+		// Who changed what is for admins only
+		"GET /api/v1/audit": models.RoleAdmin,
+		// Source logins are for admins only, PUT and DELETE by default
+		"GET /api/v1/counties/{county}/credentials": models.RoleAdmin,
+		// So are profiles and runtime stats, and the log level
+		"/admin/debug/":       models.RoleAdmin,
+		"GET /admin/loglevel": models.RoleAdmin,
+		// GET /admin checks for the admin role itself, so a browser without
+		// a key is prompted for one rather than refused
+	}
+
+	// Paths polled all day, such as health checks, have only one in
+	// ACCESS_LOG_SAMPLE of their successful requests logged, none if 0
+	quiet := &accessSampling{
+		paths: getEnvList("ACCESS_LOG_QUIET_PATHS"),
+		every: uint64(max(getEnvInt("ACCESS_LOG_SAMPLE", 100), 0)),
+	}
+	if len(quiet.paths) == 0 {
+		quiet.paths = []string{"/health", "/api/v1/capacity"}
+	}
+
 	// Create server with timeouts
 	server := &http.Server{
 		Addr:         ":" + config.port,
-		Handler:      requestLogger(mux, config.logger),
+		Handler:      requestid.Middleware(requestLogger(deployment.Middleware(resultStore, auth.Middleware(apiKeys, noteCaller(audit.Middleware(auditLog, config.logger, withGRPC(grpcServer, auth.Authorize(routeRoles, mux)), "POST /graphql")))), config.logger, quiet)),
 		ReadTimeout:  defaultReadTimeout,
 		WriteTimeout: defaultWriteTimeout,
 	}
+	// Cleartext HTTP/2 lets gRPC clients connect without TLS
+	server.Protocols = new(http.Protocols)
+	server.Protocols.SetHTTP1(true)
+	server.Protocols.SetUnencryptedHTTP2(true)
+	server.RegisterOnShutdown(grpcServer.Shutdown)
+
+	// DEBUG_ADDR serves the debug endpoints without an API key, so it
+	// should be an address only reachable from inside the deployment, such
+	// as localhost:6060
+	if addr := os.Getenv("DEBUG_ADDR"); addr != "" {
+		config.debugServer = &http.Server{Addr: addr, Handler: debug, ReadTimeout: defaultReadTimeout}
+		go func() {
+			logger.Info("debug endpoints listening", "addr", addr)
+			if err := config.debugServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("debug server failed", "addr", addr, "error", err)
+			}
+		}()
+	}
 
 	// Start server
 	startServer(server, config)
 }
 
-// requestLogger middleware for logging requests
-func requestLogger(handler http.Handler, logger *slog.Logger) http.Handler {
+// requestLogger middleware for logging requests, with their status, the
+// bytes written and the API key that made them
+func requestLogger(handler http.Handler, logger *slog.Logger, quiet *accessSampling) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		
-		handler.ServeHTTP(w, r)
-		
-		logger.Info("request completed",
+		aw := &accessWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(aw, r.WithContext(context.WithValue(r.Context(), accessWriterKey{}, aw)))
+
+		if !quiet.log(r.URL.Path, aw.status) {
+			return
+		}
+		logger.InfoContext(r.Context(), "request completed",
 			"method", r.Method,
 			"path", r.URL.Path,
+			"status", aw.status,
+			"bytes", aw.bytes,
 			"duration", time.Since(start),
 			"remote_addr", r.RemoteAddr,
+			"user_agent", r.UserAgent(),
+			"api_key", aw.keyID,
 		)
 	})
 }
 
+// accessWriter passes a response through while noting what requestLogger
+// logs of it
+type accessWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	keyID  string
+}
+
+type accessWriterKey struct{}
+
+func (w *accessWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, for
+// handlers that stream
+func (w *accessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// noteCaller records the API key auth.Middleware found for the request for
+// requestLogger, which runs outside it so rejected keys are logged too
+func noteCaller(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if aw, ok := r.Context().Value(accessWriterKey{}).(*accessWriter); ok {
+			if p, ok := auth.FromContext(r.Context()); ok && !p.Anonymous {
+				aw.keyID = p.Key.ID
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// accessSampling thins the access log of paths requested often and
+// uninterestingly: of their successful requests only one in every is
+// logged, none if every is 0. Failures are always logged
+type accessSampling struct {
+	paths []string
+	every uint64
+	seen  atomic.Uint64
+}
+
+func (s *accessSampling) log(path string, status int) bool {
+	if status >= http.StatusBadRequest || !slices.Contains(s.paths, path) {
+		return true
+	}
+	if s.every == 0 {
+		return false
+	}
+	return (s.seen.Add(1)-1)%s.every == 0
+}
+
+// debugEndpoints serves pprof's profiles under /debug/pprof/ and expvar's
+// variables at /debug/vars, with the build, the goroutine count, the
+// worker pool's stats and the store's version among them
+func debugEndpoints(version models.VersionInfo, st *store.Memory, workers *workerpool.Pool) *http.ServeMux {
+	expvar.Publish("build", expvar.Func(func() any { return version.Build }))
+	expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+	expvar.Publish("workers", expvar.Func(func() any { return workers.Stats() }))
+	expvar.Publish("store_version", expvar.Func(func() any { return st.Version() }))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// withGRPC sends gRPC calls to g and every other request to next
+func withGRPC(g *grpcapi.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if grpcapi.IsGRPC(r) {
+			g.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func startServer(server *http.Server, config *ServerConfig) {
 	serverErrors := make(chan error, 1)
 	shutdown := make(chan os.Signal, 1)
@@ -176,19 +839,43 @@ func startServer(server *http.Server, config *ServerConfig) {
 		config.logger.Info("shutdown signal received", "signal", sig)
 	}
 
-	gracefulShutdown(server, config.logger)
+	gracefulShutdown(server, config)
 }
 
-func gracefulShutdown(server *http.Server, logger *slog.Logger) {
+func gracefulShutdown(server *http.Server, config *ServerConfig) {
+	logger := config.logger
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
+	// Stop scheduling new refreshes before draining the HTTP side
+	config.stopScheduler()
+
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("server shutdown failed", "error", err)
 		if err := server.Close(); err != nil {
 			logger.Error("server close failed", "error", err)
 		}
 	}
+	// A profile being taken isn't worth waiting for
+	if config.debugServer != nil {
+		config.debugServer.Close()
+	}
+
+	// Let running jobs finish while there is time, keeping the rest for
+	// the next start
+	if config.jobs != nil {
+		n, err := config.jobs.Drain(ctx, config.jobsFile)
+		if err != nil {
+			logger.Error("jobs did not drain", "error", err)
+		}
+		if n > 0 {
+			logger.Info("saved unfinished jobs", "jobs", n, "path", config.jobsFile)
+		}
+	}
+
+	if err := config.workers.Stop(ctx); err != nil {
+		logger.Error("worker pool did not drain", "error", err)
+	}
 
 	logger.Info("server stopped")
 }
@@ -198,8 +885,9 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Update CORS to allow Vue.js dev server
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5174")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, "+requestid.Header)
+		w.Header().Set("Access-Control-Expose-Headers", requestid.Header+", X-Snapshot-Hash, ETag, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, "+region.HeaderRegion+", "+region.HeaderInstance+", "+region.HeaderVersion)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
@@ -212,10 +900,83 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func healthCheck(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status":"healthy"}`))
+// healthCheck reports "degraded" while the host clock is skewed; the server
+// keeps serving, so the status code stays 200.
+func healthCheck(tc *clock.Trusted, id region.Identity) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Status   string        `json:"status"`
+			Region   string        `json:"region,omitempty"`
+			Instance string        `json:"instance"`
+			Clock    *clock.Status `json:"clock,omitempty"`
+		}{Status: "healthy", Region: id.Region, Instance: id.Instance}
+		if tc != nil {
+			s := tc.Status()
+			body.Clock = &s
+			if s.Skewed {
+				body.Status = "degraded"
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// discoveryDocument describes this deployment for /.well-known/era.json.
+func discoveryDocument(id region.Identity) models.Discovery {
+	return models.Discovery{
+		Service:  "era",
+		Region:   id.Region,
+		Instance: id.Instance,
+		Versions: []models.APIVersion{{Version: "v1", Path: "/api/v1", Status: "stable"}},
+		Endpoints: map[string]string{
+			"process":    "/api/v1/process",
+			"job":        "/api/v1/jobs/{id}",
+			"results":    "/api/v1/results/{county}",
+			"export":     "/api/v1/results/{county}/export?format={format}",
+			"artifacts":  "/api/v1/results/{county}/artifacts",
+			"aggregate":  "/api/v1/aggregate?contest={contest}",
+			"geojson":    "/api/v1/results/{contest}/geojson",
+			"districts":  "/api/v1/districts/{id}/results",
+			"turnout":    "/api/v1/turnout",
+			"search":     "/api/v1/search?q={query}",
+			"elections":  "/api/v1/elections",
+			"counties":   "/api/v1/counties",
+			"manifest":   "/api/v1/manifest",
+			"quarantine": "/api/v1/quarantine",
+			"datasets":   "/api/v1/datasets",
+			"events":     "/api/v1/events",
+			"layout":     "/api/v1/layout-changes",
+			"errors":     "/api/v1/errors",
+			"alerts":     "/api/v1/alerts",
+			"slas":       "/api/v1/slas",
+			"latency":    "/api/v1/latency",
+			"anomalies":  "/api/v1/anomalies",
+			"snippets":   "/api/v1/snippets",
+			"parsers":    "/api/v1/parsers",
+			"capacity":   "/api/v1/capacity",
+			"lite":       "/lite/{county}",
+			"graphql":    "/graphql",
+			"openapi":    "/api/v1/openapi.json",
+			"docs":       "/api/v1/docs",
+			"grpc":       grpcapi.ServicePath,
+			"health":     "/health",
+			"version":    "/version",
+		},
+		Streams: []models.DiscoveryStream{
+			{Name: "broadcast", Path: "/api/v1/broadcast/{county}", Format: "text/plain", RefreshSeconds: int(refreshInterval.Seconds())},
+			{Name: "dump", Path: "/api/v1/dump.ndjson.gz", Format: "application/x-ndjson+gzip", RefreshSeconds: int(refreshInterval.Seconds())},
+			{Name: "dump-manifest", Path: "/api/v1/dump/manifest", Format: "application/json", RefreshSeconds: int(refreshInterval.Seconds())},
+			{Name: "grpc-updates", Path: grpcapi.ServicePath + "StreamUpdates", Format: "application/grpc"},
+		},
+		Auth: models.DiscoveryAuth{Headers: []string{"Authorization: Bearer", "X-API-Key", "Authorization: Basic"}},
+		Limits: models.DiscoveryLimits{
+			ConcurrentJobs:     maxConcurrentJobs,
+			MaxRequestSeconds:  int(defaultWriteTimeout.Seconds()),
+			MaxAsyncJobSeconds: int(jobTimeout.Seconds()),
+		},
+	}
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -225,53 +986,43 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
-// handleProcess is a temporary placeholder for the process handler
-func handleProcess(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Parse the request body
-	var req struct {
-		CountyName  string `json:"countyName"`
-		FileLink    string `json:"fileLink"`
-		ContentType string `json:"contentType"` // "candidate" or "measure"
-		ParseMethod string `json:"parseMethod"` // "zip", "html", "pdf", "xml"
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
 	}
+	return defaultValue
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
 	}
+	return defaultValue
+}
 
-	// For now, return a sample response
-	response := map[string]interface{}{
-		"html": fmt.Sprintf(`
-			<div class="election-results">
-				<h2>%s Results</h2>
-				<div class="results-container">
-					<p>Processing %s data from %s using %s parser</p>
-				</div>
-			</div>
-		`, req.CountyName, req.ContentType, req.FileLink, req.ParseMethod),
-		"error": nil,
+// getEnvList splits a comma-separated variable, dropping empty entries
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return list
 }
 
-func printStartupInstructions() {
-	fmt.Println("\nTo run with XCode tools bypass, use one of these commands:")
-	fmt.Println("\n1. For development:")
-	fmt.Println("   CGO_ENABLED=1 go run cmd/server/main.go")
-	fmt.Println("\n2. For building:")
-	fmt.Println("   CGO_ENABLED=1 go build -o era_api cmd/server/main.go")
-	fmt.Println("\n3. For production:")
-	fmt.Println("   CGO_ENABLED=1 GOOS=darwin go build -o era_api cmd/server/main.go")
-	fmt.Println("\nEnvironment variables:")
-	fmt.Println("- PORT: Server port (default: 8080)")
-	fmt.Println("- LOG_LEVEL: Logging level (default: info)")
-	fmt.Println()
+// enabledFeatures lists the features that are on, sorted
+func enabledFeatures(on map[string]bool) []string {
+	features := []string{}
+	for name, enabled := range on {
+		if enabled {
+			features = append(features, name)
+		}
+	}
+	sort.Strings(features)
+	return features
 }
@@ -0,0 +1,261 @@
+//go:build !cgo
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/alert"
+	"github.com/many221/era_api_v1/internal/anomaly"
+	"github.com/many221/era_api_v1/internal/archive"
+	"github.com/many221/era_api_v1/internal/browser"
+	"github.com/many221/era_api_v1/internal/clock"
+	"github.com/many221/era_api_v1/internal/contestrules"
+	"github.com/many221/era_api_v1/internal/drift"
+	"github.com/many221/era_api_v1/internal/events"
+	"github.com/many221/era_api_v1/internal/fetcher"
+	"github.com/many221/era_api_v1/internal/manifest"
+	"github.com/many221/era_api_v1/internal/migrate"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/sigv4"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/templates"
+	"github.com/many221/era_api_v1/internal/urlpolicy"
+)
+
+// pipeline is the store and processing pipeline the server publishes
+// through, as the environment configures them. The process and export
+// commands run on the same one, so what they publish and read is what the
+// server would
+type pipeline struct {
+	templates    *templates.Registry
+	store        *store.Memory
+	eventLog     *events.File
+	fetcher      *fetcher.Fetcher
+	processor    *processor.Processor
+	archive      *archive.Archive
+	clock        *clock.Trusted
+	notifier     *alert.Notifier
+	contestRules *contestrules.Engine
+	matcher      *normalize.Matcher
+}
+
+// newPipeline builds the pipeline publishing for election by default,
+// exiting if the environment configures it wrongly. Close the event log,
+// if there is one, when done
+func newPipeline(logger *slog.Logger, election string) *pipeline {
+	p := &pipeline{}
+
+	// Load HTML templates; edits on disk or through the API apply without a restart
+	templateRegistry, err := templates.Open(getEnvOrDefault("TEMPLATE_DIR", templateDir), logger)
+	if err != nil {
+		logger.Error("failed to load templates", "error", err)
+		os.Exit(1)
+	}
+
+	// Published snapshots are recorded as events, in a file when EVENT_LOG
+	// is set so results survive a restart
+	resultStore := store.New()
+	resultStore.SetDefaultElection(election)
+	if path := os.Getenv("EVENT_LOG"); path != "" {
+		// A log written by an older binary is upgraded first, unless
+		// EVENT_LOG_MIGRATE=off leaves that to era migrate
+		if os.Getenv("EVENT_LOG_MIGRATE") != "off" {
+			report, err := migrate.Run(path, migrate.Config{DefaultElection: election})
+			if err != nil {
+				logger.Error("failed to migrate event log", "path", path, "error", err)
+				os.Exit(1)
+			}
+			if len(report.Applied) > 0 {
+				logger.Info("migrated event log", "path", path, "from", report.From, "to", report.To, "migrations", report.Applied, "events", report.Events, "backup", report.Backup)
+			}
+		}
+		eventLog, err := events.OpenFile(path)
+		if err != nil {
+			logger.Error("failed to open event log", "path", path, "error", err)
+			os.Exit(1)
+		}
+		p.eventLog = eventLog
+		if resultStore, err = store.Open(eventLog, election); err != nil {
+			logger.Error("failed to replay event log", "path", path, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("replayed event log", "path", path, "events", eventLog.Last(), "elections", len(resultStore.Elections()), "counties", len(resultStore.Counties()))
+	}
+	// File links come from callers, so only fetch what the deployment allows:
+	// https to public addresses unless configured otherwise
+	fetchPolicy, err := urlpolicy.New(fetchPolicyConfig())
+	if err != nil {
+		logger.Error("invalid fetch policy", "error", err)
+		os.Exit(1)
+	}
+	sourceFetcher := fetcher.New()
+	sourceFetcher.SetPolicy(fetchPolicy)
+	// s3:// and gs:// file links, once FETCH_SCHEMES allows them, are read
+	// with the AWS keys the archive uses and Cloud Storage HMAC keys; a
+	// county's stored credentials take their place
+	sourceFetcher.SetBucket(fetcher.SchemeS3, fetcher.BucketConfig{
+		Endpoint: os.Getenv("AWS_ENDPOINT_URL_S3"),
+		Region:   os.Getenv("AWS_REGION"),
+		Credentials: sigv4.Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+	})
+	sourceFetcher.SetBucket(fetcher.SchemeGCS, fetcher.BucketConfig{
+		Endpoint: os.Getenv("GCS_ENDPOINT_URL"),
+		Credentials: sigv4.Credentials{
+			AccessKeyID:     os.Getenv("GCS_HMAC_ACCESS_ID"),
+			SecretAccessKey: os.Getenv("GCS_HMAC_SECRET"),
+		},
+	})
+	proc := processor.New(sourceFetcher, resultStore, logger)
+	proc.SetTemplates(templateRegistry)
+	// Sources are archived to a directory or an S3 bucket; SOURCE_ARCHIVE_DIR
+	// is still read under its older name
+	var sourceArchive *archive.Archive
+	if location := getEnvOrDefault("SOURCE_ARCHIVE", os.Getenv("SOURCE_ARCHIVE_DIR")); location != "" {
+		sourceArchive, err = archive.Open(location)
+		if err != nil {
+			logger.Error("invalid SOURCE_ARCHIVE", "error", err)
+			os.Exit(1)
+		}
+		proc.SetArchive(sourceArchive)
+		logger.Info("archiving sources", "location", sourceArchive.String())
+	}
+
+	// Publish times come from NTP-corrected time; a skewed host clock is
+	// logged on every check
+	var trustedClock *clock.Trusted
+	if server := getEnvOrDefault("NTP_SERVER", defaultNTPServer); server != "off" {
+		trustedClock = clock.NewTrusted(server, time.Duration(getEnvInt("MAX_CLOCK_SKEW_MS", defaultMaxSkewMillis))*time.Millisecond, logger)
+		proc.SetClock(trustedClock)
+	}
+	// Snapshots whose totals jump wildly are held for review with layout
+	// drift suggestions rather than published
+	if os.Getenv("QUARANTINE") != "off" {
+		policy := drift.DefaultPolicy
+		policy.MaxDrop = float64(getEnvInt("DRIFT_MAX_DROP_PERCENT", int(policy.MaxDrop*100))) / 100
+		policy.MaxGrowth = float64(getEnvInt("DRIFT_MAX_GROWTH_FACTOR", int(policy.MaxGrowth)))
+		policy.MinVotes = getEnvInt("DRIFT_MIN_VOTES", policy.MinVotes)
+		proc.SetDriftPolicy(policy)
+	}
+	// Snapshots are checked for totals going backwards, more votes than
+	// ballots and wild swings, flagged in the results, logs and /api/v1/anomalies
+	if os.Getenv("ANOMALY_CHECKS") != "off" {
+		detector := anomaly.DefaultDetector
+		detector.SwingPoints = float64(getEnvInt("ANOMALY_SWING_POINTS", int(detector.SwingPoints)))
+		detector.MinVotes = getEnvInt("ANOMALY_MIN_VOTES", detector.MinVotes)
+		proc.SetAnomalyDetector(detector)
+	}
+	// Contests are called from the count once enough precincts report and
+	// the lead is wide enough; results carry shares, leader and margin
+	outcomeRules := models.DefaultOutcomeRules
+	outcomeRules.MinReportingPercent = getEnvFloat("CALL_MIN_REPORTING_PERCENT", outcomeRules.MinReportingPercent)
+	outcomeRules.MinMarginPoints = getEnvFloat("CALL_MIN_MARGIN_POINTS", outcomeRules.MinMarginPoints)
+	resultStore.SetOutcomeRules(outcomeRules)
+	// Where several sources report a county, the highest listed level that
+	// has published is kept; the others are compared at /api/v1/conflicts
+	if levels := getEnvList("SOURCE_PRECEDENCE"); len(levels) > 0 {
+		proc.SetPrecedence(levels)
+	}
+	// Pages built with JavaScript are read in a headless Chrome started
+	// apart with --remote-debugging-port; each render holds a tab, so only
+	// a few run at once
+	if endpoint := os.Getenv("BROWSER_URL"); endpoint != "" {
+		b, err := browser.New(browser.Config{
+			Endpoint: endpoint,
+			Tabs:     getEnvInt("BROWSER_TABS", browser.DefaultTabs),
+			Settle:   time.Duration(getEnvInt("BROWSER_SETTLE_MS", 2000)) * time.Millisecond,
+			Policy:   fetchPolicy,
+		})
+		if err != nil {
+			logger.Error("invalid browser config", "error", err)
+			os.Exit(1)
+		}
+		proc.SetBrowser(b)
+		logger.Info("rendering pages with headless browser", "endpoint", b.String())
+	}
+	// A parse broken by a county's layout change can be retried with the
+	// parser config that last worked, published with a warning
+	// Alerts go out with their runbooks and the county's contact to Slack
+	// and PagerDuty when configured, and are kept for /api/v1/alerts
+	notifier := alert.NewNotifier(resultStore, logger, time.Duration(getEnvInt("ALERT_COOLDOWN_SECONDS", defaultAlertCooldown))*time.Second)
+	if url := os.Getenv("SLACK_WEBHOOK_URL"); url != "" {
+		notifier.AddSink(alert.NewSlack(url))
+	}
+	if key := os.Getenv("PAGERDUTY_ROUTING_KEY"); key != "" {
+		notifier.AddSink(alert.NewPagerDuty(key))
+	}
+	proc.SetAlerter(notifier)
+	if os.Getenv("PARSER_FALLBACK") == "on" {
+		proc.SetParserFallback(true)
+		logger.Info("parser config fallback enabled")
+	}
+	if name := os.Getenv("DATA_LICENSE"); name != "" {
+		proc.SetDefaultLicense(&models.License{
+			Name:           name,
+			Attribution:    os.Getenv("DATA_ATTRIBUTION"),
+			URL:            os.Getenv("DATA_LICENSE_URL"),
+			Redistribution: getEnvOrDefault("DATA_REDISTRIBUTION", models.RedistributionAttribution),
+		})
+	}
+
+	if path := os.Getenv("CONTEST_RULES"); path != "" {
+		n, err := contestrules.LoadRules(path, resultStore, election)
+		if err != nil {
+			logger.Error("failed to load contest rules", "path", path, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("loaded contest rules", "path", path, "rules", n)
+	}
+	contestRules := contestrules.NewEngine(resultStore, election)
+	proc.AddTransform(contestRules.Apply)
+
+	if path := os.Getenv("CANDIDATE_REGISTRY"); path != "" {
+		n, err := normalize.LoadRegistry(path, resultStore)
+		if err != nil {
+			logger.Error("failed to load candidate registry", "path", path, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("loaded candidate registry", "path", path, "candidates", n)
+	}
+	matcher := normalize.NewMatcher(resultStore, normalize.DefaultThreshold)
+	proc.AddTransform(matcher.Apply)
+
+	// The expected ballot, from a CSV or JSON file or URL, flags snapshots
+	// with missing or unexpected contests; it can be replaced through the API
+	if location := os.Getenv("ELECTION_MANIFEST"); location != "" {
+		m, err := manifest.Load(context.Background(), sourceFetcher, location, election)
+		if err != nil {
+			logger.Error("failed to load election manifest", "location", location, "error", err)
+			os.Exit(1)
+		}
+		m.ImportedAt = time.Now().UTC()
+		resultStore.SaveManifest(m)
+		logger.Info("loaded election manifest", "location", location, "contests", len(m.Contests))
+	}
+	proc.SetManifest(manifest.NewChecker(resultStore, election))
+
+	p.templates, p.store, p.fetcher, p.processor = templateRegistry, resultStore, sourceFetcher, proc
+	p.archive, p.clock, p.notifier = sourceArchive, trustedClock, notifier
+	p.contestRules, p.matcher = contestRules, matcher
+	return p
+}
+
+// fetchPolicyConfig is the fetch policy FETCH_SCHEMES, FETCH_ALLOW_HOSTS,
+// FETCH_DENY_HOSTS and FETCH_ALLOW_PRIVATE configure
+func fetchPolicyConfig() urlpolicy.Config {
+	return urlpolicy.Config{
+		Schemes:      getEnvList("FETCH_SCHEMES"),
+		Allow:        getEnvList("FETCH_ALLOW_HOSTS"),
+		Deny:         getEnvList("FETCH_DENY_HOSTS"),
+		AllowPrivate: os.Getenv("FETCH_ALLOW_PRIVATE") == "on",
+	}
+}
@@ -0,0 +1,134 @@
+//go:build !cgo
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// processCommand is era-server process: a county's source parsed and
+// published as POST /api/v1/process or /api/v1/upload would, printing the
+// response.
+// The county's registration, if it has one, supplies what the flags don't
+func processCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("process", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	file := fs.String("file", "", "source file to publish (default fetch the county's registered fileLink)")
+	county := fs.String("county", "", "county the results are for")
+	election := fs.String("election", "", "election the results are for (default ELECTION_ID)")
+	method := fs.String("method", "", "parse method, or auto to detect it (default the county's, else auto)")
+	contentType := fs.String("content-type", "", "candidate or measure (default the county's, else candidate)")
+	threshold := fs.String("measure-threshold", "", "measure threshold, e.g. two-thirds")
+	dryRun := fs.Bool("dry-run", false, "parse and check the source but publish nothing")
+	debug := fs.Bool("debug", false, "include the parser's trace in the response")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "era-server process: unexpected argument %q\n", fs.Arg(0))
+		return 2
+	}
+	if *county == "" {
+		fmt.Fprintln(stderr, "era-server process: --county is required")
+		return 2
+	}
+	logger, err := commandLogger(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "era-server process: %v\n", err)
+		return 2
+	}
+	if os.Getenv("EVENT_LOG") == "" && !*dryRun {
+		logger.Warn("EVENT_LOG isn't set, so the published results aren't kept")
+	}
+
+	var upload []byte
+	if *file != "" {
+		if upload, err = os.ReadFile(*file); err != nil {
+			fmt.Fprintf(stderr, "era-server process: %v\n", err)
+			return 1
+		}
+	}
+
+	pipe := newPipeline(logger, getEnvOrDefault("ELECTION_ID", defaultElection))
+	if pipe.eventLog != nil {
+		defer pipe.eventLog.Close()
+	}
+
+	req := models.ProcessRequest{CountyName: *county, Election: *election}
+	if c, err := pipe.store.ElectionCounty(*election, *county); err == nil {
+		req = c.ProcessRequest()
+		req.Browser = nil
+	}
+	if upload != nil {
+		// Results name the file they came from where a link would be
+		req.FileLink = "file:" + filepath.Base(*file)
+		if *method == "" && req.ParseMethod == "" {
+			req.ParseMethod = parser.MethodOf(*file)
+		}
+	}
+	if *method != "" {
+		req.ParseMethod = *method
+	}
+	if req.ParseMethod == "" {
+		req.ParseMethod = models.ParseMethodAuto
+	}
+	if *contentType != "" {
+		req.ContentType = *contentType
+	}
+	if req.ContentType == "" {
+		req.ContentType = models.ContentTypeCandidate
+	}
+	if *threshold != "" {
+		req.MeasureThreshold = *threshold
+	}
+	req.Debug, req.DryRun = *debug, *dryRun
+
+	if upload != nil {
+		err = validate.Upload(req, int64(len(upload)))
+	} else {
+		err = validate.ProcessRequest(req)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "era-server process: %v\n", err)
+		return 2
+	}
+
+	ctx := context.Background()
+	var trace *parser.Trace
+	if req.Debug {
+		trace = parser.NewTrace()
+		ctx = parser.WithTrace(ctx, trace)
+	}
+	var resp *models.ProcessResponse
+	if upload != nil {
+		resp, err = pipe.processor.ProcessUpload(ctx, req, upload, nil)
+	} else {
+		resp, err = pipe.processor.Process(ctx, req, nil)
+	}
+	status := 0
+	if err != nil {
+		msg := err.Error()
+		resp = &models.ProcessResponse{Error: &msg}
+		fmt.Fprintf(stderr, "era-server process: %s: %v\n", req.CountyName, err)
+		status = 1
+	}
+	resp.Trace = trace.Entries()
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(resp); err != nil {
+		fmt.Fprintf(stderr, "era-server process: write response: %v\n", err)
+		return 1
+	}
+	return status
+}
@@ -0,0 +1,259 @@
+//go:build !cgo
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/archive"
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/browser"
+	"github.com/many221/era_api_v1/internal/contestrules"
+	"github.com/many221/era_api_v1/internal/embargo"
+	"github.com/many221/era_api_v1/internal/fetcher"
+	"github.com/many221/era_api_v1/internal/handlers"
+	"github.com/many221/era_api_v1/internal/manifest"
+	"github.com/many221/era_api_v1/internal/migrate"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/redis"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/urlpolicy"
+	"github.com/many221/era_api_v1/internal/visibility"
+)
+
+// numericSettings are read as numbers; one that doesn't parse is ignored
+// by the server in favour of its default, which is worth knowing before
+// it happens
+var numericSettings = []string{
+	"PORT",
+	"ACCESS_LOG_SAMPLE",
+	"ALERT_COOLDOWN_SECONDS",
+	"ANOMALY_MIN_VOTES",
+	"ANOMALY_SWING_POINTS",
+	"BROWSER_SETTLE_MS",
+	"BROWSER_TABS",
+	"CACHE_MAX_AGE",
+	"CACHE_S_MAXAGE",
+	"CALL_MIN_MARGIN_POINTS",
+	"CALL_MIN_REPORTING_PERCENT",
+	"DEMO_INTERVAL_SECONDS",
+	"DRIFT_MAX_DROP_PERCENT",
+	"DRIFT_MAX_GROWTH_FACTOR",
+	"DRIFT_MIN_VOTES",
+	"DUMP_PART_BYTES",
+	"IMAP_POLL_SECONDS",
+	"MAX_CLOCK_SKEW_MS",
+	"REFRESH_PER_SOURCE_LIMIT",
+	"REFRESH_QUEUE_SIZE",
+	"REFRESH_WORKERS",
+}
+
+// validateConfig is era-server validate-config: the settings serve reads
+// from the environment checked, and the files they name loaded, as it
+// would on start but without opening, migrating or writing anything, so a
+// deploy can be checked before it goes out. Each check is reported; the status
+// is 1 if any failed
+func validateConfig(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	quiet := fs.Bool("quiet", false, "only report failed checks")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "era-server validate-config: unexpected argument %q\n", fs.Arg(0))
+		return 2
+	}
+
+	c := &configCheck{out: stdout, quiet: *quiet}
+	election := getEnvOrDefault("ELECTION_ID", defaultElection)
+
+	c.check("LOG_LEVEL", func() (string, error) {
+		level, err := handlers.ParseLogLevel(getEnvOrDefault("LOG_LEVEL", "info"))
+		return level.String(), err
+	})
+	c.check("LOG_FORMAT", func() (string, error) {
+		switch format := strings.ToLower(getEnvOrDefault("LOG_FORMAT", "json")); format {
+		case "json", "text":
+			return format, nil
+		}
+		return "", fmt.Errorf("%q isn't json or text", os.Getenv("LOG_FORMAT"))
+	})
+	for _, name := range numericSettings {
+		if value := os.Getenv(name); value != "" {
+			c.check(name, func() (string, error) {
+				if _, err := strconv.ParseFloat(value, 64); err != nil {
+					return "", fmt.Errorf("%q isn't a number; the default would be used", value)
+				}
+				return value, nil
+			})
+		}
+	}
+
+	if path := os.Getenv("EVENT_LOG"); path != "" {
+		c.check("EVENT_LOG", func() (string, error) {
+			s, err := migrate.Check(path)
+			switch {
+			case err != nil:
+				return "", err
+			case s.Version > s.Latest:
+				return "", fmt.Errorf("%s is schema version %d, newer than this build's %d", path, s.Version, s.Latest)
+			case len(s.Pending) > 0 && os.Getenv("EVENT_LOG_MIGRATE") == "off":
+				return "", fmt.Errorf("%s needs migrating (%s) and EVENT_LOG_MIGRATE=off; run era migrate", path, strings.Join(s.Pending, ", "))
+			case len(s.Pending) > 0:
+				return fmt.Sprintf("%s, migrated on start (%s)", path, strings.Join(s.Pending, ", ")), nil
+			}
+			return fmt.Sprintf("%s, schema version %d", path, s.Version), nil
+		})
+	}
+	var fetchPolicy *urlpolicy.Policy
+	c.check("FETCH_*", func() (string, error) {
+		var err error
+		fetchPolicy, err = urlpolicy.New(fetchPolicyConfig())
+		return "fetch policy", err
+	})
+	if location := getEnvOrDefault("SOURCE_ARCHIVE", os.Getenv("SOURCE_ARCHIVE_DIR")); location != "" {
+		c.check("SOURCE_ARCHIVE", func() (string, error) {
+			a, err := archive.Open(location)
+			if err != nil {
+				return "", err
+			}
+			return a.String(), nil
+		})
+	}
+	if endpoint := os.Getenv("BROWSER_URL"); endpoint != "" {
+		c.check("BROWSER_URL", func() (string, error) {
+			b, err := browser.New(browser.Config{Endpoint: endpoint})
+			if err != nil {
+				return "", err
+			}
+			return b.String(), nil
+		})
+	}
+
+	// Rules and registries are loaded into a store of their own
+	scratch := store.New()
+	scratch.SetDefaultElection(election)
+	if path := os.Getenv("CONTEST_RULES"); path != "" {
+		c.check("CONTEST_RULES", func() (string, error) {
+			n, err := contestrules.LoadRules(path, scratch, election)
+			return fmt.Sprintf("%s, %d rules", path, n), err
+		})
+	}
+	if path := os.Getenv("CANDIDATE_REGISTRY"); path != "" {
+		c.check("CANDIDATE_REGISTRY", func() (string, error) {
+			n, err := normalize.LoadRegistry(path, scratch)
+			return fmt.Sprintf("%s, %d candidates", path, n), err
+		})
+	}
+	if location := os.Getenv("ELECTION_MANIFEST"); location != "" && fetchPolicy != nil {
+		c.check("ELECTION_MANIFEST", func() (string, error) {
+			f := fetcher.New()
+			f.SetPolicy(fetchPolicy)
+			m, err := manifest.Load(context.Background(), f, location, election)
+			return fmt.Sprintf("%s, %d contests", location, len(m.Contests)), err
+		})
+	}
+	if url := os.Getenv("REDIS_URL"); url != "" {
+		c.check("REDIS_URL", func() (string, error) {
+			_, err := redis.Open(url)
+			return "parsed", err
+		})
+	}
+
+	profiles := visibility.DefaultProfiles()
+	if path := os.Getenv("RESPONSE_PROFILES"); path != "" {
+		c.check("RESPONSE_PROFILES", func() (string, error) {
+			p, err := visibility.LoadProfiles(path)
+			if err == nil {
+				profiles = p
+			}
+			return path, err
+		})
+	}
+	var tiers *embargo.Tiers
+	if path := os.Getenv("ACCESS_TIERS_FILE"); path != "" {
+		c.check("ACCESS_TIERS_FILE", func() (string, error) {
+			var err error
+			tiers, err = embargo.LoadTiers(path)
+			return path, err
+		})
+	}
+	anonymousVisibility := getEnvOrDefault("ANONYMOUS_VISIBILITY", models.VisibilityPublic)
+	anonymousRole := getEnvOrDefault("ANONYMOUS_ROLE", models.RoleViewer)
+	if path := os.Getenv("API_KEYS_FILE"); path != "" {
+		c.check("API_KEYS_FILE", func() (string, error) {
+			_, err := auth.LoadKeys(path, anonymousVisibility, anonymousRole, profiles, tiers)
+			return path, err
+		})
+	} else if os.Getenv("OIDC_ISSUER") != "" {
+		c.check("ANONYMOUS_*", func() (string, error) {
+			_, err := auth.NewKeys(nil, anonymousVisibility, anonymousRole, profiles, tiers)
+			return anonymousVisibility + ", " + anonymousRole, err
+		})
+	}
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		c.check("OIDC_*", func() (string, error) {
+			roleMap := make(map[string]string)
+			for _, pair := range getEnvList("OIDC_ROLE_MAP") {
+				value, role, _ := strings.Cut(pair, "=")
+				roleMap[strings.TrimSpace(value)] = strings.TrimSpace(role)
+			}
+			oidc, err := auth.NewOIDC(auth.OIDCConfig{
+				Issuer:      issuer,
+				Audience:    os.Getenv("OIDC_AUDIENCE"),
+				RoleClaim:   os.Getenv("OIDC_ROLE_CLAIM"),
+				RoleMap:     roleMap,
+				DefaultRole: os.Getenv("OIDC_DEFAULT_ROLE"),
+				Visibility:  os.Getenv("OIDC_VISIBILITY"),
+				Profile:     os.Getenv("OIDC_PROFILE"),
+				Tier:        os.Getenv("OIDC_TIER"),
+			})
+			if err != nil {
+				return issuer, err
+			}
+			keys, err := auth.NewKeys(nil, anonymousVisibility, anonymousRole, profiles, tiers)
+			if err != nil {
+				return issuer, err
+			}
+			return issuer, keys.UseOIDC(oidc)
+		})
+	}
+
+	if c.failed > 0 {
+		fmt.Fprintf(stdout, "%d of %d checks failed\n", c.failed, c.n)
+		return 1
+	}
+	if !c.quiet {
+		fmt.Fprintf(stdout, "configuration ok, %d checks\n", c.n)
+	}
+	return 0
+}
+
+// configCheck reports the checks validateConfig runs
+type configCheck struct {
+	out       io.Writer
+	quiet     bool
+	n, failed int
+}
+
+// check runs fn, which checks the setting name and describes it
+func (c *configCheck) check(name string, fn func() (string, error)) {
+	c.n++
+	detail, err := fn()
+	if err != nil {
+		c.failed++
+		fmt.Fprintf(c.out, "FAIL  %-20s %v\n", name, err)
+		return
+	}
+	if !c.quiet {
+		fmt.Fprintf(c.out, "ok    %-20s %s\n", name, detail)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/many221/era_api_v1
+
+go 1.24
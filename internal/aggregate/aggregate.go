@@ -0,0 +1,164 @@
+package aggregate
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/measures"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/outcome"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Contest rolls up the contest with the given ID across every county with
+// stored results. If the ID is a measure link's, each member county's
+// contest is taken under its own ID, and other counties' under the link's.
+// Measure choices are combined as Yes and No however counties spell them.
+// It returns store.ErrNotFound when no county reports it.
+func Contest(st store.Store, contestID string) (*models.Aggregate, error) {
+	agg := &models.Aggregate{ContestID: contestID}
+	byName := make(map[string]int)
+	link, linkErr := st.MeasureLink("", contestID)
+	if linkErr == nil {
+		agg.Title = link.Title
+	}
+	var threshold string
+	var measure bool
+
+	snap := st.ElectionSnapshot("")
+	agg.Version = snap.Version
+	for _, results := range snap.Results {
+		id := contestID
+		if linkErr == nil {
+			if m, ok := link.Member(results.County); ok {
+				id = m.ContestID
+			}
+		}
+		c, ok := results.ContestByID(id)
+		if !ok {
+			continue
+		}
+
+		if agg.Title == "" {
+			agg.Title = c.Title
+		}
+		if c.Measure != nil && !measure {
+			measure, threshold = true, c.Measure.Threshold
+		}
+		agg.Counties++
+		agg.PrecinctsReporting += c.PrecinctsReporting
+		agg.PrecinctsTotal += c.PrecinctsTotal
+
+		total := c.TotalVotes()
+		agg.TotalVotes += total
+		agg.Breakdown = append(agg.Breakdown, models.CountyBreakdown{
+			County:             results.County,
+			AsOf:               results.ParsedAt,
+			SnapshotHash:       results.Hash,
+			TotalVotes:         total,
+			PrecinctsReporting: c.PrecinctsReporting,
+			PrecinctsTotal:     c.PrecinctsTotal,
+			ReportingPercent:   outcome.Percent(c.PrecinctsReporting, c.PrecinctsTotal),
+			License:            results.License,
+			Candidates:         c.Candidates,
+		})
+		if results.License != nil && !hasLicense(agg.Licenses, *results.License) {
+			agg.Licenses = append(agg.Licenses, *results.License)
+		}
+
+		for _, cand := range c.Candidates {
+			var key string
+			key, cand.Name = mergeKey(c, cand)
+			i, ok := byName[key]
+			if !ok {
+				i = len(agg.Candidates)
+				byName[key] = i
+				agg.Candidates = append(agg.Candidates, models.AggregateCandidate{
+					Name:             cand.Name,
+					Party:            cand.Party,
+					WriteIn:          cand.WriteIn,
+					WriteInAggregate: cand.WriteInAggregate,
+				})
+			}
+			agg.Candidates[i].Votes += cand.Votes
+		}
+	}
+
+	if agg.Counties == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	for i := range agg.Candidates {
+		agg.Candidates[i].Percent = outcome.Percent(agg.Candidates[i].Votes, agg.TotalVotes)
+	}
+	sort.SliceStable(agg.Candidates, func(i, j int) bool {
+		return agg.Candidates[i].Votes > agg.Candidates[j].Votes
+	})
+	agg.ReportingPercent = outcome.Percent(agg.PrecinctsReporting, agg.PrecinctsTotal)
+	if measure {
+		agg.Measure, _ = measures.Compute(combined(agg), threshold)
+	}
+	agg.Outcome = outcome.Compute(combined(agg), st.OutcomeRules())
+	if call, err := st.RaceCall("", contestID); err == nil {
+		agg.Outcome.Call = &call.RaceCall
+	}
+	return agg, nil
+}
+
+// combined returns the aggregate as one contest, so its outcome is derived
+// as a county's would be.
+func combined(agg *models.Aggregate) models.Contest {
+	c := models.Contest{
+		ID:                 agg.ContestID,
+		Title:              agg.Title,
+		PrecinctsReporting: agg.PrecinctsReporting,
+		PrecinctsTotal:     agg.PrecinctsTotal,
+		Measure:            agg.Measure,
+	}
+	for _, cand := range agg.Candidates {
+		c.Candidates = append(c.Candidates, models.Candidate{
+			Name:             cand.Name,
+			Party:            cand.Party,
+			Votes:            cand.Votes,
+			WriteIn:          cand.WriteIn,
+			WriteInAggregate: cand.WriteInAggregate,
+		})
+	}
+	return c
+}
+
+// choiceNames are what measure choices are combined as.
+var choiceNames = map[string]string{"yes": "Yes", "no": "No"}
+
+// mergeKey returns what cand, of contest c, is combined with other
+// counties' candidates by, and the name it is combined under: a measure's
+// choices are combined as Yes and No however counties spell them.
+func mergeKey(c *models.Contest, cand models.Candidate) (key, name string) {
+	if c.Measure != nil {
+		if choice := measures.Choice(cand.Name); choice != "" {
+			return "choice:" + choice, choiceNames[choice]
+		}
+	}
+	return candidateKey(cand), cand.Name
+}
+
+// candidateKey matches candidates across counties, preferring the registry
+// link and falling back to the normalized spelling.
+func candidateKey(c models.Candidate) string {
+	if c.WriteInAggregate {
+		return "write-in"
+	}
+	if c.CanonicalID != "" {
+		return "id:" + c.CanonicalID
+	}
+	return "name:" + normalize.Key(c.Name)
+}
+
+func hasLicense(list []models.License, l models.License) bool {
+	for _, have := range list {
+		if have == l {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package aggregate
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/outcome"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// District rolls up election's published results into d's: each contest
+// with votes in its precincts, summed over them, with candidates combined
+// across counties as Contest combines them. Precincts are matched by name
+// within their county, regardless of case. The outcome has the shares,
+// leader and margin but no call, since it is rolled up from precincts
+// without their reporting status.
+func District(st store.Store, election string, d models.District) *models.DistrictResults {
+	res := &models.DistrictResults{
+		ID:        d.ID,
+		Name:      d.Name,
+		Type:      d.Type,
+		Election:  election,
+		Contests:  []models.DistrictContest{},
+		Precincts: len(d.Precincts),
+	}
+	in := make(map[string]map[string]bool) // precinct keys by county key
+	for _, p := range d.Precincts {
+		county := store.CountyKey(p.County)
+		if in[county] == nil {
+			in[county] = make(map[string]bool)
+		}
+		in[county][precinctKey(p.Precinct)] = true
+	}
+
+	type tally struct {
+		contest   models.DistrictContest
+		byName    map[string]int
+		counties  map[string]bool
+		precincts map[string]bool
+	}
+	var tallies []*tally
+	byID := make(map[string]*tally)
+	matched := make(map[string]bool) // county and precinct keys
+
+	snap := st.ElectionSnapshot(election)
+	res.Version = snap.Version
+	for _, results := range snap.Results {
+		county := store.CountyKey(results.County)
+		precincts := in[county]
+		if precincts == nil {
+			continue
+		}
+		for i := range results.Contests {
+			c := &results.Contests[i]
+			var t *tally
+			for _, cand := range c.Candidates {
+				votes, found := 0, false
+				for _, pv := range cand.Precincts {
+					key := precinctKey(pv.Name)
+					if !precincts[key] {
+						continue
+					}
+					votes, found = votes+pv.Votes, true
+					if t == nil {
+						if t = byID[c.ID]; t == nil {
+							t = &tally{
+								contest:   models.DistrictContest{ID: c.ID, Title: c.Title},
+								byName:    make(map[string]int),
+								counties:  make(map[string]bool),
+								precincts: make(map[string]bool),
+							}
+							byID[c.ID] = t
+							tallies = append(tallies, t)
+						}
+					}
+					t.counties[county] = true
+					t.precincts[county+"/"+key] = true
+					matched[county+"/"+key] = true
+				}
+				if !found {
+					continue
+				}
+				key, name := mergeKey(c, cand)
+				j, ok := t.byName[key]
+				if !ok {
+					j = len(t.contest.Candidates)
+					t.byName[key] = j
+					t.contest.Candidates = append(t.contest.Candidates, models.AggregateCandidate{
+						Name:             name,
+						Party:            cand.Party,
+						WriteIn:          cand.WriteIn,
+						WriteInAggregate: cand.WriteInAggregate,
+					})
+				}
+				t.contest.Candidates[j].Votes += votes
+				t.contest.TotalVotes += votes
+			}
+		}
+	}
+
+	rules := st.OutcomeRules()
+	for _, t := range tallies {
+		c := &t.contest
+		c.Counties, c.Precincts = len(t.counties), len(t.precincts)
+		for i := range c.Candidates {
+			c.Candidates[i].Percent = outcome.Percent(c.Candidates[i].Votes, c.TotalVotes)
+		}
+		sort.SliceStable(c.Candidates, func(i, j int) bool {
+			return c.Candidates[i].Votes > c.Candidates[j].Votes
+		})
+		combined := models.Contest{ID: c.ID, Title: c.Title}
+		for _, cand := range c.Candidates {
+			combined.Candidates = append(combined.Candidates, models.Candidate{
+				Name:             cand.Name,
+				Votes:            cand.Votes,
+				WriteInAggregate: cand.WriteInAggregate,
+			})
+		}
+		c.Outcome = outcome.Compute(combined, rules)
+		res.Contests = append(res.Contests, *c)
+	}
+	for _, p := range d.Precincts {
+		if !matched[store.CountyKey(p.County)+"/"+precinctKey(p.Precinct)] {
+			res.Unmatched = append(res.Unmatched, p)
+		}
+	}
+	return res
+}
+
+// precinctKey is what precinct names are matched by.
+func precinctKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,63 @@
+package aggregate
+
+import (
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/outcome"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// GeoJSON returns the contest with the given ID, taken from each county as
+// Contest takes it, as a feature per county with its votes and outcome,
+// and the FIPS code and boundary of its jurisdiction if it has one. It
+// returns store.ErrNotFound when no county reports it.
+func GeoJSON(st store.Store, contestID string) (*models.FeatureCollection, error) {
+	agg, err := Contest(st, contestID)
+	if err != nil {
+		return nil, err
+	}
+	fc := &models.FeatureCollection{
+		Type:      "FeatureCollection",
+		ContestID: agg.ContestID,
+		Title:     agg.Title,
+		Features:  make([]models.Feature, 0, len(agg.Breakdown)),
+		Version:   agg.Version,
+	}
+	rules := st.OutcomeRules()
+	for _, b := range agg.Breakdown {
+		o := outcome.Compute(models.Contest{
+			ID:                 agg.ContestID,
+			Candidates:         b.Candidates,
+			PrecinctsReporting: b.PrecinctsReporting,
+			PrecinctsTotal:     b.PrecinctsTotal,
+		}, rules)
+		p := models.FeatureProperties{
+			County:             b.County,
+			AsOf:               b.AsOf,
+			SnapshotHash:       b.SnapshotHash,
+			TotalVotes:         b.TotalVotes,
+			PrecinctsReporting: b.PrecinctsReporting,
+			PrecinctsTotal:     b.PrecinctsTotal,
+			ReportingPercent:   b.ReportingPercent,
+			Leader:             o.Leader,
+			Tied:               o.Tied,
+			Margin:             o.Margin,
+			MarginPoints:       o.MarginPoints,
+			Candidates:         o.Shares,
+		}
+		for _, cand := range b.Candidates {
+			if cand.Name == o.Leader {
+				p.LeaderParty = cand.Party
+				break
+			}
+		}
+		id := store.CountyKey(b.County)
+		if j, err := st.Jurisdiction(id); err == nil {
+			p.FIPS, p.Boundary = j.FIPS, j.Boundary
+			if j.FIPS != "" {
+				id = j.FIPS
+			}
+		}
+		fc.Features = append(fc.Features, models.Feature{Type: "Feature", ID: id, Properties: p})
+	}
+	return fc, nil
+}
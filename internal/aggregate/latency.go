@@ -0,0 +1,80 @@
+package aggregate
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Latency summarizes the post-to-publish latency of recent snapshots: every
+// county's combined first, then each county's own.
+func Latency(st store.Store) []models.LatencySummary {
+	history := st.LatencyHistory()
+	keys := make([]string, 0, len(history))
+	var all []models.LatencySample
+	for k, samples := range history {
+		keys = append(keys, k)
+		all = append(all, samples...)
+	}
+	sort.Strings(keys)
+
+	out := []models.LatencySummary{summarize("", all)}
+	for _, k := range keys {
+		out = append(out, summarize(history[k][0].County, history[k]))
+	}
+	return out
+}
+
+// CountyLatency summarizes county's recent snapshots, listing them newest
+// first. It returns store.ErrNotFound for counties with none.
+func CountyLatency(st store.Store, county string) (models.LatencySummary, error) {
+	samples := st.LatencyHistory()[store.CountyKey(county)]
+	if len(samples) == 0 {
+		return models.LatencySummary{}, store.ErrNotFound
+	}
+	s := summarize(samples[0].County, samples)
+	s.Samples = samples
+	slices.Reverse(s.Samples)
+	return s, nil
+}
+
+func summarize(county string, samples []models.LatencySample) models.LatencySummary {
+	var total, detect, fetch, process []int64
+	for _, s := range samples {
+		if s.PostToPublishMs != nil {
+			total = append(total, *s.PostToPublishMs)
+			detect = append(detect, *s.DetectMs)
+		}
+		fetch = append(fetch, s.FetchMs)
+		process = append(process, s.ProcessMs)
+	}
+	return models.LatencySummary{
+		County:        county,
+		Snapshots:     len(samples),
+		PostToPublish: stats(total),
+		Detect:        stats(detect),
+		Fetch:         stats(fetch),
+		Process:       stats(process),
+	}
+}
+
+// stats returns nearest-rank percentiles of values.
+func stats(values []int64) models.LatencyStats {
+	if len(values) == 0 {
+		return models.LatencyStats{}
+	}
+	slices.Sort(values)
+	rank := func(p int) int64 {
+		i := (p*len(values)+99)/100 - 1
+		return values[max(i, 0)]
+	}
+	return models.LatencyStats{
+		Count: len(values),
+		P50:   rank(50),
+		P90:   rank(90),
+		P99:   rank(99),
+		Max:   values[len(values)-1],
+	}
+}
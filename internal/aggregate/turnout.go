@@ -0,0 +1,73 @@
+package aggregate
+
+import (
+	"sort"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Turnout sums county turnout into a statewide figure. The history has one
+// point per county update, each combining the latest known figures of every
+// county at that moment.
+func Turnout(st store.Store) models.StatewideTurnout {
+	out := models.StatewideTurnout{Counties: []models.CountyTurnout{}, History: []models.TurnoutSample{}}
+
+	snap := st.ElectionSnapshot("")
+	out.Version = snap.Version
+	for _, results := range snap.Results {
+		if results.Turnout == nil {
+			continue
+		}
+		t := results.Turnout
+		out.Counties = append(out.Counties, models.CountyTurnout{
+			County: results.County,
+			TurnoutSample: models.TurnoutSample{
+				At:               results.ParsedAt,
+				RegisteredVoters: t.RegisteredVoters,
+				BallotsCast:      t.BallotsCast,
+				Percent:          t.Percent,
+			},
+		})
+		out.Current.RegisteredVoters += t.RegisteredVoters
+		out.Current.BallotsCast += t.BallotsCast
+		if results.ParsedAt.After(out.Current.At) {
+			out.Current.At = results.ParsedAt
+		}
+	}
+	out.Current.Percent = models.TurnoutPercent(out.Current.BallotsCast, out.Current.RegisteredVoters)
+
+	type event struct {
+		county string
+		sample models.TurnoutSample
+	}
+	var events []event
+	for county, samples := range st.TurnoutHistory() {
+		for _, s := range samples {
+			events = append(events, event{county, s})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].sample.At.Before(events[j].sample.At) })
+
+	latest := make(map[string]models.TurnoutSample)
+	for i, e := range events {
+		latest[e.county] = e.sample
+		// Collapse updates that land at the same instant into one point.
+		if i+1 < len(events) && events[i+1].sample.At.Equal(e.sample.At) {
+			continue
+		}
+		out.History = append(out.History, sumSamples(latest, e.sample.At))
+	}
+	return out
+}
+
+func sumSamples(latest map[string]models.TurnoutSample, at time.Time) models.TurnoutSample {
+	sum := models.TurnoutSample{At: at}
+	for _, s := range latest {
+		sum.RegisteredVoters += s.RegisteredVoters
+		sum.BallotsCast += s.BallotsCast
+	}
+	sum.Percent = models.TurnoutPercent(sum.BallotsCast, sum.RegisteredVoters)
+	return sum
+}
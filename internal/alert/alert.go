@@ -0,0 +1,114 @@
+// Package alert sends operational alerts to on-call channels. Each alert is
+// sent with the runbooks attached to its type and source and with the
+// county's contact, so whoever is paged sees what to do and who to call
+// instead of a bare error string.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+const sendTimeout = 10 * time.Second
+
+// Sink delivers alerts to one channel.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, a models.Alert) error
+}
+
+// Notifier raises alerts: it records them, attaches runbooks and contacts
+// and fans them out to its sinks. The same alert type about the same county
+// is sent at most once per cooldown; repeats are recorded as suppressed.
+type Notifier struct {
+	store    store.Store
+	logger   *slog.Logger
+	cooldown time.Duration
+	sinks    []Sink
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// NewNotifier returns a Notifier with no sinks that reads runbooks and
+// contacts from st.
+func NewNotifier(st store.Store, logger *slog.Logger, cooldown time.Duration) *Notifier {
+	return &Notifier{store: st, logger: logger, cooldown: cooldown, last: make(map[string]time.Time)}
+}
+
+// AddSink makes n send alerts to s. It must be called before alerts are
+// raised.
+func (n *Notifier) AddSink(s Sink) {
+	n.sinks = append(n.sinks, s)
+}
+
+// Raise records a and sends it to every sink in the background.
+func (n *Notifier) Raise(ctx context.Context, a models.Alert) {
+	if a.RaisedAt.IsZero() {
+		a.RaisedAt = time.Now().UTC()
+	}
+	a.Runbooks = n.store.RunbooksFor(a.Type, a.County)
+	if a.County != "" {
+		if c, err := n.store.Contact(a.County); err == nil {
+			a.Contact = &c
+		}
+	}
+
+	key := a.Type + "/" + store.CountyKey(a.County)
+	n.mu.Lock()
+	if last, ok := n.last[key]; ok && a.RaisedAt.Sub(last) < n.cooldown {
+		a.Suppressed = true
+	} else {
+		n.last[key] = a.RaisedAt
+	}
+	n.mu.Unlock()
+
+	n.store.AddAlert(a)
+	if a.Suppressed {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, s := range n.sinks {
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+			defer cancel()
+			if err := s.Send(ctx, a); err != nil {
+				n.logger.ErrorContext(ctx, "failed to send alert", "sink", s.Name(), "type", a.Type, "county", a.County, "error", err)
+			}
+		}()
+	}
+}
+
+// post sends body as JSON to url, treating any status but 2xx as failure.
+func post(ctx context.Context, client *http.Client, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode alert: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Slack posts alerts to a Slack incoming webhook.
+type Slack struct {
+	webhook string
+	client  *http.Client
+}
+
+// NewSlack returns a sink posting to the incoming webhook at url.
+func NewSlack(url string) *Slack {
+	return &Slack{webhook: url, client: &http.Client{Timeout: sendTimeout}}
+}
+
+// Name implements Sink.
+func (s *Slack) Name() string { return "slack" }
+
+// Send implements Sink. The message is mrkdwn text: the summary, the
+// alert's details, then its runbooks and the county's contact.
+func (s *Slack) Send(ctx context.Context, a models.Alert) error {
+	var b strings.Builder
+	icon := ":warning:"
+	if a.Severity == models.SeverityError {
+		icon = ":rotating_light:"
+	}
+	fmt.Fprintf(&b, "%s *%s*\n", icon, a.Summary)
+	if a.Source != "" {
+		fmt.Fprintf(&b, "Source: %s\n", a.Source)
+	}
+	for _, k := range sortedKeys(a.Details) {
+		fmt.Fprintf(&b, "%s: %s\n", k, a.Details[k])
+	}
+	for _, rb := range a.Runbooks {
+		b.WriteString(":book: Runbook")
+		if rb.County != "" {
+			b.WriteString(" for " + rb.County)
+		}
+		if rb.URL != "" {
+			fmt.Fprintf(&b, ": <%s>", rb.URL)
+		}
+		if rb.Notes != "" {
+			b.WriteString("\n> " + strings.ReplaceAll(rb.Notes, "\n", "\n> "))
+		}
+		b.WriteString("\n")
+	}
+	if a.Contact != nil {
+		b.WriteString(":telephone_receiver: " + contactLine(a.Contact) + "\n")
+	}
+	return post(ctx, s.client, s.webhook, map[string]string{"text": b.String()})
+}
+
+// pagerDutyURL is the PagerDuty Events API v2 endpoint.
+const pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers PagerDuty incidents through the Events API v2.
+type PagerDuty struct {
+	routingKey string
+	url        string
+	client     *http.Client
+}
+
+// NewPagerDuty returns a sink triggering events on the service integration
+// with routingKey.
+func NewPagerDuty(routingKey string) *PagerDuty {
+	return &PagerDuty{routingKey: routingKey, url: pagerDutyURL, client: &http.Client{Timeout: sendTimeout}}
+}
+
+// Name implements Sink.
+func (p *PagerDuty) Name() string { return "pagerduty" }
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Send implements Sink. Runbook URLs become the event's links, so they show
+// on the incident; notes and the contact go in its custom details. Events
+// are deduplicated by alert type and county, so repeats while an incident
+// is open are folded into it.
+func (p *PagerDuty) Send(ctx context.Context, a models.Alert) error {
+	details := make(map[string]any, len(a.Details)+3)
+	for k, v := range a.Details {
+		details[k] = v
+	}
+	var links []pagerDutyLink
+	var notes []string
+	for _, rb := range a.Runbooks {
+		if rb.URL != "" {
+			text := "Runbook: " + rb.AlertType
+			if rb.County != "" {
+				text += " (" + rb.County + ")"
+			}
+			links = append(links, pagerDutyLink{Href: rb.URL, Text: text})
+		}
+		if rb.Notes != "" {
+			notes = append(notes, rb.Notes)
+		}
+	}
+	if len(notes) > 0 {
+		details["runbook_notes"] = strings.Join(notes, "\n\n")
+	}
+	if a.Contact != nil {
+		details["contact"] = contactLine(a.Contact)
+		if a.Contact.PostingSchedule != "" {
+			details["posting_schedule"] = a.Contact.PostingSchedule
+		}
+	}
+
+	source := a.Source
+	if source == "" {
+		source = "era"
+	}
+	return post(ctx, p.client, p.url, map[string]any{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    "era/" + a.Type + "/" + store.CountyKey(a.County),
+		"payload": map[string]any{
+			"summary":        a.Summary,
+			"source":         source,
+			"severity":       a.Severity,
+			"timestamp":      a.RaisedAt,
+			"component":      a.County,
+			"class":          a.Type,
+			"custom_details": details,
+		},
+		"links": links,
+	})
+}
+
+// contactLine summarizes who to call at a county.
+func contactLine(c *models.CountyContact) string {
+	var parts []string
+	add := func(label string, fields ...string) {
+		var set []string
+		for _, f := range fields {
+			if f != "" {
+				set = append(set, f)
+			}
+		}
+		if len(set) > 0 {
+			parts = append(parts, label+" "+strings.Join(set, ", "))
+		}
+	}
+	add("Office:", c.OfficeName, c.OfficePhone, c.OfficeEmail)
+	add("IT:", c.ITName, c.ITPhone, c.ITEmail)
+	return strings.Join(parts, "; ")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
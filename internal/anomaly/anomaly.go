@@ -0,0 +1,127 @@
+// Package anomaly flags integrity problems in parsed snapshots: votes that
+// went down, candidates with more votes than ballots were cast, precincts
+// reporting going backwards and vote shares swinging further than a count
+// plausibly moves. Unlike drift, which holds a snapshot back, anomalies are
+// published with it for someone to look at.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// maxAnomalies bounds how many anomalies one snapshot is flagged with; a
+// misread source would otherwise flag every candidate.
+const maxAnomalies = 50
+
+// Detector sets the thresholds anomalies are flagged at.
+type Detector struct {
+	// SwingPoints is how many percentage points a candidate's vote share
+	// may move between two snapshots.
+	SwingPoints float64
+	// MinVotes skips swing checks on contests whose previous total was
+	// below it, since early counts swing wildly.
+	MinVotes int
+}
+
+// DefaultDetector flags a share moving by more than 20 points in a contest
+// that already had 1000 votes.
+var DefaultDetector = Detector{SwingPoints: 20, MinVotes: 1000}
+
+// Check returns the anomalies in cur, compared with prev where that takes a
+// previous snapshot. prev may be nil.
+func (d Detector) Check(prev, cur *models.Results) []models.Anomaly {
+	var out []models.Anomaly
+	flag := func(kind string, c models.Contest, candidate string, was, now int, format string, args ...any) {
+		if len(out) >= maxAnomalies {
+			return
+		}
+		a := models.Anomaly{
+			Kind:         kind,
+			County:       cur.County,
+			ContestID:    c.ID,
+			Contest:      c.Title,
+			Candidate:    candidate,
+			Message:      fmt.Sprintf(format, args...),
+			Previous:     was,
+			Current:      now,
+			SnapshotHash: cur.Hash,
+		}
+		if prev != nil {
+			a.PreviousHash = prev.Hash
+		}
+		out = append(out, a)
+	}
+
+	ballots := 0
+	if cur.Turnout != nil {
+		ballots = cur.Turnout.BallotsCast
+	}
+	for _, c := range cur.Contests {
+		if ballots > 0 {
+			for _, cand := range c.Candidates {
+				if cand.Votes > ballots {
+					flag(models.AnomalyExceedsBallots, c, cand.Name, ballots, cand.Votes,
+						"%s in %q has %d votes but only %d ballots were cast", cand.Name, c.Title, cand.Votes, ballots)
+				}
+			}
+		}
+		if prev == nil {
+			continue
+		}
+		was, ok := prev.ContestByID(c.ID)
+		if !ok {
+			continue
+		}
+		if c.PrecinctsReporting < was.PrecinctsReporting {
+			flag(models.AnomalyPrecinctsBackwards, c, "", was.PrecinctsReporting, c.PrecinctsReporting,
+				"%q precincts reporting went from %d to %d", c.Title, was.PrecinctsReporting, c.PrecinctsReporting)
+		}
+		d.compareCandidates(*was, c, flag)
+	}
+	return out
+}
+
+type flagFunc func(kind string, c models.Contest, candidate string, was, now int, format string, args ...any)
+
+// compareCandidates flags candidates of now whose votes fell, or whose
+// share swung too far, since was.
+func (d Detector) compareCandidates(was, now models.Contest, flag flagFunc) {
+	before := make(map[string]models.Candidate, len(was.Candidates))
+	for _, cand := range was.Candidates {
+		before[candidateKey(cand)] = cand
+	}
+	wasTotal, nowTotal := was.TotalVotes(), now.TotalVotes()
+	swings := d.SwingPoints > 0 && wasTotal >= d.MinVotes && wasTotal > 0 && nowTotal > 0
+
+	for _, cand := range now.Candidates {
+		old, ok := before[candidateKey(cand)]
+		if !ok {
+			continue
+		}
+		if cand.Votes < old.Votes {
+			flag(models.AnomalyVotesDecreased, now, cand.Name, old.Votes, cand.Votes,
+				"%s in %q went from %d to %d votes", cand.Name, now.Title, old.Votes, cand.Votes)
+		}
+		if !swings {
+			continue
+		}
+		oldShare := 100 * float64(old.Votes) / float64(wasTotal)
+		newShare := 100 * float64(cand.Votes) / float64(nowTotal)
+		if math.Abs(newShare-oldShare) > d.SwingPoints {
+			flag(models.AnomalySwing, now, cand.Name, int(math.Round(oldShare*100)), int(math.Round(newShare*100)),
+				"%s in %q moved from %.1f%% to %.1f%% of the vote", cand.Name, now.Title, oldShare, newShare)
+		}
+	}
+}
+
+// candidateKey pairs a candidate across snapshots by registry ID when it
+// has one, since names may be respelled.
+func candidateKey(c models.Candidate) string {
+	if c.CanonicalID != "" {
+		return "id:" + c.CanonicalID
+	}
+	return "name:" + c.Name
+}
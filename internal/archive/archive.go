@@ -0,0 +1,304 @@
+// Package archive keeps the raw source file behind every snapshot, so a
+// snapshot can be audited against, or re-extracted from, exactly what the
+// county posted. Sources are stored in a local directory or an S3 bucket as
+// <county key>/<snapshot hash>.<parse method>, each beside a
+// <snapshot hash>.json manifest recording the file's content hash, where it
+// was fetched from and when.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+var (
+	// ErrNotFound is returned when no archived source matches.
+	ErrNotFound = errors.New("archived source not found")
+	// ErrAmbiguous is returned when a snapshot prefix matches several
+	// archived sources.
+	ErrAmbiguous = errors.New("snapshot is ambiguous")
+	// ErrExists is returned when saving a certification bundle over one
+	// already archived.
+	ErrExists = errors.New("certification bundle already archived")
+)
+
+// manifestExt marks the manifest of an archived source. No parse method
+// uses it, so manifests are never mistaken for sources.
+const manifestExt = ".json"
+
+// Backend stores archived files under slash-separated keys.
+type Backend interface {
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns ErrNotFound for a key that doesn't exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns the objects directly under prefix, which ends in a
+	// slash.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	String() string
+}
+
+// Object is a stored file.
+type Object struct {
+	Key     string
+	ModTime time.Time
+}
+
+// Archive keeps sources in a Backend.
+type Archive struct {
+	backend Backend
+}
+
+// New returns an archive stored in b.
+func New(b Backend) *Archive {
+	return &Archive{backend: b}
+}
+
+// Open returns the archive at location: a local directory, or an S3 bucket
+// and optional key prefix as s3://bucket/prefix. S3 credentials, region
+// and endpoint are read from the standard AWS_* environment variables.
+func Open(location string) (*Archive, error) {
+	if !strings.HasPrefix(location, "s3://") {
+		return New(Dir(location)), nil
+	}
+	u, err := url.Parse(location)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("archive location %q: want s3://bucket/prefix", location)
+	}
+	b, err := NewS3(S3Config{
+		Bucket:          u.Host,
+		Prefix:          strings.Trim(u.Path, "/"),
+		Region:          os.Getenv("AWS_REGION"),
+		Endpoint:        os.Getenv("AWS_ENDPOINT_URL_S3"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return New(b), nil
+}
+
+// String describes where the archive is stored.
+func (a *Archive) String() string {
+	return a.backend.String()
+}
+
+func hashName(snapshotHash string) string {
+	return strings.TrimPrefix(snapshotHash, "sha256:")
+}
+
+func keyOf(county, snapshotHash string) string {
+	return store.CountyKey(county) + "/" + hashName(snapshotHash)
+}
+
+// Save archives the source of art's snapshot. An existing copy is left
+// alone, since the same snapshot hash means the same results; the manifest
+// keeps recording the first fetch that produced them.
+func (a *Archive) Save(ctx context.Context, art models.Artifact, data []byte) error {
+	key := keyOf(art.County, art.SnapshotHash)
+	if _, err := a.backend.Get(ctx, key+manifestExt); err == nil {
+		return nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("archive source: %w", err)
+	}
+	art.County = store.CountyKey(art.County)
+	art.Size = len(data)
+
+	manifest, err := json.Marshal(art)
+	if err != nil {
+		return fmt.Errorf("archive source: %w", err)
+	}
+	// The manifest goes last: a source without one is from an interrupted
+	// save, or from before manifests, and is listed from its name alone.
+	if err := a.backend.Put(ctx, key+"."+art.ParseMethod, data); err != nil {
+		return fmt.Errorf("archive source: %w", err)
+	}
+	if err := a.backend.Put(ctx, key+manifestExt, manifest); err != nil {
+		return fmt.Errorf("archive source: %w", err)
+	}
+	return nil
+}
+
+// List returns the archived sources of county, newest first.
+func (a *Archive) List(ctx context.Context, county string) ([]models.Artifact, error) {
+	key := store.CountyKey(county)
+	objects, err := a.backend.List(ctx, key+"/")
+	if err != nil {
+		return nil, fmt.Errorf("list archive: %w", err)
+	}
+
+	manifests := make(map[string]bool)
+	for _, o := range objects {
+		if name := path.Base(o.Key); path.Ext(name) == manifestExt {
+			manifests[strings.TrimSuffix(name, manifestExt)] = true
+		}
+	}
+	var out []models.Artifact
+	for _, o := range objects {
+		name := path.Base(o.Key)
+		ext := path.Ext(name)
+		if ext == "" || ext == ".tmp" || ext == manifestExt {
+			continue
+		}
+		snapshot := strings.TrimSuffix(name, ext)
+		art := models.Artifact{
+			County:       key,
+			SnapshotHash: "sha256:" + snapshot,
+			ParseMethod:  strings.TrimPrefix(ext, "."),
+			FetchedAt:    o.ModTime.UTC(),
+		}
+		if manifests[snapshot] {
+			data, err := a.backend.Get(ctx, key+"/"+snapshot+manifestExt)
+			if err != nil {
+				return nil, fmt.Errorf("list archive: %w", err)
+			}
+			if err := json.Unmarshal(data, &art); err != nil {
+				return nil, fmt.Errorf("list archive: %s: %w", o.Key, err)
+			}
+		}
+		out = append(out, art)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FetchedAt.After(out[j].FetchedAt) })
+	return out, nil
+}
+
+// Find returns the archived source of county whose snapshot hash starts
+// with snapshot, or the newest one if snapshot is "" or "latest".
+func (a *Archive) Find(ctx context.Context, county, snapshot string) (models.Artifact, error) {
+	artifacts, err := a.List(ctx, county)
+	if err != nil {
+		return models.Artifact{}, err
+	}
+	if snapshot == "" || snapshot == "latest" {
+		if len(artifacts) == 0 {
+			return models.Artifact{}, ErrNotFound
+		}
+		return artifacts[0], nil
+	}
+
+	prefix := "sha256:" + hashName(snapshot)
+	var match *models.Artifact
+	for i := range artifacts {
+		if strings.HasPrefix(artifacts[i].SnapshotHash, prefix) {
+			if match != nil && match.SnapshotHash != artifacts[i].SnapshotHash {
+				return models.Artifact{}, fmt.Errorf("%w: %q", ErrAmbiguous, snapshot)
+			}
+			match = &artifacts[i]
+		}
+	}
+	if match == nil {
+		return models.Artifact{}, ErrNotFound
+	}
+	return *match, nil
+}
+
+// bundleKey is where the certification bundle of county in election is
+// kept. County keys never start with an underscore, so bundles are never
+// listed as sources.
+func bundleKey(election, county string) string {
+	return "_certified/" + election + "/" + store.CountyKey(county) + ".zip"
+}
+
+// SaveBundle archives the certification bundle of county in election. A
+// bundle is the permanent record of certified results, so one already
+// archived is never replaced: saving another fails with ErrExists.
+func (a *Archive) SaveBundle(ctx context.Context, election, county string, data []byte) error {
+	key := bundleKey(election, county)
+	if _, err := a.backend.Get(ctx, key); err == nil {
+		return fmt.Errorf("%s/%s: %w", election, county, ErrExists)
+	} else if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("archive bundle: %w", err)
+	}
+	if err := a.backend.Put(ctx, key, data); err != nil {
+		return fmt.Errorf("archive bundle: %w", err)
+	}
+	return nil
+}
+
+// ReadBundle returns the certification bundle of county in election.
+func (a *Archive) ReadBundle(ctx context.Context, election, county string) ([]byte, error) {
+	data, err := a.backend.Get(ctx, bundleKey(election, county))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	return data, err
+}
+
+// Read returns the archived file of art.
+func (a *Archive) Read(ctx context.Context, art models.Artifact) ([]byte, error) {
+	data, err := a.backend.Get(ctx, keyOf(art.County, art.SnapshotHash)+"."+art.ParseMethod)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	return data, err
+}
+
+// Dir is a Backend storing files under a local directory.
+type Dir string
+
+func (d Dir) path(key string) string {
+	return filepath.Join(string(d), filepath.FromSlash(key))
+}
+
+// Put implements Backend, writing through a temporary file so readers
+// never see a partial one.
+func (d Dir) Put(_ context.Context, key string, data []byte) error {
+	p := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// Get implements Backend.
+func (d Dir) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(d.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// List implements Backend.
+func (d Dir) List(_ context.Context, prefix string) ([]Object, error) {
+	entries, err := os.ReadDir(d.path(prefix))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Object
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, Object{Key: prefix + e.Name(), ModTime: info.ModTime()})
+	}
+	return out, nil
+}
+
+func (d Dir) String() string {
+	return string(d)
+}
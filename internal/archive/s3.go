@@ -0,0 +1,193 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/sigv4"
+)
+
+const (
+	s3Timeout       = 30 * time.Second
+	s3DefaultRegion = "us-east-1"
+	s3MaxError      = 1 << 10
+)
+
+// S3Config locates a bucket and the credentials to use it.
+type S3Config struct {
+	Bucket string
+	Prefix string // key prefix, without leading or trailing slashes
+
+	// Region defaults to us-east-1. Endpoint overrides the AWS endpoint
+	// for S3-compatible stores such as MinIO, addressing the bucket by
+	// path rather than by host.
+	Region   string
+	Endpoint string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3 is a Backend storing files in an S3 bucket, signing requests with
+// sigv4.
+type S3 struct {
+	cfg    S3Config
+	base   *url.URL // bucket root
+	client *http.Client
+}
+
+// NewS3 returns a backend for the bucket in cfg.
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3 archive: bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3 archive: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = s3DefaultRegion
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+
+	var base *url.URL
+	if cfg.Endpoint != "" {
+		u, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("s3 archive: invalid endpoint %q", cfg.Endpoint)
+		}
+		u.Path += "/" + cfg.Bucket
+		base = u
+	} else {
+		base = &url.URL{Scheme: "https", Host: cfg.Bucket + ".s3." + cfg.Region + ".amazonaws.com"}
+	}
+	return &S3{cfg: cfg, base: base, client: &http.Client{Timeout: s3Timeout}}, nil
+}
+
+func (s *S3) objectKey(key string) string {
+	if s.cfg.Prefix == "" {
+		return key
+	}
+	return s.cfg.Prefix + "/" + key
+}
+
+// Put implements Backend.
+func (s *S3) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s.objectKey(key), nil, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get implements Backend.
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectKey(key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List implements Backend, paging through ListObjectsV2.
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	full := s.objectKey(prefix)
+	var out []Object
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {full}, "delimiter": {"/"}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, "", q, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3 list: %w", err)
+		}
+		for _, c := range page.Contents {
+			out = append(out, Object{Key: prefix + strings.TrimPrefix(c.Key, full), ModTime: c.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return out, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *S3) String() string {
+	u := "s3://" + s.cfg.Bucket
+	if s.cfg.Prefix != "" {
+		u += "/" + s.cfg.Prefix
+	}
+	return u
+}
+
+// do sends a signed request for key, or for the bucket itself if key is
+// empty. Missing keys are reported as ErrNotFound and any other status but
+// 2xx as an error carrying S3's message.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u := *s.base
+	if key != "" {
+		u.Path += "/" + key
+	} else {
+		u.Path += "/"
+	}
+	u.RawPath = sigv4.EscapePath(u.Path)
+	u.RawQuery = sigv4.CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	sigv4.Sign(req, body, s.cfg.Region, sigv4.Credentials{
+		AccessKeyID:     s.cfg.AccessKeyID,
+		SecretAccessKey: s.cfg.SecretAccessKey,
+		SessionToken:    s.cfg.SessionToken,
+	}, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodGet && key != "" {
+		return nil, ErrNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, s3MaxError))
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(msg, &e) == nil && e.Code != "" {
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", method, key, e.Code, e.Message)
+	}
+	return nil, fmt.Errorf("s3 %s %s: unexpected status %s", method, key, resp.Status)
+}
@@ -0,0 +1,197 @@
+// Package audit keeps the append-only record of who changed what: every
+// registration, upload, reprocess, race call and other mutating request
+// made through the API, in memory or in a JSON lines file that survives
+// restarts.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/requestid"
+)
+
+// Log is the append-only audit log. Entries are kept in memory to be
+// queried, and written to a file too if one is open.
+type Log struct {
+	mu      sync.RWMutex
+	entries []models.AuditEntry
+	f       *os.File
+}
+
+// NewLog returns an empty log held in memory only.
+func NewLog() *Log {
+	return &Log{}
+}
+
+// OpenLog opens the log at path, creating it if needed, and reads back the
+// entries already in it. A final line cut short by a crash is dropped.
+func OpenLog(path string) (*Log, error) {
+	l := &Log{}
+	size, err := l.load(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("truncate audit log: %w", err)
+	}
+	if _, err := f.Seek(size, 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("seek audit log: %w", err)
+	}
+	l.f = f
+	return l, nil
+}
+
+func (l *Log) load(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var size int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A line without its newline was never fully written.
+			return size, nil
+		}
+		if err != nil {
+			return size, fmt.Errorf("read audit log: %w", err)
+		}
+		var e models.AuditEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return size, fmt.Errorf("audit log %s at byte %d: %w", path, size, err)
+		}
+		size += int64(len(line))
+		l.entries = append(l.entries, e)
+	}
+}
+
+// Record appends e, numbering it after the last entry.
+func (l *Log) Record(e models.AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = uint64(len(l.entries)) + 1
+	if l.f != nil {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("encode audit entry: %w", err)
+		}
+		if _, err := l.f.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("append to audit log: %w", err)
+		}
+		if err := l.f.Sync(); err != nil {
+			return fmt.Errorf("sync audit log: %w", err)
+		}
+	}
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+// Query selects audit entries. Zero fields match every entry.
+type Query struct {
+	After  uint64    // only entries with a greater Seq
+	Since  time.Time // only entries at or after
+	Actor  string    // API key ID
+	Action string    // route, such as "POST /api/v1/counties"
+	Limit  int
+}
+
+// Entries returns the entries matching q, oldest first.
+func (l *Log) Entries(q Query) []models.AuditEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	out := []models.AuditEntry{}
+	start := min(q.After, uint64(len(l.entries)))
+	for _, e := range l.entries[start:] {
+		if q.Limit > 0 && len(out) == q.Limit {
+			break
+		}
+		if (q.Since.IsZero() || !e.At.Before(q.Since)) && (q.Actor == "" || e.Actor == q.Actor) && (q.Action == "" || e.Action == q.Action) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Close closes the file, if the log has one.
+func (l *Log) Close() error {
+	if l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// Middleware records every request to next that isn't a GET, HEAD or
+// OPTIONS once it is answered, refused or not. Wrap the mux with it, inside
+// auth.Middleware, so requests carry their route and caller. Routes in
+// readOnly, such as a query endpoint taking POST, aren't recorded, nor are
+// requests no route matched. An entry that can't be written is logged.
+func Middleware(l *Log, logger *slog.Logger, next http.Handler, readOnly ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		if r.Pattern == "" || slices.Contains(readOnly, r.Pattern) {
+			return
+		}
+
+		e := models.AuditEntry{
+			At:         time.Now().UTC(),
+			Action:     r.Pattern,
+			Path:       r.URL.Path,
+			Query:      r.URL.RawQuery,
+			Status:     sw.status,
+			RemoteAddr: r.RemoteAddr,
+			RequestID:  requestid.From(r.Context()),
+		}
+		if p, ok := auth.FromContext(r.Context()); ok && !p.Anonymous {
+			e.Actor, e.ActorName = p.Key.ID, p.Key.Name
+		}
+		if err := l.Record(e); err != nil {
+			logger.ErrorContext(r.Context(), "failed to record audit entry", "action", e.Action, "path", e.Path, "actor", e.Actor, "error", err)
+		}
+	})
+}
+
+// statusWriter passes a response through while noting its status.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, for
+// handlers that stream.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,228 @@
+// Package auth authenticates API keys and carries the caller's identity
+// through the request context.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/embargo"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/visibility"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Key       models.APIKey
+	Anonymous bool
+	Role      string // what the caller may do, one of models.Roles
+	Policy    visibility.Policy
+	Profile   *visibility.Profile
+	// Tier is the access tier delaying what the caller sees, nil for real
+	// time.
+	Tier *embargo.Tier
+}
+
+type principalKey struct{}
+
+// FromContext returns the principal attached by Middleware.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// Keys is the set of configured API keys.
+type Keys struct {
+	byHash              map[string]models.APIKey
+	anonymousVisibility string
+	anonymousRole       string
+	profiles            *visibility.Profiles
+	tiers               *embargo.Tiers
+	oidc                *OIDC
+}
+
+// LoadKeys reads a JSON array of API keys from path. Requests without a key
+// are given the anonymousVisibility preset, the anonymousRole and the
+// embargo.Anonymous tier, if there is one. Key profiles are resolved against
+// profiles and key tiers against tiers.
+func LoadKeys(path, anonymousVisibility, anonymousRole string, profiles *visibility.Profiles, tiers *embargo.Tiers) (*Keys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read api keys: %w", err)
+	}
+	var list []models.APIKey
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode api keys: %w", err)
+	}
+	return NewKeys(list, anonymousVisibility, anonymousRole, profiles, tiers)
+}
+
+// NewKeys indexes list for lookup. Keys without a role are admins, as every
+// key was before roles.
+func NewKeys(list []models.APIKey, anonymousVisibility, anonymousRole string, profiles *visibility.Profiles, tiers *embargo.Tiers) (*Keys, error) {
+	if !visibility.Known(anonymousVisibility) {
+		return nil, fmt.Errorf("unknown visibility preset %q", anonymousVisibility)
+	}
+	if !slices.Contains(models.Roles, anonymousRole) {
+		return nil, fmt.Errorf("unknown role %q", anonymousRole)
+	}
+	k := &Keys{
+		byHash:              make(map[string]models.APIKey, len(list)),
+		anonymousVisibility: anonymousVisibility,
+		anonymousRole:       anonymousRole,
+		profiles:            profiles,
+		tiers:               tiers,
+	}
+	for i, key := range list {
+		if key.Visibility == "" {
+			key.Visibility = models.VisibilityFull
+		}
+		if !visibility.Known(key.Visibility) {
+			return nil, fmt.Errorf("api key %d: unknown visibility preset %q", i, key.Visibility)
+		}
+		if key.Role == "" {
+			key.Role = models.RoleAdmin
+		}
+		if !slices.Contains(models.Roles, key.Role) {
+			return nil, fmt.Errorf("api key %d: unknown role %q", i, key.Role)
+		}
+		for _, g := range key.Hide {
+			if !visibility.KnownGroup(g) {
+				return nil, fmt.Errorf("api key %d: unknown field group %q", i, g)
+			}
+		}
+		if _, ok := profiles.Get(key.Profile); key.Profile != "" && !ok {
+			return nil, fmt.Errorf("api key %d: unknown profile %q", i, key.Profile)
+		}
+		if _, ok := tiers.Get(key.Tier); key.Tier != "" && !ok {
+			return nil, fmt.Errorf("api key %d: unknown access tier %q", i, key.Tier)
+		}
+
+		hash := strings.ToLower(key.KeyHash)
+		if key.Key != "" {
+			hash = HashKey(key.Key)
+		}
+		if hash == "" {
+			return nil, fmt.Errorf("api key %d: key or keyHash is required", i)
+		}
+		if key.ID == "" {
+			key.ID = hash[:12]
+		}
+		key.Key = ""
+		key.KeyHash = hash
+		k.byHash[hash] = key
+	}
+	return k, nil
+}
+
+// UseOIDC accepts JWTs validated by o as well as the configured keys, so
+// organizations with single sign-on needn't hand out static keys. o's
+// profile, and its tier unless it is the default, must be configured.
+func (k *Keys) UseOIDC(o *OIDC) error {
+	if _, ok := k.profiles.Get(o.cfg.Profile); o.cfg.Profile != "" && !ok {
+		return fmt.Errorf("oidc: unknown profile %q", o.cfg.Profile)
+	}
+	if _, ok := k.tiers.Get(o.cfg.Tier); o.cfg.Tier != embargo.Anonymous && !ok {
+		return fmt.Errorf("oidc: unknown access tier %q", o.cfg.Tier)
+	}
+	k.oidc = o
+	return nil
+}
+
+// HashKey returns the hex SHA-256 of a plaintext key.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Lookup returns the key matching the plaintext secret.
+func (k *Keys) Lookup(secret string) (models.APIKey, bool) {
+	hash := HashKey(secret)
+	for h, key := range k.byHash {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			return key, true
+		}
+	}
+	return models.APIKey{}, false
+}
+
+// Realm is the HTTP Basic realm browsers are challenged with. The key is
+// given as the password; the user name is ignored.
+const Realm = "era"
+
+// Middleware identifies the caller from the Authorization: Bearer or
+// X-API-Key header, or from the password of HTTP Basic credentials so a
+// browser can sign in to the admin pages. A bearer token that is a JWT is
+// validated against the OIDC issuer, if one is in use. Unknown keys and
+// invalid tokens are rejected; requests without a key continue as
+// anonymous. A nil Keys lets every request through with full visibility as
+// an admin.
+func Middleware(keys *Keys, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if keys == nil {
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), Principal{Anonymous: true, Role: models.RoleAdmin})))
+			return
+		}
+
+		secret := requestKey(r)
+		if secret == "" {
+			p := Principal{Anonymous: true, Role: keys.anonymousRole, Policy: visibility.New(keys.anonymousVisibility)}
+			p.Tier, _ = keys.tiers.Get(embargo.Anonymous)
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+			return
+		}
+
+		if keys.oidc != nil && strings.Count(secret, ".") == 2 {
+			key, err := keys.oidc.Verify(r.Context(), secret)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid token: " + err.Error()})
+				return
+			}
+			p := Principal{Key: key, Role: key.Role, Policy: visibility.For(key)}
+			p.Profile, _ = keys.profiles.Get(key.Profile)
+			p.Tier, _ = keys.tiers.Get(key.Tier)
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+			return
+		}
+
+		key, ok := keys.Lookup(secret)
+		if !ok {
+			if _, _, basic := r.BasicAuth(); basic {
+				w.Header().Set("WWW-Authenticate", `Basic realm="`+Realm+`"`)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid API key"}`))
+			return
+		}
+		p := Principal{Key: key, Role: key.Role, Policy: visibility.For(key)}
+		p.Profile, _ = keys.profiles.Get(key.Profile)
+		p.Tier, _ = keys.tiers.Get(key.Tier)
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+func requestKey(r *http.Request) string {
+	if v, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(v)
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		return strings.TrimSpace(password)
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
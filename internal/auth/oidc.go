@@ -0,0 +1,461 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for crypto.SHA256.New
+	_ "crypto/sha512" // and SHA-384 and SHA-512
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/embargo"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/visibility"
+)
+
+const (
+	oidcTimeout = 10 * time.Second
+	// oidcRefreshEvery limits how often an unknown signing key makes the
+	// issuer's keys be fetched again, so forged tokens can't flood it.
+	oidcRefreshEvery = time.Minute
+	// oidcLeeway allows for clocks differing between the issuer and us.
+	oidcLeeway = time.Minute
+	oidcMaxDoc = 1 << 20
+)
+
+// OIDCConfig configures validating JWTs signed by an OpenID Connect issuer
+// such as Auth0, Keycloak or Google.
+type OIDCConfig struct {
+	Issuer   string // as in tokens' iss claim, e.g. "https://example.eu.auth0.com/"
+	Audience string // required in tokens' aud claim if set
+
+	// RoleClaim names the claim holding the caller's roles or groups, a
+	// string or an array of them. A name that isn't a top-level claim, such
+	// as "realm_access.roles", is looked up as a dotted path. Defaults to
+	// "roles".
+	RoleClaim string
+	// RoleMap maps claim values to models.Roles; without it, values that
+	// are role names are taken as they are. A caller with several gets
+	// the most trusted, and one with none gets DefaultRole.
+	RoleMap     map[string]string
+	DefaultRole string
+
+	// Visibility is the preset callers are given, defaults to full.
+	Visibility string
+	// Profile is the response profile callers are given, none if empty.
+	Profile string
+	// Tier is the access tier callers are in. Defaults to
+	// embargo.Anonymous, so signing in sees no sooner than not signing in;
+	// name a tier without a delay for real time.
+	Tier string
+}
+
+// OIDC validates JWTs from an issuer against the signing keys it publishes
+// at the jwks_uri of its discovery document, fetched when first needed and
+// again when a token is signed with a key not seen yet.
+type OIDC struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey // by key ID
+	fetched  time.Time
+	fetching *keyFetch // in flight, nil if none
+}
+
+// keyFetch is a fetch of the issuer's keys that callers needing them wait
+// on, so only one is made at a time. err is set before done is closed.
+type keyFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewOIDC returns a validator for the issuer in cfg.
+func NewOIDC(cfg OIDCConfig) (*OIDC, error) {
+	if cfg.Issuer == "" {
+		return nil, errors.New("oidc: issuer is required")
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = "roles"
+	}
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = models.RoleViewer
+	}
+	if cfg.Visibility == "" {
+		cfg.Visibility = models.VisibilityFull
+	}
+	if cfg.Tier == "" {
+		cfg.Tier = embargo.Anonymous
+	}
+	if !slices.Contains(models.Roles, cfg.DefaultRole) {
+		return nil, fmt.Errorf("oidc: unknown role %q", cfg.DefaultRole)
+	}
+	for value, role := range cfg.RoleMap {
+		if !slices.Contains(models.Roles, role) {
+			return nil, fmt.Errorf("oidc: %q maps to unknown role %q", value, role)
+		}
+	}
+	if !visibility.Known(cfg.Visibility) {
+		return nil, fmt.Errorf("oidc: unknown visibility preset %q", cfg.Visibility)
+	}
+	return &OIDC{cfg: cfg, client: &http.Client{Timeout: oidcTimeout}}, nil
+}
+
+// Refresh fetches the issuer's discovery document and signing keys, or
+// waits for the fetch already in flight. The lock is only held to swap the
+// keys in, so tokens signed with known keys are verified meanwhile.
+func (o *OIDC) Refresh(ctx context.Context) error {
+	o.mu.Lock()
+	if f := o.fetching; f != nil {
+		o.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	f := &keyFetch{done: make(chan struct{})}
+	o.fetching = f
+	o.fetched = time.Now()
+	o.mu.Unlock()
+
+	// Others may be waiting on the fetch, so it isn't cut short by this
+	// caller going away; the client's timeout bounds it.
+	keys, err := o.fetchKeys(context.WithoutCancel(ctx))
+
+	o.mu.Lock()
+	if err == nil {
+		o.keys = keys
+	}
+	o.fetching = nil
+	o.mu.Unlock()
+	f.err = err
+	close(f.done)
+	return err
+}
+
+func (o *OIDC) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := o.get(ctx, strings.TrimSuffix(o.cfg.Issuer, "/")+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if doc.Issuer != o.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer is %q, not %q", doc.Issuer, o.cfg.Issuer)
+	}
+	if doc.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: no jwks_uri")
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := o.get(ctx, doc.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Keys of types we can't verify with are skipped rather than
+		// failing the others.
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("oidc keys: no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (o *OIDC) get(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxDoc)).Decode(v)
+}
+
+// key returns the signing key with the given ID, fetching the issuer's keys
+// if it hasn't been seen, or waiting for a fetch in flight.
+func (o *OIDC) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	o.mu.Lock()
+	pub, ok := o.keys[kid]
+	refresh := o.fetching != nil || time.Since(o.fetched) >= oidcRefreshEvery
+	o.mu.Unlock()
+	if ok {
+		return pub, nil
+	}
+	if refresh {
+		if err := o.Refresh(ctx); err != nil {
+			return nil, err
+		}
+		o.mu.Lock()
+		pub, ok = o.keys[kid]
+		o.mu.Unlock()
+		if ok {
+			return pub, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// Verify checks token's signature and claims and returns the caller as an
+// API key: its ID is the subject, its name the email or name claimed, and
+// its role mapped from the role claim.
+func (o *OIDC) Verify(ctx context.Context, token string) (models.APIKey, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return models.APIKey{}, errors.New("not a JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return models.APIKey{}, fmt.Errorf("token header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("token signature: %w", err)
+	}
+	pub, err := o.key(ctx, header.Kid)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	if err := verifySignature(header.Alg, pub, parts[0]+"."+parts[1], sig); err != nil {
+		return models.APIKey{}, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return models.APIKey{}, fmt.Errorf("token claims: %w", err)
+	}
+	if err := o.checkClaims(claims, time.Now()); err != nil {
+		return models.APIKey{}, err
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return models.APIKey{}, errors.New("token has no subject")
+	}
+	key := models.APIKey{ID: sub, Visibility: o.cfg.Visibility, Profile: o.cfg.Profile, Tier: o.cfg.Tier, Role: o.role(claims)}
+	for _, c := range []string{"email", "name", "preferred_username"} {
+		if v, ok := claims[c].(string); ok && v != "" {
+			key.Name = v
+			break
+		}
+	}
+	return key, nil
+}
+
+func (o *OIDC) checkClaims(claims map[string]any, now time.Time) error {
+	if iss, _ := claims["iss"].(string); iss != o.cfg.Issuer {
+		return fmt.Errorf("token issued by %q", iss)
+	}
+	if o.cfg.Audience != "" {
+		var aud []string
+		switch v := claims["aud"].(type) {
+		case string:
+			aud = []string{v}
+		case []any:
+			for _, a := range v {
+				if s, ok := a.(string); ok {
+					aud = append(aud, s)
+				}
+			}
+		}
+		if !slices.Contains(aud, o.cfg.Audience) {
+			return errors.New("token isn't for this audience")
+		}
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.Add(-oidcLeeway).After(time.Unix(int64(exp), 0)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(oidcLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not valid yet")
+	}
+	return nil
+}
+
+// role returns the most trusted role the claims map to.
+func (o *OIDC) role(claims map[string]any) string {
+	v, ok := claims[o.cfg.RoleClaim]
+	if !ok {
+		v = lookupPath(claims, strings.Split(o.cfg.RoleClaim, "."))
+	}
+	var values []string
+	switch v := v.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []any:
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	best := o.cfg.DefaultRole
+	for _, value := range values {
+		role := value
+		if len(o.cfg.RoleMap) > 0 {
+			role = o.cfg.RoleMap[value]
+		}
+		if slices.Contains(models.Roles, role) && models.RoleAllows(role, best) {
+			best = role
+		}
+	}
+	return best
+}
+
+func lookupPath(claims map[string]any, path []string) any {
+	var v any = claims
+	for _, name := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verifySignature checks sig over signed with pub for the JWS algorithm
+// alg. Only asymmetric algorithms are accepted: "none" and shared-secret
+// HMAC tokens are refused.
+func verifySignature(alg string, pub crypto.PublicKey, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		if alg[0] != 'R' {
+			break
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hash, digest, sig); err != nil {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if alg[0] != 'E' || len(sig) != 2*size {
+			break
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("token algorithm %s doesn't match its signing key", alg)
+}
+
+// jwk is a JSON Web Key as published in a JWKS document.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC key isn't on its curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/embargo"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/visibility"
+)
+
+// testIssuer serves a discovery document and the JWKS of its keys.
+type testIssuer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]crypto.Signer // by key ID
+	fetches int
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	iss := &testIssuer{keys: make(map[string]crypto.Signer)}
+	iss.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": iss.URL, "jwks_uri": iss.URL + "/jwks"})
+		case "/jwks":
+			iss.mu.Lock()
+			defer iss.mu.Unlock()
+			iss.fetches++
+			var set struct {
+				Keys []map[string]string `json:"keys"`
+			}
+			for kid, k := range iss.keys {
+				set.Keys = append(set.Keys, publicJWK(kid, k.Public()))
+			}
+			json.NewEncoder(w).Encode(set)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+func (iss *testIssuer) addKey(kid string, k crypto.Signer) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.keys[kid] = k
+}
+
+func (iss *testIssuer) fetchCount() int {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	return iss.fetches
+}
+
+func publicJWK(kid string, pub crypto.PublicKey) map[string]string {
+	enc := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": enc(pub.N.Bytes()), "e": enc(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return map[string]string{"kty": "EC", "kid": kid, "crv": pub.Curve.Params().Name, "x": enc(pub.X.FillBytes(make([]byte, size))), "y": enc(pub.Y.FillBytes(make([]byte, size)))}
+	}
+	panic("unsupported key")
+}
+
+// sign returns a JWT of claims with the given header algorithm and key ID,
+// signed by k; a nil k leaves the signature empty.
+func sign(t *testing.T, alg, kid string, k crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	var sig []byte
+	if k != nil {
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		switch k := k.(type) {
+		case *rsa.PrivateKey:
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		case *ecdsa.PrivateKey:
+			var r, s *big.Int
+			r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDCVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := newTestIssuer(t)
+	iss.addKey("rsa", rsaKey)
+	iss.addKey("ec", ecKey)
+
+	o, err := NewOIDC(OIDCConfig{Issuer: iss.URL, Audience: "era"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	claims := func(edit func(map[string]any)) map[string]any {
+		c := map[string]any{"iss": iss.URL, "aud": "era", "sub": "u1", "exp": now.Add(time.Hour).Unix()}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"RS256", sign(t, "RS256", "rsa", rsaKey, claims(nil)), ""},
+		{"ES256", sign(t, "ES256", "ec", ecKey, claims(nil)), ""},
+		{"audience in list", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]any) { c["aud"] = []string{"other", "era"} })), ""},
+		{"alg none", sign(t, "none", "rsa", nil, claims(nil)), "unsupported token algorithm"},
+		{"alg HS256", sign(t, "HS256", "rsa", rsaKey, claims(nil)), "unsupported token algorithm"},
+		{"alg for another key type", sign(t, "ES256", "rsa", rsaKey, claims(nil)), "doesn't match its signing key"},
+		{"signed by another key", sign(t, "RS256", "rsa", otherKey, claims(nil)), "invalid token signature"},
+		{"other issuer", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]any) { c["iss"] = "https://evil.example/" })), "token issued by"},
+		{"other audience", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]any) { c["aud"] = "other" })), "audience"},
+		{"no audience", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]any) { delete(c, "aud") })), "audience"},
+		{"expired", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]any) { c["exp"] = now.Add(-2 * oidcLeeway).Unix() })), "token expired"},
+		{"expired within leeway", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]any) { c["exp"] = now.Add(-oidcLeeway / 2).Unix() })), ""},
+		{"no expiry", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]any) { delete(c, "exp") })), "no expiry"},
+		{"not valid yet", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]any) { c["nbf"] = now.Add(2 * oidcLeeway).Unix() })), "not valid yet"},
+		{"not valid yet within leeway", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]any) { c["nbf"] = now.Add(oidcLeeway / 2).Unix() })), ""},
+		{"no subject", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]any) { delete(c, "sub") })), "no subject"},
+		{"not a JWT", "a.b", "not a JWT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := o.Verify(context.Background(), tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if key.ID != "u1" {
+					t.Errorf("ID = %q, want u1", key.ID)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Verify error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCUnknownKeyRefresh(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := newTestIssuer(t)
+	iss.addKey("old", oldKey)
+
+	o, err := NewOIDC(OIDCConfig{Issuer: iss.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]any{"iss": iss.URL, "sub": "u1", "exp": time.Now().Add(time.Hour).Unix()}
+
+	// The first token fetches the keys.
+	if _, err := o.Verify(context.Background(), sign(t, "ES256", "old", oldKey, claims)); err != nil {
+		t.Fatalf("Verify with the published key: %v", err)
+	}
+	if n := iss.fetchCount(); n != 1 {
+		t.Fatalf("fetches = %d, want 1", n)
+	}
+
+	// The issuer rotates in a key; a token signed with it is only known
+	// after a refresh, which is allowed once the last one is old enough.
+	iss.addKey("new", newKey)
+	o.mu.Lock()
+	o.fetched = time.Now().Add(-oidcRefreshEvery)
+	o.mu.Unlock()
+	if _, err := o.Verify(context.Background(), sign(t, "ES256", "new", newKey, claims)); err != nil {
+		t.Fatalf("Verify with a rotated-in key: %v", err)
+	}
+	if n := iss.fetchCount(); n != 2 {
+		t.Fatalf("fetches = %d, want 2", n)
+	}
+
+	// Unknown key IDs refresh at most once per oidcRefreshEvery, so forged
+	// tokens can't flood the issuer.
+	for range 3 {
+		_, err := o.Verify(context.Background(), sign(t, "ES256", "forged", newKey, claims))
+		if err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+			t.Fatalf("Verify with an unknown key = %v, want unknown signing key", err)
+		}
+	}
+	if n := iss.fetchCount(); n != 2 {
+		t.Fatalf("fetches = %d, want still 2", n)
+	}
+}
+
+func TestOIDCRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    OIDCConfig
+		claims map[string]any
+		want   string
+	}{
+		{"no claim", OIDCConfig{}, map[string]any{}, models.RoleViewer},
+		{"role names", OIDCConfig{}, map[string]any{"roles": []any{"viewer", "editor"}}, models.RoleEditor},
+		{"space separated", OIDCConfig{}, map[string]any{"roles": "viewer admin"}, models.RoleAdmin},
+		{"unknown names ignored", OIDCConfig{}, map[string]any{"roles": []any{"superuser"}}, models.RoleViewer},
+		{"mapped", OIDCConfig{RoleMap: map[string]string{"newsroom": models.RoleEditor}}, map[string]any{"roles": []any{"newsroom", "admin"}}, models.RoleEditor},
+		{"dotted path", OIDCConfig{RoleClaim: "realm_access.roles"}, map[string]any{"realm_access": map[string]any{"roles": []any{"ingester"}}}, models.RoleIngester},
+		{"default role", OIDCConfig{DefaultRole: models.RoleIngester}, map[string]any{"roles": []any{"viewer"}}, models.RoleIngester},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Issuer = "https://issuer.example/"
+			o, err := NewOIDC(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := o.role(tt.claims); got != tt.want {
+				t.Errorf("role = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCPrincipalTier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := newTestIssuer(t)
+	iss.addKey("k", key)
+	tiers, err := embargo.NewTiers([]embargo.Tier{{Name: embargo.Anonymous, Delay: "5m"}, {Name: "partner"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, "ES256", "k", key, map[string]any{"iss": iss.URL, "sub": "u1", "exp": time.Now().Add(time.Hour).Unix()})
+
+	tests := []struct {
+		tier    string
+		want    string
+		delayed bool
+	}{
+		{"", embargo.Anonymous, true},
+		{"partner", "partner", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			keys, err := NewKeys(nil, models.VisibilityPublic, models.RoleViewer, visibility.DefaultProfiles(), tiers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			o, err := NewOIDC(OIDCConfig{Issuer: iss.URL, Tier: tt.tier})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := keys.UseOIDC(o); err != nil {
+				t.Fatal(err)
+			}
+			var got Principal
+			h := Middleware(keys, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = FromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			if got.Tier == nil || got.Tier.Name != tt.want || got.Tier.Delayed() != tt.delayed {
+				t.Errorf("tier = %+v, want %s (delayed %v)", got.Tier, tt.want, tt.delayed)
+			}
+		})
+	}
+
+	keys, err := NewKeys(nil, models.VisibilityPublic, models.RoleViewer, visibility.DefaultProfiles(), tiers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOIDC(OIDCConfig{Issuer: iss.URL, Tier: "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.UseOIDC(o); err == nil {
+		t.Error("UseOIDC accepted an unknown tier")
+	}
+}
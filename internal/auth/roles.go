@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// Routes maps route patterns, as registered on the mux, to the least role
+// that may call them. Routes not listed need models.RoleViewer if they only
+// read (GET, HEAD or OPTIONS) and models.RoleAdmin otherwise, so a new route
+// that changes something is closed to all but admins until it is given a
+// role.
+type Routes map[string]string
+
+// Required returns the least role that may call the route pattern matched
+// for a request with the given method.
+func (rt Routes) Required(method, pattern string) string {
+	if role, ok := rt[pattern]; ok {
+		return role
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return models.RoleViewer
+	}
+	return models.RoleAdmin
+}
+
+// Authorize answers 403 to callers whose role doesn't allow the route mux
+// matches for their request, and hands the rest to mux. Wrap the mux with
+// it inside Middleware. Requests no route matches are left to mux to
+// answer.
+func Authorize(routes Routes, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if pattern == "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		need := routes.Required(r.Method, pattern)
+		if p, ok := FromContext(r.Context()); !ok || !models.RoleAllows(p.Role, need) {
+			// Name the route as the mux would have, so the audit log
+			// records the refusal.
+			r.Pattern = pattern
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":"this needs an API key with the ` + need + ` role"}`))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role, least string
+		want        bool
+	}{
+		{models.RoleViewer, models.RoleViewer, true},
+		{models.RoleViewer, models.RoleIngester, false},
+		{models.RoleIngester, models.RoleViewer, true},
+		{models.RoleEditor, models.RoleIngester, true},
+		{models.RoleEditor, models.RoleAdmin, false},
+		{models.RoleAdmin, models.RoleEditor, true},
+		{models.RoleAdmin, models.RoleAdmin, true},
+		{"", models.RoleViewer, false},
+		{"root", models.RoleViewer, false},
+		{models.RoleAdmin, "root", false},
+	}
+	for _, tt := range tests {
+		if got := models.RoleAllows(tt.role, tt.least); got != tt.want {
+			t.Errorf("RoleAllows(%q, %q) = %v, want %v", tt.role, tt.least, got, tt.want)
+		}
+	}
+}
+
+func TestRoutesRequired(t *testing.T) {
+	routes := Routes{
+		"POST /process":         models.RoleIngester,
+		"GET /admin/keys":       models.RoleAdmin,
+		"DELETE /results/{id}":  models.RoleEditor,
+		"GET /results/{county}": models.RoleViewer,
+	}
+	tests := []struct {
+		method, pattern string
+		want            string
+	}{
+		{http.MethodPost, "POST /process", models.RoleIngester},
+		{http.MethodGet, "GET /admin/keys", models.RoleAdmin},
+		{http.MethodDelete, "DELETE /results/{id}", models.RoleEditor},
+		{http.MethodGet, "GET /results/{county}", models.RoleViewer},
+		{http.MethodGet, "GET /unlisted", models.RoleViewer},
+		{http.MethodHead, "GET /unlisted", models.RoleViewer},
+		{http.MethodOptions, "OPTIONS /unlisted", models.RoleViewer},
+		{http.MethodPost, "POST /unlisted", models.RoleAdmin},
+		{http.MethodPut, "PUT /unlisted", models.RoleAdmin},
+		{http.MethodPatch, "PATCH /unlisted", models.RoleAdmin},
+		{http.MethodDelete, "DELETE /unlisted", models.RoleAdmin},
+	}
+	for _, tt := range tests {
+		if got := routes.Required(tt.method, tt.pattern); got != tt.want {
+			t.Errorf("Required(%s, %q) = %q, want %q", tt.method, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	mux.HandleFunc("GET /results/{county}", ok)
+	mux.HandleFunc("POST /process", ok)
+	mux.HandleFunc("POST /races/{id}/call", ok)
+	mux.HandleFunc("DELETE /counties/{county}", ok)
+	h := Authorize(Routes{
+		"POST /process":         models.RoleIngester,
+		"POST /races/{id}/call": models.RoleEditor,
+	}, mux)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		role   string // empty for no principal
+		want   int
+	}{
+		{"viewer reads", http.MethodGet, "/results/adams", models.RoleViewer, http.StatusNoContent},
+		{"no principal", http.MethodGet, "/results/adams", "", http.StatusForbidden},
+		{"unknown role", http.MethodGet, "/results/adams", "root", http.StatusForbidden},
+		{"viewer processes", http.MethodPost, "/process", models.RoleViewer, http.StatusForbidden},
+		{"ingester processes", http.MethodPost, "/process", models.RoleIngester, http.StatusNoContent},
+		{"ingester calls", http.MethodPost, "/races/r1/call", models.RoleIngester, http.StatusForbidden},
+		{"editor calls", http.MethodPost, "/races/r1/call", models.RoleEditor, http.StatusNoContent},
+		{"admin calls", http.MethodPost, "/races/r1/call", models.RoleAdmin, http.StatusNoContent},
+		{"unlisted change", http.MethodDelete, "/counties/adams", models.RoleEditor, http.StatusForbidden},
+		{"unlisted change by admin", http.MethodDelete, "/counties/adams", models.RoleAdmin, http.StatusNoContent},
+		{"no route", http.MethodGet, "/missing", "", http.StatusNotFound},
+		{"wrong method", http.MethodPut, "/process", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.role != "" {
+				req = req.WithContext(WithPrincipal(req.Context(), Principal{Role: tt.role}))
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
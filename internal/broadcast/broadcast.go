@@ -0,0 +1,210 @@
+// Package broadcast produces the flat data feeds polled by broadcast
+// graphics systems (Viz/Chyron-style key/value files and simple XML).
+package broadcast
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Feed is the rendered broadcast payload for one county snapshot.
+type Feed struct {
+	County    string
+	UpdatedAt time.Time
+	KeyValue  []byte
+	XML       []byte
+}
+
+// Service regenerates broadcast feeds whenever a county snapshot is saved.
+type Service struct {
+	store  store.Store
+	logger *slog.Logger
+
+	mu    sync.RWMutex
+	feeds map[string]*Feed
+}
+
+// NewService returns a Service that renders feeds from results in st.
+func NewService(st store.Store, logger *slog.Logger) *Service {
+	return &Service{store: st, logger: logger, feeds: make(map[string]*Feed)}
+}
+
+// Feed returns the current feed for county, rendering it from stored
+// results if no snapshot has been seen since startup.
+func (s *Service) Feed(county string) (*Feed, error) {
+	s.mu.RLock()
+	f, ok := s.feeds[store.CountyKey(county)]
+	s.mu.RUnlock()
+	if ok {
+		return f, nil
+	}
+
+	results, err := s.store.Results(county)
+	if err != nil {
+		return nil, err
+	}
+	return s.render(results)
+}
+
+// OnSnapshot re-renders the county's feed. Register it with
+// Processor.OnSnapshot.
+func (s *Service) OnSnapshot(_ context.Context, results *models.Results) {
+	if _, err := s.render(results); err != nil {
+		s.logger.Error("broadcast feed render failed", "county", results.County, "error", err)
+	}
+}
+
+func (s *Service) render(results *models.Results) (*Feed, error) {
+	x, err := renderXML(results)
+	if err != nil {
+		return nil, err
+	}
+	f := &Feed{
+		County:    results.County,
+		UpdatedAt: results.ParsedAt,
+		KeyValue:  renderKeyValue(results),
+		XML:       x,
+	}
+
+	s.mu.Lock()
+	s.feeds[store.CountyKey(results.County)] = f
+	s.mu.Unlock()
+	return f, nil
+}
+
+// ranked returns the contest's candidates ordered leader first, the order
+// graphics templates expect.
+func ranked(c models.Contest) []models.Candidate {
+	out := append([]models.Candidate(nil), c.Candidates...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Votes > out[j].Votes })
+	return out
+}
+
+func pct(n, total int) string {
+	if total == 0 {
+		return "0.0"
+	}
+	return fmt.Sprintf("%.1f", float64(n)*100/float64(total))
+}
+
+// renderKeyValue writes one KEY=VALUE pair per line, e.g.
+//
+//	C01_TITLE=Mayor
+//	C01_CAND01_NAME=Jane Doe
+//	C01_CAND01_VOTES=1200
+func renderKeyValue(results *models.Results) []byte {
+	var b bytes.Buffer
+	kv := func(key, value string) {
+		// Values must stay on one line for line-oriented graphics parsers.
+		value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+		fmt.Fprintf(&b, "%s=%s\n", key, value)
+	}
+
+	kv("COUNTY", results.County)
+	kv("UPDATED", results.ParsedAt.Format(time.RFC3339))
+	if l := results.License; l != nil {
+		kv("LICENSE", l.Name)
+		kv("ATTRIBUTION", l.Attribution)
+	}
+	kv("CONTEST_COUNT", fmt.Sprint(len(results.Contests)))
+	for i, c := range results.Contests {
+		prefix := fmt.Sprintf("C%02d", i+1)
+		total := c.TotalVotes()
+		kv(prefix+"_ID", c.ID)
+		kv(prefix+"_TITLE", c.Title)
+		kv(prefix+"_TOTAL_VOTES", fmt.Sprint(total))
+		kv(prefix+"_PRECINCTS_PCT", pct(c.PrecinctsReporting, c.PrecinctsTotal))
+		kv(prefix+"_CAND_COUNT", fmt.Sprint(len(c.Candidates)))
+		for j, cand := range ranked(c) {
+			cp := fmt.Sprintf("%s_CAND%02d", prefix, j+1)
+			kv(cp+"_NAME", cand.Name)
+			kv(cp+"_PARTY", cand.Party)
+			kv(cp+"_VOTES", fmt.Sprint(cand.Votes))
+			kv(cp+"_PCT", pct(cand.Votes, total))
+			kv(cp+"_WRITEIN", boolFlag(cand.WriteIn))
+		}
+	}
+	return b.Bytes()
+}
+
+type xmlFeed struct {
+	XMLName  xml.Name     `xml:"BroadcastData"`
+	County   string       `xml:"county,attr"`
+	Updated  string       `xml:"updated,attr"`
+	License  *xmlLicense  `xml:"License,omitempty"`
+	Contests []xmlContest `xml:"Contest"`
+}
+
+type xmlLicense struct {
+	Name           string `xml:"name,attr"`
+	URL            string `xml:"url,attr,omitempty"`
+	Redistribution string `xml:"redistribution,attr,omitempty"`
+	Attribution    string `xml:",chardata"`
+}
+
+type xmlContest struct {
+	ID           string         `xml:"id,attr"`
+	Title        string         `xml:"title,attr"`
+	TotalVotes   int            `xml:"totalVotes,attr"`
+	PrecinctsPct string         `xml:"precinctsPct,attr"`
+	Candidates   []xmlCandidate `xml:"Candidate"`
+}
+
+type xmlCandidate struct {
+	Rank    int    `xml:"rank,attr"`
+	Name    string `xml:"name,attr"`
+	Party   string `xml:"party,attr,omitempty"`
+	Votes   int    `xml:"votes,attr"`
+	Pct     string `xml:"pct,attr"`
+	WriteIn bool   `xml:"writeIn,attr,omitempty"`
+}
+
+func boolFlag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func renderXML(results *models.Results) ([]byte, error) {
+	feed := xmlFeed{County: results.County, Updated: results.ParsedAt.Format(time.RFC3339)}
+	if l := results.License; l != nil {
+		feed.License = &xmlLicense{Name: l.Name, URL: l.URL, Redistribution: l.Redistribution, Attribution: l.Attribution}
+	}
+	for _, c := range results.Contests {
+		total := c.TotalVotes()
+		xc := xmlContest{
+			ID:           c.ID,
+			Title:        c.Title,
+			TotalVotes:   total,
+			PrecinctsPct: pct(c.PrecinctsReporting, c.PrecinctsTotal),
+		}
+		for j, cand := range ranked(c) {
+			xc.Candidates = append(xc.Candidates, xmlCandidate{
+				Rank:    j + 1,
+				Name:    cand.Name,
+				Party:   cand.Party,
+				Votes:   cand.Votes,
+				Pct:     pct(cand.Votes, total),
+				WriteIn: cand.WriteIn,
+			})
+		}
+		feed.Contests = append(feed.Contests, xc)
+	}
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode broadcast xml: %w", err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
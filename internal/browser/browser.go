@@ -0,0 +1,428 @@
+// Package browser reads pages that render their results with JavaScript by
+// driving a headless Chrome over the DevTools protocol (CDP). The browser
+// runs apart from the server, started with --remote-debugging-port; each
+// page is loaded in a tab of its own, closed once read.
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/urlpolicy"
+)
+
+// Defaults for Config.
+const (
+	DefaultTabs   = 2
+	DefaultSettle = 2 * time.Second
+)
+
+// ErrNotCaptured is returned by Render when the page made no request whose
+// URL matched the capture pattern.
+var ErrNotCaptured = errors.New("no matching response captured")
+
+// Config configures a Browser.
+type Config struct {
+	// Endpoint is the browser's DevTools HTTP address, such as
+	// http://localhost:9222.
+	Endpoint string
+	// Tabs bounds how many pages are loaded at once; renders beyond it
+	// wait. Each tab costs the browser a renderer process.
+	Tabs int
+	// Settle is how long a page is left to run its scripts after it has
+	// loaded before its DOM is read.
+	Settle time.Duration
+	// Policy is checked against each page URL. The browser fetches the
+	// page's scripts and requests itself, beyond the policy's reach, so it
+	// should run where its network is restricted too.
+	Policy *urlpolicy.Policy
+}
+
+// Browser renders pages in tabs of a headless Chrome.
+type Browser struct {
+	endpoint *url.URL
+	tabs     chan struct{}
+	settle   time.Duration
+	policy   *urlpolicy.Policy
+	client   *http.Client
+	dialer   *net.Dialer
+}
+
+// New returns a Browser for the DevTools endpoint cfg names. It doesn't
+// connect until a page is rendered.
+func New(cfg Config) (*Browser, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || u.Scheme != "http" || u.Host == "" {
+		return nil, fmt.Errorf("browser endpoint %q must be an http URL such as http://localhost:9222", cfg.Endpoint)
+	}
+	if cfg.Tabs <= 0 {
+		cfg.Tabs = DefaultTabs
+	}
+	if cfg.Settle <= 0 {
+		cfg.Settle = DefaultSettle
+	}
+	return &Browser{
+		endpoint: u,
+		tabs:     make(chan struct{}, cfg.Tabs),
+		settle:   cfg.Settle,
+		policy:   cfg.Policy,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		dialer:   &net.Dialer{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (b *Browser) String() string {
+	return b.endpoint.String()
+}
+
+// Render loads link in a new tab and returns the HTML of its DOM once the
+// page has loaded and settled. If capture is set, it instead returns the
+// body of the first response, such as an XHR or fetch of the page's
+// results JSON, whose URL matches it; capture is a glob in which * matches
+// any run of characters, "/" included.
+func (b *Browser) Render(ctx context.Context, link, capture string) ([]byte, error) {
+	if err := b.policy.CheckURL(link); err != nil {
+		return nil, fmt.Errorf("render %s: %w", link, err)
+	}
+	select {
+	case b.tabs <- struct{}{}:
+		defer func() { <-b.tabs }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	tab, err := b.open(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("render %s: open tab: %w", link, err)
+	}
+	// The tab is closed even if ctx is done, so it doesn't linger.
+	defer b.close(context.WithoutCancel(ctx), tab.ID)
+
+	s, err := b.session(ctx, tab)
+	if err != nil {
+		return nil, fmt.Errorf("render %s: %w", link, err)
+	}
+	defer s.close()
+
+	var data []byte
+	if capture != "" {
+		data, err = s.capture(link, globRegexp(capture))
+	} else {
+		data, err = s.dom(link, b.settle)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("render %s: %w", link, err)
+	}
+	return data, nil
+}
+
+// target is a tab listed by the DevTools HTTP endpoint.
+type target struct {
+	ID                   string `json:"id"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+func (b *Browser) devtools(ctx context.Context, method, path string) (*http.Response, error) {
+	u := *b.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return resp, nil
+}
+
+// open creates a blank tab. Chrome asks for PUT here, older versions GET.
+func (b *Browser) open(ctx context.Context) (target, error) {
+	resp, err := b.devtools(ctx, http.MethodPut, "/json/new")
+	if err != nil {
+		if resp, err = b.devtools(ctx, http.MethodGet, "/json/new"); err != nil {
+			return target{}, err
+		}
+	}
+	defer resp.Body.Close()
+	var t target
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&t); err != nil {
+		return target{}, err
+	}
+	if t.ID == "" || t.WebSocketDebuggerURL == "" {
+		return target{}, errors.New("browser listed no debugger URL for the tab")
+	}
+	return t, nil
+}
+
+func (b *Browser) close(ctx context.Context, id string) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if resp, err := b.devtools(ctx, http.MethodGet, "/json/close/"+url.PathEscape(id)); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// session is a DevTools connection to one tab.
+type session struct {
+	ctx     context.Context
+	ws      *wsConn
+	stop    func() bool
+	nextID  int
+	pending []message // events read while waiting for a reply
+	idle    atomic.Bool
+}
+
+// message is a DevTools command reply or event.
+type message struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (b *Browser) session(ctx context.Context, t target) (*session, error) {
+	// The browser names itself as it sees itself, often 127.0.0.1, which
+	// isn't where it's reached from when it runs elsewhere.
+	u, err := url.Parse(t.WebSocketDebuggerURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Host = b.endpoint.Host
+	ws, err := dialWebSocket(ctx, b.dialer, u.String())
+	if err != nil {
+		return nil, fmt.Errorf("connect to tab: %w", err)
+	}
+	// Deadlines follow ctx, so an unresponsive page fails the render
+	// rather than holding a tab.
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(2 * time.Minute)
+	}
+	ws.conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { ws.conn.Close() })
+	return &session{ctx: ctx, ws: ws, stop: stop}, nil
+}
+
+func (s *session) close() {
+	s.stop()
+	s.ws.Close()
+}
+
+// call sends a command and waits for its reply, keeping events that arrive
+// meanwhile for next.
+func (s *session) call(method string, params any, result any) error {
+	s.nextID++
+	id := s.nextID
+	msg, err := json.Marshal(map[string]any{"id": id, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	if err := s.ws.WriteText(msg); err != nil {
+		return err
+	}
+	for {
+		m, err := s.read()
+		if err != nil {
+			return err
+		}
+		if m.ID != id {
+			if m.Method != "" {
+				s.pending = append(s.pending, m)
+			}
+			continue
+		}
+		if m.Error != nil {
+			return fmt.Errorf("%s: %s", method, m.Error.Message)
+		}
+		if result != nil {
+			return json.Unmarshal(m.Result, result)
+		}
+		return nil
+	}
+}
+
+func (s *session) read() (message, error) {
+	data, err := s.ws.ReadMessage()
+	if err != nil {
+		return message{}, err
+	}
+	var m message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return message{}, fmt.Errorf("undecodable DevTools message: %w", err)
+	}
+	return m, nil
+}
+
+// next returns the next event.
+func (s *session) next() (message, error) {
+	if len(s.pending) > 0 {
+		m := s.pending[0]
+		s.pending = s.pending[1:]
+		return m, nil
+	}
+	for {
+		m, err := s.read()
+		if err != nil {
+			return message{}, err
+		}
+		if m.Method != "" {
+			return m, nil
+		}
+	}
+}
+
+func (s *session) navigate(link string) error {
+	var nav struct {
+		ErrorText string `json:"errorText"`
+	}
+	if err := s.call("Page.navigate", map[string]string{"url": link}, &nav); err != nil {
+		return err
+	}
+	if nav.ErrorText != "" {
+		return fmt.Errorf("navigate: %s", nav.ErrorText)
+	}
+	return nil
+}
+
+// dom loads link and returns its DOM serialized as HTML, settle after the
+// load event.
+func (s *session) dom(link string, settle time.Duration) ([]byte, error) {
+	if err := s.call("Page.enable", struct{}{}, nil); err != nil {
+		return nil, err
+	}
+	if err := s.navigate(link); err != nil {
+		return nil, err
+	}
+	for {
+		m, err := s.next()
+		if err != nil {
+			return nil, err
+		}
+		if m.Method == "Page.loadEventFired" {
+			break
+		}
+	}
+	select {
+	case <-time.After(settle):
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+	var eval struct {
+		Result struct {
+			Value string `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text string `json:"text"`
+		} `json:"exceptionDetails"`
+	}
+	if err := s.call("Runtime.evaluate", map[string]any{
+		"expression":    "document.documentElement.outerHTML",
+		"returnByValue": true,
+	}, &eval); err != nil {
+		return nil, err
+	}
+	if eval.ExceptionDetails != nil {
+		return nil, fmt.Errorf("read DOM: %s", eval.ExceptionDetails.Text)
+	}
+	return []byte(eval.Result.Value), nil
+}
+
+// capture loads link and returns the body of the first response whose URL
+// matches pattern, once it has finished loading.
+func (s *session) capture(link string, pattern *regexp.Regexp) ([]byte, error) {
+	if err := s.call("Network.enable", struct{}{}, nil); err != nil {
+		return nil, err
+	}
+	if err := s.call("Page.enable", struct{}{}, nil); err != nil {
+		return nil, err
+	}
+	if err := s.navigate(link); err != nil {
+		return nil, err
+	}
+	matched := make(map[string]bool) // request IDs
+	loaded := false
+	for {
+		m, err := s.next()
+		if err != nil {
+			if s.idle.Load() {
+				return nil, ErrNotCaptured
+			}
+			return nil, err
+		}
+		var ev struct {
+			RequestID string `json:"requestId"`
+			Response  struct {
+				URL    string `json:"url"`
+				Status int    `json:"status"`
+			} `json:"response"`
+			ErrorText string `json:"errorText"`
+		}
+		json.Unmarshal(m.Params, &ev)
+		switch m.Method {
+		case "Network.responseReceived":
+			if pattern.MatchString(ev.Response.URL) && ev.Response.Status < 400 {
+				matched[ev.RequestID] = true
+			}
+		case "Network.loadingFinished":
+			if !matched[ev.RequestID] {
+				continue
+			}
+			var body struct {
+				Body          string `json:"body"`
+				Base64Encoded bool   `json:"base64Encoded"`
+			}
+			if err := s.call("Network.getResponseBody", map[string]string{"requestId": ev.RequestID}, &body); err != nil {
+				return nil, err
+			}
+			if body.Base64Encoded {
+				return base64.StdEncoding.DecodeString(body.Body)
+			}
+			return []byte(body.Body), nil
+		case "Network.loadingFailed":
+			delete(matched, ev.RequestID)
+		case "Page.loadEventFired":
+			// Pages often fetch their results after they load; give
+			// them as long as a render would before giving up.
+			if !loaded {
+				loaded = true
+				idle := time.AfterFunc(captureGrace, func() {
+					s.idle.Store(true)
+					s.ws.conn.SetReadDeadline(time.Now())
+				})
+				defer idle.Stop()
+			}
+		}
+	}
+}
+
+// captureGrace is how long a page may take after loading to make the
+// request a capture waits for.
+const captureGrace = 10 * time.Second
+
+// globRegexp compiles a glob in which * matches anything.
+func globRegexp(glob string) *regexp.Regexp {
+	parts := strings.Split(glob, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
@@ -0,0 +1,49 @@
+// Package buildinfo identifies the running binary. Release builds set the
+// version, and the commit when building outside a checkout, at link time:
+//
+//	go build -ldflags "-X github.com/many221/era_api_v1/internal/buildinfo.Version=1.4.0" ./cmd/server
+//
+// Otherwise the commit and its time come from the VCS stamp the go command
+// embeds when building in a git checkout.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// Set with -ldflags -X.
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// Read returns what is known about the binary.
+func Read() models.BuildInfo {
+	b := models.BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if b.Commit == "" {
+				b.Commit = s.Value
+			}
+		case "vcs.time":
+			b.CommitTime = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
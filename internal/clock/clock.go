@@ -0,0 +1,182 @@
+// Package clock provides the time used for publish decisions. Publishing is
+// gated on wall-clock time, so a trusted clock measures the host's offset
+// from an NTP server, corrects for it, and warns loudly when the host clock
+// has drifted.
+package clock
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// Clock tells the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the host clock, uncorrected.
+type System struct{}
+
+// Now returns time.Now().
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Status is the outcome of the most recent skew check.
+type Status struct {
+	Server    string     `json:"server"`
+	Offset    string     `json:"offset"` // host clock minus NTP time; positive means the host is behind
+	MaxSkew   string     `json:"maxSkew"`
+	Skewed    bool       `json:"skewed"`
+	CheckedAt *time.Time `json:"checkedAt,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Trusted is a Clock corrected by the offset last measured against an NTP
+// server. Until the first successful check it behaves like System.
+type Trusted struct {
+	server  string
+	maxSkew time.Duration
+	logger  *slog.Logger
+
+	mu        sync.RWMutex
+	offset    time.Duration
+	checkedAt time.Time
+	err       error
+}
+
+// NewTrusted returns a clock checked against the NTP server at addr
+// (host:port), warning when the host is off by more than maxSkew.
+func NewTrusted(addr string, maxSkew time.Duration, logger *slog.Logger) *Trusted {
+	return &Trusted{server: addr, maxSkew: maxSkew, logger: logger}
+}
+
+// Now returns the host time corrected by the measured offset.
+func (t *Trusted) Now() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return time.Now().Add(t.offset)
+}
+
+// Status reports the last measurement.
+func (t *Trusted) Status() Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	s := Status{
+		Server:  t.server,
+		Offset:  t.offset.String(),
+		MaxSkew: t.maxSkew.String(),
+		Skewed:  skewed(t.offset, t.maxSkew),
+	}
+	if !t.checkedAt.IsZero() {
+		at := t.checkedAt
+		s.CheckedAt = &at
+	}
+	if t.err != nil {
+		s.Error = t.err.Error()
+	}
+	return s
+}
+
+// Run checks the offset immediately and then every interval until ctx is
+// done.
+func (t *Trusted) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		t.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check measures the offset once. A failed check keeps the previous offset.
+func (t *Trusted) Check(ctx context.Context) {
+	offset, err := query(ctx, t.server)
+
+	t.mu.Lock()
+	t.err = err
+	if err == nil {
+		t.offset = offset
+		t.checkedAt = time.Now().UTC()
+	}
+	t.mu.Unlock()
+
+	switch {
+	case err != nil:
+		t.logger.Warn("clock skew check failed; publish times use the host clock",
+			"server", t.server,
+			"error", err,
+		)
+	case skewed(offset, t.maxSkew):
+		t.logger.Error("HOST CLOCK IS SKEWED: publish gating is using NTP-corrected time",
+			"server", t.server,
+			"offset", offset,
+			"max_skew", t.maxSkew,
+		)
+	}
+}
+
+func skewed(offset, max time.Duration) bool {
+	return offset > max || offset < -max
+}
+
+// ntpEpochOffset is the number of seconds between 1900 and 1970.
+const ntpEpochOffset = 2208988800
+
+// query runs one SNTP (RFC 4330) exchange with server and returns how far
+// the host clock must be adjusted to match it.
+func query(ctx context.Context, server string) (time.Duration, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, fmt.Errorf("dial ntp server: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	conn.SetDeadline(deadline)
+
+	req := make([]byte, 48)
+	req[0] = 0x1B // leap indicator 0, version 3, mode 3 (client)
+	sent := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, fmt.Errorf("query ntp server: %w", err)
+	}
+	resp := make([]byte, 48)
+	if _, err := conn.Read(resp); err != nil {
+		return 0, fmt.Errorf("read ntp response: %w", err)
+	}
+	received := time.Now()
+
+	if mode := resp[0] & 0x7; mode != 4 {
+		return 0, errors.New("unexpected ntp response mode")
+	}
+	if stratum := resp[1]; stratum == 0 || stratum > 15 {
+		return 0, errors.New("ntp server is unsynchronized")
+	}
+
+	serverReceived := ntpTime(resp[32:40])
+	serverSent := ntpTime(resp[40:48])
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+// ntpTime decodes a 64-bit NTP timestamp.
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(secs, frac*int64(time.Second)>>32)
+}
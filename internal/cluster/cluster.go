@@ -0,0 +1,166 @@
+// Package cluster lets several replicas behind a load balancer serve as one.
+// Each instance keeps its own in-memory store, so snapshots published on one
+// are broadcast over Redis pub/sub and replicated into every other, where
+// update streams and feeds see them like local ones. Read responses can also
+// be cached in Redis so a response built on one instance serves them all.
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/redis"
+)
+
+const (
+	// Channel carries snapshot events between instances.
+	Channel = "era:snapshots"
+
+	generationKey  = "era:cache:generation"
+	cacheKeyPrefix = "era:cache:"
+	publishTimeout = 5 * time.Second
+	retryDelay     = 2 * time.Second
+)
+
+// event is a snapshot published by one instance.
+type event struct {
+	Instance   string          `json:"instance"`
+	Generation uint64          `json:"generation"`
+	Results    *models.Results `json:"results"`
+}
+
+type remoteKey struct{}
+
+// Node is this instance's membership in the cluster.
+type Node struct {
+	redis    *redis.Client
+	proc     *processor.Processor
+	logger   *slog.Logger
+	instance string
+
+	// generation counts snapshots published anywhere in the cluster. It
+	// prefixes shared cache keys, so a publish anywhere retires every
+	// cached response at once.
+	generation atomic.Uint64
+}
+
+// NewNode returns a node sharing snapshots through c and replicating those
+// of other instances into proc. Register its OnSnapshot with proc and start
+// Run.
+func NewNode(c *redis.Client, proc *processor.Processor, logger *slog.Logger) *Node {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &Node{redis: c, proc: proc, logger: logger, instance: hex.EncodeToString(id)}
+}
+
+// Instance returns the random ID this instance publishes under.
+func (n *Node) Instance() string {
+	return n.instance
+}
+
+// OnSnapshot broadcasts a snapshot published on this instance. Register it
+// with Processor.OnSnapshot; snapshots replicated from elsewhere are not
+// sent on again.
+func (n *Node) OnSnapshot(ctx context.Context, results *models.Results) {
+	if ctx.Value(remoteKey{}) != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	gen, err := n.redis.Incr(ctx, generationKey)
+	if err != nil {
+		n.logger.Error("failed to publish snapshot to cluster", "county", results.County, "error", err)
+		return
+	}
+	n.advance(uint64(gen))
+	data, err := json.Marshal(event{Instance: n.instance, Generation: uint64(gen), Results: results})
+	if err == nil {
+		err = n.redis.Publish(ctx, Channel, data)
+	}
+	if err != nil {
+		n.logger.Error("failed to publish snapshot to cluster", "county", results.County, "error", err)
+	}
+}
+
+// Run receives other instances' snapshots until ctx is done, resubscribing
+// after connection failures. Snapshots published while disconnected are
+// missed until their county is next refreshed.
+func (n *Node) Run(ctx context.Context) {
+	n.logger.Info("cluster node started", "instance", n.instance, "redis", n.redis.Addr())
+	for {
+		if data, err := n.redis.Get(ctx, generationKey); err == nil {
+			if gen, err := strconv.ParseUint(string(data), 10, 64); err == nil {
+				n.advance(gen)
+			}
+		}
+		err := n.redis.Subscribe(ctx, Channel, func(msg []byte) { n.receive(ctx, msg) })
+		if ctx.Err() != nil {
+			n.logger.Info("cluster node stopped")
+			return
+		}
+		n.logger.Warn("cluster subscription lost, retrying", "error", err, "retry_in", retryDelay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (n *Node) receive(ctx context.Context, msg []byte) {
+	var e event
+	if err := json.Unmarshal(msg, &e); err != nil || e.Results == nil {
+		n.logger.Warn("ignoring malformed cluster event", "error", err)
+		return
+	}
+	n.advance(e.Generation)
+	if e.Instance == n.instance {
+		return
+	}
+	n.proc.Replicate(context.WithValue(ctx, remoteKey{}, e.Instance), e.Results)
+	n.logger.Debug("replicated snapshot", "county", e.Results.County, "from", e.Instance)
+}
+
+// advance raises the generation to gen if it is behind.
+func (n *Node) advance(gen uint64) {
+	for {
+		cur := n.generation.Load()
+		if gen <= cur || n.generation.CompareAndSwap(cur, gen) {
+			return
+		}
+	}
+}
+
+// Get returns the cached response stored under key in the current
+// generation. Redis being unreachable counts as a miss.
+func (n *Node) Get(ctx context.Context, key string) ([]byte, bool) {
+	data, err := n.redis.Get(ctx, n.cacheKey(key))
+	if err != nil {
+		if !errors.Is(err, redis.ErrNil) {
+			n.logger.Debug("shared cache read failed", "error", err)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+// Set caches data under key in the current generation for ttl.
+func (n *Node) Set(ctx context.Context, key string, data []byte, ttl time.Duration) {
+	if err := n.redis.Set(ctx, n.cacheKey(key), data, ttl); err != nil {
+		n.logger.Debug("shared cache write failed", "error", err)
+	}
+}
+
+func (n *Node) cacheKey(key string) string {
+	return cacheKeyPrefix + strconv.FormatUint(n.generation.Load(), 10) + ":" + key
+}
@@ -0,0 +1,166 @@
+// Package contestrules maps the contest titles each county uses onto
+// canonical contest IDs so aggregation and the API agree on identifiers.
+package contestrules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// Engine evaluates the mapping rules of an election, by default the one it
+// was created for.
+type Engine struct {
+	store    store.Store
+	election string
+
+	mu      sync.Mutex
+	regexps map[string]*regexp.Regexp
+}
+
+// NewEngine returns an Engine applying the rules stored for election.
+func NewEngine(st store.Store, election string) *Engine {
+	return &Engine{store: st, election: election, regexps: make(map[string]*regexp.Regexp)}
+}
+
+// Validate checks that a rule is well formed before it is stored.
+func Validate(r models.ContestRule) error {
+	return validate.ContestRule(r)
+}
+
+// Resolve returns the first rule of election matching a contest title
+// reported by county. An empty election is the engine's.
+func (e *Engine) Resolve(election, county, title string) (models.ContestRule, bool) {
+	if election == "" {
+		election = e.election
+	}
+	countyKey := store.CountyKey(county)
+	for _, r := range e.store.ContestRules(election) {
+		if r.County != "" && store.CountyKey(r.County) != countyKey {
+			continue
+		}
+		if e.matches(r, title) {
+			return r, true
+		}
+	}
+	return models.ContestRule{}, false
+}
+
+func (e *Engine) matches(r models.ContestRule, title string) bool {
+	t, p := fold(title), fold(r.Pattern)
+	switch r.Match {
+	case models.RuleExact:
+		return t == p
+	case models.RulePrefix:
+		return strings.HasPrefix(t, p)
+	case models.RuleContains:
+		return strings.Contains(t, p)
+	case models.RuleRegex:
+		re := e.compiled(r.Pattern)
+		return re != nil && re.MatchString(title)
+	}
+	return false
+}
+
+// compiled caches compiled patterns; invalid ones never match.
+func (e *Engine) compiled(pattern string) *regexp.Regexp {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	re, ok := e.regexps[pattern]
+	if !ok {
+		re, _ = regexp.Compile(pattern)
+		e.regexps[pattern] = re
+	}
+	return re
+}
+
+// Apply rewrites contest IDs and titles of freshly parsed contests according
+// to the rules. It is meant to be registered with Processor.AddTransform
+// ahead of anything that depends on contest IDs.
+func (e *Engine) Apply(req models.ProcessRequest, contests []models.Contest) []models.Contest {
+	for i := range contests {
+		r, ok := e.Resolve(req.Election, req.CountyName, contests[i].Title)
+		if !ok {
+			continue
+		}
+		contests[i].ID = r.CanonicalID
+		if r.CanonicalTitle != "" && r.CanonicalTitle != contests[i].Title {
+			contests[i].RawTitle = contests[i].Title
+			contests[i].Title = r.CanonicalTitle
+		}
+		if r.Threshold != "" {
+			contests[i].Measure = &models.MeasureResult{Threshold: r.Threshold}
+		}
+	}
+	return contests
+}
+
+// LoadRules seeds rules from a JSON array. Rules without an election are
+// assigned to election.
+func LoadRules(path string, st store.Store, election string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("read contest rules: %w", err)
+	}
+
+	var rules []models.ContestRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return 0, fmt.Errorf("decode contest rules: %w", err)
+	}
+	for i, r := range rules {
+		if err := Validate(r); err != nil {
+			return 0, fmt.Errorf("rule %d: %w", i, err)
+		}
+		if r.Election == "" {
+			r.Election = election
+		}
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("%s-%d", r.CanonicalID, i)
+		}
+		st.SaveContestRule(r)
+	}
+	return len(rules), nil
+}
+
+// CopyRules copies the rules of election from into election to, skipping
+// those to already has a rule for the same county, match and pattern. It
+// returns how many it copied.
+func CopyRules(st store.Store, from, to string) int {
+	have := make(map[string]bool)
+	for _, r := range st.ContestRules(to) {
+		have[ruleKey(r)] = true
+	}
+	copied := 0
+	for _, r := range st.ContestRules(from) {
+		if have[ruleKey(r)] {
+			continue
+		}
+		have[ruleKey(r)] = true
+		r.ID = to + "-" + r.ID
+		r.Election = to
+		st.SaveContestRule(r)
+		copied++
+	}
+	return copied
+}
+
+func ruleKey(r models.ContestRule) string {
+	pattern := r.Pattern
+	if r.Match != models.RuleRegex {
+		pattern = fold(pattern)
+	}
+	return strings.Join([]string{store.CountyKey(r.County), r.Match, pattern}, "\x00")
+}
+
+// fold normalizes case and spacing for non-regex comparisons.
+func fold(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,216 @@
+// Package demo runs a server on a bundled sample election: a few counties
+// with their contests, registered at startup, and a scripted sequence of
+// updates fed through the parse pipeline as counting progresses, so the
+// API, renders and feeds can be tried without any county sources.
+package demo
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// DefaultInterval is how long the demo waits between updates by default.
+const DefaultInterval = 30 * time.Second
+
+//go:embed election.json
+var scenarioJSON []byte
+
+// scenario is the sample election. Updates are the fraction of ballots
+// counted at each step; a county starting late reports its first update
+// at step Start.
+type scenario struct {
+	Election models.Election `json:"election"`
+	Updates  []float64       `json:"updates"`
+	Counties []county        `json:"counties"`
+}
+
+type county struct {
+	Name             string    `json:"name"`
+	Registered       int       `json:"registered"`
+	Turnout          float64   `json:"turnout"` // of registered voters, once counting ends
+	Precincts        int       `json:"precincts"`
+	Start            int       `json:"start"`
+	ContentType      string    `json:"contentType"`
+	MeasureThreshold string    `json:"measureThreshold"`
+	Contests         []contest `json:"contests"`
+}
+
+type contest struct {
+	Title   string   `json:"title"`
+	Choices []choice `json:"choices"`
+}
+
+// choice is a candidate's share of the ballots counted in the first half
+// of counting and of those counted later, which differ as early mail
+// ballots lean differently from those counted last.
+type choice struct {
+	Name  string  `json:"name"`
+	Party string  `json:"party"`
+	Early float64 `json:"early"`
+	Late  float64 `json:"late"`
+}
+
+var sample = func() scenario {
+	var sc scenario
+	if err := json.Unmarshal(scenarioJSON, &sc); err != nil {
+		panic("demo: bad election.json: " + err.Error())
+	}
+	return sc
+}()
+
+// Election returns the sample election, which the server makes its default
+// in demo mode.
+func Election() models.Election {
+	return sample.Election
+}
+
+// Demo plays the sample election into a store.
+type Demo struct {
+	store     store.Store
+	processor *processor.Processor
+	logger    *slog.Logger
+	interval  time.Duration
+}
+
+// New returns a Demo publishing through p into st, an update every
+// interval, or DefaultInterval if it isn't positive.
+func New(st store.Store, p *processor.Processor, logger *slog.Logger, interval time.Duration) *Demo {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Demo{store: st, processor: p, logger: logger, interval: interval}
+}
+
+// Setup registers the sample election and its counties. The counties have
+// no file link, so the scheduler leaves them to Run.
+func (d *Demo) Setup() {
+	d.store.SaveElection(sample.Election)
+	now := time.Now().UTC()
+	for _, c := range sample.Counties {
+		d.store.SaveCounty(c.source(now))
+	}
+}
+
+// Run publishes the scripted updates until they run out or ctx is
+// cancelled. The counties' first results are published at once.
+func (d *Demo) Run(ctx context.Context) {
+	steps := len(sample.Updates)
+	for _, c := range sample.Counties {
+		steps = max(steps, c.Start+len(sample.Updates))
+	}
+	d.logger.Info("demo election started", "election", sample.Election.ID, "counties", len(sample.Counties), "updates", steps, "interval", d.interval)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for step := 0; step < steps; step++ {
+		if step > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+		for _, c := range sample.Counties {
+			if i := step - c.Start; i >= 0 && i < len(sample.Updates) {
+				d.publish(ctx, c, sample.Updates[i])
+			}
+		}
+	}
+	d.logger.Info("demo election fully reported", "election", sample.Election.ID)
+}
+
+func (d *Demo) publish(ctx context.Context, c county, counted float64) {
+	data, err := c.report(counted)
+	if err != nil {
+		d.logger.Error("failed to build demo source", "county", c.Name, "error", err)
+		return
+	}
+	req := c.source(time.Time{}).ProcessRequest()
+	req.FileLink = "demo:" + models.Slug(c.Name) + ".xml"
+	if _, err := d.processor.ProcessUpload(ctx, req, data, nil); err != nil {
+		d.logger.Error("demo update failed", "county", c.Name, "error", err)
+		return
+	}
+	d.logger.Info("demo update published", "county", c.Name, "counted", fmt.Sprintf("%.0f%%", counted*100))
+}
+
+func (c county) source(registeredAt time.Time) models.CountySource {
+	contentType := c.ContentType
+	if contentType == "" {
+		contentType = models.ContentTypeCandidate
+	}
+	return models.CountySource{
+		Name:             c.Name,
+		ContentType:      contentType,
+		ParseMethod:      models.ParseMethodXML,
+		Election:         sample.Election.ID,
+		MeasureThreshold: c.MeasureThreshold,
+		RegisteredAt:     registeredAt,
+	}
+}
+
+// The report is written in the Clarity ENR detail.xml layout many
+// counties publish.
+type enrResult struct {
+	XMLName      xml.Name   `xml:"ElectionResult"`
+	ElectionName string     `xml:"ElectionName"`
+	VoterTurnout enrTurnout `xml:"VoterTurnout"`
+	Contests     []enrContest
+}
+
+type enrTurnout struct {
+	TotalVoters int `xml:"totalVoters,attr"`
+	BallotsCast int `xml:"ballotsCast,attr"`
+}
+
+type enrContest struct {
+	XMLName                xml.Name    `xml:"Contest"`
+	Text                   string      `xml:"text,attr"`
+	PrecinctsReported      int         `xml:"precinctsReported,attr"`
+	PrecinctsParticipating int         `xml:"precinctsParticipating,attr"`
+	Choices                []enrChoice `xml:"Choice"`
+}
+
+type enrChoice struct {
+	Text       string `xml:"text,attr"`
+	Party      string `xml:"party,attr,omitempty"`
+	TotalVotes int    `xml:"totalVotes,attr"`
+}
+
+// report is c's results feed with the fraction counted of its ballots
+// counted.
+func (c county) report(counted float64) ([]byte, error) {
+	ballots := float64(c.Registered) * c.Turnout
+	early, late := min(counted, 0.5), max(counted-0.5, 0)
+	doc := enrResult{
+		ElectionName: sample.Election.Name,
+		VoterTurnout: enrTurnout{TotalVoters: c.Registered, BallotsCast: int(math.Round(ballots * counted))},
+	}
+	for _, ct := range c.Contests {
+		out := enrContest{
+			Text:                   ct.Title,
+			PrecinctsReported:      int(math.Round(float64(c.Precincts) * counted)),
+			PrecinctsParticipating: c.Precincts,
+		}
+		for _, ch := range ct.Choices {
+			votes := ballots * (ch.Early*early + ch.Late*late)
+			out.Choices = append(out.Choices, enrChoice{Text: ch.Name, Party: ch.Party, TotalVotes: int(math.Round(votes))})
+		}
+		doc.Contests = append(doc.Contests, out)
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,157 @@
+// Package districts imports district definitions, the precincts each
+// congressional, legislative or school board district is drawn from, so
+// precinct results can be rolled up into district results.
+package districts
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// Formats districts can be imported from.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// csvColumns are the columns of a CSV district file. Each row adds a
+// precinct to its district, whose other columns are taken from its first
+// row.
+var csvColumns = []string{"district_id", "district", "type", "county", "precinct"}
+
+// Parse decodes districts in format, or in the format their content looks
+// like if format is empty. JSON is an array of districts, or one; CSV has
+// a header row naming csvColumns. Districts without an ID get the slug of
+// their name, and those with the same ID are merged.
+func Parse(data []byte, format string) ([]models.District, error) {
+	if format == "" {
+		format = FormatCSV
+		if b := bytes.TrimSpace(data); len(b) > 0 && (b[0] == '{' || b[0] == '[') {
+			format = FormatJSON
+		}
+	}
+
+	var list []models.District
+	var err error
+	switch format {
+	case FormatJSON:
+		list, err = parseJSON(data)
+	case FormatCSV:
+		list, err = parseCSV(data)
+	default:
+		return nil, fmt.Errorf("unknown district format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	list = merge(list)
+	if len(list) == 0 {
+		return nil, errors.New("no districts to import")
+	}
+	if err := validate.Districts(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func parseJSON(data []byte) ([]models.District, error) {
+	var list []models.District
+	if b := bytes.TrimSpace(data); len(b) > 0 && b[0] == '{' {
+		var d models.District
+		if err := json.Unmarshal(b, &d); err != nil {
+			return nil, fmt.Errorf("decode districts: %w", err)
+		}
+		return []models.District{d}, nil
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode districts: %w", err)
+	}
+	return list, nil
+}
+
+func parseCSV(data []byte) ([]models.District, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read districts header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"county", "precinct"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("districts header has no %s column; columns are %s", name, strings.Join(csvColumns, ", "))
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var list []models.District
+	for line := 2; ; line++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read districts: %w", err)
+		}
+		d := models.District{
+			ID:   field(row, "district_id"),
+			Name: field(row, "district"),
+			Type: field(row, "type"),
+			Precincts: []models.DistrictPrecinct{{
+				County:   field(row, "county"),
+				Precinct: field(row, "precinct"),
+			}},
+		}
+		if d.Name == "" && d.ID == "" {
+			return nil, fmt.Errorf("districts line %d: district is required", line)
+		}
+		list = append(list, d)
+	}
+	return list, nil
+}
+
+// merge folds the precincts of districts with the same ID into the first
+// of them, filling in missing IDs from names and normalizing types.
+func merge(list []models.District) []models.District {
+	out := []models.District{}
+	index := make(map[string]int)
+	for _, d := range list {
+		d.Name = strings.TrimSpace(d.Name)
+		d.Type = strings.ToLower(strings.TrimSpace(d.Type))
+		if d.ID == "" {
+			d.ID = models.Slug(d.Name)
+		}
+		i, ok := index[d.ID]
+		if !ok {
+			index[d.ID] = len(out)
+			out = append(out, d)
+			continue
+		}
+		if out[i].Name == "" {
+			out[i].Name = d.Name
+		}
+		if out[i].Type == "" {
+			out[i].Type = d.Type
+		}
+		out[i].Precincts = append(out[i].Precincts, d.Precincts...)
+	}
+	return out
+}
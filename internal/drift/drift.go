@@ -0,0 +1,295 @@
+// Package drift decides when a freshly parsed snapshot differs too much from
+// the county's previous one to publish unreviewed, and diffs the raw tables
+// of the two sources to suggest which layout change most likely caused it:
+// a column added or removed, a header renamed, a table retitled.
+package drift
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// maxReasons bounds how many contests a Check result names individually.
+const maxReasons = 10
+
+// Policy sets how far totals may move between two snapshots of a county.
+type Policy struct {
+	// MaxDrop is the largest fraction a contest's total may fall by.
+	MaxDrop float64
+	// MaxGrowth is the largest factor a contest's total may rise by.
+	MaxGrowth float64
+	// MinVotes skips contests whose previous total was below it, since
+	// the first batches of a count swing wildly.
+	MinVotes int
+}
+
+// DefaultPolicy holds snapshots where a contest lost a quarter of its votes
+// or grew tenfold.
+var DefaultPolicy = Policy{MaxDrop: 0.25, MaxGrowth: 10, MinVotes: 100}
+
+// Check compares cur with prev and returns why cur looks drifted, or nil
+// when it doesn't.
+func (p Policy) Check(prev, cur *models.Results) []string {
+	var (
+		reasons []string
+		more    int
+		checked int
+		missing int
+	)
+	note := func(format string, args ...any) {
+		if len(reasons) < maxReasons {
+			reasons = append(reasons, fmt.Sprintf(format, args...))
+		} else {
+			more++
+		}
+	}
+
+	for _, was := range prev.Contests {
+		before := was.TotalVotes()
+		if before < p.MinVotes {
+			continue
+		}
+		checked++
+		now, ok := cur.ContestByID(was.ID)
+		if !ok {
+			missing++
+			continue
+		}
+		after := now.TotalVotes()
+		switch {
+		case p.MaxDrop > 0 && float64(after) < float64(before)*(1-p.MaxDrop):
+			note("%q total fell from %d to %d", was.Title, before, after)
+		case p.MaxGrowth > 0 && float64(after) > float64(before)*p.MaxGrowth:
+			note("%q total rose from %d to %d", was.Title, before, after)
+		}
+	}
+	// A contest or two can legitimately drop off a results page; most of
+	// them disappearing means the page stopped being read correctly.
+	if missing > 0 && missing*2 > checked {
+		reasons = append(reasons, fmt.Sprintf("%d of %d contests are missing", missing, checked))
+	}
+	if more > 0 {
+		reasons = append(reasons, fmt.Sprintf("and %d more contests", more))
+	}
+	return reasons
+}
+
+// Suggest diffs the sample of the previous snapshot's source against the
+// current one. Either may be nil, in which case there is nothing to compare.
+func Suggest(prev, cur *models.SourceSample) []models.MappingSuggestion {
+	out := []models.MappingSuggestion{}
+	if prev == nil || cur == nil {
+		return out
+	}
+
+	// Tables are paired by name; repeated names pair up in order.
+	byName := make(map[string][]int)
+	for i, t := range cur.Tables {
+		byName[t.Name] = append(byName[t.Name], i)
+	}
+	paired := make([]bool, len(cur.Tables))
+	var removed []models.SampleTable
+	for _, was := range prev.Tables {
+		idx := byName[was.Name]
+		if len(idx) == 0 {
+			removed = append(removed, was)
+			continue
+		}
+		byName[was.Name] = idx[1:]
+		paired[idx[0]] = true
+		out = append(out, compareTables(was, cur.Tables[idx[0]])...)
+	}
+
+	var added []models.SampleTable
+	for i, t := range cur.Tables {
+		if !paired[i] {
+			added = append(added, t)
+		}
+	}
+	for _, was := range removed {
+		if i := similarTable(was, added); i >= 0 {
+			out = append(out, models.MappingSuggestion{
+				Kind:    models.DriftTableRenamed,
+				Table:   was.Name,
+				Message: fmt.Sprintf("table %q looks like it was retitled %q; add a contest rule mapping the new title if it's the same contest", was.Name, added[i].Name),
+			})
+			added = append(added[:i], added[i+1:]...)
+			continue
+		}
+		out = append(out, models.MappingSuggestion{
+			Kind:    models.DriftTableRemoved,
+			Table:   was.Name,
+			Message: fmt.Sprintf("table %q is no longer in the source", was.Name),
+		})
+	}
+	for _, t := range added {
+		out = append(out, models.MappingSuggestion{
+			Kind:    models.DriftTableAdded,
+			Table:   t.Name,
+			Message: fmt.Sprintf("table %q is new in the source", t.Name),
+		})
+	}
+	return out
+}
+
+// compareTables diffs two samples of the same table.
+func compareTables(was, now models.SampleTable) []models.MappingSuggestion {
+	var out []models.MappingSuggestion
+	add := func(kind, format string, args ...any) {
+		out = append(out, models.MappingSuggestion{Kind: kind, Table: now.Name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(was.Header) > 0 && len(now.Header) > 0 {
+		oldCols := columnSet(was.Header)
+		newCols := columnSet(now.Header)
+		if len(was.Header) == len(now.Header) {
+			for i := range now.Header {
+				o, n := fold(was.Header[i]), fold(now.Header[i])
+				if o != n && !newCols[o] && !oldCols[n] {
+					add(models.DriftHeaderRenamed, "header %q in column %d was renamed %q", was.Header[i], i+1, now.Header[i])
+				}
+			}
+		}
+		for i, h := range now.Header {
+			if !oldCols[fold(h)] && len(was.Header) != len(now.Header) {
+				add(models.DriftColumnAdded, "column %q was added at position %d; columns after it have moved right", h, i+1)
+			}
+		}
+		for i, h := range was.Header {
+			if !newCols[fold(h)] && len(was.Header) != len(now.Header) {
+				add(models.DriftColumnRemoved, "column %q (position %d) was removed; columns after it have moved left", h, i+1)
+			}
+		}
+	} else if o, n := rowWidth(was.Rows), rowWidth(now.Rows); o > 0 && n > 0 && o != n {
+		kind := models.DriftColumnAdded
+		if n < o {
+			kind = models.DriftColumnRemoved
+		}
+		add(kind, "rows now have %d cells, previously %d", n, o)
+	}
+
+	// The same candidate read from a different column means the vote count
+	// now comes from somewhere else, whatever the headers say.
+	if o, n, ok := voteColumnShift(was.Rows, now.Rows); ok {
+		add(models.DriftColumnShifted, "vote counts are now read from column %d (%s), previously column %d (%s)",
+			n+1, columnName(now.Header, n), o+1, columnName(was.Header, o))
+	}
+	return out
+}
+
+// voteColumnShift finds rows labelled the same in both samples and reports
+// the columns their vote counts were taken from, if most of them moved.
+func voteColumnShift(was, now [][]string) (from, to int, ok bool) {
+	oldCol := make(map[string]int)
+	for _, row := range was {
+		if len(row) > 0 {
+			oldCol[fold(row[0])] = firstNumeric(row)
+		}
+	}
+	shifts := make(map[[2]int]int)
+	same, total := 0, 0
+	for _, row := range now {
+		if len(row) == 0 {
+			continue
+		}
+		o, found := oldCol[fold(row[0])]
+		n := firstNumeric(row)
+		if !found || o < 0 || n < 0 {
+			continue
+		}
+		total++
+		if o == n {
+			same++
+			continue
+		}
+		shifts[[2]int{o, n}]++
+	}
+	best, count := [2]int{}, 0
+	for k, c := range shifts {
+		if c > count || (c == count && k[0] < best[0]) {
+			best, count = k, c
+		}
+	}
+	if total == 0 || count*2 <= total || same >= count {
+		return 0, 0, false
+	}
+	return best[0], best[1], true
+}
+
+// similarTable returns the index of the table in candidates with the same
+// header as t and a similar number of rows, or -1.
+func similarTable(t models.SampleTable, candidates []models.SampleTable) int {
+	for i, c := range candidates {
+		if strings.Join(foldAll(c.Header), "\x00") != strings.Join(foldAll(t.Header), "\x00") {
+			continue
+		}
+		if c.RowCount*2 >= t.RowCount && t.RowCount*2 >= c.RowCount {
+			return i
+		}
+	}
+	return -1
+}
+
+// firstNumeric returns the column the parsers take a row's vote count from:
+// the first cell after the label that is a whole number.
+func firstNumeric(row []string) int {
+	for i := 1; i < len(row); i++ {
+		cell := strings.ReplaceAll(strings.TrimSpace(row[i]), ",", "")
+		if cell == "" {
+			continue
+		}
+		numeric := true
+		for _, r := range cell {
+			if r < '0' || r > '9' {
+				numeric = false
+				break
+			}
+		}
+		if numeric {
+			return i
+		}
+	}
+	return -1
+}
+
+// rowWidth returns the most common number of cells in rows.
+func rowWidth(rows [][]string) int {
+	counts := make(map[int]int)
+	best := 0
+	for _, r := range rows {
+		counts[len(r)]++
+		if counts[len(r)] > counts[best] {
+			best = len(r)
+		}
+	}
+	return best
+}
+
+func columnName(header []string, i int) string {
+	if i >= 0 && i < len(header) && header[i] != "" {
+		return fmt.Sprintf("%q", header[i])
+	}
+	return "unlabelled"
+}
+
+func columnSet(header []string) map[string]bool {
+	set := make(map[string]bool, len(header))
+	for _, h := range header {
+		set[fold(h)] = true
+	}
+	return set
+}
+
+func foldAll(list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = fold(s)
+	}
+	return out
+}
+
+func fold(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,215 @@
+// Package dump splits the published dataset into content-addressed, gzip
+// compressed NDJSON parts described by a manifest, so bulk consumers can
+// verify each part and resume or retry them independently.
+package dump
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+const (
+	// DefaultPartSize is the uncompressed size after which a part is cut.
+	DefaultPartSize = 4 << 20
+
+	// maxSnapshots bounds how many snapshots are kept, so downloads started
+	// against a superseded manifest can usually still finish.
+	maxSnapshots = 16
+)
+
+// Part is one chunk of a snapshot: whole counties, one per line.
+type Part struct {
+	Index    int      `json:"index"`
+	Counties []string `json:"counties"`
+	Size     int      `json:"size"` // compressed bytes
+	SHA256   string   `json:"sha256"`
+
+	data []byte
+}
+
+// Data returns the gzip-compressed NDJSON body of the part.
+func (p Part) Data() []byte {
+	return p.data
+}
+
+// Snapshot is an immutable chunking of the dataset as seen by one view.
+type Snapshot struct {
+	ID          string    `json:"id"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	Parts       []Part    `json:"parts"`
+
+	view    string
+	version uint64
+}
+
+// RecordFunc returns the line written for a county's results, already
+// shaped for the caller. ok is false for counties that should be skipped.
+type RecordFunc func(results *models.Results) (record any, ok bool)
+
+// Builder builds snapshots from a store and caches them until the store
+// changes.
+type Builder struct {
+	store    store.Store
+	partSize int
+
+	mu        sync.Mutex
+	latest    map[string]*Snapshot // by view
+	snapshots map[string]*Snapshot // by ID
+	order     []string
+}
+
+// NewBuilder returns a Builder cutting parts of roughly partSize
+// uncompressed bytes, or DefaultPartSize if partSize is not positive.
+func NewBuilder(st store.Store, partSize int) *Builder {
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	return &Builder{
+		store:     st,
+		partSize:  partSize,
+		latest:    make(map[string]*Snapshot),
+		snapshots: make(map[string]*Snapshot),
+	}
+}
+
+// Latest returns the current snapshot for view, building it with record if
+// the store changed since the last one. view identifies what the caller may
+// see; snapshots are never shared between views.
+func (b *Builder) Latest(view string, record RecordFunc) (*Snapshot, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	version := b.store.Version()
+	if s, ok := b.latest[view]; ok && s.version == version {
+		return s, nil
+	}
+
+	s, err := b.build(view, record)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := b.snapshots[s.ID]; !ok {
+		b.snapshots[s.ID] = s
+		b.order = append(b.order, s.ID)
+		for len(b.order) > maxSnapshots {
+			delete(b.snapshots, b.order[0])
+			b.order = b.order[1:]
+		}
+	}
+	b.latest[view] = s
+	return s, nil
+}
+
+// Snapshot returns a retained snapshot by ID, provided it was built for view.
+func (b *Builder) Snapshot(view, id string) (*Snapshot, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.snapshots[id]
+	if !ok || s.view != view {
+		return nil, false
+	}
+	return s, true
+}
+
+// build reads every county in one step, so a snapshot never mixes results
+// from before and after a publish.
+func (b *Builder) build(view string, record RecordFunc) (*Snapshot, error) {
+	snap := b.store.ElectionSnapshot("")
+	s := &Snapshot{GeneratedAt: time.Now().UTC(), view: view, version: snap.Version}
+
+	var buf bytes.Buffer
+	var counties []string
+	for _, results := range snap.Results {
+		county := store.CountyKey(results.County)
+		rec, ok := record(results)
+		if !ok {
+			continue
+		}
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		counties = append(counties, county)
+
+		if buf.Len() >= b.partSize {
+			if err := s.cut(&buf, counties); err != nil {
+				return nil, err
+			}
+			counties = nil
+		}
+	}
+	if len(counties) > 0 {
+		if err := s.cut(&buf, counties); err != nil {
+			return nil, err
+		}
+	}
+
+	// The view is part of the ID so identical data seen by two views still
+	// yields distinct snapshots.
+	id := sha256.New()
+	id.Write([]byte(view))
+	for _, p := range s.Parts {
+		id.Write([]byte(p.SHA256))
+	}
+	s.ID = hex.EncodeToString(id.Sum(nil))[:16]
+	return s, nil
+}
+
+// cut compresses buf into a new part and resets it.
+func (s *Snapshot) cut(buf *bytes.Buffer, counties []string) error {
+	var out bytes.Buffer
+	gz := gzip.NewWriter(&out)
+	// A zero header timestamp keeps parts byte-identical across rebuilds of
+	// the same data, so their hashes and the snapshot ID are stable.
+	gz.ModTime = time.Time{}
+	if _, err := gz.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	buf.Reset()
+
+	sum := sha256.Sum256(out.Bytes())
+	s.Parts = append(s.Parts, Part{
+		Index:    len(s.Parts),
+		Counties: counties,
+		Size:     out.Len(),
+		SHA256:   hex.EncodeToString(sum[:]),
+		data:     out.Bytes(),
+	})
+	return nil
+}
+
+// Manifest is the description of a snapshot served to clients.
+type Manifest struct {
+	ID          string         `json:"id"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Parts       []ManifestPart `json:"parts"`
+}
+
+// ManifestPart is a Part with the URL it can be downloaded from.
+type ManifestPart struct {
+	Part
+	URL string `json:"url"`
+}
+
+// NewManifest describes s, building part URLs with url.
+func NewManifest(s *Snapshot, url func(snapshotID string, index int) string) Manifest {
+	m := Manifest{ID: s.ID, GeneratedAt: s.GeneratedAt, Parts: make([]ManifestPart, len(s.Parts))}
+	for i, p := range s.Parts {
+		m.Parts[i] = ManifestPart{Part: p, URL: url(s.ID, p.Index)}
+	}
+	return m
+}
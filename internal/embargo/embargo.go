@@ -0,0 +1,187 @@
+// Package embargo delays what partner tiers see of new results. A tier is
+// served a county's snapshot only once it is older than the tier's delay,
+// which can be set per contest, so real-time access can be offered apart
+// from delayed access under tiered partner agreements.
+package embargo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Anonymous names the tier callers without an API key are in, if one is
+// configured.
+const Anonymous = "anonymous"
+
+// MaxDelay is the longest delay a tier may have, as older snapshots aren't
+// kept.
+const MaxDelay = store.HistoryWindow
+
+// Tier is an access tier API keys are assigned to.
+type Tier struct {
+	Name string `json:"name"`
+
+	// Delay is how old a snapshot must be before the tier sees it, as a Go
+	// duration such as "5m". Empty is real time.
+	Delay string `json:"delay,omitempty"`
+
+	// Contests overrides Delay for the contest IDs it lists, such as a
+	// headline race embargoed for longer.
+	Contests map[string]string `json:"contests,omitempty"`
+
+	delay    time.Duration
+	contests []contestDelay // sorted by ID
+}
+
+type contestDelay struct {
+	id    string
+	delay time.Duration
+}
+
+// Tiers is the set of configured access tiers.
+type Tiers struct {
+	byName map[string]*Tier
+}
+
+// LoadTiers reads a JSON array of tiers from path.
+func LoadTiers(path string) (*Tiers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read access tiers: %w", err)
+	}
+	var list []Tier
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode access tiers: %w", err)
+	}
+	return NewTiers(list)
+}
+
+// NewTiers indexes list by name, parsing each delay.
+func NewTiers(list []Tier) (*Tiers, error) {
+	t := &Tiers{byName: make(map[string]*Tier, len(list))}
+	for i, tier := range list {
+		if tier.Name == "" {
+			return nil, fmt.Errorf("access tier %d: name is required", i)
+		}
+		var err error
+		if tier.delay, err = parseDelay(tier.Delay); err != nil {
+			return nil, fmt.Errorf("access tier %q: %w", tier.Name, err)
+		}
+		for id, v := range tier.Contests {
+			d, err := parseDelay(v)
+			if err != nil {
+				return nil, fmt.Errorf("access tier %q contest %q: %w", tier.Name, id, err)
+			}
+			tier.contests = append(tier.contests, contestDelay{id: id, delay: d})
+		}
+		sort.Slice(tier.contests, func(a, b int) bool { return tier.contests[a].id < tier.contests[b].id })
+		t.byName[tier.Name] = &tier
+	}
+	return t, nil
+}
+
+func parseDelay(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid delay %q", v)
+	}
+	if d < 0 || d > MaxDelay {
+		return 0, fmt.Errorf("delay %q must be between 0 and %s", v, MaxDelay)
+	}
+	return d, nil
+}
+
+// Get returns the named tier.
+func (t *Tiers) Get(name string) (*Tier, bool) {
+	if t == nil {
+		return nil, false
+	}
+	tier, ok := t.byName[name]
+	return tier, ok
+}
+
+// Delayed reports whether the tier sees anything later than real time.
+func (t *Tier) Delayed() bool {
+	if t == nil {
+		return false
+	}
+	if t.delay > 0 {
+		return true
+	}
+	for _, c := range t.contests {
+		if c.delay > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Longest returns the longest delay anything is shown to the tier with.
+func (t *Tier) Longest() time.Duration {
+	d := t.delay
+	for _, c := range t.contests {
+		d = max(d, c.delay)
+	}
+	return d
+}
+
+// Results returns a county's results as the tier sees them at now. at
+// looks up the county's snapshot as of a time. Contests with their own
+// delay are taken from the snapshot live that long ago, or left out if
+// there was none; the rest, and the hash, are from the snapshot live the
+// tier's delay ago. It returns the error of at if the tier sees nothing.
+func (t *Tier) Results(now time.Time, at func(time.Time) (*models.Results, error)) (*models.Results, error) {
+	base, err := at(now.Add(-t.delay))
+	for _, c := range t.contests {
+		if c.delay == t.delay {
+			continue
+		}
+		snap, snapErr := at(now.Add(-c.delay))
+		if base == nil {
+			if snapErr != nil {
+				continue
+			}
+			// Only contests with a shorter delay are out yet.
+			shell := *snap
+			shell.Contests = nil
+			base, err = &shell, nil
+		}
+		var contest *models.Contest
+		if snapErr == nil {
+			contest, _ = snap.ContestByID(c.id)
+		}
+		base.Contests = replaceContest(base.Contests, c.id, contest)
+	}
+	if base == nil {
+		return nil, err
+	}
+	return base, nil
+}
+
+// replaceContest replaces the contest id in contests with c, appending c if
+// id isn't there and removing the contest if c is nil.
+func replaceContest(contests []models.Contest, id string, c *models.Contest) []models.Contest {
+	for i := range contests {
+		if contests[i].ID != id {
+			continue
+		}
+		if c == nil {
+			return append(contests[:i], contests[i+1:]...)
+		}
+		contests[i] = *c
+		return contests
+	}
+	if c != nil {
+		contests = append(contests, *c)
+	}
+	return contests
+}
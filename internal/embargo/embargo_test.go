@@ -0,0 +1,167 @@
+package embargo
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+var errNone = errors.New("no snapshot")
+
+// history returns an at func over snapshots sorted by ParsedAt, copying
+// what it returns as the store does.
+func history(snaps ...models.Results) func(time.Time) (*models.Results, error) {
+	return func(at time.Time) (*models.Results, error) {
+		for i := len(snaps) - 1; i >= 0; i-- {
+			if !snaps[i].ParsedAt.After(at) {
+				r := snaps[i]
+				r.Contests = slices.Clone(r.Contests)
+				return &r, nil
+			}
+		}
+		return nil, errNone
+	}
+}
+
+func snapshot(hash string, at time.Time, votes map[string]int) models.Results {
+	r := models.Results{County: "Adams", Hash: hash, ParsedAt: at}
+	for _, id := range []string{"gov", "mayor"} {
+		if v, ok := votes[id]; ok {
+			r.Contests = append(r.Contests, models.Contest{ID: id, Candidates: []models.Candidate{{Name: "A", Votes: v}}})
+		}
+	}
+	return r
+}
+
+func TestNewTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		tier    Tier
+		wantErr string
+	}{
+		{"real time", Tier{Name: "live"}, ""},
+		{"delayed", Tier{Name: "free", Delay: "5m", Contests: map[string]string{"gov": "1h"}}, ""},
+		{"longest delay", Tier{Name: "free", Delay: MaxDelay.String()}, ""},
+		{"no name", Tier{Delay: "5m"}, "name is required"},
+		{"invalid delay", Tier{Name: "free", Delay: "soon"}, "invalid delay"},
+		{"negative delay", Tier{Name: "free", Delay: "-1m"}, "must be between"},
+		{"delay too long", Tier{Name: "free", Delay: (MaxDelay + time.Minute).String()}, "must be between"},
+		{"invalid contest delay", Tier{Name: "free", Contests: map[string]string{"gov": "soon"}}, `contest "gov"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiers, err := NewTiers([]Tier{tt.tier})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewTiers: %v", err)
+				}
+				if _, ok := tiers.Get(tt.tier.Name); !ok {
+					t.Errorf("Get(%q) found nothing", tt.tier.Name)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewTiers error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTierDelay(t *testing.T) {
+	tests := []struct {
+		name        string
+		tier        Tier
+		wantDelayed bool
+		wantLongest time.Duration
+	}{
+		{"real time", Tier{Name: "t"}, false, 0},
+		{"delayed", Tier{Name: "t", Delay: "5m"}, true, 5 * time.Minute},
+		{"contest delayed", Tier{Name: "t", Contests: map[string]string{"gov": "15m"}}, true, 15 * time.Minute},
+		{"contest real time", Tier{Name: "t", Delay: "5m", Contests: map[string]string{"gov": "0s"}}, true, 5 * time.Minute},
+		{"contest zero", Tier{Name: "t", Contests: map[string]string{"gov": "0s"}}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiers, err := NewTiers([]Tier{tt.tier})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tier, _ := tiers.Get("t")
+			if got := tier.Delayed(); got != tt.wantDelayed {
+				t.Errorf("Delayed = %v, want %v", got, tt.wantDelayed)
+			}
+			if got := tier.Longest(); got != tt.wantLongest {
+				t.Errorf("Longest = %s, want %s", got, tt.wantLongest)
+			}
+		})
+	}
+	var none *Tier
+	if none.Delayed() {
+		t.Error("nil tier is delayed")
+	}
+}
+
+func TestTierResults(t *testing.T) {
+	t0 := time.Date(2026, 11, 3, 20, 0, 0, 0, time.UTC)
+	at := history(
+		snapshot("h1", t0, map[string]int{"gov": 1, "mayor": 1}),
+		snapshot("h2", t0.Add(10*time.Minute), map[string]int{"gov": 2, "mayor": 2}),
+	)
+
+	tests := []struct {
+		name     string
+		tier     Tier
+		now      time.Duration // after t0
+		wantHash string
+		want     map[string]int // contest ID to votes; nil for no results
+	}{
+		{"real time", Tier{Delay: ""}, 12 * time.Minute, "h2", map[string]int{"gov": 2, "mayor": 2}},
+		{"delayed", Tier{Delay: "5m"}, 12 * time.Minute, "h1", map[string]int{"gov": 1, "mayor": 1}},
+		{"delay lifted", Tier{Delay: "5m"}, 15 * time.Minute, "h2", map[string]int{"gov": 2, "mayor": 2}},
+		{"nothing out yet", Tier{Delay: "5m"}, 2 * time.Minute, "", nil},
+		{"contest delayed longer", Tier{Delay: "5m", Contests: map[string]string{"gov": "15m"}}, 20 * time.Minute, "h2", map[string]int{"gov": 1, "mayor": 2}},
+		{"contest not out yet", Tier{Delay: "5m", Contests: map[string]string{"gov": "15m"}}, 12 * time.Minute, "h1", map[string]int{"mayor": 1}},
+		{"contest delayed shorter", Tier{Delay: "15m", Contests: map[string]string{"gov": "0s"}}, 20 * time.Minute, "h1", map[string]int{"gov": 2, "mayor": 1}},
+		{"only shorter contest out", Tier{Delay: "15m", Contests: map[string]string{"gov": "0s"}}, 12 * time.Minute, "h2", map[string]int{"gov": 2}},
+		{"contest same delay", Tier{Delay: "5m", Contests: map[string]string{"gov": "5m"}}, 12 * time.Minute, "h1", map[string]int{"gov": 1, "mayor": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.tier.Name = "t"
+			tiers, err := NewTiers([]Tier{tt.tier})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tier, _ := tiers.Get("t")
+			got, err := tier.Results(t0.Add(tt.now), at)
+			if tt.want == nil {
+				if !errors.Is(err, errNone) {
+					t.Fatalf("Results = %v, %v; want the error of at", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Results: %v", err)
+			}
+			if got.Hash != tt.wantHash {
+				t.Errorf("Hash = %q, want %q", got.Hash, tt.wantHash)
+			}
+			votes := make(map[string]int)
+			for _, c := range got.Contests {
+				votes[c.ID] = c.Candidates[0].Votes
+			}
+			if len(votes) != len(tt.want) {
+				t.Errorf("contests = %v, want %v", votes, tt.want)
+			}
+			for id, v := range tt.want {
+				if votes[id] != v {
+					t.Errorf("contest %s votes = %d, want %d", id, votes[id], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// Package events keeps the append-only log of events published data is
+// projected from, in memory or in a JSON lines file that survives restarts.
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SchemaVersion is the version of the log file format this binary reads
+// and writes, recorded in the file's first line. A change to how events are
+// recorded bumps it, with the migration upgrading older files in package
+// migrate.
+const SchemaVersion = 1
+
+// ErrSchemaVersion is returned when opening a log file of another version;
+// an older one is migrated first.
+var ErrSchemaVersion = errors.New("event log schema version")
+
+// header is the first line of a log file. It has no sequence number, so it
+// replays as nothing.
+type header struct {
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+// Header returns the first line of a log file at version.
+func Header(version int) []byte {
+	data, _ := json.Marshal(header{SchemaVersion: version})
+	return append(data, '\n')
+}
+
+// FileVersion returns the schema version of the log file at path: 0 if
+// it was written before versions were recorded, and SchemaVersion if it is
+// empty or doesn't exist, as that is what OpenFile writes.
+func FileVersion(path string) (int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return SchemaVersion, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReaderSize(f, 1<<20).ReadBytes('\n')
+	if errors.Is(err, io.EOF) {
+		return SchemaVersion, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read event log: %w", err)
+	}
+	var h header
+	if err := json.Unmarshal(line, &h); err != nil {
+		return 0, fmt.Errorf("event log %s: %w", path, err)
+	}
+	return h.SchemaVersion, nil
+}
+
+// Log is an append-only sequence of events.
+type Log interface {
+	// Append adds events to the end of the log in one step, numbering them
+	// in order after the last event appended.
+	Append(events ...*models.Event) error
+	// Replay calls fn with every event after seq, oldest first, stopping
+	// at the first error fn returns.
+	Replay(after uint64, fn func(models.Event) error) error
+	// Last returns the sequence number of the last event, or 0.
+	Last() uint64
+}
+
+// Memory is a Log held in memory.
+type Memory struct {
+	mu     sync.RWMutex
+	events []models.Event
+}
+
+// NewMemory returns an empty in-memory log.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Append implements Log.
+func (m *Memory) Append(events ...*models.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range events {
+		e.Seq = uint64(len(m.events)) + 1
+		m.events = append(m.events, *e)
+	}
+	return nil
+}
+
+// Replay implements Log.
+func (m *Memory) Replay(after uint64, fn func(models.Event) error) error {
+	m.mu.RLock()
+	var pending []models.Event
+	if after < uint64(len(m.events)) {
+		pending = m.events[after:]
+	}
+	m.mu.RUnlock()
+	for _, e := range pending {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Last implements Log.
+func (m *Memory) Last() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return uint64(len(m.events))
+}
+
+// File is a Log kept as one JSON event per line in a file. Events aren't
+// held in memory; replays read them back from the file.
+type File struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	last uint64
+}
+
+// OpenFile opens the log at path, creating it if needed. A final line cut
+// short by a crash is dropped. A log of a schema version other than
+// SchemaVersion isn't opened.
+func OpenFile(path string) (*File, error) {
+	version, err := FileVersion(path)
+	if err != nil {
+		return nil, err
+	}
+	if version != SchemaVersion {
+		return nil, fmt.Errorf("%w: %s is at version %d, this binary reads %d; migrate it with era migrate", ErrSchemaVersion, path, version, SchemaVersion)
+	}
+	l := &File{path: path}
+	size, err := l.scan(0, func(e models.Event) error {
+		l.last = e.Seq
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open event log: %w", err)
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("truncate event log: %w", err)
+	}
+	if _, err := f.Seek(size, 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("seek event log: %w", err)
+	}
+	if size == 0 {
+		if _, err := f.Write(Header(SchemaVersion)); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("write event log: %w", err)
+		}
+	}
+	l.f = f
+	return l, nil
+}
+
+// Append implements Log, writing every event before syncing once.
+func (l *File) Append(events ...*models.Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var buf bytes.Buffer
+	seq := l.last
+	for _, e := range events {
+		seq++
+		e.Seq = seq
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("encode event: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if _, err := l.f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("append to event log: %w", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("sync event log: %w", err)
+	}
+	l.last = seq
+	return nil
+}
+
+// Replay implements Log. Events appended while it runs may or may not be
+// included.
+func (l *File) Replay(after uint64, fn func(models.Event) error) error {
+	_, err := l.scan(after, fn)
+	return err
+}
+
+// Last implements Log.
+func (l *File) Last() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.last
+}
+
+// Close closes the file.
+func (l *File) Close() error {
+	return l.f.Close()
+}
+
+// String returns the path of the file.
+func (l *File) String() string {
+	return l.path
+}
+
+// scan calls fn with each complete event after seq and returns the length
+// of the file up to the last complete line.
+func (l *File) scan(after uint64, fn func(models.Event) error) (int64, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, 1<<20)
+	var size int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A line without its newline was never fully written.
+			return size, nil
+		}
+		if err != nil {
+			return size, fmt.Errorf("read event log: %w", err)
+		}
+		var e models.Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return size, fmt.Errorf("event log %s at byte %d: %w", l.path, size, err)
+		}
+		size += int64(len(line))
+		// The header has no sequence number, so it is always skipped.
+		if e.Seq <= after {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return size, err
+		}
+	}
+}
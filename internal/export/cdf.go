@@ -0,0 +1,212 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// The subset of the NIST SP 1500-100 election results common data format
+// (the JSON schema Google Civic and other aggregators ingest) needed to
+// describe one county's summary results.
+
+type cdfReport struct {
+	Type                string        `json:"@type"`
+	Format              string        `json:"Format"`
+	GeneratedDate       string        `json:"GeneratedDate"`
+	Issuer              string        `json:"Issuer"`
+	IssuerAbbreviation  string        `json:"IssuerAbbreviation"`
+	SequenceStart       int           `json:"SequenceStart"`
+	SequenceEnd         int           `json:"SequenceEnd"`
+	Status              string        `json:"Status"`
+	VendorApplicationID string        `json:"VendorApplicationId"`
+	Election            []cdfElection `json:"Election"`
+	GpUnit              []cdfGpUnit   `json:"GpUnit"`
+	Party               []cdfParty    `json:"Party,omitempty"`
+}
+
+type cdfElection struct {
+	Type            string         `json:"@type"`
+	Name            cdfText        `json:"Name"`
+	ElectionScopeID string         `json:"ElectionScopeId"`
+	ElectionType    string         `json:"Type"`
+	StartDate       string         `json:"StartDate"`
+	EndDate         string         `json:"EndDate"`
+	Candidate       []cdfCandidate `json:"Candidate,omitempty"`
+	Contest         []cdfContest   `json:"Contest"`
+}
+
+type cdfGpUnit struct {
+	ID   string `json:"@id"`
+	Type string `json:"@type"`
+	Name string `json:"Name"`
+	Unit string `json:"Type"`
+}
+
+type cdfParty struct {
+	ID   string  `json:"@id"`
+	Type string  `json:"@type"`
+	Name cdfText `json:"Name"`
+}
+
+type cdfCandidate struct {
+	ID         string  `json:"@id"`
+	Type       string  `json:"@type"`
+	BallotName cdfText `json:"BallotName"`
+	PartyID    string  `json:"PartyId,omitempty"`
+	IsWriteIn  bool    `json:"IsWriteIn,omitempty"`
+}
+
+type cdfContest struct {
+	ID                 string         `json:"@id"`
+	Type               string         `json:"@type"`
+	Name               string         `json:"Name"`
+	ElectionDistrictID string         `json:"ElectionDistrictId"`
+	VotesAllowed       int            `json:"VotesAllowed,omitempty"`
+	ContestSelection   []cdfSelection `json:"ContestSelection"`
+}
+
+type cdfSelection struct {
+	ID           string          `json:"@id"`
+	Type         string          `json:"@type"`
+	CandidateIDs []string        `json:"CandidateIds,omitempty"`
+	IsWriteIn    bool            `json:"IsWriteIn,omitempty"`
+	Selection    *cdfText        `json:"Selection,omitempty"`
+	VoteCounts   []cdfVoteCounts `json:"VoteCounts"`
+}
+
+type cdfVoteCounts struct {
+	Type     string `json:"@type"`
+	GpUnitID string `json:"GpUnitId"`
+	Count    int    `json:"Count"`
+	Kind     string `json:"Type"`
+}
+
+type cdfText struct {
+	Type string          `json:"@type"`
+	Text []cdfLangString `json:"Text"`
+}
+
+type cdfLangString struct {
+	Type     string `json:"@type"`
+	Content  string `json:"Content"`
+	Language string `json:"Language"`
+}
+
+func cdfString(s string) cdfText {
+	return cdfText{
+		Type: "ElectionResults.InternationalizedText",
+		Text: []cdfLangString{{Type: "ElectionResults.LanguageString", Content: s, Language: "en"}},
+	}
+}
+
+// CDF writes results as a NIST SP 1500-100 election results report in JSON.
+// election names the election the results belong to.
+func CDF(w io.Writer, results *models.Results, election string) error {
+	county := "gpu-" + models.Slug(results.County)
+	date := results.ParsedAt.UTC().Format("2006-01-02")
+
+	e := cdfElection{
+		Type:            "ElectionResults.Election",
+		Name:            cdfString(election),
+		ElectionScopeID: county,
+		ElectionType:    "general",
+		StartDate:       date,
+		EndDate:         date,
+	}
+	parties := make(map[string]bool)
+	var partyList []cdfParty
+	complete := len(results.Contests) > 0
+
+	for _, c := range results.Contests {
+		if c.PrecinctsTotal == 0 || c.PrecinctsReporting < c.PrecinctsTotal {
+			complete = false
+		}
+		contestID := "contest-" + c.ID
+		measure := c.Measure != nil || results.ContentType == models.ContentTypeMeasure
+		contest := cdfContest{
+			ID:                 contestID,
+			Type:               "ElectionResults.CandidateContest",
+			Name:               c.Title,
+			ElectionDistrictID: county,
+			VotesAllowed:       1,
+		}
+		if measure {
+			contest.Type = "ElectionResults.BallotMeasureContest"
+			contest.VotesAllowed = 0
+		}
+
+		for i, cand := range c.Candidates {
+			sel := cdfSelection{
+				ID: fmt.Sprintf("%s-sel-%d", contestID, i),
+				VoteCounts: []cdfVoteCounts{{
+					Type:     "ElectionResults.VoteCounts",
+					GpUnitID: county,
+					Count:    cand.Votes,
+					Kind:     "total",
+				}},
+			}
+			if measure {
+				sel.Type = "ElectionResults.BallotMeasureSelection"
+				name := cdfString(cand.Name)
+				sel.Selection = &name
+			} else {
+				candID := fmt.Sprintf("cand-%s-%d", c.ID, i)
+				sel.Type = "ElectionResults.CandidateSelection"
+				sel.CandidateIDs = []string{candID}
+				sel.IsWriteIn = cand.WriteIn
+				var partyID string
+				if cand.Party != "" {
+					partyID = "party-" + models.Slug(cand.Party)
+					if !parties[partyID] {
+						parties[partyID] = true
+						partyList = append(partyList, cdfParty{ID: partyID, Type: "ElectionResults.Party", Name: cdfString(cand.Party)})
+					}
+				}
+				e.Candidate = append(e.Candidate, cdfCandidate{
+					ID:         candID,
+					Type:       "ElectionResults.Candidate",
+					BallotName: cdfString(cand.Name),
+					PartyID:    partyID,
+					IsWriteIn:  cand.WriteIn,
+				})
+			}
+			contest.ContestSelection = append(contest.ContestSelection, sel)
+		}
+		e.Contest = append(e.Contest, contest)
+	}
+
+	status := "unofficial-partial"
+	if complete {
+		status = "unofficial-complete"
+	}
+	report := cdfReport{
+		Type:                "ElectionResults.ElectionReport",
+		Format:              "summary-contest",
+		GeneratedDate:       time.Now().UTC().Format(time.RFC3339),
+		Issuer:              "ERA API",
+		IssuerAbbreviation:  "ERA",
+		SequenceStart:       1,
+		SequenceEnd:         1,
+		Status:              status,
+		VendorApplicationID: "era-api-v1",
+		Election:            []cdfElection{e},
+		GpUnit: []cdfGpUnit{{
+			ID:   county,
+			Type: "ElectionResults.ReportingUnit",
+			Name: results.County,
+			Unit: "county",
+		}},
+		Party: partyList,
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("write cdf: %w", err)
+	}
+	return nil
+}
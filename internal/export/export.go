@@ -0,0 +1,137 @@
+// Package export writes processed results as spreadsheets: one row per
+// candidate, with the contest repeated on every row so the sheet can be
+// filtered and pivoted directly.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/outcome"
+)
+
+// column is one exported column. field is the JSON field it mirrors, so
+// columns can be dropped by the caller's visibility policy.
+type column struct {
+	header string
+	field  string
+	value  func(r *models.Results, c *models.Contest, cand *models.Candidate) cell
+}
+
+// cell is a typed spreadsheet value; numbers stay numeric in XLSX.
+type cell struct {
+	text   string
+	number *float64
+}
+
+func text(s string) cell { return cell{text: s} }
+
+func number(f float64) cell {
+	return cell{text: strconv.FormatFloat(f, 'f', -1, 64), number: &f}
+}
+
+var columns = []column{
+	{"County", "county", func(r *models.Results, _ *models.Contest, _ *models.Candidate) cell { return text(r.County) }},
+	{"Contest ID", "id", func(_ *models.Results, c *models.Contest, _ *models.Candidate) cell { return text(c.ID) }},
+	{"Contest", "title", func(_ *models.Results, c *models.Contest, _ *models.Candidate) cell { return text(c.Title) }},
+	{"Reported Contest", "rawTitle", func(_ *models.Results, c *models.Contest, _ *models.Candidate) cell { return text(c.RawTitle) }},
+	{"Candidate", "name", func(_ *models.Results, _ *models.Contest, cand *models.Candidate) cell { return text(cand.Name) }},
+	{"Reported Candidate", "rawName", func(_ *models.Results, _ *models.Contest, cand *models.Candidate) cell { return text(cand.RawName) }},
+	{"Party", "party", func(_ *models.Results, _ *models.Contest, cand *models.Candidate) cell { return text(cand.Party) }},
+	{"Write-in", "writeIn", func(_ *models.Results, _ *models.Contest, cand *models.Candidate) cell {
+		if cand.WriteIn {
+			return text("yes")
+		}
+		return text("")
+	}},
+	{"Votes", "votes", func(_ *models.Results, _ *models.Contest, cand *models.Candidate) cell {
+		return number(float64(cand.Votes))
+	}},
+	{"Percent", "percent", func(_ *models.Results, c *models.Contest, cand *models.Candidate) cell {
+		return number(outcome.Percent(cand.Votes, c.TotalVotes()))
+	}},
+	{"Precincts Reporting", "precinctsReporting", func(_ *models.Results, c *models.Contest, _ *models.Candidate) cell {
+		return number(float64(c.PrecinctsReporting))
+	}},
+	{"Precincts Total", "precinctsTotal", func(_ *models.Results, c *models.Contest, _ *models.Candidate) cell {
+		return number(float64(c.PrecinctsTotal))
+	}},
+	{"Parsed At", "parsedAt", func(r *models.Results, _ *models.Contest, _ *models.Candidate) cell {
+		return text(r.ParsedAt.UTC().Format(time.RFC3339))
+	}},
+	{"Source", "source", func(r *models.Results, _ *models.Contest, _ *models.Candidate) cell { return text(r.Source) }},
+}
+
+// visible returns the columns not hidden by hidden, which may be nil.
+func visible(hidden func(field string) bool) []column {
+	if hidden == nil {
+		return columns
+	}
+	out := make([]column, 0, len(columns))
+	for _, c := range columns {
+		if !hidden(c.field) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// table returns the header row and candidate rows of results.
+func table(results *models.Results, hidden func(string) bool) ([]string, [][]cell) {
+	cols := visible(hidden)
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.header
+	}
+
+	var rows [][]cell
+	for i := range results.Contests {
+		contest := &results.Contests[i]
+		for j := range contest.Candidates {
+			row := make([]cell, len(cols))
+			for k, c := range cols {
+				row[k] = c.value(results, contest, &contest.Candidates[j])
+			}
+			rows = append(rows, row)
+		}
+	}
+	return header, rows
+}
+
+// defuse stops spreadsheet applications from evaluating text taken from
+// county files as a formula.
+func defuse(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// CSV writes results as CSV. Columns whose JSON field hidden reports true
+// are left out.
+func CSV(w io.Writer, results *models.Results, hidden func(field string) bool) error {
+	header, rows := table(results, hidden)
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, c := range row {
+			record[i] = c.text
+			if c.number == nil {
+				record[i] = defuse(c.text)
+			}
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	return nil
+}
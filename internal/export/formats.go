@@ -0,0 +1,66 @@
+package export
+
+import (
+	"io"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// Format is a layout results are exported in.
+type Format struct {
+	Name        string
+	ContentType string
+	// Ext ends the file name, as in alder-county-results.<Ext>.
+	Ext   string
+	write func(w io.Writer, results *models.Results, election string, hidden func(string) bool) error
+}
+
+// Formats are the layouts results are exported in: csv or xlsx
+// spreadsheets with one row per candidate, or the open-data layouts
+// openelections (county level OpenElections CSV) and cdf (NIST SP
+// 1500-100 election results JSON).
+var Formats = []Format{
+	{Name: "csv", ContentType: "text/csv; charset=utf-8", Ext: "csv", write: func(w io.Writer, results *models.Results, _ string, hidden func(string) bool) error {
+		return CSV(w, results, hidden)
+	}},
+	{Name: "xlsx", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Ext: "xlsx", write: func(w io.Writer, results *models.Results, _ string, hidden func(string) bool) error {
+		return XLSX(w, results, hidden)
+	}},
+	{Name: "openelections", ContentType: "text/csv; charset=utf-8", Ext: "openelections.csv", write: func(w io.Writer, results *models.Results, _ string, _ func(string) bool) error {
+		return OpenElections(w, results)
+	}},
+	{Name: "cdf", ContentType: "application/json", Ext: "cdf.json", write: func(w io.Writer, results *models.Results, election string, _ func(string) bool) error {
+		return CDF(w, results, election)
+	}},
+}
+
+// Lookup returns the format called name; an empty name is csv.
+func Lookup(name string) (Format, bool) {
+	if name == "" {
+		name = "csv"
+	}
+	for _, f := range Formats {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// FormatNames lists the formats' names, as "csv, xlsx, openelections or
+// cdf".
+func FormatNames() string {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = f.Name
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// Write writes results of election in f's layout. Spreadsheet columns
+// whose JSON field hidden reports true are left out; the open-data
+// layouts have no optional columns.
+func (f Format) Write(w io.Writer, results *models.Results, election string, hidden func(field string) bool) error {
+	return f.write(w, results, election, hidden)
+}
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// districtSuffix splits "U.S. House District 5" into office and district.
+var districtSuffix = regexp.MustCompile(`(?i)^(.*?)[\s,\-–]*\b(?:district|dist\.?)\s+(?:no\.?\s*)?([0-9A-Z]+)\s*$`)
+
+// splitOffice separates a contest title into the OpenElections office and
+// district columns.
+func splitOffice(title string) (office, district string) {
+	if m := districtSuffix.FindStringSubmatch(strings.TrimSpace(title)); m != nil && m[1] != "" {
+		return m[1], m[2]
+	}
+	return title, ""
+}
+
+// OpenElections writes results in the county-level OpenElections CSV layout
+// (county, office, district, party, candidate, votes).
+func OpenElections(w io.Writer, results *models.Results) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"county", "office", "district", "party", "candidate", "votes"})
+	for _, c := range results.Contests {
+		office, district := splitOffice(c.Title)
+		for _, cand := range c.Candidates {
+			cw.Write([]string{
+				defuse(results.County),
+				defuse(office),
+				defuse(district),
+				defuse(cand.Party),
+				defuse(cand.Name),
+				strconv.Itoa(cand.Votes),
+			})
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/sigv4"
+)
+
+// Bucket URL schemes.
+const (
+	SchemeS3  = "s3"
+	SchemeGCS = "gs"
+)
+
+// gcsEndpoint is Cloud Storage's XML API, which speaks S3's protocol to
+// callers holding HMAC keys.
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// BucketConfig says where and as whom the objects s3:// or gs:// URLs name
+// are fetched.
+type BucketConfig struct {
+	// Endpoint overrides the store's URL, addressing buckets by path, for
+	// S3-compatible stores such as MinIO. For s3 it defaults to AWS,
+	// addressing buckets by host, and for gs to Cloud Storage.
+	Endpoint string
+	// Region defaults to us-east-1 for s3 and to auto for gs.
+	Region string
+	// Credentials sign requests; without them objects are fetched
+	// unsigned, as public buckets allow.
+	Credentials sigv4.Credentials
+}
+
+// SetBucket configures fetching of URLs with scheme, SchemeS3 or SchemeGCS.
+// Until it is called, s3 URLs are fetched from AWS and gs URLs from Cloud
+// Storage, unsigned.
+func (f *Fetcher) SetBucket(scheme string, cfg BucketConfig) {
+	if f.buckets == nil {
+		f.buckets = make(map[string]BucketConfig)
+	}
+	f.buckets[scheme] = cfg
+}
+
+// bucket returns the configuration for scheme, with defaults filled in.
+func (f *Fetcher) bucket(scheme string) BucketConfig {
+	cfg := f.buckets[scheme]
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+		if scheme == SchemeGCS {
+			cfg.Region = "auto"
+		}
+	}
+	if cfg.Endpoint == "" && scheme == SchemeGCS {
+		cfg.Endpoint = gcsEndpoint
+	}
+	return cfg
+}
+
+// fetchBucket downloads the object u names, signing as login if it isn't
+// nil, its username the access key ID and its password the secret. As for
+// FTP, a key ending in "/" or in a glob fetches the most recently modified
+// object under that prefix, or the newest whose name matches.
+func (f *Fetcher) fetchBucket(ctx context.Context, u *url.URL, login *url.Userinfo) (*Source, error) {
+	scheme := strings.ToLower(u.Scheme)
+	cfg := f.bucket(scheme)
+	if login != nil {
+		secret, _ := login.Password()
+		cfg.Credentials = sigv4.Credentials{AccessKeyID: login.Username(), SecretAccessKey: secret}
+	}
+	b := &bucketClient{f: f, cfg: cfg, scheme: scheme, name: u.Host}
+
+	key := strings.TrimPrefix(u.Path, "/")
+	dir, name := path.Split(key)
+	if name == "" || strings.ContainsAny(name, "*?[") {
+		pattern := strings.ToLower(name)
+		if pattern == "" {
+			pattern = "*"
+		}
+		var err error
+		if key, err = b.newest(ctx, dir, pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := b.do(ctx, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", key, err)
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, ErrTooLarge
+	}
+	src := &Source{Data: data, Name: key, ContentType: resp.Header.Get("Content-Type")}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		t = t.UTC()
+		src.ModifiedAt = &t
+	}
+	return src, nil
+}
+
+// bucketClient reads one bucket.
+type bucketClient struct {
+	f      *Fetcher
+	cfg    BucketConfig
+	scheme string
+	name   string
+}
+
+// do sends a GET for key, or for the bucket itself if key is empty, and
+// fails unless it succeeds.
+func (b *bucketClient) do(ctx context.Context, key string, query url.Values) (*http.Response, error) {
+	var u *url.URL
+	if b.cfg.Endpoint != "" {
+		base, err := url.Parse(strings.TrimSuffix(b.cfg.Endpoint, "/"))
+		if err != nil || base.Host == "" {
+			return nil, fmt.Errorf("%s endpoint %q is not a URL", b.scheme, b.cfg.Endpoint)
+		}
+		u = base
+		u.Path += "/" + b.name + "/" + key
+	} else {
+		u = &url.URL{Scheme: "https", Host: b.name + ".s3." + b.cfg.Region + ".amazonaws.com", Path: "/" + key}
+	}
+	u.RawPath = sigv4.EscapePath(u.Path)
+	u.RawQuery = sigv4.CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if b.cfg.Credentials.AccessKeyID != "" {
+		sigv4.Sign(req, nil, b.cfg.Region, b.cfg.Credentials, time.Now().UTC())
+	}
+	resp, err := b.f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(msg, &e) == nil && e.Code != "" {
+		return nil, fmt.Errorf("%s: %s", e.Code, e.Message)
+	}
+	return nil, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// maxListPages bounds how much of a prefix is listed looking for the
+// newest object.
+const maxListPages = 20
+
+// newest returns the key of the most recently modified object directly
+// under prefix whose name, in lower case, matches pattern.
+func (b *bucketClient) newest(ctx context.Context, prefix, pattern string) (string, error) {
+	var entries []listed
+	token := ""
+	for range maxListPages {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}, "delimiter": {"/"}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		resp, err := b.do(ctx, "", q)
+		if err != nil {
+			return "", err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(io.LimitReader(resp.Body, maxListing)).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("list %s: %w", prefix, err)
+		}
+		for _, c := range page.Contents {
+			if name := strings.TrimPrefix(c.Key, prefix); name != "" {
+				entries = append(entries, listed{name: name, modified: c.LastModified})
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	name, ok := newestMatch(entries, pattern)
+	if !ok {
+		return "", fmt.Errorf("%w: %s%s", ErrNoFile, prefix, pattern)
+	}
+	return prefix + name, nil
+}
@@ -0,0 +1,159 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/urlpolicy"
+)
+
+const (
+	defaultTimeout  = 2 * time.Minute
+	defaultMaxBytes = 256 << 20
+	dialTimeout     = 30 * time.Second
+	maxRedirects    = 10
+	userAgent       = "era-api/1.0 (+election results aggregator)"
+)
+
+// ErrTooLarge is returned when a source exceeds the configured size limit.
+var ErrTooLarge = errors.New("source file exceeds size limit")
+
+// Fetcher downloads county source files referenced by fileLink.
+type Fetcher struct {
+	client   *http.Client
+	dialer   *net.Dialer
+	policy   *urlpolicy.Policy
+	maxBytes int64
+	buckets  map[string]BucketConfig
+}
+
+// New returns a Fetcher with sensible defaults for county election sites.
+// It fetches under urlpolicy.Default until SetPolicy says otherwise.
+func New() *Fetcher {
+	f := &Fetcher{maxBytes: defaultMaxBytes}
+	f.SetPolicy(urlpolicy.Default())
+	return f
+}
+
+// SetPolicy restricts which URLs f fetches. The policy is checked against
+// each URL, including redirect targets, and against every address dialed,
+// so names resolving to blocked addresses are refused too. Requests bypass
+// any proxy from the environment, which would otherwise be the address
+// checked.
+func (f *Fetcher) SetPolicy(p *urlpolicy.Policy) {
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: dialTimeout, Control: p.Control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	f.dialer = dialer
+	f.policy = p
+	f.client = &http.Client{
+		Timeout:   defaultTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return p.CheckURL(req.URL.String())
+		},
+	}
+}
+
+// Source is a downloaded source file.
+type Source struct {
+	Data []byte
+	// Name is the path of the file fetched, which for a directory or a
+	// pattern is the one picked.
+	Name string
+	// ContentType is the media type the server gave the file, if any.
+	ContentType string
+	// ModifiedAt is when the server says the file last changed, from its
+	// Last-Modified header, if it sent one.
+	ModifiedAt *time.Time
+}
+
+// Fetch downloads the resource at url and returns its body. A URL the
+// policy refuses fails with an error wrapping urlpolicy.ErrBlocked.
+func (f *Fetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	src, err := f.FetchSource(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return src.Data, nil
+}
+
+// FetchSource is Fetch, also returning what the server says about the file.
+func (f *Fetcher) FetchSource(ctx context.Context, url string) (*Source, error) {
+	return f.FetchSourceAs(ctx, url, nil)
+}
+
+// FetchSourceAs is FetchSource, logging in as login if it isn't nil: with
+// HTTP basic authentication, as the FTP user, or with a bucket access key.
+// ftp:// URLs are fetched as fetchFTP describes, and s3:// and gs:// ones
+// as fetchBucket does; sftp:// ones fail with ErrSFTP.
+func (f *Fetcher) FetchSourceAs(ctx context.Context, rawURL string, login *url.Userinfo) (*Source, error) {
+	if u, err := url.Parse(rawURL); err == nil && strings.EqualFold(u.Scheme, "sftp") {
+		return nil, fmt.Errorf("fetch %s: %w", rawURL, ErrSFTP)
+	}
+	if err := f.policy.CheckURL(rawURL); err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	if strings.EqualFold(u.Scheme, "ftp") {
+		src, err := f.fetchFTP(ctx, u, login)
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+		}
+		return src, nil
+	}
+	if scheme := strings.ToLower(u.Scheme); scheme == SchemeS3 || scheme == SchemeGCS {
+		src, err := f.fetchBucket(ctx, u, login)
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+		}
+		return src, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if login != nil {
+		password, _ := login.Password()
+		req.SetBasicAuth(login.Username(), password)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: unexpected status %s", rawURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", rawURL, err)
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, ErrTooLarge
+	}
+	src := &Source{Data: data, Name: resp.Request.URL.Path, ContentType: resp.Header.Get("Content-Type")}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		t = t.UTC()
+		src.ModifiedAt = &t
+	}
+	return src, nil
+}
@@ -0,0 +1,325 @@
+package fetcher
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoFile is returned for an FTP directory with no file to fetch.
+var ErrNoFile = errors.New("no matching file in directory")
+
+// ErrSFTP is returned for sftp:// URLs. SFTP runs over SSH, which the
+// standard library has no client for, and the module takes no third-party
+// dependencies; counties on SFTP are asked for ftp or https links instead.
+var ErrSFTP = errors.New("sftp is not supported")
+
+// mdtmLayout is the time format of MDTM replies and MLSD modify facts.
+const mdtmLayout = "20060102150405"
+
+// fetchFTP downloads the file u names from an FTP server, logging in as
+// login or anonymously. A path ending in "/" names a directory, of which
+// the most recently modified file is fetched; so does one whose last
+// segment is a glob, as in ftp://host/results/*.zip, of which the newest
+// file whose name matches is. Transfers are binary and passive, and data
+// connections go to the address of the control connection, whatever the
+// server names, so the fetch policy's checks hold for both.
+func (f *Fetcher) fetchFTP(ctx context.Context, u *url.URL, login *url.Userinfo) (*Source, error) {
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "21")
+	}
+	conn, err := f.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &ftpConn{Conn: textproto.NewConn(conn), raw: conn, dialer: f.dialer, ctx: ctx}
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	c.deadline()
+
+	if _, _, err := c.ReadResponse(220); err != nil {
+		return nil, fmt.Errorf("ftp greeting: %w", err)
+	}
+	user, password := "anonymous", "anonymous@"
+	if login != nil {
+		user = login.Username()
+		password, _ = login.Password()
+	}
+	if strings.ContainsAny(user+password, "\r\n") {
+		return nil, errors.New("ftp login must not contain line breaks")
+	}
+	if err := c.login(user, password); err != nil {
+		return nil, err
+	}
+	if _, err := c.cmd(2, "TYPE I"); err != nil {
+		return nil, err
+	}
+
+	file := u.Path
+	if strings.ContainsAny(file, "\r\n") {
+		return nil, errors.New("ftp path must not contain line breaks")
+	}
+	dir, name := path.Split(file)
+	if name == "" || strings.ContainsAny(name, "*?[") {
+		pattern := strings.ToLower(name)
+		if pattern == "" {
+			pattern = "*"
+		}
+		if file, err = c.newest(dir, pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	src := &Source{Name: file}
+	if msg, err := c.cmd(2, "MDTM %s", file); err == nil {
+		if t, err := time.Parse(mdtmLayout, strings.TrimSpace(msg)); err == nil {
+			src.ModifiedAt = &t
+		}
+	}
+	data, err := c.retrieve(file, f.maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	src.Data = data
+	c.cmd(2, "QUIT")
+	return src, nil
+}
+
+// ftpConn is the control connection of an FTP session.
+type ftpConn struct {
+	*textproto.Conn
+	raw    net.Conn
+	dialer *net.Dialer
+	ctx    context.Context
+}
+
+// deadline bounds the next exchange on conn by the context's deadline, or
+// by the fetch timeout if it has none.
+func (c *ftpConn) deadline(conns ...net.Conn) {
+	d, ok := c.ctx.Deadline()
+	if !ok {
+		d = time.Now().Add(defaultTimeout)
+	}
+	c.raw.SetDeadline(d)
+	for _, conn := range conns {
+		conn.SetDeadline(d)
+	}
+}
+
+// cmd sends a command and reads its reply, which must have a code
+// starting with expect.
+func (c *ftpConn) cmd(expect int, format string, args ...any) (string, error) {
+	id, err := c.Cmd(format, args...)
+	if err != nil {
+		return "", err
+	}
+	c.StartResponse(id)
+	defer c.EndResponse(id)
+	_, msg, err := c.ReadResponse(expect)
+	if err != nil {
+		return "", fmt.Errorf("ftp %s: %w", strings.Fields(format)[0], err)
+	}
+	return msg, nil
+}
+
+func (c *ftpConn) login(user, password string) error {
+	id, err := c.Cmd("USER %s", user)
+	if err != nil {
+		return err
+	}
+	c.StartResponse(id)
+	code, msg, err := c.ReadResponse(0)
+	c.EndResponse(id)
+	switch {
+	case err != nil:
+		return fmt.Errorf("ftp USER: %w", err)
+	case code == 230:
+		return nil
+	case code != 331:
+		return fmt.Errorf("ftp login as %s refused: %d %s", user, code, msg)
+	}
+	if _, err := c.cmd(2, "PASS %s", password); err != nil {
+		return fmt.Errorf("ftp login as %s refused: %w", user, err)
+	}
+	return nil
+}
+
+// data opens a passive data connection, preferring EPSV to PASV.
+func (c *ftpConn) data() (net.Conn, error) {
+	host, _, err := net.SplitHostPort(c.raw.RemoteAddr().String())
+	if err != nil {
+		return nil, err
+	}
+	var port int
+	if msg, err := c.cmd(2, "EPSV"); err == nil {
+		// 229 Entering Extended Passive Mode (|||port|)
+		start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+		if start < 0 || end < start+4 {
+			return nil, fmt.Errorf("ftp EPSV: unexpected reply %q", msg)
+		}
+		if port, err = strconv.Atoi(msg[start+4 : end]); err != nil {
+			return nil, fmt.Errorf("ftp EPSV: unexpected reply %q", msg)
+		}
+	} else {
+		msg, err := c.cmd(2, "PASV")
+		if err != nil {
+			return nil, err
+		}
+		// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2); the address is
+		// ignored for the control connection's.
+		start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+		if start < 0 || end < start {
+			return nil, fmt.Errorf("ftp PASV: unexpected reply %q", msg)
+		}
+		parts := strings.Split(msg[start+1:end], ",")
+		if len(parts) != 6 {
+			return nil, fmt.Errorf("ftp PASV: unexpected reply %q", msg)
+		}
+		hi, err1 := strconv.Atoi(strings.TrimSpace(parts[4]))
+		lo, err2 := strconv.Atoi(strings.TrimSpace(parts[5]))
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("ftp PASV: unexpected reply %q", msg)
+		}
+		port = hi<<8 | lo
+	}
+	conn, err := c.dialer.DialContext(c.ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("ftp data connection: %w", err)
+	}
+	c.deadline(conn)
+	return conn, nil
+}
+
+// transfer runs a command that sends its output over a data connection and
+// returns at most limit+1 bytes of it.
+func (c *ftpConn) transfer(limit int64, format string, args ...any) ([]byte, error) {
+	conn, err := c.data()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := c.cmd(1, format, args...); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(conn, limit+1))
+	conn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("ftp %s: %w", strings.Fields(format)[0], err)
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+	if _, _, err := c.ReadResponse(2); err != nil {
+		return nil, fmt.Errorf("ftp %s: %w", strings.Fields(format)[0], err)
+	}
+	return data, nil
+}
+
+func (c *ftpConn) retrieve(file string, limit int64) ([]byte, error) {
+	return c.transfer(limit, "RETR %s", file)
+}
+
+// maxListing bounds a directory listing.
+const maxListing = 8 << 20
+
+// newest returns the path of the most recently modified file in dir whose
+// name, in lower case, matches pattern. Servers that don't support MLSD are
+// asked for names with NLST and for each one's time with MDTM.
+func (c *ftpConn) newest(dir, pattern string) (string, error) {
+	var entries []listed
+	if listing, err := c.transfer(maxListing, "MLSD %s", dir); err == nil {
+		for _, line := range lines(listing) {
+			facts, name, ok := strings.Cut(line, " ")
+			if !ok {
+				continue
+			}
+			e := listed{name: name}
+			isFile := false
+			for _, fact := range strings.Split(facts, ";") {
+				k, v, _ := strings.Cut(fact, "=")
+				switch strings.ToLower(k) {
+				case "type":
+					isFile = strings.EqualFold(v, "file")
+				case "modify":
+					// Fractional seconds, if any, are dropped.
+					v, _, _ = strings.Cut(v, ".")
+					e.modified, _ = time.Parse(mdtmLayout, v)
+				}
+			}
+			if isFile {
+				entries = append(entries, e)
+			}
+		}
+	} else {
+		listing, err := c.transfer(maxListing, "NLST %s", dir)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range lines(listing) {
+			// Some servers list paths rather than names.
+			name := path.Base(line)
+			e := listed{name: name}
+			msg, err := c.cmd(2, "MDTM %s", path.Join(dir, name))
+			if err != nil {
+				continue // a directory, or a file the server won't date
+			}
+			e.modified, _ = time.Parse(mdtmLayout, strings.TrimSpace(msg))
+			entries = append(entries, e)
+		}
+	}
+
+	name, ok := newestMatch(entries, pattern)
+	if !ok {
+		return "", fmt.Errorf("%w: %s%s", ErrNoFile, dir, pattern)
+	}
+	return path.Join(dir, name), nil
+}
+
+// listed is a file in a directory listing.
+type listed struct {
+	name     string
+	modified time.Time
+}
+
+// newestMatch returns the name of the most recently modified of entries
+// whose name, in lower case, matches pattern.
+func newestMatch(entries []listed, pattern string) (string, bool) {
+	var best *listed
+	for i, e := range entries {
+		if ok, _ := path.Match(pattern, strings.ToLower(e.name)); !ok {
+			continue
+		}
+		// Ties go to the name sorting last, as a later upload usually
+		// has a later name.
+		if best == nil || e.modified.After(best.modified) || (e.modified.Equal(best.modified) && e.name > best.name) {
+			best = &entries[i]
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.name, true
+}
+
+// lines splits a listing into its non-empty lines.
+func lines(listing []byte) []string {
+	var out []string
+	s := bufio.NewScanner(strings.NewReader(string(listing)))
+	for s.Scan() {
+		if line := strings.TrimRight(s.Text(), "\r"); strings.TrimSpace(line) != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package forecast
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Provider produces win-probability estimates for the contests in a snapshot.
+type Provider interface {
+	Forecast(ctx context.Context, results *models.Results) ([]models.ContestForecast, error)
+}
+
+// HTTPProvider asks an external model service for forecasts by POSTing the
+// snapshot as JSON. The service answers with:
+//
+//	{"model": "...", "version": "...", "generatedAt": "...",
+//	 "contests": [{"contestId": "...", "probabilities": [{"candidate": "...", "probability": 0.7}]}]}
+type HTTPProvider struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPProvider returns a Provider backed by the model service at url.
+func NewHTTPProvider(url string, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+type serviceResponse struct {
+	Model       string    `json:"model"`
+	Version     string    `json:"version"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	Contests    []struct {
+		ContestID     string                  `json:"contestId"`
+		Probabilities []models.WinProbability `json:"probabilities"`
+	} `json:"contests"`
+}
+
+// Forecast implements Provider.
+func (p *HTTPProvider) Forecast(ctx context.Context, results *models.Results) ([]models.ContestForecast, error) {
+	body, err := json.Marshal(results)
+	if err != nil {
+		return nil, fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("call forecast service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("forecast service: unexpected status %s", resp.Status)
+	}
+
+	var sr serviceResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("decode forecast response: %w", err)
+	}
+
+	received := time.Now().UTC()
+	if sr.GeneratedAt.IsZero() {
+		sr.GeneratedAt = received
+	}
+	forecasts := make([]models.ContestForecast, 0, len(sr.Contests))
+	for _, c := range sr.Contests {
+		forecasts = append(forecasts, models.ContestForecast{
+			ContestID:     c.ContestID,
+			Model:         sr.Model,
+			ModelVersion:  sr.Version,
+			GeneratedAt:   sr.GeneratedAt,
+			ReceivedAt:    received,
+			SnapshotAt:    results.ParsedAt,
+			Probabilities: c.Probabilities,
+		})
+	}
+	return forecasts, nil
+}
+
+// Service refreshes forecasts every time a county snapshot is saved.
+type Service struct {
+	provider Provider
+	store    store.Store
+	logger   *slog.Logger
+	timeout  time.Duration
+}
+
+// NewService returns a Service that stores forecasts from provider in st.
+func NewService(provider Provider, st store.Store, logger *slog.Logger, timeout time.Duration) *Service {
+	return &Service{provider: provider, store: st, logger: logger, timeout: timeout}
+}
+
+// OnSnapshot requests fresh forecasts for results. It is meant to be
+// registered with Processor.OnSnapshot; failures are logged and leave the
+// previous forecasts in place.
+func (s *Service) OnSnapshot(ctx context.Context, results *models.Results) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	forecasts, err := s.provider.Forecast(ctx, results)
+	if err != nil {
+		s.logger.Warn("forecast update failed", "county", results.County, "error", err)
+		return
+	}
+
+	valid := forecasts[:0]
+	for _, f := range forecasts {
+		if _, ok := results.ContestByID(f.ContestID); ok && f.Model != "" {
+			valid = append(valid, f)
+		}
+	}
+	s.store.SetForecasts(results.County, valid)
+	s.logger.Info("forecasts updated", "county", results.County, "contests", len(valid))
+}
@@ -0,0 +1,39 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// ampSnippet renders contests with markup that is valid inside an AMP
+// document: no scripts, no inline style attributes, only class hooks.
+const ampSnippet = `<section class="era-snippet" data-snippet-id="{{.ID}}">
+{{- range .Contests}}
+	<div class="era-contest">
+		<h3 class="era-contest-title">{{.Title}}</h3>
+		<table class="era-contest-table">
+			<tbody>
+			{{- range .Candidates}}
+				<tr><td class="era-name">{{.Name}}</td><td class="era-votes">{{.Votes}}</td><td class="era-pct">{{printf "%.1f" .Percent}}%</td></tr>
+			{{- end}}
+			</tbody>
+		</table>
+		<p class="era-asof">{{.County}} &middot; as of <time datetime="{{.AsOf.Format "2006-01-02T15:04:05Z07:00"}}">{{.AsOf.Format "Jan 2, 3:04 PM MST"}}</time>{{with .License}} &middot; {{.Attribution}}{{end}}</p>
+	</div>
+{{- end}}
+</section>
+`
+
+var amp = template.Must(template.New("amp").Parse(ampSnippet))
+
+// AMPSnippet renders a snippet as an AMP-valid HTML fragment.
+func AMPSnippet(s *models.RenderedSnippet) (string, error) {
+	var buf bytes.Buffer
+	if err := amp.Execute(&buf, s); err != nil {
+		return "", fmt.Errorf("render snippet: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,81 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/outcome"
+)
+
+// resultsFragment is the HTML snippet embedded by the frontend.
+const resultsFragment = `
+<div class="election-results">
+	<h2>{{.County}} Results</h2>
+	<div class="results-container">
+	{{- range .Contests}}
+		<div class="contest">
+			<h3>{{.Title}}</h3>
+			<table>
+				<thead><tr><th>Candidate</th><th>Votes</th><th>%</th></tr></thead>
+				<tbody>
+				{{- $total := .TotalVotes}}
+				{{- range .Candidates}}
+					<tr{{if .WriteIn}} class="write-in"{{end}}><td>{{.Name}}{{if .Party}} ({{.Party}}){{end}}{{if and .WriteIn (not .WriteInAggregate)}} <span class="write-in-label">(write-in)</span>{{end}}</td><td>{{.Votes}}</td><td>{{percent .Votes $total}}</td></tr>
+				{{- end}}
+				</tbody>
+			</table>
+			{{- with .Measure}}
+			<p class="measure {{if .Passing}}measure-passing{{else}}measure-failing{{end}}">{{if .Passing}}Passing{{else}}Failing{{end}} &mdash; {{printf "%.2f" .YesPercent}}% yes ({{.Threshold}} required)</p>
+			{{- end}}
+			{{- with .RCV}}
+			<div class="rcv-rounds">
+				{{- range .Rounds}}
+				<h4>Round {{.Round}}{{if .Eliminated}} &mdash; eliminated: {{join .Eliminated ", "}}{{end}}</h4>
+				<table>
+					<tbody>
+					{{- range .Tallies}}
+						<tr class="rcv-{{.Status}}"><td>{{.Candidate}}</td><td>{{.Votes}}</td><td>{{if .Transfer}}{{printf "%+d" .Transfer}}{{end}}</td></tr>
+					{{- end}}
+					</tbody>
+				</table>
+				{{- end}}
+				{{- if .Winner}}<p class="rcv-winner">Winner: {{.Winner}}</p>{{end}}
+			</div>
+			{{- end}}
+		</div>
+	{{- else}}
+		<p>No contests found.</p>
+	{{- end}}
+	</div>
+	{{- with .License}}
+	<p class="attribution">{{.Attribution}}{{if .URL}} (<a href="{{.URL}}">{{.Name}}</a>){{else if .Name}} ({{.Name}}){{end}}</p>
+	{{- end}}
+</div>
+`
+
+var fragment = template.Must(template.New("results").Funcs(Funcs()).Parse(resultsFragment))
+
+// Funcs returns the template functions available to the results fragment,
+// for templates that lay results out themselves.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"percent": percent,
+		"join":    strings.Join,
+	}
+}
+
+// HTML renders parsed results as an embeddable HTML fragment.
+func HTML(results *models.Results) (string, error) {
+	var buf bytes.Buffer
+	if err := fragment.Execute(&buf, results); err != nil {
+		return "", fmt.Errorf("render results: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func percent(votes, total int) string {
+	return fmt.Sprintf("%.2f%%", outcome.Percent(votes, total))
+}
@@ -0,0 +1,105 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/outcome"
+)
+
+// LiteContest is the compact JSON form of a contest: short keys and
+// candidates as [name, votes, percent] tuples, for low-bandwidth consumers.
+type LiteContest struct {
+	ID  string  `json:"id"`
+	T   string  `json:"t"`
+	Rpt float64 `json:"rpt,omitempty"` // precincts reporting, percent
+	C   [][]any `json:"c"`
+}
+
+// LiteResults is the compact JSON form of a county's results.
+type LiteResults struct {
+	County string        `json:"county"`
+	At     int64         `json:"at"` // unix seconds
+	Attr   string        `json:"attr,omitempty"`
+	R      []LiteContest `json:"r"`
+}
+
+// Lite converts results to their compact JSON form, leaders first.
+func Lite(results *models.Results) LiteResults {
+	out := LiteResults{County: results.County, At: results.ParsedAt.Unix(), R: make([]LiteContest, 0, len(results.Contests))}
+	if results.License != nil {
+		out.Attr = results.License.Attribution
+	}
+	for _, c := range results.Contests {
+		total := c.TotalVotes()
+		lc := LiteContest{ID: c.ID, T: c.Title, Rpt: round1(outcome.Percent(c.PrecinctsReporting, c.PrecinctsTotal))}
+		for _, cand := range leadersFirst(c.Candidates) {
+			lc.C = append(lc.C, []any{cand.Name, cand.Votes, round1(outcome.Percent(cand.Votes, total))})
+		}
+		out.R = append(out.R, lc)
+	}
+	return out
+}
+
+// LiteText renders one line per contest, e.g.
+//
+//	Mayor (60% rpt): Jane Doe 1200 60.0% | John Roe 800 40.0%
+//
+// A positive maxLen truncates the output for SMS-sized messages.
+func LiteText(results *models.Results, maxLen int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", results.County, results.ParsedAt.Format("01/02 15:04 MST"))
+	for _, c := range results.Contests {
+		b.WriteString(liteLine(c))
+		b.WriteByte('\n')
+	}
+	if results.License != nil && results.License.Attribution != "" {
+		fmt.Fprintf(&b, "Source: %s\n", results.License.Attribution)
+	}
+	return truncate(b.String(), maxLen)
+}
+
+// LiteAggregateText renders a statewide rollup as a single line.
+func LiteAggregateText(a *models.Aggregate, maxLen int) string {
+	parts := make([]string, 0, len(a.Candidates))
+	for _, cand := range a.Candidates {
+		parts = append(parts, fmt.Sprintf("%s %d %.1f%%", cand.Name, cand.Votes, cand.Percent))
+	}
+	line := fmt.Sprintf("%s (%d counties, %.0f%% rpt): %s\n", a.Title, a.Counties, a.ReportingPercent, strings.Join(parts, " | "))
+	return truncate(line, maxLen)
+}
+
+func liteLine(c models.Contest) string {
+	total := c.TotalVotes()
+	parts := make([]string, 0, len(c.Candidates))
+	for _, cand := range leadersFirst(c.Candidates) {
+		parts = append(parts, fmt.Sprintf("%s %d %.1f%%", cand.Name, cand.Votes, outcome.Percent(cand.Votes, total)))
+	}
+	title := c.Title
+	if c.PrecinctsTotal > 0 {
+		title = fmt.Sprintf("%s (%.0f%% rpt)", title, outcome.Percent(c.PrecinctsReporting, c.PrecinctsTotal))
+	}
+	return title + ": " + strings.Join(parts, " | ")
+}
+
+func leadersFirst(cands []models.Candidate) []models.Candidate {
+	out := append([]models.Candidate(nil), cands...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Votes > out[j].Votes })
+	return out
+}
+
+func round1(f float64) float64 {
+	return float64(int(f*10+0.5)) / 10
+}
+
+func truncate(s string, maxLen int) string {
+	if maxLen <= 0 || len(s) <= maxLen {
+		return s
+	}
+	if maxLen <= 3 {
+		return s[:maxLen]
+	}
+	return s[:maxLen-3] + "..."
+}
@@ -0,0 +1,448 @@
+// Package graphql serves a read-only GraphQL endpoint over the same data as
+// the REST API. Root fields are resolved by Go functions; their results are
+// encoded exactly as the REST API would encode them and the query's
+// selections are then projected onto that JSON, so the two APIs can't drift
+// apart and visibility rules apply to both.
+//
+// List fields below the root accept generic filter arguments: first: Int
+// limits the list, <field>: value keeps elements whose field equals value,
+// and <field>_contains: String keeps elements whose field contains it,
+// ignoring case.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxDepth bounds selection nesting, including through fragments.
+	maxDepth = 12
+	// MaxQueryBytes bounds the size of an accepted query document.
+	MaxQueryBytes = 64 << 10
+)
+
+// Resolver resolves a root query field from its arguments.
+type Resolver func(ctx context.Context, args map[string]any) (any, error)
+
+type field struct {
+	params  []string
+	resolve Resolver
+}
+
+// Schema is the set of root query fields.
+type Schema struct {
+	fields map[string]field
+}
+
+// NewSchema returns an empty schema.
+func NewSchema() *Schema {
+	return &Schema{fields: make(map[string]field)}
+}
+
+// Field registers a root field. params are the arguments passed to resolve;
+// any other argument is applied as a generic filter to the resolved list.
+func (s *Schema) Field(name string, params []string, resolve Resolver) {
+	s.fields[name] = field{params: params, resolve: resolve}
+}
+
+// Request is a GraphQL request body.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is a GraphQL response body.
+type Response struct {
+	Data   *object `json:"data"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Error is one entry of a response's errors.
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// object is a JSON object that keeps its keys in selection order.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+func newObject() *object {
+	return &object{values: make(map[string]any)}
+}
+
+func (o *object) set(key string, v any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		v, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// ShapeFunc turns a resolved value into what the caller may see, as the REST
+// API's serialization does.
+type ShapeFunc func(any) any
+
+// Execute runs req against the schema. Field errors are reported alongside
+// partial data; a request that can't be run at all has no data.
+func (s *Schema) Execute(ctx context.Context, req Request, shape ShapeFunc) Response {
+	if len(req.Query) > MaxQueryBytes {
+		return Response{Errors: []Error{{Message: "query is too large"}}}
+	}
+	doc, err := parse(req.Query)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	op, err := pickOperation(doc, req.OperationName)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	vars := make(map[string]any, len(op.variables))
+	for name, def := range op.variables {
+		vars[name] = def
+		if v, ok := req.Variables[name]; ok {
+			vars[name] = v
+		}
+	}
+
+	e := &executor{doc: doc, vars: vars}
+	data := newObject()
+	roots, err := e.collect(op.selections, 0)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	for _, sel := range roots {
+		data.set(sel.alias, e.root(ctx, s, sel, shape))
+	}
+	return Response{Data: data, Errors: e.errors}
+}
+
+func pickOperation(doc *document, name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("operationName is required for documents with several operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+type executor struct {
+	doc    *document
+	vars   map[string]any
+	errors []Error
+}
+
+func (e *executor) fail(path []any, format string, args ...any) {
+	e.errors = append(e.errors, Error{Message: fmt.Sprintf(format, args...), Path: slices.Clone(path)})
+}
+
+func (e *executor) root(ctx context.Context, s *Schema, sel *selection, shape ShapeFunc) any {
+	path := []any{sel.alias}
+	if sel.name == "__typename" {
+		return "Query"
+	}
+	f, ok := s.fields[sel.name]
+	if !ok {
+		e.fail(path, "cannot query field %q on type Query", sel.name)
+		return nil
+	}
+
+	args, err := e.arguments(sel.args)
+	if err != nil {
+		e.fail(path, "%v", err)
+		return nil
+	}
+	params := make(map[string]any)
+	filters := make(map[string]any)
+	for k, v := range args {
+		if slices.Contains(f.params, k) {
+			params[k] = v
+		} else {
+			filters[k] = v
+		}
+	}
+
+	v, err := f.resolve(ctx, params)
+	if err != nil {
+		e.fail(path, "%v", err)
+		return nil
+	}
+	tree, err := toTree(shape(v))
+	if err != nil {
+		e.fail(path, "%v", err)
+		return nil
+	}
+	if len(filters) > 0 {
+		if tree, err = filter(tree, filters); err != nil {
+			e.fail(path, "%v", err)
+			return nil
+		}
+	}
+	return e.project(tree, sel, path, 1)
+}
+
+// project applies sel's sub-selections to v.
+func (e *executor) project(v any, sel *selection, path []any, depth int) any {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			out[i] = e.project(item, sel, append(path, i), depth)
+		}
+		return out
+	case map[string]any:
+		if sel.selections == nil {
+			e.fail(path, "field %q of object type must have a selection of subfields", sel.name)
+			return nil
+		}
+		if depth > maxDepth {
+			e.fail(path, "query is nested too deeply")
+			return nil
+		}
+		children, err := e.collect(sel.selections, depth)
+		if err != nil {
+			e.fail(path, "%v", err)
+			return nil
+		}
+		obj := newObject()
+		for _, c := range children {
+			if c.name == "__typename" {
+				obj.set(c.alias, "Object")
+				continue
+			}
+			child := t[c.name]
+			if len(c.args) > 0 {
+				args, err := e.arguments(c.args)
+				if err == nil {
+					child, err = filter(child, args)
+				}
+				if err != nil {
+					e.fail(append(path, c.alias), "%v", err)
+					obj.set(c.alias, nil)
+					continue
+				}
+			}
+			obj.set(c.alias, e.project(child, c, append(path, c.alias), depth+1))
+		}
+		return obj
+	default:
+		if sel.selections != nil {
+			e.fail(path, "field %q is a scalar and has no subfields", sel.name)
+			return nil
+		}
+		return v
+	}
+}
+
+// collect flattens fragments and applies @skip/@include.
+func (e *executor) collect(sels []*selection, depth int) ([]*selection, error) {
+	if depth > maxDepth {
+		return nil, errors.New("query is nested too deeply")
+	}
+	var out []*selection
+	for _, s := range sels {
+		include, err := e.included(s)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			continue
+		}
+		switch {
+		case s.fragment != "":
+			frag, ok := e.doc.fragments[s.fragment]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %q", s.fragment)
+			}
+			inner, err := e.collect(frag, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, inner...)
+		case s.inline != nil:
+			inner, err := e.collect(s.inline, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, inner...)
+		default:
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (e *executor) included(s *selection) (bool, error) {
+	for name, want := range map[string]bool{"skip": false, "include": true} {
+		d, ok := s.directives[name]
+		if !ok {
+			continue
+		}
+		v, err := e.resolve(d["if"])
+		if err != nil {
+			return false, err
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s requires a boolean if argument", name)
+		}
+		if b != want {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// arguments substitutes variables into args.
+func (e *executor) arguments(args map[string]any) (map[string]any, error) {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		r, err := e.resolve(v)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = r
+	}
+	return out, nil
+}
+
+func (e *executor) resolve(v any) (any, error) {
+	switch t := v.(type) {
+	case variable:
+		val, ok := e.vars[string(t)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", t)
+		}
+		return val, nil
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			r, err := e.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			r, err := e.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// filter applies generic list arguments to v.
+func filter(v any, args map[string]any) (any, error) {
+	list, ok := v.([]any)
+	if !ok {
+		if v == nil {
+			return nil, nil
+		}
+		return nil, errors.New("arguments are only supported on list fields")
+	}
+
+	first := -1
+	var out []any
+	for _, item := range list {
+		obj, _ := item.(map[string]any)
+		keep := true
+		for k, want := range args {
+			if k == "first" {
+				continue
+			}
+			if name, ok := strings.CutSuffix(k, "_contains"); ok {
+				got := fmt.Sprint(obj[name])
+				if obj[name] == nil || !strings.Contains(strings.ToLower(got), strings.ToLower(fmt.Sprint(want))) {
+					keep = false
+				}
+				continue
+			}
+			if obj == nil || fmt.Sprint(obj[k]) != fmt.Sprint(want) {
+				keep = false
+			}
+		}
+		if keep {
+			out = append(out, item)
+		}
+	}
+
+	if n, ok := args["first"]; ok {
+		switch t := n.(type) {
+		case int:
+			first = t
+		case float64:
+			first = int(t)
+		default:
+			return nil, errors.New("first must be an integer")
+		}
+		if first < 0 {
+			return nil, errors.New("first must not be negative")
+		}
+		if first < len(out) {
+			out = out[:first]
+		}
+	}
+	if out == nil {
+		out = []any{}
+	}
+	return out, nil
+}
+
+// toTree converts v to its generic JSON form, keeping numbers exact.
+func toTree(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
@@ -0,0 +1,377 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The query language subset understood here: query operations with
+// variables, fields with aliases and arguments, nested selections, named and
+// inline fragments, and the @skip/@include directives. Fragment type
+// conditions are accepted but not checked, since resolvers aren't typed.
+
+type document struct {
+	operations []*operation
+	fragments  map[string][]*selection
+}
+
+type operation struct {
+	name       string
+	variables  map[string]any // declared defaults
+	selections []*selection
+}
+
+type selection struct {
+	// A field selection.
+	alias, name string
+	args        map[string]any
+	selections  []*selection
+
+	// A fragment spread (fragment set) or inline fragment (inline set).
+	fragment string
+	inline   []*selection
+
+	directives map[string]map[string]any
+}
+
+// variable is an unresolved $name reference in an argument value.
+type variable string
+
+type token struct {
+	kind  byte // 'n' name, 's' string, '0' number, 'p' punctuator, 0 EOF
+	value string
+	pos   int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+func parse(src string) (doc *document, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if pe, ok := r.(parseError); ok {
+				doc, err = nil, pe
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	p := &parser{src: src}
+	p.next()
+	doc = &document{fragments: make(map[string][]*selection)}
+	for p.tok.kind != 0 {
+		switch {
+		case p.peek('p', "{"):
+			doc.operations = append(doc.operations, &operation{selections: p.selectionSet()})
+		case p.peek('n', "query"):
+			doc.operations = append(doc.operations, p.operation())
+		case p.peek('n', "mutation"), p.peek('n', "subscription"):
+			p.fail("only query operations are supported")
+		case p.peek('n', "fragment"):
+			p.next()
+			name := p.name()
+			p.expectName("on")
+			p.name()
+			doc.fragments[name] = p.selectionSet()
+		default:
+			p.fail("unexpected %q", p.tok.value)
+		}
+	}
+	if len(doc.operations) == 0 {
+		p.fail("no operation in document")
+	}
+	return doc, nil
+}
+
+type parseError struct {
+	msg string
+}
+
+func (e parseError) Error() string { return e.msg }
+
+func (p *parser) fail(format string, args ...any) {
+	line := 1 + strings.Count(p.src[:p.tok.pos], "\n")
+	panic(parseError{fmt.Sprintf("syntax error at line %d: %s", line, fmt.Sprintf(format, args...))})
+}
+
+func (p *parser) operation() *operation {
+	p.next() // query
+	op := &operation{variables: make(map[string]any)}
+	if p.tok.kind == 'n' {
+		op.name = p.name()
+	}
+	if p.peek('p', "(") {
+		p.next()
+		for !p.peek('p', ")") {
+			p.expect("$")
+			name := p.name()
+			p.expect(":")
+			p.typeRef()
+			if p.peek('p', "=") {
+				p.next()
+				op.variables[name] = p.value(true)
+			} else {
+				op.variables[name] = nil
+			}
+		}
+		p.next()
+	}
+	p.directives()
+	op.selections = p.selectionSet()
+	return op
+}
+
+// typeRef skips a variable type such as [String!]!.
+func (p *parser) typeRef() {
+	if p.peek('p', "[") {
+		p.next()
+		p.typeRef()
+		p.expect("]")
+	} else {
+		p.name()
+	}
+	if p.peek('p', "!") {
+		p.next()
+	}
+}
+
+func (p *parser) selectionSet() []*selection {
+	p.expect("{")
+	var out []*selection
+	for !p.peek('p', "}") {
+		out = append(out, p.selection())
+	}
+	p.next()
+	return out
+}
+
+func (p *parser) selection() *selection {
+	if p.peek('p', "...") {
+		p.next()
+		if p.peek('n', "on") {
+			p.next()
+			p.name()
+		} else if p.tok.kind == 'n' {
+			s := &selection{fragment: p.name()}
+			s.directives = p.directives()
+			return s
+		}
+		s := &selection{}
+		s.directives = p.directives()
+		s.inline = p.selectionSet()
+		return s
+	}
+
+	s := &selection{name: p.name()}
+	if p.peek('p', ":") {
+		p.next()
+		s.alias, s.name = s.name, p.name()
+	}
+	if s.alias == "" {
+		s.alias = s.name
+	}
+	if p.peek('p', "(") {
+		s.args = p.arguments()
+	}
+	s.directives = p.directives()
+	if p.peek('p', "{") {
+		s.selections = p.selectionSet()
+	}
+	return s
+}
+
+func (p *parser) arguments() map[string]any {
+	p.expect("(")
+	args := make(map[string]any)
+	for !p.peek('p', ")") {
+		name := p.name()
+		p.expect(":")
+		args[name] = p.value(false)
+	}
+	p.next()
+	return args
+}
+
+func (p *parser) directives() map[string]map[string]any {
+	var out map[string]map[string]any
+	for p.peek('p', "@") {
+		p.next()
+		name := p.name()
+		var args map[string]any
+		if p.peek('p', "(") {
+			args = p.arguments()
+		}
+		if out == nil {
+			out = make(map[string]map[string]any)
+		}
+		out[name] = args
+	}
+	return out
+}
+
+// value parses an input value. Variables aren't allowed in constants.
+func (p *parser) value(constant bool) any {
+	t := p.tok
+	switch {
+	case t.kind == 'p' && t.value == "$":
+		if constant {
+			p.fail("variables are not allowed here")
+		}
+		p.next()
+		return variable(p.name())
+	case t.kind == 's':
+		p.next()
+		return t.value
+	case t.kind == '0':
+		p.next()
+		if n, err := strconv.Atoi(t.value); err == nil {
+			return n
+		}
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			p.fail("invalid number %q", t.value)
+		}
+		return f
+	case t.kind == 'n':
+		p.next()
+		switch t.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return t.value // enum values are passed as strings
+	case t.kind == 'p' && t.value == "[":
+		p.next()
+		list := []any{}
+		for !p.peek('p', "]") {
+			list = append(list, p.value(constant))
+		}
+		p.next()
+		return list
+	case t.kind == 'p' && t.value == "{":
+		p.next()
+		obj := make(map[string]any)
+		for !p.peek('p', "}") {
+			name := p.name()
+			p.expect(":")
+			obj[name] = p.value(constant)
+		}
+		p.next()
+		return obj
+	}
+	p.fail("unexpected %q", t.value)
+	return nil
+}
+
+func (p *parser) peek(kind byte, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) expect(punct string) {
+	if !p.peek('p', punct) {
+		p.fail("expected %q, found %q", punct, p.tok.value)
+	}
+	p.next()
+}
+
+func (p *parser) expectName(name string) {
+	if !p.peek('n', name) {
+		p.fail("expected %q, found %q", name, p.tok.value)
+	}
+	p.next()
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != 'n' {
+		p.fail("expected a name, found %q", p.tok.value)
+	}
+	name := p.tok.value
+	p.next()
+	return name
+}
+
+// next advances to the next token, skipping whitespace, commas and comments.
+func (p *parser) next() {
+	src := p.src
+	for p.pos < len(src) {
+		c := src[p.pos]
+		if c == '#' {
+			for p.pos < len(src) && src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if c == ',' || c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			p.pos++
+			continue
+		}
+		break
+	}
+
+	start := p.pos
+	p.tok = token{pos: start}
+	if p.pos >= len(src) {
+		return
+	}
+
+	c := src[p.pos]
+	switch {
+	case strings.HasPrefix(src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: 'p', value: "...", pos: start}
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		p.pos++
+		p.tok = token{kind: 'p', value: string(c), pos: start}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.pos < len(src) && (src[p.pos] == '_' || isAlnum(src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: 'n', value: src[start:p.pos], pos: start}
+	case c == '-' || (c >= '0' && c <= '9'):
+		p.pos++
+		for p.pos < len(src) && (isAlnum(src[p.pos]) || src[p.pos] == '.' || src[p.pos] == '-' || src[p.pos] == '+') {
+			p.pos++
+		}
+		p.tok = token{kind: '0', value: src[start:p.pos], pos: start}
+	case c == '"':
+		p.tok = token{kind: 's', value: p.stringValue(), pos: start}
+	default:
+		p.fail("unexpected character %q", c)
+	}
+}
+
+// stringValue reads a quoted string; block strings aren't supported.
+func (p *parser) stringValue() string {
+	src := p.src
+	end := p.pos + 1
+	for end < len(src) && src[end] != '"' {
+		if src[end] == '\\' {
+			end++
+		}
+		if end < len(src) && src[end] == '\n' {
+			break
+		}
+		end++
+	}
+	if end >= len(src) || src[end] != '"' {
+		p.fail("unterminated string")
+	}
+	s, err := strconv.Unquote(src[p.pos : end+1])
+	if err != nil {
+		p.fail("invalid string %s", src[p.pos:end+1])
+	}
+	p.pos = end + 1
+	return s
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,79 @@
+package grpcapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// cursorHashLen is how much of each snapshot hash a cursor keeps: enough to
+// tell a county's snapshots apart while keeping the cursor short.
+const cursorHashLen = 16
+
+var errBadCursor = errors.New("malformed cursor")
+
+// cursor records how far an update stream got: the snapshot last sent for
+// each county and the region that sent it. Every streamed message carries
+// the cursor as of that message; a client that reconnects passes back the
+// last one it saw and is sent only what changed since.
+type cursor struct {
+	Region   string                 `json:"r,omitempty"`
+	Counties map[string]cursorEntry `json:"c"`
+}
+
+type cursorEntry struct {
+	Hash     string `json:"h"`
+	ParsedAt int64  `json:"t"` // Unix milliseconds
+	// Calls fingerprints the race calls sent with the snapshot, which can
+	// change while the snapshot doesn't.
+	Calls string `json:"k,omitempty"`
+}
+
+func entryFor(results *models.Results) cursorEntry {
+	hash := strings.TrimPrefix(results.Hash, "sha256:")
+	if len(hash) > cursorHashLen {
+		hash = hash[:cursorHashLen]
+	}
+	return cursorEntry{Hash: hash, ParsedAt: results.ParsedAt.UnixMilli(), Calls: callsPrint(results)}
+}
+
+// callsPrint returns a short fingerprint of the race calls in results, or
+// "" if none is called.
+func callsPrint(results *models.Results) string {
+	h := fnv.New64a()
+	called := false
+	for _, c := range results.Contests {
+		if c.Outcome == nil || c.Outcome.Call == nil {
+			continue
+		}
+		called = true
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00", c.ID, c.Outcome.Call.Winner, c.Outcome.Call.Method, c.Outcome.Call.CalledBy)
+	}
+	if !called {
+		return ""
+	}
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func parseCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return cursor{}, errBadCursor
+	}
+	if c.Counties == nil {
+		c.Counties = make(map[string]cursorEntry)
+	}
+	return c, nil
+}
@@ -0,0 +1,114 @@
+package grpcapi
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// encodeDelta encodes next as a CountyResults with delta set: the changes
+// since prev, the county's results as last sent on the stream. The county,
+// source, parse time and hash are always sent; the license and turnout
+// only when they changed. Of the contests, only those that changed are
+// sent, with all of their own fields but, while their candidates are the
+// same, only the candidates that changed. A contest that is new or whose
+// candidates changed is sent whole, with complete set. Each candidate sent
+// carries its vote delta; removed contests are listed by ID.
+//
+// A change a delta can't carry, the license or turnout being removed, is
+// sent as the full results instead.
+func encodeDelta(e *encoder, prev, next *models.Results) {
+	if (prev.License != nil && next.License == nil) || (prev.Turnout != nil && next.Turnout == nil) {
+		encodeResults(e, next)
+		return
+	}
+	e.string(1, next.County)
+	e.string(2, next.ContentType)
+	e.string(3, next.Source)
+	if !next.ParsedAt.IsZero() {
+		e.string(4, next.ParsedAt.UTC().Format(time.RFC3339Nano))
+	}
+	if next.License != nil && (prev.License == nil || *prev.License != *next.License) {
+		e.message(5, func(e *encoder) { encodeLicense(e, next.License) })
+	}
+	if next.Turnout != nil && (prev.Turnout == nil || !unchanged(prev.Turnout, next.Turnout, encodeTurnout)) {
+		e.message(6, func(e *encoder) { encodeTurnout(e, next.Turnout) })
+	}
+
+	before := make(map[string]*models.Contest, len(prev.Contests))
+	for i := range prev.Contests {
+		before[prev.Contests[i].ID] = &prev.Contests[i]
+	}
+	for i := range next.Contests {
+		c := &next.Contests[i]
+		old, ok := before[c.ID]
+		delete(before, c.ID)
+		if ok && unchanged(old, c, encodeContest) {
+			continue
+		}
+		e.message(7, func(e *encoder) { encodeContestDelta(e, old, c) })
+	}
+	e.string(8, next.Hash)
+	e.bool(10, true)
+	for i := range prev.Contests {
+		if id := prev.Contests[i].ID; before[id] != nil {
+			e.string(11, id)
+		}
+	}
+}
+
+// encodeContestDelta encodes c, which changed since old, nil if c is new.
+func encodeContestDelta(e *encoder, old, c *models.Contest) {
+	encodeContestFields(e, c)
+	if old == nil || !sameCandidates(old, c) {
+		votes := make(map[string]int)
+		if old != nil {
+			for _, cand := range old.Candidates {
+				votes[cand.Name] = cand.Votes
+			}
+		}
+		for i := range c.Candidates {
+			cand := &c.Candidates[i]
+			e.message(4, func(e *encoder) {
+				encodeCandidate(e, cand)
+				e.int(8, cand.Votes-votes[cand.Name])
+			})
+		}
+		e.bool(10, true)
+		return
+	}
+	for i := range c.Candidates {
+		cand, was := &c.Candidates[i], &old.Candidates[i]
+		if unchanged(was, cand, encodeCandidate) {
+			continue
+		}
+		e.message(4, func(e *encoder) {
+			encodeCandidate(e, cand)
+			e.int(8, cand.Votes-was.Votes)
+		})
+	}
+}
+
+// sameCandidates reports whether a and b list the same candidates in the
+// same order, so b's can be sent as changes to a's.
+func sameCandidates(a, b *models.Contest) bool {
+	if len(a.Candidates) != len(b.Candidates) {
+		return false
+	}
+	for i := range a.Candidates {
+		if a.Candidates[i].Name != b.Candidates[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+// unchanged reports whether a and b encode the same, so a change to a
+// field the stream doesn't carry isn't sent as one.
+func unchanged[T any](a, b *T, encode func(*encoder, *T)) bool {
+	var ea, eb encoder
+	encode(&ea, a)
+	encode(&eb, b)
+	return bytes.Equal(ea.b, eb.b)
+}
@@ -0,0 +1,237 @@
+package grpcapi
+
+import (
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// Field numbers below follow proto/era/v1/results.proto.
+
+func encodeResults(e *encoder, r *models.Results) {
+	e.string(1, r.County)
+	e.string(2, r.ContentType)
+	e.string(3, r.Source)
+	if !r.ParsedAt.IsZero() {
+		e.string(4, r.ParsedAt.UTC().Format(time.RFC3339Nano))
+	}
+	if r.License != nil {
+		e.message(5, func(e *encoder) { encodeLicense(e, r.License) })
+	}
+	if r.Turnout != nil {
+		e.message(6, func(e *encoder) { encodeTurnout(e, r.Turnout) })
+	}
+	for i := range r.Contests {
+		e.message(7, func(e *encoder) { encodeContest(e, &r.Contests[i]) })
+	}
+	e.string(8, r.Hash)
+}
+
+func encodeTurnout(e *encoder, t *models.Turnout) {
+	e.int(1, t.RegisteredVoters)
+	e.int(2, t.BallotsCast)
+	e.double(3, t.Percent)
+	for _, p := range t.Precincts {
+		e.message(4, func(e *encoder) {
+			e.string(1, p.Name)
+			e.int(2, p.RegisteredVoters)
+			e.int(3, p.BallotsCast)
+			e.double(4, p.Percent)
+		})
+	}
+}
+
+func encodeLicense(e *encoder, l *models.License) {
+	e.string(1, l.Name)
+	e.string(2, l.Attribution)
+	e.string(3, l.URL)
+	e.string(4, l.Redistribution)
+}
+
+func encodeContest(e *encoder, c *models.Contest) {
+	encodeContestFields(e, c)
+	for i := range c.Candidates {
+		e.message(4, func(e *encoder) { encodeCandidate(e, &c.Candidates[i]) })
+	}
+}
+
+func encodeCandidate(e *encoder, cand *models.Candidate) {
+	e.string(1, cand.Name)
+	e.string(2, cand.Party)
+	e.int(3, cand.Votes)
+	e.string(4, cand.CanonicalID)
+	e.string(5, cand.RawName)
+	e.bool(6, cand.WriteIn)
+	e.bool(7, cand.WriteInAggregate)
+}
+
+// encodeContestFields encodes the fields of a contest other than its
+// candidates.
+func encodeContestFields(e *encoder, c *models.Contest) {
+	e.string(1, c.ID)
+	e.string(2, c.Title)
+	e.string(3, c.RawTitle)
+	e.int(5, c.PrecinctsReporting)
+	e.int(6, c.PrecinctsTotal)
+	if t := c.RCV; t != nil {
+		e.message(7, func(e *encoder) {
+			for _, round := range t.Rounds {
+				e.message(1, func(e *encoder) {
+					e.int(1, round.Round)
+					for _, tally := range round.Tallies {
+						e.message(2, func(e *encoder) {
+							e.string(1, tally.Candidate)
+							e.int(2, tally.Votes)
+							e.int(3, tally.Transfer)
+							e.string(4, tally.Status)
+						})
+					}
+					e.strings(3, round.Eliminated)
+					e.int(4, round.Exhausted)
+				})
+			}
+			e.string(2, t.Winner)
+			e.int(3, t.Threshold)
+			e.bool(4, t.Final)
+		})
+	}
+	if m := c.Measure; m != nil {
+		e.message(8, func(e *encoder) {
+			e.int(1, m.Yes)
+			e.int(2, m.No)
+			e.double(3, m.YesPercent)
+			e.string(4, m.Threshold)
+			e.bool(5, m.Passing)
+		})
+	}
+	if o := c.Outcome; o != nil && o.Call != nil {
+		e.message(9, func(e *encoder) {
+			e.string(1, o.Call.Winner)
+			e.string(2, o.Call.Method)
+			e.string(3, o.Call.CalledBy)
+			if o.Call.CalledAt != nil {
+				e.string(4, o.Call.CalledAt.UTC().Format(time.RFC3339Nano))
+			}
+		})
+	}
+}
+
+func encodeProcessResponse(resp *models.ProcessResponse) []byte {
+	var e encoder
+	if resp.Results != nil {
+		e.message(1, func(e *encoder) { encodeResults(e, resp.Results) })
+	}
+	e.string(2, resp.HTML)
+	e.strings(3, resp.Warnings)
+	return e.b
+}
+
+// decodeString returns a bytes field as a string.
+func decodeString(v value) (string, error) {
+	if v.wire != wireBytes {
+		return "", errMalformed
+	}
+	return v.string(), nil
+}
+
+func decodeProcessRequest(b []byte) (models.ProcessRequest, error) {
+	var req models.ProcessRequest
+	err := decode(b, func(field int, v value) error {
+		var err error
+		switch field {
+		case 1:
+			req.CountyName, err = decodeString(v)
+		case 2:
+			req.FileLink, err = decodeString(v)
+		case 3:
+			req.ContentType, err = decodeString(v)
+		case 4:
+			req.ParseMethod, err = decodeString(v)
+		case 5:
+			req.MeasureThreshold, err = decodeString(v)
+		case 6:
+			if v.wire != wireBytes {
+				return errMalformed
+			}
+			req.License, err = decodeLicense(v.bytes)
+		}
+		return err
+	})
+	return req, err
+}
+
+func decodeLicense(b []byte) (*models.License, error) {
+	var l models.License
+	err := decode(b, func(field int, v value) error {
+		var err error
+		switch field {
+		case 1:
+			l.Name, err = decodeString(v)
+		case 2:
+			l.Attribution, err = decodeString(v)
+		case 3:
+			l.URL, err = decodeString(v)
+		case 4:
+			l.Redistribution, err = decodeString(v)
+		}
+		return err
+	})
+	return &l, err
+}
+
+func decodeGetResults(b []byte) (county string, err error) {
+	err = decode(b, func(field int, v value) error {
+		if field == 1 {
+			county, err = decodeString(v)
+			return err
+		}
+		return nil
+	})
+	return county, err
+}
+
+// streamRequest is a decoded StreamUpdatesRequest.
+type streamRequest struct {
+	counties    []string
+	skipCurrent bool
+	cursor      string
+	pinRegion   bool
+	deltas      bool
+}
+
+func decodeStreamUpdates(b []byte) (streamRequest, error) {
+	var req streamRequest
+	err := decode(b, func(field int, v value) error {
+		switch field {
+		case 1:
+			s, err := decodeString(v)
+			if err != nil {
+				return err
+			}
+			req.counties = append(req.counties, s)
+		case 2:
+			if v.wire != wireVarint {
+				return errMalformed
+			}
+			req.skipCurrent = v.varint != 0
+		case 3:
+			s, err := decodeString(v)
+			if err != nil {
+				return err
+			}
+			req.cursor = s
+		case 4:
+			if v.wire != wireVarint {
+				return errMalformed
+			}
+			req.pinRegion = v.varint != 0
+		case 5:
+			if v.wire != wireVarint {
+				return errMalformed
+			}
+			req.deltas = v.varint != 0
+		}
+		return nil
+	})
+	return req, err
+}
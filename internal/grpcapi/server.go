@@ -0,0 +1,397 @@
+// Package grpcapi serves the gRPC API defined in proto/era/v1/results.proto
+// alongside the HTTP API. It speaks the gRPC wire protocol directly on
+// net/http's HTTP/2 server: length-prefixed protobuf messages in the body
+// and the call status in trailers. No generated code or gRPC runtime is
+// needed, and calls share the HTTP server's port, middleware and API keys.
+package grpcapi
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/clock"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/outcome"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/region"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/urlpolicy"
+	"github.com/many221/era_api_v1/internal/validate"
+	"github.com/many221/era_api_v1/internal/visibility"
+)
+
+const (
+	// ServicePath prefixes the path of every method of the Results service.
+	ServicePath = "/era.v1.Results/"
+
+	// maxMessageBytes bounds an accepted request message.
+	maxMessageBytes = 4 << 20
+)
+
+// Status codes defined by gRPC.
+const (
+	codeOK                 = 0
+	codeCanceled           = 1
+	codeInvalidArgument    = 3
+	codeDeadlineExceeded   = 4
+	codeNotFound           = 5
+	codePermissionDenied   = 7
+	codeResourceExhausted  = 8
+	codeFailedPrecondition = 9
+	codeUnimplemented      = 12
+	codeInternal           = 13
+	codeUnavailable        = 14
+)
+
+// statusError is an error with a gRPC status code.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string { return e.msg }
+
+func errorf(code int, format string, args ...any) error {
+	return &statusError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// Server implements the Results service.
+type Server struct {
+	proc   *processor.Processor
+	store  store.Store
+	logger *slog.Logger
+	id     region.Identity
+	clock  clock.Clock
+
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+
+	done     chan struct{}
+	shutdown sync.Once
+}
+
+// NewServer returns a Server that processes sources with proc and reads
+// results from st. Register its OnSnapshot with the processor so
+// StreamUpdates sees new snapshots.
+func NewServer(proc *processor.Processor, st store.Store, logger *slog.Logger) *Server {
+	return &Server{
+		proc:   proc,
+		store:  st,
+		logger: logger,
+		id:     region.New("", ""),
+		clock:  clock.System{},
+		subs:   make(map[*subscriber]struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// SetIdentity sets the region and instance reported in response metadata
+// and stream cursors. It must be called before serving.
+func (s *Server) SetIdentity(id region.Identity) {
+	s.id = id
+}
+
+// SetClock sets the clock embargoes are lifted by, which should be the one
+// snapshots are timestamped with. It must be called before serving.
+func (s *Server) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// IsGRPC reports whether r is a gRPC call rather than a plain HTTP request.
+func IsGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// Shutdown ends open update streams with UNAVAILABLE so clients reconnect
+// elsewhere. Register it with http.Server.RegisterOnShutdown.
+func (s *Server) Shutdown() {
+	s.shutdown.Do(func() { close(s.done) })
+}
+
+// call is the response side of one gRPC call.
+type call struct {
+	w     http.ResponseWriter
+	rc    *http.ResponseController
+	wrote bool
+}
+
+func (c *call) send(msg []byte) error {
+	if !c.wrote {
+		c.w.WriteHeader(http.StatusOK)
+		c.wrote = true
+	}
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	if _, err := c.w.Write(append(frame, msg...)); err != nil {
+		return err
+	}
+	return c.rc.Flush()
+}
+
+// finish reports the call's status: in trailers after messages, or as a
+// trailers-only response when nothing was sent.
+func (c *call) finish(err error) {
+	code, msg := codeOK, ""
+	if err != nil {
+		code, msg = codeInternal, err.Error()
+		var se *statusError
+		if errors.As(err, &se) {
+			code, msg = se.code, se.msg
+		}
+	}
+
+	prefix := ""
+	if c.wrote {
+		prefix = http.TrailerPrefix
+	}
+	h := c.w.Header()
+	h.Set(prefix+"Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		h.Set(prefix+"Grpc-Message", encodeGRPCMessage(msg))
+	}
+	if !c.wrote {
+		c.w.WriteHeader(http.StatusOK)
+	}
+}
+
+// ServeHTTP handles a gRPC call. Route only requests for which IsGRPC is
+// true here.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "application/grpc" && ct != "application/grpc+proto" {
+		http.Error(w, "Unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Accept-Encoding", "identity")
+	c := &call{w: w, rc: http.NewResponseController(w)}
+
+	ctx := r.Context()
+	if v := r.Header.Get("Grpc-Timeout"); v != "" {
+		d, ok := parseTimeout(v)
+		if !ok {
+			c.finish(errorf(codeInvalidArgument, "invalid grpc-timeout %q", v))
+			return
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	method, ok := strings.CutPrefix(r.URL.Path, ServicePath)
+	if !ok {
+		c.finish(errorf(codeUnimplemented, "unknown service for %s", r.URL.Path))
+		return
+	}
+	if r.Header.Get("Grpc-Encoding") != "" && r.Header.Get("Grpc-Encoding") != "identity" {
+		c.finish(errorf(codeUnimplemented, "grpc-encoding %q is not supported", r.Header.Get("Grpc-Encoding")))
+		return
+	}
+	msg, err := readMessage(r.Body)
+	if err != nil {
+		c.finish(err)
+		return
+	}
+
+	switch method {
+	case "ProcessSource":
+		err = s.processSource(ctx, c, msg)
+	case "GetResults":
+		err = s.getResults(ctx, c, msg)
+	case "StreamUpdates":
+		err = s.streamUpdates(ctx, c, msg)
+	default:
+		err = errorf(codeUnimplemented, "unknown method %s", method)
+	}
+	if err != nil {
+		s.logger.DebugContext(r.Context(), "grpc call failed", "method", method, "error", err)
+	}
+	c.finish(err)
+}
+
+func (s *Server) processSource(ctx context.Context, c *call, msg []byte) error {
+	if p, ok := auth.FromContext(ctx); ok && !models.RoleAllows(p.Role, models.RoleIngester) {
+		return errorf(codePermissionDenied, "processing sources needs an API key with the %s role", models.RoleIngester)
+	}
+	req, err := decodeProcessRequest(msg)
+	if err != nil {
+		return errorf(codeInvalidArgument, "%v", err)
+	}
+	if err := validate.ProcessRequest(req); err != nil {
+		return errorf(codeInvalidArgument, "%v", err)
+	}
+
+	resp, err := s.proc.Process(ctx, req, nil)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "process failed", "county", req.CountyName, "error", err)
+		return processError(err)
+	}
+	resp, err = shapeAs(ctx, resp)
+	if err != nil {
+		return err
+	}
+	return c.send(encodeProcessResponse(resp))
+}
+
+func (s *Server) getResults(ctx context.Context, c *call, msg []byte) error {
+	county, err := decodeGetResults(msg)
+	if err != nil {
+		return errorf(codeInvalidArgument, "%v", err)
+	}
+	results, err := s.store.Results(county)
+	if p, ok := auth.FromContext(ctx); ok && p.Tier.Delayed() {
+		results, err = p.Tier.Results(s.clock.Now().UTC(), func(at time.Time) (*models.Results, error) {
+			return s.store.ElectionResultsAt("", county, at)
+		})
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return errorf(codeNotFound, "no results for county %q", county)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.sendResults(ctx, c, s.withOutcomes(results), nil, "")
+	return err
+}
+
+// withOutcomes returns a copy of results with each contest's outcome, as
+// the HTTP API serves them, leaving results itself untouched as it may be
+// shared between streams.
+func (s *Server) withOutcomes(results *models.Results) *models.Results {
+	out := *results
+	out.Contests = append([]models.Contest(nil), results.Contests...)
+	outcome.Apply(&out, s.store.OutcomeRules(), s.store.RaceCalls(results.Election))
+	return &out
+}
+
+// sendResults sends results followed by the response metadata: who served
+// them, at which store version and, on streams, the cursor. Given prev, the
+// county's results as last sent on the stream, only what changed since is
+// sent. It returns the results as the caller may see them.
+func (s *Server) sendResults(ctx context.Context, c *call, results, prev *models.Results, cur string) (*models.Results, error) {
+	results, err := shapeAs(ctx, results)
+	if err != nil {
+		return nil, err
+	}
+	var e encoder
+	if prev != nil {
+		encodeDelta(&e, prev, results)
+	} else {
+		encodeResults(&e, results)
+	}
+	e.message(9, func(e *encoder) {
+		e.string(1, s.id.Region)
+		e.string(2, s.id.Instance)
+		e.int(3, int(s.store.Version()))
+		e.string(4, cur)
+	})
+	return results, c.send(e.b)
+}
+
+// processError maps pipeline errors onto gRPC codes, as the HTTP handler
+// maps them onto status codes.
+func processError(err error) error {
+	code := codeUnavailable
+	switch {
+	case errors.Is(err, parser.ErrUnsupportedMethod), errors.Is(err, processor.ErrUnknownElection), errors.Is(err, processor.ErrUnknownJurisdiction):
+		code = codeInvalidArgument
+	case errors.Is(err, urlpolicy.ErrBlocked):
+		code = codePermissionDenied
+	case errors.Is(err, parser.ErrNoResults), errors.Is(err, processor.ErrQuarantined):
+		code = codeFailedPrecondition
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codeDeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codeCanceled
+	}
+	return errorf(code, "%v", err)
+}
+
+// shapeAs applies the caller's visibility policy and profile to v, as the
+// HTTP API does when serializing. Hidden fields come back as zero values and
+// are therefore left off the wire.
+func shapeAs[T any](ctx context.Context, v *T) (*T, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok || (p.Policy.Full() && p.Profile == nil) {
+		return v, nil
+	}
+	data, err := json.Marshal(visibility.Shape(v, p.Policy, p.Profile))
+	if err != nil {
+		return nil, err
+	}
+	var out T
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// readMessage reads the single request message of a unary or server
+// streaming call.
+func readMessage(body io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(body, prefix[:]); err != nil {
+		return nil, errorf(codeInvalidArgument, "read request message: %v", err)
+	}
+	if prefix[0] != 0 {
+		return nil, errorf(codeUnimplemented, "compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxMessageBytes {
+		return nil, errorf(codeResourceExhausted, "request message exceeds %d bytes", maxMessageBytes)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(body, msg); err != nil {
+		return nil, errorf(codeInvalidArgument, "read request message: %v", err)
+	}
+	return msg, nil
+}
+
+// parseTimeout parses a grpc-timeout header value such as "250m" or "5S".
+func parseTimeout(v string) (time.Duration, bool) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	unit := map[byte]time.Duration{
+		'H': time.Hour, 'M': time.Minute, 'S': time.Second,
+		'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond,
+	}[v[len(v)-1]]
+	if unit == 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// encodeGRPCMessage percent-encodes a status message as the protocol
+// requires.
+func encodeGRPCMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,204 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// subscriber is one open StreamUpdates call. Snapshots are coalesced per
+// county, so a slow client gets the newest one rather than a backlog.
+type subscriber struct {
+	counties map[string]bool // county keys; nil means every county
+	notify   chan struct{}
+
+	mu      sync.Mutex
+	order   []string
+	pending map[string]*models.Results
+}
+
+func (sub *subscriber) offer(key string, results *models.Results) {
+	if sub.counties != nil && !sub.counties[key] {
+		return
+	}
+	sub.mu.Lock()
+	if _, ok := sub.pending[key]; !ok {
+		sub.order = append(sub.order, key)
+	}
+	sub.pending[key] = results
+	sub.mu.Unlock()
+
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *subscriber) take() []*models.Results {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	out := make([]*models.Results, 0, len(sub.order))
+	for _, key := range sub.order {
+		out = append(out, sub.pending[key])
+	}
+	sub.order = sub.order[:0]
+	clear(sub.pending)
+	return out
+}
+
+// OnSnapshot passes a new snapshot to open update streams. Register it with
+// Processor.OnSnapshot.
+func (s *Server) OnSnapshot(_ context.Context, results *models.Results) {
+	key := store.CountyKey(results.County)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		sub.offer(key, results)
+	}
+}
+
+// OnRaceCall passes the snapshots of the counties reporting a contest that
+// was just called, or had its call retracted, to open update streams again,
+// so they send the change. Streams carry the default election, so calls in
+// others are ignored.
+func (s *Server) OnRaceCall(call models.ManualCall) {
+	if e, err := s.store.Election(""); err != nil || e.ID != call.Election {
+		return
+	}
+	for _, county := range s.store.Counties() {
+		results, err := s.store.Results(county)
+		if err != nil {
+			continue
+		}
+		if _, ok := results.ContestByID(call.ContestID); ok {
+			s.OnSnapshot(context.Background(), results)
+		}
+	}
+}
+
+func (s *Server) streamUpdates(ctx context.Context, c *call, msg []byte) error {
+	if p, ok := auth.FromContext(ctx); ok && p.Tier.Delayed() {
+		return errorf(codePermissionDenied, "updates are streamed in real time, which the API key's access tier doesn't include")
+	}
+	req, err := decodeStreamUpdates(msg)
+	if err != nil {
+		return errorf(codeInvalidArgument, "%v", err)
+	}
+	cur := cursor{Region: s.id.Region, Counties: make(map[string]cursorEntry)}
+	// held marks counties whose cursor entry came from another region and
+	// hasn't been superseded here yet.
+	held := make(map[string]bool)
+	if req.cursor != "" {
+		resumed, err := parseCursor(req.cursor)
+		if err != nil {
+			return errorf(codeInvalidArgument, "%v", err)
+		}
+		crossRegion := resumed.Region != s.id.Region
+		if crossRegion && req.pinRegion {
+			return errorf(codeFailedPrecondition, "cursor was issued in region %q, this is region %q", resumed.Region, s.id.Region)
+		}
+		for key, entry := range resumed.Counties {
+			cur.Counties[key] = entry
+			held[key] = crossRegion
+		}
+	}
+
+	sub := &subscriber{notify: make(chan struct{}, 1), pending: make(map[string]*models.Results)}
+	if len(req.counties) > 0 {
+		sub.counties = make(map[string]bool, len(req.counties))
+		for _, county := range req.counties {
+			sub.counties[store.CountyKey(county)] = true
+		}
+	}
+	// Subscribe before reading the current snapshots so none published in
+	// between is missed.
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, sub)
+		s.mu.Unlock()
+	}()
+
+	// Streams outlive the server's request timeouts.
+	c.rc.SetReadDeadline(time.Time{})
+	c.rc.SetWriteDeadline(time.Time{})
+
+	// With deltas, sent holds the results last sent for each county, which
+	// the next are sent as changes to.
+	var sent map[string]*models.Results
+	if req.deltas {
+		sent = make(map[string]*models.Results)
+	}
+	send := func(results *models.Results) error {
+		results = s.withOutcomes(results)
+		key := store.CountyKey(results.County)
+		entry := entryFor(results)
+		last, ok := cur.Counties[key]
+		if ok && last.Hash == entry.Hash && last.Calls == entry.Calls {
+			return nil
+		}
+		// A region behind the one the client came from would move it back
+		// to an older snapshot; wait until this region catches up.
+		if ok && held[key] && entry.ParsedAt < last.ParsedAt {
+			return nil
+		}
+		delete(held, key)
+		cur.Counties[key] = entry
+		shaped, err := s.sendResults(ctx, c, results, sent[key], cur.encode())
+		if err == nil && sent != nil {
+			sent[key] = shaped
+		}
+		return err
+	}
+
+	// Open the stream right away so clients know they're subscribed.
+	if !c.wrote {
+		c.w.WriteHeader(http.StatusOK)
+		c.wrote = true
+		c.rc.Flush()
+	}
+	if !req.skipCurrent {
+		keys := req.counties
+		if len(keys) == 0 {
+			keys = s.store.Counties()
+		}
+		for _, key := range keys {
+			results, err := s.store.Results(key)
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := send(results); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return errorf(codeDeadlineExceeded, "stream deadline exceeded")
+			}
+			return errorf(codeCanceled, "stream canceled")
+		case <-s.done:
+			return errorf(codeUnavailable, "server is shutting down")
+		case <-sub.notify:
+			for _, results := range sub.take() {
+				if err := send(results); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
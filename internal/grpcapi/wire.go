@@ -0,0 +1,135 @@
+package grpcapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errMalformed = errors.New("malformed protobuf message")
+
+// encoder appends proto3 fields to a buffer. Zero values are omitted, as
+// proto3 does for scalar fields.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) key(field, wire int) {
+	e.b = binary.AppendUvarint(e.b, uint64(field)<<3|uint64(wire))
+}
+
+func (e *encoder) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.key(field, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(s)))
+	e.b = append(e.b, s...)
+}
+
+func (e *encoder) strings(field int, list []string) {
+	for _, s := range list {
+		// Repeated strings keep empty elements.
+		e.key(field, wireBytes)
+		e.b = binary.AppendUvarint(e.b, uint64(len(s)))
+		e.b = append(e.b, s...)
+	}
+}
+
+func (e *encoder) int(field int, n int) {
+	if n == 0 {
+		return
+	}
+	e.key(field, wireVarint)
+	e.b = binary.AppendUvarint(e.b, uint64(int64(n)))
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if !v {
+		return
+	}
+	e.key(field, wireVarint)
+	e.b = append(e.b, 1)
+}
+
+func (e *encoder) double(field int, f float64) {
+	if f == 0 {
+		return
+	}
+	e.key(field, wireFixed64)
+	e.b = binary.LittleEndian.AppendUint64(e.b, math.Float64bits(f))
+}
+
+// message encodes a nested message written by fn.
+func (e *encoder) message(field int, fn func(*encoder)) {
+	var sub encoder
+	fn(&sub)
+	e.key(field, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(sub.b)))
+	e.b = append(e.b, sub.b...)
+}
+
+// value is one decoded field. Only the member matching wire is set.
+type value struct {
+	wire   int
+	varint uint64
+	bytes  []byte
+}
+
+func (v value) string() string { return string(v.bytes) }
+
+// decode calls fn for every field in b, in order. Fields fn doesn't know
+// should be ignored, so newer clients can talk to older servers.
+func decode(b []byte, fn func(field int, v value) error) error {
+	for len(b) > 0 {
+		k, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errMalformed
+		}
+		b = b[n:]
+		field, wire := int(k>>3), int(k&7)
+		if field <= 0 {
+			return errMalformed
+		}
+
+		v := value{wire: wire}
+		switch wire {
+		case wireVarint:
+			v.varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errMalformed
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errMalformed
+			}
+			v.varint, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errMalformed
+			}
+			v.varint, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errMalformed
+			}
+			v.bytes, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return errMalformed
+		}
+		if err := fn(field, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
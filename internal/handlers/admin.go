@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/formatter"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/scheduler"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/workerpool"
+)
+
+// adminAlerts is how many recent alerts the dashboard lists.
+const adminAlerts = 25
+
+// AdminHandler serves the operator dashboard: registered counties and how
+// their last fetches went, sources that failed to parse, snapshots held in
+// quarantine, recent alerts and the refresh queue, with a button to refresh
+// a county on demand.
+type AdminHandler struct {
+	store     store.Store
+	scheduler *scheduler.Scheduler
+	pool      *workerpool.Pool
+}
+
+// NewAdminHandler returns a dashboard over st that queues manual refreshes
+// with sched and reports the utilization of pool.
+func NewAdminHandler(st store.Store, sched *scheduler.Scheduler, pool *workerpool.Pool) *AdminHandler {
+	return &AdminHandler{store: st, scheduler: sched, pool: pool}
+}
+
+type adminData struct {
+	Counties    []models.CountySource
+	ParseErrors []models.ParseError
+	Quarantined []models.QuarantineRecord
+	Alerts      []models.Alert
+	Workers     workerpool.Stats
+	Notice      string
+	Error       string
+	GeneratedAt time.Time
+}
+
+// authorized reports whether the caller may use the dashboard: it needs an
+// API key with full visibility and at least the role least. Anonymous
+// callers are challenged for Basic credentials so a browser prompts for the
+// key.
+func (h *AdminHandler) authorized(w http.ResponseWriter, r *http.Request, least string) bool {
+	p, ok := auth.FromContext(r.Context())
+	switch {
+	case !ok || p.Anonymous:
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+auth.Realm+`"`)
+		http.Error(w, "Sign in with an API key as the password.", http.StatusUnauthorized)
+		return false
+	case !p.Policy.Full():
+		http.Error(w, "The admin dashboard needs an API key with full visibility.", http.StatusForbidden)
+		return false
+	case !models.RoleAllows(p.Role, least):
+		http.Error(w, "This needs an API key with the "+least+" role.", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Dashboard serves GET /admin.
+func (h *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(w, r, models.RoleAdmin) {
+		return
+	}
+	var notice string
+	if county := r.URL.Query().Get("queued"); county != "" {
+		notice = "Refresh of " + county + " queued."
+	}
+	h.render(w, http.StatusOK, notice, "")
+}
+
+// Refresh serves POST /admin/counties/{county}/refresh?election=, queueing
+// a refresh of the county's registration and sending the browser back to
+// the dashboard.
+func (h *AdminHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(w, r, models.RoleIngester) {
+		return
+	}
+	// Browsers resend Basic credentials on their own, so a form on another
+	// site could otherwise trigger refreshes.
+	if !sameOrigin(r) {
+		http.Error(w, "Cross-origin requests are not allowed.", http.StatusForbidden)
+		return
+	}
+
+	county := r.PathValue("county")
+	switch err := h.scheduler.RefreshNow(r.URL.Query().Get("election"), county); {
+	case errors.Is(err, store.ErrNotFound):
+		h.render(w, http.StatusNotFound, "", county+" is not registered.")
+	case errors.Is(err, scheduler.ErrNoFileLink):
+		h.render(w, http.StatusConflict, "", county+" emails its results and has no fileLink to refresh.")
+	case errors.Is(err, scheduler.ErrInFlight):
+		h.render(w, http.StatusConflict, "", "A refresh of "+county+" is already queued or running.")
+	case errors.Is(err, workerpool.ErrQueueFull):
+		h.render(w, http.StatusServiceUnavailable, "", "The refresh queue is full; try again shortly.")
+	case err != nil:
+		h.render(w, http.StatusInternalServerError, "", "Failed to queue refresh: "+err.Error())
+	default:
+		http.Redirect(w, r, "/admin?queued="+url.QueryEscape(county), http.StatusSeeOther)
+	}
+}
+
+func (h *AdminHandler) render(w http.ResponseWriter, status int, notice, errMsg string) {
+	data := adminData{
+		Counties:    h.store.CountySources(),
+		Workers:     h.pool.Stats(),
+		Notice:      notice,
+		Error:       errMsg,
+		GeneratedAt: time.Now().UTC(),
+	}
+	for i, c := range data.Counties {
+		if c.Status.LastError == "" {
+			continue
+		}
+		if contact, err := h.store.Contact(c.Name); err == nil {
+			data.Counties[i].Contact = &contact
+		}
+	}
+	for _, rec := range h.store.ParseErrors() {
+		if rec.Status == models.ParseErrorOpen {
+			data.ParseErrors = append(data.ParseErrors, rec)
+		}
+	}
+	for _, rec := range h.store.Quarantines() {
+		if rec.Status == models.QuarantineHeld {
+			data.Quarantined = append(data.Quarantined, rec)
+		}
+	}
+	data.Alerts = h.store.Alerts("")
+	if len(data.Alerts) > adminAlerts {
+		data.Alerts = data.Alerts[:adminAlerts]
+	}
+
+	var buf bytes.Buffer
+	if err := adminTemplate.Execute(&buf, data); err != nil {
+		http.Error(w, "Failed to render dashboard.", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// sameOrigin reports whether a state-changing request came from this
+// server's own pages. Browsers send Sec-Fetch-Site, or at least Origin, on
+// form posts; requests with neither aren't from a browser and carry no
+// ambient credentials to abuse.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+var adminTemplate = template.Must(template.New("admin").
+	Funcs(formatter.Funcs()).
+	Funcs(template.FuncMap{"contact": contactSummary}).
+	Parse(adminPage))
+
+// contactSummary lists who to call about a failing county.
+func contactSummary(c *models.CountyContact) string {
+	var parts []string
+	for _, p := range [][]string{
+		{"Office", c.OfficeName, c.OfficePhone, c.OfficeEmail},
+		{"IT", c.ITName, c.ITPhone, c.ITEmail},
+	} {
+		var fields []string
+		for _, f := range p[1:] {
+			if f != "" {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) > 0 {
+			parts = append(parts, p[0]+": "+strings.Join(fields, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+const adminPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>era admin</title>
+<style>
+	body { font-family: Arial, sans-serif; margin: 0; padding: 20px; }
+	.container { max-width: 1200px; margin: 0 auto; }
+	table { border-collapse: collapse; width: 100%; margin: 10px 0 20px; }
+	th, td { border: 1px solid #ddd; padding: 6px 8px; text-align: left; vertical-align: top; }
+	th { background: #f5f5f5; }
+	.failing { background: #fff1f0; }
+	.notice { background: #f0f7ff; border: 1px solid #9cc3f0; padding: 8px 12px; }
+	.error { background: #fff1f0; border: 1px solid #f0a09c; padding: 8px 12px; }
+	.muted { color: #777; }
+	.stats span { margin-right: 20px; }
+</style>
+</head>
+<body>
+<div class="container">
+	<h1>era admin</h1>
+	{{- with .Notice}}
+	<p class="notice">{{.}}</p>
+	{{- end}}
+	{{- with .Error}}
+	<p class="error">{{.}}</p>
+	{{- end}}
+
+	<h2>Refresh queue</h2>
+	<p class="stats"><span>Workers: {{.Workers.Workers}}</span><span>Running: {{.Workers.Active}}</span><span>Queued: {{.Workers.Queued}}</span></p>
+
+	<h2>Counties</h2>
+	<table>
+		<thead><tr><th>County</th><th>Election</th><th>Source</th><th>Last run</th><th>Last success</th><th>Last error</th><th></th></tr></thead>
+		<tbody>
+		{{- range .Counties}}
+			<tr{{if .Status.LastError}} class="failing"{{end}}>
+				<td>{{.Name}}</td>
+				<td>{{.Election}}</td>
+				<td><a href="{{.FileLink}}" rel="noreferrer">{{.FileLink}}</a></td>
+				<td>{{with .Status.LastRunAt}}{{.Format "2006-01-02 15:04:05 MST"}}{{else}}<span class="muted">never</span>{{end}}</td>
+				<td>{{with .Status.LastSuccessAt}}{{.Format "2006-01-02 15:04:05 MST"}}{{else}}<span class="muted">never</span>{{end}}</td>
+				<td>{{.Status.LastError}}
+				{{- with .Contact}}
+					<br><span class="muted">{{contact .}}</span>
+				{{- end}}
+				</td>
+				<td><form method="post" action="/admin/counties/{{.Name}}/refresh?election={{.Election}}"><button type="submit">Refresh now</button></form></td>
+			</tr>
+		{{- else}}
+			<tr><td colspan="7" class="muted">No counties registered.</td></tr>
+		{{- end}}
+		</tbody>
+	</table>
+
+	<h2>Parse errors</h2>
+	<table>
+		<thead><tr><th>County</th><th>Last seen</th><th>Seen</th><th>Error</th><th>File</th></tr></thead>
+		<tbody>
+		{{- range .ParseErrors}}
+			<tr class="failing"><td>{{.County}}</td><td>{{.LastSeenAt.Format "2006-01-02 15:04:05 MST"}}</td><td>{{.Occurrences}}</td><td>{{.Error}}</td><td><a href="/api/v1/errors/{{.ID}}/source">Download</a> ({{.Size}} bytes)</td></tr>
+		{{- else}}
+			<tr><td colspan="5" class="muted">No open parse errors.</td></tr>
+		{{- end}}
+		</tbody>
+	</table>
+
+	<h2>Quarantined snapshots</h2>
+	<table>
+		<thead><tr><th>County</th><th>Held since</th><th>Reasons</th><th>ID</th></tr></thead>
+		<tbody>
+		{{- range .Quarantined}}
+			<tr><td>{{.County}}</td><td>{{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}</td><td>{{join .Reasons "; "}}</td><td>{{.ID}}</td></tr>
+		{{- else}}
+			<tr><td colspan="4" class="muted">Nothing held.</td></tr>
+		{{- end}}
+		</tbody>
+	</table>
+
+	<h2>Recent alerts</h2>
+	<table>
+		<thead><tr><th>Raised</th><th>Type</th><th>County</th><th>Summary</th></tr></thead>
+		<tbody>
+		{{- range .Alerts}}
+			<tr{{if eq .Severity "error"}} class="failing"{{end}}><td>{{.RaisedAt.Format "2006-01-02 15:04:05 MST"}}</td><td>{{.Type}}{{if .Suppressed}} <span class="muted">(suppressed)</span>{{end}}</td><td>{{.County}}</td><td>{{.Summary}}{{with .Details.error}}<br><span class="muted">{{.}}</span>{{end}}</td></tr>
+		{{- else}}
+			<tr><td colspan="4" class="muted">No alerts.</td></tr>
+		{{- end}}
+		</tbody>
+	</table>
+
+	<p class="muted">Generated {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+</div>
+</body>
+</html>
+`
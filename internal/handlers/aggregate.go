@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/aggregate"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// AggregateHandler serves GET /api/v1/aggregate.
+type AggregateHandler struct {
+	store store.Store
+}
+
+// NewAggregateHandler returns a handler aggregating results from st.
+func NewAggregateHandler(st store.Store) *AggregateHandler {
+	return &AggregateHandler{store: st}
+}
+
+func (h *AggregateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	contest := r.URL.Query().Get("contest")
+	if contest == "" {
+		writeError(w, http.StatusBadRequest, "contest query parameter is required")
+		return
+	}
+
+	agg, err := aggregate.Contest(h.store, models.Slug(contest))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no county reports this contest")
+		return
+	}
+	setSnapshotVersion(w, agg.Version)
+	writeJSON(w, r, http.StatusOK, agg)
+}
+
+// GeoJSON serves GET /api/v1/results/{contest}/geojson, the contest as a
+// GeoJSON feature collection of the counties reporting it, for choropleth
+// maps.
+func (h *AggregateHandler) GeoJSON(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	fc, err := aggregate.GeoJSON(h.store, models.Slug(r.PathValue("contest")))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no county reports this contest")
+		return
+	}
+	setSnapshotVersion(w, fc.Version)
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(shape(r, fc))
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// AlertsHandler serves raised alerts and manages the runbooks attached to
+// them.
+type AlertsHandler struct {
+	store store.Store
+}
+
+// NewAlertsHandler returns a handler over the alerts and runbooks in st.
+func NewAlertsHandler(st store.Store) *AlertsHandler {
+	return &AlertsHandler{store: st}
+}
+
+// List serves GET /api/v1/alerts, newest first, optionally only those about
+// ?county=.
+func (h *AlertsHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.store.Alerts(r.URL.Query().Get("county")))
+}
+
+// Runbooks serves GET /api/v1/runbooks.
+func (h *AlertsHandler) Runbooks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.store.Runbooks())
+}
+
+// PutRunbook serves PUT /api/v1/runbooks/{type}, the runbook for every
+// source, and PUT /api/v1/runbooks/{type}/{county}, one county's own, which
+// is sent ahead of the general one.
+func (h *AlertsHandler) PutRunbook(w http.ResponseWriter, r *http.Request) {
+	var rb models.Runbook
+	if !decodeBody(w, r, &rb) {
+		return
+	}
+	rb.AlertType = r.PathValue("type")
+	rb.County = r.PathValue("county")
+	if err := validate.Runbook(rb); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if reg, err := h.store.County(rb.County); err == nil {
+		rb.County = reg.Name
+	}
+	rb.UpdatedAt = time.Now().UTC()
+	h.store.SaveRunbook(rb)
+	writeJSON(w, r, http.StatusOK, rb)
+}
+
+// DeleteRunbook serves DELETE /api/v1/runbooks/{type} and
+// DELETE /api/v1/runbooks/{type}/{county}.
+func (h *AlertsHandler) DeleteRunbook(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteRunbook(r.PathValue("type"), r.PathValue("county")); errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "runbook not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
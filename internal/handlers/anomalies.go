@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// AnomaliesHandler serves the integrity checks published snapshots failed.
+type AnomaliesHandler struct {
+	store store.Store
+}
+
+// NewAnomaliesHandler returns a handler over the anomaly history in st.
+func NewAnomaliesHandler(st store.Store) *AnomaliesHandler {
+	return &AnomaliesHandler{store: st}
+}
+
+// List serves GET /api/v1/anomalies, newest first, optionally only one
+// county's (?county=) or one kind (?kind=). Like snapshots' anomalies
+// field, it is hidden from partner and public keys.
+func (h *AnomaliesHandler) List(w http.ResponseWriter, r *http.Request) {
+	if p, ok := auth.FromContext(r.Context()); ok && p.Policy.Hides("anomalies") {
+		writeError(w, http.StatusForbidden, "anomalies are not visible to this API key")
+		return
+	}
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && !slices.Contains(models.AnomalyKinds, kind) {
+		writeError(w, http.StatusBadRequest, "unknown anomaly kind")
+		return
+	}
+	out := []models.Anomaly{}
+	for _, a := range h.store.Anomalies(r.URL.Query().Get("county")) {
+		if kind == "" || a.Kind == kind {
+			out = append(out, a)
+		}
+	}
+	writeJSON(w, r, http.StatusOK, out)
+}
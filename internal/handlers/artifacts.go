@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/archive"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/parser"
+)
+
+// ArtifactsHandler serves the archived source files snapshots were parsed
+// from, so anyone auditing a published result can check it against exactly
+// what the county posted.
+type ArtifactsHandler struct {
+	archive *archive.Archive
+	logger  *slog.Logger
+}
+
+// NewArtifactsHandler returns a handler serving sources from a.
+func NewArtifactsHandler(a *archive.Archive, logger *slog.Logger) *ArtifactsHandler {
+	return &ArtifactsHandler{archive: a, logger: logger}
+}
+
+// List serves GET /api/v1/results/{county}/artifacts, newest first.
+func (h *ArtifactsHandler) List(w http.ResponseWriter, r *http.Request) {
+	artifacts, err := h.archive.List(r.Context(), r.PathValue("county"))
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to list archived sources", "county", r.PathValue("county"), "error", err)
+		writeError(w, http.StatusBadGateway, "failed to read the source archive")
+		return
+	}
+	if artifacts == nil {
+		artifacts = []models.Artifact{}
+	}
+	writeJSON(w, r, http.StatusOK, artifacts)
+}
+
+// Get serves GET /api/v1/results/{county}/artifacts/{snapshot}, the source
+// of the snapshot whose hash is or starts with {snapshot}, or of the newest
+// archived one for "latest", as an attachment. The Repr-Digest header
+// carries the file's SHA-256 so downloads can be verified. A source holds
+// every contest as of its snapshot, so delayed access tiers are refused.
+func (h *ArtifactsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	county, snapshot := r.PathValue("county"), r.PathValue("snapshot")
+	art, err := h.archive.Find(r.Context(), county, snapshot)
+	if errors.Is(err, archive.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no archived source for snapshot")
+		return
+	}
+	if errors.Is(err, archive.ErrAmbiguous) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var data []byte
+	if err == nil {
+		data, err = h.archive.Read(r.Context(), art)
+	}
+	if errors.Is(err, archive.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no archived source for snapshot")
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to read archived source", "county", county, "snapshot", snapshot, "error", err)
+		writeError(w, http.StatusBadGateway, "failed to read the source archive")
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	name := art.County + "-" + strings.TrimPrefix(art.SnapshotHash, "sha256:") + parser.Extension(art.ParseMethod)
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+	w.Header().Set("X-Snapshot-Hash", art.SnapshotHash)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(data)
+}
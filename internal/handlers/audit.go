@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/audit"
+)
+
+// Page sizes of GET /api/v1/audit.
+const (
+	defaultAuditPage = 100
+	maxAuditPage     = 1000
+)
+
+// AuditHandler serves the audit log of mutating requests.
+type AuditHandler struct {
+	log *audit.Log
+}
+
+// NewAuditHandler returns a handler over log.
+func NewAuditHandler(log *audit.Log) *AuditHandler {
+	return &AuditHandler{log: log}
+}
+
+// List serves GET /api/v1/audit?after=&since=&actor=&action=&limit=,
+// entries oldest first. Passing the seq of the last entry received as
+// ?after= pages through the log. Only callers that see every field may
+// read it.
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	if _, ok := operator(w, r, "audit log queries"); !ok {
+		return
+	}
+	query := r.URL.Query()
+	q := audit.Query{Actor: query.Get("actor"), Action: query.Get("action"), Limit: defaultAuditPage}
+	if v := query.Get("after"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "after must be a non-negative integer")
+			return
+		}
+		q.After = n
+	}
+	if v := query.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+			return
+		}
+		q.Since = t
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditPage {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxAuditPage))
+			return
+		}
+		q.Limit = n
+	}
+	writeJSON(w, r, http.StatusOK, h.log.Entries(q))
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/scheduler"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+	"github.com/many221/era_api_v1/internal/workerpool"
+)
+
+const (
+	// maxBatchItems bounds the requests of one batch, a state's counties
+	// with room to spare.
+	maxBatchItems = 500
+	// batchTimeout bounds a batch, which unlike other requests isn't held
+	// to the server's write timeout.
+	batchTimeout = 10 * time.Minute
+)
+
+// BatchHandler serves POST /api/v1/process/batch.
+type BatchHandler struct {
+	processor *processor.Processor
+	store     store.Store
+	pool      *workerpool.Pool
+	logger    *slog.Logger
+}
+
+// NewBatchHandler returns a handler running batches with p on pool, the
+// workers scheduled refreshes share, so a batch is held to the same limit
+// on fetches from each county site.
+func NewBatchHandler(p *processor.Processor, st store.Store, pool *workerpool.Pool, logger *slog.Logger) *BatchHandler {
+	return &BatchHandler{processor: p, store: st, pool: pool, logger: logger}
+}
+
+// ServeHTTP serves POST /api/v1/process/batch?election=&dryRun=, a JSON
+// array of process requests run at once, answered when all of them have
+// finished with how each went. A request naming only its county runs as the
+// county's registration would be refreshed. One failing doesn't stop the
+// rest; the batch is only refused as a whole if it is malformed.
+func (h *BatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var reqs []models.ProcessRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		writeProblem(w, r, models.Problem{
+			Type:   models.ProblemMalformedBody,
+			Title:  "Malformed request body",
+			Status: http.StatusBadRequest,
+			Detail: "request body must be a JSON array of process requests",
+		})
+		return
+	}
+	if len(reqs) == 0 || len(reqs) > maxBatchItems {
+		writeProblem(w, r, models.Problem{
+			Type:   models.ProblemInvalidRequest,
+			Title:  "Invalid request",
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("a batch has 1 to %d requests", maxBatchItems),
+		})
+		return
+	}
+	election := r.URL.Query().Get("election")
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), batchTimeout)
+	defer cancel()
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(batchTimeout + 10*time.Second))
+
+	resp := models.BatchProcessResponse{Items: make([]models.BatchItem, len(reqs))}
+	// Runs report on a channel with room for them all, so those finishing
+	// after the batch has timed out don't block.
+	done := make(chan models.BatchItem, len(reqs))
+	pending := 0
+	for i, req := range reqs {
+		if election != "" {
+			req.Election = election
+		}
+		req = h.resolve(req)
+		req.Async, req.Debug = false, false
+		req.DryRun = req.DryRun || dryRun
+
+		item := models.BatchItem{Index: i, CountyName: req.CountyName, Election: req.Election, DryRun: req.DryRun}
+		resp.Items[i] = item
+		if err := validate.ProcessRequest(req); err != nil {
+			resp.Items[i].Status, resp.Items[i].Error = http.StatusBadRequest, err.Error()
+			continue
+		}
+		err := h.pool.Submit(workerpool.Task{
+			Source: scheduler.SourceKey(req.FileLink),
+			Run: func(poolCtx context.Context) {
+				// The batch's context carries its request ID into the run;
+				// the pool's stops it on shutdown.
+				ctx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				defer context.AfterFunc(poolCtx, cancel)()
+				done <- h.run(ctx, req, item)
+			},
+		})
+		if err != nil {
+			resp.Items[i].Status, resp.Items[i].Error = http.StatusServiceUnavailable, err.Error()
+			continue
+		}
+		pending++
+	}
+
+wait:
+	for ; pending > 0; pending-- {
+		select {
+		case item := <-done:
+			resp.Items[item.Index] = item
+		case <-ctx.Done():
+			// Runs still queued or going see the cancelled context and end
+			// on their own.
+			break wait
+		}
+	}
+	for i := range resp.Items {
+		item := &resp.Items[i]
+		if item.Status == 0 {
+			item.Status, item.Error = http.StatusGatewayTimeout, "the batch timed out before this request finished"
+		}
+		if item.Status == http.StatusOK {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+	h.logger.InfoContext(r.Context(), "batch processed", "requests", len(reqs), "succeeded", resp.Succeeded, "failed", resp.Failed, "dry_run", dryRun)
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// resolve fills in a request naming only its county, with no fileLink,
+// from the county's registration.
+func (h *BatchHandler) resolve(req models.ProcessRequest) models.ProcessRequest {
+	if req.FileLink != "" || req.Jurisdiction != "" {
+		return req
+	}
+	c, err := h.store.ElectionCounty(req.Election, req.CountyName)
+	if err != nil {
+		return req
+	}
+	full := c.ProcessRequest()
+	full.Election = c.Election
+	full.Dataset, full.DryRun = req.Dataset, req.DryRun
+	return full
+}
+
+// run processes req, returning item with how it went.
+func (h *BatchHandler) run(ctx context.Context, req models.ProcessRequest, item models.BatchItem) models.BatchItem {
+	start := time.Now()
+	resp, err := h.processor.Process(ctx, req, nil)
+	item.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		h.logger.ErrorContext(ctx, "batch item failed", "county", req.CountyName, "error", err)
+		item.Status, item.Error = processErrorStatus(err), err.Error()
+		if errors.Is(err, context.Canceled) {
+			item.Status = http.StatusServiceUnavailable
+		}
+		return item
+	}
+	item.Status = http.StatusOK
+	item.Warnings, item.Unchanged = resp.Warnings, resp.Unchanged
+	if resp.Results != nil {
+		item.Election = resp.Results.Election
+		item.SnapshotHash = resp.Results.Hash
+		item.Contests = len(resp.Results.Contests)
+	}
+	return item
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/broadcast"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// BroadcastHandler serves GET /api/v1/broadcast/{county}, the stable polling
+// endpoint for broadcast graphics systems.
+type BroadcastHandler struct {
+	feeds *broadcast.Service
+}
+
+// NewBroadcastHandler returns a handler serving feeds from svc.
+func NewBroadcastHandler(svc *broadcast.Service) *BroadcastHandler {
+	return &BroadcastHandler{feeds: svc}
+}
+
+func (h *BroadcastHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	feed, err := h.feeds.Feed(r.PathValue("county"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no results for county")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to render feed")
+		return
+	}
+
+	w.Header().Set("Last-Modified", feed.UpdatedAt.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+	switch r.URL.Query().Get("format") {
+	case "", "kv", "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(feed.KeyValue)
+	case "xml":
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Write(feed.XML)
+	default:
+		writeError(w, http.StatusBadRequest, "format must be kv or xml")
+	}
+}
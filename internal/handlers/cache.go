@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Cache makes read endpoints cacheable. Their bodies depend only on
+// published data and on who is asking, so the ETag is the store's version
+// plus the caller's key: revalidating is answered with 304 Not Modified
+// without rebuilding the body, and anonymous responses may be kept by CDNs
+// for sharedMaxAge to absorb election-night traffic.
+type Cache struct {
+	store        store.Store
+	maxAge       time.Duration
+	sharedMaxAge time.Duration
+	shared       SharedCache
+}
+
+// SharedCache holds response bodies across server instances. Get and Set
+// must treat the backing store being unavailable as a miss.
+type SharedCache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, data []byte, ttl time.Duration)
+}
+
+// maxSharedBytes bounds the responses kept in a shared cache; larger ones,
+// such as big exports, are rebuilt each time.
+const maxSharedBytes = 1 << 20
+
+// cachedResponse is a response as kept in a shared cache.
+type cachedResponse struct {
+	Header map[string]string `json:"header"`
+	Body   []byte            `json:"body"`
+}
+
+// cachedHeaders are the handler-set headers kept with a shared response.
+var cachedHeaders = []string{"Content-Type", "Content-Disposition", "X-Snapshot-Hash"}
+
+// NewCache returns a Cache over st. Browsers may reuse a response for
+// maxAge and shared caches for sharedMaxAge before revalidating.
+func NewCache(st store.Store, maxAge, sharedMaxAge time.Duration) *Cache {
+	return &Cache{store: st, maxAge: maxAge, sharedMaxAge: sharedMaxAge}
+}
+
+// SetShared makes c keep successful responses in shared for sharedMaxAge,
+// so instances behind one load balancer build each response once between
+// publishes. The shared cache must retire its entries whenever results are
+// published anywhere; other changes to the store are kept apart by its
+// version.
+func (c *Cache) SetShared(shared SharedCache) {
+	c.shared = shared
+}
+
+// Wrap adds caching headers to next and answers matching If-None-Match
+// requests for it. The version is read before next runs, so a body can
+// only be newer than its ETag, never older; a client revalidating with it
+// gets the newer body.
+func (c *Cache) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+		p, ok := auth.FromContext(r.Context())
+		if ok && p.Tier.Delayed() {
+			// An embargoed view moves on with the clock, not the version.
+			w.Header().Set("Cache-Control", "private, no-cache")
+			next(w, r)
+			return
+		}
+		anonymous := !ok || p.Anonymous
+		caller := callerTag(p.Key.ID, anonymous)
+		version := strconv.FormatUint(c.store.Version(), 36)
+		etag := `"` + version + "-" + caller + `"`
+
+		h := w.Header()
+		h.Set("ETag", etag)
+		h.Add("Vary", "Authorization, X-API-Key")
+		if anonymous {
+			h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d, s-maxage=%d", seconds(c.maxAge), seconds(c.sharedMaxAge)))
+		} else {
+			// Keyed responses are shaped for one consumer, so they stay
+			// out of shared caches.
+			h.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", seconds(c.maxAge)))
+		}
+
+		if etagMatch(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if c.shared == nil {
+			next(w, r)
+			return
+		}
+
+		// The version is part of the key as well as the ETag: admin edits,
+		// unlike publishes, don't retire the shared cache's entries, and a
+		// body cached before one mustn't be sent under the ETag after it.
+		key := version + " " + caller + " " + r.URL.RequestURI()
+		if data, ok := c.shared.Get(r.Context(), key); ok {
+			var cached cachedResponse
+			if json.Unmarshal(data, &cached) == nil {
+				for name, v := range cached.Header {
+					h.Set(name, v)
+				}
+				h.Set("X-Cache", "hit")
+				w.Write(cached.Body)
+				return
+			}
+		}
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		if rec.status != http.StatusOK || rec.body.Len() > maxSharedBytes {
+			return
+		}
+		cached := cachedResponse{Header: make(map[string]string), Body: rec.body.Bytes()}
+		for _, name := range cachedHeaders {
+			if v := h.Get(name); v != "" {
+				cached.Header[name] = v
+			}
+		}
+		if data, err := json.Marshal(cached); err == nil {
+			c.shared.Set(r.Context(), key, data, c.sharedMaxAge)
+		}
+	}
+}
+
+// recorder passes a response through while keeping a copy of its body.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if r.body.Len() <= maxSharedBytes {
+		r.body.Write(p)
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// callerTag tells apart callers whose responses may differ, for ETags and
+// shared cache keys. The key is hashed so its ID doesn't appear in
+// responses.
+func callerTag(keyID string, anonymous bool) string {
+	if anonymous {
+		return "anon"
+	}
+	sum := sha256.Sum256([]byte(keyID))
+	return hex.EncodeToString(sum[:6])
+}
+
+// etagMatch reports whether an If-None-Match header matches etag, using the
+// weak comparison RFC 9110 specifies for it.
+func etagMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func seconds(d time.Duration) int {
+	return int(d / time.Second)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// CandidatesHandler manages the canonical candidate registry.
+type CandidatesHandler struct {
+	store   store.Store
+	matcher *normalize.Matcher
+}
+
+// NewCandidatesHandler returns a handler for the registry in st.
+func NewCandidatesHandler(st store.Store, m *normalize.Matcher) *CandidatesHandler {
+	return &CandidatesHandler{store: st, matcher: m}
+}
+
+// List serves GET /api/v1/candidates.
+func (h *CandidatesHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.store.Candidates())
+}
+
+// Create serves POST /api/v1/candidates.
+func (h *CandidatesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var c models.CanonicalCandidate
+	if !decodeBody(w, r, &c) {
+		return
+	}
+	if err := validate.Candidate(c); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	c.Name = strings.TrimSpace(c.Name)
+	if c.ID == "" {
+		c.ID = models.Slug(c.Name)
+	}
+
+	h.store.SaveCandidate(c)
+	writeJSON(w, r, http.StatusCreated, c)
+}
+
+// AddAliases serves POST /api/v1/candidates/{id}/aliases.
+func (h *CandidatesHandler) AddAliases(w http.ResponseWriter, r *http.Request) {
+	c, err := h.store.Candidate(r.PathValue("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "candidate not found")
+		return
+	}
+
+	var body struct {
+		Aliases []string `json:"aliases"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if err := validate.Aliases(body.Aliases); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	normalize.AddAliases(&c, body.Aliases...)
+	h.store.SaveCandidate(c)
+	writeJSON(w, r, http.StatusOK, c)
+}
+
+// Delete serves DELETE /api/v1/candidates/{id}.
+func (h *CandidatesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteCandidate(r.PathValue("id")); errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "candidate not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Match serves GET /api/v1/candidates/match?name=&contest=, reporting how a
+// county spelling would be resolved.
+func (h *CandidatesHandler) Match(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name query parameter is required")
+		return
+	}
+
+	match, ok := h.matcher.Match(r.URL.Query().Get("contest"), name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no confident match")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, match)
+}
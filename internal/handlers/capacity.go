@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/many221/era_api_v1/internal/jobs"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/workerpool"
+)
+
+// maxRetryAfter caps the Retry-After estimate, in seconds; past it the
+// estimate is too uncertain to be worth more.
+const maxRetryAfter = 300
+
+// CapacityHandler reports how loaded processing and refreshes are, and puts
+// the same figures in headers on the routes it tracks.
+type CapacityHandler struct {
+	jobs     *jobs.Manager
+	pool     *workerpool.Pool
+	inFlight atomic.Int64
+}
+
+// NewCapacityHandler returns a handler reporting on the jobs m runs and
+// the refreshes pool does.
+func NewCapacityHandler(m *jobs.Manager, pool *workerpool.Pool) *CapacityHandler {
+	return &CapacityHandler{jobs: m, pool: pool}
+}
+
+// ServeHTTP serves GET /api/v1/capacity. It answers 503 while saturated,
+// so a load balancer checking it sends work to other instances.
+func (h *CapacityHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c := h.capacity()
+	h.setHeaders(w, c)
+	w.Header().Set("Cache-Control", "no-store")
+	status := http.StatusOK
+	if c.Status == models.CapacitySaturated {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, r, status, c)
+}
+
+// Track counts requests to next as processing in flight and sets
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset on their
+// responses, with Retry-After once every slot is taken. Requests are
+// never refused for load; the headers let clients slow down themselves.
+func (h *CapacityHandler) Track(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.setHeaders(w, h.capacity())
+		h.inFlight.Add(1)
+		defer h.inFlight.Add(-1)
+		next(w, r)
+	}
+}
+
+func (h *CapacityHandler) setHeaders(w http.ResponseWriter, c models.Capacity) {
+	p := c.Processing
+	remaining := max(0, p.Slots-p.Running-p.Queued-p.InFlight)
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(p.Slots))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(c.RetryAfterSeconds))
+	if c.Status != models.CapacityOK {
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, c.RetryAfterSeconds)))
+	}
+}
+
+func (h *CapacityHandler) capacity() models.Capacity {
+	js, ps := h.jobs.Stats(), h.pool.Stats()
+	c := models.Capacity{
+		Processing: models.ProcessingCapacity{
+			Slots:             js.MaxConcurrent,
+			Running:           js.Running,
+			Queued:            js.Queued,
+			InFlight:          int(h.inFlight.Load()),
+			AverageRunSeconds: math.Round(js.AverageRunSeconds*1000) / 1000,
+		},
+		Refresh: models.RefreshCapacity{
+			Workers:           ps.Workers,
+			Active:            ps.Active,
+			Queued:            ps.Queued,
+			QueueSize:         ps.QueueSize,
+			AverageRunSeconds: math.Round(ps.AverageRunSeconds*1000) / 1000,
+		},
+	}
+	processing := c.Processing.Running + c.Processing.Queued + c.Processing.InFlight
+	refreshing := ps.Active + ps.Queued
+	c.Load = math.Round(max(load(processing, js.MaxConcurrent), load(refreshing, ps.Workers))*100) / 100
+	c.RetryAfterSeconds = min(maxRetryAfter, max(
+		drain(processing, js.MaxConcurrent, js.AverageRunSeconds),
+		drain(refreshing, ps.Workers, ps.AverageRunSeconds),
+	))
+
+	switch {
+	case c.Load >= 2 || (ps.QueueSize > 0 && ps.Queued >= ps.QueueSize):
+		c.Status = models.CapacitySaturated
+	case c.Load >= 1:
+		c.Status = models.CapacityBusy
+	default:
+		c.Status = models.CapacityOK
+	}
+	return c
+}
+
+func load(work, slots int) float64 {
+	return float64(work) / float64(max(1, slots))
+}
+
+// drain estimates the seconds until one more task would start with work
+// tasks taking slots, each round of them taking avg seconds.
+func drain(work, slots int, avg float64) int {
+	slots = max(1, slots)
+	if work < slots {
+		return 0
+	}
+	if avg <= 0 {
+		avg = 1 // nothing has finished yet to go by
+	}
+	// The next task starts as the work/slots-th round ahead of it ends.
+	return int(math.Ceil(float64(work/slots) * avg))
+}
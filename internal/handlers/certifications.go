@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/many221/era_api_v1/internal/archive"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// CertificationsHandler certifies counties' results and serves the
+// bundles archived when they were, the permanent public record of them.
+type CertificationsHandler struct {
+	processor *processor.Processor
+	store     store.Store
+	archive   *archive.Archive
+	logger    *slog.Logger
+}
+
+// NewCertificationsHandler returns a handler certifying through p and
+// serving bundles from a.
+func NewCertificationsHandler(p *processor.Processor, st store.Store, a *archive.Archive, logger *slog.Logger) *CertificationsHandler {
+	return &CertificationsHandler{processor: p, store: st, archive: a, logger: logger}
+}
+
+// Certify serves POST /api/v1/elections/{election}/results/{county}/certify,
+// certifying the county's live results: their bundle is archived and they
+// are frozen, nothing more being published for the county.
+func (h *CertificationsHandler) Certify(w http.ResponseWriter, r *http.Request) {
+	by, ok := operator(w, r, "certifications")
+	if !ok {
+		return
+	}
+	c, err := h.processor.Certify(r.Context(), r.PathValue("election"), r.PathValue("county"), by)
+	switch {
+	case errors.Is(err, processor.ErrUnknownElection):
+		writeError(w, http.StatusNotFound, "unknown election")
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "no results for county")
+	case errors.Is(err, store.ErrCertified), errors.Is(err, archive.ErrExists):
+		writeError(w, http.StatusConflict, "county results are already certified")
+	case errors.Is(err, store.ErrElectionArchived):
+		writeError(w, http.StatusConflict, "election is archived")
+	case errors.Is(err, store.ErrSnapshotChanged):
+		writeError(w, http.StatusConflict, "county results changed while certifying; try again")
+	case err != nil:
+		h.logger.ErrorContext(r.Context(), "failed to certify county", "county", r.PathValue("county"), "election", r.PathValue("election"), "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to certify county results")
+	default:
+		writeJSON(w, r, http.StatusCreated, c)
+	}
+}
+
+// List serves GET /api/v1/elections/{election}/certifications, the
+// election's certified counties.
+func (h *CertificationsHandler) List(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, r, http.StatusOK, h.store.Certifications(election))
+}
+
+// Get serves GET /api/v1/elections/{election}/results/{county}/certification,
+// the record of the county's certification.
+func (h *CertificationsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	c, err := h.store.Certification(election, r.PathValue("county"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "county results aren't certified")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, c)
+}
+
+// Bundle serves GET
+// /api/v1/elections/{election}/results/{county}/certification/bundle.zip,
+// the county's certification bundle. It never changes, so it may be cached
+// for good; the Repr-Digest header carries its SHA-256.
+func (h *CertificationsHandler) Bundle(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	c, err := h.store.Certification(election, r.PathValue("county"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "county results aren't certified")
+		return
+	}
+	data, err := h.archive.ReadBundle(r.Context(), c.Election, c.County)
+	if errors.Is(err, archive.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "certification bundle not found in the archive")
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to read certification bundle", "county", c.County, "election", c.Election, "error", err)
+		writeError(w, http.StatusBadGateway, "failed to read the source archive")
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	name := c.Election + "-" + store.CountyKey(c.County) + "-certified.zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", `"`+c.Bundle.SHA256+`"`)
+	w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+	w.Header().Set("X-Snapshot-Hash", c.SnapshotHash)
+	w.Write(data)
+}
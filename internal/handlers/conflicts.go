@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// ConflictsHandler compares the sources that report the same counties.
+type ConflictsHandler struct {
+	store     store.Store
+	processor *processor.Processor
+}
+
+// NewConflictsHandler returns a handler comparing the source snapshots in
+// st, ranked by p's precedence.
+func NewConflictsHandler(st store.Store, p *processor.Processor) *ConflictsHandler {
+	return &ConflictsHandler{store: st, processor: p}
+}
+
+// List serves GET /api/v1/conflicts?election=&county=&tolerance=, the
+// contests that sources of the same county report differently by more
+// than tolerance, in votes or as a percentage, and which source's numbers
+// are published. Sources that weren't published are compared too, so only
+// callers that see every field, in real time, may read it.
+func (h *ConflictsHandler) List(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	if _, ok := operator(w, r, "source conflicts"); !ok {
+		return
+	}
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	tol, err := processor.ParseTolerance(r.URL.Query().Get("tolerance"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, r, http.StatusOK, models.ConflictsResponse{
+		Election:  election,
+		Tolerance: tol.String(),
+		Conflicts: h.processor.Conflicts(election, r.URL.Query().Get("county"), tol),
+	})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// ContactsHandler manages the county contact directory used to escalate
+// source outages.
+type ContactsHandler struct {
+	store store.Store
+}
+
+// NewContactsHandler returns a handler storing contacts in st.
+func NewContactsHandler(st store.Store) *ContactsHandler {
+	return &ContactsHandler{store: st}
+}
+
+// visible reports whether the caller may see contacts, answering 403 if
+// not. Whole records are served here, so the visibility policy can't just
+// strip a field from them.
+func (h *ContactsHandler) visible(w http.ResponseWriter, r *http.Request) bool {
+	if p, ok := auth.FromContext(r.Context()); ok && p.Policy.Hides("contact") {
+		writeError(w, http.StatusForbidden, "county contacts are not visible to this API key")
+		return false
+	}
+	return true
+}
+
+// List serves GET /api/v1/contacts.
+func (h *ContactsHandler) List(w http.ResponseWriter, r *http.Request) {
+	if !h.visible(w, r) {
+		return
+	}
+	writeJSON(w, r, http.StatusOK, h.store.Contacts())
+}
+
+// Get serves GET /api/v1/contacts/{county}.
+func (h *ContactsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !h.visible(w, r) {
+		return
+	}
+	c, err := h.store.Contact(r.PathValue("county"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no contact for county")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, c)
+}
+
+// Put serves PUT /api/v1/contacts/{county}, creating or replacing the
+// county's contact. The county may be set up before it is registered.
+func (h *ContactsHandler) Put(w http.ResponseWriter, r *http.Request) {
+	if !h.visible(w, r) {
+		return
+	}
+	var c models.CountyContact
+	if !decodeBody(w, r, &c) {
+		return
+	}
+	if err := validate.Contact(c); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	// Name the county as registered, or as first given, rather than by
+	// however this request spelled the path.
+	c.County = r.PathValue("county")
+	status := http.StatusCreated
+	if old, err := h.store.Contact(c.County); err == nil {
+		c.County, status = old.County, http.StatusOK
+	}
+	if reg, err := h.store.County(c.County); err == nil {
+		c.County = reg.Name
+	}
+	c.UpdatedAt = time.Now().UTC()
+	h.store.SaveContact(c)
+	writeJSON(w, r, status, c)
+}
+
+// Delete serves DELETE /api/v1/contacts/{county}.
+func (h *ContactsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !h.visible(w, r) {
+		return
+	}
+	if err := h.store.DeleteContact(r.PathValue("county")); errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no contact for county")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
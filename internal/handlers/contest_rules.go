@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/contestrules"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// ContestRulesHandler manages contest title mapping rules.
+type ContestRulesHandler struct {
+	store    store.Store
+	engine   *contestrules.Engine
+	election string
+}
+
+// NewContestRulesHandler returns a handler for the rules of election.
+func NewContestRulesHandler(st store.Store, e *contestrules.Engine, election string) *ContestRulesHandler {
+	return &ContestRulesHandler{store: st, engine: e, election: election}
+}
+
+// List serves GET /api/v1/contest-rules?election=.
+func (h *ContestRulesHandler) List(w http.ResponseWriter, r *http.Request) {
+	election := r.URL.Query().Get("election")
+	if election == "" {
+		election = h.election
+	}
+	writeJSON(w, r, http.StatusOK, h.store.ContestRules(election))
+}
+
+// Create serves POST /api/v1/contest-rules.
+func (h *ContestRulesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var rule models.ContestRule
+	if !decodeBody(w, r, &rule) {
+		return
+	}
+	if err := validate.ContestRule(rule); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if rule.Election == "" {
+		rule.Election = h.election
+	}
+	if rule.ID == "" {
+		id, err := models.NewID()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to create rule")
+			return
+		}
+		rule.ID = id
+	}
+
+	h.store.SaveContestRule(rule)
+	writeJSON(w, r, http.StatusCreated, rule)
+}
+
+// Delete serves DELETE /api/v1/contest-rules/{id}.
+func (h *ContestRulesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteContestRule(r.PathValue("id")); errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "rule not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Test serves GET /api/v1/contest-rules/test?title=&county=&election=,
+// showing which rule a title would match.
+func (h *ContestRulesHandler) Test(w http.ResponseWriter, r *http.Request) {
+	title := r.URL.Query().Get("title")
+	if title == "" {
+		writeError(w, http.StatusBadRequest, "title query parameter is required")
+		return
+	}
+
+	rule, ok := h.engine.Resolve(r.URL.Query().Get("election"), r.URL.Query().Get("county"), title)
+	if !ok {
+		writeJSON(w, r, http.StatusOK, map[string]any{"matched": false, "contestId": models.Slug(title)})
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]any{"matched": true, "contestId": rule.CanonicalID, "rule": rule})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// CountiesHandler manages counties registered for scheduled refreshes.
+type CountiesHandler struct {
+	store store.Store
+}
+
+// NewCountiesHandler returns a handler storing registrations in st.
+func NewCountiesHandler(st store.Store) *CountiesHandler {
+	return &CountiesHandler{store: st}
+}
+
+// List serves GET /api/v1/counties?election=, the counties registered in
+// the election. Sources whose last fetch failed carry the county's contact
+// so an outage can be escalated from the listing.
+func (h *CountiesHandler) List(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	sources := h.store.ElectionCountySources(election)
+	for i, c := range sources {
+		if c.Status.LastError == "" {
+			continue
+		}
+		if contact, err := h.store.Contact(c.Name); err == nil {
+			sources[i].Contact = &contact
+		}
+	}
+	writeJSON(w, r, http.StatusOK, sources)
+}
+
+// Register serves POST /api/v1/counties, registering the county in the
+// election named in the body or by ?election=, or the default one.
+func (h *CountiesHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var c models.CountySource
+	if !decodeBody(w, r, &c) {
+		return
+	}
+	if election := r.URL.Query().Get("election"); election != "" {
+		c.Election = election
+	}
+	if err := validate.CountySource(c); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	election, err := h.store.Election(c.Election)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "unknown election")
+		return
+	}
+	if election.Archived() {
+		writeError(w, http.StatusConflict, "election is archived")
+		return
+	}
+	c.Election = election.ID
+	c.Name = strings.TrimSpace(c.Name)
+	c.Status = models.SourceStatus{}
+	c.Contact = nil
+	c.RegisteredAt = time.Now().UTC()
+
+	h.store.SaveCounty(c)
+	saved, _ := h.store.ElectionCounty(c.Election, c.Name)
+	writeJSON(w, r, http.StatusCreated, saved)
+}
+
+// Delete serves DELETE /api/v1/counties/{county}?election=.
+func (h *CountiesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteCounty(r.URL.Query().Get("election"), r.PathValue("county")); errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "county not registered")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// CredentialsHandler manages the logins used to fetch counties' sources
+// from servers that need one, such as FTP servers. Passwords can be set
+// but are never served back.
+type CredentialsHandler struct {
+	store store.Store
+}
+
+// NewCredentialsHandler returns a handler storing credentials in st.
+func NewCredentialsHandler(st store.Store) *CredentialsHandler {
+	return &CredentialsHandler{store: st}
+}
+
+// Get serves GET /api/v1/counties/{county}/credentials, without the
+// password.
+func (h *CredentialsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if _, ok := operator(w, r, "source credentials"); !ok {
+		return
+	}
+	c, err := h.store.Credentials(r.PathValue("county"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no credentials for county")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, c.Redacted())
+}
+
+// Put serves PUT /api/v1/counties/{county}/credentials, creating or
+// replacing the login used for every source of the county. The county may
+// be set up before it is registered.
+func (h *CredentialsHandler) Put(w http.ResponseWriter, r *http.Request) {
+	if _, ok := operator(w, r, "source credentials"); !ok {
+		return
+	}
+	var c models.SourceCredentials
+	if !decodeBody(w, r, &c) {
+		return
+	}
+	if err := validate.Credentials(c); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	c.County = r.PathValue("county")
+	status := http.StatusCreated
+	if old, err := h.store.Credentials(c.County); err == nil {
+		c.County, status = old.County, http.StatusOK
+	}
+	if reg, err := h.store.County(c.County); err == nil {
+		c.County = reg.Name
+	}
+	c.UpdatedAt = time.Now().UTC()
+	h.store.SaveCredentials(c)
+	writeJSON(w, r, status, c.Redacted())
+}
+
+// Delete serves DELETE /api/v1/counties/{county}/credentials.
+func (h *CredentialsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if _, ok := operator(w, r, "source credentials"); !ok {
+		return
+	}
+	if err := h.store.DeleteCredentials(r.PathValue("county")); errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no credentials for county")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// DatasetsHandler serves results staged under a label and switches them
+// live, so a correction touching many contests is cut over in one step.
+// Results are staged by processing with "dataset" set.
+type DatasetsHandler struct {
+	store     store.Store
+	processor *processor.Processor
+}
+
+// NewDatasetsHandler returns a handler over the datasets in st that
+// switches them live with p.
+func NewDatasetsHandler(st store.Store, p *processor.Processor) *DatasetsHandler {
+	return &DatasetsHandler{store: st, processor: p}
+}
+
+// List serves GET /api/v1/datasets, most recently updated first.
+func (h *DatasetsHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.store.Datasets())
+}
+
+// Get serves GET /api/v1/datasets/{label}.
+func (h *DatasetsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	d, err := h.store.Dataset(r.PathValue("label"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "dataset not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, d)
+}
+
+// Results serves GET /api/v1/datasets/{label}/results/{county}?election=,
+// a staged snapshot as it would be published, paged and trimmed to the
+// named fields as published results are. Delayed access tiers are
+// refused, as staged snapshots are not embargoed.
+func (h *DatasetsHandler) Results(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	offset, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	results, err := h.store.StagedResults(r.PathValue("label"), r.URL.Query().Get("election"), r.PathValue("county"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "no staged results for county")
+		return
+	}
+	w.Header().Set("X-Snapshot-Hash", results.Hash)
+	results.Contests, results.Page = paginate(results.Contests, offset, limit)
+	writeJSON(w, r, http.StatusOK, selectFields(r, results))
+}
+
+// Activate serves POST /api/v1/datasets/{label}/activate, switching the
+// dataset live. Activating "previous" switches back.
+func (h *DatasetsHandler) Activate(w http.ResponseWriter, r *http.Request) {
+	sw, err := h.processor.ActivateDataset(r.Context(), r.PathValue("label"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "dataset not found")
+		return
+	}
+	if errors.Is(err, store.ErrElectionArchived) {
+		writeError(w, http.StatusConflict, "dataset has results for an archived election")
+		return
+	}
+	if errors.Is(err, store.ErrCertified) {
+		writeError(w, http.StatusConflict, "dataset has results for a certified county")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to switch dataset live")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, sw)
+}
+
+// Delete serves DELETE /api/v1/datasets/{label}, discarding the staged
+// results.
+func (h *DatasetsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	switch err := h.store.DeleteDataset(r.PathValue("label")); {
+	case errors.Is(err, store.ErrLegalHold):
+		writeError(w, http.StatusConflict, "dataset has snapshots under a legal hold")
+	case err != nil:
+		writeError(w, http.StatusNotFound, "dataset not found")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// DiscoveryHandler serves GET /.well-known/era.json.
+type DiscoveryHandler struct {
+	doc   models.Discovery
+	store store.Store
+}
+
+// NewDiscoveryHandler returns a handler serving doc, which is fixed at
+// startup apart from its elections, listed from st.
+func NewDiscoveryHandler(doc models.Discovery, st store.Store) *DiscoveryHandler {
+	return &DiscoveryHandler{doc: doc, store: st}
+}
+
+func (h *DiscoveryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	doc := h.doc
+	doc.Elections = []models.DiscoveryElection{}
+	for _, e := range h.store.Elections() {
+		doc.Elections = append(doc.Elections, models.DiscoveryElection{ID: e.ID, Default: e.Default})
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, r, http.StatusOK, doc)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/aggregate"
+	"github.com/many221/era_api_v1/internal/districts"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// maxDistrictBytes bounds an imported district file, which lists every
+// precinct of a state's districts.
+const maxDistrictBytes = 20 << 20
+
+// DistrictsHandler manages district definitions and rolls precinct results
+// up into theirs.
+type DistrictsHandler struct {
+	store store.Store
+}
+
+// NewDistrictsHandler returns a handler for the districts in st.
+func NewDistrictsHandler(st store.Store) *DistrictsHandler {
+	return &DistrictsHandler{store: st}
+}
+
+// List serves GET /api/v1/districts?type=, every district or those of a
+// type.
+func (h *DistrictsHandler) List(w http.ResponseWriter, r *http.Request) {
+	typ := strings.ToLower(r.URL.Query().Get("type"))
+	out := []models.District{}
+	for _, d := range h.store.Districts() {
+		if typ == "" || d.Type == typ {
+			out = append(out, d)
+		}
+	}
+	writeJSON(w, r, http.StatusOK, out)
+}
+
+// Get serves GET /api/v1/districts/{id}.
+func (h *DistrictsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	d, err := h.store.District(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "district not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, d)
+}
+
+// Import serves POST /api/v1/districts/import?format=, adding the
+// districts in the body, JSON or CSV, and replacing those already defined
+// with the same IDs. The format is taken from ?format=, then the
+// Content-Type, then the content. Like other configuration, districts are
+// kept in memory.
+func (h *DistrictsHandler) Import(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxDistrictBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read body")
+		return
+	}
+	if len(data) > maxDistrictBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "district file is too large")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		switch mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt {
+		case "text/csv":
+			format = districts.FormatCSV
+		case "application/json":
+			format = districts.FormatJSON
+		}
+	}
+	list, err := districts.Parse(data, format)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	imported := models.DistrictImport{Districts: make([]string, 0, len(list))}
+	for _, d := range list {
+		h.store.SaveDistrict(d)
+		imported.Districts = append(imported.Districts, d.ID)
+		imported.Precincts += len(d.Precincts)
+	}
+	writeJSON(w, r, http.StatusOK, imported)
+}
+
+// Delete serves DELETE /api/v1/districts/{id}.
+func (h *DistrictsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteDistrict(r.PathValue("id")); err != nil {
+		writeError(w, http.StatusNotFound, "district not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Results serves GET /api/v1/districts/{id}/results?election=, the
+// district's results rolled up from its precincts' in the election or the
+// default one.
+func (h *DistrictsHandler) Results(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	d, err := h.store.District(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "district not found")
+		return
+	}
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	res := aggregate.District(h.store, election, d)
+	setSnapshotVersion(w, res.Version)
+	writeJSON(w, r, http.StatusOK, res)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/dump"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// DumpHandler serves bulk downloads of the full published dataset as
+// gzip-compressed newline-delimited JSON: one line per county, in the same
+// shape as GET /api/v1/results/{county}. It suits consumers that prefer a
+// periodic bulk sync over polling each county.
+type DumpHandler struct {
+	store    store.Store
+	snapshot *dump.Builder
+}
+
+// NewDumpHandler returns a handler streaming every county stored in st and
+// serving chunked snapshots built by b.
+func NewDumpHandler(st store.Store, b *dump.Builder) *DumpHandler {
+	return &DumpHandler{store: st, snapshot: b}
+}
+
+// ServeHTTP serves GET /api/v1/dump.ndjson.gz as a single stream.
+func (h *DumpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="dump.ndjson.gz"`)
+
+	gz := gzip.NewWriter(w)
+	defer gz.Close()
+	enc := json.NewEncoder(gz)
+
+	// Counties are read in one step so the stream is a consistent
+	// snapshot; each is shaped only as it is written.
+	snap := h.store.ElectionSnapshot("")
+	setSnapshotVersion(w, snap.Version)
+	w.WriteHeader(http.StatusOK)
+	record := h.record(r)
+	for _, results := range snap.Results {
+		if r.Context().Err() != nil {
+			return
+		}
+		rec, ok := record(results)
+		if !ok {
+			continue
+		}
+		if err := enc.Encode(rec); err != nil {
+			return
+		}
+	}
+}
+
+// Manifest serves GET /api/v1/dump/manifest, the parts of the current
+// snapshot with their sizes and SHA-256 hashes. Parts stay downloadable for
+// a while after a newer snapshot supersedes them.
+func (h *DumpHandler) Manifest(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	s, err := h.snapshot.Latest(dumpView(r), h.record(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to build snapshot")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, dump.NewManifest(s, func(id string, index int) string {
+		return fmt.Sprintf("/api/v1/dump/%s/parts/%d", id, index)
+	}))
+}
+
+// Part serves GET /api/v1/dump/{snapshot}/parts/{index}. Range and If-Range
+// requests are honored so interrupted downloads can resume.
+func (h *DumpHandler) Part(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	s, ok := h.snapshot.Snapshot(dumpView(r), r.PathValue("snapshot"))
+	if !ok {
+		writeError(w, http.StatusGone, "snapshot expired; fetch a new manifest")
+		return
+	}
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || index < 0 || index >= len(s.Parts) {
+		writeError(w, http.StatusNotFound, "no such part")
+		return
+	}
+
+	part := s.Parts[index]
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("ETag", `"`+part.SHA256+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	name := fmt.Sprintf("dump-%s-%d.ndjson.gz", s.ID, index)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	http.ServeContent(w, r, name, s.GeneratedAt, bytes.NewReader(part.Data()))
+}
+
+// record returns the dump line for a county as r's caller may see it.
+func (h *DumpHandler) record(r *http.Request) dump.RecordFunc {
+	return func(results *models.Results) (any, bool) {
+		attachForecasts(h.store, results)
+		attachOutcomes(h.store, results)
+		attachJurisdictions(h.store, results)
+		return shape(r, results), true
+	}
+}
+
+// dumpView identifies the fields r's caller may see, so snapshots shaped for
+// one key are never served to another.
+func dumpView(r *http.Request) string {
+	p, ok := auth.FromContext(r.Context())
+	switch {
+	case !ok:
+		return ""
+	case p.Anonymous:
+		return "anonymous"
+	default:
+		return "key:" + p.Key.ID
+	}
+}
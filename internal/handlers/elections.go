@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/contestrules"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// ElectionsHandler manages the elections the server publishes results for.
+type ElectionsHandler struct {
+	store store.Store
+}
+
+// NewElectionsHandler returns a handler storing elections in st.
+func NewElectionsHandler(st store.Store) *ElectionsHandler {
+	return &ElectionsHandler{store: st}
+}
+
+// List serves GET /api/v1/elections.
+func (h *ElectionsHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.store.Elections())
+}
+
+// Get serves GET /api/v1/elections/{election}.
+func (h *ElectionsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	e, err := h.store.Election(r.PathValue("election"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "unknown election")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, e)
+}
+
+// Create serves POST /api/v1/elections. Counties can be registered and
+// results published for the election once it exists. Like other
+// configuration, elections are kept in memory; after a restart one with
+// events in the event log is known again by its ID alone.
+func (h *ElectionsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var e models.Election
+	if !decodeBody(w, r, &e) {
+		return
+	}
+	e.ID = strings.TrimSpace(e.ID)
+	if err := validate.Election(e); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if _, err := h.store.Election(e.ID); err == nil {
+		writeError(w, http.StatusConflict, "election already exists")
+		return
+	}
+	e.Type = strings.ToLower(e.Type)
+	e.CreatedAt = time.Now().UTC()
+	h.store.SaveElection(e)
+	saved, _ := h.store.Election(e.ID)
+	writeJSON(w, r, http.StatusCreated, saved)
+}
+
+// Delete serves DELETE /api/v1/elections/{election}. Only elections with
+// nothing registered or published can be deleted, and never the default.
+func (h *ElectionsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	switch err := h.store.DeleteElection(r.PathValue("election")); {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "unknown election")
+	case errors.Is(err, store.ErrElectionInUse):
+		writeError(w, http.StatusConflict, "the default election, or one with registered counties or published results, can't be deleted")
+	case errors.Is(err, store.ErrLegalHold):
+		writeError(w, http.StatusConflict, "election is under a legal hold")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Archive serves POST /api/v1/elections/{election}/archive, freezing the
+// election's results for good. They stay queryable under
+// /api/v1/elections/{election}/results; nothing more is published for it
+// and its counties are no longer refreshed.
+func (h *ElectionsHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	e, err := h.store.ArchiveElection(r.PathValue("election"), time.Now().UTC())
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "unknown election")
+	case errors.Is(err, store.ErrElectionInUse):
+		writeError(w, http.StatusConflict, "the default election can't be archived")
+	case errors.Is(err, store.ErrElectionArchived):
+		writeError(w, http.StatusConflict, "election is already archived")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "failed to archive election")
+	default:
+		writeJSON(w, r, http.StatusOK, e)
+	}
+}
+
+// Results serves GET /api/v1/elections/{election}/results?offset=&limit=&fields=:
+// the counties with results in the election and the snapshot each is at,
+// paged and trimmed to the named fields as for a county's results.
+func (h *ElectionsHandler) Results(w http.ResponseWriter, r *http.Request) {
+	offset, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	summary, err := h.store.ElectionSummary(r.PathValue("election"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "unknown election")
+		return
+	}
+	summary.Counties, summary.Page = paginate(summary.Counties, offset, limit)
+	writeJSON(w, r, http.StatusOK, selectFields(r, summary))
+}
+
+// ImportAliases serves POST /api/v1/elections/{election}/aliases/import,
+// seeding the election's mappings from {"from": election}, a prior one: its
+// contest rules are copied, and the county spellings its results resolved
+// to registry candidates become aliases, so recurring offices and
+// candidates match from the first snapshot of the next cycle. Importing
+// again only adds what is new.
+func (h *ElectionsHandler) ImportAliases(w http.ResponseWriter, r *http.Request) {
+	to, err := h.store.Election(r.PathValue("election"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "unknown election")
+		return
+	}
+	if to.Archived() {
+		writeError(w, http.StatusConflict, "election is archived")
+		return
+	}
+	var body struct {
+		From string `json:"from"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.From == "" {
+		writeError(w, http.StatusBadRequest, "from is required")
+		return
+	}
+	from, err := h.store.Election(body.From)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "unknown election to import from")
+		return
+	}
+	if from.ID == to.ID {
+		writeError(w, http.StatusBadRequest, "an election can't import from itself")
+		return
+	}
+
+	var results []*models.Results
+	for _, county := range h.store.ElectionCounties(from.ID) {
+		if res, err := h.store.ElectionResults(from.ID, county); err == nil {
+			results = append(results, res)
+		}
+	}
+	writeJSON(w, r, http.StatusOK, models.AliasImport{
+		From:             from.ID,
+		To:               to.ID,
+		ImportedAt:       time.Now().UTC(),
+		ContestRules:     contestrules.CopyRules(h.store, from.ID, to.ID),
+		CandidateAliases: normalize.SeedAliases(h.store, results),
+	})
+}
+
+// electionParam returns the ID of the election in the path or named by
+// ?election=, the default one if neither is, answering 404 for an unknown
+// election.
+func electionParam(st store.Store, w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.PathValue("election")
+	if id == "" {
+		id = r.URL.Query().Get("election")
+	}
+	e, err := st.Election(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "unknown election")
+		return "", false
+	}
+	return e.ID, true
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/clock"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// callerResults returns the county's results in election as r's caller may
+// see them: the live snapshot, or for a caller in a delayed access tier the
+// one live when the embargo lifts by clk, noting the delay in
+// X-Embargo-Delay.
+func callerResults(st store.Store, clk clock.Clock, w http.ResponseWriter, r *http.Request, election, county string) (*models.Results, error) {
+	p, ok := auth.FromContext(r.Context())
+	if !ok || !p.Tier.Delayed() {
+		return st.ElectionResults(election, county)
+	}
+	w.Header().Set("X-Embargo-Delay", strconv.Itoa(int(p.Tier.Longest().Seconds())))
+	return p.Tier.Results(clk.Now().UTC(), func(at time.Time) (*models.Results, error) {
+		return st.ElectionResultsAt(election, county, at)
+	})
+}
+
+// realTime answers 403 and reports false if r's caller is in a delayed
+// access tier, for feeds that can only be served live.
+func realTime(w http.ResponseWriter, r *http.Request) bool {
+	if p, ok := auth.FromContext(r.Context()); ok && p.Tier.Delayed() {
+		writeError(w, http.StatusForbidden, "this feed is real time, which the API key's access tier doesn't include")
+		return false
+	}
+	return true
+}
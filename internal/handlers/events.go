@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Page sizes of GET /api/v1/events.
+const (
+	defaultEventPage = 100
+	maxEventPage     = 1000
+)
+
+// EventsHandler serves the event log published data is projected from and
+// rebuilds the projections.
+type EventsHandler struct {
+	store      store.Store
+	rebuilding atomic.Bool
+}
+
+// NewEventsHandler returns a handler over the event log of st.
+func NewEventsHandler(st store.Store) *EventsHandler {
+	return &EventsHandler{store: st}
+}
+
+// List serves GET /api/v1/events?after=&election=&county=&limit=, events
+// oldest first, of every election unless ?election= names one. Passing the
+// seq of the last event received as ?after= pages through the log.
+func (h *EventsHandler) List(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	q := r.URL.Query()
+	var after uint64
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "after must be a non-negative integer")
+			return
+		}
+		after = n
+	}
+	limit := defaultEventPage
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxEventPage {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxEventPage))
+			return
+		}
+		limit = n
+	}
+
+	var election string
+	if q.Get("election") != "" {
+		var ok bool
+		if election, ok = electionParam(h.store, w, r); !ok {
+			return
+		}
+	}
+	events, err := h.store.Events(after, election, q.Get("county"), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read event log")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, events)
+}
+
+// Rebuild serves POST /api/v1/projections/rebuild?election=&from=,
+// replaying the election's events through the current projection code and
+// swapping its rebuilt read models in at once. ?from= (RFC 3339) limits it
+// to counties with events since then. Progress is streamed as
+// newline-delimited RebuildUpdates, ending with the report or an error.
+// Only callers that see every field may rebuild, and one rebuild runs at a
+// time.
+func (h *EventsHandler) Rebuild(w http.ResponseWriter, r *http.Request) {
+	if p, ok := auth.FromContext(r.Context()); ok && !p.Policy.Full() {
+		writeError(w, http.StatusForbidden, "rebuilding requires an API key with full visibility")
+		return
+	}
+	q := r.URL.Query()
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	opts := store.RebuildOptions{Election: election}
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "from must be an RFC 3339 time")
+			return
+		}
+		opts.From = t
+	}
+	if !h.rebuilding.CompareAndSwap(false, true) {
+		writeError(w, http.StatusConflict, "a rebuild is already running")
+		return
+	}
+	defer h.rebuilding.Store(false)
+
+	rc := http.NewResponseController(w)
+	// A rebuild can outlast the server's write timeout.
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	send := func(u models.RebuildUpdate) {
+		enc.Encode(u)
+		rc.Flush()
+	}
+
+	step := 1
+	opts.Progress = func(done, total int) {
+		if done == 1 {
+			step = max(1, total/100)
+		}
+		if done%step == 0 || done == total {
+			send(models.RebuildUpdate{Done: done, Total: total})
+		}
+	}
+	report, err := h.store.Rebuild(r.Context(), opts)
+	if err != nil {
+		send(models.RebuildUpdate{Done: report.Events, Error: err.Error()})
+		return
+	}
+	send(models.RebuildUpdate{Done: report.Events, Total: report.Events, Report: &report})
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/aggregate"
+	"github.com/many221/era_api_v1/internal/graphql"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// GraphQLHandler serves /graphql, a read-only query API over counties,
+// contests, snapshots and aggregates.
+type GraphQLHandler struct {
+	store  store.Store
+	schema *graphql.Schema
+}
+
+// countyNode is a county in GraphQL results: its latest snapshot plus its
+// snapshot log.
+type countyNode struct {
+	Key string `json:"key"`
+	*models.Results
+	Snapshots []models.LogEntry `json:"snapshots"`
+}
+
+// contestNode is a contest with the county reporting it.
+type contestNode struct {
+	County string `json:"county"`
+	models.Contest
+}
+
+// NewGraphQLHandler returns a handler resolving queries against st.
+func NewGraphQLHandler(st store.Store) *GraphQLHandler {
+	h := &GraphQLHandler{store: st, schema: graphql.NewSchema()}
+
+	h.schema.Field("counties", nil, func(ctx context.Context, _ map[string]any) (any, error) {
+		nodes := []countyNode{}
+		for _, key := range st.Counties() {
+			if n, err := h.county(key); err == nil {
+				nodes = append(nodes, n)
+			}
+		}
+		return nodes, nil
+	})
+	h.schema.Field("county", []string{"key"}, func(ctx context.Context, args map[string]any) (any, error) {
+		key, err := stringArg(args, "key")
+		if err != nil {
+			return nil, err
+		}
+		n, err := h.county(key)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return n, err
+	})
+	h.schema.Field("contests", []string{"county"}, func(ctx context.Context, args map[string]any) (any, error) {
+		keys := st.Counties()
+		if _, ok := args["county"]; ok {
+			key, err := stringArg(args, "county")
+			if err != nil {
+				return nil, err
+			}
+			keys = []string{store.CountyKey(key)}
+		}
+		nodes := []contestNode{}
+		for _, key := range keys {
+			n, err := h.county(key)
+			if err != nil {
+				continue
+			}
+			for _, c := range n.Contests {
+				nodes = append(nodes, contestNode{County: n.County, Contest: c})
+			}
+		}
+		return nodes, nil
+	})
+	h.schema.Field("snapshots", []string{"county"}, func(ctx context.Context, args map[string]any) (any, error) {
+		county, err := stringArg(args, "county")
+		if err != nil {
+			return nil, err
+		}
+		l, err := st.SnapshotLog(county)
+		if errors.Is(err, store.ErrNotFound) {
+			return []models.LogEntry{}, nil
+		}
+		return l.Entries, err
+	})
+	h.schema.Field("aggregate", []string{"contest"}, func(ctx context.Context, args map[string]any) (any, error) {
+		contest, err := stringArg(args, "contest")
+		if err != nil {
+			return nil, err
+		}
+		agg, err := aggregate.Contest(st, models.Slug(contest))
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return agg, err
+	})
+	h.schema.Field("aggregates", nil, func(ctx context.Context, _ map[string]any) (any, error) {
+		aggs := []*models.Aggregate{}
+		for _, id := range h.contestIDs() {
+			if agg, err := aggregate.Contest(st, id); err == nil {
+				aggs = append(aggs, agg)
+			}
+		}
+		return aggs, nil
+	})
+	h.schema.Field("turnout", nil, func(ctx context.Context, _ map[string]any) (any, error) {
+		return aggregate.Turnout(st), nil
+	})
+	return h
+}
+
+func (h *GraphQLHandler) county(key string) (countyNode, error) {
+	results, err := h.store.Results(key)
+	if err != nil {
+		return countyNode{}, err
+	}
+	attachForecasts(h.store, results)
+	attachOutcomes(h.store, results)
+	attachJurisdictions(h.store, results)
+	n := countyNode{Key: store.CountyKey(results.County), Results: results, Snapshots: []models.LogEntry{}}
+	if l, err := h.store.SnapshotLog(key); err == nil {
+		n.Snapshots = l.Entries
+	}
+	return n, nil
+}
+
+// contestIDs returns every contest ID reported by any county, sorted.
+func (h *GraphQLHandler) contestIDs() []string {
+	seen := make(map[string]bool)
+	for _, key := range h.store.Counties() {
+		results, err := h.store.Results(key)
+		if err != nil {
+			continue
+		}
+		for _, c := range results.Contests {
+			seen[c.ID] = true
+		}
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ServeHTTP serves GET /graphql?query= and POST /graphql with a JSON body of
+// {"query", "operationName", "variables"}.
+func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	var req graphql.Request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	default:
+		body := io.LimitReader(r.Body, graphql.MaxQueryBytes+4096)
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	resp := h.schema.Execute(r.Context(), req, func(v any) any { return shape(r, v) })
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	// The response is already shaped field by field, so it's encoded
+	// directly rather than through writeJSON.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func stringArg(args map[string]any, name string) (string, error) {
+	s, ok := args[name].(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("argument %q must be a non-empty string", name)
+	}
+	return s, nil
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/jobs"
+)
+
+// JobsHandler serves GET /api/v1/jobs/{id}.
+type JobsHandler struct {
+	jobs *jobs.Manager
+}
+
+// NewJobsHandler returns a handler reporting on jobs tracked by jm.
+func NewJobsHandler(jm *jobs.Manager) *JobsHandler {
+	return &JobsHandler{jobs: jm}
+}
+
+func (h *JobsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Get(r.PathValue("id"))
+	if errors.Is(err, jobs.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, job)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// JurisdictionsHandler manages the jurisdiction tree and which level each
+// contest is held at.
+type JurisdictionsHandler struct {
+	store store.Store
+}
+
+// NewJurisdictionsHandler returns a handler for the tree in st.
+func NewJurisdictionsHandler(st store.Store) *JurisdictionsHandler {
+	return &JurisdictionsHandler{store: st}
+}
+
+// List serves GET /api/v1/jurisdictions?type=&parent=, every jurisdiction,
+// top levels first, or those of a type or under a parent. ?parent= with no
+// value lists the roots.
+func (h *JurisdictionsHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	typ := strings.ToLower(q.Get("type"))
+	parent, byParent := q.Get("parent"), q.Has("parent")
+	out := []models.Jurisdiction{}
+	for _, j := range h.store.Jurisdictions() {
+		if (typ == "" || j.Type == typ) && (!byParent || j.Parent == parent) {
+			out = append(out, j)
+		}
+	}
+	writeJSON(w, r, http.StatusOK, out)
+}
+
+// Get serves GET /api/v1/jurisdictions/{id}?election=: the jurisdiction
+// with its ancestors, its children and the contests held at its level in
+// the election.
+func (h *JurisdictionsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	v, err := h.store.JurisdictionView(election, r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "jurisdiction not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, v)
+}
+
+// Create serves POST /api/v1/jurisdictions, adding a jurisdiction under
+// its parent, or as a root. A county's ID is its county key; others
+// default to the slug of their parent's ID and their name, so same-named
+// cities in different counties don't collide. Like other configuration,
+// the tree is kept in memory.
+func (h *JurisdictionsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var j models.Jurisdiction
+	if !decodeBody(w, r, &j) {
+		return
+	}
+	j.Name = strings.TrimSpace(j.Name)
+	j.Type = strings.ToLower(j.Type)
+	if err := validate.Jurisdiction(j); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if j.ID == "" {
+		switch {
+		case j.Type == models.JurisdictionCounty:
+			j.ID = store.CountyKey(j.Name)
+		case j.Parent != "":
+			j.ID = models.Slug(j.Parent + " " + j.Name)
+		default:
+			j.ID = models.Slug(j.Name)
+		}
+	}
+	if _, err := h.store.Jurisdiction(j.ID); err == nil {
+		writeError(w, http.StatusConflict, "jurisdiction already exists")
+		return
+	}
+	if err := h.store.SaveJurisdiction(j); errors.Is(err, store.ErrJurisdictionParent) {
+		writeError(w, http.StatusBadRequest, "parent must be an existing jurisdiction at a higher level")
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, j)
+}
+
+// Delete serves DELETE /api/v1/jurisdictions/{id}. Only jurisdictions
+// without children or contests can be deleted.
+func (h *JurisdictionsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	switch err := h.store.DeleteJurisdiction(r.PathValue("id")); {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "jurisdiction not found")
+	case errors.Is(err, store.ErrJurisdictionInUse):
+		writeError(w, http.StatusConflict, "jurisdiction has children or contests attached")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AttachContest serves POST /api/v1/jurisdictions/{id}/contests?election=,
+// holding {"contestId": ...} at the jurisdiction, so a city's race is filed
+// under the city rather than the county reporting it. A contest is held at
+// one level; attaching it again moves it.
+func (h *JurisdictionsHandler) AttachContest(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		ContestID string `json:"contestId"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.ContestID == "" {
+		writeError(w, http.StatusBadRequest, "contestId is required")
+		return
+	}
+	if err := h.store.AttachContest(election, body.ContestID, r.PathValue("id")); err != nil {
+		writeError(w, http.StatusNotFound, "jurisdiction not found")
+		return
+	}
+	v, _ := h.store.JurisdictionView(election, r.PathValue("id"))
+	writeJSON(w, r, http.StatusOK, v)
+}
+
+// DetachContest serves
+// DELETE /api/v1/jurisdictions/{id}/contests/{contest}?election=.
+func (h *JurisdictionsHandler) DetachContest(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	if err := h.store.DetachContest(election, r.PathValue("contest"), r.PathValue("id")); err != nil {
+		writeError(w, http.StatusNotFound, "contest isn't attached to the jurisdiction")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// attachJurisdictions sets the jurisdiction each contest attached to one is
+// held in.
+func attachJurisdictions(st store.Store, results *models.Results) {
+	at := st.ContestJurisdictions(results.Election)
+	for i := range results.Contests {
+		results.Contests[i].Jurisdiction = at[results.Contests[i].ID]
+	}
+}
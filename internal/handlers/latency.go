@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/aggregate"
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// LatencyHandler serves how long snapshots take to get from a county's
+// source file to the API.
+type LatencyHandler struct {
+	store store.Store
+}
+
+// NewLatencyHandler returns a handler summarizing the latency history in
+// st.
+func NewLatencyHandler(st store.Store) *LatencyHandler {
+	return &LatencyHandler{store: st}
+}
+
+// visible reports whether the caller may see latency, answering 403 if not.
+// Like snapshots' latency field, it is hidden from partner and public keys.
+func (h *LatencyHandler) visible(w http.ResponseWriter, r *http.Request) bool {
+	if p, ok := auth.FromContext(r.Context()); ok && p.Policy.Hides("latency") {
+		writeError(w, http.StatusForbidden, "latency is not visible to this API key")
+		return false
+	}
+	return true
+}
+
+// List serves GET /api/v1/latency: percentiles over every county, then per
+// county.
+func (h *LatencyHandler) List(w http.ResponseWriter, r *http.Request) {
+	if !h.visible(w, r) {
+		return
+	}
+	writeJSON(w, r, http.StatusOK, aggregate.Latency(h.store))
+}
+
+// Get serves GET /api/v1/latency/{county}, the county's percentiles and its
+// recent snapshots newest first.
+func (h *LatencyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !h.visible(w, r) {
+		return
+	}
+	s, err := aggregate.CountyLatency(h.store, r.PathValue("county"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no snapshots for county")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, s)
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// LayoutHandler serves the history of source layout changes.
+type LayoutHandler struct {
+	store store.Store
+}
+
+// NewLayoutHandler returns a handler reading from st.
+func NewLayoutHandler(st store.Store) *LayoutHandler {
+	return &LayoutHandler{store: st}
+}
+
+// Changes serves GET /api/v1/layout-changes, newest first, optionally for a
+// single ?county=.
+func (h *LayoutHandler) Changes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.store.LayoutChanges(r.URL.Query().Get("county")))
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// LegalHoldsHandler places and releases legal holds, logging each for the
+// audit trail.
+type LegalHoldsHandler struct {
+	store  store.Store
+	logger *slog.Logger
+}
+
+// NewLegalHoldsHandler returns a handler keeping holds in st.
+func NewLegalHoldsHandler(st store.Store, logger *slog.Logger) *LegalHoldsHandler {
+	return &LegalHoldsHandler{store: st, logger: logger}
+}
+
+// List serves GET /api/v1/legal-holds?active=, every hold or, with
+// ?active=true, those in force.
+func (h *LegalHoldsHandler) List(w http.ResponseWriter, r *http.Request) {
+	active := r.URL.Query().Get("active") == "true"
+	out := []models.LegalHold{}
+	for _, hold := range h.store.LegalHolds() {
+		if !active || hold.Active() {
+			out = append(out, hold)
+		}
+	}
+	writeJSON(w, r, http.StatusOK, out)
+}
+
+// Get serves GET /api/v1/legal-holds/{id}.
+func (h *LegalHoldsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	hold, err := h.store.LegalHold(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "legal hold not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, hold)
+}
+
+// Create serves POST /api/v1/legal-holds, placing a hold on an election or
+// one county in it. Like other configuration, holds are kept in memory.
+func (h *LegalHoldsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	by, ok := operator(w, r, "legal holds")
+	if !ok {
+		return
+	}
+	var hold models.LegalHold
+	if !decodeBody(w, r, &hold) {
+		return
+	}
+	hold.Reason = strings.TrimSpace(hold.Reason)
+	hold.County = strings.TrimSpace(hold.County)
+	if err := validate.LegalHold(hold); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	election, err := h.store.Election(hold.Election)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "unknown election")
+		return
+	}
+	id, err := models.NewID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate id")
+		return
+	}
+	hold.ID = id
+	hold.Election = election.ID
+	hold.PlacedBy = by
+	hold.PlacedAt = time.Now().UTC()
+	hold.ReleasedBy, hold.ReleasedAt = "", nil
+	hold = h.store.SaveLegalHold(hold)
+	h.logger.InfoContext(r.Context(), "legal hold placed", "id", hold.ID, "election", hold.Election, "county", hold.County, "reason", hold.Reason, "by", hold.PlacedBy)
+	writeJSON(w, r, http.StatusCreated, hold)
+}
+
+// Release serves POST /api/v1/legal-holds/{id}/release. The hold is kept
+// as a record of when it was in force.
+func (h *LegalHoldsHandler) Release(w http.ResponseWriter, r *http.Request) {
+	by, ok := operator(w, r, "legal holds")
+	if !ok {
+		return
+	}
+	hold, err := h.store.ReleaseLegalHold(r.PathValue("id"), by, time.Now().UTC())
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "legal hold not found")
+	case errors.Is(err, store.ErrHoldReleased):
+		writeError(w, http.StatusConflict, "legal hold is already released")
+	default:
+		h.logger.InfoContext(r.Context(), "legal hold released", "id", hold.ID, "election", hold.Election, "county", hold.County, "by", by)
+		writeJSON(w, r, http.StatusOK, hold)
+	}
+}
+
+// operator returns the ID of the API key making a change recorded for the
+// audit trail, such as placing a hold, answering 403 unless the key sees
+// every field. what names the change in the error.
+func operator(w http.ResponseWriter, r *http.Request, what string) (string, bool) {
+	p, ok := auth.FromContext(r.Context())
+	if ok && !p.Policy.Full() {
+		writeError(w, http.StatusForbidden, what+" require an API key with full visibility")
+		return "", false
+	}
+	return p.Key.ID, true
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/aggregate"
+	"github.com/many221/era_api_v1/internal/clock"
+	"github.com/many221/era_api_v1/internal/formatter"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// LiteHandler serves the /lite endpoints: tiny plain-text or compact JSON
+// payloads for SMS gateways, radio scripts and other low-bandwidth consumers.
+// Plain text is the default; ?format=json selects compact JSON and ?max=
+// truncates text output.
+type LiteHandler struct {
+	store store.Store
+	clock clock.Clock
+}
+
+// NewLiteHandler returns a handler reading from st, lifting embargoes by
+// clk.
+func NewLiteHandler(st store.Store, clk clock.Clock) *LiteHandler {
+	return &LiteHandler{store: st, clock: clk}
+}
+
+// Counties serves GET /lite/counties.
+func (h *LiteHandler) Counties(w http.ResponseWriter, r *http.Request) {
+	writeLiteText(w, strings.Join(h.store.Counties(), "\n")+"\n")
+}
+
+// County serves GET /lite/{county}.
+func (h *LiteHandler) County(w http.ResponseWriter, r *http.Request) {
+	results, err := callerResults(h.store, h.clock, w, r, "", r.PathValue("county"))
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "no results", http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		writeJSON(w, r, http.StatusOK, formatter.Lite(results))
+		return
+	}
+	writeLiteText(w, formatter.LiteText(results, liteMax(r)))
+}
+
+// Aggregate serves GET /lite/aggregate/{contest}.
+func (h *LiteHandler) Aggregate(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	agg, err := aggregate.Contest(h.store, models.Slug(r.PathValue("contest")))
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "no results", http.StatusNotFound)
+		return
+	}
+	setSnapshotVersion(w, agg.Version)
+
+	if r.URL.Query().Get("format") == "json" {
+		writeJSON(w, r, http.StatusOK, agg)
+		return
+	}
+	writeLiteText(w, formatter.LiteAggregateText(agg, liteMax(r)))
+}
+
+func liteMax(r *http.Request) int {
+	n, _ := strconv.Atoi(r.URL.Query().Get("max"))
+	return n
+}
+
+func writeLiteText(w http.ResponseWriter, s string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(s))
+}
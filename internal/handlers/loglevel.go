@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// LogLevelHandler serves GET and PUT /admin/loglevel, so debug logging can
+// be turned on during an incident and off again without a restart.
+type LogLevelHandler struct {
+	level  *slog.LevelVar
+	logger *slog.Logger
+}
+
+// NewLogLevelHandler returns a handler reporting and changing level, the
+// level the server's log handler is built with.
+func NewLogLevelHandler(level *slog.LevelVar, logger *slog.Logger) *LogLevelHandler {
+	return &LogLevelHandler{level: level, logger: logger}
+}
+
+// Get reports the current level.
+func (h *LogLevelHandler) Get(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, models.LogLevel{Level: levelName(h.level.Level())})
+}
+
+// Set changes the level to the one the body names.
+func (h *LogLevelHandler) Set(w http.ResponseWriter, r *http.Request) {
+	var req models.LogLevel
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	level, err := ParseLogLevel(req.Level)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	previous := h.level.Level()
+	h.level.Set(level)
+	actor := ""
+	if p, ok := auth.FromContext(r.Context()); ok {
+		actor = p.Key.ID
+	}
+	// Logged at warn so the change shows whatever the level.
+	h.logger.WarnContext(r.Context(), "log level changed", "from", levelName(previous), "to", levelName(level), "actor", actor)
+	writeJSON(w, r, http.StatusOK, models.LogLevel{Level: levelName(level)})
+}
+
+// ParseLogLevel parses debug, info, warn or error, in any case, as LOG_LEVEL
+// and PUT /admin/loglevel take them.
+func ParseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q, want debug, info, warn or error", s)
+}
+
+func levelName(l slog.Level) string {
+	return strings.ToLower(l.String())
+}
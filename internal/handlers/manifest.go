@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/fetcher"
+	"github.com/many221/era_api_v1/internal/manifest"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// maxManifestBytes bounds an uploaded manifest.
+const maxManifestBytes = 10 << 20
+
+// ManifestHandler imports the expected ballot of an election and checks
+// published results against it.
+type ManifestHandler struct {
+	store    store.Store
+	fetcher  *fetcher.Fetcher
+	election string
+}
+
+// NewManifestHandler returns a handler for the manifests in st, importing
+// from URLs with f and defaulting to election.
+func NewManifestHandler(st store.Store, f *fetcher.Fetcher, election string) *ManifestHandler {
+	return &ManifestHandler{store: st, fetcher: f, election: election}
+}
+
+func (h *ManifestHandler) electionOf(r *http.Request) string {
+	if e := r.URL.Query().Get("election"); e != "" {
+		return e
+	}
+	return h.election
+}
+
+// Get serves GET /api/v1/manifest?election=.
+func (h *ManifestHandler) Get(w http.ResponseWriter, r *http.Request) {
+	m, err := h.store.Manifest(h.electionOf(r))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "no manifest for election")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, m)
+}
+
+// Put serves PUT /api/v1/manifest?election=&format=, replacing the
+// election's manifest with the body. The format is taken from ?format=,
+// then the Content-Type, then the content.
+func (h *ManifestHandler) Put(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxManifestBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read body")
+		return
+	}
+	if len(data) > maxManifestBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "manifest is too large")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		switch mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt {
+		case "text/csv":
+			format = manifest.FormatCSV
+		case "application/json":
+			format = manifest.FormatJSON
+		}
+	}
+	h.save(w, r, data, format, "upload")
+}
+
+// Import serves POST /api/v1/manifest/import, replacing the election's
+// manifest with one downloaded from {"url": ...}.
+func (h *ManifestHandler) Import(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URL    string `json:"url"`
+		Format string `json:"format"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.URL == "" {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+	data, err := h.fetcher.Fetch(r.Context(), body.URL)
+	if err != nil {
+		writeError(w, processErrorStatus(err), err.Error())
+		return
+	}
+	if body.Format == "" {
+		body.Format = manifest.FormatOf(body.URL)
+	}
+	h.save(w, r, data, body.Format, body.URL)
+}
+
+func (h *ManifestHandler) save(w http.ResponseWriter, r *http.Request, data []byte, format, source string) {
+	m, err := manifest.Import(data, format, source, h.electionOf(r))
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	m.ImportedAt = time.Now().UTC()
+	h.store.SaveManifest(m)
+	writeJSON(w, r, http.StatusOK, m)
+}
+
+// Delete serves DELETE /api/v1/manifest?election=.
+func (h *ManifestHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteManifest(h.electionOf(r)); err != nil {
+		writeError(w, http.StatusNotFound, "no manifest for election")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Check serves GET /api/v1/manifest/check?election=&county=: every
+// published county's results compared with the manifest, or only one
+// county's. Counties the manifest expects contests from that have published
+// nothing are listed with every contest missing.
+func (h *ManifestHandler) Check(w http.ResponseWriter, r *http.Request) {
+	m, err := h.store.Manifest(h.electionOf(r))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "no manifest for election")
+		return
+	}
+	now := time.Now().UTC()
+	county := r.URL.Query().Get("county")
+	counties := h.store.Counties()
+	if county != "" {
+		counties = []string{county}
+	}
+
+	checks := []models.ManifestCheck{}
+	seen := make(map[string]bool)
+	for _, c := range counties {
+		results, err := h.store.Results(c)
+		if err != nil {
+			results = &models.Results{County: c}
+		}
+		seen[store.CountyKey(results.County)] = true
+		checks = append(checks, manifest.Compare(m, results, now))
+	}
+	if county == "" {
+		for _, contest := range m.Contests {
+			for _, c := range contest.Counties {
+				if !seen[store.CountyKey(c)] {
+					seen[store.CountyKey(c)] = true
+					checks = append(checks, manifest.Compare(m, &models.Results{County: c}, now))
+				}
+			}
+		}
+	}
+	writeJSON(w, r, http.StatusOK, checks)
+}
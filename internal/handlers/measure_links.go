@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/measures"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// MeasureLinksHandler links the contests counties report a regional
+// measure as, and suggests links.
+type MeasureLinksHandler struct {
+	store store.Store
+}
+
+// NewMeasureLinksHandler returns a handler keeping links in st.
+func NewMeasureLinksHandler(st store.Store) *MeasureLinksHandler {
+	return &MeasureLinksHandler{store: st}
+}
+
+// List serves GET /api/v1/measure-links?election=.
+func (h *MeasureLinksHandler) List(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, r, http.StatusOK, h.store.MeasureLinks(election))
+}
+
+// Create serves POST /api/v1/measure-links?election=, adding or replacing
+// a link. Its ID, the slug of its title by default, is the contest ID the
+// measure aggregates under. Members' titles are filled in from their
+// counties' results. Like other configuration, links are kept in memory.
+func (h *MeasureLinksHandler) Create(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	var l models.MeasureLink
+	if !decodeBody(w, r, &l) {
+		return
+	}
+	l.Title = strings.TrimSpace(l.Title)
+	if err := validate.MeasureLink(l); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if l.ID == "" {
+		l.ID = models.Slug(l.Title)
+	}
+	l.Election = election
+	for i, m := range l.Members {
+		if results, err := h.store.ElectionResults(election, m.County); err == nil {
+			if c, ok := results.ContestByID(m.ContestID); ok {
+				l.Members[i].Title = c.Title
+			}
+		}
+	}
+	l.CreatedAt = time.Now().UTC()
+	writeJSON(w, r, http.StatusCreated, h.store.SaveMeasureLink(l))
+}
+
+// Delete serves DELETE /api/v1/measure-links/{id}?election=.
+func (h *MeasureLinksHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	if err := h.store.DeleteMeasureLink(election, r.PathValue("id")); errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "measure link not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Suggestions serves GET /api/v1/measure-links/suggestions?election=:
+// measures in the election's published results that read alike across
+// counties but aren't linked, best matches first. Posting a suggestion to
+// /api/v1/measure-links accepts it.
+func (h *MeasureLinksHandler) Suggestions(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	var results []*models.Results
+	for _, county := range h.store.ElectionCounties(election) {
+		if res, err := h.store.ElectionResults(election, county); err == nil {
+			results = append(results, res)
+		}
+	}
+	writeJSON(w, r, http.StatusOK, measures.Suggest(results, h.store.MeasureLinks(election)))
+}
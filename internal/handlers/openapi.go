@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/openapi"
+)
+
+// OpenAPIHandler serves the API's OpenAPI document and a docs UI over it.
+type OpenAPIHandler struct {
+	spec []byte
+}
+
+// NewOpenAPIHandler returns a handler serving doc, which is fixed at
+// startup.
+func NewOpenAPIHandler(doc *openapi.Document) (*OpenAPIHandler, error) {
+	spec, err := doc.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{spec: spec}, nil
+}
+
+// Spec serves GET /api/v1/openapi.json. The document describes every
+// field, so it is served as is rather than shaped for the caller.
+func (h *OpenAPIHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(h.spec)
+}
+
+// Docs serves GET /api/v1/docs, Swagger UI loaded from a CDN and pointed at
+// the document.
+func (h *OpenAPIHandler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write([]byte(swaggerUI))
+}
+
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>era API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.ui = SwaggerUIBundle({url: "/api/v1/openapi.json", dom_id: "#swagger-ui", deepLinking: true});
+</script>
+</body>
+</html>
+`
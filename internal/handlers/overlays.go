@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// OverlaysHandler manages supplementary datasets attached to contests.
+type OverlaysHandler struct {
+	store store.Store
+}
+
+// NewOverlaysHandler returns a handler storing overlays in st.
+func NewOverlaysHandler(st store.Store) *OverlaysHandler {
+	return &OverlaysHandler{store: st}
+}
+
+// Create serves POST /api/v1/results/{county}/contests/{contest}/overlays.
+func (h *OverlaysHandler) Create(w http.ResponseWriter, r *http.Request) {
+	results, err := h.store.Results(r.PathValue("county"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no results for county")
+		return
+	}
+	contestID := r.PathValue("contest")
+	if _, ok := results.ContestByID(contestID); !ok {
+		writeError(w, http.StatusNotFound, "contest not found")
+		return
+	}
+
+	var o models.Overlay
+	if !decodeBody(w, r, &o) {
+		return
+	}
+	if err := validate.Overlay(o); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if o.Kind == "" {
+		o.Kind = models.OverlayOther
+	}
+
+	id, err := models.NewID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create overlay")
+		return
+	}
+	o.ID = id
+	o.County = results.County
+	o.ContestID = contestID
+	o.Official = false
+	o.CreatedAt = time.Now().UTC()
+
+	h.store.AddOverlay(o)
+	writeJSON(w, r, http.StatusCreated, o)
+}
+
+// List serves GET /api/v1/results/{county}/contests/{contest}/overlays.
+func (h *OverlaysHandler) List(w http.ResponseWriter, r *http.Request) {
+	overlays := h.store.Overlays(r.PathValue("county"))[r.PathValue("contest")]
+	if overlays == nil {
+		overlays = []models.Overlay{}
+	}
+	writeJSON(w, r, http.StatusOK, overlays)
+}
+
+// Delete serves DELETE /api/v1/overlays/{id}.
+func (h *OverlaysHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteOverlay(r.PathValue("id")); errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "overlay not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// ParseErrorsHandler lists sources that failed to parse, serves their
+// files and retries them.
+type ParseErrorsHandler struct {
+	store     store.Store
+	processor *processor.Processor
+	logger    *slog.Logger
+}
+
+// NewParseErrorsHandler returns a handler over the parse errors in st that
+// retries them with p.
+func NewParseErrorsHandler(st store.Store, p *processor.Processor, logger *slog.Logger) *ParseErrorsHandler {
+	return &ParseErrorsHandler{store: st, processor: p, logger: logger}
+}
+
+// List serves GET /api/v1/errors, most recently seen first and optionally
+// filtered by ?county= and ?status=. Source samples are left out; fetch a
+// record to see them.
+func (h *ParseErrorsHandler) List(w http.ResponseWriter, r *http.Request) {
+	county := r.URL.Query().Get("county")
+	status := r.URL.Query().Get("status")
+	out := []models.ParseError{}
+	for _, rec := range h.store.ParseErrors() {
+		if county != "" && store.CountyKey(rec.County) != store.CountyKey(county) {
+			continue
+		}
+		if status != "" && rec.Status != status {
+			continue
+		}
+		rec.Sample = nil
+		out = append(out, rec)
+	}
+	writeJSON(w, r, http.StatusOK, out)
+}
+
+// Get serves GET /api/v1/errors/{id}.
+func (h *ParseErrorsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	rec, err := h.store.ParseError(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "parse error not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, rec)
+}
+
+// Source serves GET /api/v1/errors/{id}/source, the file that failed, as
+// an attachment. Files of resolved records are no longer kept. Delayed
+// access tiers are refused, as the file holds current numbers.
+func (h *ParseErrorsHandler) Source(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	rec, err := h.store.ParseError(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "parse error not found")
+		return
+	}
+	if rec.Source == nil {
+		writeError(w, http.StatusGone, "the source of a resolved parse error is not kept")
+		return
+	}
+	name := store.CountyKey(rec.County) + "-" + rec.ID + parser.Extension(rec.ParseMethod)
+	w.Header().Set("Content-Type", http.DetectContentType(rec.Source))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(rec.Source)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(rec.Source)
+}
+
+// Retry serves POST /api/v1/errors/{id}/retry, parsing the file again with
+// the county's current configuration and publishing it if it now parses.
+func (h *ParseErrorsHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.processor.RetryParseError(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "parse error not found")
+	case errors.Is(err, processor.ErrResolved):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		h.logger.InfoContext(r.Context(), "parse error retry failed", "id", r.PathValue("id"), "error", err)
+		writeError(w, processErrorStatus(err), err.Error())
+	default:
+		writeJSON(w, r, http.StatusOK, resp)
+	}
+}
+
+// Delete serves DELETE /api/v1/errors/{id}, discarding the record and its
+// file.
+func (h *ParseErrorsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	switch err := h.store.DeleteParseError(r.PathValue("id")); {
+	case errors.Is(err, store.ErrLegalHold):
+		writeError(w, http.StatusConflict, "parse error is under a legal hold")
+	case err != nil:
+		writeError(w, http.StatusNotFound, "parse error not found")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
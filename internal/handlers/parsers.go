@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/parser"
+)
+
+// ParsersHandler serves GET /api/v1/parsers, the parse methods this server
+// was built with.
+type ParsersHandler struct{}
+
+// NewParsersHandler returns a handler listing the registered parsers.
+func NewParsersHandler() *ParsersHandler {
+	return &ParsersHandler{}
+}
+
+func (h *ParsersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, parser.Parsers())
+}
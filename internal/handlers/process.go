@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/many221/era_api_v1/internal/archive"
+	"github.com/many221/era_api_v1/internal/jobs"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/urlpolicy"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// ProcessHandler serves POST /api/v1/process.
+type ProcessHandler struct {
+	processor *processor.Processor
+	jobs      *jobs.Manager
+	logger    *slog.Logger
+}
+
+// NewProcessHandler returns a handler that runs requests with p, handing
+// async requests to jm, which is told how to resume them after a restart.
+func NewProcessHandler(p *processor.Processor, jm *jobs.Manager, logger *slog.Logger) *ProcessHandler {
+	h := &ProcessHandler{processor: p, jobs: jm, logger: logger}
+	jm.Resumable("process", func(data json.RawMessage) (jobs.RunFunc, error) {
+		var j processJob
+		if err := json.Unmarshal(data, &j); err != nil {
+			return nil, err
+		}
+		return h.processRun(j.Request, j.Upload), nil
+	})
+	jm.Resumable("reprocess", func(data json.RawMessage) (jobs.RunFunc, error) {
+		var j processJob
+		if err := json.Unmarshal(data, &j); err != nil {
+			return nil, err
+		}
+		return h.reprocessRun(j.Request, j.Snapshot), nil
+	})
+	return h
+}
+
+// processJob is what a process or reprocess job runs, as its spec keeps it
+// across a restart.
+type processJob struct {
+	Request  models.ProcessRequest `json:"request"`
+	Upload   []byte                `json:"upload,omitempty"`
+	Snapshot string                `json:"snapshot,omitempty"`
+}
+
+func (j processJob) spec(kind string) *jobs.Spec {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return nil
+	}
+	return &jobs.Spec{Kind: kind, Data: data}
+}
+
+func (h *ProcessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.ProcessRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if dataset := r.URL.Query().Get("dataset"); dataset != "" {
+		req.Dataset = dataset
+	}
+	if election := r.URL.Query().Get("election"); election != "" {
+		req.Election = election
+	}
+	if err := validate.ProcessRequest(req); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); async {
+		req.Async = true
+	}
+	if debug, _ := strconv.ParseBool(r.URL.Query().Get("debug")); debug {
+		req.Debug = true
+	}
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun")); dryRun {
+		req.DryRun = true
+	}
+
+	if req.Async {
+		h.submit(w, r, req, nil)
+		return
+	}
+
+	resp, err := h.process(r.Context(), req, nil, nil)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "process failed", "county", req.CountyName, "error", err)
+		writeJSON(w, r, processErrorStatus(err), resp)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// process runs req on upload, or on the source it links to if upload is
+// nil, tracing the parser when req.Debug is set. On failure it returns the
+// error response body along with the error, so the trace of a failed parse
+// isn't lost.
+func (h *ProcessHandler) process(ctx context.Context, req models.ProcessRequest, upload []byte, progress processor.ProgressFunc) (*models.ProcessResponse, error) {
+	return h.run(ctx, req, func(ctx context.Context) (*models.ProcessResponse, error) {
+		if upload != nil {
+			return h.processor.ProcessUpload(ctx, req, upload, progress)
+		}
+		return h.processor.Process(ctx, req, progress)
+	})
+}
+
+// run is process for any way of running req.
+func (h *ProcessHandler) run(ctx context.Context, req models.ProcessRequest, fn func(context.Context) (*models.ProcessResponse, error)) (*models.ProcessResponse, error) {
+	var trace *parser.Trace
+	if req.Debug {
+		trace = parser.NewTrace()
+		ctx = parser.WithTrace(ctx, trace)
+	}
+	resp, err := fn(ctx)
+	if err != nil {
+		msg := err.Error()
+		return &models.ProcessResponse{Error: &msg, Trace: trace.Entries()}, err
+	}
+	resp.Trace = trace.Entries()
+	return resp, nil
+}
+
+// submit queues req as a background job and answers 202 Accepted.
+func (h *ProcessHandler) submit(w http.ResponseWriter, r *http.Request, req models.ProcessRequest, upload []byte) {
+	job := processJob{Request: req, Upload: upload}
+	h.enqueue(w, r, req.CountyName, jobKey("process", req, upload), job.spec("process"), h.processRun(req, upload))
+}
+
+// processRun is the run of a job processing req.
+func (h *ProcessHandler) processRun(req models.ProcessRequest, upload []byte) jobs.RunFunc {
+	return func(ctx context.Context, progress func(string, int)) (*models.ProcessResponse, error) {
+		return h.process(ctx, req, upload, progress)
+	}
+}
+
+// enqueue queues run as a background job for county and answers 202
+// Accepted. A job with the same key that hasn't finished is joined
+// instead; a different one for the county runs after it, as the processor
+// publishes to a county one run at a time.
+func (h *ProcessHandler) enqueue(w http.ResponseWriter, r *http.Request, county, key string, spec *jobs.Spec, run jobs.RunFunc) {
+	id, joined, err := h.jobs.Submit(r.Context(), key, spec, run)
+	if errors.Is(err, jobs.ErrDraining) {
+		w.Header().Set("Retry-After", "10")
+		writeError(w, http.StatusServiceUnavailable, "the server is shutting down; retry shortly")
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to submit job", "county", county, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to queue job")
+		return
+	}
+	status := models.JobQueued
+	if joined {
+		if job, err := h.jobs.Get(id); err == nil {
+			status = job.Status
+		}
+		h.logger.InfoContext(r.Context(), "joined running job", "county", county, "job_id", id)
+	}
+
+	statusURL := "/api/v1/jobs/" + id
+	w.Header().Set("Location", statusURL)
+	writeJSON(w, r, http.StatusAccepted, models.JobAccepted{
+		JobID:     id,
+		Status:    status,
+		StatusURL: statusURL,
+		Joined:    joined,
+	})
+}
+
+// jobKey identifies the work of a job of the given kind running req, on
+// the given data: jobs with the same key would do the same thing.
+func jobKey(kind string, req models.ProcessRequest, data ...[]byte) string {
+	h := sha256.New()
+	h.Write([]byte(kind))
+	json.NewEncoder(h).Encode(req)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Reprocess serves POST /api/v1/results/{county}/reprocess, parsing the
+// archived source of the snapshot ?snapshot= names, the newest by default,
+// again and publishing the results, without fetching anything. ?async,
+// ?debug and ?dryRun work as they do for POST /api/v1/process.
+func (h *ProcessHandler) Reprocess(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := models.ProcessRequest{CountyName: r.PathValue("county"), Election: q.Get("election")}
+	req.Async, _ = strconv.ParseBool(q.Get("async"))
+	req.Debug, _ = strconv.ParseBool(q.Get("debug"))
+	req.DryRun, _ = strconv.ParseBool(q.Get("dryRun"))
+	snapshot := q.Get("snapshot")
+
+	run := h.reprocessRun(req, snapshot)
+	if req.Async {
+		job := processJob{Request: req, Snapshot: snapshot}
+		h.enqueue(w, r, req.CountyName, jobKey("reprocess", req, []byte(snapshot)), job.spec("reprocess"), run)
+		return
+	}
+	resp, err := run(r.Context(), nil)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "reprocess failed", "county", req.CountyName, "snapshot", snapshot, "error", err)
+		writeJSON(w, r, processErrorStatus(err), resp)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// reprocessRun is the run of a job reprocessing snapshot for req.
+func (h *ProcessHandler) reprocessRun(req models.ProcessRequest, snapshot string) jobs.RunFunc {
+	return func(ctx context.Context, progress func(string, int)) (*models.ProcessResponse, error) {
+		return h.run(ctx, req, func(ctx context.Context) (*models.ProcessResponse, error) {
+			return h.processor.Reprocess(ctx, req, snapshot, progress)
+		})
+	}
+}
+
+// processErrorStatus maps pipeline errors onto HTTP status codes.
+func processErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, parser.ErrUnsupportedMethod), errors.Is(err, processor.ErrUnknownElection), errors.Is(err, processor.ErrUnknownJurisdiction), errors.Is(err, processor.ErrBrowserDisabled):
+		return http.StatusBadRequest
+	case errors.Is(err, urlpolicy.ErrBlocked):
+		return http.StatusForbidden
+	case errors.Is(err, parser.ErrNoResults), errors.Is(err, parser.ErrUndetected):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, processor.ErrQuarantined), errors.Is(err, store.ErrElectionArchived), errors.Is(err, store.ErrCertified), errors.Is(err, store.ErrSnapshotChanged):
+		return http.StatusConflict
+	case errors.Is(err, archive.ErrNotFound), errors.Is(err, store.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, archive.ErrAmbiguous), errors.Is(err, processor.ErrNoArchive):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
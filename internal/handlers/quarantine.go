@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// QuarantineHandler lists snapshots held back for drift and releases or
+// discards them.
+type QuarantineHandler struct {
+	store     store.Store
+	processor *processor.Processor
+	logger    *slog.Logger
+}
+
+// NewQuarantineHandler returns a handler over the records in st that
+// publishes released snapshots with p.
+func NewQuarantineHandler(st store.Store, p *processor.Processor, logger *slog.Logger) *QuarantineHandler {
+	return &QuarantineHandler{store: st, processor: p, logger: logger}
+}
+
+// List serves GET /api/v1/quarantine, newest first and optionally filtered
+// by ?county= and ?status=. Held results are left out; fetch a record to
+// see them.
+func (h *QuarantineHandler) List(w http.ResponseWriter, r *http.Request) {
+	county := r.URL.Query().Get("county")
+	status := r.URL.Query().Get("status")
+	out := []models.QuarantineRecord{}
+	for _, rec := range h.store.Quarantines() {
+		if county != "" && store.CountyKey(rec.County) != store.CountyKey(county) {
+			continue
+		}
+		if status != "" && rec.Status != status {
+			continue
+		}
+		rec.Results = nil
+		out = append(out, rec)
+	}
+	writeJSON(w, r, http.StatusOK, out)
+}
+
+// Get serves GET /api/v1/quarantine/{id}, with the held snapshot's results.
+// Delayed access tiers are refused, as those results are current.
+func (h *QuarantineHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	rec, err := h.store.Quarantine(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "quarantine record not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, rec)
+}
+
+// Release serves POST /api/v1/quarantine/{id}/release?force=, publishing
+// the held snapshot. One older than the county's published results is
+// refused unless ?force=true.
+func (h *QuarantineHandler) Release(w http.ResponseWriter, r *http.Request) {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	resp, err := h.processor.Release(r.Context(), r.PathValue("id"), force)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "quarantine record not found")
+		return
+	case errors.Is(err, processor.ErrNotHeld), errors.Is(err, processor.ErrSuperseded):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.logger.ErrorContext(r.Context(), "failed to release quarantined snapshot", "id", r.PathValue("id"), "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to publish snapshot")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// Delete serves DELETE /api/v1/quarantine/{id}, discarding the record.
+func (h *QuarantineHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	switch err := h.store.DeleteQuarantine(r.PathValue("id")); {
+	case errors.Is(err, store.ErrLegalHold):
+		writeError(w, http.StatusConflict, "quarantine record is under a legal hold")
+	case err != nil:
+		writeError(w, http.StatusNotFound, "quarantine record not found")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
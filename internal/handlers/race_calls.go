@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// RaceCallsHandler lets editors call contests by hand, logging each call
+// and retraction for the audit trail.
+type RaceCallsHandler struct {
+	store  store.Store
+	logger *slog.Logger
+	onCall func(models.ManualCall)
+}
+
+// NewRaceCallsHandler returns a handler keeping calls in st. onCall, if not
+// nil, is run after a contest is called or its call retracted, so open
+// streams can send the change.
+func NewRaceCallsHandler(st store.Store, logger *slog.Logger, onCall func(models.ManualCall)) *RaceCallsHandler {
+	return &RaceCallsHandler{store: st, logger: logger, onCall: onCall}
+}
+
+// Get serves GET /api/v1/contests/{id}/call?election=.
+func (h *RaceCallsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	call, err := h.store.RaceCall(election, r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "contest has no manual call")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, call)
+}
+
+// Call serves POST /api/v1/contests/{id}/call?election=, calling the
+// contest for {"winner": ...}: one of its candidates, or "Yes" or "No" for
+// a measure. The call overrides what the count says in every county's
+// results and the aggregate until it is retracted, and replaces any earlier
+// manual call. Like other configuration, calls are kept in memory.
+func (h *RaceCallsHandler) Call(w http.ResponseWriter, r *http.Request) {
+	by, ok := operator(w, r, "race calls")
+	if !ok {
+		return
+	}
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	if e, _ := h.store.Election(election); e.Archived() {
+		writeError(w, http.StatusConflict, "election is archived")
+		return
+	}
+	var body struct {
+		Winner string `json:"winner"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Winner) == "" {
+		writeError(w, http.StatusBadRequest, "winner is required")
+		return
+	}
+	contest, ok := h.contest(election, r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "contest not found in election")
+		return
+	}
+	winner, ok := winnerOf(contest, body.Winner)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "winner is not a candidate in the contest")
+		return
+	}
+
+	now := time.Now().UTC()
+	call := h.store.SaveRaceCall(models.ManualCall{
+		Election:  election,
+		ContestID: contest.ID,
+		RaceCall:  models.RaceCall{Winner: winner, Method: models.CallManual, CalledBy: by, CalledAt: &now},
+	})
+	h.logger.InfoContext(r.Context(), "race called", "election", call.Election, "contest", call.ContestID, "winner", call.Winner, "by", by)
+	if h.onCall != nil {
+		h.onCall(call)
+	}
+	writeJSON(w, r, http.StatusOK, call)
+}
+
+// Retract serves DELETE /api/v1/contests/{id}/call?election=, withdrawing
+// the manual call so the count decides again.
+func (h *RaceCallsHandler) Retract(w http.ResponseWriter, r *http.Request) {
+	by, ok := operator(w, r, "race calls")
+	if !ok {
+		return
+	}
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	call, err := h.store.DeleteRaceCall(election, r.PathValue("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "contest has no manual call")
+		return
+	}
+	h.logger.InfoContext(r.Context(), "race call retracted", "election", call.Election, "contest", call.ContestID, "winner", call.Winner, "by", by)
+	if h.onCall != nil {
+		h.onCall(call)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// contest returns the contest with the given ID from the first county in
+// election that reports it.
+func (h *RaceCallsHandler) contest(election, id string) (*models.Contest, bool) {
+	for _, county := range h.store.ElectionCounties(election) {
+		results, err := h.store.ElectionResults(election, county)
+		if err != nil {
+			continue
+		}
+		if c, ok := results.ContestByID(id); ok {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// winnerOf returns the contest's spelling of winner.
+func winnerOf(c *models.Contest, winner string) (string, bool) {
+	if c.Measure != nil {
+		for _, side := range []string{"Yes", "No"} {
+			if strings.EqualFold(strings.TrimSpace(winner), side) {
+				return side, true
+			}
+		}
+		return "", false
+	}
+	key := normalize.Key(winner)
+	for _, cand := range c.Candidates {
+		if !cand.WriteInAggregate && normalize.Key(cand.Name) == key {
+			return cand.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/templates"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// maxRenderBytes bounds a render request's body.
+const maxRenderBytes = 8 << 20
+
+// RenderHandler renders results the caller supplies with the server's
+// templates.
+type RenderHandler struct {
+	processor *processor.Processor
+}
+
+// NewRenderHandler returns a handler rendering with p.
+func NewRenderHandler(p *processor.Processor) *RenderHandler {
+	return &RenderHandler{processor: p}
+}
+
+// Render serves POST /api/v1/render, for frontends showing results they
+// got elsewhere as published ones look. The default output is HTML;
+// ?format=json returns it with the results as rendered.
+func (h *RenderHandler) Render(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "html" && format != "json" {
+		writeError(w, http.StatusBadRequest, "format must be html or json")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRenderBytes)
+	var req models.RenderRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if err := validate.RenderRequest(req); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	results, html, err := h.processor.Render(r.Context(), req)
+	switch {
+	case errors.Is(err, templates.ErrNotFound):
+		writeError(w, http.StatusBadRequest, "unknown template "+req.Template)
+		return
+	case err != nil:
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if format == "json" {
+		writeJSON(w, r, http.StatusOK, models.RenderResponse{HTML: html, Results: results})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(html))
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/region"
+	"github.com/many221/era_api_v1/internal/validate"
+	"github.com/many221/era_api_v1/internal/visibility"
+)
+
+// writeJSON encodes v as the response body with the given status code. This
+// is the single serialization point for JSON responses, so the caller's
+// field visibility policy and response profile are applied here.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(shape(r, v))
+}
+
+// shape applies the caller's visibility policy and profile to v. Handlers
+// that stream several records encode each one through shape themselves.
+func shape(r *http.Request, v any) any {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return visibility.Shape(v, p.Policy, p.Profile)
+	}
+	return v
+}
+
+// setSnapshotVersion replaces the store version region.Middleware stamped
+// on the response as the request arrived with v, the version a response
+// built from several counties was read at.
+func setSnapshotVersion(w http.ResponseWriter, v uint64) {
+	w.Header().Set(region.HeaderVersion, strconv.FormatUint(v, 10))
+}
+
+// writeError sends {"error": msg}, matching the error field of process responses.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// writeProblem sends p as application/problem+json.
+func writeProblem(w http.ResponseWriter, r *http.Request, p models.Problem) {
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	p.Instance = r.URL.Path
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// writeInvalid rejects a request body that failed validation, listing each
+// invalid field.
+func writeInvalid(w http.ResponseWriter, r *http.Request, err error) {
+	p := models.Problem{
+		Type:   models.ProblemInvalidRequest,
+		Title:  "Invalid request",
+		Status: http.StatusBadRequest,
+		Detail: err.Error(),
+	}
+	var ve *validate.Error
+	if errors.As(err, &ve) {
+		p.Detail = fmt.Sprintf("%d invalid fields", len(ve.Fields))
+		if len(ve.Fields) == 1 {
+			p.Detail = "1 invalid field"
+		}
+		p.Errors = ve.Fields
+	}
+	writeProblem(w, r, p)
+}
+
+// decodeBody decodes the JSON request body into v. A body that isn't JSON
+// or has a field of the wrong type is rejected with a problem response and
+// decodeBody returns false.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+	p := models.Problem{
+		Type:   models.ProblemMalformedBody,
+		Title:  "Malformed request body",
+		Status: http.StatusBadRequest,
+		Detail: "request body must be a JSON object",
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		p.Detail = "a field has the wrong type"
+		p.Errors = []models.FieldError{{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type)}}
+	}
+	writeProblem(w, r, p)
+	return false
+}
+
+// jsonType names the JSON type t is decoded from.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// includes reports whether name appears in the comma-separated ?include= list.
+func includes(r *http.Request, name string) bool {
+	for _, v := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(v) == name {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	defaultResultsPage = 100
+	maxResultsPage     = 1000
+)
+
+// pageParams reads ?offset=&limit=, answering 400 and reporting false if
+// either is invalid. Without either, limit is 0: the whole list is wanted.
+func pageParams(w http.ResponseWriter, r *http.Request) (offset, limit int, ok bool) {
+	q := r.URL.Query()
+	if q.Get("offset") == "" && q.Get("limit") == "" {
+		return 0, 0, true
+	}
+	limit = defaultResultsPage
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return 0, 0, false
+		}
+		offset = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxResultsPage {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxResultsPage))
+			return 0, 0, false
+		}
+		limit = n
+	}
+	return offset, limit, true
+}
+
+// paginate returns the page of list pageParams read, and where it falls;
+// with no limit, all of list and a nil page.
+func paginate[T any](list []T, offset, limit int) ([]T, *models.Page) {
+	if limit == 0 {
+		return list, nil
+	}
+	page := &models.Page{Offset: offset, Limit: limit, Total: len(list)}
+	end := min(offset+limit, len(list))
+	if offset >= end {
+		return []T{}, page
+	}
+	if end < len(list) {
+		page.NextOffset = end
+	}
+	return list[offset:end], page
+}
+
+// selectFields returns v with only the fields the comma-separated
+// ?fields= list names, and its page, or v whole if it names none.
+func selectFields(r *http.Request, v any) any {
+	var fields []string
+	for _, f := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return v
+	}
+	return visibility.Select(v, append(fields, "page"))
+}
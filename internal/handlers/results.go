@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/clock"
+	"github.com/many221/era_api_v1/internal/export"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/outcome"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// ResultsHandler serves the stored results of processed counties.
+type ResultsHandler struct {
+	store store.Store
+	clock clock.Clock
+}
+
+// NewResultsHandler returns a handler reading results from st, lifting
+// embargoes by clk.
+func NewResultsHandler(st store.Store, clk clock.Clock) *ResultsHandler {
+	return &ResultsHandler{store: st, clock: clk}
+}
+
+// Get serves GET /api/v1/results/{county}?election=&offset=&limit=&fields=,
+// the county's results in the election or the default one, as of the
+// caller's access tier. Supplementary overlays are only included with
+// ?include=overlays. ?offset= and ?limit= page through the contests, and
+// ?fields= keeps only the fields it names.
+func (h *ResultsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	offset, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	results, err := callerResults(h.store, h.clock, w, r, election, r.PathValue("county"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no results for county")
+		return
+	}
+
+	attachForecasts(h.store, results)
+	attachOutcomes(h.store, results)
+	attachJurisdictions(h.store, results)
+	w.Header().Set("X-Snapshot-Hash", results.Hash)
+
+	if includes(r, "overlays") {
+		overlays := h.store.Overlays(results.County)
+		for i := range results.Contests {
+			results.Contests[i].Overlays = overlays[results.Contests[i].ID]
+		}
+	}
+	results.Contests, results.Page = paginate(results.Contests, offset, limit)
+
+	writeJSON(w, r, http.StatusOK, selectFields(r, results))
+}
+
+// Export serves GET /api/v1/results/{county}/export?format=&election= as a
+// download:
+// csv or xlsx spreadsheets with one row per candidate, leaving out columns
+// the caller may not see, or the open-data layouts openelections (county
+// level OpenElections CSV) and cdf (NIST SP 1500-100 election results JSON).
+func (h *ResultsHandler) Export(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	results, err := callerResults(h.store, h.clock, w, r, election, r.PathValue("county"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no results for county")
+		return
+	}
+
+	var hidden func(string) bool
+	if p, ok := auth.FromContext(r.Context()); ok {
+		hidden = p.Policy.Hides
+	}
+
+	format, ok := export.Lookup(r.URL.Query().Get("format"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "format must be "+export.FormatNames())
+		return
+	}
+	var buf bytes.Buffer
+	if err := format.Write(&buf, results, election, hidden); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to export results")
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-results.%s"`, store.CountyKey(results.County), format.Ext))
+	w.Write(buf.Bytes())
+}
+
+// attachOutcomes derives each contest's outcome under the store's rules,
+// with the manual calls of its election.
+func attachOutcomes(st store.Store, results *models.Results) {
+	outcome.Apply(results, st.OutcomeRules(), st.RaceCalls(results.Election))
+}
+
+// attachForecasts sets each contest's latest stored forecast, if any.
+func attachForecasts(st store.Store, results *models.Results) {
+	forecasts := st.Forecasts(results.County)
+	for i := range results.Contests {
+		if f, ok := forecasts[results.Contests[i].ID]; ok {
+			results.Contests[i].Forecast = &f
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/search"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchHandler serves GET /api/v1/search.
+type SearchHandler struct {
+	store store.Store
+}
+
+// NewSearchHandler returns a handler searching the results in st.
+func NewSearchHandler(st store.Store) *SearchHandler {
+	return &SearchHandler{store: st}
+}
+
+// ServeHTTP serves GET /api/v1/search?q=&election=&limit=, the contests,
+// candidates and measures whose title or name approximately matches q in
+// the election's results, best first, with links to those results.
+func (h *SearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "q query parameter is required")
+		return
+	}
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+
+	res := search.Search(h.store, election, q, limit)
+	setSnapshotVersion(w, res.Version)
+	writeJSON(w, r, http.StatusOK, res)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/sla"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// defaultSLAReportPeriod is how far back SLA reports look without ?since=.
+const defaultSLAReportPeriod = 24 * time.Hour
+
+// SLAHandler manages freshness SLAs and reports on how they were kept.
+type SLAHandler struct {
+	store   store.Store
+	monitor *sla.Monitor
+}
+
+// NewSLAHandler returns a handler storing SLAs in st and reporting on them
+// from the breaches mon records.
+func NewSLAHandler(st store.Store, mon *sla.Monitor) *SLAHandler {
+	return &SLAHandler{store: st, monitor: mon}
+}
+
+// List serves GET /api/v1/slas, optionally only those made to ?tenant=.
+func (h *SLAHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenant := r.URL.Query().Get("tenant")
+	out := []models.SLA{}
+	for _, s := range h.store.SLAs() {
+		if tenant == "" || s.Tenant == tenant {
+			out = append(out, s)
+		}
+	}
+	writeJSON(w, r, http.StatusOK, out)
+}
+
+// Create serves POST /api/v1/slas.
+func (h *SLAHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var s models.SLA
+	if !decodeBody(w, r, &s) {
+		return
+	}
+	if err := validate.SLA(s); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	id, err := models.NewID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create SLA")
+		return
+	}
+	s.ID = id
+	s.CreatedAt = time.Now().UTC()
+	for i, county := range s.Counties {
+		if reg, err := h.store.County(county); err == nil {
+			s.Counties[i] = reg.Name
+		}
+	}
+	h.store.SaveSLA(s)
+
+	w.Header().Set("Location", "/api/v1/slas/"+id)
+	writeJSON(w, r, http.StatusCreated, s)
+}
+
+// Get serves GET /api/v1/slas/{id}.
+func (h *SLAHandler) Get(w http.ResponseWriter, r *http.Request) {
+	s, err := h.store.SLA(r.PathValue("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "SLA not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, s)
+}
+
+// Delete serves DELETE /api/v1/slas/{id}. Its breach history is kept.
+func (h *SLAHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteSLA(r.PathValue("id")); errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "SLA not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Breaches serves GET /api/v1/slas/breaches, newest first, filtered by
+// ?sla=, ?tenant= and ?county=.
+func (h *SLAHandler) Breaches(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tenant, county := q.Get("tenant"), q.Get("county")
+	out := []models.SLABreach{}
+	for _, b := range h.store.SLABreaches(q.Get("sla")) {
+		if tenant != "" && b.Tenant != tenant {
+			continue
+		}
+		if county != "" && store.CountyKey(b.County) != store.CountyKey(county) {
+			continue
+		}
+		out = append(out, b)
+	}
+	writeJSON(w, r, http.StatusOK, out)
+}
+
+// Report serves GET /api/v1/slas/report: compliance with each SLA, or only
+// ?sla= or those made to ?tenant=, from ?since= (RFC 3339, a day ago by
+// default) until now.
+func (h *SLAHandler) Report(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	now := time.Now().UTC()
+	since := now.Add(-defaultSLAReportPeriod)
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil || !t.Before(now) {
+			writeError(w, http.StatusBadRequest, "since must be a past RFC 3339 time")
+			return
+		}
+		since = t.UTC()
+	}
+
+	var slas []models.SLA
+	if id := q.Get("sla"); id != "" {
+		s, err := h.store.SLA(id)
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "SLA not found")
+			return
+		}
+		slas = append(slas, s)
+	} else {
+		slas = h.store.SLAs()
+	}
+
+	tenant := q.Get("tenant")
+	out := []models.SLAReport{}
+	for _, s := range slas {
+		if tenant == "" || s.Tenant == tenant {
+			out = append(out, h.monitor.Report(s, since, now))
+		}
+	}
+	writeJSON(w, r, http.StatusOK, out)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// SnapshotLogHandler serves each county's Merkle-chained log of published
+// snapshots, so auditors can verify published history wasn't rewritten.
+type SnapshotLogHandler struct {
+	store store.Store
+}
+
+// NewSnapshotLogHandler returns a handler reading logs from st.
+func NewSnapshotLogHandler(st store.Store) *SnapshotLogHandler {
+	return &SnapshotLogHandler{store: st}
+}
+
+// Log serves GET /api/v1/results/{county}/log?election=, every entry and
+// the current tree head.
+func (h *SnapshotLogHandler) Log(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	l, err := h.store.ElectionSnapshotLog(election, r.PathValue("county"))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no snapshot log for county")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, l)
+}
+
+// Proof serves GET /api/v1/results/{county}/log/proof, the inclusion proof
+// of the entry given by ?index= or, for the latest entry with a snapshot
+// hash, ?hash=. ?size= proves against an earlier tree head, ?election=
+// picks the election's log.
+func (h *SnapshotLogHandler) Proof(w http.ResponseWriter, r *http.Request) {
+	election, ok := electionParam(h.store, w, r)
+	if !ok {
+		return
+	}
+	county := r.PathValue("county")
+	q := r.URL.Query()
+
+	size := 0
+	if v := q.Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "size must be a positive integer")
+			return
+		}
+		size = n
+	}
+
+	index := -1
+	switch {
+	case q.Get("index") != "":
+		n, err := strconv.Atoi(q.Get("index"))
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "index must be a non-negative integer")
+			return
+		}
+		index = n
+	case q.Get("hash") != "":
+		l, err := h.store.ElectionSnapshotLog(election, county)
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "no snapshot log for county")
+			return
+		}
+		for _, e := range l.Entries {
+			if e.SnapshotHash == q.Get("hash") && (size == 0 || e.Index < size) {
+				index = e.Index
+			}
+		}
+		if index < 0 {
+			writeError(w, http.StatusNotFound, "snapshot hash not in log")
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "index or hash is required")
+		return
+	}
+
+	p, err := h.store.ElectionInclusionProof(election, county, index, size)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "entry not in log")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, p)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/auth"
+	"github.com/many221/era_api_v1/internal/clock"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/snippets"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// SnippetsHandler manages live-blog snippets and serves their output.
+type SnippetsHandler struct {
+	store    store.Store
+	snippets *snippets.Service
+	clock    clock.Clock
+}
+
+// NewSnippetsHandler returns a handler backed by st and svc, lifting
+// embargoes by clk.
+func NewSnippetsHandler(st store.Store, svc *snippets.Service, clk clock.Clock) *SnippetsHandler {
+	return &SnippetsHandler{store: st, snippets: svc, clock: clk}
+}
+
+// Create serves POST /api/v1/snippets.
+func (h *SnippetsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var sn models.Snippet
+	if !decodeBody(w, r, &sn) {
+		return
+	}
+	if err := validate.Snippet(sn); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	for i, ref := range sn.Contests {
+		election, err := h.store.Election(ref.Election)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "unknown election")
+			return
+		}
+		sn.Contests[i].Election = election.ID
+	}
+
+	id, err := models.NewID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create snippet")
+		return
+	}
+	sn.ID = id
+	sn.CreatedAt = time.Now().UTC()
+	h.store.SaveSnippet(sn)
+
+	w.Header().Set("Location", "/api/v1/snippets/"+id)
+	writeJSON(w, r, http.StatusCreated, sn)
+}
+
+// List serves GET /api/v1/snippets.
+func (h *SnippetsHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.store.Snippets())
+}
+
+// Get serves GET /api/v1/snippets/{id}. The default output is AMP-valid
+// HTML; ?format=shortcode returns the shortcode JSON instead. A caller in a
+// delayed access tier gets the snippet rendered from the results it may
+// see rather than the live output.
+func (h *SnippetsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var rendered *models.RenderedSnippet
+	var err error
+	if p, ok := auth.FromContext(r.Context()); ok && p.Tier.Delayed() {
+		rendered, err = h.snippets.Render(id, func(election, county string) (*models.Results, error) {
+			return callerResults(h.store, h.clock, w, r, election, county)
+		})
+	} else {
+		rendered, err = h.snippets.Rendered(id)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "snippet not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to render snippet")
+		return
+	}
+
+	w.Header().Set("Last-Modified", rendered.UpdatedAt.Format(http.TimeFormat))
+	switch r.URL.Query().Get("format") {
+	case "", "amp", "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(rendered.HTML))
+	case "shortcode", "json":
+		writeJSON(w, r, http.StatusOK, rendered)
+	default:
+		writeError(w, http.StatusBadRequest, "format must be amp or shortcode")
+	}
+}
+
+// Delete serves DELETE /api/v1/snippets/{id}.
+func (h *SnippetsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.store.DeleteSnippet(id); errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "snippet not found")
+		return
+	}
+	h.snippets.Forget(id)
+	w.WriteHeader(http.StatusNoContent)
+}
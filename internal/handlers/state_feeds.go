@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// StateFeedsHandler publishes secretaries of state's aggregate feeds.
+type StateFeedsHandler struct {
+	processor *processor.Processor
+	logger    *slog.Logger
+}
+
+// NewStateFeedsHandler returns a handler that runs feeds with p.
+func NewStateFeedsHandler(p *processor.Processor, logger *slog.Logger) *StateFeedsHandler {
+	return &StateFeedsHandler{processor: p, logger: logger}
+}
+
+// Process serves POST /api/v1/state-feeds/process?election=, publishing
+// each county a state feed reports, and answers with what became of each.
+// A county whose own source takes precedence keeps its results.
+func (h *StateFeedsHandler) Process(w http.ResponseWriter, r *http.Request) {
+	var req models.StateFeedRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if election := r.URL.Query().Get("election"); election != "" {
+		req.Election = election
+	}
+	if err := validate.StateFeedRequest(req); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	resp, err := h.processor.ProcessState(r.Context(), req)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "state feed failed", "state", req.State, "source", req.FileLink, "error", err)
+		writeError(w, processErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/formatter"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/templates"
+)
+
+// maxTemplateBytes bounds a template previewed.
+const maxTemplateBytes = 1 << 20
+
+// TemplatesHandler manages the named HTML templates results are rendered
+// with.
+type TemplatesHandler struct {
+	registry *templates.Registry
+	store    store.Store
+}
+
+// NewTemplatesHandler returns a handler managing reg, previewing templates
+// with the results in st.
+func NewTemplatesHandler(reg *templates.Registry, st store.Store) *TemplatesHandler {
+	return &TemplatesHandler{registry: reg, store: st}
+}
+
+// List serves GET /api/v1/templates.
+func (h *TemplatesHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.registry.List())
+}
+
+// Get serves GET /api/v1/templates/{name}, including the template source.
+func (h *TemplatesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	t, err := h.registry.Get(r.PathValue("name"))
+	if errors.Is(err, templates.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "template not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, t)
+}
+
+// Put serves PUT /api/v1/templates/{name} with a body of {"source": "..."},
+// creating or replacing the template. It takes effect immediately.
+func (h *TemplatesHandler) Put(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Source string `json:"source"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name := r.PathValue("name")
+	if !templates.ValidName(name) {
+		writeError(w, http.StatusBadRequest, "template names are lowercase letters, digits, - and _")
+		return
+	}
+
+	created, err := h.registry.Save(name, body.Source)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	t, _ := h.registry.Get(name)
+	writeJSON(w, r, status, t)
+}
+
+// Preview serves POST /admin/templates/preview with a body of
+// {"source": "...", "county": "..."}, rendering the template without saving
+// it and reporting syntax errors, fields results don't have and output that
+// can't be escaped. It renders with the county's published results, or
+// sample ones when county is empty.
+func (h *TemplatesHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Source string `json:"source"`
+		County string `json:"county"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxTemplateBytes)
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	results := templates.Sample()
+	if body.County != "" {
+		var err error
+		if results, err = h.store.Results(body.County); err != nil {
+			writeError(w, http.StatusNotFound, "county not found")
+			return
+		}
+	}
+	fragment, err := formatter.HTML(results)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to render results")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, templates.Preview(body.Source, results, fragment))
+}
+
+// Delete serves DELETE /api/v1/templates/{name}. Assigned templates must be
+// unassigned first.
+func (h *TemplatesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	switch err := h.registry.Delete(r.PathValue("name")); {
+	case errors.Is(err, templates.ErrNotFound):
+		writeError(w, http.StatusNotFound, "template not found")
+	case errors.Is(err, templates.ErrInUse):
+		writeError(w, http.StatusConflict, "template is assigned to a county or content type")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "failed to delete template")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Assignments serves GET /api/v1/templates/assignments.
+func (h *TemplatesHandler) Assignments(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.registry.Assignments())
+}
+
+// SetAssignments serves PUT /api/v1/templates/assignments, replacing which
+// template each county and content type uses.
+func (h *TemplatesHandler) SetAssignments(w http.ResponseWriter, r *http.Request) {
+	var a templates.Assignments
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := h.registry.SetAssignments(a); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, templates.ErrNotFound) {
+			status = http.StatusBadRequest
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, r, http.StatusOK, h.registry.Assignments())
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/aggregate"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// TurnoutHandler serves GET /api/v1/turnout, statewide turnout with a
+// per-county breakdown and its history over the night.
+type TurnoutHandler struct {
+	store store.Store
+}
+
+// NewTurnoutHandler returns a handler aggregating turnout from st.
+func NewTurnoutHandler(st store.Store) *TurnoutHandler {
+	return &TurnoutHandler{store: st}
+}
+
+func (h *TurnoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !realTime(w, r) {
+		return
+	}
+	t := aggregate.Turnout(h.store)
+	setSnapshotVersion(w, t.Version)
+	writeJSON(w, r, http.StatusOK, t)
+}
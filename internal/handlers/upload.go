@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+const (
+	// maxUploadBytes bounds an uploaded source, as the fetcher bounds
+	// downloaded ones.
+	maxUploadBytes = 256 << 20
+	// uploadMemory is how much of a form is held in memory; the rest of
+	// the file is spooled to disk while it is read.
+	uploadMemory = 32 << 20
+)
+
+// Upload serves POST /api/v1/upload, processing a source posted as the file
+// part of a multipart form, for counties that email their results rather
+// than publish them. The form's other fields are those of a process
+// request: countyName or jurisdiction, parseMethod, contentType,
+// measureThreshold, election, dataset, sourceLevel, async and debug. parseMethod may be
+// left out when the file's extension names one.
+func (h *ProcessHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+uploadMemory)
+	if err := r.ParseMultipartForm(uploadMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "upload is too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "request body must be a multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	var (
+		data []byte
+		name string
+	)
+	if f, fh, err := r.FormFile("file"); err == nil {
+		name = path.Base(fh.Filename)
+		data, err = io.ReadAll(io.LimitReader(f, maxUploadBytes+1))
+		f.Close()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "failed to read the uploaded file")
+			return
+		}
+		if len(data) > maxUploadBytes {
+			writeError(w, http.StatusRequestEntityTooLarge, "upload is too large")
+			return
+		}
+	}
+
+	req := models.ProcessRequest{
+		CountyName:       strings.TrimSpace(r.FormValue("countyName")),
+		Jurisdiction:     r.FormValue("jurisdiction"),
+		ContentType:      r.FormValue("contentType"),
+		ParseMethod:      strings.ToLower(r.FormValue("parseMethod")),
+		Election:         r.FormValue("election"),
+		MeasureThreshold: r.FormValue("measureThreshold"),
+		Dataset:          r.FormValue("dataset"),
+		SourceLevel:      r.FormValue("sourceLevel"),
+		// Results name the file they came from where a link would be.
+		FileLink: "upload:" + name,
+	}
+	if req.ParseMethod == "" {
+		req.ParseMethod = parser.MethodOf(name)
+	}
+	if req.ParseMethod == "" {
+		req.ParseMethod = models.ParseMethodAuto
+	}
+	req.Async, _ = strconv.ParseBool(r.FormValue("async"))
+	req.Debug, _ = strconv.ParseBool(r.FormValue("debug"))
+	req.DryRun, _ = strconv.ParseBool(r.FormValue("dryRun"))
+	if err := validate.Upload(req, int64(len(data))); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "source uploaded", "county", req.CountyName, "file", name, "bytes", len(data), "parse_method", req.ParseMethod)
+
+	if req.Async {
+		h.submit(w, r, req, data)
+		return
+	}
+	resp, err := h.process(r.Context(), req, data, nil)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "process failed", "county", req.CountyName, "file", name, "error", err)
+		writeJSON(w, r, processErrorStatus(err), resp)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
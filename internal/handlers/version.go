@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// VersionHandler serves GET /version.
+type VersionHandler struct {
+	info models.VersionInfo
+}
+
+// NewVersionHandler returns a handler serving info, which is fixed at
+// startup.
+func NewVersionHandler(info models.VersionInfo) *VersionHandler {
+	return &VersionHandler{info: info}
+}
+
+func (h *VersionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.info)
+}
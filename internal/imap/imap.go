@@ -0,0 +1,287 @@
+// Package imap is a minimal IMAP4rev1 client (RFC 3501): enough to log in,
+// select a mailbox, find unseen messages, download them and flag them as
+// seen.
+package imap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTooLarge is returned by Fetch for a message over its limit.
+var ErrTooLarge = errors.New("message exceeds size limit")
+
+// maxLine bounds a response line, literals aside.
+const maxLine = 1 << 20
+
+// Client is a connection to an IMAP server. It isn't safe for concurrent
+// use.
+type Client struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	tag     int
+	ctx     context.Context
+	timeout time.Duration
+	stop    func() bool
+}
+
+// response is an untagged response line, with the literals it carried
+// replaced by {} and collected in order.
+type response struct {
+	line     string
+	literals [][]byte
+}
+
+// Dial connects to addr, over TLS unless tlsConfig is nil, and reads the
+// server's greeting. Each command must be answered within timeout, and the
+// connection is closed once ctx is done.
+func Dial(ctx context.Context, addr string, tlsConfig *tls.Config, timeout time.Duration) (*Client, error) {
+	d := &net.Dialer{Timeout: timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if tlsConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: d, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, r: bufio.NewReader(conn), ctx: ctx, timeout: timeout}
+	c.stop = context.AfterFunc(ctx, func() { conn.Close() })
+	c.deadline()
+	greeting, err := c.readLine()
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		c.Close()
+		return nil, fmt.Errorf("imap greeting: %s", greeting)
+	}
+	return c, nil
+}
+
+// deadline bounds the next exchange by the timeout, or by ctx's deadline
+// if that is sooner.
+func (c *Client) deadline() {
+	d := time.Now().Add(c.timeout)
+	if cd, ok := c.ctx.Deadline(); ok && cd.Before(d) {
+		d = cd
+	}
+	c.conn.SetDeadline(d)
+}
+
+// Close closes the connection without logging out.
+func (c *Client) Close() error {
+	c.stop()
+	return c.conn.Close()
+}
+
+// Logout ends the session and closes the connection.
+func (c *Client) Logout() error {
+	_, err := c.cmd(0, "LOGOUT")
+	c.Close()
+	return err
+}
+
+// Login authenticates with a username and password.
+func (c *Client) Login(username, password string) error {
+	u, err := quote(username)
+	if err != nil {
+		return err
+	}
+	p, err := quote(password)
+	if err != nil {
+		return err
+	}
+	_, err = c.cmd(0, "LOGIN %s %s", u, p)
+	return err
+}
+
+// Select opens mailbox read-write and returns its UIDVALIDITY, which
+// changes when the server renumbers its messages' UIDs.
+func (c *Client) Select(mailbox string) (uint32, error) {
+	m, err := quote(mailbox)
+	if err != nil {
+		return 0, err
+	}
+	untagged, err := c.cmd(0, "SELECT %s", m)
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range untagged {
+		if _, rest, ok := strings.Cut(r.line, "[UIDVALIDITY "); ok {
+			n, _, _ := strings.Cut(rest, "]")
+			v, err := strconv.ParseUint(n, 10, 32)
+			if err == nil {
+				return uint32(v), nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// SearchUnseen returns the UIDs of the selected mailbox's messages not yet
+// flagged as seen.
+func (c *Client) SearchUnseen() ([]uint32, error) {
+	untagged, err := c.cmd(0, "UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, r := range untagged {
+		rest, ok := strings.CutPrefix(r.line, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, f := range strings.Fields(rest) {
+			if v, err := strconv.ParseUint(f, 10, 32); err == nil {
+				uids = append(uids, uint32(v))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// Fetch returns the raw message with uid, headers and body, without
+// flagging it as seen. Messages over limit bytes fail with ErrTooLarge,
+// after which the connection can't be used.
+func (c *Client) Fetch(uid uint32, limit int64) ([]byte, error) {
+	untagged, err := c.cmd(limit, "UID FETCH %d (BODY.PEEK[])", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range untagged {
+		if strings.Contains(r.line, " FETCH ") && len(r.literals) > 0 {
+			return r.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap FETCH: no message with UID %d", uid)
+}
+
+// MarkSeen flags the message with uid as seen.
+func (c *Client) MarkSeen(uid uint32) error {
+	_, err := c.cmd(0, `UID STORE %d +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+// cmd sends a command and reads its responses up to the tagged one, which
+// must be OK. limit bounds each literal; 0 means maxLine.
+func (c *Client) cmd(limit int64, format string, args ...any) ([]response, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	command := fmt.Sprintf(format, args...)
+	name := strings.Fields(command)[0]
+	if name == "UID" {
+		name += " " + strings.Fields(command)[1]
+	}
+	c.deadline()
+	if _, err := io.WriteString(c.conn, tag+" "+command+"\r\n"); err != nil {
+		return nil, fmt.Errorf("imap %s: %w", name, err)
+	}
+	if limit <= 0 {
+		limit = maxLine
+	}
+
+	var untagged []response
+	for {
+		r, err := c.readResponse(limit)
+		if err != nil {
+			return nil, fmt.Errorf("imap %s: %w", name, err)
+		}
+		rest, ok := strings.CutPrefix(r.line, tag+" ")
+		if !ok {
+			untagged = append(untagged, r)
+			continue
+		}
+		if status, _, _ := strings.Cut(rest, " "); strings.EqualFold(status, "OK") {
+			return untagged, nil
+		}
+		return nil, fmt.Errorf("imap %s: %s", name, rest)
+	}
+}
+
+// readResponse reads one response line and the literals it announces with
+// a trailing {n}.
+func (c *Client) readResponse(limit int64) (response, error) {
+	var r response
+	var line strings.Builder
+	for {
+		l, err := c.readLine()
+		if err != nil {
+			return r, err
+		}
+		n, ok := literalSize(l)
+		if !ok {
+			line.WriteString(l)
+			r.line = line.String()
+			return r, nil
+		}
+		if n > limit {
+			return r, ErrTooLarge
+		}
+		line.WriteString(l[:strings.LastIndexByte(l, '{')] + "{}")
+		lit := make([]byte, n)
+		if _, err := io.ReadFull(c.r, lit); err != nil {
+			return r, err
+		}
+		r.literals = append(r.literals, lit)
+		if line.Len() > maxLine {
+			return r, errors.New("response line too long")
+		}
+	}
+}
+
+func (c *Client) readLine() (string, error) {
+	var b []byte
+	for {
+		chunk, isPrefix, err := c.r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		b = append(b, chunk...)
+		if len(b) > maxLine {
+			return "", errors.New("response line too long")
+		}
+		if !isPrefix {
+			return string(b), nil
+		}
+	}
+}
+
+// literalSize returns n for a line ending in {n}.
+func literalSize(line string) (int64, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(line[open+1:len(line)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// quote returns s as an IMAP quoted string.
+func quote(s string) (string, error) {
+	if strings.ContainsAny(s, "\r\n\x00") {
+		return "", errors.New("imap: argument must not contain line breaks")
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`, nil
+}
@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/requestid"
+)
+
+// haltGrace is how long Drain waits for jobs to stop once it has cancelled
+// them.
+const haltGrace = 2 * time.Second
+
+// Spec describes the work of a job in a form that outlives the process:
+// the kind of job, naming the ResumeFunc that runs it again, and whatever
+// that needs, as JSON.
+type Spec struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ResumeFunc returns the run of a job from its spec's data.
+type ResumeFunc func(data json.RawMessage) (RunFunc, error)
+
+// pending is an unfinished job with a spec.
+type pending struct {
+	Key  string `json:"key,omitempty"`
+	Spec Spec   `json:"spec"`
+}
+
+// saved is a job as Drain writes it out.
+type saved struct {
+	Job models.Job `json:"job"`
+	pending
+}
+
+// Resumable lets Restore run jobs of the given kind again with fn.
+func (m *Manager) Resumable(kind string, fn ResumeFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resumers[kind] = fn
+}
+
+// Drain stops taking jobs and waits for those running to finish or ctx to
+// expire, cancelling them then. Jobs that never started, or were cut short,
+// are written to path, if it isn't empty, for Restore to run on the next
+// start; it returns how many. Jobs without a spec are lost.
+func (m *Manager) Drain(ctx context.Context, path string) (int, error) {
+	m.mu.Lock()
+	if !m.draining {
+		m.draining = true
+		close(m.stop)
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		m.kill()
+		select {
+		case <-done:
+		case <-time.After(haltGrace):
+		}
+	}
+
+	m.mu.RLock()
+	var out []saved
+	lost := 0
+	for id, job := range m.jobs {
+		if job.FinishedAt != nil {
+			continue
+		}
+		p, ok := m.pending[id]
+		if !ok {
+			lost++
+			continue
+		}
+		j := *job
+		j.Status = models.JobQueued
+		j.Progress = models.JobProgress{Stage: "queued"}
+		j.StartedAt = nil
+		out = append(out, saved{Job: j, pending: p})
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Job.CreatedAt.Before(out[j].Job.CreatedAt) })
+
+	if lost > 0 {
+		m.logger.Warn("unfinished jobs dropped", "jobs", lost)
+	}
+	if path == "" {
+		if len(out) > 0 {
+			m.logger.Warn("unfinished jobs dropped, set JOBS_FILE to keep them", "jobs", len(out))
+		}
+		return 0, err
+	}
+	if len(out) == 0 {
+		return 0, err
+	}
+	data, merr := json.Marshal(out)
+	if merr != nil {
+		return 0, fmt.Errorf("save jobs: %w", merr)
+	}
+	tmp := path + ".tmp"
+	if werr := os.WriteFile(tmp, data, 0600); werr != nil {
+		return 0, fmt.Errorf("save jobs: %w", werr)
+	}
+	if werr := os.Rename(tmp, path); werr != nil {
+		return 0, fmt.Errorf("save jobs: %w", werr)
+	}
+	return len(out), err
+}
+
+// Restore runs the jobs Drain saved to path again, under their IDs, so
+// clients polling them across a restart find them, and removes the file.
+// Jobs of a kind with no Resumable are dropped. It returns how many were
+// resumed; a missing file resumes none.
+func (m *Manager) Restore(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("restore jobs: %w", err)
+	}
+	var jobs []saved
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return 0, fmt.Errorf("restore jobs: %s: %w", path, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	now := time.Now().UTC()
+	for _, s := range jobs {
+		resume, ok := m.resumers[s.Spec.Kind]
+		if !ok {
+			m.logger.Warn("saved job dropped, its kind can't be resumed", "job_id", s.Job.ID, "kind", s.Spec.Kind)
+			continue
+		}
+		run, err := resume(s.Spec.Data)
+		if err != nil {
+			m.logger.Warn("saved job dropped", "job_id", s.Job.ID, "kind", s.Spec.Kind, "error", err)
+			continue
+		}
+		ctx := requestid.With(context.Background(), s.Job.RequestID)
+		s.Job.ResumedAt = &now
+		spec := s.Spec
+		m.startLocked(ctx, s.Job, s.Key, &spec, run)
+		n++
+	}
+	if err := os.Remove(path); err != nil {
+		return n, fmt.Errorf("restore jobs: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/requestid"
+)
+
+func newTestManager() *Manager {
+	return NewManager(Config{MaxConcurrent: 1, Timeout: time.Minute, Retention: time.Hour}, slog.New(slog.DiscardHandler))
+}
+
+type countData struct {
+	N int `json:"n"`
+}
+
+func countSpec(data string) *Spec {
+	return &Spec{Kind: "count", Data: json.RawMessage(data)}
+}
+
+func TestDrainRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+
+	// A job running when draining starts, cut short, and queued ones
+	// behind it.
+	m := newTestManager()
+	started := make(chan struct{})
+	block := func(ctx context.Context, _ func(string, int)) (*models.ProcessResponse, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	idle := func(context.Context, func(string, int)) (*models.ProcessResponse, error) { return nil, nil }
+	ctx := requestid.With(context.Background(), "req-1")
+	running, _, err := m.Submit(ctx, "k1", countSpec(`{"n":1}`), block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	queued, _, err := m.Submit(context.Background(), "k2", countSpec(`{"n":2}`), idle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	badData, _, _ := m.Submit(context.Background(), "", countSpec(`"two"`), idle)
+	otherKind, _, _ := m.Submit(context.Background(), "", &Spec{Kind: "other", Data: json.RawMessage(`{}`)}, idle)
+	if _, _, err := m.Submit(context.Background(), "", nil, idle); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	saved, err := m.Drain(expired, path)
+	if err != context.Canceled {
+		t.Errorf("Drain error = %v, want context.Canceled", err)
+	}
+	if saved != 4 {
+		t.Errorf("Drain saved %d jobs, want 4", saved)
+	}
+	if _, _, err := m.Submit(context.Background(), "", nil, idle); err != ErrDraining {
+		t.Errorf("Submit while draining = %v, want ErrDraining", err)
+	}
+
+	// The next process resumes those of a kind it knows.
+	r := newTestManager()
+	var mu sync.Mutex
+	var ran []int
+	release := make(chan struct{})
+	r.Resumable("count", func(data json.RawMessage) (RunFunc, error) {
+		var d countData
+		if err := json.Unmarshal(data, &d); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, _ func(string, int)) (*models.ProcessResponse, error) {
+			<-release
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, d.N)
+			if requestid.From(ctx) == "" && d.N == 1 {
+				return nil, fmt.Errorf("job %d lost its request ID", d.N)
+			}
+			return nil, nil
+		}, nil
+	})
+	resumed, err := r.Restore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed != 2 {
+		t.Errorf("Restore resumed %d jobs, want 2", resumed)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Restore left %s: %v", path, err)
+	}
+	// Resubmitting a resumed job's work joins it.
+	if id, joined, err := r.Submit(context.Background(), "k1", countSpec(`{"n":1}`), idle); err != nil || !joined || id != running {
+		t.Errorf("Submit k1 = %q, %v, %v; want %q joined", id, joined, err, running)
+	}
+	close(release)
+	r.wg.Wait()
+
+	for _, id := range []string{running, queued} {
+		job, err := r.Get(id)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", id, err)
+		}
+		if job.Status != models.JobSucceeded || job.ResumedAt == nil {
+			t.Errorf("job %s: status %s, resumed at %v; want succeeded and resumed: %s", id, job.Status, job.ResumedAt, job.Error)
+		}
+	}
+	if job, _ := r.Get(running); job.RequestID != "req-1" {
+		t.Errorf("request ID = %q, want req-1", job.RequestID)
+	}
+	for _, id := range []string{badData, otherKind} {
+		if _, err := r.Get(id); err != ErrNotFound {
+			t.Errorf("Get(%s) = %v, want ErrNotFound for a dropped job", id, err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	slices.Sort(ran)
+	if !slices.Equal(ran, []int{1, 2}) {
+		t.Errorf("ran %v, want [1 2]", ran)
+	}
+}
+
+func TestRestoreFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		data    string // file contents; empty for no file
+		want    int
+		wantErr bool
+	}{
+		{"no file", "", 0, false},
+		{"no jobs", "[]", 0, false},
+		{"corrupt", "[{", 0, true},
+		{"unknown kind", `[{"job":{"id":"j1","status":"queued"},"spec":{"kind":"other","data":{}}}]`, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if tt.data != "" {
+				if err := os.WriteFile(path, []byte(tt.data), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			n, err := newTestManager().Restore(path)
+			if (err != nil) != tt.wantErr || n != tt.want {
+				t.Fatalf("Restore = %d, %v; want %d, error %v", n, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/requestid"
+)
+
+// ErrNotFound is returned when a job ID is unknown or has expired.
+var ErrNotFound = errors.New("job not found")
+
+// ErrDraining is returned by Submit once Drain has been called.
+var ErrDraining = errors.New("jobs are draining for shutdown")
+
+// RunFunc does the work of a job, reporting progress as it goes. A failed run
+// may still return a response, e.g. with an error and debug trace, which is
+// kept as the job's result.
+type RunFunc func(ctx context.Context, progress func(stage string, percent int)) (*models.ProcessResponse, error)
+
+// Config controls job execution limits.
+type Config struct {
+	MaxConcurrent int           // jobs allowed to run at once
+	Timeout       time.Duration // upper bound on a single job's run time
+	Retention     time.Duration // how long finished jobs stay queryable
+}
+
+// Stats is a point-in-time view of the jobs in progress.
+type Stats struct {
+	MaxConcurrent int `json:"maxConcurrent"`
+	Running       int `json:"running"`
+	Queued        int `json:"queued"`
+	// AverageRunSeconds is a moving average of how long jobs take.
+	AverageRunSeconds float64 `json:"averageRunSeconds"`
+}
+
+// Manager runs process requests in the background and keeps their state in
+// memory so clients can poll for completion.
+type Manager struct {
+	cfg    Config
+	logger *slog.Logger
+	sem    chan struct{}
+
+	queued  atomic.Int64
+	running atomic.Int64
+	avgRun  atomic.Int64 // nanoseconds
+
+	mu       sync.RWMutex
+	jobs     map[string]*models.Job
+	active   map[string]string // job key to the ID of the unfinished job with it
+	pending  map[string]pending
+	resumers map[string]ResumeFunc
+	draining bool
+
+	wg sync.WaitGroup
+	// stop is closed when draining starts, so queued jobs stay queued;
+	// halt cancels the jobs still running when draining runs out of time.
+	stop chan struct{}
+	halt context.Context
+	kill context.CancelFunc
+}
+
+// NewManager returns a Manager using cfg.
+func NewManager(cfg Config, logger *slog.Logger) *Manager {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 1
+	}
+	halt, kill := context.WithCancel(context.Background())
+	return &Manager{
+		cfg:      cfg,
+		logger:   logger,
+		sem:      make(chan struct{}, cfg.MaxConcurrent),
+		jobs:     make(map[string]*models.Job),
+		active:   make(map[string]string),
+		pending:  make(map[string]pending),
+		resumers: make(map[string]ResumeFunc),
+		stop:     make(chan struct{}),
+		halt:     halt,
+		kill:     kill,
+	}
+}
+
+// Submit queues run and returns the ID of the new job. run is given a
+// context with the values of ctx, that of the request submitting it, so
+// its logs carry the request's ID, which the job records too; ctx being
+// cancelled doesn't stop it.
+//
+// A non-empty key names the work run does, so a job submitted again while
+// the first is queued or running joins it: the first job's ID is returned,
+// with joined set, and run is dropped.
+//
+// spec, if not nil, describes the work so a job Drain cuts short can be
+// saved and run again by Restore; its kind needs a Resumable.
+func (m *Manager) Submit(ctx context.Context, key string, spec *Spec, run RunFunc) (id string, joined bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.draining {
+		return "", false, ErrDraining
+	}
+	if id, ok := m.active[key]; ok && key != "" {
+		return id, true, nil
+	}
+	if id, err = models.NewID(); err != nil {
+		return "", false, err
+	}
+
+	m.pruneLocked(time.Now())
+	m.startLocked(ctx, models.Job{
+		ID:        id,
+		Status:    models.JobQueued,
+		Progress:  models.JobProgress{Stage: "queued"},
+		CreatedAt: time.Now().UTC(),
+		RequestID: requestid.From(ctx),
+	}, key, spec, run)
+	return id, false, nil
+}
+
+// startLocked records job and runs it in the background.
+func (m *Manager) startLocked(ctx context.Context, job models.Job, key string, spec *Spec, run RunFunc) {
+	m.jobs[job.ID] = &job
+	if key != "" {
+		m.active[key] = job.ID
+	}
+	if spec != nil {
+		m.pending[job.ID] = pending{Key: key, Spec: *spec}
+	}
+
+	m.queued.Add(1)
+	m.wg.Add(1)
+	go m.run(context.WithoutCancel(ctx), job.ID, key, run)
+}
+
+// Stats reports the jobs running and waiting for a slot.
+func (m *Manager) Stats() Stats {
+	return Stats{
+		MaxConcurrent:     m.cfg.MaxConcurrent,
+		Running:           int(m.running.Load()),
+		Queued:            int(m.queued.Load()),
+		AverageRunSeconds: time.Duration(m.avgRun.Load()).Seconds(),
+	}
+}
+
+// Get returns a snapshot of the job with the given ID.
+func (m *Manager) Get(id string) (models.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return models.Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+func (m *Manager) run(ctx context.Context, id, key string, run RunFunc) {
+	defer m.wg.Done()
+	// A job still waiting for a slot when draining starts stays queued,
+	// for Drain to save.
+	select {
+	case m.sem <- struct{}{}:
+	case <-m.stop:
+		return
+	}
+	defer func() { <-m.sem }()
+	select {
+	case <-m.stop:
+		return
+	default:
+	}
+	m.queued.Add(-1)
+	m.running.Add(1)
+	defer m.running.Add(-1)
+	defer m.observe(time.Now())
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+	defer context.AfterFunc(m.halt, cancel)()
+
+	m.update(id, func(j *models.Job) {
+		now := time.Now().UTC()
+		j.Status = models.JobRunning
+		j.StartedAt = &now
+	})
+
+	result, err := run(ctx, func(stage string, percent int) {
+		m.update(id, func(j *models.Job) {
+			j.Progress = models.JobProgress{Stage: stage, Percent: percent}
+		})
+	})
+	// A job Drain cut short is left unfinished, to be saved and run again.
+	if err != nil && m.halt.Err() != nil {
+		m.logger.WarnContext(ctx, "job interrupted by shutdown", "job_id", id, "error", err)
+		return
+	}
+
+	m.update(id, func(j *models.Job) {
+		now := time.Now().UTC()
+		j.FinishedAt = &now
+		j.Result = result
+		if err != nil {
+			j.Status = models.JobFailed
+			j.Error = err.Error()
+			return
+		}
+		j.Status = models.JobSucceeded
+	})
+	m.mu.Lock()
+	if key != "" {
+		delete(m.active, key)
+	}
+	delete(m.pending, id)
+	m.mu.Unlock()
+
+	if err != nil {
+		m.logger.ErrorContext(ctx, "job failed", "job_id", id, "error", err)
+		return
+	}
+	m.logger.InfoContext(ctx, "job completed", "job_id", id)
+}
+
+// observe folds the time since start into the moving average of run
+// times, weighting the latest run by an eighth.
+func (m *Manager) observe(start time.Time) {
+	d := int64(time.Since(start))
+	for {
+		old := m.avgRun.Load()
+		next := d
+		if old > 0 {
+			next = old + (d-old)/8
+		}
+		if m.avgRun.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (m *Manager) update(id string, fn func(*models.Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, ok := m.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// pruneLocked drops finished jobs older than the retention window.
+func (m *Manager) pruneLocked(now time.Time) {
+	for id, job := range m.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > m.cfg.Retention {
+			delete(m.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package mailwatch ingests results counties email in. It polls an IMAP
+// mailbox for unseen messages and feeds each attachment that a county's
+// email rule picks through the parse pipeline, as if it had been uploaded.
+package mailwatch
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/imap"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/requestid"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+const (
+	// DefaultInterval is how often the mailbox is polled by default.
+	DefaultInterval = time.Minute
+	// commandTimeout bounds each exchange with the server.
+	commandTimeout = time.Minute
+	// maxMessage bounds a message downloaded, attachments included.
+	maxMessage = 64 << 20
+)
+
+// Config locates the mailbox to watch.
+type Config struct {
+	// Addr is the server's host:port.
+	Addr string
+	// Insecure speaks plain IMAP rather than IMAP over TLS, for local
+	// servers only.
+	Insecure bool
+	Username string
+	Password string
+	// Mailbox defaults to INBOX.
+	Mailbox string
+	// Interval defaults to DefaultInterval.
+	Interval time.Duration
+}
+
+// Watcher polls a mailbox and ingests the attachments of matching emails.
+type Watcher struct {
+	cfg       Config
+	store     store.Store
+	processor *processor.Processor
+	logger    *slog.Logger
+
+	mu       sync.Mutex
+	validity uint32
+	skipped  map[uint32]bool // unseen messages no rule picked, by UID
+}
+
+// New returns a Watcher for the mailbox cfg names.
+func New(cfg Config, st store.Store, p *processor.Processor, logger *slog.Logger) *Watcher {
+	if cfg.Mailbox == "" {
+		cfg.Mailbox = "INBOX"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	return &Watcher{cfg: cfg, store: st, processor: p, logger: logger, skipped: make(map[uint32]bool)}
+}
+
+func (w *Watcher) String() string {
+	scheme := "imaps"
+	if w.cfg.Insecure {
+		scheme = "imap"
+	}
+	return scheme + "://" + w.cfg.Addr + "/" + w.cfg.Mailbox
+}
+
+// Run polls the mailbox until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	w.logger.Info("mail watcher started", "mailbox", w.String(), "interval", w.cfg.Interval)
+	for {
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("mailbox poll failed", "mailbox", w.String(), "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			w.logger.Info("mail watcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll ingests the unseen messages in the mailbox that a registered
+// county's email rule picks, flagging each as seen once its attachments
+// have been processed. Messages no rule picks are left unseen, for whoever
+// else reads the mailbox, and aren't downloaded again while the server
+// keeps their UIDs.
+func (w *Watcher) Poll(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var rules []models.CountySource
+	for _, c := range w.store.CountySources() {
+		if c.Email != nil {
+			rules = append(rules, c)
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var tlsConfig *tls.Config
+	if !w.cfg.Insecure {
+		host, _, _ := strings.Cut(w.cfg.Addr, ":")
+		tlsConfig = &tls.Config{ServerName: host}
+	}
+	c, err := imap.Dial(ctx, w.cfg.Addr, tlsConfig, commandTimeout)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := c.Login(w.cfg.Username, w.cfg.Password); err != nil {
+		return err
+	}
+	validity, err := c.Select(w.cfg.Mailbox)
+	if err != nil {
+		return err
+	}
+	if validity != w.validity {
+		w.validity = validity
+		clear(w.skipped)
+	}
+	uids, err := c.SearchUnseen()
+	if err != nil {
+		return err
+	}
+
+	for _, uid := range uids {
+		if w.skipped[uid] {
+			continue
+		}
+		raw, err := c.Fetch(uid, maxMessage)
+		if err != nil {
+			return err
+		}
+		msg, err := parseMessage(raw)
+		if err != nil {
+			w.logger.Warn("unreadable email skipped", "uid", uid, "error", err)
+			w.skipped[uid] = true
+			continue
+		}
+		if msg.ID == "" {
+			msg.ID = fmt.Sprintf("uid-%d-%d", validity, uid)
+		}
+		if !w.ingest(ctx, msg, rules) {
+			w.skipped[uid] = true
+			continue
+		}
+		if err := c.MarkSeen(uid); err != nil {
+			return err
+		}
+	}
+	return c.Logout()
+}
+
+// ingest processes the attachments of msg the rules pick, reporting
+// whether any rule picked the message.
+func (w *Watcher) ingest(ctx context.Context, msg *message, rules []models.CountySource) bool {
+	picked := false
+	for _, county := range rules {
+		if !senderMatches(county.Email.From, msg.From) || !strings.Contains(strings.ToLower(msg.Subject), strings.ToLower(county.Email.Subject)) {
+			continue
+		}
+		picked = true
+		seen := w.store.WatchedFiles(county.Election, county.Name)
+		for _, a := range msg.Attachments {
+			if !attachmentMatches(county, a.Name) {
+				continue
+			}
+			// An email read again, say after the server renumbered its
+			// messages, isn't ingested twice.
+			key := "email:" + msg.ID + "/" + a.Name
+			if seen[key] {
+				continue
+			}
+			w.process(ctx, county, msg, a)
+			w.store.MarkWatched(county.Election, county.Name, key)
+		}
+	}
+	return picked
+}
+
+func (w *Watcher) process(ctx context.Context, county models.CountySource, msg *message, a attachment) {
+	ctx = requestid.With(ctx, requestid.New())
+	start := time.Now()
+	req := county.ProcessRequest()
+	// Results name the file they came from where a link would be.
+	req.FileLink = "email:" + a.Name
+	req.Browser = nil
+	_, err := w.processor.ProcessUpload(ctx, req, a.Data, nil)
+	w.store.RecordFetch(county.Election, county.Name, start.UTC(), err)
+	if err != nil {
+		w.logger.ErrorContext(ctx, "emailed source failed", "county", county.Name, "from", msg.From, "file", a.Name, "error", err)
+		return
+	}
+	w.logger.InfoContext(ctx, "emailed source ingested", "county", county.Name, "from", msg.From, "subject", msg.Subject, "file", a.Name, "bytes", len(a.Data))
+}
+
+// senderMatches reports whether from is one of the addresses allowed, or
+// at one of the domains given as "@domain".
+func senderMatches(allowed []string, from string) bool {
+	if from == "" {
+		return false
+	}
+	for _, a := range allowed {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == from || (strings.HasPrefix(a, "@") && strings.HasSuffix(from, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentMatches reports whether name is one of county's attachments:
+// one matching its rule's glob, or with an extension of its parse method,
+// or of any for auto.
+func attachmentMatches(county models.CountySource, name string) bool {
+	name = strings.ToLower(name)
+	pattern := strings.ToLower(county.Email.Attachment)
+	if pattern == "" {
+		method := parser.MethodOf(name)
+		if strings.EqualFold(county.ParseMethod, models.ParseMethodAuto) {
+			return method != ""
+		}
+		return method == strings.ToLower(county.ParseMethod)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
@@ -0,0 +1,119 @@
+package mailwatch
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"strings"
+)
+
+// maxParts bounds the MIME parts of a message read, nested ones included.
+const maxParts = 200
+
+// message is an email as far as the rules care.
+type message struct {
+	ID          string
+	From        string // address, in lower case
+	Subject     string
+	Attachments []attachment
+}
+
+// attachment is a file attached to an email.
+type attachment struct {
+	Name string
+	Data []byte
+}
+
+var words = new(mime.WordDecoder)
+
+// parseMessage reads a raw RFC 5322 message and the files attached to it.
+func parseMessage(raw []byte) (*message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	msg := &message{ID: strings.Trim(strings.TrimSpace(m.Header.Get("Message-Id")), "<>")}
+	if addr, err := mail.ParseAddress(m.Header.Get("From")); err == nil {
+		msg.From = strings.ToLower(addr.Address)
+	}
+	msg.Subject, err = words.DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		msg.Subject = m.Header.Get("Subject")
+	}
+	parts := 0
+	err = walk(textproto.MIMEHeader(m.Header), m.Body, &msg.Attachments, &parts)
+	return msg, err
+}
+
+// walk appends the attachment body is, or those of its parts if it is
+// multipart.
+func walk(header textproto.MIMEHeader, body io.Reader, out *[]attachment, parts *int) error {
+	if *parts++; *parts > maxParts {
+		return errors.New("message has too many parts")
+	}
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := r.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := walk(p.Header, p, out, parts); err != nil {
+				return err
+			}
+		}
+	}
+
+	name := fileName(header, params)
+	if name == "" {
+		return nil // a message body rather than an attachment
+	}
+	var r io.Reader = body
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		r = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	*out = append(*out, attachment{Name: name, Data: data})
+	return nil
+}
+
+// fileName returns the name a part is attached under, from its
+// Content-Disposition or, failing that, its Content-Type, or "".
+func fileName(header textproto.MIMEHeader, typeParams map[string]string) string {
+	name := ""
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		name = typeParams["name"]
+	}
+	if decoded, err := words.DecodeHeader(name); err == nil {
+		name = decoded
+	}
+	// Names are the sender's to choose; only the last element is kept.
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
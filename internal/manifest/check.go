@@ -0,0 +1,123 @@
+package manifest
+
+import (
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Checker checks snapshots against the manifest of their election in a
+// store, so an imported manifest applies to the next check.
+type Checker struct {
+	store    store.Store
+	election string
+}
+
+// NewChecker returns a Checker for the manifests in st, using election's
+// for snapshots without one.
+func NewChecker(st store.Store, election string) *Checker {
+	return &Checker{store: st, election: election}
+}
+
+// Check compares r with its election's manifest. It reports false if no
+// manifest has been imported.
+func (c *Checker) Check(r *models.Results, at time.Time) (models.ManifestCheck, bool) {
+	election := r.Election
+	if election == "" {
+		election = c.election
+	}
+	m, err := c.store.Manifest(election)
+	if err != nil {
+		return models.ManifestCheck{}, false
+	}
+	return Compare(m, r, at), true
+}
+
+// Applies reports whether contest is on the ballot in county.
+func Applies(contest models.ManifestContest, county string) bool {
+	if len(contest.Counties) == 0 {
+		return true
+	}
+	for _, c := range contest.Counties {
+		if store.CountyKey(c) == store.CountyKey(county) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare checks r against the contests m expects in its county. Contests
+// are matched by ID, or by the slug of the county's title for results that
+// no contest rule mapped; candidates by their normalized name or the
+// county's spelling of it. Candidates of measures aren't compared.
+func Compare(m models.Manifest, r *models.Results, at time.Time) models.ManifestCheck {
+	check := models.ManifestCheck{County: r.County, Election: m.Election, SnapshotHash: r.Hash, CheckedAt: at}
+
+	found := make(map[string]bool)
+	for _, contest := range m.Contests {
+		if !Applies(contest, r.County) {
+			continue
+		}
+		check.Expected++
+		got, ok := findContest(r, contest)
+		if !ok {
+			check.Missing = append(check.Missing, models.ManifestContestRef{ID: contest.ID, Title: contest.Title})
+			continue
+		}
+		found[got.ID] = true
+		if contest.Type != models.ContentTypeMeasure && len(contest.Candidates) > 0 {
+			if mm, ok := compareCandidates(contest, got); ok {
+				check.Candidates = append(check.Candidates, mm)
+			}
+		}
+	}
+	for _, c := range r.Contests {
+		if !found[c.ID] {
+			check.Unexpected = append(check.Unexpected, models.ManifestContestRef{ID: c.ID, Title: c.Title})
+		}
+	}
+	check.OK = len(check.Missing) == 0 && len(check.Unexpected) == 0 && len(check.Candidates) == 0
+	return check
+}
+
+func findContest(r *models.Results, want models.ManifestContest) (*models.Contest, bool) {
+	if c, ok := r.ContestByID(want.ID); ok {
+		return c, true
+	}
+	for i, c := range r.Contests {
+		if models.Slug(c.Title) == want.ID || (c.RawTitle != "" && models.Slug(c.RawTitle) == want.ID) {
+			return &r.Contests[i], true
+		}
+	}
+	return nil, false
+}
+
+// compareCandidates reports the candidates of got that differ from want's,
+// and false if there are none.
+func compareCandidates(want models.ManifestContest, got *models.Contest) (models.CandidateMismatch, bool) {
+	expected := make(map[string]string, len(want.Candidates))
+	for _, cand := range want.Candidates {
+		expected[normalize.Key(cand.Name)] = cand.Name
+	}
+	seen := make(map[string]bool)
+	mm := models.CandidateMismatch{ContestID: got.ID}
+	for _, cand := range got.Candidates {
+		key := normalize.Key(cand.Name)
+		if _, ok := expected[key]; !ok && cand.RawName != "" {
+			key = normalize.Key(cand.RawName)
+		}
+		if _, ok := expected[key]; ok {
+			seen[key] = true
+		} else if !cand.WriteIn && !cand.WriteInAggregate {
+			mm.Unexpected = append(mm.Unexpected, cand.Name)
+		}
+	}
+	for _, cand := range want.Candidates {
+		if !seen[normalize.Key(cand.Name)] {
+			mm.Missing = append(mm.Missing, cand.Name)
+		}
+	}
+	return mm, len(mm.Missing) > 0 || len(mm.Unexpected) > 0
+}
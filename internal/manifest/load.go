@@ -0,0 +1,56 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/fetcher"
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// Load imports the manifest at location, an http(s) URL downloaded with f
+// or a file path, as the manifest of election. The format is taken from a
+// .csv or .json extension, or else from the content.
+func Load(ctx context.Context, f *fetcher.Fetcher, location, election string) (models.Manifest, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		data, err = f.Fetch(ctx, location)
+	} else {
+		data, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return models.Manifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+	return Import(data, FormatOf(location), location, election)
+}
+
+// Import parses data as a manifest from source and stamps it as election's,
+// unless it names an election of its own.
+func Import(data []byte, format, source, election string) (models.Manifest, error) {
+	m, err := Parse(data, format)
+	if err != nil {
+		return models.Manifest{}, err
+	}
+	if m.Election == "" {
+		m.Election = election
+	}
+	m.Source = source
+	return m, nil
+}
+
+// FormatOf returns the format a file name or URL's extension implies, or
+// "" if it implies none.
+func FormatOf(name string) string {
+	name, _, _ = strings.Cut(name, "?")
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	}
+	return ""
+}
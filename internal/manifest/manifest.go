@@ -0,0 +1,151 @@
+// Package manifest imports the expected ballot of an election, its
+// contests, candidates, measures and districts, and checks published
+// snapshots against it so missing or misread contests are caught.
+package manifest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/validate"
+)
+
+// Formats a manifest can be imported from.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// csvColumns are the columns of a CSV manifest. Only contest is required;
+// each row adds a candidate to its contest, and a contest's other columns
+// are taken from its first row.
+var csvColumns = []string{"contest_id", "contest", "type", "district", "counties", "candidate", "party"}
+
+// Parse decodes a manifest in format, or in the format its content looks
+// like if format is empty. JSON may be a manifest object or just its array
+// of contests; CSV has a header row naming csvColumns, with counties
+// separated by semicolons. Contests without an ID get the slug of their
+// title, and rows of the same contest are merged.
+func Parse(data []byte, format string) (models.Manifest, error) {
+	if format == "" {
+		format = FormatCSV
+		if b := bytes.TrimSpace(data); len(b) > 0 && (b[0] == '{' || b[0] == '[') {
+			format = FormatJSON
+		}
+	}
+
+	var m models.Manifest
+	var err error
+	switch format {
+	case FormatJSON:
+		m, err = parseJSON(data)
+	case FormatCSV:
+		m, err = parseCSV(data)
+	default:
+		return models.Manifest{}, fmt.Errorf("unknown manifest format %q", format)
+	}
+	if err != nil {
+		return models.Manifest{}, err
+	}
+	m.Contests = merge(m.Contests)
+	if err := validate.Manifest(m); err != nil {
+		return models.Manifest{}, err
+	}
+	return m, nil
+}
+
+func parseJSON(data []byte) (models.Manifest, error) {
+	var m models.Manifest
+	if b := bytes.TrimSpace(data); len(b) > 0 && b[0] == '[' {
+		if err := json.Unmarshal(b, &m.Contests); err != nil {
+			return models.Manifest{}, fmt.Errorf("decode manifest: %w", err)
+		}
+		return m, nil
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return models.Manifest{}, fmt.Errorf("decode manifest: %w", err)
+	}
+	return m, nil
+}
+
+func parseCSV(data []byte) (models.Manifest, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return models.Manifest{}, fmt.Errorf("read manifest header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["contest"]; !ok {
+		return models.Manifest{}, fmt.Errorf("manifest header has no contest column; columns are %s", strings.Join(csvColumns, ", "))
+	}
+	field := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var m models.Manifest
+	for line := 2; ; line++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return models.Manifest{}, fmt.Errorf("read manifest: %w", err)
+		}
+		c := models.ManifestContest{
+			ID:       field(row, "contest_id"),
+			Title:    field(row, "contest"),
+			Type:     field(row, "type"),
+			District: field(row, "district"),
+		}
+		for _, county := range strings.Split(field(row, "counties"), ";") {
+			if county = strings.TrimSpace(county); county != "" {
+				c.Counties = append(c.Counties, county)
+			}
+		}
+		if name := field(row, "candidate"); name != "" {
+			c.Candidates = []models.ManifestCandidate{{Name: name, Party: field(row, "party")}}
+		}
+		if c.Title == "" && c.ID == "" {
+			return models.Manifest{}, fmt.Errorf("manifest line %d: contest is required", line)
+		}
+		m.Contests = append(m.Contests, c)
+	}
+	return m, nil
+}
+
+// merge folds the candidates of contests with the same ID into the first
+// of them, filling in missing IDs from titles and normalizing types.
+func merge(contests []models.ManifestContest) []models.ManifestContest {
+	out := []models.ManifestContest{}
+	index := make(map[string]int)
+	for _, c := range contests {
+		c.Title = strings.TrimSpace(c.Title)
+		if c.ID == "" {
+			c.ID = models.Slug(c.Title)
+		}
+		c.Type = strings.ToLower(c.Type)
+		i, ok := index[c.ID]
+		if !ok {
+			index[c.ID] = len(out)
+			out = append(out, c)
+			continue
+		}
+		out[i].Candidates = append(out[i].Candidates, c.Candidates...)
+	}
+	return out
+}
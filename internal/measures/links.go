@@ -0,0 +1,133 @@
+package measures
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+)
+
+// SuggestThreshold is the minimum similarity of two measures' text for
+// Suggest to propose linking them.
+const SuggestThreshold = 0.8
+
+// designatorWords introduce a measure's letter or number rather than say
+// what it is about.
+var designatorWords = map[string]bool{
+	"MEASURE": true, "PROPOSITION": true, "PROP": true, "QUESTION": true, "ISSUE": true,
+	"AMENDMENT": true, "REFERENDUM": true, "INITIATIVE": true, "BALLOT": true, "NO": true, "NUMBER": true,
+}
+
+// Text returns the words of a measure title that say what it is about,
+// leaving out designators such as "Measure A" or "Prop 12-B", or "" if
+// fewer than two words are left to compare.
+func Text(title string) string {
+	words := strings.FieldsFunc(strings.ToUpper(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var keep []string
+	for _, w := range words {
+		if designatorWords[w] || isDesignator(w) {
+			continue
+		}
+		keep = append(keep, w)
+	}
+	if len(keep) < 2 {
+		return ""
+	}
+	return strings.Join(keep, " ")
+}
+
+// isDesignator reports whether w looks like a measure's letter or number:
+// a single letter, or a short word with a digit in it.
+func isDesignator(w string) bool {
+	if len([]rune(w)) == 1 {
+		return true
+	}
+	return len(w) <= 4 && strings.ContainsAny(w, "0123456789")
+}
+
+// Suggest proposes links for the measures in results whose text reads
+// alike across counties but which are reported under different contest
+// IDs, so they don't aggregate. Contests already in one of links are left
+// out. Each county contributes at most one contest to a suggestion.
+func Suggest(results []*models.Results, links []models.MeasureLink) []models.MeasureLinkSuggestion {
+	linked := make(map[string]bool)
+	for _, l := range links {
+		for _, m := range l.Members {
+			linked[models.Slug(m.County)+"/"+m.ContestID] = true
+		}
+	}
+
+	type cluster struct {
+		text     string
+		score    float64
+		counties map[string]bool
+		members  []models.MeasureLinkMember
+	}
+	var clusters []*cluster
+	for _, r := range results {
+		county := models.Slug(r.County)
+		for _, c := range r.Contests {
+			if c.Measure == nil || linked[county+"/"+c.ID] {
+				continue
+			}
+			text := Text(c.Title)
+			if text == "" {
+				continue
+			}
+			member := models.MeasureLinkMember{County: r.County, ContestID: c.ID, Title: c.Title}
+
+			var best *cluster
+			var bestScore float64
+			for _, cl := range clusters {
+				if cl.counties[county] {
+					continue
+				}
+				if s := normalize.Similarity(text, cl.text); s >= SuggestThreshold && s > bestScore {
+					best, bestScore = cl, s
+				}
+			}
+			if best == nil {
+				clusters = append(clusters, &cluster{text: text, score: 1, counties: map[string]bool{county: true}, members: []models.MeasureLinkMember{member}})
+				continue
+			}
+			best.score = min(best.score, bestScore)
+			best.counties[county] = true
+			best.members = append(best.members, member)
+		}
+	}
+
+	out := []models.MeasureLinkSuggestion{}
+	for _, cl := range clusters {
+		if len(cl.members) < 2 || sameID(cl.members) {
+			continue
+		}
+		out = append(out, models.MeasureLinkSuggestion{
+			ID:      models.Slug(cl.text),
+			Title:   cl.members[0].Title,
+			Score:   cl.score,
+			Members: cl.members,
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// sameID reports whether every member is reported under the same contest
+// ID, in which case the measure aggregates already.
+func sameID(members []models.MeasureLinkMember) bool {
+	for _, m := range members[1:] {
+		if m.ContestID != members[0].ContestID {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+// Package measures computes the Yes/No outcome of ballot measures against the
+// vote threshold they require.
+package measures
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// requirement is the share of Yes votes a measure needs: more than num/den
+// when strict, at least num/den otherwise.
+type requirement struct {
+	num, den int
+	strict   bool
+}
+
+func parseThreshold(s string) (requirement, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "", models.ThresholdMajority, "simple-majority":
+		return requirement{1, 2, true}, nil
+	case models.ThresholdThreeFifths:
+		return requirement{3, 5, false}, nil
+	case models.ThresholdTwoThirds:
+		return requirement{2, 3, false}, nil
+	}
+
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(pct))
+		if err != nil || n <= 0 || n >= 100 {
+			return requirement{}, fmt.Errorf("invalid threshold %q", s)
+		}
+		return requirement{n, 100, false}, nil
+	}
+	if a, b, ok := strings.Cut(s, "/"); ok {
+		n, err1 := strconv.Atoi(strings.TrimSpace(a))
+		d, err2 := strconv.Atoi(strings.TrimSpace(b))
+		if err1 != nil || err2 != nil || n <= 0 || d <= n {
+			return requirement{}, fmt.Errorf("invalid threshold %q", s)
+		}
+		return requirement{n, d, false}, nil
+	}
+	return requirement{}, fmt.Errorf("invalid threshold %q: use majority, three-fifths, two-thirds, a fraction or a percentage", s)
+}
+
+// ValidThreshold reports whether s is a threshold Compute understands. The
+// empty string means a simple majority.
+func ValidThreshold(s string) error {
+	_, err := parseThreshold(s)
+	return err
+}
+
+func (q requirement) met(yes, no int) bool {
+	total := yes + no
+	if total == 0 {
+		return false
+	}
+	if q.strict {
+		return yes*q.den > q.num*total
+	}
+	return yes*q.den >= q.num*total
+}
+
+// Compute tallies the Yes and No choices of c against threshold. It reports
+// false if c doesn't look like a measure or the threshold is invalid.
+func Compute(c models.Contest, threshold string) (*models.MeasureResult, bool) {
+	q, err := parseThreshold(threshold)
+	if err != nil {
+		return nil, false
+	}
+
+	var yes, no int
+	var found bool
+	for _, cand := range c.Candidates {
+		switch Choice(cand.Name) {
+		case "yes":
+			yes += cand.Votes
+			found = true
+		case "no":
+			no += cand.Votes
+			found = true
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	if threshold == "" {
+		threshold = models.ThresholdMajority
+	}
+	m := &models.MeasureResult{Yes: yes, No: no, Threshold: threshold, Passing: q.met(yes, no)}
+	if yes+no > 0 {
+		m.YesPercent = math.Round(float64(yes)*10000/float64(yes+no)) / 100
+	}
+	return m, true
+}
+
+// Apply computes the outcome of every contest of a measure source, and of
+// contests in any source that a contest rule gave a threshold. A threshold
+// already set on a contest takes precedence over the request's.
+func Apply(req models.ProcessRequest, contests []models.Contest) []models.Contest {
+	for i := range contests {
+		threshold := req.MeasureThreshold
+		if contests[i].Measure != nil {
+			threshold = contests[i].Measure.Threshold
+		} else if req.ContentType != models.ContentTypeMeasure {
+			continue
+		}
+		contests[i].Measure, _ = Compute(contests[i], threshold)
+	}
+	return contests
+}
+
+var (
+	yesWords = map[string]bool{"YES": true, "FOR": true, "APPROVE": true, "APPROVED": true}
+	noWords  = map[string]bool{"NO": true, "AGAINST": true, "REJECT": true, "REJECTED": true}
+)
+
+// Choice classifies a measure choice such as "YES", "Bonds No" or
+// "No on Measure A" by its first Yes or No word, as "yes", "no" or "".
+func Choice(name string) string {
+	words := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return r == ' ' || r == '-' || r == ',' || r == '/'
+	})
+	for _, w := range words {
+		switch {
+		case yesWords[w]:
+			return "yes"
+		case noWords[w]:
+			return "no"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// rfc6962Leaves are the leaf inputs of the RFC 6962 test vectors used by
+// Certificate Transparency implementations.
+var rfc6962Leaves = []string{
+	"",
+	"00",
+	"10",
+	"2021",
+	"3031",
+	"40414243",
+	"5051525354555657",
+	"606162636465666768696a6b6c6d6e6f",
+}
+
+func leafHashes(t *testing.T, n int) [][]byte {
+	t.Helper()
+	var leaves [][]byte
+	for _, l := range rfc6962Leaves[:n] {
+		data, err := hex.DecodeString(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves = append(leaves, LeafHash(data))
+	}
+	return leaves
+}
+
+func TestRoot(t *testing.T) {
+	tests := []struct {
+		size int
+		want string
+	}{
+		{0, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{1, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"},
+		{2, "fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125"},
+		{3, "aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77"},
+		{4, "d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7"},
+		{5, "4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4"},
+		{6, "76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef"},
+		{7, "ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c"},
+		{8, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(Root(leafHashes(t, tt.size))); got != tt.want {
+			t.Errorf("Root of %d leaves = %s, want %s", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestProof(t *testing.T) {
+	for size := 1; size <= len(rfc6962Leaves); size++ {
+		leaves := leafHashes(t, size)
+		root := Root(leaves)
+		for i := range size {
+			proof, err := Proof(i, leaves)
+			if err != nil {
+				t.Fatalf("Proof(%d) of %d leaves: %v", i, size, err)
+			}
+			if !Verify(i, size, leaves[i], proof, root) {
+				t.Errorf("proof of leaf %d of %d doesn't verify", i, size)
+			}
+			if size > 1 && Verify(i, size, leaves[(i+1)%size], proof, root) {
+				t.Errorf("proof of leaf %d of %d verifies another leaf", i, size)
+			}
+			if len(proof) > 0 {
+				bad := append([][]byte(nil), proof...)
+				bad[0] = bytes.Repeat([]byte{0xff}, 32)
+				if Verify(i, size, leaves[i], bad, root) {
+					t.Errorf("tampered proof of leaf %d of %d verifies", i, size)
+				}
+				if Verify(i, size, leaves[i], proof[:len(proof)-1], root) {
+					t.Errorf("short proof of leaf %d of %d verifies", i, size)
+				}
+			}
+			if Verify(i, size, leaves[i], append(proof, root), root) {
+				t.Errorf("long proof of leaf %d of %d verifies", i, size)
+			}
+		}
+	}
+}
+
+func TestProofRange(t *testing.T) {
+	leaves := leafHashes(t, 3)
+	for _, i := range []int{-1, 3} {
+		if _, err := Proof(i, leaves); err == nil {
+			t.Errorf("Proof(%d) of 3 leaves succeeded", i)
+		}
+		if Verify(i, 3, leaves[0], nil, Root(leaves)) {
+			t.Errorf("Verify(%d) of 3 leaves succeeded", i)
+		}
+	}
+}
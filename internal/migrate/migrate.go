@@ -0,0 +1,193 @@
+// Package migrate upgrades the event log file, the one store of published
+// results that outlives the process, from the format an older binary wrote
+// to the one this binary reads. Migrations are compiled in and numbered in
+// order; each upgrades the log from the version before it, rewriting every
+// event, and the file is only replaced once all of them have run, the
+// original being kept beside it.
+package migrate
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/many221/era_api_v1/internal/events"
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// Config is what migrations may need to know about the deployment.
+type Config struct {
+	// DefaultElection is the election events recorded without one belong
+	// to, as the server's ELECTION_ID.
+	DefaultElection string
+}
+
+// Migration upgrades the log from version Version-1 to Version.
+type Migration struct {
+	Version int
+	Name    string
+	// Event returns e as recorded at Version.
+	Event func(e models.Event, cfg Config) models.Event
+}
+
+// migrations are every upgrade, oldest first. The last one's version is
+// events.SchemaVersion.
+var migrations = []Migration{
+	{
+		// Results were once all for one election and their events named
+		// none. Stamping them with it makes the log mean the same whatever
+		// ELECTION_ID is set to later.
+		Version: 1,
+		Name:    "stamp-default-election",
+		Event: func(e models.Event, cfg Config) models.Event {
+			if e.Election == "" {
+				e.Election = cfg.DefaultElection
+			}
+			return e
+		},
+	},
+}
+
+func init() {
+	if last := migrations[len(migrations)-1].Version; last != events.SchemaVersion {
+		panic("migrate: last migration is version " + strconv.Itoa(last) + ", events.SchemaVersion is " + strconv.Itoa(events.SchemaVersion))
+	}
+}
+
+// Status describes the log at a path and what migrating it would do.
+type Status struct {
+	Path    string   `json:"path"`
+	Version int      `json:"version"`
+	Latest  int      `json:"latest"`
+	Pending []string `json:"pending"`
+}
+
+// Report describes a migration run.
+type Report struct {
+	From    int      `json:"from"`
+	To      int      `json:"to"`
+	Applied []string `json:"applied"`
+	Events  int      `json:"events"`
+	// Backup is where the log as it was before is kept.
+	Backup string `json:"backup,omitempty"`
+}
+
+// Check returns the status of the log at path.
+func Check(path string) (Status, error) {
+	v, err := events.FileVersion(path)
+	if err != nil {
+		return Status{}, err
+	}
+	s := Status{Path: path, Version: v, Latest: events.SchemaVersion, Pending: []string{}}
+	for _, m := range pending(v) {
+		s.Pending = append(s.Pending, name(m))
+	}
+	return s, nil
+}
+
+// Run applies the migrations the log at path is missing. The upgraded log
+// is written beside it and swapped in once complete, the original kept as
+// <path>.v<version>.bak, so a failed run leaves the log as it was. A log
+// written by a newer binary is refused.
+func Run(path string, cfg Config) (Report, error) {
+	v, err := events.FileVersion(path)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{From: v, To: v, Applied: []string{}}
+	if v > events.SchemaVersion {
+		return report, fmt.Errorf("%w: %s is at version %d, newer than this binary's %d", events.ErrSchemaVersion, path, v, events.SchemaVersion)
+	}
+	todo := pending(v)
+	if len(todo) == 0 {
+		return report, nil
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return report, fmt.Errorf("migrate event log: %w", err)
+	}
+	defer in.Close()
+	tmp := path + ".migrating"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return report, fmt.Errorf("migrate event log: %w", err)
+	}
+	defer os.Remove(tmp)
+	defer out.Close()
+
+	w := bufio.NewWriterSize(out, 1<<20)
+	w.Write(events.Header(events.SchemaVersion))
+	r := bufio.NewReaderSize(in, 1<<20)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A line without its newline was never fully written.
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("read event log: %w", err)
+		}
+		var e models.Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return report, fmt.Errorf("event log %s: %w", path, err)
+		}
+		if e.Seq == 0 {
+			continue // a header
+		}
+		for _, m := range todo {
+			e = m.Event(e, cfg)
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return report, fmt.Errorf("encode event: %w", err)
+		}
+		w.Write(data)
+		w.WriteByte('\n')
+		report.Events++
+	}
+	if err := w.Flush(); err != nil {
+		return report, fmt.Errorf("write event log: %w", err)
+	}
+	if err := out.Sync(); err != nil {
+		return report, fmt.Errorf("sync event log: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return report, fmt.Errorf("write event log: %w", err)
+	}
+
+	backup := path + ".v" + strconv.Itoa(v) + ".bak"
+	if err := os.Rename(path, backup); err != nil {
+		return report, fmt.Errorf("back up event log: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		// Put the original back rather than leave no log at all.
+		os.Rename(backup, path)
+		return report, fmt.Errorf("replace event log: %w", err)
+	}
+	report.To = events.SchemaVersion
+	report.Backup = backup
+	for _, m := range todo {
+		report.Applied = append(report.Applied, name(m))
+	}
+	return report, nil
+}
+
+// pending returns the migrations a log at version is missing.
+func pending(version int) []Migration {
+	var out []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func name(m Migration) string {
+	return strconv.Itoa(m.Version) + "-" + m.Name
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Aggregate is a contest rolled up across every county that reports it.
+type Aggregate struct {
+	ContestID          string               `json:"contestId"`
+	Title              string               `json:"title"`
+	Counties           int                  `json:"counties"`
+	TotalVotes         int                  `json:"totalVotes"`
+	PrecinctsReporting int                  `json:"precinctsReporting"`
+	PrecinctsTotal     int                  `json:"precinctsTotal"`
+	ReportingPercent   float64              `json:"reportingPercent"`
+	Candidates         []AggregateCandidate `json:"candidates"`
+	Breakdown          []CountyBreakdown    `json:"breakdown"`
+	Measure            *MeasureResult       `json:"measure,omitempty"` // combined Yes/No of a measure
+	Outcome            *ContestOutcome      `json:"outcome,omitempty"`
+
+	// Licenses lists the distinct licenses of the contributing counties;
+	// consumers must honor all of them.
+	Licenses []License `json:"licenses,omitempty"`
+
+	// Version is the store version every county was read at, so two
+	// aggregates with the same version add up the same snapshots.
+	Version uint64 `json:"version"`
+}
+
+// AggregateCandidate is a candidate's combined total across counties.
+type AggregateCandidate struct {
+	Name             string  `json:"name"`
+	Party            string  `json:"party,omitempty"`
+	Votes            int     `json:"votes"`
+	Percent          float64 `json:"percent"`
+	WriteIn          bool    `json:"writeIn,omitempty"`
+	WriteInAggregate bool    `json:"writeInAggregate,omitempty"`
+}
+
+// CountyBreakdown is one county's contribution to an Aggregate.
+type CountyBreakdown struct {
+	County             string      `json:"county"`
+	AsOf               time.Time   `json:"asOf"`
+	SnapshotHash       string      `json:"snapshotHash"`
+	TotalVotes         int         `json:"totalVotes"`
+	PrecinctsReporting int         `json:"precinctsReporting"`
+	PrecinctsTotal     int         `json:"precinctsTotal"`
+	ReportingPercent   float64     `json:"reportingPercent"`
+	License            *License    `json:"license,omitempty"`
+	Candidates         []Candidate `json:"candidates"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Alert types raised by the pipeline.
+const (
+	AlertFetchFailed    = "fetch_failed"    // a scheduled refresh failed
+	AlertLayoutChanged  = "layout_changed"  // a source's structure changed
+	AlertQuarantined    = "quarantined"     // a snapshot was held for review
+	AlertParserFallback = "parser_fallback" // published with an earlier parser config
+	AlertSLABreached    = "sla_breached"    // a county's data went staler than an SLA allows
+)
+
+// AlertTypes lists every alert type.
+var AlertTypes = []string{AlertFetchFailed, AlertLayoutChanged, AlertQuarantined, AlertParserFallback, AlertSLABreached}
+
+// Alert severities, as PagerDuty names them.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Alert is an operational problem sent to on-call channels, carrying what a
+// responder needs to act on it rather than a bare error string.
+type Alert struct {
+	Type     string            `json:"type"`
+	Severity string            `json:"severity"`
+	County   string            `json:"county,omitempty"`
+	Source   string            `json:"source,omitempty"`
+	Summary  string            `json:"summary"`
+	Details  map[string]string `json:"details,omitempty"`
+	RaisedAt time.Time         `json:"raisedAt"`
+
+	// Runbooks and Contact are filled in when the alert is raised.
+	Runbooks []Runbook      `json:"runbooks,omitempty"`
+	Contact  *CountyContact `json:"contact,omitempty"`
+
+	// Suppressed is set on an alert recorded but not sent, because the same
+	// alert went out within the cooldown.
+	Suppressed bool `json:"suppressed,omitempty"`
+}
+
+// Runbook tells responders what to do about an alert type, for every source
+// or for one county's only.
+type Runbook struct {
+	AlertType string    `json:"alertType"`
+	County    string    `json:"county,omitempty"` // empty applies to every source
+	URL       string    `json:"url,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AliasImport reports what seeding an election's mappings from a prior
+// election added.
+type AliasImport struct {
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	ImportedAt time.Time `json:"importedAt"`
+
+	// ContestRules is how many of the prior election's contest rules were
+	// copied, CandidateAliases how many county spellings its results
+	// resolved were added to the candidate registry.
+	ContestRules     int `json:"contestRules"`
+	CandidateAliases int `json:"candidateAliases"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Kinds of Anomaly.
+const (
+	AnomalyVotesDecreased     = "votes-decreased"
+	AnomalyExceedsBallots     = "exceeds-ballots-cast"
+	AnomalyPrecinctsBackwards = "precincts-backwards"
+	AnomalySwing              = "swing"
+)
+
+// AnomalyKinds lists every Anomaly kind.
+var AnomalyKinds = []string{AnomalyVotesDecreased, AnomalyExceedsBallots, AnomalyPrecinctsBackwards, AnomalySwing}
+
+// Anomaly is an integrity problem found in a published snapshot, alone or
+// against the county's previous one. Anomalies are flags for a human to
+// look at; unlike drift, they don't hold the snapshot back.
+type Anomaly struct {
+	Kind      string `json:"kind"`
+	County    string `json:"county"`
+	ContestID string `json:"contestId"`
+	Contest   string `json:"contest"`
+	Candidate string `json:"candidate,omitempty"`
+	Message   string `json:"message"`
+
+	// Previous and Current are the values compared: votes, precincts
+	// reporting, or a vote share in hundredths of a percent for swings.
+	// For exceeds-ballots-cast, Previous is the ballots cast.
+	Previous int `json:"previous"`
+	Current  int `json:"current"`
+
+	SnapshotHash string    `json:"snapshotHash"`
+	PreviousHash string    `json:"previousHash,omitempty"`
+	DetectedAt   time.Time `json:"detectedAt"`
+}
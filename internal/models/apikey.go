@@ -0,0 +1,52 @@
+package models
+
+// Visibility presets for APIKey.Visibility.
+const (
+	VisibilityFull    = "full"
+	VisibilityPartner = "partner" // summaries, no precinct detail or county contacts
+	VisibilityPublic  = "public"  // summaries, no precinct detail, provenance or county contacts
+)
+
+// Roles for APIKey.Role, from least to most trusted. Each role may do
+// everything the ones before it may.
+const (
+	RoleViewer   = "viewer"   // reads only
+	RoleIngester = "ingester" // also submits sources to process
+	RoleEditor   = "editor"   // also calls races and curates contests, candidates and review queues
+	RoleAdmin    = "admin"    // also manages counties, elections, legal holds and deployment settings
+)
+
+// Roles lists the roles from least to most trusted.
+var Roles = []string{RoleViewer, RoleIngester, RoleEditor, RoleAdmin}
+
+// RoleAllows reports whether role may do what needs at least the role
+// least. Unknown roles allow nothing.
+func RoleAllows(role, least string) bool {
+	have, need := -1, len(Roles)
+	for i, r := range Roles {
+		if r == role {
+			have = i
+		}
+		if r == least {
+			need = i
+		}
+	}
+	return have >= need
+}
+
+// APIKey identifies a consumer of the API and what it may see.
+type APIKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// Key is the plaintext secret; KeyHash, the hex SHA-256 of it, may be
+	// configured instead so secrets don't sit in config files.
+	Key     string `json:"key,omitempty"`
+	KeyHash string `json:"keyHash,omitempty"`
+
+	Visibility string   `json:"visibility,omitempty"` // preset, defaults to full
+	Hide       []string `json:"hide,omitempty"`       // extra field groups to hide
+	Profile    string   `json:"profile,omitempty"`    // response profile, e.g. "broadcast"
+	Tier       string   `json:"tier,omitempty"`       // access tier delaying results, real time if empty
+	Role       string   `json:"role,omitempty"`       // what it may do, defaults to admin
+}
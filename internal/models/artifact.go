@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Artifact describes an archived source file: the exact bytes a snapshot
+// was parsed from, as fetched from the county.
+type Artifact struct {
+	County       string    `json:"county"` // county key
+	SnapshotHash string    `json:"snapshotHash"`
+	ContentHash  string    `json:"contentHash,omitempty"` // "sha256:..." of the file
+	ParseMethod  string    `json:"parseMethod"`
+	FileLink     string    `json:"fileLink,omitempty"`
+	Size         int       `json:"size"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+}
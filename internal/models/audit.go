@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// AuditEntry records one request that changed, or tried to change, what
+// the server holds or publishes: who made it, what it was and how it went.
+type AuditEntry struct {
+	Seq uint64    `json:"seq"`
+	At  time.Time `json:"at"`
+
+	// Actor is the ID of the API key the request was made with, empty for
+	// anonymous requests; ActorName is the key's name.
+	Actor     string `json:"actor,omitempty"`
+	ActorName string `json:"actorName,omitempty"`
+
+	// Action is the route the request matched, such as
+	// "POST /api/v1/counties", and Path and Query what was requested.
+	Action string `json:"action"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+
+	Status     int    `json:"status"`
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+
+	// RequestID is the request's X-Request-ID, which its logs carry too.
+	RequestID string `json:"requestId,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// CanonicalCandidate is an entry in the candidate registry. County spellings
+// of the same person are resolved to it through normalization, aliases and
+// fuzzy matching.
+type CanonicalCandidate struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Party   string   `json:"party,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+
+	// ContestID optionally restricts matching to a single contest, for
+	// common names that appear on several races.
+	ContestID string `json:"contestId,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+// Values of Capacity.Status.
+const (
+	CapacityOK        = "ok"        // new work starts on arrival
+	CapacityBusy      = "busy"      // every slot is taken, so new work waits
+	CapacitySaturated = "saturated" // the backlog is over a round deep
+)
+
+// Capacity is how loaded the server is, served at /api/v1/capacity for
+// clients and load balancers pacing their requests.
+type Capacity struct {
+	Status string `json:"status"`
+	// Load is the work running and waiting over the slots for it, of the
+	// busier of processing and refreshes. Above 1, work queues.
+	Load float64 `json:"load"`
+	// RetryAfterSeconds estimates when work submitted would start without
+	// waiting; 0 when it would start now.
+	RetryAfterSeconds int                `json:"retryAfterSeconds"`
+	Processing        ProcessingCapacity `json:"processing"`
+	Refresh           RefreshCapacity    `json:"refresh"`
+}
+
+// ProcessingCapacity covers process and upload requests: async jobs, which
+// wait for one of the slots, and requests answered in line.
+type ProcessingCapacity struct {
+	Slots             int     `json:"slots"`
+	Running           int     `json:"running"`
+	Queued            int     `json:"queued"`
+	InFlight          int     `json:"inFlight"`
+	AverageRunSeconds float64 `json:"averageRunSeconds"`
+}
+
+// RefreshCapacity covers the worker pool scheduled fetches run on.
+type RefreshCapacity struct {
+	Workers           int     `json:"workers"`
+	Active            int     `json:"active"`
+	Queued            int     `json:"queued"`
+	QueueSize         int     `json:"queueSize"`
+	AverageRunSeconds float64 `json:"averageRunSeconds"`
+}
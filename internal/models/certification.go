@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Certification records a county's results certified as final: the
+// snapshot that was live when it was certified, frozen from then on, and
+// the bundle archived for the public record.
+type Certification struct {
+	Election     string    `json:"election"`
+	County       string    `json:"county"`
+	SnapshotHash string    `json:"snapshotHash"`
+	CertifiedBy  string    `json:"certifiedBy,omitempty"` // API key ID
+	CertifiedAt  time.Time `json:"certifiedAt"`
+	Bundle       Bundle    `json:"bundle"`
+}
+
+// Bundle describes the archive of a certified county's results: a ZIP of
+// the results as JSON and CSV, the source file they were parsed from and a
+// manifest of the other files' SHA-256 hashes. It is written once and never
+// replaced.
+type Bundle struct {
+	// URL is the bundle's permanent address, relative to the API's base.
+	URL    string `json:"url"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"` // "sha256:..." of the ZIP
+	// ManifestHash is the "sha256:..." of manifest.json, which lists Files.
+	ManifestHash string       `json:"manifestHash"`
+	Files        []BundleFile `json:"files"`
+}
+
+// BundleFile is a file in a Bundle, as its manifest lists it.
+type BundleFile struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// CountyContact is who to reach at a county when its results source fails:
+// the elections office, its IT staff, and notes on when results are posted.
+type CountyContact struct {
+	County string `json:"county"`
+
+	OfficeName  string `json:"officeName,omitempty"`
+	OfficePhone string `json:"officePhone,omitempty"`
+	OfficeEmail string `json:"officeEmail,omitempty"`
+
+	ITName  string `json:"itName,omitempty"`
+	ITPhone string `json:"itPhone,omitempty"`
+	ITEmail string `json:"itEmail,omitempty"`
+
+	// PostingSchedule describes when the county posts updates, e.g.
+	// "first drop 8:15pm, then hourly until midnight".
+	PostingSchedule string `json:"postingSchedule,omitempty"`
+	Notes           string `json:"notes,omitempty"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// Match types for ContestRule.
+const (
+	RuleExact    = "exact"
+	RulePrefix   = "prefix"
+	RuleContains = "contains"
+	RuleRegex    = "regex"
+)
+
+// ContestRule maps county-specific contest titles onto a canonical contest
+// ID, e.g. both "PROP 12 - PARKS BOND" and "Measure 12" onto "prop-12".
+type ContestRule struct {
+	ID       string `json:"id"`
+	Election string `json:"election"`
+	County   string `json:"county,omitempty"` // empty applies to every county
+	Match    string `json:"match"`            // exact, prefix, contains or regex
+	Pattern  string `json:"pattern"`
+	Priority int    `json:"priority,omitempty"` // higher wins
+
+	CanonicalID    string `json:"canonicalId"`
+	CanonicalTitle string `json:"canonicalTitle,omitempty"`
+	// Threshold marks the contest as a measure requiring this vote threshold,
+	// overriding the source's default.
+	Threshold string `json:"threshold,omitempty"`
+}
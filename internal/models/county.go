@@ -0,0 +1,137 @@
+package models
+
+import "time"
+
+// CountySource is a county registered for scheduled refreshes.
+type CountySource struct {
+	Name        string `json:"name"`
+	FileLink    string `json:"fileLink"`
+	ContentType string `json:"contentType"`
+	ParseMethod string `json:"parseMethod"`
+
+	// Election is the election the source publishes results for; empty
+	// registers it for the default one.
+	Election string `json:"election"`
+
+	// IntervalSeconds overrides the scheduler's default refresh interval.
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+
+	// Schedule refreshes the county at other intervals during windows of
+	// time, such as every 2 minutes from 8pm to 2am on election night, its
+	// IntervalSeconds or the default applying outside them.
+	Schedule []RefreshWindow `json:"schedule,omitempty"`
+
+	// License overrides the deployment's default data license for this source.
+	License *License `json:"license,omitempty"`
+
+	// MeasureThreshold is the default threshold for this county's measures.
+	MeasureThreshold string `json:"measureThreshold,omitempty"`
+
+	// Precedence names the source level published first for this county
+	// when several report it, e.g. SourceLevelState to prefer the state
+	// feed; the deployment's order ranks the rest. Empty keeps the
+	// deployment's order, which ranks the county's own source first.
+	Precedence string `json:"precedence,omitempty"`
+
+	// Browser fetches FileLink with a headless browser; see
+	// ProcessRequest.Browser.
+	Browser *BrowserFetch `json:"browser,omitempty"`
+
+	// Watch makes FileLink a downloads page or directory listing to watch
+	// rather than the source itself, for counties that post each update
+	// as a new file instead of replacing one at a stable URL.
+	Watch *DirectoryWatch `json:"watch,omitempty"`
+
+	// Email ingests the attachments of emails the rule picks from the
+	// deployment's watched mailbox, for counties that email their results.
+	// FileLink may be left empty for a county that only does.
+	Email *EmailRule `json:"email,omitempty"`
+
+	// RegisteredAt is when the county was first registered.
+	RegisteredAt time.Time `json:"registeredAt"`
+
+	Status SourceStatus `json:"status"`
+
+	// Contact is filled in on listings for sources whose last fetch failed,
+	// so whoever is watching knows who to call. Contacts are managed at
+	// /api/v1/contacts, not through registration.
+	Contact *CountyContact `json:"contact,omitempty"`
+}
+
+// SourceStatus records the outcome of the most recent scheduled fetches.
+type SourceStatus struct {
+	LastRunAt     *time.Time `json:"lastRunAt,omitempty"`
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+
+	// LastFile is the file a watched source, or an email, last ingested.
+	LastFile string `json:"lastFile,omitempty"`
+}
+
+// DirectoryWatch picks the files of a watched page to ingest.
+type DirectoryWatch struct {
+	// Pattern is a glob the names of the files linked from the page must
+	// match, e.g. "results_*.zip", compared without regard to case.
+	Pattern string `json:"pattern"`
+}
+
+// EmailRule picks the emails and attachments that are a county's source.
+type EmailRule struct {
+	// From lists the senders accepted, as addresses or as "@example.gov"
+	// for any address of a domain. The sender is the one the From header
+	// names, so the mail server should reject mail that fails its checks.
+	From []string `json:"from"`
+	// Subject, if set, must appear in the subject, ignoring case.
+	Subject string `json:"subject,omitempty"`
+	// Attachment is a glob the attachment's file name must match, ignoring
+	// case, e.g. "results*.xml". Empty matches names with the extension of
+	// the county's parse method.
+	Attachment string `json:"attachment,omitempty"`
+}
+
+// RefreshWindow is a span of time a county is refreshed every
+// IntervalSeconds, from From until Until.
+type RefreshWindow struct {
+	From            time.Time `json:"from"`
+	Until           time.Time `json:"until"`
+	IntervalSeconds int       `json:"intervalSeconds"`
+}
+
+// Contains reports whether t falls in w.
+func (w RefreshWindow) Contains(t time.Time) bool {
+	return !t.Before(w.From) && t.Before(w.Until)
+}
+
+// RefreshInterval returns how often c should be fetched at t: the shortest
+// interval of the windows of its schedule t falls in, or outside them its
+// own interval, falling back to def.
+func (c CountySource) RefreshInterval(t time.Time, def time.Duration) time.Duration {
+	var shortest time.Duration
+	for _, w := range c.Schedule {
+		if d := time.Duration(w.IntervalSeconds) * time.Second; d > 0 && w.Contains(t) && (shortest == 0 || d < shortest) {
+			shortest = d
+		}
+	}
+	if shortest > 0 {
+		return shortest
+	}
+	if c.IntervalSeconds > 0 {
+		return time.Duration(c.IntervalSeconds) * time.Second
+	}
+	return def
+}
+
+// ProcessRequest returns the process request equivalent to a refresh of c.
+func (c CountySource) ProcessRequest() ProcessRequest {
+	return ProcessRequest{
+		CountyName:  c.Name,
+		Election:    c.Election,
+		FileLink:    c.FileLink,
+		ContentType: c.ContentType,
+		ParseMethod: c.ParseMethod,
+		License:     c.License,
+		Browser:     c.Browser,
+
+		MeasureThreshold: c.MeasureThreshold,
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"net/url"
+	"time"
+)
+
+// SourceCredentials log in to a county's source server, for FTP servers
+// and sites behind HTTP basic authentication, or are an access key ID and
+// secret for a bucket. The password is never served back.
+type SourceCredentials struct {
+	County   string `json:"county"`
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Redacted returns c without its password.
+func (c SourceCredentials) Redacted() SourceCredentials {
+	c.Password = ""
+	return c
+}
+
+// Userinfo returns c as URL user information.
+func (c SourceCredentials) Userinfo() *url.Userinfo {
+	return url.UserPassword(c.Username, c.Password)
+}
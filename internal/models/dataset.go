@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// DatasetPrevious labels the snapshots a dataset switch displaced. It is
+// replaced by every switch, so activating it switches back and activating
+// it again switches forward.
+const DatasetPrevious = "previous"
+
+// Dataset is a set of snapshots prepared under a label while the published
+// results stay live, to be switched live all at once.
+type Dataset struct {
+	Label     string          `json:"label"`
+	Counties  []DatasetCounty `json:"counties"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// DatasetCounty is one county's snapshot in a dataset.
+type DatasetCounty struct {
+	County       string    `json:"county"`
+	Election     string    `json:"election,omitempty"`
+	SnapshotHash string    `json:"snapshotHash"`
+	ParsedAt     time.Time `json:"parsedAt"`
+}
+
+// DatasetSwitch reports a dataset switched live. The snapshots it displaced
+// are kept as the dataset labeled DatasetPrevious; counties that had no
+// published results before are listed in Added and stay live if the switch
+// is reversed, since there is nothing to go back to.
+type DatasetSwitch struct {
+	Label      string    `json:"label"`
+	Counties   []string  `json:"counties"`
+	Added      []string  `json:"added,omitempty"`
+	Previous   string    `json:"previous,omitempty"`
+	SwitchedAt time.Time `json:"switchedAt"`
+}
@@ -0,0 +1,55 @@
+package models
+
+// Discovery is the document served at /.well-known/era.json so partner
+// tooling can configure itself against a deployment. Paths may contain
+// {placeholders} matching the API's path parameters.
+type Discovery struct {
+	Service   string              `json:"service"`
+	Region    string              `json:"region,omitempty"`
+	Instance  string              `json:"instance"`
+	Versions  []APIVersion        `json:"versions"`
+	Elections []DiscoveryElection `json:"elections"`
+	Endpoints map[string]string   `json:"endpoints"`
+	Streams   []DiscoveryStream   `json:"streams"`
+	Auth      DiscoveryAuth       `json:"auth"`
+	Limits    DiscoveryLimits     `json:"rateLimits"`
+}
+
+// APIVersion is a served API version and where it is rooted.
+type APIVersion struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	Status  string `json:"status"` // "stable" or "deprecated"
+}
+
+// DiscoveryElection is an election this deployment publishes. Requests
+// are for the default election unless ?election= names another.
+type DiscoveryElection struct {
+	ID      string `json:"id"`
+	Default bool   `json:"default,omitempty"`
+}
+
+// DiscoveryStream is a feed consumers poll or sync from rather than query.
+type DiscoveryStream struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Format string `json:"format"`
+	// RefreshSeconds is how often the underlying data is refreshed by
+	// default; polling faster gains nothing.
+	RefreshSeconds int `json:"refreshSeconds,omitempty"`
+}
+
+// DiscoveryAuth describes how callers present API keys.
+type DiscoveryAuth struct {
+	Required bool     `json:"required"`
+	Headers  []string `json:"headers"`
+}
+
+// DiscoveryLimits are the limits a client should plan around. Zero means
+// the limit isn't enforced.
+type DiscoveryLimits struct {
+	RequestsPerMinute  int `json:"requestsPerMinute"`
+	ConcurrentJobs     int `json:"concurrentJobs"`
+	MaxRequestSeconds  int `json:"maxRequestSeconds"`
+	MaxAsyncJobSeconds int `json:"maxAsyncJobSeconds"`
+}
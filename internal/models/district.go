@@ -0,0 +1,66 @@
+package models
+
+// District types.
+const (
+	DistrictCongressional = "congressional"
+	DistrictLegislative   = "legislative"
+	DistrictSchoolBoard   = "school-board"
+	DistrictOther         = "other"
+)
+
+// DistrictTypes lists the valid District types.
+var DistrictTypes = []string{DistrictCongressional, DistrictLegislative, DistrictSchoolBoard, DistrictOther}
+
+// District is a district defined by the precincts it is drawn from, whose
+// results are rolled up into its own. Unlike a jurisdiction, which has one
+// parent, a district may cross counties, and a precinct may be in several
+// districts.
+type District struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Type      string             `json:"type"`
+	Precincts []DistrictPrecinct `json:"precincts"`
+}
+
+// DistrictPrecinct is a precinct of a district, named as its county's
+// results name it.
+type DistrictPrecinct struct {
+	County   string `json:"county"`
+	Precinct string `json:"precinct"`
+}
+
+// DistrictImport reports the districts an import added or replaced.
+type DistrictImport struct {
+	Districts []string `json:"districts"` // IDs
+	Precincts int      `json:"precincts"`
+}
+
+// DistrictResults are a district's results in an election, every contest
+// with votes in its precincts rolled up from theirs.
+type DistrictResults struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Election string            `json:"election"`
+	Contests []DistrictContest `json:"contests"`
+
+	// Precincts counts the district's precincts. Unmatched lists those no
+	// county's results have votes for, usually because the source spells
+	// them differently or doesn't report by precinct.
+	Precincts int                `json:"precincts"`
+	Unmatched []DistrictPrecinct `json:"unmatched,omitempty"`
+
+	// Version is the store version every county was read at.
+	Version uint64 `json:"version"`
+}
+
+// DistrictContest is a contest's votes in a district's precincts.
+type DistrictContest struct {
+	ID         string               `json:"id"`
+	Title      string               `json:"title"`
+	Counties   int                  `json:"counties"`
+	Precincts  int                  `json:"precincts"` // the district's precincts with votes in it
+	TotalVotes int                  `json:"totalVotes"`
+	Candidates []AggregateCandidate `json:"candidates"`
+	Outcome    *ContestOutcome      `json:"outcome,omitempty"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Election types.
+const (
+	ElectionPrimary = "primary"
+	ElectionGeneral = "general"
+	ElectionSpecial = "special"
+	ElectionRunoff  = "runoff"
+)
+
+// ElectionTypes are the accepted values of Election.Type.
+var ElectionTypes = []string{ElectionPrimary, ElectionGeneral, ElectionSpecial, ElectionRunoff}
+
+// Election is an election the server publishes results for. County
+// registrations and results belong to one election, so a primary, the
+// general and a special election can be served side by side.
+type Election struct {
+	// ID addresses the election in ?election= parameters; lowercase
+	// letters, digits and dashes.
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	Date string `json:"date,omitempty"` // YYYY-MM-DD
+	Type string `json:"type,omitempty"`
+
+	// Default marks the deployment's election, which requests that name
+	// none read and write.
+	Default bool `json:"default"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	// ArchivedAt is set once the election is archived: its results are
+	// frozen as they were then and stay queryable, but nothing more is
+	// published for it.
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+}
+
+// Archived reports whether e is archived.
+func (e Election) Archived() bool {
+	return e.ArchivedAt != nil
+}
+
+// ElectionResults lists the counties with results in an election, as
+// served for archived elections to compare against.
+type ElectionResults struct {
+	Election Election         `json:"election"`
+	Counties []ElectionCounty `json:"counties"`
+	Page     *Page            `json:"page,omitempty"` // when only a page of the counties was asked for
+}
+
+// ElectionCounty is a county's latest snapshot in an election.
+type ElectionCounty struct {
+	County       string    `json:"county"`
+	SnapshotHash string    `json:"snapshotHash"`
+	ParsedAt     time.Time `json:"parsedAt"`
+	Contests     int       `json:"contests"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Event types.
+const (
+	// EventPublished records a snapshot made live, whether freshly parsed,
+	// replicated from another instance or switched live with a dataset.
+	EventPublished = "snapshot.published"
+	// EventElectionArchived records an election made read-only, its results
+	// frozen as they were. It names no county.
+	EventElectionArchived = "election.archived"
+	// EventCountyCertified records a county's results certified, freezing
+	// its live snapshot.
+	EventCountyCertified = "county.certified"
+)
+
+// Event is an entry in the event log, the source of truth published data is
+// projected from. Events are never changed once appended, so the read
+// models derived from them can be rebuilt with corrected projection code;
+// only a migration of the log's format rewrites them, keeping what they
+// mean.
+type Event struct {
+	Seq    uint64    `json:"seq"`
+	Type   string    `json:"type"`
+	At     time.Time `json:"at"`
+	County string    `json:"county"`
+	Hash   string    `json:"hash"`
+	// Election is empty in events recorded before results were scoped by
+	// election; they belong to the default one.
+	Election string `json:"election,omitempty"`
+
+	// Results is the published snapshot when its content is new to the
+	// county. A snapshot republished unchanged only records when it was
+	// parsed and how long it took, to keep the log from repeating whole
+	// snapshots every refresh.
+	Results  *Results  `json:"results,omitempty"`
+	ParsedAt time.Time `json:"parsedAt"`
+	Latency  *Latency  `json:"latency,omitempty"`
+
+	// Certification is the record of an EventCountyCertified.
+	Certification *Certification `json:"certification,omitempty"`
+}
+
+// RebuildReport describes a rebuild of the read models from the event log.
+type RebuildReport struct {
+	Election string     `json:"election,omitempty"`
+	From     *time.Time `json:"from,omitempty"`
+	// Events is how many events were replayed, Counties the counties whose
+	// read models were rebuilt.
+	Events     int       `json:"events"`
+	Counties   []string  `json:"counties"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// RebuildUpdate is a line of the progress stream of a rebuild: progress
+// while events are replayed, then the report or the error it failed with.
+type RebuildUpdate struct {
+	Done   int            `json:"done"`
+	Total  int            `json:"total"`
+	Report *RebuildReport `json:"report,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
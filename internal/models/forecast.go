@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ContestForecast is a win-probability estimate for a contest produced by an
+// external model. It is reported next to, never instead of, vote totals.
+type ContestForecast struct {
+	ContestID     string           `json:"contestId"`
+	Model         string           `json:"model"`
+	ModelVersion  string           `json:"modelVersion,omitempty"`
+	GeneratedAt   time.Time        `json:"generatedAt"`
+	ReceivedAt    time.Time        `json:"receivedAt"`
+	SnapshotAt    time.Time        `json:"snapshotAt"` // ParsedAt of the results the model saw
+	Probabilities []WinProbability `json:"probabilities"`
+}
+
+// WinProbability is one candidate's estimated chance of winning, from 0 to 1.
+type WinProbability struct {
+	Candidate   string  `json:"candidate"`
+	Probability float64 `json:"probability"`
+}
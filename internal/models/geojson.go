@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FeatureCollection is a contest's results as a GeoJSON (RFC 7946) feature
+// collection, one feature per county, for choropleth maps.
+type FeatureCollection struct {
+	Type      string    `json:"type"` // "FeatureCollection"
+	ContestID string    `json:"contestId"`
+	Title     string    `json:"title"`
+	Features  []Feature `json:"features"`
+
+	// Version is the store version every county was read at.
+	Version uint64 `json:"version"`
+}
+
+// Feature is a county's results in a contest. Its geometry is null: maps
+// join it to their boundaries by its ID, the county's FIPS code if its
+// jurisdiction has one and otherwise its county key, or by the boundary
+// reference in its properties.
+type Feature struct {
+	Type       string            `json:"type"` // "Feature"
+	ID         string            `json:"id"`
+	Geometry   json.RawMessage   `json:"geometry"`
+	Properties FeatureProperties `json:"properties"`
+}
+
+// FeatureProperties are the vote counts a map is shaded by.
+type FeatureProperties struct {
+	County   string `json:"county"`
+	FIPS     string `json:"fips,omitempty"`
+	Boundary string `json:"boundary,omitempty"`
+
+	AsOf               time.Time `json:"asOf"`
+	SnapshotHash       string    `json:"snapshotHash"`
+	TotalVotes         int       `json:"totalVotes"`
+	PrecinctsReporting int       `json:"precinctsReporting"`
+	PrecinctsTotal     int       `json:"precinctsTotal"`
+	ReportingPercent   float64   `json:"reportingPercent"`
+
+	// Leader, its party and its margin over the runner-up, as in the
+	// county's contest outcome.
+	Leader       string  `json:"leader,omitempty"`
+	LeaderParty  string  `json:"leaderParty,omitempty"`
+	Tied         bool    `json:"tied,omitempty"`
+	Margin       int     `json:"margin"`
+	MarginPoints float64 `json:"marginPoints"`
+
+	Candidates []CandidateShare `json:"candidates"`
+}
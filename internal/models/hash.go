@@ -0,0 +1,45 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// SnapshotHash returns a canonical hash of the published content of r, as
+// "sha256:<hex>". It covers everything parsed from the source but not when
+// or how fast it was parsed, the URL it was fetched from, where it is
+// filed, or the data attached afterwards (forecasts, outcomes,
+// jurisdictions, overlays), so two snapshots of unchanged data hash the
+// same, even once a county moves its file, and two consumers can check
+// they hold the same revision. The license is covered: its terms bind what
+// consumers may do with the data, so a change to them is a new revision.
+func SnapshotHash(r *Results) string {
+	c := *r
+	c.ParsedAt = time.Time{}
+	c.Source = ""
+	c.Hash = ""
+	c.Election = ""
+	c.Jurisdiction = ""
+	c.Latency = nil
+	c.Anomalies = nil
+	c.Page = nil
+	c.Contests = make([]Contest, len(r.Contests))
+	for i, contest := range r.Contests {
+		contest.Forecast = nil
+		contest.Outcome = nil
+		contest.Jurisdiction = ""
+		contest.Overlays = nil
+		c.Contests[i] = contest
+	}
+
+	// encoding/json writes struct fields in declaration order and sorts map
+	// keys, so the encoding of a given value is stable.
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,62 @@
+package models
+
+// Jurisdiction types, from the top of the tree down.
+const (
+	JurisdictionState          = "state"
+	JurisdictionCounty         = "county"
+	JurisdictionCity           = "city"
+	JurisdictionSchoolDistrict = "school-district"
+	JurisdictionDistrict       = "district" // any other special district
+	JurisdictionPrecinct       = "precinct"
+)
+
+// JurisdictionTypes lists the valid Jurisdiction types.
+var JurisdictionTypes = []string{
+	JurisdictionState, JurisdictionCounty, JurisdictionCity,
+	JurisdictionSchoolDistrict, JurisdictionDistrict, JurisdictionPrecinct,
+}
+
+// JurisdictionLevel returns how deep jurisdictions of type t sit in the
+// tree: 0 for a state, 1 for a county, 2 for cities and districts and 3
+// for precincts. A child is always at a deeper level than its parent.
+func JurisdictionLevel(t string) int {
+	switch t {
+	case JurisdictionState:
+		return 0
+	case JurisdictionCounty:
+		return 1
+	case JurisdictionPrecinct:
+		return 3
+	}
+	return 2
+}
+
+// Jurisdiction is a node of the tree contests are held in: a state, its
+// counties, the cities and districts within them, and their precincts. A
+// county's ID is its county key, so it names the same county results are
+// published under.
+type Jurisdiction struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Parent string `json:"parent,omitempty"` // empty for a root
+
+	// FIPS is the jurisdiction's Census code: 2 digits for a state, 5 for
+	// a county, the code of its GEOID for anything else. Boundary refers
+	// to its geometry, such as the URL of a GeoJSON file or an ID in a
+	// boundary set maps already hold, for maps to join results to.
+	FIPS     string `json:"fips,omitempty"`
+	Boundary string `json:"boundary,omitempty"`
+}
+
+// JurisdictionView is a jurisdiction with where it sits in the tree and
+// the contests held at its level.
+type JurisdictionView struct {
+	Jurisdiction
+	// Path lists its ancestors, root first.
+	Path     []Jurisdiction `json:"path"`
+	Children []Jurisdiction `json:"children"`
+	// Contests are the IDs of the contests attached to it in the election
+	// asked for.
+	Contests []string `json:"contests"`
+}
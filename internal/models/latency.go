@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Latency traces a snapshot from the upstream change to its source file to
+// its publication in the API. Durations are in milliseconds; those measured
+// from the source's modification time are only set when the county's server
+// sends Last-Modified, and are negative if its clock runs ahead of ours.
+type Latency struct {
+	SourceModifiedAt *time.Time `json:"sourceModifiedAt,omitempty"`
+	FetchStartedAt   time.Time  `json:"fetchStartedAt"`
+	FetchedAt        time.Time  `json:"fetchedAt"`
+	PublishedAt      time.Time  `json:"publishedAt,omitzero"`
+
+	// DetectMs runs from the source's modification to the start of the fetch
+	// that picked it up, so it mostly reflects the refresh interval.
+	DetectMs *int64 `json:"detectMs,omitempty"`
+	FetchMs  int64  `json:"fetchMs"`
+	// ProcessMs runs from the end of the fetch to publication: parsing,
+	// checks, rendering and, for released snapshots, time held for review.
+	ProcessMs       int64  `json:"processMs"`
+	PostToPublishMs *int64 `json:"postToPublishMs,omitempty"`
+}
+
+// Publish stamps l as published at at and derives its stage durations.
+func (l *Latency) Publish(at time.Time) {
+	l.PublishedAt = at
+	l.FetchMs = l.FetchedAt.Sub(l.FetchStartedAt).Milliseconds()
+	l.ProcessMs = at.Sub(l.FetchedAt).Milliseconds()
+	if l.SourceModifiedAt != nil {
+		detect := l.FetchStartedAt.Sub(*l.SourceModifiedAt).Milliseconds()
+		total := at.Sub(*l.SourceModifiedAt).Milliseconds()
+		l.DetectMs, l.PostToPublishMs = &detect, &total
+	}
+}
+
+// LatencySample is the latency of one published snapshot with new content.
+type LatencySample struct {
+	County       string `json:"county"`
+	SnapshotHash string `json:"snapshotHash"`
+	Latency
+}
+
+// LatencyStats are percentiles of one latency measure, in milliseconds.
+type LatencyStats struct {
+	Count int   `json:"count"`
+	P50   int64 `json:"p50"`
+	P90   int64 `json:"p90"`
+	P99   int64 `json:"p99"`
+	Max   int64 `json:"max"`
+}
+
+// LatencySummary describes the latency of a county's recent snapshots, or
+// of every county's when County is empty.
+type LatencySummary struct {
+	County        string          `json:"county,omitempty"`
+	Snapshots     int             `json:"snapshots"`
+	PostToPublish LatencyStats    `json:"postToPublish"`
+	Detect        LatencyStats    `json:"detect"`
+	Fetch         LatencyStats    `json:"fetch"`
+	Process       LatencyStats    `json:"process"`
+	Samples       []LatencySample `json:"samples,omitempty"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// SourceLayout is the structural signature of a source: the CSV header rows,
+// XML element paths and HTML table shapes its results were read from, with
+// none of the values. Hash changes when a county changes its format, even
+// if the new format still parses.
+type SourceLayout struct {
+	Hash     string   `json:"hash"`
+	Features []string `json:"features"`
+}
+
+// LayoutChange records a fetch whose source layout differed from the
+// county's previous fetch.
+type LayoutChange struct {
+	County       string    `json:"county"`
+	Source       string    `json:"source"`
+	DetectedAt   time.Time `json:"detectedAt"`
+	PreviousHash string    `json:"previousHash"`
+	Hash         string    `json:"hash"`
+	Added        []string  `json:"added"`
+	Removed      []string  `json:"removed"`
+}
+
+// ParserConfig is the part of a process request that decides how a source
+// is read. Each distinct config that published a county's snapshot is kept
+// as a numbered revision, so a parse broken by a layout change can be
+// retried with one that worked before.
+type ParserConfig struct {
+	Revision         int       `json:"revision"`
+	ParseMethod      string    `json:"parseMethod"`
+	ContentType      string    `json:"contentType,omitempty"`
+	MeasureThreshold string    `json:"measureThreshold,omitempty"`
+	PublishedAt      time.Time `json:"publishedAt"`
+}
+
+// ParserConfigOf returns the parser config of req, without a revision.
+func ParserConfigOf(req ProcessRequest) ParserConfig {
+	return ParserConfig{ParseMethod: req.ParseMethod, ContentType: req.ContentType, MeasureThreshold: req.MeasureThreshold}
+}
+
+// Same reports whether c and o read a source the same way.
+func (c ParserConfig) Same(o ParserConfig) bool {
+	return strings.EqualFold(c.ParseMethod, o.ParseMethod) && c.ContentType == o.ContentType && c.MeasureThreshold == o.MeasureThreshold
+}
+
+// Apply returns req reading its source with c.
+func (c ParserConfig) Apply(req ProcessRequest) ProcessRequest {
+	req.ParseMethod = c.ParseMethod
+	req.ContentType = c.ContentType
+	req.MeasureThreshold = c.MeasureThreshold
+	return req
+}
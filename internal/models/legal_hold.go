@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// LegalHold preserves an election's data, or one county's in it, while it
+// is in litigation or a recount: nothing it covers is pruned or purged
+// until the hold is released. Released holds are kept as a record.
+type LegalHold struct {
+	ID       string `json:"id"`
+	Election string `json:"election"`
+	County   string `json:"county,omitempty"` // empty holds the whole election
+	Reason   string `json:"reason"`
+
+	PlacedBy string    `json:"placedBy,omitempty"` // API key ID
+	PlacedAt time.Time `json:"placedAt"`
+
+	ReleasedBy string     `json:"releasedBy,omitempty"`
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+}
+
+// Active reports whether h is still in force.
+func (h LegalHold) Active() bool {
+	return h.ReleasedAt == nil
+}
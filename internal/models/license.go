@@ -0,0 +1,18 @@
+package models
+
+// Redistribution terms for License.
+const (
+	RedistributionUnrestricted = "unrestricted"
+	RedistributionAttribution  = "attribution-required"
+	RedistributionRestricted   = "restricted"
+)
+
+// License describes the terms under which a source's results may be
+// redistributed. AP-sourced and county-direct data usually differ, so it is
+// carried with the results into every response and export.
+type License struct {
+	Name           string `json:"name"`
+	Attribution    string `json:"attribution"`
+	URL            string `json:"url,omitempty"`
+	Redistribution string `json:"redistribution,omitempty"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Manifest is the ballot an election is expected to have, imported before
+// results come in so parsed snapshots can be checked against it.
+type Manifest struct {
+	Election   string            `json:"election"`
+	Source     string            `json:"source,omitempty"` // file or URL it was imported from
+	ImportedAt time.Time         `json:"importedAt"`
+	Contests   []ManifestContest `json:"contests"`
+}
+
+// ManifestContest is one expected contest. Its ID is matched against the
+// contest IDs of parsed results, so it should be the canonical ID contest
+// rules map county titles onto.
+type ManifestContest struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Type     string `json:"type,omitempty"` // "candidate" (default) or "measure"
+	District string `json:"district,omitempty"`
+	// Counties lists the counties the contest is on the ballot in. Empty
+	// means every county.
+	Counties   []string            `json:"counties,omitempty"`
+	Candidates []ManifestCandidate `json:"candidates,omitempty"`
+}
+
+// ManifestCandidate is a candidate expected on a contest's ballot.
+type ManifestCandidate struct {
+	Name  string `json:"name"`
+	Party string `json:"party,omitempty"`
+}
+
+// ManifestCheck compares a county's published snapshot with the manifest.
+type ManifestCheck struct {
+	County       string    `json:"county"`
+	Election     string    `json:"election"`
+	SnapshotHash string    `json:"snapshotHash,omitempty"`
+	CheckedAt    time.Time `json:"checkedAt"`
+
+	// OK reports that every expected contest was found with the expected
+	// candidates and nothing else.
+	OK       bool `json:"ok"`
+	Expected int  `json:"expected"`
+
+	// Missing are expected contests absent from the snapshot, Unexpected
+	// contests in the snapshot the manifest doesn't list for the county.
+	Missing    []ManifestContestRef `json:"missing,omitempty"`
+	Unexpected []ManifestContestRef `json:"unexpected,omitempty"`
+	Candidates []CandidateMismatch  `json:"candidates,omitempty"`
+}
+
+// ManifestContestRef names a contest in a ManifestCheck.
+type ManifestContestRef struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// CandidateMismatch lists the candidates of a contest that differ from the
+// manifest's. Write-ins are never unexpected.
+type CandidateMismatch struct {
+	ContestID  string   `json:"contestId"`
+	Missing    []string `json:"missing,omitempty"`
+	Unexpected []string `json:"unexpected,omitempty"`
+}
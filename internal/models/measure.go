@@ -0,0 +1,20 @@
+package models
+
+// Named vote thresholds a measure may require. Thresholds may also be given
+// as a fraction ("2/3") or a percentage ("55%").
+const (
+	ThresholdMajority    = "majority"
+	ThresholdThreeFifths = "three-fifths"
+	ThresholdTwoThirds   = "two-thirds"
+)
+
+// MeasureResult is the Yes/No outcome of a ballot measure or proposition.
+type MeasureResult struct {
+	Yes        int     `json:"yes"`
+	No         int     `json:"no"`
+	YesPercent float64 `json:"yesPercent"`
+	Threshold  string  `json:"threshold"`
+	// Passing reports whether the current count meets the threshold. It is
+	// not a call: the count may still change.
+	Passing bool `json:"passing"`
+}
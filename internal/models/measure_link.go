@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// MeasureLink joins the contests several counties report one regional
+// measure as, each under its own letter or number, so it aggregates into
+// one result under the link's ID.
+type MeasureLink struct {
+	ID        string              `json:"id"` // canonical contest ID
+	Election  string              `json:"election"`
+	Title     string              `json:"title"`
+	Members   []MeasureLinkMember `json:"members"`
+	CreatedAt time.Time           `json:"createdAt"`
+}
+
+// Member returns the contest county reports the measure as.
+func (l MeasureLink) Member(county string) (MeasureLinkMember, bool) {
+	for _, m := range l.Members {
+		if Slug(m.County) == Slug(county) {
+			return m, true
+		}
+	}
+	return MeasureLinkMember{}, false
+}
+
+// MeasureLinkMember is one county's contest in a MeasureLink.
+type MeasureLinkMember struct {
+	County    string `json:"county"`
+	ContestID string `json:"contestId"`
+	Title     string `json:"title,omitempty"`
+}
+
+// MeasureLinkSuggestion proposes a MeasureLink for measures in different
+// counties whose text reads alike once their letters and numbers are set
+// aside. Score is the lowest similarity of a member to the first.
+type MeasureLinkSuggestion struct {
+	ID      string              `json:"id"`
+	Title   string              `json:"title"`
+	Score   float64             `json:"score"`
+	Members []MeasureLinkMember `json:"members"`
+}
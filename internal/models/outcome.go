@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Race call methods.
+const (
+	CallThreshold = "threshold" // the count met the configured OutcomeRules
+	CallManual    = "manual"    // an editor called it
+)
+
+// OutcomeRules set when a contest is called from its count alone.
+type OutcomeRules struct {
+	// MinReportingPercent is the share of precincts that must be reporting.
+	// Contests that don't report precincts are never called on count.
+	MinReportingPercent float64 `json:"minReportingPercent"`
+	// MinMarginPoints is how many percentage points the leader must be
+	// ahead by.
+	MinMarginPoints float64 `json:"minMarginPoints"`
+}
+
+// DefaultOutcomeRules call a contest once every precinct reports and the
+// leader is ahead by at least the half point many states recount within.
+var DefaultOutcomeRules = OutcomeRules{MinReportingPercent: 100, MinMarginPoints: 0.5}
+
+// ContestOutcome is what a contest's count currently says: shares, leader,
+// margin and whether the race is called. It is derived when results are
+// served, so every consumer gets the same math and the same call.
+type ContestOutcome struct {
+	TotalVotes       int     `json:"totalVotes"`
+	ReportingPercent float64 `json:"reportingPercent"`
+
+	// Shares are the candidates' percentages of TotalVotes, in ballot
+	// order, rounded to two places.
+	Shares []CandidateShare `json:"shares"`
+
+	// Leader has the most votes, or for ranked-choice contests the most in
+	// the latest round, and is empty with no votes or a tie for first.
+	// Margin is the lead over the runner-up, in votes and in points.
+	Leader       string  `json:"leader,omitempty"`
+	Tied         bool    `json:"tied,omitempty"`
+	Margin       int     `json:"margin"`
+	MarginPoints float64 `json:"marginPoints"`
+
+	// Call is set once the race is called.
+	Call *RaceCall `json:"call,omitempty"`
+}
+
+// CandidateShare is a candidate's share of a contest's votes.
+type CandidateShare struct {
+	Name    string  `json:"name"`
+	Votes   int     `json:"votes"`
+	Percent float64 `json:"percent"`
+}
+
+// RaceCall declares the winner of a contest: a candidate, or for measures
+// "Yes" or "No".
+type RaceCall struct {
+	Winner   string     `json:"winner"`
+	Method   string     `json:"method"`
+	CalledBy string     `json:"calledBy,omitempty"` // API key ID of a manual call
+	CalledAt *time.Time `json:"calledAt,omitempty"`
+}
+
+// ManualCall is an editor's call of a contest across an election. It
+// overrides whatever the count says until it is retracted.
+type ManualCall struct {
+	Election  string `json:"election"`
+	ContestID string `json:"contestId"`
+	RaceCall
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Overlay kinds for supplementary, non-official datasets.
+const (
+	OverlayExitPoll = "exit_poll"
+	OverlayForecast = "forecast"
+	OverlayOther    = "other"
+)
+
+// Overlay is a supplementary dataset (exit poll, pre-election forecast, ...)
+// attached to a contest. Overlays are always labeled unofficial and are kept
+// apart from the contest's candidate totals.
+type Overlay struct {
+	ID          string         `json:"id"`
+	County      string         `json:"county"`
+	ContestID   string         `json:"contestId"`
+	Kind        string         `json:"kind"`
+	Label       string         `json:"label"`
+	Source      string         `json:"source"`
+	Official    bool           `json:"official"`
+	CollectedAt *time.Time     `json:"collectedAt,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	Notes       string         `json:"notes,omitempty"`
+	Entries     []OverlayEntry `json:"entries"`
+}
+
+// OverlayEntry is one candidate's (or choice's) figure in an overlay.
+type OverlayEntry struct {
+	Candidate     string   `json:"candidate"`
+	Share         float64  `json:"share"` // percent, 0-100
+	MarginOfError *float64 `json:"marginOfError,omitempty"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Parse error statuses.
+const (
+	ParseErrorOpen     = "open"
+	ParseErrorResolved = "resolved"
+)
+
+// ParseError is a fetched source that failed to parse, kept with the file
+// so it can be downloaded for debugging and retried once the parser or the
+// county's configuration is fixed. Repeated failures on the same file are
+// folded into one record.
+type ParseError struct {
+	ID          string `json:"id"`
+	County      string `json:"county"`
+	FileLink    string `json:"fileLink"`
+	ParseMethod string `json:"parseMethod"`
+	Error       string `json:"error"`
+	Status      string `json:"status"`
+
+	SourceHash  string    `json:"sourceHash"` // sha256 of the file
+	Size        int       `json:"size"`
+	Occurrences int       `json:"occurrences"`
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	LastSeenAt  time.Time `json:"lastSeenAt"`
+
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	// SnapshotHash is the snapshot published by the retry that resolved
+	// the record, unless it was quarantined instead.
+	SnapshotHash string `json:"snapshotHash,omitempty"`
+
+	// Sample is what the parser read of the file before failing.
+	Sample *SourceSample `json:"sample,omitempty"`
+
+	// Request is what the file was fetched and parsed with; Source is the
+	// file, dropped once the record is resolved unless a legal hold
+	// preserves it.
+	Request ProcessRequest `json:"-"`
+	Source  []byte         `json:"-"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// Capabilities a parser can declare in ParserInfo.
+const (
+	ParserTurnout = "turnout" // reads registration and ballots cast
+	ParserRCV     = "rcv"     // reads ranked-choice rounds
+	ParserArchive = "archive" // reads sources packed in other formats
+	ParserDetect  = "detect"  // recognises its format from the content
+)
+
+// ParserInfo describes a parse method, as listed at /api/v1/parsers.
+type ParserInfo struct {
+	Method      string `json:"method"`
+	Description string `json:"description"`
+	// Extensions are the file extensions naming sources in the format,
+	// canonical one first.
+	Extensions   []string `json:"extensions"`
+	Capabilities []string `json:"capabilities"`
+	// Version goes up when a change to the parser makes it read the same
+	// source differently.
+	Version int `json:"version"`
+}
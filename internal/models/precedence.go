@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Source levels, for ProcessRequest.SourceLevel and CountySource.Precedence.
+// Deployments may name others, such as "ap" for a wire service.
+const (
+	SourceLevelCounty = "county" // the county's own source
+	SourceLevelState  = "state"  // a secretary of state's aggregate feed
+)
+
+// DefaultPrecedence is the order source levels are published in when the
+// deployment doesn't set one: the county's own source, then the state's.
+var DefaultPrecedence = []string{SourceLevelCounty, SourceLevelState}
+
+// ConflictSource is one source level's latest snapshot of a county.
+type ConflictSource struct {
+	Level     string    `json:"level"`
+	Source    string    `json:"source"`
+	ParsedAt  time.Time `json:"parsedAt"`
+	Hash      string    `json:"hash"`
+	Published bool      `json:"published"`
+}
+
+// ConflictCandidate is a candidate whose votes differ between sources.
+type ConflictCandidate struct {
+	Name string `json:"name"`
+	// Votes holds each source level's count; a level that doesn't list
+	// the candidate is left out.
+	Votes map[string]int `json:"votes"`
+	// Spread is the difference between the highest and lowest count,
+	// counting a level that doesn't list the candidate as zero.
+	Spread int `json:"spread"`
+}
+
+// SourceConflict is a contest that sources of one county report
+// differently, by more than the tolerance asked for.
+type SourceConflict struct {
+	County  string `json:"county"`
+	Contest string `json:"contestId"`
+	Title   string `json:"title"`
+
+	// Precedence is the county's order of source levels, most trusted
+	// first; Published is the level whose numbers are published.
+	Precedence []string `json:"precedence"`
+	Published  string   `json:"published"`
+	Reason     string   `json:"reason"`
+
+	Sources    []ConflictSource    `json:"sources"`
+	Candidates []ConflictCandidate `json:"candidates"`
+}
+
+// ConflictsResponse is returned by GET /api/v1/conflicts.
+type ConflictsResponse struct {
+	Election string `json:"election"`
+	// Tolerance is the spread allowed before counts conflict, in votes
+	// ("25") or as a percentage of the highest count ("0.5%").
+	Tolerance string           `json:"tolerance"`
+	Conflicts []SourceConflict `json:"conflicts"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// Problem types identify the kind of a Problem independently of its
+// human-readable title.
+const (
+	ProblemInvalidRequest = "urn:era:problem:invalid-request"
+	ProblemMalformedBody  = "urn:era:problem:malformed-body"
+)
+
+// Problem is an RFC 7807 problem details body, served as
+// application/problem+json when a request is rejected before it's acted on.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+
+	// Instance is the path of the rejected request.
+	Instance string `json:"instance,omitempty"`
+
+	// Errors lists every invalid field, so a client can fix them all at
+	// once.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is one invalid field of a request body. Field is its JSON
+// path, such as "fileLink" or "contests[2].county".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
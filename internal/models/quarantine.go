@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// SourceSample is the raw tabular shape of a parsed source: each table's
+// header and leading rows exactly as extracted, before they were read as
+// candidates. Samples of two snapshots are diffed to explain layout changes.
+type SourceSample struct {
+	Tables []SampleTable `json:"tables"`
+
+	// Layout is the source's structural signature, if its format has one.
+	Layout *SourceLayout `json:"layout,omitempty"`
+}
+
+// SampleTable is one table of a source: an HTML table, or a CSV file inside
+// an archive. Rows holds at most the first few rows; RowCount counts all.
+type SampleTable struct {
+	Name     string     `json:"name"`
+	Header   []string   `json:"header,omitempty"`
+	Rows     [][]string `json:"rows,omitempty"`
+	RowCount int        `json:"rowCount"`
+}
+
+// Kinds of MappingSuggestion.
+const (
+	DriftColumnAdded   = "column-added"
+	DriftColumnRemoved = "column-removed"
+	DriftHeaderRenamed = "header-renamed"
+	DriftColumnShifted = "column-shifted"
+	DriftTableAdded    = "table-added"
+	DriftTableRemoved  = "table-removed"
+	DriftTableRenamed  = "table-renamed"
+)
+
+// MappingSuggestion is a likely cause of a drifted snapshot found by diffing
+// its source sample against the previous one.
+type MappingSuggestion struct {
+	Kind    string `json:"kind"`
+	Table   string `json:"table"`
+	Message string `json:"message"`
+}
+
+// Quarantine statuses.
+const (
+	QuarantineHeld     = "held"
+	QuarantineReleased = "released"
+)
+
+// QuarantineRecord is a snapshot held back from publishing because it
+// differed too much from the previous one. It can be released to publish it
+// anyway or discarded.
+type QuarantineRecord struct {
+	ID           string              `json:"id"`
+	County       string              `json:"county"`
+	Status       string              `json:"status"`
+	Reasons      []string            `json:"reasons"`
+	CreatedAt    time.Time           `json:"createdAt"`
+	PreviousHash string              `json:"previousHash"`
+	Suggestions  []MappingSuggestion `json:"suggestions"`
+	Results      *Results            `json:"results,omitempty"`
+
+	// Request and Source are what's needed to publish the snapshot on
+	// release; Sample becomes the county's baseline when it is.
+	Request ProcessRequest `json:"-"`
+	Source  []byte         `json:"-"`
+	Sample  *SourceSample  `json:"-"`
+}
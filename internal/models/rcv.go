@@ -0,0 +1,57 @@
+package models
+
+// RCV candidate statuses within a round.
+const (
+	RCVContinuing = "continuing"
+	RCVEliminated = "eliminated"
+	RCVElected    = "elected"
+)
+
+// RCVTabulation is the round-by-round count of a ranked-choice contest.
+// Contest.Candidates carries the first-choice totals; the later rounds live
+// here.
+type RCVTabulation struct {
+	Rounds    []RCVRound `json:"rounds"`
+	Winner    string     `json:"winner,omitempty"`
+	Threshold int        `json:"threshold"` // votes needed to win the final round
+	Final     bool       `json:"final"`     // true once a candidate passed the threshold
+}
+
+// RCVRound is one round of tabulation.
+type RCVRound struct {
+	Round      int        `json:"round"`
+	Tallies    []RCVTally `json:"tallies"`
+	Eliminated []string   `json:"eliminated,omitempty"`
+	Exhausted  int        `json:"exhausted"`
+}
+
+// RCVTally is a candidate's count in a round. Transfer is the change from the
+// previous round.
+type RCVTally struct {
+	Candidate string `json:"candidate"`
+	Votes     int    `json:"votes"`
+	Transfer  int    `json:"transfer"`
+	Status    string `json:"status"`
+}
+
+// RenameCandidate replaces a candidate's name throughout the tabulation.
+func (t *RCVTabulation) RenameCandidate(from, to string) {
+	if t == nil {
+		return
+	}
+	for i := range t.Rounds {
+		for j := range t.Rounds[i].Tallies {
+			if t.Rounds[i].Tallies[j].Candidate == from {
+				t.Rounds[i].Tallies[j].Candidate = to
+			}
+		}
+		for j, name := range t.Rounds[i].Eliminated {
+			if name == from {
+				t.Rounds[i].Eliminated[j] = to
+			}
+		}
+	}
+	if t.Winner == from {
+		t.Winner = to
+	}
+}
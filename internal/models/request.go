@@ -0,0 +1,84 @@
+package models
+
+// Accepted values for ProcessRequest.ContentType.
+const (
+	ContentTypeCandidate = "candidate"
+	ContentTypeMeasure   = "measure"
+)
+
+// Accepted values for ProcessRequest.ParseMethod.
+const (
+	ParseMethodZIP  = "zip"
+	ParseMethodHTML = "html"
+	ParseMethodPDF  = "pdf"
+	ParseMethodXML  = "xml"
+	ParseMethodCSV  = "csv"
+	ParseMethodJSON = "json"
+	// ParseMethodAuto picks one of the others by the content of each
+	// source fetched, its Content-Type and its file name.
+	ParseMethodAuto = "auto"
+)
+
+// ProcessRequest is the body accepted by POST /api/v1/process.
+type ProcessRequest struct {
+	CountyName string `json:"countyName"`
+
+	// Jurisdiction is the ID of the jurisdiction the source reports for, a
+	// county or one within a county. Results are published under its
+	// county, so countyName may be left out when it is set.
+	Jurisdiction string `json:"jurisdiction,omitempty"`
+
+	FileLink    string `json:"fileLink"`
+	ContentType string `json:"contentType"` // "candidate" or "measure"
+	ParseMethod string `json:"parseMethod"` // "zip", "html", "pdf", "xml", "csv", "json", another registered one (see /api/v1/parsers), or "auto"
+
+	// Election is the ID of the election the results are published for;
+	// empty means the default one. It can also be set with ?election=.
+	Election string `json:"election,omitempty"`
+
+	// Async makes the server answer 202 Accepted with a job ID instead of
+	// waiting for the parse to finish. It can also be set with ?async=true.
+	Async bool `json:"async,omitempty"`
+
+	// License overrides the deployment's default data license for this source.
+	License *License `json:"license,omitempty"`
+
+	// MeasureThreshold is the vote threshold measures in this source need to
+	// pass, e.g. "majority" (the default), "two-thirds" or "55%".
+	MeasureThreshold string `json:"measureThreshold,omitempty"`
+
+	// Browser fetches fileLink by loading it in a headless browser, for
+	// pages that render their results with JavaScript. It needs the
+	// deployment to have a browser configured.
+	Browser *BrowserFetch `json:"browser,omitempty"`
+
+	// SourceLevel is the kind of source reporting the county, deciding
+	// which of several sources is published: SourceLevelCounty, the
+	// default, SourceLevelState, or another level such as "ap" for a wire
+	// service.
+	SourceLevel string `json:"sourceLevel,omitempty"`
+
+	// Dataset stages the parsed results under this label instead of
+	// publishing them; see Dataset. It can also be set with ?dataset=.
+	Dataset string `json:"dataset,omitempty"`
+
+	// Debug records the parser's intermediate decisions (detected headers,
+	// matched rows, skipped lines and why) in the response or job. It can
+	// also be set with ?debug=true.
+	Debug bool `json:"debug,omitempty"`
+
+	// DryRun parses the source and returns the results with the warnings
+	// publishing them would raise, but publishes, stages and records
+	// nothing and notifies no webhooks, for checking a new county config.
+	// It can also be set with ?dryRun=true.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// BrowserFetch configures how a headless browser reads a page.
+type BrowserFetch struct {
+	// Capture returns the body of the first response the page loads whose
+	// URL matches this glob, such as "*/api/results*", instead of the
+	// rendered DOM, for pages that fetch their results as JSON. * matches
+	// any run of characters.
+	Capture string `json:"capture,omitempty"`
+}
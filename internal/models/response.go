@@ -0,0 +1,246 @@
+package models
+
+import "time"
+
+// ProcessResponse is the body returned by a synchronous process request and
+// stored as the result of an asynchronous job.
+type ProcessResponse struct {
+	HTML    string   `json:"html"`
+	Error   *string  `json:"error"`
+	Results *Results `json:"results,omitempty"`
+
+	// Trace is the parser's decision log, included when the request set
+	// Debug. It is kept on failed jobs too, since that's when it's needed.
+	Trace []TraceEntry `json:"trace,omitempty"`
+
+	// Warnings flag a snapshot that was published but needs attention, such
+	// as one read with a fallback parser config, or for a dry run what
+	// publishing it would have flagged.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// DryRun is set on the response to a dry run, whose results weren't
+	// published.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Unchanged is set when the source's results were those the county had
+	// already published, which are returned and weren't published again.
+	Unchanged bool `json:"unchanged,omitempty"`
+}
+
+// Results holds everything extracted from a single county source file.
+type Results struct {
+	County       string    `json:"county"`
+	Election     string    `json:"election,omitempty"`
+	Jurisdiction string    `json:"jurisdiction,omitempty"` // what the source reported for, if the request named it
+	ContentType  string    `json:"contentType"`
+	Source       string    `json:"source"`
+	SourceLevel  string    `json:"sourceLevel,omitempty"` // e.g. "state"; empty for the county's own source
+	ParsedAt     time.Time `json:"parsedAt"`
+	License      *License  `json:"license,omitempty"`
+	Turnout      *Turnout  `json:"turnout,omitempty"`
+	Contests     []Contest `json:"contests"`
+
+	// Hash is the SnapshotHash of this snapshot.
+	Hash string `json:"hash"`
+
+	// Latency is how long the snapshot took to reach the API from the
+	// change to its source.
+	Latency *Latency `json:"latency,omitempty"`
+
+	// Anomalies are the integrity checks this snapshot failed.
+	Anomalies []Anomaly `json:"anomalies,omitempty"`
+
+	// Page is set when only a page of the contests was asked for.
+	Page *Page `json:"page,omitempty"`
+}
+
+// Page is where a page of a list falls in the whole of it: the offset and
+// limit it was read with, the list's length and, if more follow, the
+// offset of the next page.
+type Page struct {
+	Offset     int `json:"offset"`
+	Limit      int `json:"limit"`
+	Total      int `json:"total"`
+	NextOffset int `json:"nextOffset,omitempty"`
+}
+
+// Contest is a single race or measure with its vote totals.
+type Contest struct {
+	ID         string      `json:"id"`
+	Title      string      `json:"title"`
+	RawTitle   string      `json:"rawTitle,omitempty"` // county's title when mapped to a canonical one
+	Candidates []Candidate `json:"candidates"`
+
+	PrecinctsReporting int `json:"precinctsReporting,omitempty"`
+	PrecinctsTotal     int `json:"precinctsTotal,omitempty"`
+
+	// RCV is set for ranked-choice contests.
+	RCV *RCVTabulation `json:"rcv,omitempty"`
+
+	// Measure is set for ballot measures and propositions.
+	Measure *MeasureResult `json:"measure,omitempty"`
+
+	// Forecast is the latest external win-probability estimate, if any.
+	Forecast *ContestForecast `json:"forecast,omitempty"`
+
+	// Outcome is derived from the count when the contest is served.
+	Outcome *ContestOutcome `json:"outcome,omitempty"`
+
+	// Jurisdiction is the ID of the jurisdiction the contest is held in,
+	// set when it is served if the contest was attached to one.
+	Jurisdiction string `json:"jurisdiction,omitempty"`
+
+	// Overlays are only populated when requested with ?include=overlays.
+	Overlays []Overlay `json:"overlays,omitempty"`
+}
+
+// Candidate is a single choice within a contest.
+type Candidate struct {
+	Name  string `json:"name"`
+	Party string `json:"party,omitempty"`
+	Votes int    `json:"votes"`
+
+	// CanonicalID links the candidate to the candidate registry. RawName
+	// keeps the county's spelling when the name was replaced by the
+	// canonical one.
+	CanonicalID string `json:"canonicalId,omitempty"`
+	RawName     string `json:"rawName,omitempty"`
+
+	// WriteIn marks write-in candidates. WriteInAggregate marks the combined
+	// total of uncertified write-ins, which isn't a person.
+	WriteIn          bool `json:"writeIn,omitempty"`
+	WriteInAggregate bool `json:"writeInAggregate,omitempty"`
+
+	// Precincts are the candidate's votes by precinct, from sources that
+	// report them; districts are rolled up from them.
+	Precincts []PrecinctVotes `json:"precincts,omitempty"`
+}
+
+// PrecinctVotes is a candidate's votes in one precinct.
+type PrecinctVotes struct {
+	Name  string `json:"name"`
+	Votes int    `json:"votes"`
+}
+
+// TotalVotes sums the votes of every candidate in the contest.
+func (c Contest) TotalVotes() int {
+	total := 0
+	for _, cand := range c.Candidates {
+		total += cand.Votes
+	}
+	return total
+}
+
+// ContestByID returns the contest with the given ID.
+func (r *Results) ContestByID(id string) (*Contest, bool) {
+	for i := range r.Contests {
+		if r.Contests[i].ID == id {
+			return &r.Contests[i], true
+		}
+	}
+	return nil, false
+}
+
+// Level returns the source level r came from, SourceLevelCounty if it
+// came from the county's own source.
+func (r *Results) Level() string {
+	if r.SourceLevel == "" {
+		return SourceLevelCounty
+	}
+	return r.SourceLevel
+}
+
+// JobStatus is the lifecycle state of an asynchronous processing job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// JobProgress describes how far a running job has got.
+type JobProgress struct {
+	Stage   string `json:"stage"`
+	Percent int    `json:"percent"`
+}
+
+// Job is the representation returned by GET /api/v1/jobs/{id}.
+type Job struct {
+	ID         string           `json:"id"`
+	Status     JobStatus        `json:"status"`
+	Progress   JobProgress      `json:"progress"`
+	Error      string           `json:"error,omitempty"`
+	Result     *ProcessResponse `json:"result,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	StartedAt  *time.Time       `json:"startedAt,omitempty"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+
+	// RequestID is the X-Request-ID of the request that submitted the job.
+	RequestID string `json:"requestId,omitempty"`
+	// ResumedAt is when the job was queued again after a restart cut it
+	// short.
+	ResumedAt *time.Time `json:"resumedAt,omitempty"`
+}
+
+// JobAccepted is the body returned with 202 Accepted for an async request.
+// Joined is set when the same request was already queued or running, and
+// the job is that one's.
+type JobAccepted struct {
+	JobID     string    `json:"jobId"`
+	Status    JobStatus `json:"status"`
+	StatusURL string    `json:"statusUrl"`
+	Joined    bool      `json:"joined,omitempty"`
+}
+
+// BatchProcessResponse is the body returned by POST /api/v1/process/batch:
+// how each of the requests went, in the order they were given.
+type BatchProcessResponse struct {
+	Items     []BatchItem `json:"items"`
+	Succeeded int         `json:"succeeded"`
+	Failed    int         `json:"failed"`
+}
+
+// BatchItem is how one request of a batch went. Status is what POST
+// /api/v1/process would have answered it with.
+type BatchItem struct {
+	Index        int      `json:"index"`
+	CountyName   string   `json:"countyName"`
+	Election     string   `json:"election,omitempty"`
+	Status       int      `json:"status"`
+	Error        string   `json:"error,omitempty"`
+	SnapshotHash string   `json:"snapshotHash,omitempty"`
+	Contests     int      `json:"contests,omitempty"`
+	Warnings     []string `json:"warnings,omitempty"`
+	DryRun       bool     `json:"dryRun,omitempty"`
+	Unchanged    bool     `json:"unchanged,omitempty"`
+	DurationMs   int64    `json:"durationMs"`
+}
+
+// RenderRequest is the body accepted by POST /api/v1/render: results
+// obtained elsewhere, rendered as a published snapshot of them would be.
+type RenderRequest struct {
+	County           string    `json:"county"`
+	ContentType      string    `json:"contentType,omitempty"`
+	MeasureThreshold string    `json:"measureThreshold,omitempty"`
+	License          *License  `json:"license,omitempty"`
+	Turnout          *Turnout  `json:"turnout,omitempty"`
+	Contests         []Contest `json:"contests"`
+
+	// Template names the template to render with; empty uses the one
+	// assigned to the county or content type, if any.
+	Template string `json:"template,omitempty"`
+}
+
+// RenderResponse is the ?format=json answer to POST /api/v1/render.
+type RenderResponse struct {
+	HTML    string   `json:"html"`
+	Results *Results `json:"results"`
+}
+
+// LogLevel is the server's log verbosity, as GET and PUT /admin/loglevel
+// report and take it: debug, info, warn or error.
+type LogLevel struct {
+	Level string `json:"level"`
+}
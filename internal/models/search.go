@@ -0,0 +1,42 @@
+package models
+
+// Kinds of search match.
+const (
+	SearchContest   = "contest"
+	SearchCandidate = "candidate"
+	SearchMeasure   = "measure"
+)
+
+// SearchResults are what a search of an election's results found, best
+// match first.
+type SearchResults struct {
+	Query    string        `json:"query"`
+	Election string        `json:"election"`
+	Matches  []SearchMatch `json:"matches"`
+
+	// Version is the store version every county was searched at.
+	Version uint64 `json:"version"`
+}
+
+// SearchMatch is a contest, candidate or measure matching a search, with
+// links to the results it appears in.
+type SearchMatch struct {
+	Kind      string  `json:"kind"` // contest, candidate or measure
+	Name      string  `json:"name"` // the contest's title or the candidate's name
+	Party     string  `json:"party,omitempty"`
+	ContestID string  `json:"contestId"`
+	Contest   string  `json:"contest"` // the contest's title
+	Score     float64 `json:"score"`   // 0 to 1, 1 for an exact match
+
+	// Aggregate links the contest rolled up across counties, set for the
+	// default election, which aggregates cover.
+	Aggregate string         `json:"aggregate,omitempty"`
+	Counties  []SearchCounty `json:"counties"`
+}
+
+// SearchCounty is a county whose results have a search match, and where
+// they are.
+type SearchCounty struct {
+	County  string `json:"county"`
+	Results string `json:"results"`
+}
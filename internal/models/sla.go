@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// SLA is a freshness promise: published data for each covered county is
+// never older than MaxStalenessSeconds behind its source. Data counts as
+// current as of its last successful fetch, since anything the county posted
+// after that hasn't been picked up yet.
+type SLA struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Tenant is the ID of the API key the promise is made to, for SLAs
+	// agreed with a partner. Internal targets leave it empty.
+	Tenant string `json:"tenant,omitempty"`
+	// Counties are the sources covered; empty covers every registered
+	// county.
+	Counties            []string  `json:"counties,omitempty"`
+	MaxStalenessSeconds int       `json:"maxStalenessSeconds"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+// MaxStaleness returns the SLA's limit as a duration.
+func (s SLA) MaxStaleness() time.Duration {
+	return time.Duration(s.MaxStalenessSeconds) * time.Second
+}
+
+// SLABreach is a period during which a county's data was staler than an SLA
+// allows. It starts when the limit ran out after FreshAt, not when the
+// breach was noticed, and ends at the fetch that brought the data back
+// within it.
+type SLABreach struct {
+	ID        string     `json:"id"`
+	SLAID     string     `json:"slaId"`
+	Tenant    string     `json:"tenant,omitempty"`
+	County    string     `json:"county"`
+	FreshAt   time.Time  `json:"freshAt"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+}
+
+// SLAReport summarizes how an SLA was kept over a period.
+type SLAReport struct {
+	SLA  SLA       `json:"sla"`
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// CompliancePercent is the share of covered county time spent within
+	// the limit.
+	CompliancePercent float64           `json:"compliancePercent"`
+	Met               bool              `json:"met"` // no breach during the period
+	Counties          []SLACountyReport `json:"counties"`
+}
+
+// SLACountyReport is one county's part of an SLAReport.
+type SLACountyReport struct {
+	County               string  `json:"county"`
+	StalenessSeconds     int     `json:"stalenessSeconds"` // as of the end of the period
+	Breached             bool    `json:"breached"`         // breached at the end of the period
+	Breaches             int     `json:"breaches"`
+	BreachedSeconds      int     `json:"breachedSeconds"`
+	LongestBreachSeconds int     `json:"longestBreachSeconds"`
+	CompliancePercent    float64 `json:"compliancePercent"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// LogEntry is one published snapshot in a county's append-only snapshot
+// log. LeafHash is the RFC 6962 leaf hash of the entry's canonical encoding
+// (see LogEntryData).
+type LogEntry struct {
+	Index        int       `json:"index"`
+	SnapshotHash string    `json:"snapshotHash"`
+	PublishedAt  time.Time `json:"publishedAt"`
+	LeafHash     string    `json:"leafHash"`
+}
+
+// LogHead is the Merkle tree head of a county's snapshot log.
+type LogHead struct {
+	County string `json:"county"`
+	Size   int    `json:"size"`
+	Root   string `json:"root"`
+}
+
+// SnapshotLog is a county's full snapshot log.
+type SnapshotLog struct {
+	LogHead
+	Entries []LogEntry `json:"entries"`
+}
+
+// InclusionProof proves an entry is part of the log with the given head.
+// Path is the RFC 6962 audit path, leaf to root, hex encoded.
+type InclusionProof struct {
+	LogHead
+	Entry LogEntry `json:"entry"`
+	Path  []string `json:"path"`
+}
+
+// LogEntryData is the canonical encoding hashed into a log leaf:
+// "<county>\n<index>\n<published at, RFC 3339 nanoseconds UTC>\n<snapshot hash>".
+func LogEntryData(county string, e LogEntry) []byte {
+	return []byte(county + "\n" + strconv.Itoa(e.Index) + "\n" + e.PublishedAt.UTC().Format(time.RFC3339Nano) + "\n" + e.SnapshotHash)
+}
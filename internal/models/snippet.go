@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Snippet is a saved selection of contests rendered for live-blog tooling.
+type Snippet struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Contests  []ContestRef `json:"contests"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// ContestRef identifies a contest within a county's results in an
+// election. An empty election is the default one when the snippet is
+// created.
+type ContestRef struct {
+	Election  string `json:"election,omitempty"`
+	County    string `json:"county"`
+	ContestID string `json:"contestId"`
+}
+
+// RenderedSnippet is the generated output of a snippet.
+type RenderedSnippet struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Shortcode string           `json:"shortcode"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+	Contests  []SnippetContest `json:"contests"`
+	HTML      string           `json:"-"`
+}
+
+// SnippetContest is a contest as embedded in a snippet.
+type SnippetContest struct {
+	County     string             `json:"county"`
+	ContestID  string             `json:"contestId"`
+	Title      string             `json:"title"`
+	AsOf       time.Time          `json:"asOf"`
+	License    *License           `json:"license,omitempty"`
+	Candidates []SnippetCandidate `json:"candidates"`
+}
+
+// SnippetCandidate is a candidate line in a snippet.
+type SnippetCandidate struct {
+	Name    string  `json:"name"`
+	Votes   int     `json:"votes"`
+	Percent float64 `json:"percent"`
+	WriteIn bool    `json:"writeIn,omitempty"`
+}
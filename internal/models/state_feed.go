@@ -0,0 +1,76 @@
+package models
+
+// Formats of a state feed, for StateFeedRequest.Format.
+const (
+	StateFeedXML = "xml" // statewide Clarity ENR detail.xml, votes by county
+	StateFeedCSV = "csv" // county, contest, candidate and votes columns
+)
+
+// StateFeedFormats lists the accepted state feed formats.
+var StateFeedFormats = []string{StateFeedXML, StateFeedCSV}
+
+// StateFeedRequest is the body accepted by POST /api/v1/state-feeds/process:
+// a secretary of state's aggregate feed, published as the results of each
+// county it reports.
+type StateFeedRequest struct {
+	// State names the feed's publisher in logs and results, e.g.
+	// "Georgia Secretary of State".
+	State string `json:"state,omitempty"`
+
+	FileLink    string `json:"fileLink"`
+	Format      string `json:"format"`      // "xml" or "csv"
+	ContentType string `json:"contentType"` // "candidate" or "measure"
+
+	// Election is the ID of the election the results are published for;
+	// empty means the default one. It can also be set with ?election=.
+	Election string `json:"election,omitempty"`
+
+	// Counties limits publishing to these counties of the feed; empty
+	// publishes every county in it.
+	Counties []string `json:"counties,omitempty"`
+
+	License          *License `json:"license,omitempty"`
+	MeasureThreshold string   `json:"measureThreshold,omitempty"`
+}
+
+// ProcessRequest returns the process request for county's share of the
+// feed.
+func (r StateFeedRequest) ProcessRequest(county string) ProcessRequest {
+	return ProcessRequest{
+		CountyName:  county,
+		Election:    r.Election,
+		FileLink:    r.FileLink,
+		ContentType: r.ContentType,
+		License:     r.License,
+		SourceLevel: SourceLevelState,
+
+		MeasureThreshold: r.MeasureThreshold,
+	}
+}
+
+// Outcomes of a county in a state feed, for StateFeedCounty.Status.
+const (
+	StateFeedPublished   = "published"
+	StateFeedUnchanged   = "unchanged"   // same snapshot as already published
+	StateFeedSkipped     = "skipped"     // the county's own source takes precedence
+	StateFeedQuarantined = "quarantined" // held for review by the drift policy
+	StateFeedFailed      = "failed"
+)
+
+// StateFeedCounty reports what became of one county of a state feed.
+type StateFeedCounty struct {
+	County   string   `json:"county"`
+	Status   string   `json:"status"`
+	Hash     string   `json:"hash,omitempty"`
+	Contests int      `json:"contests"`
+	Reason   string   `json:"reason,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// StateFeedResponse is returned by POST /api/v1/state-feeds/process.
+type StateFeedResponse struct {
+	State    string            `json:"state,omitempty"`
+	Election string            `json:"election"`
+	Source   string            `json:"source"`
+	Counties []StateFeedCounty `json:"counties"`
+}
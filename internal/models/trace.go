@@ -0,0 +1,19 @@
+package models
+
+// Kinds of TraceEntry.
+const (
+	TraceFormat    = "format"    // the layout or entry being read
+	TraceHeader    = "header"    // a detected header row or column mapping
+	TraceContest   = "contest"   // a contest started or completed
+	TraceCandidate = "candidate" // a row accepted as a candidate
+	TraceSkip      = "skip"      // a line or row ignored, with the reason
+	TraceTurnout   = "turnout"   // a turnout figure picked up
+	TraceNote      = "note"      // anything else worth knowing
+)
+
+// TraceEntry is one intermediate decision made by a parser, recorded when a
+// process request sets Debug.
+type TraceEntry struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
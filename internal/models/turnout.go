@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Turnout holds registration and ballots-cast figures for a county, with
+// per-precinct detail when the source provides it.
+type Turnout struct {
+	RegisteredVoters int               `json:"registeredVoters"`
+	BallotsCast      int               `json:"ballotsCast"`
+	Percent          float64           `json:"percent"`
+	Precincts        []PrecinctTurnout `json:"precincts,omitempty"`
+}
+
+// PrecinctTurnout is the turnout of a single precinct.
+type PrecinctTurnout struct {
+	Name             string  `json:"name"`
+	RegisteredVoters int     `json:"registeredVoters"`
+	BallotsCast      int     `json:"ballotsCast"`
+	Percent          float64 `json:"percent"`
+}
+
+// TurnoutSample is a county's turnout as of one snapshot.
+type TurnoutSample struct {
+	At               time.Time `json:"at"`
+	RegisteredVoters int       `json:"registeredVoters"`
+	BallotsCast      int       `json:"ballotsCast"`
+	Percent          float64   `json:"percent"`
+}
+
+// CountyTurnout is one county's current turnout in a statewide summary.
+type CountyTurnout struct {
+	County string `json:"county"`
+	TurnoutSample
+}
+
+// StatewideTurnout is turnout summed across counties, now and over time.
+type StatewideTurnout struct {
+	Current  TurnoutSample   `json:"current"`
+	Counties []CountyTurnout `json:"counties"`
+	History  []TurnoutSample `json:"history"`
+	// Version is the store version the counties were read at.
+	Version uint64 `json:"version"`
+}
+
+// TurnoutPercent returns ballots as a percentage of registered voters.
+func TurnoutPercent(ballots, registered int) float64 {
+	if registered == 0 {
+		return 0
+	}
+	return float64(ballots) * 100 / float64(registered)
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// BuildInfo identifies a binary: the release it was built as, "dev" for
+// builds that weren't given one, and the commit it was built from, with
+// Modified set if the checkout had uncommitted changes.
+type BuildInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	GoVersion  string `json:"goVersion"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+}
+
+// VersionInfo is served at /version so a deployed server can be identified
+// by tooling: its build, the version of each parser, which changes when
+// the parser would read the same source differently, and the optional
+// features this deployment was started with.
+type VersionInfo struct {
+	Service   string         `json:"service"`
+	Build     BuildInfo      `json:"build"`
+	Parsers   map[string]int `json:"parsers"`
+	Features  []string       `json:"features"`
+	Region    string         `json:"region,omitempty"`
+	Instance  string         `json:"instance"`
+	StartedAt time.Time      `json:"startedAt"`
+}
@@ -0,0 +1,61 @@
+package normalize
+
+import (
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// AddAliases appends the aliases c doesn't already answer to, by name or
+// alias, and returns how many it added.
+func AddAliases(c *models.CanonicalCandidate, aliases ...string) int {
+	known := make(map[string]bool, len(c.Aliases)+1)
+	known[Key(c.Name)] = true
+	for _, a := range c.Aliases {
+		known[Key(a)] = true
+	}
+	added := 0
+	for _, a := range aliases {
+		if k := Key(a); k != "" && !known[k] {
+			known[k] = true
+			c.Aliases = append(c.Aliases, strings.TrimSpace(a))
+			added++
+		}
+	}
+	return added
+}
+
+// SeedAliases adds the county spellings results resolved to registry
+// candidates as aliases of those candidates, so they match by alias from
+// then on rather than by fuzzy matching. It returns how many it added.
+func SeedAliases(st store.Store, results []*models.Results) int {
+	spellings := make(map[string][]string)
+	var ids []string
+	for _, r := range results {
+		for _, contest := range r.Contests {
+			for _, cand := range contest.Candidates {
+				if cand.CanonicalID == "" || cand.RawName == "" {
+					continue
+				}
+				if _, ok := spellings[cand.CanonicalID]; !ok {
+					ids = append(ids, cand.CanonicalID)
+				}
+				spellings[cand.CanonicalID] = append(spellings[cand.CanonicalID], cand.RawName)
+			}
+		}
+	}
+
+	added := 0
+	for _, id := range ids {
+		c, err := st.Candidate(id)
+		if err != nil {
+			continue
+		}
+		if n := AddAliases(&c, spellings[id]...); n > 0 {
+			st.SaveCandidate(c)
+			added += n
+		}
+	}
+	return added
+}
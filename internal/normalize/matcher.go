@@ -0,0 +1,122 @@
+package normalize
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// DefaultThreshold is the minimum Similarity for a fuzzy match.
+const DefaultThreshold = 0.85
+
+// ambiguityMargin is how close the runner-up may score before a fuzzy match
+// is considered ambiguous and rejected.
+const ambiguityMargin = 0.03
+
+// Match is the outcome of resolving a county spelling against the registry.
+type Match struct {
+	Candidate models.CanonicalCandidate `json:"candidate"`
+	Score     float64                   `json:"score"`
+	Method    string                    `json:"method"` // "alias", "exact" or "fuzzy"
+}
+
+// Matcher resolves candidate names against the registry kept in the store.
+type Matcher struct {
+	store     store.Store
+	threshold float64
+}
+
+// NewMatcher returns a Matcher accepting fuzzy matches at or above threshold.
+func NewMatcher(st store.Store, threshold float64) *Matcher {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultThreshold
+	}
+	return &Matcher{store: st, threshold: threshold}
+}
+
+// Match resolves name, as reported in contestID, to a canonical candidate.
+func (m *Matcher) Match(contestID, name string) (Match, bool) {
+	key := Key(name)
+	if key == "" {
+		return Match{}, false
+	}
+
+	var best, runnerUp Match
+	for _, c := range m.store.Candidates() {
+		if c.ContestID != "" && c.ContestID != contestID {
+			continue
+		}
+
+		for _, alias := range c.Aliases {
+			if Key(alias) == key {
+				return Match{Candidate: c, Score: 1, Method: "alias"}, true
+			}
+		}
+		if Key(c.Name) == key {
+			return Match{Candidate: c, Score: 1, Method: "exact"}, true
+		}
+
+		score := Similarity(key, Key(c.Name))
+		if score > best.Score {
+			runnerUp = best
+			best = Match{Candidate: c, Score: score, Method: "fuzzy"}
+		} else if score > runnerUp.Score {
+			runnerUp = Match{Candidate: c, Score: score, Method: "fuzzy"}
+		}
+	}
+
+	if best.Score < m.threshold || best.Score-runnerUp.Score < ambiguityMargin {
+		return Match{}, false
+	}
+	return best, true
+}
+
+// Apply links every candidate in contests to the registry, replacing county
+// spellings with the canonical name and keeping the original in RawName.
+func (m *Matcher) Apply(_ models.ProcessRequest, contests []models.Contest) []models.Contest {
+	for i := range contests {
+		for j := range contests[i].Candidates {
+			cand := &contests[i].Candidates[j]
+			if cand.WriteInAggregate {
+				continue
+			}
+			match, ok := m.Match(contests[i].ID, cand.Name)
+			if !ok {
+				continue
+			}
+			cand.CanonicalID = match.Candidate.ID
+			if cand.Name != match.Candidate.Name {
+				contests[i].RCV.RenameCandidate(cand.Name, match.Candidate.Name)
+				cand.RawName = cand.Name
+				cand.Name = match.Candidate.Name
+			}
+			if cand.Party == "" {
+				cand.Party = match.Candidate.Party
+			}
+		}
+	}
+	return contests
+}
+
+// LoadRegistry seeds the registry from a JSON array of canonical candidates.
+func LoadRegistry(path string, st store.Store) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("read candidate registry: %w", err)
+	}
+
+	var candidates []models.CanonicalCandidate
+	if err := json.Unmarshal(data, &candidates); err != nil {
+		return 0, fmt.Errorf("decode candidate registry: %w", err)
+	}
+	for _, c := range candidates {
+		if c.ID == "" {
+			c.ID = models.Slug(c.Name)
+		}
+		st.SaveCandidate(c)
+	}
+	return len(candidates), nil
+}
@@ -0,0 +1,130 @@
+// Package normalize reconciles the inconsistent ways counties spell
+// candidate names ("SMITH, JOHN A." vs "John Smith") against a registry of
+// canonical candidates.
+package normalize
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	parenthetical = regexp.MustCompile(`\([^)]*\)|"[^"]*"|“[^”]*”`)
+	suffixes      = map[string]bool{"JR": true, "SR": true, "II": true, "III": true, "IV": true, "V": true}
+)
+
+// Key returns the comparison form of a candidate name: upper case, "LAST,
+// FIRST" reordered to "FIRST LAST", punctuation, nicknames, parenthesized
+// party labels, suffixes and middle initials removed.
+func Key(name string) string {
+	name = parenthetical.ReplaceAllString(strings.ToUpper(name), " ")
+
+	// Reorder "LAST, FIRST MIDDLE" unless the part after the comma is just
+	// a suffix, as in "JOHN SMITH, JR.".
+	if last, first, ok := strings.Cut(name, ","); ok {
+		rest := strings.Fields(stripPunct(first))
+		if len(rest) > 0 && !(len(rest) == 1 && suffixes[rest[0]]) {
+			name = first + " " + last
+		}
+	}
+
+	var tokens []string
+	for _, t := range strings.Fields(stripPunct(name)) {
+		if !suffixes[t] {
+			tokens = append(tokens, t)
+		}
+	}
+
+	// Middle initials are dropped, but only when a first and last name remain.
+	if len(tokens) > 2 {
+		kept := tokens[:1]
+		for _, t := range tokens[1 : len(tokens)-1] {
+			if len(t) > 1 {
+				kept = append(kept, t)
+			}
+		}
+		tokens = append(kept, tokens[len(tokens)-1])
+	}
+	return strings.Join(tokens, " ")
+}
+
+func stripPunct(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), unicode.IsSpace(r):
+			return r
+		case r == '-' || r == '\'':
+			return r
+		}
+		return ' '
+	}, s)
+}
+
+// Similarity scores how alike two normalized keys are, from 0 to 1. It takes
+// the best of a plain edit-distance ratio, a token-order-insensitive ratio,
+// and an initial-plus-surname comparison ("J SMITH" vs "JOHN SMITH").
+func Similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	score := ratio(a, b)
+	if s := ratio(sortTokens(a), sortTokens(b)); s > score {
+		score = s
+	}
+	if initialMatch(a, b) && score < 0.9 {
+		score = 0.9
+	}
+	return score
+}
+
+func sortTokens(s string) string {
+	t := strings.Fields(s)
+	sort.Strings(t)
+	return strings.Join(t, " ")
+}
+
+// initialMatch reports whether the names share a surname and one first name
+// is the other's initial.
+func initialMatch(a, b string) bool {
+	ta, tb := strings.Fields(a), strings.Fields(b)
+	if len(ta) < 2 || len(tb) < 2 || ta[len(ta)-1] != tb[len(tb)-1] {
+		return false
+	}
+	fa, fb := ta[0], tb[0]
+	return (len(fa) == 1 || len(fb) == 1) && fa[0] == fb[0]
+}
+
+// ratio is 1 minus the Levenshtein distance normalized by the longer length.
+func ratio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
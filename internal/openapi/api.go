@@ -0,0 +1,1593 @@
+package openapi
+
+import (
+	"github.com/many221/era_api_v1/internal/clock"
+	"github.com/many221/era_api_v1/internal/dump"
+	"github.com/many221/era_api_v1/internal/formatter"
+	"github.com/many221/era_api_v1/internal/graphql"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/templates"
+)
+
+// errorBody is the body of every JSON error response.
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// ruleTest is the body of GET /api/v1/contest-rules/test.
+type ruleTest struct {
+	Matched   bool                `json:"matched"`
+	ContestID string              `json:"contestId"`
+	Rule      *models.ContestRule `json:"rule,omitempty"`
+}
+
+const apiDescription = "Election results scraped from county sources, normalized and published as JSON, feeds and exports. " +
+	"Every response names the region and instance that served it, and that instance's store version, " +
+	"in the X-ERA-Region, X-ERA-Instance and X-ERA-Snapshot-Version headers, and carries X-Request-ID: the one the request sent, " +
+	"if any, or one assigned to it, which the server's logs, the jobs it starts and the audit log record, to quote when reporting a problem. " +
+	"API keys in a delayed access tier are served each county's results as they were when the embargo lifts, " +
+	"with the longest delay in X-Embargo-Delay (seconds); feeds that are only served live answer 403 for them. " +
+	"Each API key has a role: viewers only read, ingesters also submit sources to process, editors also call races and curate contests, " +
+	"candidates and review queues, and admins may do anything, including managing counties and elections. " +
+	"Requests the caller's role doesn't allow are answered 403."
+
+// API describes the HTTP API served by cmd/server. Keep it in step with the
+// routes registered there.
+func API() *Document {
+	d := New(Info{
+		Title:       "era API",
+		Description: apiDescription,
+		Version:     "v1",
+	})
+	d.Components.SecuritySchemes = map[string]*SecurityScheme{
+		"apiKey": {Type: "apiKey", Name: "X-API-Key", In: "header"},
+		"bearer": {Type: "http", Scheme: "bearer", Description: "An API key, or a JWT from the OIDC issuer if the deployment has one, with the role mapped from its claims"},
+	}
+	// Keys are optional; the empty requirement admits anonymous callers.
+	d.Security = []map[string][]string{{"apiKey": {}}, {"bearer": {}}, {}}
+	d.Tags = []Tag{
+		{Name: "processing", Description: "Parse county sources into results"},
+		{Name: "results", Description: "Published results, exports and feeds"},
+		{Name: "review", Description: "Snapshots held for review before publishing, sources that failed to parse and datasets staged to switch live"},
+		{Name: "monitoring", Description: "Alerts, freshness SLAs, post-to-publish latency and data anomalies"},
+		{Name: "configuration", Description: "Elections, legal holds, counties, contacts, candidates, contest rules, election manifests and templates"},
+		{Name: "meta", Description: "Discovery, schema and health"},
+	}
+
+	d.Name(errorBody{}, "Error")
+	d.Name(ruleTest{}, "ContestRuleTest")
+	d.Name(clock.Status{}, "ClockStatus")
+	d.Name(dump.Manifest{}, "DumpManifest")
+	d.Name(dump.ManifestPart{}, "DumpManifestPart")
+	d.Name(graphql.Request{}, "GraphQLRequest")
+	d.Name(graphql.Response{}, "GraphQLResponse")
+	d.Name(graphql.Error{}, "GraphQLError")
+	d.Name(normalize.Match{}, "CandidateMatch")
+	d.Name(templates.Info{}, "Template")
+	d.Name(templates.Assignments{}, "TemplateAssignments")
+
+	r := responses{d}
+	// Results and registrations belong to an election, the default one
+	// unless a request names another.
+	election := query("election", "string", "election ID; defaults to the deployment's")
+	// Result lists are paged and trimmed to the fields a client needs.
+	paging := []Parameter{
+		query("offset", "integer", "skip this many contests, or counties for an election's results; with limit, the response's page says where it falls (default 0)"),
+		query("limit", "integer", "at most this many, 1 to 1000 (default 100 if offset is given, else all)"),
+		query("fields", "string", "comma-separated JSON fields to keep, dotted for nested ones, e.g. county,contests.id,contests.candidates.votes"),
+	}
+	const realTimeOnly = "Served live only, and the API key's access tier is delayed"
+
+	d.Add("POST", "/api/v1/process", &Operation{
+		OperationID: "processSource",
+		Summary:     "Fetch and parse a county source",
+		Description: "Parses the source and publishes the snapshot, or stages it under a dataset label. With async the parse runs as a job; poll it at /api/v1/jobs/{id}. With browser set, a page that renders its results with JavaScript is loaded in the deployment's headless browser, which must be configured, and its DOM is parsed, or with browser.capture the body of the first response it loads whose URL matches the glob, usually JSON for parseMethod json. With parseMethod auto, the parser is picked by what the source's content looks like, its Content-Type and its file name; the trace says which was chosen and why. With dryRun the results are returned with the warnings publishing them would raise, such as a layout change, a manifest mismatch or drift that would quarantine them, and nothing is published, staged or recorded. Results the county has already published aren't published again: they are returned with unchanged set, and no snapshot is added to the history or streamed.",
+		Tags:        []string{"processing"},
+		Parameters: []Parameter{
+			query("async", "boolean", "answer 202 with a job instead of waiting"),
+			query("debug", "boolean", "include the parser's decision trace"),
+			query("dryRun", "boolean", "parse and return the results and warnings without publishing anything"),
+			query("dataset", "string", "stage the snapshot under this label instead of publishing it"),
+			query("election", "string", "publish for this election instead of the deployment's; overrides the body"),
+		},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.ProcessRequest{})},
+		Responses: map[string]*Response{
+			"200": r.json("Parsed and published, or parsed only for a dry run", models.ProcessResponse{}),
+			"202": r.json("Queued as a job", models.JobAccepted{}),
+			"400": r.problem("Invalid request, or an unknown election"),
+			"403": r.json("fileLink refused by the fetch policy", models.ProcessResponse{}),
+			"409": r.json("Snapshot held in quarantine, the election is archived or the county's results are certified", models.ProcessResponse{}),
+			"422": r.json("No results found in the source, or its format wasn't recognised", models.ProcessResponse{}),
+			"502": r.json("Source could not be fetched or parsed", models.ProcessResponse{}),
+			"503": r.error("An async request arrived while the server is shutting down; retry after Retry-After"),
+			"504": r.json("Processing timed out", models.ProcessResponse{}),
+		},
+	})
+	d.Add("POST", "/api/v1/process/batch", &Operation{
+		OperationID: "processBatch",
+		Summary:     "Fetch and parse several county sources at once",
+		Description: "Runs each process request of the array on the workers scheduled refreshes use, under the same limit on fetches from one site, and answers once all have finished with each one's status, the one POST /api/v1/process would have answered it with, in the order given. A request naming only countyName runs the county's registration. A failed request doesn't stop the others. Requests are run synchronously, with debug and async ignored; the batch may take up to 10 minutes, after which unfinished requests are reported 504.",
+		Tags:        []string{"processing"},
+		Parameters: []Parameter{
+			query("dryRun", "boolean", "parse every source and report without publishing anything"),
+			query("election", "string", "publish for this election instead of the one each request names"),
+		},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON([]models.ProcessRequest{})},
+		Responses: map[string]*Response{
+			"200": r.json("How each request went", models.BatchProcessResponse{}),
+			"400": r.problem("The body isn't an array of 1 to 500 process requests"),
+		},
+	})
+	str := func(desc string) *Schema { return &Schema{Type: "string", Description: desc} }
+	d.Add("POST", "/api/v1/upload", &Operation{
+		OperationID: "uploadSource",
+		Summary:     "Parse an uploaded county source",
+		Description: "For sources that arrive by email rather than at a link. The file is processed as POST /api/v1/process would process it had it been fetched, and results name it as upload:<file name> in place of a fileLink.",
+		Tags:        []string{"processing"},
+		RequestBody: &RequestBody{Required: true, Content: map[string]*MediaType{"multipart/form-data": {Schema: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"file":             {Type: "string", Format: "binary", Description: "the source, in a format one of the parsers at /api/v1/parsers reads, up to 256 MiB"},
+				"countyName":       str("required unless jurisdiction is set"),
+				"jurisdiction":     str("ID of the jurisdiction the source reports for"),
+				"parseMethod":      {Type: "string", Enum: append(parser.Methods(), models.ParseMethodAuto), Description: "defaults to the one the file's extension names, or auto"},
+				"contentType":      {Type: "string", Enum: []string{models.ContentTypeCandidate, models.ContentTypeMeasure}},
+				"measureThreshold": str("vote threshold the source's measures need to pass"),
+				"election":         str("publish for this election instead of the deployment's"),
+				"dataset":          str("stage the snapshot under this label instead of publishing it"),
+				"async":            {Type: "boolean", Description: "answer 202 with a job instead of waiting"},
+				"debug":            {Type: "boolean", Description: "include the parser's decision trace"},
+				"dryRun":           {Type: "boolean", Description: "parse and return the results and warnings without publishing anything"},
+			},
+			Required: []string{"file"},
+		}}}},
+		Responses: map[string]*Response{
+			"200": r.json("Parsed and published, or parsed only for a dry run", models.ProcessResponse{}),
+			"202": r.json("Queued as a job", models.JobAccepted{}),
+			"400": r.problem("Invalid form, or an unknown election"),
+			"409": r.json("Snapshot held in quarantine, or the election is archived", models.ProcessResponse{}),
+			"413": r.error("Upload too large"),
+			"422": r.json("No results found in the file, or its format wasn't recognised", models.ProcessResponse{}),
+			"502": r.json("File could not be parsed", models.ProcessResponse{}),
+		},
+	})
+	d.Add("GET", "/api/v1/parsers", &Operation{
+		OperationID: "listParsers",
+		Summary:     "List parse methods",
+		Description: "The formats this server reads, with the file extensions naming them and what each can read: turnout, ranked-choice rounds, nested sources in archives, and whether it recognises its format from the content.",
+		Tags:        []string{"processing"},
+		Responses:   map[string]*Response{"200": r.json("The registered parsers, by method", []models.ParserInfo{})},
+	})
+	d.Add("POST", "/api/v1/state-feeds/process", &Operation{
+		OperationID: "processStateFeed",
+		Summary:     "Fetch a state's aggregate feed and publish each county in it",
+		Description: "Each county's share of the feed is published as its results, marked with sourceLevel state, one county at a time. A county is skipped if a source level that takes precedence has published for it: by default its own source, unless registered with precedence state or the deployment's SOURCE_PRECEDENCE ranks state first. Every county's share is kept for comparison at /api/v1/conflicts, published or not.",
+		Tags:        []string{"processing"},
+		Parameters:  []Parameter{query("election", "string", "publish for this election instead of the deployment's; overrides the body")},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.StateFeedRequest{})},
+		Responses: map[string]*Response{
+			"200": r.json("What became of each county", models.StateFeedResponse{}),
+			"400": r.problem("Invalid request, or an unknown election"),
+			"403": r.error("fileLink refused by the fetch policy"),
+			"409": r.error("The election is archived"),
+			"422": r.error("No results found in the feed"),
+			"502": r.error("Feed could not be fetched or parsed"),
+		},
+	})
+	d.Add("GET", "/api/v1/conflicts", &Operation{
+		OperationID: "listConflicts",
+		Summary:     "List contests that sources of the same county report differently",
+		Description: "Every source level's latest snapshot of a county is kept, whether published or not: the county's own source, state feeds and any posted with another sourceLevel. Where two or more report a contest and a candidate's counts differ by more than the tolerance, the contest is listed with each level's count, the county's precedence and the level published. The published snapshot is the one of the highest level in the precedence that has published; the order is the deployment's SOURCE_PRECEDENCE (county, then state, by default), with a county's registered precedence moved to the front. Only callers with full visibility may read it.",
+		Tags:        []string{"processing"},
+		Parameters: []Parameter{
+			election,
+			query("county", "string", "only this county"),
+			query("tolerance", "string", "allowed spread, in votes (25) or as a percentage of the highest count (0.5%); default 0"),
+		},
+		Responses: map[string]*Response{
+			"200": r.json("The conflicts", models.ConflictsResponse{}),
+			"400": r.error("Invalid tolerance"),
+			"403": r.error("The API key doesn't have full visibility"),
+			"404": r.error("Unknown election"),
+		},
+	})
+	d.Add("GET", "/api/v1/jobs/{id}", &Operation{
+		OperationID: "getJob",
+		Summary:     "Get an asynchronous process job",
+		Tags:        []string{"processing"},
+		Responses:   map[string]*Response{"200": r.json("The job", models.Job{}), "404": r.error("Job not found")},
+	})
+
+	d.Add("GET", "/api/v1/quarantine", &Operation{
+		OperationID: "listQuarantine",
+		Summary:     "List quarantined snapshots, newest first",
+		Tags:        []string{"review"},
+		Parameters: []Parameter{
+			query("county", "string", "only this county"),
+			query("status", "string", "held or released"),
+		},
+		Responses: map[string]*Response{"200": r.json("Quarantine records without their results", []models.QuarantineRecord{})},
+	})
+	d.Add("GET", "/api/v1/quarantine/{id}", &Operation{
+		OperationID: "getQuarantine",
+		Summary:     "Get a quarantined snapshot with its results",
+		Tags:        []string{"review"},
+		Responses:   map[string]*Response{"200": r.json("The record", models.QuarantineRecord{}), "404": r.error("Record not found")},
+	})
+	d.Add("POST", "/api/v1/quarantine/{id}/release", &Operation{
+		OperationID: "releaseQuarantine",
+		Summary:     "Publish a held snapshot",
+		Tags:        []string{"review"},
+		Parameters:  []Parameter{query("force", "boolean", "publish even if the county has published newer results since")},
+		Responses: map[string]*Response{
+			"200": r.json("Published", models.ProcessResponse{}),
+			"404": r.error("Record not found"),
+			"409": r.error("Record is not held, or newer results were published since"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/quarantine/{id}", &Operation{
+		OperationID: "deleteQuarantine",
+		Summary:     "Discard a quarantine record",
+		Tags:        []string{"review"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("Record not found"), "409": r.error("Under a legal hold")},
+	})
+
+	d.Add("GET", "/api/v1/errors", &Operation{
+		OperationID: "listParseErrors",
+		Summary:     "List sources that failed to parse, most recently seen first",
+		Tags:        []string{"review"},
+		Parameters: []Parameter{
+			query("county", "string", "only this county"),
+			query("status", "string", "open or resolved"),
+		},
+		Responses: map[string]*Response{"200": r.json("Parse errors without their source samples", []models.ParseError{})},
+	})
+	d.Add("GET", "/api/v1/errors/{id}", &Operation{
+		OperationID: "getParseError",
+		Summary:     "Get a parse error with what the parser read of the file",
+		Tags:        []string{"review"},
+		Responses:   map[string]*Response{"200": r.json("The record", models.ParseError{}), "404": r.error("Record not found")},
+	})
+	d.Add("GET", "/api/v1/errors/{id}/source", &Operation{
+		OperationID: "downloadParseErrorSource",
+		Summary:     "Download the file that failed to parse",
+		Tags:        []string{"review"},
+		Responses: map[string]*Response{
+			"200": r.files("The file as fetched", "application/octet-stream"),
+			"404": r.error("Record not found"),
+			"410": r.error("The record is resolved and its file discarded"),
+		},
+	})
+	d.Add("POST", "/api/v1/errors/{id}/retry", &Operation{
+		OperationID: "retryParseError",
+		Summary:     "Parse the kept file again and publish it if it now parses",
+		Description: "Uses the county's current registration if it has one, so a corrected parser config is picked up.",
+		Tags:        []string{"review"},
+		Responses: map[string]*Response{
+			"200": r.json("Published", models.ProcessResponse{}),
+			"404": r.error("Record not found"),
+			"409": r.error("Record is resolved, or the snapshot was quarantined"),
+			"422": r.error("The file has no results"),
+			"502": r.error("The file still fails to parse"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/errors/{id}", &Operation{
+		OperationID: "deleteParseError",
+		Summary:     "Discard a parse error and its file",
+		Tags:        []string{"review"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("Record not found"), "409": r.error("Under a legal hold")},
+	})
+
+	d.Add("GET", "/api/v1/datasets", &Operation{
+		OperationID: "listDatasets",
+		Summary:     "List datasets of staged snapshots",
+		Description: "Snapshots are staged by processing with dataset set. \"previous\" holds the snapshots the last switch displaced.",
+		Tags:        []string{"review"},
+		Responses:   map[string]*Response{"200": r.json("Datasets, most recently updated first", []models.Dataset{})},
+	})
+	d.Add("GET", "/api/v1/datasets/{label}", &Operation{
+		OperationID: "getDataset",
+		Summary:     "Get a dataset",
+		Tags:        []string{"review"},
+		Responses:   map[string]*Response{"200": r.json("The dataset", models.Dataset{}), "404": r.error("Dataset not found")},
+	})
+	d.Add("GET", "/api/v1/datasets/{label}/results/{county}", &Operation{
+		OperationID: "getStagedResults",
+		Summary:     "Preview a staged snapshot",
+		Tags:        []string{"review"},
+		Parameters:  append([]Parameter{election}, paging...),
+		Responses:   map[string]*Response{"200": r.json("The staged snapshot", models.Results{}), "400": r.error("Invalid offset or limit"), "404": r.error("No staged results for county")},
+	})
+	d.Add("POST", "/api/v1/datasets/{label}/activate", &Operation{
+		OperationID: "activateDataset",
+		Summary:     "Switch a dataset live in one step",
+		Description: "Every staged snapshot is published at once and the dataset is consumed. The snapshots it replaced become the dataset \"previous\"; activate that to switch back.",
+		Tags:        []string{"review"},
+		Responses: map[string]*Response{
+			"200": r.json("Switched", models.DatasetSwitch{}),
+			"404": r.error("Dataset not found"),
+			"409": r.error("The dataset has results for an archived election"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/datasets/{label}", &Operation{
+		OperationID: "deleteDataset",
+		Summary:     "Discard a dataset",
+		Tags:        []string{"review"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("Dataset not found"), "409": r.error("Has snapshots under a legal hold")},
+	})
+
+	d.Add("GET", "/api/v1/layout-changes", &Operation{
+		OperationID: "listLayoutChanges",
+		Summary:     "List fetches whose source layout differed from the previous fetch, newest first",
+		Tags:        []string{"review"},
+		Parameters:  []Parameter{query("county", "string", "only this county")},
+		Responses:   map[string]*Response{"200": r.json("The changes", []models.LayoutChange{})},
+	})
+	d.Add("GET", "/api/v1/capacity", &Operation{
+		OperationID: "getCapacity",
+		Summary:     "Report processing and refresh load",
+		Description: "Load is the work running and waiting over the slots for it, for whichever of processing and scheduled refreshes is busier: busy from 1, when new work waits, and saturated from 2 or once the refresh queue is full. Responses to process, upload and state feed requests carry the same figures as RateLimit-Limit (processing slots), RateLimit-Remaining (slots free) and RateLimit-Reset (seconds until one would be), with Retry-After while busy. Requests aren't refused for load.",
+		Tags:        []string{"monitoring"},
+		Responses: map[string]*Response{
+			"200": r.json("Not saturated", models.Capacity{}),
+			"503": r.json("Saturated; send work to another instance, or after Retry-After", models.Capacity{}),
+		},
+	})
+	d.Add("GET", "/api/v1/alerts", &Operation{
+		OperationID: "listAlerts",
+		Summary:     "List raised alerts with their runbooks, newest first",
+		Tags:        []string{"monitoring"},
+		Parameters:  []Parameter{query("county", "string", "only this county")},
+		Responses:   map[string]*Response{"200": r.json("The alerts, including those suppressed as repeats", []models.Alert{})},
+	})
+	d.Add("GET", "/api/v1/slas", &Operation{
+		OperationID: "listSLAs",
+		Summary:     "List freshness SLAs",
+		Tags:        []string{"monitoring"},
+		Parameters:  []Parameter{query("tenant", "string", "only SLAs made to this API key ID")},
+		Responses:   map[string]*Response{"200": r.json("The SLAs", []models.SLA{})},
+	})
+	d.Add("POST", "/api/v1/slas", &Operation{
+		OperationID: "createSLA",
+		Summary:     "Promise a maximum staleness for some or all counties",
+		Description: "Data is current as of its last successful fetch. Without counties the SLA covers every registered county.",
+		Tags:        []string{"monitoring"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.SLA{})},
+		Responses:   map[string]*Response{"201": r.json("Created", models.SLA{}), "400": r.problem("Invalid SLA")},
+	})
+	d.Add("GET", "/api/v1/slas/breaches", &Operation{
+		OperationID: "listSLABreaches",
+		Summary:     "List SLA breaches, newest first",
+		Tags:        []string{"monitoring"},
+		Parameters: []Parameter{
+			query("sla", "string", "only this SLA"),
+			query("tenant", "string", "only SLAs made to this API key ID"),
+			query("county", "string", "only this county"),
+		},
+		Responses: map[string]*Response{"200": r.json("The breaches; open ones have no endedAt", []models.SLABreach{})},
+	})
+	d.Add("GET", "/api/v1/slas/report", &Operation{
+		OperationID: "reportSLAs",
+		Summary:     "Report compliance with SLAs from a point in time until now",
+		Tags:        []string{"monitoring"},
+		Parameters: []Parameter{
+			query("sla", "string", "only this SLA"),
+			query("tenant", "string", "only SLAs made to this API key ID"),
+			query("since", "string", "RFC 3339 start of the period, a day ago by default"),
+		},
+		Responses: map[string]*Response{
+			"200": r.json("A report per SLA", []models.SLAReport{}),
+			"400": r.error("Invalid since"),
+			"404": r.error("SLA not found"),
+		},
+	})
+	d.Add("GET", "/api/v1/slas/{id}", &Operation{
+		OperationID: "getSLA",
+		Summary:     "Get an SLA",
+		Tags:        []string{"monitoring"},
+		Responses:   map[string]*Response{"200": r.json("The SLA", models.SLA{}), "404": r.error("SLA not found")},
+	})
+	d.Add("DELETE", "/api/v1/slas/{id}", &Operation{
+		OperationID: "deleteSLA",
+		Summary:     "Delete an SLA, keeping its breach history",
+		Tags:        []string{"monitoring"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("SLA not found")},
+	})
+	d.Add("GET", "/api/v1/latency", &Operation{
+		OperationID: "listLatency",
+		Summary:     "Get post-to-publish latency percentiles over every county, then per county",
+		Description: "Measured from each source's Last-Modified time to publication, over snapshots with new content.",
+		Tags:        []string{"monitoring"},
+		Responses: map[string]*Response{
+			"200": r.json("The summaries, every county's combined first", []models.LatencySummary{}),
+			"403": r.error("Latency is hidden from this API key"),
+		},
+	})
+	d.Add("GET", "/api/v1/latency/{county}", &Operation{
+		OperationID: "getCountyLatency",
+		Summary:     "Get a county's latency percentiles and recent snapshots",
+		Tags:        []string{"monitoring"},
+		Responses: map[string]*Response{
+			"200": r.json("The summary with samples newest first", models.LatencySummary{}),
+			"403": r.error("Latency is hidden from this API key"),
+			"404": r.error("No snapshots for county"),
+		},
+	})
+	d.Add("GET", "/api/v1/anomalies", &Operation{
+		OperationID: "listAnomalies",
+		Summary:     "List integrity anomalies flagged in published snapshots, newest first",
+		Description: "Vote totals or precincts reporting going backwards, candidates with more votes than ballots cast and sudden vote share swings. Flagged snapshots are still published, with the same anomalies in their results.",
+		Tags:        []string{"monitoring"},
+		Parameters: []Parameter{
+			query("county", "string", "only this county"),
+			enum(query("kind", "string", "only this kind"), models.AnomalyKinds...),
+		},
+		Responses: map[string]*Response{
+			"200": r.json("The anomalies", []models.Anomaly{}),
+			"400": r.error("Unknown kind"),
+			"403": r.error("Anomalies are hidden from this API key"),
+		},
+	})
+	d.Add("GET", "/api/v1/runbooks", &Operation{
+		OperationID: "listRunbooks",
+		Summary:     "List the runbooks attached to alert types",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"200": r.json("The runbooks", []models.Runbook{})},
+	})
+	alertType := enum(Parameter{Name: "type", In: "path", Required: true, Schema: &Schema{Type: "string"}}, models.AlertTypes...)
+	d.Add("PUT", "/api/v1/runbooks/{type}", &Operation{
+		OperationID: "putRunbook",
+		Summary:     "Set the runbook for an alert type on every source",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{alertType},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.Runbook{})},
+		Responses:   map[string]*Response{"200": r.json("Saved", models.Runbook{}), "400": r.problem("Invalid runbook")},
+	})
+	d.Add("PUT", "/api/v1/runbooks/{type}/{county}", &Operation{
+		OperationID: "putCountyRunbook",
+		Summary:     "Set the runbook for an alert type on one county's source",
+		Description: "Sent ahead of the runbook for every source.",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{alertType},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.Runbook{})},
+		Responses:   map[string]*Response{"200": r.json("Saved", models.Runbook{}), "400": r.problem("Invalid runbook")},
+	})
+	d.Add("DELETE", "/api/v1/runbooks/{type}", &Operation{
+		OperationID: "deleteRunbook",
+		Summary:     "Delete the runbook for an alert type on every source",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{alertType},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("Runbook not found")},
+	})
+	d.Add("DELETE", "/api/v1/runbooks/{type}/{county}", &Operation{
+		OperationID: "deleteCountyRunbook",
+		Summary:     "Delete the runbook for an alert type on one county's source",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{alertType},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("Runbook not found")},
+	})
+
+	d.Add("GET", "/api/v1/results/{county}", &Operation{
+		OperationID: "getResults",
+		Summary:     "Get a county's latest results",
+		Description: "Each contest carries its outcome: the candidates' shares, the leader and margin and, once the count meets the deployment's rules, the call.",
+		Tags:        []string{"results"},
+		Parameters:  append([]Parameter{query("include", "string", "comma-separated extras: overlays"), election}, paging...),
+		Responses:   map[string]*Response{"200": r.json("The latest snapshot", models.Results{}), "400": r.error("Invalid offset or limit"), "404": r.error("No results for county, or an unknown election")},
+	})
+	d.Add("GET", "/api/v1/results/{county}/export", &Operation{
+		OperationID: "exportResults",
+		Summary:     "Download a county's results as a file",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{enum(query("format", "string", "csv (default), xlsx, openelections or cdf"), "csv", "xlsx", "openelections", "cdf"), election},
+		Responses: map[string]*Response{
+			"200": r.files("The export",
+				"text/csv",
+				"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+				"application/json"),
+			"400": r.error("Unknown format"),
+			"404": r.error("No results for county, or an unknown election"),
+		},
+	})
+	d.Add("GET", "/api/v1/results/{county}/artifacts", &Operation{
+		OperationID: "listArtifacts",
+		Summary:     "List the archived source files a county's snapshots were parsed from",
+		Description: "Served when the deployment archives sources. Newest first.",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"200": r.json("Archived sources", []models.Artifact{}), "502": r.error("The archive could not be read")},
+	})
+	d.Add("GET", "/api/v1/results/{county}/artifacts/{snapshot}", &Operation{
+		OperationID: "downloadArtifact",
+		Summary:     "Download the exact source file a snapshot was parsed from",
+		Description: "{snapshot} is a snapshot hash, a unique prefix of one, or latest. Repr-Digest carries the file's SHA-256.",
+		Tags:        []string{"results"},
+		Responses: map[string]*Response{
+			"200": r.files("The file as fetched", "application/octet-stream"),
+			"400": r.error("The prefix matches several snapshots"),
+			"404": r.error("No archived source for the snapshot"),
+			"502": r.error("The archive could not be read"),
+		},
+	})
+	d.Add("POST", "/api/v1/results/{county}/reprocess", &Operation{
+		OperationID: "reprocessArtifact",
+		Summary:     "Parse a snapshot's archived source again and publish the results",
+		Description: "Served when the deployment archives sources. Nothing is fetched: the file the snapshot was parsed from is parsed with the current parsers and the county's current registration, or the parse method it was archived under for a county that isn't registered, and published as POST /api/v1/process would publish it, so a parser fixed mid-count corrects results already out. Drift checks still apply, so a large correction may be held in quarantine for review.",
+		Tags:        []string{"processing"},
+		Parameters: []Parameter{
+			query("snapshot", "string", "snapshot hash, a unique prefix of one, or latest, the default"),
+			election,
+			query("async", "boolean", "answer 202 with a job instead of waiting"),
+			query("debug", "boolean", "include the parser's decision trace"),
+			query("dryRun", "boolean", "parse and return the results and warnings without publishing anything"),
+		},
+		Responses: map[string]*Response{
+			"200": r.json("Parsed and published, or parsed only for a dry run", models.ProcessResponse{}),
+			"202": r.json("Queued as a job", models.JobAccepted{}),
+			"400": r.json("The prefix matches several snapshots, or an unknown election", models.ProcessResponse{}),
+			"404": r.json("No archived source for the snapshot, or the county has neither a registration nor results", models.ProcessResponse{}),
+			"409": r.json("Snapshot held in quarantine, or the election is archived", models.ProcessResponse{}),
+			"422": r.json("No results found in the source", models.ProcessResponse{}),
+			"502": r.json("The archive could not be read, or the source could not be parsed", models.ProcessResponse{}),
+		},
+	})
+	d.Add("GET", "/api/v1/results/{county}/log", &Operation{
+		OperationID: "getSnapshotLog",
+		Summary:     "Get a county's append-only snapshot log",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{election},
+		Responses:   map[string]*Response{"200": r.json("The log", models.SnapshotLog{}), "404": r.error("No snapshot log for county, or an unknown election")},
+	})
+	d.Add("GET", "/api/v1/results/{county}/log/proof", &Operation{
+		OperationID: "getInclusionProof",
+		Summary:     "Prove a snapshot is in a county's log",
+		Tags:        []string{"results"},
+		Parameters: []Parameter{
+			query("index", "integer", "log entry to prove"),
+			query("hash", "string", "prove the latest entry with this snapshot hash"),
+			query("size", "integer", "prove against the tree head of this size"),
+			election,
+		},
+		Responses: map[string]*Response{
+			"200": r.json("The proof", models.InclusionProof{}),
+			"400": r.error("Neither index nor hash given, or a malformed one"),
+			"404": r.error("No such log or entry, or an unknown election"),
+		},
+	})
+	d.Add("GET", "/api/v1/events", &Operation{
+		OperationID: "listEvents",
+		Summary:     "Page through the event log published results are projected from, oldest first",
+		Description: "Each published snapshot is an event. Snapshots republished unchanged carry only their parse time and latency; the full snapshot is in the county's earlier event with the same hash. Archiving an election is an event naming no county.",
+		Tags:        []string{"results"},
+		Parameters: []Parameter{
+			query("after", "integer", "only events after this seq"),
+			query("election", "string", "only this election's events"),
+			query("county", "string", "only this county's events"),
+			query("limit", "integer", "at most this many events, 1 to 1000 (default 100)"),
+		},
+		Responses: map[string]*Response{
+			"200": r.json("The events", []models.Event{}),
+			"400": r.error("Invalid after or limit"),
+			"403": r.error(realTimeOnly),
+			"404": r.error("Unknown election"),
+		},
+	})
+	d.Add("POST", "/api/v1/projections/rebuild", &Operation{
+		OperationID: "rebuildProjections",
+		Summary:     "Rebuild the read models from the event log and swap them in at once",
+		Description: "Replays the election's events through the current projection code, so a fix to how read models are derived applies to results already published. Readers are served the old read models until the rebuild completes. Progress is streamed as newline-delimited JSON, ending with the report or an error. Requires an API key with full visibility; `era rebuild-projections` drives it from the command line.",
+		Tags:        []string{"results"},
+		Parameters: []Parameter{
+			election,
+			query("from", "string", "RFC 3339 time; only rebuild counties with events since then, replaying their whole history"),
+		},
+		Responses: map[string]*Response{
+			"200": {Description: "Progress updates, then the report or an error", Content: map[string]*MediaType{"application/x-ndjson": {Schema: d.Schema(models.RebuildUpdate{})}}},
+			"400": r.error("Invalid from"),
+			"403": r.error("The API key doesn't see every field"),
+			"404": r.error("Unknown election"),
+			"409": r.error("A rebuild is already running"),
+		},
+	})
+	d.Add("GET", "/api/v1/results/{county}/contests/{contest}/overlays", &Operation{
+		OperationID: "listOverlays",
+		Summary:     "List a contest's supplementary overlays",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"200": r.json("The overlays", []models.Overlay{})},
+	})
+	d.Add("POST", "/api/v1/results/{county}/contests/{contest}/overlays", &Operation{
+		OperationID: "createOverlay",
+		Summary:     "Attach an exit poll, forecast or other overlay to a contest",
+		Tags:        []string{"results"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.Overlay{})},
+		Responses: map[string]*Response{
+			"201": r.json("Created", models.Overlay{}),
+			"400": r.problem("Invalid overlay"),
+			"404": r.error("No such county or contest"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/overlays/{id}", &Operation{
+		OperationID: "deleteOverlay",
+		Summary:     "Delete an overlay",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("Overlay not found")},
+	})
+	d.Add("GET", "/api/v1/aggregate", &Operation{
+		OperationID: "aggregateContest",
+		Summary:     "Sum a contest across every county reporting it",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{required(query("contest", "string", "contest ID"))},
+		Responses: map[string]*Response{
+			"200": r.json("The aggregate", models.Aggregate{}),
+			"400": r.error("contest is missing"),
+			"403": r.error(realTimeOnly),
+			"404": r.error("No county reports this contest"),
+		},
+	})
+	d.Add("GET", "/api/v1/results/{contest}/geojson", &Operation{
+		OperationID: "getContestGeoJSON",
+		Summary:     "Get a contest's results by county as GeoJSON",
+		Description: "A feature per county reporting the contest, taken as the aggregate takes it, with its votes, leader and margin as properties. Geometry is null: maps join features to their boundaries by ID, the FIPS code of the county's jurisdiction if it has one and otherwise its county key, or by the jurisdiction's boundary reference. Precinct results aren't parsed, so features are of counties only.",
+		Tags:        []string{"results"},
+		Responses: map[string]*Response{
+			"200": {Description: "The feature collection", Content: map[string]*MediaType{"application/geo+json": {Schema: d.Schema(models.FeatureCollection{})}}},
+			"403": r.error(realTimeOnly),
+			"404": r.error("No county reports this contest"),
+		},
+	})
+	d.Add("GET", "/api/v1/turnout", &Operation{
+		OperationID: "getTurnout",
+		Summary:     "Get statewide turnout",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"200": r.json("Turnout by county and in total", models.StatewideTurnout{}), "403": r.error(realTimeOnly)},
+	})
+	d.Add("GET", "/api/v1/search", &Operation{
+		OperationID: "search",
+		Summary:     "Search contests, candidates and measures across counties",
+		Description: "Matches titles and names approximately: whole, by the start of a word, within a word and, allowing for typos, word by word. A contest or candidate spelled the same by several counties is one match, linking each county's results and, in the default election, the contest's aggregate.",
+		Tags:        []string{"results"},
+		Parameters: []Parameter{
+			required(query("q", "string", "what to search for")),
+			query("limit", "integer", "at most this many matches, 1 to 100 (default 20)"),
+			election,
+		},
+		Responses: map[string]*Response{
+			"200": r.json("The matches, best first", models.SearchResults{}),
+			"400": r.error("Missing q, or an invalid limit"),
+			"403": r.error(realTimeOnly),
+			"404": r.error("Unknown election"),
+		},
+	})
+	d.Add("GET", "/api/v1/broadcast/{county}", &Operation{
+		OperationID: "getBroadcastFeed",
+		Summary:     "Get a county's feed for broadcast graphics systems",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{enum(query("format", "string", "kv (default) or xml"), "kv", "xml")},
+		Responses: map[string]*Response{
+			"200": r.files("The feed", "text/plain", "application/xml"),
+			"400": r.error("Unknown format"),
+			"403": r.error(realTimeOnly),
+			"404": r.error("No results for county"),
+		},
+	})
+	d.Add("GET", "/api/v1/dump.ndjson.gz", &Operation{
+		OperationID: "streamDump",
+		Summary:     "Stream every county's results as gzipped NDJSON",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"200": r.files("One Results object per line", "application/gzip"), "403": r.error(realTimeOnly)},
+	})
+	d.Add("GET", "/api/v1/dump/manifest", &Operation{
+		OperationID: "getDumpManifest",
+		Summary:     "Get the parts of the current dump snapshot",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"200": r.json("The manifest", dump.Manifest{}), "403": r.error(realTimeOnly)},
+	})
+	d.Add("GET", "/api/v1/dump/{snapshot}/parts/{index}", &Operation{
+		OperationID: "getDumpPart",
+		Summary:     "Download one part of a dump snapshot",
+		Description: "Range requests are honored so interrupted downloads can resume.",
+		Tags:        []string{"results"},
+		Responses: map[string]*Response{
+			"200": r.files("Gzipped NDJSON", "application/gzip"),
+			"403": r.error(realTimeOnly),
+			"404": r.error("No such part"),
+			"410": r.error("Snapshot expired"),
+		},
+	})
+
+	d.Add("GET", "/api/v1/snippets", &Operation{
+		OperationID: "listSnippets",
+		Summary:     "List embeddable snippets",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"200": r.json("The snippets", []models.Snippet{})},
+	})
+	d.Add("POST", "/api/v1/snippets", &Operation{
+		OperationID: "createSnippet",
+		Summary:     "Create an embeddable snippet of selected contests",
+		Tags:        []string{"results"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.Snippet{})},
+		Responses:   map[string]*Response{"201": r.json("Created", models.Snippet{}), "400": r.problem("Invalid snippet")},
+	})
+	d.Add("GET", "/api/v1/snippets/{id}", &Operation{
+		OperationID: "getSnippet",
+		Summary:     "Get a rendered snippet",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{enum(query("format", "string", "amp (default) for HTML or shortcode for JSON"), "amp", "shortcode")},
+		Responses: map[string]*Response{
+			"200": {Description: "The snippet", Content: map[string]*MediaType{
+				"text/html":        {Schema: &Schema{Type: "string"}},
+				"application/json": {Schema: d.Schema(models.RenderedSnippet{})},
+			}},
+			"400": r.error("Unknown format"),
+			"404": r.error("Snippet not found"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/snippets/{id}", &Operation{
+		OperationID: "deleteSnippet",
+		Summary:     "Delete a snippet",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("Snippet not found")},
+	})
+
+	d.Add("GET", "/lite/counties", &Operation{
+		OperationID: "liteCounties",
+		Summary:     "List counties with results, one per line",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"200": r.files("County keys", "text/plain")},
+	})
+	d.Add("GET", "/lite/{county}", &Operation{
+		OperationID: "liteCounty",
+		Summary:     "Get a county's results in a compact form for low-bandwidth clients",
+		Tags:        []string{"results"},
+		Parameters: []Parameter{
+			query("format", "string", "json for compact JSON instead of text"),
+			query("max", "integer", "truncate the text to this many bytes"),
+		},
+		Responses: map[string]*Response{
+			"200": {Description: "The results", Content: map[string]*MediaType{
+				"text/plain":       {Schema: &Schema{Type: "string"}},
+				"application/json": {Schema: d.Schema(formatter.LiteResults{})},
+			}},
+			"404": r.files("No results", "text/plain"),
+		},
+	})
+	d.Add("GET", "/lite/aggregate/{contest}", &Operation{
+		OperationID: "liteAggregate",
+		Summary:     "Get a contest's aggregate in a compact form",
+		Tags:        []string{"results"},
+		Parameters: []Parameter{
+			query("format", "string", "json for JSON instead of text"),
+			query("max", "integer", "truncate the text to this many bytes"),
+		},
+		Responses: map[string]*Response{
+			"200": {Description: "The aggregate", Content: map[string]*MediaType{
+				"text/plain":       {Schema: &Schema{Type: "string"}},
+				"application/json": {Schema: d.Schema(models.Aggregate{})},
+			}},
+			"403": r.error(realTimeOnly),
+			"404": r.files("No results", "text/plain"),
+		},
+	})
+
+	d.Add("GET", "/api/v1/elections", &Operation{
+		OperationID: "listElections",
+		Summary:     "List the elections results are published for",
+		Description: "Most recent date first. The default election is the one requests that name none are for.",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"200": r.json("The elections", []models.Election{})},
+	})
+	d.Add("POST", "/api/v1/elections", &Operation{
+		OperationID: "createElection",
+		Summary:     "Add an election, such as a primary or special election served beside the general",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.Election{})},
+		Responses: map[string]*Response{
+			"201": r.json("Created", models.Election{}),
+			"400": r.problem("Invalid election"),
+			"409": r.error("An election with the ID exists"),
+		},
+	})
+	d.Add("GET", "/api/v1/elections/{election}", &Operation{
+		OperationID: "getElection",
+		Summary:     "Get an election",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"200": r.json("The election", models.Election{}), "404": r.error("Unknown election")},
+	})
+	d.Add("DELETE", "/api/v1/elections/{election}", &Operation{
+		OperationID: "deleteElection",
+		Summary:     "Remove an election nothing was registered or published for",
+		Tags:        []string{"configuration"},
+		Responses: map[string]*Response{
+			"204": r.empty("Deleted"),
+			"404": r.error("Unknown election"),
+			"409": r.error("The default election, one with registered counties or published results, or one under a legal hold"),
+		},
+	})
+	d.Add("POST", "/api/v1/elections/{election}/archive", &Operation{
+		OperationID: "archiveElection",
+		Summary:     "Archive a past election, freezing its results",
+		Description: "The election's results stay queryable under /api/v1/elections/{election}/results. Nothing more is published for it and its county registrations are removed. Archiving is recorded in the event log and can't be undone.",
+		Tags:        []string{"configuration"},
+		Responses: map[string]*Response{
+			"200": r.json("Archived", models.Election{}),
+			"404": r.error("Unknown election"),
+			"409": r.error("The default election, or one already archived"),
+		},
+	})
+	d.Add("POST", "/api/v1/elections/{election}/results/{county}/certify", &Operation{
+		OperationID: "certifyCounty",
+		Summary:     "Certify a county's results, freezing them and archiving their bundle",
+		Description: "Served when the deployment archives sources. Waits for any run of the county in progress, then archives a ZIP bundle of the live results as results.json and results.csv, the source they were parsed from if it was archived, and manifest.json listing every other file's SHA-256. Nothing more is published for the county and its registration is removed. Certifying is recorded in the event log and can't be undone.",
+		Tags:        []string{"results"},
+		Responses: map[string]*Response{
+			"201": r.json("Certified", models.Certification{}),
+			"403": r.error("The API key doesn't see every field"),
+			"404": r.error("Unknown election, or no results for the county"),
+			"409": r.error("Already certified, the election is archived, or the results changed while certifying"),
+		},
+	})
+	d.Add("GET", "/api/v1/elections/{election}/certifications", &Operation{
+		OperationID: "listCertifications",
+		Summary:     "List an election's certified counties",
+		Description: "Served when the deployment archives sources. By county.",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"200": r.json("Certifications", []models.Certification{}), "404": r.error("Unknown election")},
+	})
+	d.Add("GET", "/api/v1/elections/{election}/results/{county}/certification", &Operation{
+		OperationID: "getCertification",
+		Summary:     "Get the record of a county's certification",
+		Description: "Served when the deployment archives sources. Includes the bundle's permanent URL, its SHA-256 and that of its manifest.",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"200": r.json("Certification", models.Certification{}), "404": r.error("The county's results aren't certified")},
+	})
+	d.Add("GET", "/api/v1/elections/{election}/results/{county}/certification/bundle.zip", &Operation{
+		OperationID: "downloadCertificationBundle",
+		Summary:     "Download a certified county's archived bundle",
+		Description: "The bundle never changes once archived and may be cached indefinitely. Repr-Digest carries its SHA-256.",
+		Tags:        []string{"results"},
+		Responses: map[string]*Response{
+			"200": r.files("The bundle", "application/zip"),
+			"404": r.error("The county's results aren't certified"),
+			"502": r.error("The archive could not be read"),
+		},
+	})
+	d.Add("POST", "/api/v1/elections/{election}/aliases/import", &Operation{
+		OperationID: "importElectionAliases",
+		Summary:     "Seed an election's contest rules and candidate aliases from a prior election",
+		Description: "Copies the contest rules of the election named by from, and adds the county spellings its results resolved to registry candidates as aliases. Importing again only adds what is new.",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(struct {
+			From string `json:"from"`
+		}{})},
+		Responses: map[string]*Response{
+			"200": r.json("What was imported", models.AliasImport{}),
+			"400": r.error("from is missing, unknown or the election itself"),
+			"404": r.error("Unknown election"),
+			"409": r.error("The election is archived"),
+		},
+	})
+	d.Add("GET", "/api/v1/elections/{election}/results", &Operation{
+		OperationID: "listElectionResults",
+		Summary:     "List the counties with results in an election, archived or not",
+		Tags:        []string{"results"},
+		Parameters:  paging,
+		Responses:   map[string]*Response{"200": r.json("The election and its counties' snapshots", models.ElectionResults{}), "400": r.error("Invalid offset or limit"), "404": r.error("Unknown election")},
+	})
+	d.Add("GET", "/api/v1/elections/{election}/results/{county}", &Operation{
+		OperationID: "getElectionResults",
+		Summary:     "Get a county's results in an election",
+		Description: "The same as /api/v1/results/{county}?election=; for an archived election, its final snapshot.",
+		Tags:        []string{"results"},
+		Parameters:  append([]Parameter{query("include", "string", "comma-separated extras: overlays")}, paging...),
+		Responses:   map[string]*Response{"200": r.json("The latest snapshot", models.Results{}), "400": r.error("Invalid offset or limit"), "404": r.error("No results for county, or an unknown election")},
+	})
+	d.Add("GET", "/api/v1/elections/{election}/results/{county}/export", &Operation{
+		OperationID: "exportElectionResults",
+		Summary:     "Download a county's results in an election as a file",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{enum(query("format", "string", "csv (default), xlsx, openelections or cdf"), "csv", "xlsx", "openelections", "cdf")},
+		Responses: map[string]*Response{
+			"200": r.files("The export",
+				"text/csv",
+				"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+				"application/json"),
+			"400": r.error("Unknown format"),
+			"404": r.error("No results for county, or an unknown election"),
+		},
+	})
+	d.Add("GET", "/api/v1/elections/{election}/results/{county}/log", &Operation{
+		OperationID: "getElectionSnapshotLog",
+		Summary:     "Get a county's snapshot log in an election",
+		Tags:        []string{"results"},
+		Responses:   map[string]*Response{"200": r.json("The log", models.SnapshotLog{}), "404": r.error("No snapshot log for county, or an unknown election")},
+	})
+	d.Add("GET", "/api/v1/elections/{election}/results/{county}/log/proof", &Operation{
+		OperationID: "getElectionInclusionProof",
+		Summary:     "Prove a snapshot is in a county's log in an election",
+		Tags:        []string{"results"},
+		Parameters: []Parameter{
+			query("index", "integer", "log entry to prove"),
+			query("hash", "string", "prove the latest entry with this snapshot hash"),
+			query("size", "integer", "prove against the tree head of this size"),
+		},
+		Responses: map[string]*Response{
+			"200": r.json("The proof", models.InclusionProof{}),
+			"400": r.error("Neither index nor hash given, or a malformed one"),
+			"404": r.error("No such log or entry, or an unknown election"),
+		},
+	})
+
+	d.Add("GET", "/api/v1/legal-holds", &Operation{
+		OperationID: "listLegalHolds",
+		Summary:     "List legal holds, most recently placed first",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{enum(query("active", "string", "true for only the holds in force"), "true", "false")},
+		Responses:   map[string]*Response{"200": r.json("The holds", []models.LegalHold{})},
+	})
+	d.Add("POST", "/api/v1/legal-holds", &Operation{
+		OperationID: "placeLegalHold",
+		Summary:     "Place a legal hold on an election or one county in it",
+		Description: "While the hold is in force, quarantine records, parse errors with their files and staged datasets it covers can't be discarded or pruned, and the election can't be deleted. Placing and releasing holds is logged and requires an API key with full visibility.",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.LegalHold{})},
+		Responses: map[string]*Response{
+			"201": r.json("Placed", models.LegalHold{}),
+			"400": r.problem("Invalid hold, or an unknown election"),
+			"403": r.error("The API key doesn't see every field"),
+		},
+	})
+	d.Add("GET", "/api/v1/legal-holds/{id}", &Operation{
+		OperationID: "getLegalHold",
+		Summary:     "Get a legal hold",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"200": r.json("The hold", models.LegalHold{}), "404": r.error("Legal hold not found")},
+	})
+	d.Add("POST", "/api/v1/legal-holds/{id}/release", &Operation{
+		OperationID: "releaseLegalHold",
+		Summary:     "Release a legal hold",
+		Description: "The hold is kept, with who released it and when.",
+		Tags:        []string{"configuration"},
+		Responses: map[string]*Response{
+			"200": r.json("Released", models.LegalHold{}),
+			"403": r.error("The API key doesn't see every field"),
+			"404": r.error("Legal hold not found"),
+			"409": r.error("Already released"),
+		},
+	})
+	d.Add("GET", "/api/v1/audit", &Operation{
+		OperationID: "listAudit",
+		Summary:     "Page through the audit log of changes made through the API, oldest first",
+		Description: "Every request other than a GET, HEAD or OPTIONS that matched a route is recorded once answered, with the API key that made it and its status, whether it succeeded or not. GraphQL queries aren't recorded.",
+		Tags:        []string{"configuration"},
+		Parameters: []Parameter{
+			query("after", "integer", "only entries after this seq"),
+			query("since", "string", "only entries at or after this RFC 3339 time"),
+			query("actor", "string", "only requests made with this API key ID"),
+			query("action", "string", "only requests to this route, such as POST /api/v1/counties"),
+			query("limit", "integer", "at most this many entries, 1 to 1000 (default 100)"),
+		},
+		Responses: map[string]*Response{
+			"200": r.json("The entries", []models.AuditEntry{}),
+			"400": r.error("Invalid after, since or limit"),
+			"403": r.error("The API key doesn't see every field"),
+		},
+	})
+	d.Add("GET", "/api/v1/contests/{id}/call", &Operation{
+		OperationID: "getRaceCall",
+		Summary:     "Get the manual call of a contest",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{election},
+		Responses:   map[string]*Response{"200": r.json("The call", models.ManualCall{}), "404": r.error("No manual call, or an unknown election")},
+	})
+	d.Add("POST", "/api/v1/contests/{id}/call", &Operation{
+		OperationID: "callRace",
+		Summary:     "Call a contest for a candidate",
+		Description: "The winner is one of the contest's candidates, or Yes or No for a measure. The call overrides what the count says in results, the aggregate and update streams until it is retracted, and is logged with the API key that made it.",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{election},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(struct {
+			Winner string `json:"winner"`
+		}{})},
+		Responses: map[string]*Response{
+			"200": r.json("Called", models.ManualCall{}),
+			"400": r.error("winner is missing or not a candidate"),
+			"403": r.error("The API key doesn't see every field"),
+			"404": r.error("The election doesn't report the contest, or is unknown"),
+			"409": r.error("The election is archived"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/contests/{id}/call", &Operation{
+		OperationID: "retractRaceCall",
+		Summary:     "Retract the manual call of a contest",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{election},
+		Responses: map[string]*Response{
+			"204": r.empty("Retracted"),
+			"403": r.error("The API key doesn't see every field"),
+			"404": r.error("No manual call, or an unknown election"),
+		},
+	})
+
+	d.Add("GET", "/api/v1/counties", &Operation{
+		OperationID: "listCounties",
+		Summary:     "List the county sources registered in an election",
+		Description: "Sources whose last fetch failed include the county's contact, unless the caller's key hides contacts.",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{election},
+		Responses: map[string]*Response{
+			"200": r.json("The sources and their fetch status", []models.CountySource{}),
+			"404": r.error("Unknown election"),
+		},
+	})
+	d.Add("POST", "/api/v1/counties", &Operation{
+		OperationID: "registerCounty",
+		Summary:     "Register a county source for scheduled refreshes",
+		Description: "The source is registered in the election its body or ?election= names, the deployment's by default. A county can be registered in several elections. fileLink may be an ftp:// URL, fetched with the county's credentials if it has any, or an s3:// or gs:// one naming a bucket and object key, fetched with the county's credentials or the deployment's keys; one ending in / or in a glob such as *.zip fetches the most recently modified file of the directory or prefix, or the newest whose name matches. With watch set, fileLink is a downloads page or directory listing rather than the source: each refresh ingests the newest file linked from it whose name matches watch.pattern and that it hasn't seen, ordering names with their numbers compared by value. Older unseen files are taken as superseded, and the first refresh ingests only the newest; status.lastFile names the file last ingested. browser loads fileLink in the headless browser, as for /api/v1/process, and can't be combined with watch. With email set, unseen messages in the deployment's watched mailbox from an email.from sender, with email.subject in their subject, have their attachments matching email.attachment, or named with the parse method's extension, processed as the county's source; a county that only emails its results may leave out fileLink.",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{query("election", "string", "register in this election; overrides the body")},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.CountySource{})},
+		Responses: map[string]*Response{
+			"201": r.json("Registered", models.CountySource{}),
+			"400": r.problem("Invalid source, or an unknown election"),
+			"409": r.error("The election is archived"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/counties/{county}", &Operation{
+		OperationID: "deleteCounty",
+		Summary:     "Stop refreshing a county",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{election},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("County not registered")},
+	})
+	d.Add("GET", "/api/v1/counties/{county}/credentials", &Operation{
+		OperationID: "getCredentials",
+		Summary:     "Get the login used to fetch a county's sources, without its password",
+		Tags:        []string{"configuration"},
+		Responses: map[string]*Response{
+			"200": r.json("The credentials", models.SourceCredentials{}),
+			"403": r.error("The API key doesn't have full visibility"),
+			"404": r.error("No credentials for the county"),
+		},
+	})
+	d.Add("PUT", "/api/v1/counties/{county}/credentials", &Operation{
+		OperationID: "putCredentials",
+		Summary:     "Create or replace the login used to fetch a county's sources",
+		Description: "Every fetch for the county logs in with it: as the FTP user for ftp:// fileLinks, which are fetched anonymously otherwise, with the username as access key ID and the password as secret for s3:// and gs:// ones in place of the deployment's keys, and with HTTP basic authentication for others. The password is kept but never served back. The county need not be registered yet.",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.SourceCredentials{})},
+		Responses: map[string]*Response{
+			"200": r.json("Replaced", models.SourceCredentials{}),
+			"201": r.json("Created", models.SourceCredentials{}),
+			"400": r.problem("Invalid credentials"),
+			"403": r.error("The API key doesn't have full visibility"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/counties/{county}/credentials", &Operation{
+		OperationID: "deleteCredentials",
+		Summary:     "Delete the login used to fetch a county's sources",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("No credentials for the county")},
+	})
+
+	d.Add("GET", "/api/v1/contacts", &Operation{
+		OperationID: "listContacts",
+		Summary:     "List county contacts for escalating source outages",
+		Tags:        []string{"configuration"},
+		Responses: map[string]*Response{
+			"200": r.json("The contacts, by county", []models.CountyContact{}),
+			"403": r.error("Contacts are hidden from the caller's key"),
+		},
+	})
+	d.Add("GET", "/api/v1/contacts/{county}", &Operation{
+		OperationID: "getContact",
+		Summary:     "Get a county's contact",
+		Tags:        []string{"configuration"},
+		Responses: map[string]*Response{
+			"200": r.json("The contact", models.CountyContact{}),
+			"403": r.error("Contacts are hidden from the caller's key"),
+			"404": r.error("No contact for the county"),
+		},
+	})
+	d.Add("PUT", "/api/v1/contacts/{county}", &Operation{
+		OperationID: "putContact",
+		Summary:     "Create or replace a county's contact",
+		Description: "The county need not be registered yet.",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.CountyContact{})},
+		Responses: map[string]*Response{
+			"200": r.json("Replaced", models.CountyContact{}),
+			"201": r.json("Created", models.CountyContact{}),
+			"400": r.problem("Invalid contact"),
+			"403": r.error("Contacts are hidden from the caller's key"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/contacts/{county}", &Operation{
+		OperationID: "deleteContact",
+		Summary:     "Delete a county's contact",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("No contact for the county")},
+	})
+
+	d.Add("GET", "/api/v1/candidates", &Operation{
+		OperationID: "listCandidates",
+		Summary:     "List the candidate registry",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"200": r.json("The candidates", []models.CanonicalCandidate{})},
+	})
+	d.Add("POST", "/api/v1/candidates", &Operation{
+		OperationID: "createCandidate",
+		Summary:     "Add a canonical candidate",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.CanonicalCandidate{})},
+		Responses:   map[string]*Response{"201": r.json("Created", models.CanonicalCandidate{}), "400": r.problem("Invalid candidate")},
+	})
+	d.Add("GET", "/api/v1/candidates/match", &Operation{
+		OperationID: "matchCandidate",
+		Summary:     "Resolve a name as printed by a county to a canonical candidate",
+		Tags:        []string{"configuration"},
+		Parameters: []Parameter{
+			required(query("name", "string", "name to resolve")),
+			query("contest", "string", "contest ID to restrict the match to"),
+		},
+		Responses: map[string]*Response{
+			"200": r.json("The match", normalize.Match{}),
+			"400": r.error("name is missing"),
+			"404": r.error("No confident match"),
+		},
+	})
+	d.Add("POST", "/api/v1/candidates/{id}/aliases", &Operation{
+		OperationID: "addCandidateAliases",
+		Summary:     "Add aliases to a canonical candidate",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(struct {
+			Aliases []string `json:"aliases"`
+		}{})},
+		Responses: map[string]*Response{
+			"200": r.json("The updated candidate", models.CanonicalCandidate{}),
+			"400": r.problem("Invalid aliases"),
+			"404": r.error("Candidate not found"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/candidates/{id}", &Operation{
+		OperationID: "deleteCandidate",
+		Summary:     "Remove a canonical candidate",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("Candidate not found")},
+	})
+
+	d.Add("GET", "/api/v1/contest-rules", &Operation{
+		OperationID: "listContestRules",
+		Summary:     "List contest title rules",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{query("election", "string", "election ID; defaults to the deployment's")},
+		Responses:   map[string]*Response{"200": r.json("The rules", []models.ContestRule{})},
+	})
+	d.Add("POST", "/api/v1/contest-rules", &Operation{
+		OperationID: "createContestRule",
+		Summary:     "Add a rule mapping county contest titles to a canonical contest",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.ContestRule{})},
+		Responses:   map[string]*Response{"201": r.json("Created", models.ContestRule{}), "400": r.problem("Invalid rule")},
+	})
+	d.Add("GET", "/api/v1/contest-rules/test", &Operation{
+		OperationID: "testContestRules",
+		Summary:     "Show which rule a contest title resolves to",
+		Tags:        []string{"configuration"},
+		Parameters: []Parameter{
+			required(query("title", "string", "contest title as printed by the county")),
+			query("county", "string", "county the title comes from"),
+			election,
+		},
+		Responses: map[string]*Response{"200": r.json("The resolution", ruleTest{}), "400": r.error("title is missing")},
+	})
+	d.Add("DELETE", "/api/v1/contest-rules/{id}", &Operation{
+		OperationID: "deleteContestRule",
+		Summary:     "Delete a contest rule",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("Rule not found")},
+	})
+	d.Add("GET", "/api/v1/measure-links", &Operation{
+		OperationID: "listMeasureLinks",
+		Summary:     "List the links joining a regional measure's contests across counties",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{election},
+		Responses:   map[string]*Response{"200": r.json("The links", []models.MeasureLink{}), "404": r.error("Unknown election")},
+	})
+	d.Add("POST", "/api/v1/measure-links", &Operation{
+		OperationID: "createMeasureLink",
+		Summary:     "Link the contests counties report one measure as",
+		Description: "The link's ID, the slug of its title by default, is the contest ID the measure aggregates under; each member county's contest counts toward it under that county's own ID. A link with the same ID is replaced.",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{election},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.MeasureLink{})},
+		Responses: map[string]*Response{
+			"201": r.json("Created", models.MeasureLink{}),
+			"400": r.problem("Invalid link"),
+			"404": r.error("Unknown election"),
+		},
+	})
+	d.Add("GET", "/api/v1/measure-links/suggestions", &Operation{
+		OperationID: "suggestMeasureLinks",
+		Summary:     "Suggest links for measures that read alike across counties",
+		Description: "Measures reported under different contest IDs whose titles match once letters and numbers such as \"Measure A\" are set aside, best matches first. Measures already linked aren't suggested.",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{election},
+		Responses:   map[string]*Response{"200": r.json("The suggestions", []models.MeasureLinkSuggestion{}), "404": r.error("Unknown election")},
+	})
+	d.Add("DELETE", "/api/v1/measure-links/{id}", &Operation{
+		OperationID: "deleteMeasureLink",
+		Summary:     "Delete a measure link",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{election},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("Link not found, or an unknown election")},
+	})
+	d.Add("GET", "/api/v1/jurisdictions", &Operation{
+		OperationID: "listJurisdictions",
+		Summary:     "List the jurisdiction tree",
+		Description: "Every jurisdiction, states first, then counties, cities and districts, then precincts.",
+		Tags:        []string{"configuration"},
+		Parameters: []Parameter{
+			enum(query("type", "string", "only jurisdictions of this type"), models.JurisdictionTypes...),
+			query("parent", "string", "only jurisdictions directly under this one; empty for the roots"),
+		},
+		Responses: map[string]*Response{"200": r.json("The jurisdictions", []models.Jurisdiction{})},
+	})
+	d.Add("POST", "/api/v1/jurisdictions", &Operation{
+		OperationID: "createJurisdiction",
+		Summary:     "Add a jurisdiction to the tree",
+		Description: "A parent must be at a higher level: a state above counties, a county above cities and districts, any of them above precincts. A county's ID is its county key, the one its results are stored under; others default to the slug of their parent's ID and name.",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.Jurisdiction{})},
+		Responses: map[string]*Response{
+			"201": r.json("Created", models.Jurisdiction{}),
+			"400": r.problem("Invalid jurisdiction, or a parent that doesn't exist or isn't above it"),
+			"409": r.error("Jurisdiction already exists"),
+		},
+	})
+	d.Add("GET", "/api/v1/jurisdictions/{id}", &Operation{
+		OperationID: "getJurisdiction",
+		Summary:     "Get a jurisdiction with its ancestors, children and contests",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{election},
+		Responses:   map[string]*Response{"200": r.json("The jurisdiction", models.JurisdictionView{}), "404": r.error("Jurisdiction not found, or an unknown election")},
+	})
+	d.Add("DELETE", "/api/v1/jurisdictions/{id}", &Operation{
+		OperationID: "deleteJurisdiction",
+		Summary:     "Delete a jurisdiction",
+		Tags:        []string{"configuration"},
+		Responses: map[string]*Response{
+			"204": r.empty("Deleted"),
+			"404": r.error("Jurisdiction not found"),
+			"409": r.error("Jurisdiction has children or contests attached"),
+		},
+	})
+	d.Add("POST", "/api/v1/jurisdictions/{id}/contests", &Operation{
+		OperationID: "attachJurisdictionContest",
+		Summary:     "Hold a contest at a jurisdiction",
+		Description: "Results then report the contest's jurisdiction. A contest is held at one jurisdiction per election; attaching it elsewhere moves it.",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{election},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(struct {
+			ContestID string `json:"contestId"`
+		}{})},
+		Responses: map[string]*Response{
+			"200": r.json("The jurisdiction", models.JurisdictionView{}),
+			"400": r.error("Missing contestId"),
+			"404": r.error("Jurisdiction not found, or an unknown election"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/jurisdictions/{id}/contests/{contest}", &Operation{
+		OperationID: "detachJurisdictionContest",
+		Summary:     "Stop holding a contest at a jurisdiction",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{election},
+		Responses:   map[string]*Response{"204": r.empty("Detached"), "404": r.error("Contest isn't attached there, or an unknown election")},
+	})
+	d.Add("GET", "/api/v1/districts", &Operation{
+		OperationID: "listDistricts",
+		Summary:     "List the districts drawn from precincts",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{enum(query("type", "string", "only districts of this type"), models.DistrictTypes...)},
+		Responses:   map[string]*Response{"200": r.json("The districts, by type and name", []models.District{})},
+	})
+	districtBody := d.JSON([]models.District{})
+	districtBody["text/csv"] = &MediaType{Schema: &Schema{Type: "string"}}
+	d.Add("POST", "/api/v1/districts/import", &Operation{
+		OperationID: "importDistricts",
+		Summary:     "Import districts and the precincts they are drawn from",
+		Description: "JSON is an array of districts, or one. CSV has a header row of district_id, district, type, county and precinct columns, one row per precinct of a district; county and precinct are required. Precincts are named as their county's results name them. Districts without an ID get the slug of their name; those already defined with the same ID are replaced.",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{enum(query("format", "string", "json or csv; defaults to the Content-Type, then the content"), "json", "csv")},
+		RequestBody: &RequestBody{Required: true, Content: districtBody},
+		Responses: map[string]*Response{
+			"200": r.json("The districts imported", models.DistrictImport{}),
+			"400": r.problem("Invalid districts"),
+			"413": r.error("District file too large"),
+		},
+	})
+	d.Add("GET", "/api/v1/districts/{id}", &Operation{
+		OperationID: "getDistrict",
+		Summary:     "Get a district and its precincts",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"200": r.json("The district", models.District{}), "404": r.error("District not found")},
+	})
+	d.Add("DELETE", "/api/v1/districts/{id}", &Operation{
+		OperationID: "deleteDistrict",
+		Summary:     "Delete a district",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("District not found")},
+	})
+	d.Add("GET", "/api/v1/districts/{id}/results", &Operation{
+		OperationID: "getDistrictResults",
+		Summary:     "Roll a district's precinct results up into its own",
+		Description: "Every contest with votes in the district's precincts, summed over them, with candidates combined across counties as in the aggregate. Only sources that report votes by precinct contribute; precincts no county's results have votes for are listed as unmatched. The outcome has shares, leader and margin but no call.",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{election},
+		Responses: map[string]*Response{
+			"200": r.json("The district's results", models.DistrictResults{}),
+			"403": r.error(realTimeOnly),
+			"404": r.error("District not found, or an unknown election"),
+		},
+	})
+	electionParam := query("election", "string", "election ID; defaults to the deployment's")
+	d.Add("GET", "/api/v1/manifest", &Operation{
+		OperationID: "getManifest",
+		Summary:     "Get the election's expected ballot",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{electionParam},
+		Responses:   map[string]*Response{"200": r.json("The manifest", models.Manifest{}), "404": r.error("No manifest for election")},
+	})
+	manifestBody := d.JSON(models.Manifest{})
+	manifestBody["text/csv"] = &MediaType{Schema: &Schema{Type: "string"}}
+	d.Add("PUT", "/api/v1/manifest", &Operation{
+		OperationID: "putManifest",
+		Summary:     "Replace the election's expected ballot",
+		Description: "JSON is a manifest or an array of its contests. CSV has a header row of contest_id, contest, type, district, counties and candidate, party columns, one row per candidate, with counties separated by semicolons; only contest is required. Contests without an ID get the slug of their title, which should be the canonical ID contest rules map county titles onto.",
+		Tags:        []string{"configuration"},
+		Parameters: []Parameter{
+			electionParam,
+			enum(query("format", "string", "json or csv; defaults to the Content-Type, then the content"), "json", "csv"),
+		},
+		RequestBody: &RequestBody{Required: true, Content: manifestBody},
+		Responses: map[string]*Response{
+			"200": r.json("The imported manifest", models.Manifest{}),
+			"400": r.problem("Invalid manifest"),
+			"413": r.error("Manifest too large"),
+		},
+	})
+	d.Add("POST", "/api/v1/manifest/import", &Operation{
+		OperationID: "importManifest",
+		Summary:     "Replace the election's expected ballot with one downloaded from a URL",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{electionParam},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(struct {
+			URL    string `json:"url"`
+			Format string `json:"format,omitempty"`
+		}{})},
+		Responses: map[string]*Response{
+			"200": r.json("The imported manifest", models.Manifest{}),
+			"400": r.problem("Invalid manifest or missing url"),
+			"403": r.error("URL refused by the fetch policy"),
+			"502": r.error("Download failed"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/manifest", &Operation{
+		OperationID: "deleteManifest",
+		Summary:     "Delete the election's expected ballot",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{electionParam},
+		Responses:   map[string]*Response{"204": r.empty("Deleted"), "404": r.error("No manifest for election")},
+	})
+	d.Add("GET", "/api/v1/manifest/check", &Operation{
+		OperationID: "checkManifest",
+		Summary:     "Compare published results with the election's expected ballot",
+		Description: "One check per county with results, plus counties the manifest lists that have published nothing.",
+		Tags:        []string{"configuration"},
+		Parameters:  []Parameter{electionParam, query("county", "string", "only this county")},
+		Responses: map[string]*Response{
+			"200": r.json("The checks", []models.ManifestCheck{}),
+			"404": r.error("No manifest for election"),
+		},
+	})
+
+	d.Add("GET", "/api/v1/templates", &Operation{
+		OperationID: "listTemplates",
+		Summary:     "List HTML fragment templates",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"200": r.json("The templates without their source", []templates.Info{})},
+	})
+	d.Add("GET", "/api/v1/templates/assignments", &Operation{
+		OperationID: "getTemplateAssignments",
+		Summary:     "Get which template each county and content type uses",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"200": r.json("The assignments", templates.Assignments{})},
+	})
+	d.Add("POST", "/api/v1/render", &Operation{
+		OperationID: "renderResults",
+		Summary:     "Render results supplied in the request",
+		Description: "For frontends showing results obtained elsewhere as published ones look. The contests are given IDs, the deployment's transforms and measure outcomes, and rendered with template or the one assigned to the county or content type, falling back to the standard fragment. Nothing is saved.",
+		Tags:        []string{"results"},
+		Parameters:  []Parameter{enum(query("format", "string", "html (default), or json for the HTML with the results as rendered"), "html", "json")},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(models.RenderRequest{})},
+		Responses: map[string]*Response{
+			"200": {Description: "The rendered results", Content: map[string]*MediaType{
+				"text/html":        {Schema: &Schema{Type: "string"}},
+				"application/json": {Schema: d.Schema(models.RenderResponse{})},
+			}},
+			"400": r.problem("Invalid request or unknown template"),
+			"422": r.error("The template failed on the results"),
+		},
+	})
+	d.Add("PUT", "/api/v1/templates/assignments", &Operation{
+		OperationID: "setTemplateAssignments",
+		Summary:     "Replace the template assignments",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(templates.Assignments{})},
+		Responses:   map[string]*Response{"200": r.json("The assignments", templates.Assignments{}), "400": r.error("Unknown template")},
+	})
+	d.Add("GET", "/api/v1/templates/{name}", &Operation{
+		OperationID: "getTemplate",
+		Summary:     "Get a template with its source",
+		Tags:        []string{"configuration"},
+		Responses:   map[string]*Response{"200": r.json("The template", templates.Info{}), "404": r.error("Template not found")},
+	})
+	d.Add("PUT", "/api/v1/templates/{name}", &Operation{
+		OperationID: "putTemplate",
+		Summary:     "Create or replace a template",
+		Tags:        []string{"configuration"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(struct {
+			Source string `json:"source"`
+		}{})},
+		Responses: map[string]*Response{
+			"200": r.json("Replaced", templates.Info{}),
+			"201": r.json("Created", templates.Info{}),
+			"400": r.error("Invalid name or template"),
+		},
+	})
+	d.Add("DELETE", "/api/v1/templates/{name}", &Operation{
+		OperationID: "deleteTemplate",
+		Summary:     "Delete an unassigned template",
+		Tags:        []string{"configuration"},
+		Responses: map[string]*Response{
+			"204": r.empty("Deleted"),
+			"404": r.error("Template not found"),
+			"409": r.error("Template is assigned"),
+		},
+	})
+
+	graphqlResponses := map[string]*Response{
+		"200": r.json("The response", graphql.Response{}),
+		"400": r.json("The query failed to parse or validate", graphql.Response{}),
+		"403": r.error(realTimeOnly),
+	}
+	d.Add("GET", "/graphql", &Operation{
+		OperationID: "graphqlGet",
+		Summary:     "Run a read-only GraphQL query",
+		Tags:        []string{"results"},
+		Parameters: []Parameter{
+			required(query("query", "string", "the query document")),
+			query("operationName", "string", "operation to run"),
+			query("variables", "string", "variables as a JSON object"),
+		},
+		Responses: graphqlResponses,
+	})
+	d.Add("POST", "/graphql", &Operation{
+		OperationID: "graphqlPost",
+		Summary:     "Run a read-only GraphQL query",
+		Tags:        []string{"results"},
+		RequestBody: &RequestBody{Required: true, Content: d.JSON(graphql.Request{})},
+		Responses:   graphqlResponses,
+	})
+
+	d.Add("GET", "/.well-known/era.json", &Operation{
+		OperationID: "discover",
+		Summary:     "Describe this deployment for partner tooling",
+		Tags:        []string{"meta"},
+		Responses:   map[string]*Response{"200": r.json("The discovery document", models.Discovery{})},
+	})
+	d.Add("GET", "/api/v1/openapi.json", &Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "Get this document",
+		Tags:        []string{"meta"},
+		Responses:   map[string]*Response{"200": r.json("The OpenAPI document", map[string]any{})},
+	})
+	d.Add("GET", "/api/v1/docs", &Operation{
+		OperationID: "getDocs",
+		Summary:     "Browse this document interactively",
+		Tags:        []string{"meta"},
+		Responses:   map[string]*Response{"200": r.files("Swagger UI", "text/html")},
+	})
+	d.Add("GET", "/health", &Operation{
+		OperationID: "health",
+		Summary:     "Report whether the server is healthy",
+		Tags:        []string{"meta"},
+		Responses: map[string]*Response{"200": r.json("Healthy, or degraded when the clock is skewed", struct {
+			Status   string        `json:"status"`
+			Region   string        `json:"region,omitempty"`
+			Instance string        `json:"instance"`
+			Clock    *clock.Status `json:"clock,omitempty"`
+		}{})},
+	})
+
+	d.Add("GET", "/version", &Operation{
+		OperationID: "getVersion",
+		Summary:     "Identify the running build, its parser versions and enabled features",
+		Tags:        []string{"meta"},
+		Responses:   map[string]*Response{"200": r.json("The build", models.VersionInfo{})},
+	})
+
+	for _, path := range cachedPaths {
+		op := (*d.Paths[path])["get"]
+		op.Parameters = append(op.Parameters, Parameter{Name: "If-None-Match", In: "header", Description: "ETag of a copy already held", Schema: &Schema{Type: "string"}})
+		op.Responses["304"] = r.empty("Unchanged since the ETag given")
+	}
+	return d
+}
+
+// cachedPaths are the reads served with ETags and Cache-Control headers.
+var cachedPaths = []string{
+	"/api/v1/results/{county}",
+	"/api/v1/results/{county}/export",
+	"/api/v1/results/{county}/log",
+	"/api/v1/results/{county}/log/proof",
+	"/api/v1/results/{county}/contests/{contest}/overlays",
+	"/api/v1/aggregate",
+	"/api/v1/results/{contest}/geojson",
+	"/api/v1/districts/{id}/results",
+	"/api/v1/turnout",
+	"/api/v1/search",
+	"/lite/counties",
+	"/lite/{county}",
+	"/lite/aggregate/{contest}",
+}
+
+// responses builds the responses of the operations in d.
+type responses struct {
+	d *Document
+}
+
+func (r responses) json(desc string, v any) *Response {
+	return &Response{Description: desc, Content: r.d.JSON(v)}
+}
+
+func (r responses) error(desc string) *Response {
+	return r.json(desc, errorBody{})
+}
+
+// problem is a request rejected with an RFC 7807 problem body.
+func (r responses) problem(desc string) *Response {
+	return &Response{Description: desc, Content: map[string]*MediaType{"application/problem+json": {Schema: r.d.Schema(models.Problem{})}}}
+}
+
+func (r responses) empty(desc string) *Response {
+	return &Response{Description: desc}
+}
+
+// files is a response whose body is a document in one of contentTypes,
+// rather than JSON described by a schema.
+func (r responses) files(desc string, contentTypes ...string) *Response {
+	content := make(map[string]*MediaType, len(contentTypes))
+	for _, ct := range contentTypes {
+		s := &Schema{Type: "string"}
+		if ct == "application/gzip" || ct == "application/octet-stream" || ct == "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+			s.Format = "binary"
+		}
+		content[ct] = &MediaType{Schema: s}
+	}
+	return &Response{Description: desc, Content: content}
+}
+
+func query(name, typ, desc string) Parameter {
+	return Parameter{Name: name, In: "query", Description: desc, Schema: &Schema{Type: typ}}
+}
+
+func required(p Parameter) Parameter {
+	p.Required = true
+	return p
+}
+
+func enum(p Parameter, values ...string) Parameter {
+	p.Schema.Enum = values
+	return p
+}
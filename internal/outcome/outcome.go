@@ -0,0 +1,116 @@
+// Package outcome derives what a contest's count says: each candidate's
+// percentage, the leader and margin, and whether the race can be called
+// under the configured rules. Frontends and exports read these instead of
+// redoing the math, so they all agree.
+package outcome
+
+import (
+	"math"
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// Percent returns n as a percentage of total, rounded to two places, and 0
+// if total is 0.
+func Percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)*10000/float64(total)) / 100
+}
+
+// Compute returns the outcome of c under rules.
+func Compute(c models.Contest, rules models.OutcomeRules) *models.ContestOutcome {
+	total := c.TotalVotes()
+	o := &models.ContestOutcome{
+		TotalVotes:       total,
+		ReportingPercent: Percent(c.PrecinctsReporting, c.PrecinctsTotal),
+		Shares:           make([]models.CandidateShare, 0, len(c.Candidates)),
+	}
+	// The combined uncertified write-ins aren't a candidate, so they can't
+	// lead.
+	var standings []models.CandidateShare
+	for _, cand := range c.Candidates {
+		share := models.CandidateShare{Name: cand.Name, Votes: cand.Votes, Percent: Percent(cand.Votes, total)}
+		o.Shares = append(o.Shares, share)
+		if !cand.WriteInAggregate {
+			standings = append(standings, share)
+		}
+	}
+	counted := total
+	if c.RCV != nil && len(c.RCV.Rounds) > 0 {
+		standings, counted = lastRound(c.RCV.Rounds[len(c.RCV.Rounds)-1])
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool { return standings[i].Votes > standings[j].Votes })
+	if len(standings) > 0 && standings[0].Votes > 0 {
+		o.Margin = standings[0].Votes
+		if len(standings) > 1 {
+			o.Margin -= standings[1].Votes
+		}
+		o.MarginPoints = Percent(o.Margin, counted)
+		if o.Margin == 0 {
+			o.Tied = true
+		} else {
+			o.Leader = standings[0].Name
+		}
+	}
+
+	if call, ok := thresholdCall(c, o, rules); ok {
+		o.Call = call
+	}
+	return o
+}
+
+// Apply sets the outcome of every contest in results under rules. A manual
+// call in calls, keyed by contest ID, stands in for the count's.
+func Apply(results *models.Results, rules models.OutcomeRules, calls map[string]models.ManualCall) {
+	for i := range results.Contests {
+		o := Compute(results.Contests[i], rules)
+		if call, ok := calls[results.Contests[i].ID]; ok {
+			o.Call = &call.RaceCall
+		}
+		results.Contests[i].Outcome = o
+	}
+}
+
+// thresholdCall calls c for its leader, or for a measure's passing side,
+// once the count meets rules. A ranked-choice contest is only called once
+// its tabulation is final.
+func thresholdCall(c models.Contest, o *models.ContestOutcome, rules models.OutcomeRules) (*models.RaceCall, bool) {
+	if c.PrecinctsTotal == 0 || o.ReportingPercent < rules.MinReportingPercent || o.TotalVotes == 0 {
+		return nil, false
+	}
+	call := &models.RaceCall{Method: models.CallThreshold}
+	switch {
+	case c.Measure != nil:
+		call.Winner = "No"
+		if c.Measure.Passing {
+			call.Winner = "Yes"
+		}
+		return call, true
+	case c.RCV != nil:
+		if !c.RCV.Final || c.RCV.Winner == "" {
+			return nil, false
+		}
+		call.Winner = c.RCV.Winner
+		return call, true
+	case o.Leader == "" || o.MarginPoints < rules.MinMarginPoints:
+		return nil, false
+	}
+	call.Winner = o.Leader
+	return call, true
+}
+
+func lastRound(round models.RCVRound) ([]models.CandidateShare, int) {
+	total := 0
+	for _, t := range round.Tallies {
+		total += t.Votes
+	}
+	out := make([]models.CandidateShare, 0, len(round.Tallies))
+	for _, t := range round.Tallies {
+		out = append(out, models.CandidateShare{Name: t.Candidate, Votes: t.Votes, Percent: Percent(t.Votes, total)})
+	}
+	return out, total
+}
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// sniffLen is how much of a source the detectors look at, past any byte
+// order mark and leading space.
+const sniffLen = 4096
+
+func init() {
+	Register(models.ParserInfo{
+		Method:       models.ParseMethodZIP,
+		Description:  "ZIP archive of XML, CSV, PDF and RCTab summary JSON files, merged",
+		Extensions:   []string{".zip"},
+		Capabilities: []string{models.ParserTurnout, models.ParserRCV, models.ParserArchive, models.ParserDetect},
+	}, builtin{detectZIP, parseZIP})
+	Register(models.ParserInfo{
+		Method:       models.ParseMethodHTML,
+		Description:  "county results page, one contest per titled table",
+		Extensions:   []string{".html", ".htm"},
+		Capabilities: []string{models.ParserTurnout, models.ParserDetect},
+	}, builtin{detectHTML, ignoreContext(parseHTML)})
+	Register(models.ParserInfo{
+		Method:       models.ParseMethodPDF,
+		Description:  "text PDF canvass report; scanned pages yield nothing",
+		Extensions:   []string{".pdf"},
+		Capabilities: []string{models.ParserTurnout, models.ParserDetect},
+	}, builtin{detectPDF, parsePDF})
+	Register(models.ParserInfo{
+		Method:       models.ParseMethodXML,
+		Description:  "Clarity ENR or generic <Contest> XML",
+		Extensions:   []string{".xml"},
+		Capabilities: []string{models.ParserTurnout, models.ParserRCV, models.ParserDetect},
+	}, builtin{detectXML, ignoreContext(parseXML)})
+	Register(models.ParserInfo{
+		Method:       models.ParseMethodCSV,
+		Description:  "CSV with contest, candidate and votes columns, and optionally a round column",
+		Extensions:   []string{".csv"},
+		Capabilities: []string{models.ParserTurnout, models.ParserRCV, models.ParserDetect},
+	}, builtin{detectCSV, ignoreContext(parseCSV)})
+	Register(models.ParserInfo{
+		Method:       models.ParseMethodJSON,
+		Description:  "JSON with arrays of candidate objects carrying names and votes",
+		Extensions:   []string{".json"},
+		Capabilities: []string{models.ParserTurnout, models.ParserDetect},
+	}, builtin{detectJSON, ignoreContext(parseJSON)})
+}
+
+// sniff returns the start of data past any UTF-8 byte order mark and
+// leading space.
+func sniff(data []byte) []byte {
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	return data[:min(len(data), sniffLen)]
+}
+
+func detectZIP(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06"))
+}
+
+func detectPDF(data []byte) bool {
+	// Some generators put junk before the header, which readers tolerate
+	// within the first kilobyte.
+	return bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-"))
+}
+
+func detectHTML(data []byte) bool {
+	head := bytes.ToLower(sniff(data))
+	if bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.HasPrefix(head, []byte("<html")) {
+		return true
+	}
+	// Fragments served without a document around them.
+	return bytes.HasPrefix(head, []byte("<")) && !bytes.HasPrefix(head, []byte("<?xml")) &&
+		(bytes.Contains(head, []byte("<table")) || bytes.Contains(head, []byte("<div")) || bytes.Contains(head, []byte("<body")))
+}
+
+func detectXML(data []byte) bool {
+	if !bytes.HasPrefix(sniff(data), []byte("<")) {
+		return false
+	}
+	root, err := xmlRootName(data)
+	return err == nil && !strings.EqualFold(root, "html")
+}
+
+func detectJSON(data []byte) bool {
+	head := sniff(data)
+	return len(head) > 0 && (head[0] == '{' || head[0] == '[') && json.Valid(data)
+}
+
+// detectCSV takes data for CSV if its first lines read as records of two
+// or more fields.
+func detectCSV(data []byte) bool {
+	head := sniff(data)
+	if len(head) == 0 || bytes.IndexByte(head, 0) >= 0 || bytes.ContainsAny(head[:1], "<{[") {
+		return false
+	}
+	r := csv.NewReader(bytes.NewReader(head))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows := 0
+	for rows < 5 {
+		rec, err := r.Read()
+		if err != nil {
+			// Past the end, or where the sniffed prefix cut a record short.
+			break
+		}
+		if len(rec) < 2 {
+			return false
+		}
+		rows++
+	}
+	return rows > 0
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"slices"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// ErrUndetected is returned by Detect for a source no parser recognises.
+var ErrUndetected = errors.New("could not detect the source's format")
+
+// mediaTypes are the methods Content-Type headers name. Servers often send
+// a generic type, or the wrong one, so a header only settles the method
+// when the content agrees or nothing else recognises it.
+var mediaTypes = map[string]string{
+	"application/zip":              models.ParseMethodZIP,
+	"application/x-zip-compressed": models.ParseMethodZIP,
+	"application/pdf":              models.ParseMethodPDF,
+	"application/json":             models.ParseMethodJSON,
+	"text/json":                    models.ParseMethodJSON,
+	"text/html":                    models.ParseMethodHTML,
+	"application/xhtml+xml":        models.ParseMethodHTML,
+	"application/xml":              models.ParseMethodXML,
+	"text/xml":                     models.ParseMethodXML,
+	"text/csv":                     models.ParseMethodCSV,
+	"application/csv":              models.ParseMethodCSV,
+}
+
+// Detect picks the parse method for data, for requests with parseMethod
+// auto. Every registered parser that recognises the content is a
+// candidate; the one contentType or the extension of the file name names
+// wins among them, and otherwise the most specific does. reason says what
+// decided it.
+func Detect(data []byte, contentType, name string) (method, reason string, err error) {
+	var candidates []string
+	for _, m := range detectOrder() {
+		if p, ok := Lookup(m); ok && p.Detect(data) {
+			candidates = append(candidates, m)
+		}
+	}
+
+	byType := mediaTypeMethod(contentType)
+	name, _, _ = strings.Cut(name, "?")
+	byName := MethodOf(name)
+	switch {
+	case byType != "" && slices.Contains(candidates, byType):
+		return byType, fmt.Sprintf("content and Content-Type %s", contentType), nil
+	case byName != "" && slices.Contains(candidates, byName):
+		return byName, fmt.Sprintf("content and file name %s", name), nil
+	case len(candidates) > 0:
+		return candidates[0], "content", nil
+	case byType != "":
+		return byType, fmt.Sprintf("Content-Type %s; the content wasn't recognised", contentType), nil
+	case byName != "":
+		return byName, fmt.Sprintf("file name %s; the content wasn't recognised", name), nil
+	}
+	return "", "", ErrUndetected
+}
+
+func mediaTypeMethod(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	if m, ok := mediaTypes[mediaType]; ok {
+		return m
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return models.ParseMethodJSON
+	case strings.HasSuffix(mediaType, "+xml"):
+		return models.ParseMethodXML
+	case strings.HasSuffix(mediaType, "+zip"):
+		return models.ParseMethodZIP
+	}
+	return ""
+}
+
+// detectOrder is the order parsers are tried in: formats with a signature
+// first, then JSON and markup, then those registered from other packages,
+// and last CSV, which plenty of plain text passes for.
+func detectOrder() []string {
+	order := []string{models.ParseMethodZIP, models.ParseMethodPDF, models.ParseMethodJSON, models.ParseMethodHTML, models.ParseMethodXML}
+	for _, m := range Methods() {
+		if !slices.Contains(order, m) && m != models.ParseMethodCSV {
+			order = append(order, m)
+		}
+	}
+	return append(order, models.ParseMethodCSV)
+}
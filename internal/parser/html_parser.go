@@ -0,0 +1,256 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// scriptOrStyle strips blocks whose contents the lenient tokenizer can't cope with.
+var scriptOrStyle = regexp.MustCompile(`(?is)<(script|style|noscript)\b.*?</(script|style|noscript)>`)
+
+// htmlTable is a results table found in the page along with the heading that
+// most recently preceded it and the elements enclosing it.
+type htmlTable struct {
+	title string
+	path  string
+	rows  [][]string
+}
+
+// parseHTML extracts contests from the results tables of a county page. Each
+// table becomes a contest titled by its caption or the nearest heading.
+func parseHTML(data []byte, tr *Trace) (*models.Results, error) {
+	tables, lines := scanHTML(scriptOrStyle.ReplaceAll(data, nil), tr)
+	tr.add(models.TraceFormat, "%d titled tables, %d text lines", len(tables), len(lines))
+
+	var contests []models.Contest
+	for _, t := range tables {
+		contest := models.Contest{Title: t.title}
+		tr.add(models.TraceContest, "table %q: %d rows", t.title, len(t.rows))
+		tr.table(t.title)
+		var header []string
+		for i, row := range t.rows {
+			cand, reason := candidateFromRow(row)
+			if i == 0 && reason == noVoteCount {
+				tr.add(models.TraceHeader, "row 1: header %q", row)
+				tr.header(row)
+				header = row
+				continue
+			}
+			tr.row(row)
+			if reason != "" {
+				tr.add(models.TraceSkip, "row %d: %q: %s", i+1, row, reason)
+				continue
+			}
+			contest.Candidates = append(contest.Candidates, cand)
+			tr.add(models.TraceCandidate, "row %d: %q with %d votes", i+1, cand.Name, cand.Votes)
+		}
+		tr.shape("html table in %s: %d columns, header %q", t.path, commonWidth(t.rows), header)
+		contests = appendContest(contests, &contest, tr)
+	}
+
+	// Pages without tables usually lay results out as plain text blocks.
+	if len(contests) == 0 {
+		tr.add(models.TraceNote, "no contests in tables, reading text lines instead")
+		contests = contestsFromLines(lines, tr)
+	}
+	return &models.Results{Contests: contests, Turnout: turnoutFromLines(lines, tr)}, nil
+}
+
+// scanHTML walks the document with a lenient tokenizer, collecting tables and
+// the visible text lines of the page.
+func scanHTML(data []byte, tr *Trace) ([]htmlTable, []string) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var (
+		tables   []htmlTable
+		lines    []string
+		heading  string
+		table    *htmlTable
+		row      []string
+		text     strings.Builder
+		inCell   bool
+		inHead   bool
+		inCapt   bool
+		lineText strings.Builder
+		open     []string // enclosing elements, as element paths name them
+	)
+
+	flushLine := func() {
+		if s := collapseSpace(lineText.String()); s != "" {
+			lines = append(lines, s)
+		}
+		lineText.Reset()
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// A malformed tail shouldn't discard what was already found.
+			if !errors.Is(err, io.EOF) {
+				flushLine()
+			}
+			break
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch name := strings.ToLower(t.Name.Local); name {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				inHead = true
+				text.Reset()
+			case "caption":
+				inCapt = true
+				text.Reset()
+			case "table":
+				table = &htmlTable{title: heading, path: strings.Join(open, " > ")}
+			case "tr":
+				row = nil
+			case "td", "th":
+				inCell = true
+				text.Reset()
+			}
+			if isBlockElement(t.Name.Local) {
+				flushLine()
+			}
+			open = append(open, elementName(t))
+
+		case xml.EndElement:
+			switch name := strings.ToLower(t.Name.Local); name {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				inHead = false
+				heading = collapseSpace(text.String())
+			case "caption":
+				inCapt = false
+				if table != nil {
+					table.title = collapseSpace(text.String())
+				}
+			case "td", "th":
+				inCell = false
+				row = append(row, collapseSpace(text.String()))
+			case "tr":
+				if table != nil && len(row) > 0 {
+					table.rows = append(table.rows, row)
+				}
+				row = nil
+			case "table":
+				if table != nil && table.title != "" {
+					tables = append(tables, *table)
+				} else if table != nil {
+					tr.add(models.TraceSkip, "table with %d rows skipped: no caption or preceding heading", len(table.rows))
+				}
+				table = nil
+			}
+			if isBlockElement(t.Name.Local) {
+				flushLine()
+			}
+			open = closeElement(open, strings.ToLower(t.Name.Local))
+
+		case xml.CharData:
+			if inCell || inHead || inCapt {
+				text.Write(t)
+			}
+			lineText.Write(t)
+			lineText.WriteByte(' ')
+		}
+	}
+	flushLine()
+
+	return tables, lines
+}
+
+// noVoteCount is candidateFromRow's reason for rows, such as headers, with
+// no numeric cell.
+const noVoteCount = "no vote count cell"
+
+// candidateFromRow interprets a table row as a candidate: the first cell is
+// the name and the first numeric cell after it is the vote count. A row that
+// isn't a candidate is reported with the reason.
+func candidateFromRow(row []string) (models.Candidate, string) {
+	switch {
+	case len(row) < 2:
+		return models.Candidate{}, "fewer than two cells"
+	case row[0] == "":
+		return models.Candidate{}, "empty name cell"
+	case isTotalRow(row[0]):
+		return models.Candidate{}, "total row"
+	}
+	for _, cell := range row[1:] {
+		if strings.HasSuffix(cell, "%") {
+			continue
+		}
+		votes, err := parseVotes(cell)
+		if err != nil || cell == "" {
+			continue
+		}
+		return models.Candidate{Name: row[0], Votes: votes}, ""
+	}
+	return models.Candidate{}, noVoteCount
+}
+
+// elementName names an element in a DOM path by its tag, ID and classes, as
+// in div#results.contest.
+func elementName(e xml.StartElement) string {
+	name := strings.ToLower(e.Name.Local)
+	for _, a := range e.Attr {
+		switch strings.ToLower(a.Name.Local) {
+		case "id":
+			if id := strings.TrimSpace(a.Value); id != "" {
+				name += "#" + id
+			}
+		case "class":
+			for _, c := range strings.Fields(a.Value) {
+				name += "." + c
+			}
+		}
+	}
+	return name
+}
+
+// closeElement pops the innermost open element named tag and any left
+// unclosed inside it. Stray end tags are ignored, as browsers do.
+func closeElement(open []string, tag string) []string {
+	for i := len(open) - 1; i >= 0; i-- {
+		name := open[i]
+		if j := strings.IndexAny(name, "#."); j >= 0 {
+			name = name[:j]
+		}
+		if name == tag {
+			return open[:i]
+		}
+	}
+	return open
+}
+
+// commonWidth returns the most common number of cells in rows.
+func commonWidth(rows [][]string) int {
+	counts := make(map[int]int)
+	best := 0
+	for _, r := range rows {
+		counts[len(r)]++
+		if counts[len(r)] > counts[best] {
+			best = len(r)
+		}
+	}
+	return best
+}
+
+func isBlockElement(name string) bool {
+	switch strings.ToLower(name) {
+	case "p", "div", "br", "li", "tr", "h1", "h2", "h3", "h4", "h5", "h6", "table", "section", "pre":
+		return true
+	}
+	return false
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
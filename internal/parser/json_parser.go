@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// JSON sources are mostly the responses behind results pages built in the
+// browser, and every vendor shapes them differently, so rather than decode
+// one layout the parser looks for arrays of objects that each carry a name
+// and a vote count. Each such array is a contest's candidates, titled by
+// the nearest enclosing object that has a title.
+
+// Keys looked for, compared without regard to case.
+var (
+	jsonTitleKeys      = []string{"title", "contestname", "contest", "racename", "race", "office", "name"}
+	jsonNameKeys       = []string{"name", "candidatename", "candidate", "choicename", "choice", "ballotname", "text", "label"}
+	jsonVoteKeys       = []string{"votes", "totalvotes", "votecount", "total", "count"}
+	jsonPartyKeys      = []string{"party", "partyname", "partyabbreviation"}
+	jsonRegisteredKeys = []string{"registeredvoters", "registered", "totalvoters"}
+	jsonBallotsKeys    = []string{"ballotscast", "ballots", "totalballots"}
+)
+
+func parseJSON(data []byte, tr *Trace) (*models.Results, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+	jsonShape(doc, "", tr)
+
+	var contests []models.Contest
+	jsonContests(doc, "", &contests, tr)
+	tr.add(models.TraceFormat, "%d arrays of candidates found", len(contests))
+
+	out := &models.Results{Contests: contests}
+	if obj, ok := doc.(map[string]any); ok {
+		var t models.Turnout
+		t.RegisteredVoters, _ = jsonInt(jsonField(obj, jsonRegisteredKeys))
+		t.BallotsCast, _ = jsonInt(jsonField(obj, jsonBallotsKeys))
+		out.Turnout = finishTurnout(&t)
+	}
+	return out, nil
+}
+
+// jsonContests walks v, appending a contest for each array of candidates.
+// Object keys are visited in sorted order, so the same document always
+// yields its contests in the same order.
+func jsonContests(v any, title string, contests *[]models.Contest, tr *Trace) {
+	switch v := v.(type) {
+	case map[string]any:
+		if t, ok := jsonField(v, jsonTitleKeys).(string); ok && strings.TrimSpace(t) != "" {
+			title = strings.TrimSpace(t)
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			jsonContests(v[k], title, contests, tr)
+		}
+	case []any:
+		if candidates, ok := jsonCandidates(v, title, tr); ok {
+			*contests = appendContest(*contests, &models.Contest{Title: title, Candidates: candidates}, tr)
+			return
+		}
+		for _, e := range v {
+			jsonContests(e, title, contests, tr)
+		}
+	}
+}
+
+// jsonCandidates reads arr as a contest's candidates if every element is
+// an object with a name and a vote count.
+func jsonCandidates(arr []any, title string, tr *Trace) ([]models.Candidate, bool) {
+	if len(arr) == 0 {
+		return nil, false
+	}
+	candidates := make([]models.Candidate, 0, len(arr))
+	for _, e := range arr {
+		obj, ok := e.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		name, ok := jsonField(obj, jsonNameKeys).(string)
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, false
+		}
+		raw := jsonField(obj, jsonVoteKeys)
+		if raw == nil {
+			return nil, false
+		}
+		votes, err := jsonInt(raw)
+		if err != nil {
+			tr.add(models.TraceSkip, "candidate %q in %q: unreadable votes %v", name, title, raw)
+			continue
+		}
+		party, _ := jsonField(obj, jsonPartyKeys).(string)
+		candidates = append(candidates, models.Candidate{
+			Name:  strings.TrimSpace(name),
+			Party: strings.TrimSpace(party),
+			Votes: votes,
+		})
+	}
+	return candidates, true
+}
+
+// jsonField returns the value of the first of keys obj has.
+func jsonField(obj map[string]any, keys []string) any {
+	for _, want := range keys {
+		for k, v := range obj {
+			if strings.EqualFold(k, want) {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// jsonInt reads a count given as a number or a string.
+func jsonInt(v any) (int, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return parseVotes(v.String())
+	case string:
+		return parseVotes(v)
+	case nil:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("not a number: %v", v)
+}
+
+// jsonShape records each object path of the document with its keys.
+func jsonShape(v any, path string, tr *Trace) {
+	if tr == nil {
+		return
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		tr.shape("json %s %q", "/"+strings.TrimPrefix(path, "/"), keys)
+		for _, k := range keys {
+			jsonShape(v[k], path+"/"+k, tr)
+		}
+	case []any:
+		for _, e := range v {
+			jsonShape(e, path+"[]", tr)
+		}
+	}
+}
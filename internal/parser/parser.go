@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// ErrUnsupportedMethod is returned when the requested parse method is unknown.
+var ErrUnsupportedMethod = errors.New("unsupported parse method")
+
+// ErrNoResults is returned when a source was read but no contests were found.
+var ErrNoResults = errors.New("no results found in source")
+
+// Parse extracts contests and turnout from data using the parser registered
+// for method. Only the Contests and Turnout fields of the result are set. If
+// ctx carries a Trace (see WithTrace), the parser's decisions are recorded in
+// it.
+func Parse(ctx context.Context, method string, data []byte) (*models.Results, error) {
+	p, ok := Lookup(method)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedMethod, method)
+	}
+	tr := TraceFrom(ctx)
+	tr.add(models.TraceFormat, "%s parser, %d bytes", strings.ToLower(method), len(data))
+	out, err := p.Parse(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s parser: %w", method, err)
+	}
+	if out == nil || len(out.Contests) == 0 {
+		return nil, ErrNoResults
+	}
+	markWriteIns(out.Contests, tr)
+	tr.add(models.TraceNote, "%d contests found", len(out.Contests))
+	return out, nil
+}
+
+// voteLine matches a line of text that ends in a vote count, optionally
+// followed by a percentage, e.g. "JANE DOE  12,345  52.10%".
+var voteLine = regexp.MustCompile(`^(.*?\S)\s+([0-9][0-9,]*)(?:\s+[0-9.]+%?)?$`)
+
+// contestsFromLines groups plain text lines into contests. A line without a
+// trailing vote count starts a new contest; lines with one are its candidates.
+func contestsFromLines(lines []string, tr *Trace) []models.Contest {
+	var (
+		contests []models.Contest
+		current  *models.Contest
+	)
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		m := voteLine.FindStringSubmatch(line)
+		if m == nil {
+			contests = appendContest(contests, current, tr)
+			current = &models.Contest{Title: line}
+			tr.add(models.TraceContest, "line %d: %q has no vote count, starting a contest", i+1, line)
+			continue
+		}
+
+		switch {
+		case current == nil:
+			tr.add(models.TraceSkip, "line %d: %q: vote line before any contest heading", i+1, line)
+			continue
+		case isTotalRow(m[1]):
+			tr.add(models.TraceSkip, "line %d: %q: total row", i+1, line)
+			continue
+		}
+		votes, err := parseVotes(m[2])
+		if err != nil {
+			tr.add(models.TraceSkip, "line %d: %q: unreadable vote count %q", i+1, line, m[2])
+			continue
+		}
+		current.Candidates = append(current.Candidates, models.Candidate{
+			Name:  strings.TrimSpace(m[1]),
+			Votes: votes,
+		})
+		tr.add(models.TraceCandidate, "line %d: %q with %d votes", i+1, strings.TrimSpace(m[1]), votes)
+	}
+
+	return appendContest(contests, current, tr)
+}
+
+// turnoutLabel matches the registration and ballots-cast summary lines
+// printed on most canvass reports.
+var turnoutLabel = regexp.MustCompile(`(?i)^(registered voters|total registration|ballots cast|total ballots cast|times cast)\b[^0-9]*([0-9][0-9,]*)`)
+
+// turnoutFromLines picks county-level turnout figures out of report text.
+// The largest figure for each label wins, since reports often repeat them
+// per contest.
+func turnoutFromLines(lines []string, tr *Trace) *models.Turnout {
+	var t models.Turnout
+	for i, line := range lines {
+		m := turnoutLabel.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		n, err := parseVotes(m[2])
+		if err != nil {
+			continue
+		}
+		tr.add(models.TraceTurnout, "line %d: %s %d", i+1, m[1], n)
+		if isRegistrationLabel(m[1]) {
+			t.RegisteredVoters = max(t.RegisteredVoters, n)
+		} else {
+			t.BallotsCast = max(t.BallotsCast, n)
+		}
+	}
+	return finishTurnout(&t)
+}
+
+func isRegistrationLabel(label string) bool {
+	label = strings.ToUpper(label)
+	return strings.Contains(label, "REGIST")
+}
+
+// finishTurnout computes the percentage, returning nil when nothing was found.
+func finishTurnout(t *models.Turnout) *models.Turnout {
+	if t == nil || (t.RegisteredVoters == 0 && t.BallotsCast == 0) {
+		return nil
+	}
+	t.Percent = models.TurnoutPercent(t.BallotsCast, t.RegisteredVoters)
+	return t
+}
+
+func appendContest(contests []models.Contest, c *models.Contest, tr *Trace) []models.Contest {
+	if c == nil {
+		return contests
+	}
+	if len(c.Candidates) == 0 {
+		tr.add(models.TraceSkip, "contest %q dropped: no candidate rows", c.Title)
+		return contests
+	}
+	tr.add(models.TraceContest, "contest %q with %d candidates", c.Title, len(c.Candidates))
+	return append(contests, *c)
+}
+
+// parseVotes parses a vote count, tolerating thousands separators.
+func parseVotes(s string) (int, error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// isTotalRow reports whether a row label is a summary rather than a candidate.
+func isTotalRow(label string) bool {
+	switch strings.ToUpper(strings.TrimSpace(label)) {
+	case "TOTAL", "TOTALS", "TOTAL VOTES", "TOTAL VOTES CAST", "TIMES CAST",
+		"REGISTERED VOTERS", "BALLOTS CAST", "UNDER VOTES", "OVER VOTES",
+		"UNDERVOTES", "OVERVOTES", "PRECINCTS REPORTING":
+		return true
+	}
+	return false
+}
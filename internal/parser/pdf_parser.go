@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+var (
+	pdfStream  = regexp.MustCompile(`(?s)stream\r?\n(.*?)\r?\nendstream`)
+	pdfTextOps = regexp.MustCompile(`(?s)\[(.*?)\]\s*TJ|\((.*?[^\\])?\)\s*(?:Tj|'|")|(-?[0-9.]+)\s+(-?[0-9.]+)\s+T[dD]\b|(T\*|Tm|ET)\b`)
+	pdfTJPart  = regexp.MustCompile(`\((.*?[^\\])?\)|(-?[0-9.]+)`)
+)
+
+// maxPDFStream bounds the size of a single inflated content stream.
+const maxPDFStream = 64 << 20
+
+// parsePDF extracts the text of every content stream and groups the resulting
+// lines into contests. It handles the plain text-operator output of typical
+// tabulation systems; scanned or image-only PDFs yield no results.
+func parsePDF(ctx context.Context, data []byte, tr *Trace) (*models.Results, error) {
+	var lines []string
+	streams := pdfStream.FindAllSubmatch(data, -1)
+	for _, m := range streams {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		lines = append(lines, pdfTextLines(inflate(m[1]))...)
+	}
+	tr.add(models.TraceFormat, "%d content streams, %d text lines", len(streams), len(lines))
+	return &models.Results{Contests: contestsFromLines(lines, tr), Turnout: turnoutFromLines(lines, tr)}, nil
+}
+
+// inflate decompresses a FlateDecode stream, returning raw unchanged when it
+// isn't compressed.
+func inflate(raw []byte) []byte {
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return raw
+	}
+	defer zr.Close()
+
+	out, err := io.ReadAll(io.LimitReader(zr, maxPDFStream))
+	if err != nil && len(out) == 0 {
+		return raw
+	}
+	return out
+}
+
+// pdfTextLines turns the text-showing operators of a content stream into
+// lines, breaking on positioning operators.
+func pdfTextLines(content []byte) []string {
+	var (
+		lines []string
+		line  strings.Builder
+	)
+	flush := func() {
+		if s := collapseSpace(line.String()); s != "" {
+			lines = append(lines, s)
+		}
+		line.Reset()
+	}
+
+	for _, m := range pdfTextOps.FindAllSubmatch(content, -1) {
+		switch {
+		case m[1] != nil:
+			for _, part := range pdfTJPart.FindAllSubmatch(m[1], -1) {
+				if part[2] != nil {
+					// Large negative kerning in a TJ array is a visual gap.
+					if len(part[2]) > 0 && part[2][0] == '-' && len(part[2]) > 3 {
+						line.WriteString("  ")
+					}
+					continue
+				}
+				line.WriteString(pdfUnescape(part[1]))
+			}
+		case m[2] != nil:
+			line.WriteString(pdfUnescape(m[2]))
+		case m[4] != nil:
+			// A move with no vertical offset is the next column on the same line.
+			if ty := strings.TrimLeft(string(m[4]), "-0."); ty == "" {
+				line.WriteString("  ")
+				continue
+			}
+			flush()
+		case m[5] != nil:
+			flush()
+		}
+	}
+	flush()
+
+	return lines
+}
+
+func pdfUnescape(b []byte) string {
+	r := strings.NewReplacer(`\(`, "(", `\)`, ")", `\\`, `\`, `\n`, " ", `\r`, " ", `\t`, " ")
+	return r.Replace(string(b))
+}
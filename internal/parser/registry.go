@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// Parser reads one source format. A format is added as a package of its own
+// that calls Register from an init function; importing it into the server,
+// for its side effect, makes it a parse method requests can name. The
+// server is built without cgo, so Go plugins can't be loaded at run time.
+type Parser interface {
+	// Detect reports whether data looks like the parser's format.
+	Detect(data []byte) bool
+	// Parse extracts the contests and, where the format has them, turnout
+	// in data. Decisions worth debugging go to the Trace from
+	// TraceFrom(ctx), which may be nil.
+	Parse(ctx context.Context, data []byte) (*models.Results, error)
+}
+
+type registered struct {
+	info   models.ParserInfo
+	parser Parser
+}
+
+var registry = struct {
+	sync.RWMutex
+	parsers map[string]*registered
+}{parsers: make(map[string]*registered)}
+
+// Register makes p available as the parse method info.Method, at version
+// 1 if info gives none. It panics if the method is empty or already
+// registered, as that is a programming error.
+func Register(info models.ParserInfo, p Parser) {
+	info.Method = strings.ToLower(info.Method)
+	if info.Method == "" || p == nil {
+		panic("parser: Register needs a method and a parser")
+	}
+	if info.Version <= 0 {
+		info.Version = 1
+	}
+	exts := make([]string, len(info.Extensions))
+	for i, ext := range info.Extensions {
+		if ext = strings.ToLower(ext); !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts[i] = ext
+	}
+	info.Extensions = exts
+	registry.Lock()
+	defer registry.Unlock()
+	if _, dup := registry.parsers[info.Method]; dup {
+		panic(fmt.Sprintf("parser: Register called twice for %q", info.Method))
+	}
+	registry.parsers[info.Method] = &registered{info: info, parser: p}
+}
+
+// Lookup returns the parser registered for method.
+func Lookup(method string) (Parser, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	r, ok := registry.parsers[strings.ToLower(method)]
+	if !ok {
+		return nil, false
+	}
+	return r.parser, true
+}
+
+// Parsers describes the registered parse methods, in order of method.
+func Parsers() []models.ParserInfo {
+	registry.RLock()
+	defer registry.RUnlock()
+	out := make([]models.ParserInfo, 0, len(registry.parsers))
+	for _, r := range registry.parsers {
+		info := r.info
+		info.Extensions = slices.Clone(info.Extensions)
+		info.Capabilities = slices.Clone(info.Capabilities)
+		out = append(out, info)
+	}
+	slices.SortFunc(out, func(a, b models.ParserInfo) int { return strings.Compare(a.Method, b.Method) })
+	return out
+}
+
+// Methods returns the registered parse methods, sorted.
+func Methods() []string {
+	infos := Parsers()
+	out := make([]string, len(infos))
+	for i, info := range infos {
+		out[i] = info.Method
+	}
+	return out
+}
+
+// Extension returns the canonical file extension of method's sources, or
+// "" if it has none.
+func Extension(method string) string {
+	registry.RLock()
+	defer registry.RUnlock()
+	if r, ok := registry.parsers[strings.ToLower(method)]; ok && len(r.info.Extensions) > 0 {
+		return r.info.Extensions[0]
+	}
+	return ""
+}
+
+// MethodOf returns the parse method whose extensions include that of the
+// file name, or "".
+func MethodOf(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return ""
+	}
+	for _, info := range Parsers() {
+		if slices.Contains(info.Extensions, ext) {
+			return info.Method
+		}
+	}
+	return ""
+}
+
+// builtin adapts one of this package's parse functions to Parser.
+type builtin struct {
+	detect func(data []byte) bool
+	parse  func(ctx context.Context, data []byte, tr *Trace) (*models.Results, error)
+}
+
+func (b builtin) Detect(data []byte) bool { return b.detect(data) }
+
+func (b builtin) Parse(ctx context.Context, data []byte) (*models.Results, error) {
+	return b.parse(ctx, data, TraceFrom(ctx))
+}
+
+// ignoreContext adapts a parse function that has nothing to cancel.
+func ignoreContext(parse func(data []byte, tr *Trace) (*models.Results, error)) func(context.Context, []byte, *Trace) (*models.Results, error) {
+	return func(_ context.Context, data []byte, tr *Trace) (*models.Results, error) {
+		return parse(data, tr)
+	}
+}
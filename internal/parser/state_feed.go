@@ -0,0 +1,248 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// clarityStateResult mirrors the statewide Clarity ENR detail.xml layout
+// secretaries of state publish, which breaks each choice's votes down by
+// vote type and then by county.
+type clarityStateResult struct {
+	XMLName      xml.Name                 `xml:"ElectionResult"`
+	VoterTurnout clarityStateVoterTurnout `xml:"VoterTurnout"`
+	Contests     []clarityStateContest    `xml:"Contest"`
+}
+
+type clarityStateVoterTurnout struct {
+	Counties []clarityStateCounty `xml:"Counties>County"`
+}
+
+type clarityStateContest struct {
+	Text    string               `xml:"text,attr"`
+	Choices []clarityStateChoice `xml:"Choice"`
+}
+
+type clarityStateChoice struct {
+	Text      string             `xml:"text,attr"`
+	Party     string             `xml:"party,attr"`
+	VoteTypes []clarityStateVote `xml:"VoteType"`
+}
+
+type clarityStateVote struct {
+	Name     string               `xml:"name,attr"`
+	Counties []clarityStateCounty `xml:"County"`
+}
+
+type clarityStateCounty struct {
+	Name        string `xml:"name,attr"`
+	Votes       string `xml:"votes,attr"`
+	TotalVoters string `xml:"totalVoters,attr"`
+	BallotsCast string `xml:"ballotsCast,attr"`
+}
+
+// ParseState splits a state's aggregate feed into the results of each
+// county it reports, in the order the feed first names them. format is one
+// of models.StateFeedFormats. Only the County, Contests and Turnout fields
+// of each result are set. Counties with no contests are left out.
+func ParseState(ctx context.Context, format string, data []byte) ([]*models.Results, error) {
+	tr := TraceFrom(ctx)
+	tr.add(models.TraceFormat, "state %s feed, %d bytes", strings.ToLower(format), len(data))
+
+	var (
+		out []*models.Results
+		err error
+	)
+	switch strings.ToLower(format) {
+	case models.StateFeedXML:
+		out, err = parseStateXML(data, tr)
+	case models.StateFeedCSV:
+		out, err = parseStateCSV(data, tr)
+	default:
+		return nil, fmt.Errorf("%w: state feed format %q", ErrUnsupportedMethod, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("state %s feed: %w", format, err)
+	}
+
+	kept := out[:0]
+	for _, r := range out {
+		if len(r.Contests) == 0 {
+			tr.add(models.TraceSkip, "county %q dropped: no contests", r.County)
+			continue
+		}
+		markWriteIns(r.Contests, tr)
+		kept = append(kept, r)
+	}
+	if len(kept) == 0 {
+		return nil, ErrNoResults
+	}
+	tr.add(models.TraceNote, "%d counties found", len(kept))
+	return kept, nil
+}
+
+// stateCounties collects per-county results in feed order.
+type stateCounties struct {
+	list  []*models.Results
+	index map[string]*models.Results // by county key
+}
+
+func (s *stateCounties) get(name string) *models.Results {
+	key := models.Slug(name)
+	if r, ok := s.index[key]; ok {
+		return r
+	}
+	if s.index == nil {
+		s.index = make(map[string]*models.Results)
+	}
+	r := &models.Results{County: name}
+	s.index[key] = r
+	s.list = append(s.list, r)
+	return r
+}
+
+func parseStateXML(data []byte, tr *Trace) ([]*models.Results, error) {
+	xmlShape(data, tr)
+	var doc clarityStateResult
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode clarity xml: %w", err)
+	}
+	tr.add(models.TraceFormat, "statewide Clarity ENR layout, %d <Contest> elements", len(doc.Contests))
+
+	var counties stateCounties
+	for _, c := range doc.Contests {
+		title := strings.TrimSpace(c.Text)
+		// Each county's share of the contest, keyed like counties.
+		type share struct {
+			results *models.Results
+			contest models.Contest
+		}
+		byCounty := make(map[string]*share)
+		var shares []*share
+		for _, ch := range c.Choices {
+			name := strings.TrimSpace(ch.Text)
+			votes := make(map[string]int) // by county key
+			var order []string
+			for _, vt := range ch.VoteTypes {
+				for _, vc := range vt.Counties {
+					county := strings.TrimSpace(vc.Name)
+					n, err := parseVotes(vc.Votes)
+					if err != nil {
+						tr.add(models.TraceSkip, "choice %q in %q, %s in %s: unreadable votes %q", name, title, vt.Name, county, vc.Votes)
+						continue
+					}
+					key := models.Slug(county)
+					if _, seen := votes[key]; !seen {
+						order = append(order, county)
+					}
+					votes[key] += n
+				}
+			}
+			for _, county := range order {
+				key := models.Slug(county)
+				s, ok := byCounty[key]
+				if !ok {
+					s = &share{results: counties.get(county), contest: models.Contest{Title: title}}
+					byCounty[key] = s
+					shares = append(shares, s)
+				}
+				s.contest.Candidates = append(s.contest.Candidates, models.Candidate{
+					Name:  name,
+					Party: strings.TrimSpace(ch.Party),
+					Votes: votes[key],
+				})
+			}
+		}
+		for _, s := range shares {
+			s.results.Contests = append(s.results.Contests, s.contest)
+		}
+		tr.add(models.TraceContest, "contest %q reported by %d counties", title, len(shares))
+	}
+
+	for _, vc := range doc.VoterTurnout.Counties {
+		var t models.Turnout
+		t.RegisteredVoters, _ = parseVotes(vc.TotalVoters)
+		t.BallotsCast, _ = parseVotes(vc.BallotsCast)
+		if finishTurnout(&t) != nil {
+			counties.get(strings.TrimSpace(vc.Name)).Turnout = &t
+			tr.add(models.TraceTurnout, "county %q: %d registered, %d ballots cast", vc.Name, t.RegisteredVoters, t.BallotsCast)
+		}
+	}
+	return counties.list, nil
+}
+
+// parseStateCSV reads a results CSV with a county column besides the
+// contest, candidate and votes ones, one row per candidate per county, and
+// reads each county's rows as a county results CSV.
+func parseStateCSV(data []byte, tr *Trace) ([]*models.Results, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	if len(records) < 2 {
+		tr.add(models.TraceSkip, "csv has no data rows")
+		return nil, nil
+	}
+	countyCol := -1
+	for i, h := range records[0] {
+		if h := strings.ToLower(strings.TrimSpace(h)); strings.Contains(h, "county") || h == "jurisdiction" {
+			countyCol = i
+			break
+		}
+	}
+	tr.add(models.TraceHeader, "csv header %q: county %s", records[0], csvColumn(records[0], countyCol))
+	if countyCol < 0 {
+		return nil, fmt.Errorf("csv header missing county column")
+	}
+
+	// The county column is dropped so it can't be mistaken for another.
+	header := dropColumn(records[0], countyCol)
+	var (
+		names []string
+		rows  = make(map[string][][]string)
+	)
+	for row, rec := range records[1:] {
+		if len(rec) <= countyCol || strings.TrimSpace(rec[countyCol]) == "" {
+			tr.add(models.TraceSkip, "row %d: no county", row+2)
+			continue
+		}
+		county := strings.TrimSpace(rec[countyCol])
+		key := models.Slug(county)
+		if _, ok := rows[key]; !ok {
+			names = append(names, county)
+			rows[key] = [][]string{header}
+		}
+		rows[key] = append(rows[key], dropColumn(rec, countyCol))
+	}
+
+	out := make([]*models.Results, 0, len(names))
+	for _, county := range names {
+		tr.add(models.TraceNote, "county %q", county)
+		r, err := csvResults(rows[models.Slug(county)], tr)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			r = &models.Results{}
+		}
+		r.County = county
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func dropColumn(rec []string, col int) []string {
+	out := make([]string, 0, len(rec))
+	out = append(out, rec[:col]...)
+	return append(out, rec[col+1:]...)
+}
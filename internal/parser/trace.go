@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+const (
+	// maxTraceEntries bounds a trace so debugging a huge source can't
+	// exhaust memory; entries past it are counted but not kept.
+	maxTraceEntries = 5000
+
+	// maxSampleRows is how many rows of each table a sample keeps. Layout
+	// drift shows in the header and first rows.
+	maxSampleRows = 20
+)
+
+// Trace collects the decisions Parse makes: which layout it detected, how it
+// read headers, which rows became candidates and which were skipped and why.
+// It also records the source's sample, the raw tables rows were read from,
+// and the structural features making up its layout. A nil *Trace records
+// nothing.
+type Trace struct {
+	mu         sync.Mutex
+	sampleOnly bool
+	entries    []models.TraceEntry
+	dropped    int
+	sample     models.SourceSample
+	layout     map[string]bool
+}
+
+// NewTrace returns an empty trace.
+func NewTrace() *Trace {
+	return &Trace{}
+}
+
+// NewSampleTrace returns a trace that records only the source sample and
+// layout, which are cheap enough to collect on every parse.
+func NewSampleTrace() *Trace {
+	return &Trace{sampleOnly: true}
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context that makes Parse record its decisions in t.
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// TraceFrom returns the trace attached with WithTrace, or nil.
+func TraceFrom(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// Add records a decision of a parser registered from another package; kind
+// is one of the models.Trace kinds.
+func (t *Trace) Add(kind, format string, args ...any) {
+	t.add(kind, format, args...)
+}
+
+func (t *Trace) add(kind, format string, args ...any) {
+	if t == nil || t.sampleOnly {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.entries) >= maxTraceEntries {
+		t.dropped++
+		return
+	}
+	t.entries = append(t.entries, models.TraceEntry{Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// table starts a new table in the sample.
+func (t *Trace) table(name string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sample.Tables = append(t.sample.Tables, models.SampleTable{Name: name})
+}
+
+// header sets the header of the current table.
+func (t *Trace) header(cells []string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := len(t.sample.Tables); n > 0 {
+		t.sample.Tables[n-1].Header = append([]string(nil), cells...)
+	}
+}
+
+// row adds a data row to the current table.
+func (t *Trace) row(cells []string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := len(t.sample.Tables)
+	if n == 0 {
+		return
+	}
+	tab := &t.sample.Tables[n-1]
+	tab.RowCount++
+	if len(tab.Rows) < maxSampleRows {
+		tab.Rows = append(tab.Rows, append([]string(nil), cells...))
+	}
+}
+
+// shape records a structural feature of the source. Repeated features, such
+// as many tables laid out alike, count once.
+func (t *Trace) shape(format string, args ...any) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.layout == nil {
+		t.layout = make(map[string]bool)
+	}
+	t.layout[fmt.Sprintf(format, args...)] = true
+}
+
+// Sample returns the tables and layout recorded so far.
+func (t *Trace) Sample() *models.SourceSample {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := models.SourceSample{Tables: append([]models.SampleTable(nil), t.sample.Tables...)}
+	if len(t.layout) > 0 {
+		features := make([]string, 0, len(t.layout))
+		for f := range t.layout {
+			features = append(features, f)
+		}
+		sort.Strings(features)
+		sum := sha256.Sum256([]byte(strings.Join(features, "\n")))
+		s.Layout = &models.SourceLayout{Hash: "sha256:" + hex.EncodeToString(sum[:]), Features: features}
+	}
+	return &s
+}
+
+// Entries returns what has been recorded so far.
+func (t *Trace) Entries() []models.TraceEntry {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := append([]models.TraceEntry(nil), t.entries...)
+	if t.dropped > 0 {
+		out = append(out, models.TraceEntry{
+			Kind:    models.TraceNote,
+			Message: fmt.Sprintf("trace truncated: %d more entries not recorded", t.dropped),
+		})
+	}
+	return out
+}
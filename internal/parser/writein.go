@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+var (
+	// writeInTotal matches rows that total uncertified write-ins rather than
+	// naming a person: "WRITE-IN", "Write-ins", "Unresolved Write-In", ...
+	writeInTotal = regexp.MustCompile(`(?i)^\s*(?:(?:unresolved|unqualified|uncertified|other|misc\.?|miscellaneous)\s+)?write[\s-]?ins?(?:\s+(?:totals?|votes))?\s*$`)
+
+	// writeInNamed matches certified write-in candidates and captures the
+	// name: "WRITE-IN: JANE DOE", "Jane Doe (Write-in)", "JANE DOE (W)".
+	writeInPrefix = regexp.MustCompile(`(?i)^\s*(?:write[\s-]?in|w/i)\s*[:\-–]?\s+(.+)$`)
+	writeInSuffix = regexp.MustCompile(`(?i)^(.+?)\s*[\(\[](?:write[\s-]?in|w/i|w)[\)\]]\s*$`)
+)
+
+// markWriteIns flags write-in rows so they are kept and rendered distinctly
+// instead of being mistaken for regular candidates or dropped as totals.
+func markWriteIns(contests []models.Contest, tr *Trace) {
+	for i := range contests {
+		for j := range contests[i].Candidates {
+			c := &contests[i].Candidates[j]
+			name := c.Name
+			if classifyWriteIn(c); c.WriteIn {
+				tr.add(models.TraceNote, "%q in %q is a write-in (listed as %q)", name, contests[i].Title, c.Name)
+			}
+		}
+	}
+}
+
+func classifyWriteIn(c *models.Candidate) {
+	switch {
+	case writeInTotal.MatchString(c.Name):
+		c.WriteIn = true
+		c.WriteInAggregate = true
+		c.Name = "Write-in"
+	case writeInPrefix.MatchString(c.Name):
+		c.WriteIn = true
+		c.Name = strings.TrimSpace(writeInPrefix.FindStringSubmatch(c.Name)[1])
+	case writeInSuffix.MatchString(c.Name):
+		c.WriteIn = true
+		c.Name = strings.TrimSpace(writeInSuffix.FindStringSubmatch(c.Name)[1])
+	}
+}
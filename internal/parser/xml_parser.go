@@ -0,0 +1,291 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/rcv"
+)
+
+// clarityResult mirrors the subset of the Clarity ENR detail.xml layout used
+// by most county election sites.
+type clarityResult struct {
+	XMLName      xml.Name            `xml:"ElectionResult"`
+	VoterTurnout clarityVoterTurnout `xml:"VoterTurnout"`
+	Contests     []clarityContest    `xml:"Contest"`
+}
+
+type clarityVoterTurnout struct {
+	TotalVoters string            `xml:"totalVoters,attr"`
+	BallotsCast string            `xml:"ballotsCast,attr"`
+	Precincts   []clarityPrecinct `xml:"Precincts>Precinct"`
+}
+
+type clarityPrecinct struct {
+	Name        string `xml:"name,attr"`
+	TotalVoters string `xml:"totalVoters,attr"`
+	BallotsCast string `xml:"ballotsCast,attr"`
+}
+
+type clarityContest struct {
+	Text                   string          `xml:"text,attr"`
+	PrecinctsReported      string          `xml:"precinctsReported,attr"`
+	PrecinctsParticipating string          `xml:"precinctsParticipating,attr"`
+	Choices                []clarityChoice `xml:"Choice"`
+}
+
+type clarityChoice struct {
+	Text       string            `xml:"text,attr"`
+	Party      string            `xml:"party,attr"`
+	TotalVotes string            `xml:"totalVotes,attr"`
+	VoteTypes  []clarityVoteType `xml:"VoteType"`
+}
+
+// clarityVoteType is a choice's votes of one kind, such as election day or
+// mail, by precinct.
+type clarityVoteType struct {
+	Precincts []xmlPrecinctVotes `xml:"Precinct"`
+}
+
+type xmlPrecinctVotes struct {
+	Name  string `xml:"name,attr"`
+	Votes string `xml:"votes,attr"`
+}
+
+// genericResult covers the simpler <Results><Contest name=""><Candidate .../>
+// layout some counties publish instead. Ranked-choice contests in this
+// layout carry one <Round number=""> element per round of tabulation.
+type genericResult struct {
+	Turnout  genericTurnout   `xml:"Turnout"`
+	Contests []genericContest `xml:"Contest"`
+}
+
+type genericTurnout struct {
+	Registered  string `xml:"registered,attr"`
+	BallotsCast string `xml:"ballotsCast,attr"`
+}
+
+type genericContest struct {
+	Name       string             `xml:"name,attr"`
+	Title      string             `xml:"title,attr"`
+	Candidates []genericCandidate `xml:"Candidate"`
+	Rounds     []genericRound     `xml:"Round"`
+}
+
+type genericRound struct {
+	Number     string             `xml:"number,attr"`
+	Exhausted  string             `xml:"exhausted,attr"`
+	Candidates []genericCandidate `xml:"Candidate"`
+}
+
+type genericCandidate struct {
+	Name      string             `xml:"name,attr"`
+	Party     string             `xml:"party,attr"`
+	Votes     string             `xml:"votes,attr"`
+	Precincts []xmlPrecinctVotes `xml:"Precinct"`
+}
+
+func parseXML(data []byte, tr *Trace) (*models.Results, error) {
+	root, err := xmlRootName(data)
+	if err != nil {
+		return nil, err
+	}
+	xmlShape(data, tr)
+
+	if root == "ElectionResult" {
+		var doc clarityResult
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("decode clarity xml: %w", err)
+		}
+		tr.add(models.TraceFormat, "Clarity ENR layout, %d <Contest> elements", len(doc.Contests))
+		return &models.Results{Contests: clarityContests(doc, tr), Turnout: clarityTurnout(doc.VoterTurnout)}, nil
+	}
+
+	var doc genericResult
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode xml: %w", err)
+	}
+	tr.add(models.TraceFormat, "generic layout with root <%s>, %d <Contest> elements", root, len(doc.Contests))
+	var t models.Turnout
+	t.RegisteredVoters, _ = parseVotes(doc.Turnout.Registered)
+	t.BallotsCast, _ = parseVotes(doc.Turnout.BallotsCast)
+	return &models.Results{Contests: genericContests(doc, tr), Turnout: finishTurnout(&t)}, nil
+}
+
+// xmlRootName returns the local name of the document element.
+func xmlRootName(data []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("read xml: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// xmlShape records each element path of the document with the attributes
+// found on it, the structure the decoders above depend on.
+func xmlShape(data []byte, tr *Trace) {
+	if tr == nil {
+		return
+	}
+	var path []string
+	attrs := make(map[string]map[string]bool) // element path → attribute names
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+			p := strings.Join(path, "/")
+			if attrs[p] == nil {
+				attrs[p] = make(map[string]bool)
+			}
+			for _, a := range t.Attr {
+				if a.Name.Space != "xmlns" && a.Name.Local != "xmlns" {
+					attrs[p][a.Name.Local] = true
+				}
+			}
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		}
+	}
+	for p, names := range attrs {
+		list := make([]string, 0, len(names))
+		for name := range names {
+			list = append(list, name)
+		}
+		sort.Strings(list)
+		tr.shape("xml %s %q", p, list)
+	}
+}
+
+func clarityContests(doc clarityResult, tr *Trace) []models.Contest {
+	contests := make([]models.Contest, 0, len(doc.Contests))
+	for _, c := range doc.Contests {
+		contest := models.Contest{Title: strings.TrimSpace(c.Text)}
+		contest.PrecinctsReporting, _ = parseVotes(c.PrecinctsReported)
+		contest.PrecinctsTotal, _ = parseVotes(c.PrecinctsParticipating)
+		for _, ch := range c.Choices {
+			votes, err := parseVotes(ch.TotalVotes)
+			if err != nil {
+				tr.add(models.TraceSkip, "choice %q in %q: unreadable totalVotes %q", ch.Text, contest.Title, ch.TotalVotes)
+				continue
+			}
+			var byType [][]xmlPrecinctVotes
+			for _, vt := range ch.VoteTypes {
+				byType = append(byType, vt.Precincts)
+			}
+			contest.Candidates = append(contest.Candidates, models.Candidate{
+				Name:      strings.TrimSpace(ch.Text),
+				Party:     strings.TrimSpace(ch.Party),
+				Votes:     votes,
+				Precincts: precinctVotes(byType...),
+			})
+		}
+		contests = appendContest(contests, &contest, tr)
+	}
+	return contests
+}
+
+func clarityTurnout(vt clarityVoterTurnout) *models.Turnout {
+	var t models.Turnout
+	t.RegisteredVoters, _ = parseVotes(vt.TotalVoters)
+	t.BallotsCast, _ = parseVotes(vt.BallotsCast)
+	for _, p := range vt.Precincts {
+		pt := models.PrecinctTurnout{Name: strings.TrimSpace(p.Name)}
+		pt.RegisteredVoters, _ = parseVotes(p.TotalVoters)
+		pt.BallotsCast, _ = parseVotes(p.BallotsCast)
+		pt.Percent = models.TurnoutPercent(pt.BallotsCast, pt.RegisteredVoters)
+		t.Precincts = append(t.Precincts, pt)
+	}
+	return finishTurnout(&t)
+}
+
+func genericContests(doc genericResult, tr *Trace) []models.Contest {
+	contests := make([]models.Contest, 0, len(doc.Contests))
+	for _, c := range doc.Contests {
+		title := c.Title
+		if title == "" {
+			title = c.Name
+		}
+		contest := models.Contest{Title: strings.TrimSpace(title)}
+		if len(c.Rounds) > 0 {
+			tr.add(models.TraceNote, "contest %q is ranked-choice with %d <Round> elements", contest.Title, len(c.Rounds))
+			contest.RCV = rcv.Tabulate(genericRounds(c.Rounds))
+			contest.Candidates = rcv.FirstChoices(contest.RCV)
+			contests = appendContest(contests, &contest, tr)
+			continue
+		}
+		for _, cand := range c.Candidates {
+			votes, err := parseVotes(cand.Votes)
+			if err != nil {
+				tr.add(models.TraceSkip, "candidate %q in %q: unreadable votes %q", cand.Name, contest.Title, cand.Votes)
+				continue
+			}
+			contest.Candidates = append(contest.Candidates, models.Candidate{
+				Name:      strings.TrimSpace(cand.Name),
+				Party:     strings.TrimSpace(cand.Party),
+				Votes:     votes,
+				Precincts: precinctVotes(cand.Precincts),
+			})
+		}
+		contests = appendContest(contests, &contest, tr)
+	}
+	return contests
+}
+
+// precinctVotes sums a candidate's votes in each precinct over lists of
+// them, a Clarity choice's having one per vote type, in the order the
+// precincts first appear.
+func precinctVotes(lists ...[]xmlPrecinctVotes) []models.PrecinctVotes {
+	var out []models.PrecinctVotes
+	index := make(map[string]int)
+	for _, list := range lists {
+		for _, p := range list {
+			votes, err := parseVotes(p.Votes)
+			if err != nil {
+				continue
+			}
+			name := strings.TrimSpace(p.Name)
+			i, ok := index[name]
+			if !ok {
+				i = len(out)
+				index[name] = i
+				out = append(out, models.PrecinctVotes{Name: name})
+			}
+			out[i].Votes += votes
+		}
+	}
+	return out
+}
+
+func genericRounds(in []genericRound) []rcv.Round {
+	rounds := make([]rcv.Round, 0, len(in))
+	for i, r := range in {
+		round := rcv.Round{Number: i + 1, Tallies: make(map[string]int, len(r.Candidates))}
+		if n, err := parseVotes(r.Number); err == nil && n > 0 {
+			round.Number = n
+		}
+		round.Exhausted, _ = parseVotes(r.Exhausted)
+		for _, cand := range r.Candidates {
+			if votes, err := parseVotes(cand.Votes); err == nil {
+				round.Tallies[strings.TrimSpace(cand.Name)] = votes
+			}
+		}
+		rounds = append(rounds, round)
+	}
+	return rounds
+}
@@ -0,0 +1,327 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/rcv"
+)
+
+// maxZipEntrySize bounds how much of a single archive entry is read so a
+// malicious archive cannot exhaust memory.
+const maxZipEntrySize = 200 << 20
+
+// parseZIP reads every XML, CSV, PDF and RCTab summary JSON entry in the
+// archive and merges the contests found in each.
+func parseZIP(ctx context.Context, data []byte, tr *Trace) (*models.Results, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+
+	out := &models.Results{}
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		ext := strings.ToLower(path.Ext(f.Name))
+		if ext != ".xml" && ext != ".csv" && ext != ".pdf" && ext != ".json" {
+			tr.add(models.TraceSkip, "entry %s: unsupported file type", f.Name)
+			continue
+		}
+
+		entry, err := readZipEntry(f)
+		if err != nil {
+			return nil, err
+		}
+		tr.add(models.TraceFormat, "entry %s, %d bytes", f.Name, len(entry))
+
+		var found *models.Results
+		switch ext {
+		case ".xml":
+			found, err = parseXML(entry, tr)
+		case ".csv":
+			tr.table(f.Name)
+			found, err = parseCSV(entry, tr)
+		case ".pdf":
+			found, err = parsePDF(ctx, entry, tr)
+		case ".json":
+			found, err = parseRCTabSummary(entry, tr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if found == nil {
+			continue
+		}
+		out.Contests = append(out.Contests, found.Contests...)
+		out.Turnout = preferTurnout(out.Turnout, found.Turnout)
+	}
+
+	return out, nil
+}
+
+// preferTurnout keeps the more detailed of two turnout figures found in
+// different archive entries.
+func preferTurnout(have, found *models.Turnout) *models.Turnout {
+	switch {
+	case found == nil:
+		return have
+	case have == nil, len(found.Precincts) > len(have.Precincts):
+		return found
+	}
+	return have
+}
+
+func readZipEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxZipEntrySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", f.Name, err)
+	}
+	if len(data) > maxZipEntrySize {
+		return nil, fmt.Errorf("%s exceeds %d bytes", f.Name, maxZipEntrySize)
+	}
+	return data, nil
+}
+
+// parseCSV reads a results CSV with contest, candidate and votes columns.
+// Header names are matched loosely since every county labels them differently.
+// A round column marks ranked-choice results with one row per candidate per
+// round.
+func parseCSV(data []byte, tr *Trace) (*models.Results, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	return csvResults(records, tr)
+}
+
+// csvResults reads the records of a results CSV, header first.
+func csvResults(records [][]string, tr *Trace) (*models.Results, error) {
+	if len(records) < 2 {
+		tr.add(models.TraceSkip, "csv has no data rows")
+		return nil, nil
+	}
+
+	contestCol, candidateCol, votesCol, partyCol, roundCol := -1, -1, -1, -1, -1
+	for i, h := range records[0] {
+		switch h := strings.ToLower(strings.TrimSpace(h)); {
+		case contestCol < 0 && (strings.Contains(h, "contest") || strings.Contains(h, "race") || strings.Contains(h, "office")):
+			contestCol = i
+		case candidateCol < 0 && (strings.Contains(h, "candidate") || strings.Contains(h, "choice")):
+			candidateCol = i
+		case votesCol < 0 && strings.Contains(h, "votes"):
+			votesCol = i
+		case partyCol < 0 && strings.Contains(h, "party"):
+			partyCol = i
+		case roundCol < 0 && strings.Contains(h, "round"):
+			roundCol = i
+		}
+	}
+	tr.header(records[0])
+	tr.shape("csv header %q", records[0])
+	for _, rec := range records[1:] {
+		tr.row(rec)
+	}
+	tr.add(models.TraceHeader, "csv header %q: contest %s, candidate %s, votes %s, party %s, round %s",
+		records[0], csvColumn(records[0], contestCol), csvColumn(records[0], candidateCol),
+		csvColumn(records[0], votesCol), csvColumn(records[0], partyCol), csvColumn(records[0], roundCol))
+	if contestCol < 0 || candidateCol < 0 || votesCol < 0 {
+		return nil, fmt.Errorf("csv header missing contest, candidate or votes column")
+	}
+
+	if roundCol >= 0 {
+		return &models.Results{Contests: rcvCSVContests(records[1:], contestCol, candidateCol, votesCol, roundCol, tr)}, nil
+	}
+
+	var (
+		contests []models.Contest
+		index    = map[string]int{}
+		turnout  models.Turnout
+	)
+	for row, rec := range records[1:] {
+		line := row + 2 // 1-based, after the header
+		if len(rec) <= contestCol || len(rec) <= candidateCol || len(rec) <= votesCol {
+			tr.add(models.TraceSkip, "row %d: only %d columns", line, len(rec))
+			continue
+		}
+		title := strings.TrimSpace(rec[contestCol])
+		name := strings.TrimSpace(rec[candidateCol])
+		if n, ok := csvTurnoutRow(title, name, rec[votesCol]); ok {
+			if isRegistrationLabel(title + " " + name) {
+				turnout.RegisteredVoters = max(turnout.RegisteredVoters, n)
+			} else {
+				turnout.BallotsCast = max(turnout.BallotsCast, n)
+			}
+			tr.add(models.TraceTurnout, "row %d: %s %s %d", line, title, name, n)
+			continue
+		}
+		switch {
+		case title == "":
+			tr.add(models.TraceSkip, "row %d: empty contest", line)
+			continue
+		case name == "":
+			tr.add(models.TraceSkip, "row %d: empty candidate in %q", line, title)
+			continue
+		case isTotalRow(name):
+			tr.add(models.TraceSkip, "row %d: %q in %q: total row", line, name, title)
+			continue
+		}
+		votes, err := parseVotes(rec[votesCol])
+		if err != nil {
+			tr.add(models.TraceSkip, "row %d: %q in %q: unreadable votes %q", line, name, title, rec[votesCol])
+			continue
+		}
+
+		i, ok := index[title]
+		if !ok {
+			i = len(contests)
+			index[title] = i
+			contests = append(contests, models.Contest{Title: title})
+			tr.add(models.TraceContest, "row %d: new contest %q", line, title)
+		}
+		cand := models.Candidate{Name: name, Votes: votes}
+		if partyCol >= 0 && partyCol < len(rec) {
+			cand.Party = strings.TrimSpace(rec[partyCol])
+		}
+		contests[i].Candidates = append(contests[i].Candidates, cand)
+		tr.add(models.TraceCandidate, "row %d: %q in %q with %d votes", line, name, title, votes)
+	}
+
+	return &models.Results{Contests: contests, Turnout: finishTurnout(&turnout)}, nil
+}
+
+// csvColumn describes which header column, if any, was picked for a field.
+func csvColumn(header []string, col int) string {
+	if col < 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d (%q)", col+1, header[col])
+}
+
+// csvTurnoutRow recognizes rows reporting registration or ballots cast in
+// place of a contest or candidate.
+func csvTurnoutRow(title, name, value string) (int, bool) {
+	for _, label := range []string{title, name} {
+		switch strings.ToUpper(label) {
+		case "REGISTERED VOTERS", "TOTAL REGISTRATION", "BALLOTS CAST", "TOTAL BALLOTS CAST":
+			n, err := parseVotes(value)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// rcvCSVContests groups round-by-round CSV rows into ranked-choice contests.
+func rcvCSVContests(records [][]string, contestCol, candidateCol, votesCol, roundCol int, tr *Trace) []models.Contest {
+	var (
+		titles []string
+		rounds = map[string]map[int]map[string]int{}
+	)
+	for row, rec := range records {
+		line := row + 2
+		if len(rec) <= contestCol || len(rec) <= candidateCol || len(rec) <= votesCol || len(rec) <= roundCol {
+			tr.add(models.TraceSkip, "row %d: only %d columns", line, len(rec))
+			continue
+		}
+		title := strings.TrimSpace(rec[contestCol])
+		name := strings.TrimSpace(rec[candidateCol])
+		round, err := parseVotes(rec[roundCol])
+		if title == "" || name == "" || err != nil || isTotalRow(name) {
+			tr.add(models.TraceSkip, "row %d: %q: missing contest, candidate or round, or a total row", line, rec)
+			continue
+		}
+		votes, err := parseVotes(rec[votesCol])
+		if err != nil {
+			tr.add(models.TraceSkip, "row %d: %q in %q: unreadable votes %q", line, name, title, rec[votesCol])
+			continue
+		}
+
+		if _, ok := rounds[title]; !ok {
+			titles = append(titles, title)
+			rounds[title] = map[int]map[string]int{}
+		}
+		if rounds[title][round] == nil {
+			rounds[title][round] = map[string]int{}
+		}
+		if strings.EqualFold(name, rcv.ExhaustedLabel) {
+			name = rcv.ExhaustedLabel
+		}
+		rounds[title][round][name] = votes
+	}
+
+	contests := make([]models.Contest, 0, len(titles))
+	for _, title := range titles {
+		var rr []rcv.Round
+		for n, tallies := range rounds[title] {
+			rr = append(rr, rcv.Round{Number: n, Tallies: tallies})
+		}
+		tr.add(models.TraceNote, "contest %q is ranked-choice with %d rounds", title, len(rr))
+		contest := models.Contest{Title: title, RCV: rcv.Tabulate(rr)}
+		contest.Candidates = rcv.FirstChoices(contest.RCV)
+		contests = appendContest(contests, &contest, tr)
+	}
+	return contests
+}
+
+// rctabSummary is the subset of the Universal RCV Tabulator summary.json
+// needed to rebuild the rounds. Tallies are encoded as strings.
+type rctabSummary struct {
+	Config struct {
+		Contest string `json:"contest"`
+	} `json:"config"`
+	Results []struct {
+		Round int                        `json:"round"`
+		Tally map[string]json.RawMessage `json:"tally"`
+	} `json:"results"`
+}
+
+// parseRCTabSummary reads an RCTab summary.json. Other JSON files in an
+// archive are ignored.
+func parseRCTabSummary(data []byte, tr *Trace) (*models.Results, error) {
+	var s rctabSummary
+	if err := json.Unmarshal(data, &s); err != nil || s.Config.Contest == "" || len(s.Results) == 0 {
+		tr.add(models.TraceSkip, "json is not an RCTab summary")
+		return nil, nil
+	}
+	tr.add(models.TraceNote, "RCTab summary for %q with %d rounds", s.Config.Contest, len(s.Results))
+
+	rounds := make([]rcv.Round, 0, len(s.Results))
+	for _, r := range s.Results {
+		round := rcv.Round{Number: r.Round, Tallies: make(map[string]int, len(r.Tally))}
+		for name, raw := range r.Tally {
+			votes, err := parseVotes(strings.Split(strings.Trim(string(raw), `"`), ".")[0])
+			if err != nil {
+				continue
+			}
+			round.Tallies[name] = votes
+		}
+		rounds = append(rounds, round)
+	}
+
+	contest := models.Contest{Title: s.Config.Contest, RCV: rcv.Tabulate(rounds)}
+	contest.Candidates = rcv.FirstChoices(contest.RCV)
+	return &models.Results{Contests: appendContest(nil, &contest, tr)}, nil
+}
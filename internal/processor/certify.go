@@ -0,0 +1,178 @@
+package processor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/archive"
+	"github.com/many221/era_api_v1/internal/export"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// bundleManifest is manifest.json in a certification bundle.
+type bundleManifest struct {
+	Election     string    `json:"election"`
+	County       string    `json:"county"`
+	SnapshotHash string    `json:"snapshotHash"`
+	CertifiedBy  string    `json:"certifiedBy,omitempty"`
+	CertifiedAt  time.Time `json:"certifiedAt"`
+	// Source describes where the bundled source was fetched from.
+	Source *models.Artifact    `json:"source,omitempty"`
+	Files  []models.BundleFile `json:"files"`
+}
+
+// Certify certifies county's live results in election on behalf of the API
+// key by: it archives their bundle, the results as JSON and CSV with the
+// source they were parsed from, if it was archived, and a manifest of
+// their hashes, then freezes the county so nothing more is published for
+// it. It waits for any run of the county in progress, so what is certified
+// is what that run leaves live.
+func (p *Processor) Certify(ctx context.Context, election, county, by string) (models.Certification, error) {
+	if p.archive == nil {
+		return models.Certification{}, ErrNoArchive
+	}
+	e, err := p.store.Election(election)
+	if err != nil {
+		return models.Certification{}, fmt.Errorf("%w %q", ErrUnknownElection, election)
+	}
+	if e.Archived() {
+		return models.Certification{}, fmt.Errorf("%w: %s", store.ErrElectionArchived, e.ID)
+	}
+	unlock, err := p.counties.lock(ctx, e.ID, county, func(string, int) {})
+	if err != nil {
+		return models.Certification{}, err
+	}
+	defer unlock()
+
+	if _, err := p.store.Certification(e.ID, county); err == nil {
+		return models.Certification{}, fmt.Errorf("%w: %s", store.ErrCertified, county)
+	}
+	results, err := p.store.ElectionResults(e.ID, county)
+	if err != nil {
+		return models.Certification{}, fmt.Errorf("%w: no results for %s in election %s", err, county, e.ID)
+	}
+	c := models.Certification{
+		Election:     e.ID,
+		County:       results.County,
+		SnapshotHash: results.Hash,
+		CertifiedBy:  by,
+		CertifiedAt:  p.clock.Now().UTC(),
+	}
+
+	// The source is included when it was archived; results published
+	// before archiving was set up only have their own record.
+	var source *models.Artifact
+	var sourceData []byte
+	art, err := p.archive.Find(ctx, results.County, results.Hash)
+	switch {
+	case err == nil:
+		if sourceData, err = p.archive.Read(ctx, art); err != nil && !errors.Is(err, archive.ErrNotFound) {
+			return models.Certification{}, err
+		}
+		if err == nil {
+			source = &art
+		}
+	case !errors.Is(err, archive.ErrNotFound):
+		return models.Certification{}, err
+	}
+
+	data, bundle, err := buildBundle(c, results, source, sourceData)
+	if err != nil {
+		return models.Certification{}, err
+	}
+	if err := p.archive.SaveBundle(ctx, c.Election, c.County, data); err != nil {
+		return models.Certification{}, err
+	}
+	c.Bundle = bundle
+	if c, err = p.store.Certify(c); err != nil {
+		return models.Certification{}, err
+	}
+	p.logger.WarnContext(ctx, "county results certified", "county", c.County, "election", c.Election, "snapshot", c.SnapshotHash, "bundle_sha256", c.Bundle.SHA256, "actor", by)
+	return c, nil
+}
+
+// BundleURL is the permanent address of the certification bundle of county
+// in election.
+func BundleURL(election, county string) string {
+	return "/api/v1/elections/" + url.PathEscape(election) + "/results/" + store.CountyKey(county) + "/certification/bundle.zip"
+}
+
+// buildBundle returns the ZIP archive of c's bundle and its description.
+// Every file is stamped with the certification time, so the archive is the
+// same bytes for the same certification.
+func buildBundle(c models.Certification, results *models.Results, source *models.Artifact, sourceData []byte) ([]byte, models.Bundle, error) {
+	type file struct {
+		name string
+		data []byte
+	}
+	var files []file
+
+	resultsJSON, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, models.Bundle{}, fmt.Errorf("build bundle: %w", err)
+	}
+	files = append(files, file{"results.json", resultsJSON})
+	var csv bytes.Buffer
+	if err := export.CSV(&csv, results, nil); err != nil {
+		return nil, models.Bundle{}, fmt.Errorf("build bundle: %w", err)
+	}
+	files = append(files, file{"results.csv", csv.Bytes()})
+	if source != nil {
+		files = append(files, file{"source" + parser.Extension(source.ParseMethod), sourceData})
+	}
+
+	manifest := bundleManifest{
+		Election:     c.Election,
+		County:       c.County,
+		SnapshotHash: c.SnapshotHash,
+		CertifiedBy:  c.CertifiedBy,
+		CertifiedAt:  c.CertifiedAt,
+		Source:       source,
+		Files:        []models.BundleFile{},
+	}
+	for _, f := range files {
+		manifest.Files = append(manifest.Files, models.BundleFile{Name: f.name, Size: len(f.data), SHA256: sha256Of(f.data)})
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, models.Bundle{}, fmt.Errorf("build bundle: %w", err)
+	}
+	files = append(files, file{"manifest.json", manifestJSON})
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: c.CertifiedAt})
+		if err != nil {
+			return nil, models.Bundle{}, fmt.Errorf("build bundle: %w", err)
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return nil, models.Bundle{}, fmt.Errorf("build bundle: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, models.Bundle{}, fmt.Errorf("build bundle: %w", err)
+	}
+
+	return buf.Bytes(), models.Bundle{
+		URL:          BundleURL(c.Election, c.County),
+		Size:         buf.Len(),
+		SHA256:       sha256Of(buf.Bytes()),
+		ManifestHash: sha256Of(manifestJSON),
+		Files:        manifest.Files,
+	}, nil
+}
+
+func sha256Of(data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("sha256:%x", sum)
+}
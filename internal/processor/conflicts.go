@@ -0,0 +1,212 @@
+package processor
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Tolerance is how far sources' counts of a candidate may differ before
+// they conflict: by Votes, or by Percent of the highest count if it is set.
+type Tolerance struct {
+	Votes   int
+	Percent float64
+}
+
+// ParseTolerance reads a tolerance in votes ("25") or as a percentage of
+// the highest count ("0.5%"). An empty one allows no difference.
+func ParseTolerance(s string) (Tolerance, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Tolerance{}, nil
+	}
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+		if err != nil || f < 0 || f > 100 {
+			return Tolerance{}, fmt.Errorf("tolerance %q must be a percentage from 0%% to 100%%", s)
+		}
+		return Tolerance{Percent: f}, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return Tolerance{}, fmt.Errorf("tolerance %q must be a number of votes or a percentage such as 0.5%%", s)
+	}
+	return Tolerance{Votes: n}, nil
+}
+
+func (t Tolerance) String() string {
+	if t.Percent > 0 {
+		return strconv.FormatFloat(t.Percent, 'f', -1, 64) + "%"
+	}
+	return strconv.Itoa(t.Votes)
+}
+
+// exceeded reports whether spread is beyond t for a candidate whose highest
+// count is high.
+func (t Tolerance) exceeded(spread, high int) bool {
+	if t.Percent > 0 {
+		return float64(spread) > t.Percent/100*float64(high)
+	}
+	return spread > t.Votes
+}
+
+// Conflicts compares the latest snapshots of every source level reporting
+// a county of election and returns the contests whose counts differ beyond
+// tol, with the level published and why. county limits the comparison to
+// one county; empty compares them all.
+func (p *Processor) Conflicts(election, county string, tol Tolerance) []models.SourceConflict {
+	out := []models.SourceConflict{}
+	for _, key := range p.store.SourceCounties(election) {
+		if county != "" && key != store.CountyKey(county) {
+			continue
+		}
+		out = append(out, p.countyConflicts(election, key, tol)...)
+	}
+	return out
+}
+
+func (p *Processor) countyConflicts(election, county string, tol Tolerance) []models.SourceConflict {
+	byLevel := p.store.SourceResults(election, county)
+	if len(byLevel) < 2 {
+		return nil
+	}
+	name := county
+	for _, r := range byLevel {
+		name = r.County
+		break
+	}
+	order := p.Precedence(election, name)
+
+	levels := make([]string, 0, len(byLevel))
+	for l := range byLevel {
+		levels = append(levels, l)
+	}
+	slices.SortFunc(levels, func(a, b string) int {
+		switch {
+		case a == b:
+			return 0
+		case Outranks(order, a, b):
+			return -1
+		}
+		return 1
+	})
+
+	published := ""
+	if r, err := p.store.ElectionResults(election, name); err == nil {
+		published = r.Level()
+	}
+	var sources []models.ConflictSource
+	for _, l := range levels {
+		r := byLevel[l]
+		sources = append(sources, models.ConflictSource{
+			Level:     l,
+			Source:    r.Source,
+			ParsedAt:  r.ParsedAt,
+			Hash:      r.Hash,
+			Published: l == published,
+		})
+	}
+
+	// Contests are listed in the order the most trusted source has them.
+	var ids []string
+	seen := make(map[string]bool)
+	for _, l := range levels {
+		for _, c := range byLevel[l].Contests {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+
+	var out []models.SourceConflict
+	for _, id := range ids {
+		contests := make(map[string]*models.Contest)
+		var reporting []string
+		for _, l := range levels {
+			if c, ok := byLevel[l].ContestByID(id); ok {
+				contests[l] = c
+				reporting = append(reporting, l)
+			}
+		}
+		if len(reporting) < 2 {
+			continue
+		}
+		candidates := compareCandidates(reporting, contests, tol)
+		if len(candidates) == 0 {
+			continue
+		}
+		out = append(out, models.SourceConflict{
+			County:     name,
+			Contest:    id,
+			Title:      contests[reporting[0]].Title,
+			Precedence: order,
+			Published:  published,
+			Reason:     conflictReason(reporting, published),
+			Sources:    sources,
+			Candidates: candidates,
+		})
+	}
+	return out
+}
+
+// compareCandidates returns the candidates of a contest whose counts differ
+// beyond tol between the levels reporting it, matched by canonical ID or
+// normalized name and named as the most trusted level names them.
+func compareCandidates(reporting []string, contests map[string]*models.Contest, tol Tolerance) []models.ConflictCandidate {
+	var (
+		keys  []string
+		names = make(map[string]string)
+		votes = make(map[string]map[string]int)
+	)
+	for _, l := range reporting {
+		for _, c := range contests[l].Candidates {
+			key := c.CanonicalID
+			if key == "" {
+				key = normalize.Key(c.Name)
+			}
+			if _, ok := votes[key]; !ok {
+				keys = append(keys, key)
+				names[key] = c.Name
+				votes[key] = make(map[string]int)
+			}
+			votes[key][l] += c.Votes
+		}
+	}
+
+	var out []models.ConflictCandidate
+	for _, key := range keys {
+		v := votes[key]
+		low, high := -1, 0
+		for _, l := range reporting {
+			n := v[l] // zero if the level doesn't list the candidate
+			if low < 0 || n < low {
+				low = n
+			}
+			high = max(high, n)
+		}
+		if spread := high - low; spread > 0 && tol.exceeded(spread, high) {
+			out = append(out, models.ConflictCandidate{Name: names[key], Votes: v, Spread: spread})
+		}
+	}
+	return out
+}
+
+// conflictReason explains why published, of the levels reporting a contest
+// in order of precedence, is the one whose numbers stand.
+func conflictReason(reporting []string, published string) string {
+	switch {
+	case published == "":
+		return "no source has published results for the county"
+	case published == reporting[0]:
+		return fmt.Sprintf("the %s source ranks first of those reporting the contest", published)
+	case !slices.Contains(reporting, published):
+		return fmt.Sprintf("the %s source is published for the county but doesn't report the contest", published)
+	}
+	return fmt.Sprintf("the %s source ranks higher, but its results weren't published, so the %s source's stand", reporting[0], published)
+}
@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// countyLocks lets one run at a time publish to each county of an
+// election, so a scheduled refresh and a process call by hand can't
+// interleave their snapshot writes. The zero value is ready to use.
+type countyLocks struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+// lock waits until no other run holds county in election, or ctx is done,
+// reporting through progress if it has to wait, and returns the function
+// releasing it.
+func (l *countyLocks) lock(ctx context.Context, election, county string, progress ProgressFunc) (func(), error) {
+	key := election + "/" + store.CountyKey(county)
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]chan struct{})
+	}
+	ch, ok := l.locks[key]
+	if !ok {
+		ch = make(chan struct{}, 1)
+		l.locks[key] = ch
+	}
+	l.mu.Unlock()
+
+	select {
+	case ch <- struct{}{}:
+	default:
+		progress("waiting for another run of the county", 5)
+		select {
+		case ch <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return func() { <-ch }, nil
+}
@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"slices"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SetPrecedence sets the order source levels are published in when several
+// report a county, most trusted first. A county's registration can move one
+// level to the front. Levels left out rank after those listed, by name.
+func (p *Processor) SetPrecedence(levels []string) {
+	p.precedence = append([]string(nil), levels...)
+}
+
+// Precedence returns the order source levels are published in for county,
+// most trusted first: its registered precedence, then the deployment's
+// order. Unregistered counties have no own source to prefer, but one posted
+// by hand still ranks as the county's.
+func (p *Processor) Precedence(election, county string) []string {
+	order := p.precedence
+	if len(order) == 0 {
+		order = models.DefaultPrecedence
+	}
+	reg, err := p.store.ElectionCounty(election, county)
+	if err != nil || reg.Precedence == "" {
+		return slices.Clone(order)
+	}
+	out := []string{reg.Precedence}
+	for _, l := range order {
+		if l != reg.Precedence {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Outranks reports whether level a takes precedence over level b in order.
+func Outranks(order []string, a, b string) bool {
+	ra, rb := rank(order, a), rank(order, b)
+	if ra != rb {
+		return ra < rb
+	}
+	// Unlisted levels are ordered by name, so the outcome doesn't depend
+	// on which reported first.
+	return a < b
+}
+
+func rank(order []string, level string) int {
+	if i := slices.Index(order, level); i >= 0 {
+		return i
+	}
+	return len(order)
+}
+
+// outranked returns the county's published results if they came from a
+// source level that takes precedence over results', so results shouldn't
+// replace them.
+func (p *Processor) outranked(results *models.Results) (*models.Results, bool) {
+	prev, err := p.store.ElectionResults(results.Election, results.County)
+	if err != nil || prev.Level() == results.Level() {
+		return nil, false
+	}
+	if !Outranks(p.Precedence(results.Election, results.County), prev.Level(), results.Level()) {
+		return nil, false
+	}
+	return prev, true
+}
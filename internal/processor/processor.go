@@ -0,0 +1,1021 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/anomaly"
+	"github.com/many221/era_api_v1/internal/archive"
+	"github.com/many221/era_api_v1/internal/browser"
+	"github.com/many221/era_api_v1/internal/clock"
+	"github.com/many221/era_api_v1/internal/drift"
+	"github.com/many221/era_api_v1/internal/fetcher"
+	"github.com/many221/era_api_v1/internal/formatter"
+	"github.com/many221/era_api_v1/internal/manifest"
+	"github.com/many221/era_api_v1/internal/measures"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/templates"
+)
+
+// ErrQuarantined is returned by Process when the new snapshot was held back
+// for review instead of being published.
+var ErrQuarantined = errors.New("snapshot quarantined")
+
+// ErrNotHeld is returned by Release for records that were already released.
+var ErrNotHeld = errors.New("quarantined snapshot is not held")
+
+// ErrSuperseded is returned by Release for a snapshot older than the one
+// the county has published since it was held.
+var ErrSuperseded = errors.New("the county has published newer results since the snapshot was held")
+
+// ErrResolved is returned by RetryParseError for records already resolved.
+var ErrResolved = errors.New("parse error is already resolved")
+
+// ErrUnknownElection is returned by Process for a request naming an
+// election the store doesn't have.
+var ErrUnknownElection = errors.New("unknown election")
+
+// ErrUnknownJurisdiction is returned by Process for a request naming a
+// jurisdiction that isn't in a county of the tree, or not in the county it
+// names.
+var ErrUnknownJurisdiction = errors.New("unknown jurisdiction")
+
+// ErrBrowserDisabled is returned by Process for a request that asks for a
+// headless browser when the deployment has none.
+var ErrBrowserDisabled = errors.New("headless browser fetching isn't enabled")
+
+// ProgressFunc receives stage updates while a request is being processed.
+type ProgressFunc func(stage string, percent int)
+
+// SnapshotHook is called in its own goroutine after a new snapshot of a
+// county's results has been saved. results must be treated as read-only.
+type SnapshotHook func(ctx context.Context, results *models.Results)
+
+// Transform rewrites freshly parsed contests before they are saved, e.g. to
+// normalize names. Transforms run in registration order.
+type Transform func(req models.ProcessRequest, contests []models.Contest) []models.Contest
+
+// Alerter raises operational alerts.
+type Alerter interface {
+	Raise(ctx context.Context, a models.Alert)
+}
+
+// Processor runs the fetch → parse → render pipeline for a process request.
+type Processor struct {
+	fetcher    *fetcher.Fetcher
+	store      store.Store
+	logger     *slog.Logger
+	license    *models.License
+	clock      clock.Clock
+	templates  *templates.Registry
+	archive    *archive.Archive
+	drift      *drift.Policy
+	anomalies  *anomaly.Detector
+	manifest   *manifest.Checker
+	fallback   bool
+	alerter    Alerter
+	transforms []Transform
+	hooks      []SnapshotHook
+	precedence []string
+	browser    *browser.Browser
+	counties   countyLocks
+}
+
+// New returns a Processor that downloads sources with f and saves parsed
+// results to st.
+func New(f *fetcher.Fetcher, st store.Store, logger *slog.Logger) *Processor {
+	return &Processor{fetcher: f, store: st, logger: logger, clock: clock.System{}}
+}
+
+// SetClock sets the clock snapshots are timestamped with. Published times
+// should come from a trusted clock rather than the bare host clock.
+func (p *Processor) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// SetDefaultLicense sets the license attached to results whose source
+// doesn't specify one.
+func (p *Processor) SetDefaultLicense(l *models.License) {
+	p.license = l
+}
+
+// SetTemplates makes the processor render results with the template
+// assigned to their county or content type in reg.
+func (p *Processor) SetTemplates(reg *templates.Registry) {
+	p.templates = reg
+}
+
+// SetArchive makes the processor keep the raw source of every snapshot,
+// published or quarantined, in a, so it can be audited and re-extracted
+// later.
+func (p *Processor) SetArchive(a *archive.Archive) {
+	p.archive = a
+}
+
+// SetDriftPolicy makes the processor quarantine snapshots whose totals moved
+// further from the county's previous snapshot than policy allows, instead
+// of publishing them.
+func (p *Processor) SetDriftPolicy(policy drift.Policy) {
+	p.drift = &policy
+}
+
+// SetAnomalyDetector makes the processor check every snapshot with d
+// before it is published, attaching the anomalies found to the results and
+// recording and logging them. Anomalous snapshots are still published.
+func (p *Processor) SetAnomalyDetector(d anomaly.Detector) {
+	p.anomalies = &d
+}
+
+// SetManifest makes the processor check every snapshot against the
+// election manifest c reads, warning about contests and candidates that
+// differ from the expected ballot.
+func (p *Processor) SetManifest(c *manifest.Checker) {
+	p.manifest = c
+}
+
+// SetParserFallback makes the processor retry a parse that failed right
+// after the source's layout changed with the county's earlier parser
+// configs, publishing with a warning if one of them still reads it.
+func (p *Processor) SetParserFallback(enabled bool) {
+	p.fallback = enabled
+}
+
+// SetBrowser makes the processor fetch sources whose requests ask for it
+// by rendering them in b.
+func (p *Processor) SetBrowser(b *browser.Browser) {
+	p.browser = b
+}
+
+// SetAlerter makes the processor raise alerts through a when a source's
+// layout changes, a snapshot is quarantined or a fallback parser config is
+// used.
+func (p *Processor) SetAlerter(a Alerter) {
+	p.alerter = a
+}
+
+// AddTransform registers t to run on every parse. It must be called before
+// the processor starts serving requests.
+func (p *Processor) AddTransform(t Transform) {
+	p.transforms = append(p.transforms, t)
+}
+
+// OnSnapshot registers h to run after every saved snapshot. It must be called
+// before the processor starts serving requests.
+func (p *Processor) OnSnapshot(h SnapshotHook) {
+	p.hooks = append(p.hooks, h)
+}
+
+// Process fetches and parses the source described by req and renders the
+// result. progress may be nil.
+func (p *Processor) Process(ctx context.Context, req models.ProcessRequest, progress ProgressFunc) (*models.ProcessResponse, error) {
+	return p.process(ctx, req, nil, progress)
+}
+
+// ProcessUpload parses data, a source uploaded rather than fetched, as
+// Process would have parsed it had it been fetched from req.FileLink.
+func (p *Processor) ProcessUpload(ctx context.Context, req models.ProcessRequest, data []byte, progress ProgressFunc) (*models.ProcessResponse, error) {
+	return p.process(ctx, req, &fetcher.Source{Data: data}, progress)
+}
+
+// process runs the pipeline on src, or on the source fetched from
+// req.FileLink if src is nil.
+func (p *Processor) process(ctx context.Context, req models.ProcessRequest, src *fetcher.Source, progress ProgressFunc) (*models.ProcessResponse, error) {
+	if progress == nil {
+		progress = func(string, int) {}
+	}
+	start := time.Now()
+	election, err := p.store.Election(req.Election)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownElection, req.Election)
+	}
+	if election.Archived() {
+		return nil, fmt.Errorf("%w: %s", store.ErrElectionArchived, election.ID)
+	}
+	req.Election = election.ID
+	if req.Jurisdiction != "" {
+		county, err := p.store.JurisdictionCounty(req.Jurisdiction)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q, or it isn't in a county", ErrUnknownJurisdiction, req.Jurisdiction)
+		}
+		if req.CountyName == "" {
+			req.CountyName = county.Name
+		} else if store.CountyKey(req.CountyName) != county.ID {
+			return nil, fmt.Errorf("%w %q in county %q", ErrUnknownJurisdiction, req.Jurisdiction, req.CountyName)
+		}
+	}
+	// A dry run publishes nothing, so it needn't wait its turn.
+	if !req.DryRun {
+		unlock, err := p.counties.lock(ctx, req.Election, req.CountyName, progress)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		if _, err := p.store.Certification(req.Election, req.CountyName); err == nil {
+			return nil, fmt.Errorf("%w: %s", store.ErrCertified, req.CountyName)
+		}
+	}
+
+	// A source handed over already fetched, like an upload, has its
+	// latency start on receipt.
+	now := p.clock.Now().UTC()
+	latency := &models.Latency{FetchStartedAt: now, FetchedAt: now}
+	if src == nil {
+		progress("fetching", 10)
+		if req.Browser != nil {
+			src, err = p.renderSource(ctx, req)
+		} else {
+			src, err = p.fetch(ctx, req.CountyName, req.FileLink)
+		}
+		if err != nil {
+			return nil, err
+		}
+		latency.FetchedAt = p.clock.Now().UTC()
+	}
+	data := src.Data
+	latency.SourceModifiedAt = src.ModifiedAt
+
+	if strings.EqualFold(req.ParseMethod, models.ParseMethodAuto) {
+		name := src.Name
+		if name == "" {
+			name = req.FileLink
+		}
+		method, reason, err := parser.Detect(data, src.ContentType, name)
+		if err != nil && req.DryRun {
+			return nil, err
+		}
+		if err != nil {
+			id := p.recordParseError(ctx, req, data, nil, err)
+			return nil, fmt.Errorf("%w (source kept as parse error %s)", err, id)
+		}
+		parser.TraceFrom(ctx).Add(models.TraceFormat, "auto: %s, from the %s", method, reason)
+		p.logger.InfoContext(ctx, "parse method detected", "county", req.CountyName, "parse_method", method, "from", reason)
+		req.ParseMethod = method
+	}
+
+	progress("parsing", 40)
+	results, sample, err := p.extract(ctx, req, data)
+	if req.DryRun {
+		if err != nil {
+			return nil, err
+		}
+		results.Latency = latency
+		resp, err := p.dryRun(ctx, req, results, sample)
+		if err != nil {
+			return nil, err
+		}
+		progress("done", 100)
+		return resp, nil
+	}
+	// A method no parser implements says nothing about the source, so it
+	// neither moves the layout baseline nor is kept as a parse error.
+	if errors.Is(err, parser.ErrUnsupportedMethod) {
+		return nil, err
+	}
+	var warnings []string
+	// Staged results don't move the live layout baseline forward, so
+	// they neither report layout changes nor fall back on earlier configs;
+	// nor do those of other levels, as the baseline is the county's own.
+	own := req.SourceLevel == "" || req.SourceLevel == models.SourceLevelCounty
+	if req.Dataset == "" && own && p.checkLayout(ctx, req, sample.Layout) && err != nil && p.fallback {
+		if c, fr, fs, ok := p.parseFallback(ctx, req, data); ok {
+			warnings = append(warnings, fmt.Sprintf("source layout changed and the current parser config failed (%v); published with parser config revision %d (%s) instead", err, c.Revision, c.ParseMethod))
+			p.logger.WarnContext(ctx, "published with fallback parser config",
+				"county", req.CountyName,
+				"revision", c.Revision,
+				"parse_method", c.ParseMethod,
+				"error", err,
+			)
+			p.alert(ctx, models.Alert{
+				Type:     models.AlertParserFallback,
+				Severity: models.SeverityWarning,
+				County:   req.CountyName,
+				Source:   req.FileLink,
+				Summary:  fmt.Sprintf("%s published with fallback parser config revision %d", req.CountyName, c.Revision),
+				Details: map[string]string{
+					"error":        err.Error(),
+					"parse_method": c.ParseMethod,
+				},
+			})
+			req, results, sample, err = c.Apply(req), fr, fs, nil
+			// The next fetch is compared with the layout that was read.
+			if sample.Layout != nil {
+				p.store.SwapLayout(req.CountyName, sample.Layout)
+			}
+		}
+	}
+	if err != nil {
+		id := p.recordParseError(ctx, req, data, sample, err)
+		return nil, fmt.Errorf("%w (source kept as parse error %s)", err, id)
+	}
+	results.Latency = latency
+	if req.Dataset == "" {
+		// Every level's latest snapshot is kept for comparison, even one
+		// that isn't published.
+		p.store.SaveSourceResults(results)
+		if prev, ok := p.outranked(results); ok {
+			html, err := p.render(ctx, req, prev)
+			if err != nil {
+				return nil, err
+			}
+			progress("done", 100)
+			return &models.ProcessResponse{HTML: html, Results: prev, Warnings: append(warnings,
+				fmt.Sprintf("the %s source takes precedence for %s, so its results were kept; this source's are listed by /api/v1/conflicts where they differ", prev.Level(), req.CountyName),
+			)}, nil
+		}
+	}
+	warnings = append(warnings, p.checkManifest(ctx, req, results)...)
+	if req.Dataset != "" {
+		progress("staging", 90)
+		resp, err := p.stage(ctx, req, results, data)
+		if err != nil {
+			return nil, err
+		}
+		resp.Warnings = warnings
+		progress("done", 100)
+		return resp, nil
+	}
+	// Results the county has already published, as most fetches between
+	// updates find, aren't published again: its history gains no snapshot
+	// and no hooks fire.
+	if prev, err := p.store.ElectionResults(req.Election, req.CountyName); err == nil && prev.Hash == results.Hash {
+		html, err := p.render(ctx, req, prev)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.InfoContext(ctx, "source unchanged", "county", req.CountyName, "snapshot", prev.Hash, "duration", time.Since(start))
+		progress("done", 100)
+		return &models.ProcessResponse{HTML: html, Results: prev, Warnings: warnings, Unchanged: true}, nil
+	}
+	if rec, held := p.checkDrift(ctx, req, results, sample, data); held {
+		progress("quarantined", 100)
+		return nil, fmt.Errorf("%w as %s: %s", ErrQuarantined, rec.ID, strings.Join(rec.Reasons, "; "))
+	}
+
+	progress("rendering", 90)
+	resp, err := p.publish(ctx, req, results, sample, data)
+	if err != nil {
+		return nil, err
+	}
+	resp.Warnings = warnings
+
+	p.logger.InfoContext(ctx, "source processed",
+		"county", req.CountyName,
+		"parse_method", req.ParseMethod,
+		"bytes", len(data),
+		"contests", len(results.Contests),
+		"duration", time.Since(start),
+	)
+	progress("done", 100)
+
+	return resp, nil
+}
+
+// dryRun renders results and returns them with the warnings publishing
+// them would raise, without saving anything: no snapshot, layout, parse
+// error, quarantine or archived source is kept and no hooks run.
+func (p *Processor) dryRun(ctx context.Context, req models.ProcessRequest, results *models.Results, sample *models.SourceSample) (*models.ProcessResponse, error) {
+	var warnings []string
+	if req.Dataset == "" && (req.SourceLevel == "" || req.SourceLevel == models.SourceLevelCounty) {
+		hash := ""
+		if sample.Layout != nil {
+			hash = sample.Layout.Hash
+		}
+		if prev := p.store.Layout(req.CountyName); prev != nil && prev.Hash != hash {
+			warnings = append(warnings, "the source's layout changed since the county's last fetch, which publishing would alert on")
+		}
+	}
+	if req.Dataset == "" {
+		if prev, ok := p.outranked(results); ok {
+			warnings = append(warnings, fmt.Sprintf("the %s source takes precedence for %s, so its results would be kept", prev.Level(), req.CountyName))
+		}
+	}
+	warnings = append(warnings, p.checkManifest(ctx, req, results)...)
+	if prev, err := p.store.ElectionResults(req.Election, req.CountyName); err == nil && req.Dataset == "" {
+		if prev.Hash == results.Hash {
+			warnings = append(warnings, "the results are the same as the county's published snapshot, so it would be left as it is")
+		} else if p.drift != nil {
+			if reasons := p.drift.Check(prev, results); len(reasons) > 0 {
+				warnings = append(warnings, "the snapshot would be quarantined: "+strings.Join(reasons, "; "))
+			}
+		}
+	}
+	p.checkAnomalies(ctx, req, results, false)
+	for _, a := range results.Anomalies {
+		warnings = append(warnings, "anomaly: "+a.Message)
+	}
+
+	html, err := p.render(ctx, req, results)
+	if err != nil {
+		return nil, err
+	}
+	p.logger.InfoContext(ctx, "source dry run", "county", req.CountyName, "parse_method", req.ParseMethod, "contests", len(results.Contests), "warnings", len(warnings))
+	return &models.ProcessResponse{HTML: html, Results: results, Warnings: warnings, DryRun: true}, nil
+}
+
+// fetch downloads link, logging in with the credentials stored for county
+// if it has any.
+func (p *Processor) fetch(ctx context.Context, county, link string) (*fetcher.Source, error) {
+	var login *url.Userinfo
+	if c, err := p.store.Credentials(county); err == nil {
+		login = c.Userinfo()
+	}
+	return p.fetcher.FetchSourceAs(ctx, link, login)
+}
+
+// renderSource loads req's page in the headless browser.
+func (p *Processor) renderSource(ctx context.Context, req models.ProcessRequest) (*fetcher.Source, error) {
+	if p.browser == nil {
+		return nil, ErrBrowserDisabled
+	}
+	data, err := p.browser.Render(ctx, req.FileLink, req.Browser.Capture)
+	if err != nil {
+		return nil, err
+	}
+	return &fetcher.Source{Data: data}, nil
+}
+
+// stage renders results and adds them to the dataset req.Dataset instead of
+// publishing them. Drift checks are skipped: a staged dataset is reviewed
+// as a whole before it is switched live.
+func (p *Processor) stage(ctx context.Context, req models.ProcessRequest, results *models.Results, data []byte) (*models.ProcessResponse, error) {
+	html, err := p.render(ctx, req, results)
+	if err != nil {
+		return nil, err
+	}
+	// Latency measures post-to-publish time, which a staged snapshot
+	// doesn't have.
+	results.Latency = nil
+	p.checkAnomalies(ctx, req, results, false)
+	p.archiveSource(ctx, req, results, data)
+	p.store.StageResults(req.Dataset, results, p.clock.Now().UTC())
+	p.logger.InfoContext(ctx, "results staged", "county", req.CountyName, "dataset", req.Dataset, "hash", results.Hash)
+	return &models.ProcessResponse{HTML: html, Results: results}, nil
+}
+
+// ActivateDataset switches every snapshot staged under label live in one
+// step and notifies hooks of each. Activating models.DatasetPrevious
+// reverses the last switch.
+func (p *Processor) ActivateDataset(ctx context.Context, label string) (models.DatasetSwitch, error) {
+	activated, sw, err := p.store.ActivateDataset(label, p.clock.Now().UTC())
+	if err != nil {
+		return models.DatasetSwitch{}, err
+	}
+	for _, results := range activated {
+		p.runHooks(ctx, results)
+	}
+	p.logger.InfoContext(ctx, "dataset switched live", "dataset", label, "counties", len(sw.Counties), "added", len(sw.Added))
+	return sw, nil
+}
+
+// Release publishes a quarantined snapshot as if it had passed review. A
+// snapshot parsed before the county's published one is refused with
+// ErrSuperseded unless force is set, so releasing an old record can't roll
+// the county back by mistake.
+func (p *Processor) Release(ctx context.Context, id string, force bool) (*models.ProcessResponse, error) {
+	rec, err := p.store.Quarantine(id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status != models.QuarantineHeld {
+		return nil, fmt.Errorf("%w: %s is %s", ErrNotHeld, id, rec.Status)
+	}
+	unlock, err := p.counties.lock(ctx, rec.Request.Election, rec.County, func(string, int) {})
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if prev, err := p.store.ElectionResults(rec.Request.Election, rec.County); err == nil && !force && prev.ParsedAt.After(rec.Results.ParsedAt) {
+		return nil, fmt.Errorf("%w: %s was published at %s", ErrSuperseded, prev.Hash, prev.ParsedAt.Format(time.RFC3339))
+	}
+	resp, err := p.publish(ctx, rec.Request, rec.Results, rec.Sample, rec.Source)
+	if err != nil {
+		return nil, err
+	}
+	rec.Status = models.QuarantineReleased
+	if !p.store.Held(rec.Request.Election, rec.County) {
+		rec.Source = nil
+	}
+	p.store.SaveQuarantine(rec)
+	p.logger.InfoContext(ctx, "quarantined snapshot released", "id", id, "county", rec.County)
+	return resp, nil
+}
+
+// RetryParseError parses the file kept by a parse error again and
+// publishes the results as Process would. The county's current
+// registration is used if it has one, so a corrected parser config is
+// picked up. A file that still fails updates the record.
+func (p *Processor) RetryParseError(ctx context.Context, id string) (*models.ProcessResponse, error) {
+	rec, err := p.store.ParseError(id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status != models.ParseErrorOpen {
+		return nil, fmt.Errorf("%w: %s", ErrResolved, id)
+	}
+	req := rec.Request
+	if c, err := p.store.ElectionCounty(rec.Request.Election, rec.County); err == nil {
+		req = c.ProcessRequest()
+	}
+	unlock, err := p.counties.lock(ctx, rec.Request.Election, rec.County, func(string, int) {})
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data := rec.Source
+	results, sample, err := p.extract(ctx, req, data)
+	if err != nil {
+		p.recordParseError(ctx, req, data, sample, err)
+		return nil, err
+	}
+	now := p.clock.Now().UTC()
+	rec.Status = models.ParseErrorResolved
+	rec.ResolvedAt = &now
+	rec.ParseMethod = req.ParseMethod
+	if !p.store.Held(rec.Request.Election, rec.County) {
+		rec.Source = nil
+	}
+
+	if qrec, held := p.checkDrift(ctx, req, results, sample, data); held {
+		p.store.SaveParseError(rec)
+		return nil, fmt.Errorf("%w as %s: %s", ErrQuarantined, qrec.ID, strings.Join(qrec.Reasons, "; "))
+	}
+	resp, err := p.publish(ctx, req, results, sample, data)
+	if err != nil {
+		return nil, err
+	}
+	rec.SnapshotHash = results.Hash
+	p.store.SaveParseError(rec)
+	p.logger.InfoContext(ctx, "parse error resolved by retry", "id", id, "county", rec.County)
+	return resp, nil
+}
+
+// recordParseError keeps a source that failed to parse, folding repeated
+// failures on the same file into one record, and returns the record's ID.
+func (p *Processor) recordParseError(ctx context.Context, req models.ProcessRequest, data []byte, sample *models.SourceSample, parseErr error) string {
+	sum := sha256.Sum256(data)
+	hash := "sha256:" + hex.EncodeToString(sum[:])
+	now := p.clock.Now().UTC()
+
+	rec, ok := p.store.OpenParseError(req.CountyName, hash)
+	if !ok {
+		id, err := models.NewID()
+		if err != nil {
+			p.logger.ErrorContext(ctx, "failed to record parse error", "county", req.CountyName, "error", err)
+			return ""
+		}
+		rec = models.ParseError{
+			ID:          id,
+			County:      req.CountyName,
+			Status:      models.ParseErrorOpen,
+			SourceHash:  hash,
+			Size:        len(data),
+			FirstSeenAt: now,
+			Source:      data,
+		}
+	}
+	rec.FileLink = req.FileLink
+	rec.ParseMethod = req.ParseMethod
+	rec.Error = parseErr.Error()
+	rec.Sample = sample
+	rec.Request = req
+	rec.Occurrences++
+	rec.LastSeenAt = now
+	p.store.SaveParseError(rec)
+	p.logger.WarnContext(ctx, "source failed to parse", "county", req.CountyName, "parse_error", rec.ID, "occurrences", rec.Occurrences, "error", parseErr)
+	return rec.ID
+}
+
+// publish renders and saves results and notifies hooks.
+func (p *Processor) publish(ctx context.Context, req models.ProcessRequest, results *models.Results, sample *models.SourceSample, data []byte) (*models.ProcessResponse, error) {
+	html, err := p.render(ctx, req, results)
+	if err != nil {
+		return nil, err
+	}
+
+	p.archiveSource(ctx, req, results, data)
+	if results.Latency != nil {
+		results.Latency.Publish(p.clock.Now().UTC())
+	}
+	p.checkAnomalies(ctx, req, results, true)
+	if err := p.store.SaveResults(results); err != nil {
+		return nil, fmt.Errorf("save results: %w", err)
+	}
+	// Other levels say nothing about the county's own source, whose
+	// sample and parser configs are compared with its next fetch.
+	if results.Level() == models.SourceLevelCounty {
+		p.store.SaveSample(req.CountyName, sample)
+		config := models.ParserConfigOf(req)
+		config.PublishedAt = results.ParsedAt
+		p.store.RecordParserConfig(req.CountyName, config)
+	}
+	p.runHooks(ctx, results)
+	return &models.ProcessResponse{HTML: html, Results: results}, nil
+}
+
+// render formats results as HTML with the county's template, if it has one.
+func (p *Processor) render(ctx context.Context, req models.ProcessRequest, results *models.Results) (string, error) {
+	html, err := formatter.HTML(results)
+	if err != nil {
+		return "", fmt.Errorf("format results: %w", err)
+	}
+	if p.templates != nil {
+		// A broken custom template must not stop results from publishing,
+		// so it falls back to the standard fragment.
+		if page, err := p.templates.Render(results, html); err != nil {
+			p.logger.ErrorContext(ctx, "failed to render template", "county", req.CountyName, "error", err)
+		} else {
+			html = page
+		}
+	}
+	return html, nil
+}
+
+// Render renders results obtained elsewhere as they would be published:
+// contests get IDs, transforms and measure outcomes, and are rendered with
+// req's template or the one assigned to them. Nothing is saved.
+func (p *Processor) Render(ctx context.Context, req models.RenderRequest) (*models.Results, string, error) {
+	turnout := req.Turnout
+	if turnout != nil && turnout.Percent == 0 {
+		turnout.Percent = models.TurnoutPercent(turnout.BallotsCast, turnout.RegisteredVoters)
+	}
+	results := p.results(models.ProcessRequest{
+		CountyName:       req.County,
+		ContentType:      req.ContentType,
+		MeasureThreshold: req.MeasureThreshold,
+		License:          req.License,
+	}, &models.Results{Contests: req.Contests, Turnout: turnout})
+
+	if req.Template == "" {
+		html, err := p.render(ctx, models.ProcessRequest{CountyName: req.County}, results)
+		return results, html, err
+	}
+	if p.templates == nil {
+		return nil, "", fmt.Errorf("%w: %s", templates.ErrNotFound, req.Template)
+	}
+	fragment, err := formatter.HTML(results)
+	if err != nil {
+		return nil, "", fmt.Errorf("format results: %w", err)
+	}
+	html, err := p.templates.RenderWith(req.Template, results, fragment)
+	if err != nil {
+		return nil, "", err
+	}
+	return results, html, nil
+}
+
+// archiveSource keeps data as the source of results, if sources are
+// archived. Failing to archive doesn't stop a publish.
+func (p *Processor) archiveSource(ctx context.Context, req models.ProcessRequest, results *models.Results, data []byte) {
+	if p.archive == nil || data == nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+	art := models.Artifact{
+		County:       req.CountyName,
+		SnapshotHash: results.Hash,
+		ContentHash:  "sha256:" + hex.EncodeToString(sum[:]),
+		ParseMethod:  req.ParseMethod,
+		FileLink:     req.FileLink,
+		FetchedAt:    p.clock.Now().UTC(),
+	}
+	if results.Latency != nil && !results.Latency.FetchedAt.IsZero() {
+		art.FetchedAt = results.Latency.FetchedAt
+	}
+	if err := p.archive.Save(ctx, art, data); err != nil {
+		p.logger.ErrorContext(ctx, "failed to archive source", "county", req.CountyName, "error", err)
+	}
+}
+
+// checkAnomalies attaches the anomalies in results, against the county's
+// published snapshot, to them. New anomalies are logged and, if record is
+// set, added to the history; a republished snapshot keeps its earlier flags
+// without repeating them.
+func (p *Processor) checkAnomalies(ctx context.Context, req models.ProcessRequest, results *models.Results, record bool) {
+	if p.anomalies == nil {
+		return
+	}
+	prev, err := p.store.ElectionResults(req.Election, req.CountyName)
+	if err != nil {
+		prev = nil
+	} else if prev.Hash == results.Hash {
+		results.Anomalies = prev.Anomalies
+		return
+	}
+	found := p.anomalies.Check(prev, results)
+	if len(found) == 0 {
+		return
+	}
+	now := p.clock.Now().UTC()
+	for i := range found {
+		found[i].DetectedAt = now
+		p.logger.WarnContext(ctx, "snapshot anomaly",
+			"county", req.CountyName,
+			"kind", found[i].Kind,
+			"contest", found[i].ContestID,
+			"hash", results.Hash,
+			"message", found[i].Message,
+		)
+	}
+	results.Anomalies = found
+	if record {
+		p.store.AddAnomalies(found)
+	}
+}
+
+// checkManifest compares results with the election manifest, if there is
+// one, and returns a warning for each way they differ.
+func (p *Processor) checkManifest(ctx context.Context, req models.ProcessRequest, results *models.Results) []string {
+	if p.manifest == nil {
+		return nil
+	}
+	check, ok := p.manifest.Check(results, p.clock.Now().UTC())
+	if !ok || check.OK {
+		return nil
+	}
+	var warnings []string
+	if len(check.Missing) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d of %d contests expected by the manifest are missing: %s", len(check.Missing), check.Expected, contestIDs(check.Missing)))
+	}
+	if len(check.Unexpected) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d contests are not in the manifest: %s", len(check.Unexpected), contestIDs(check.Unexpected)))
+	}
+	for _, mm := range check.Candidates {
+		var diffs []string
+		if len(mm.Missing) > 0 {
+			diffs = append(diffs, "missing "+strings.Join(mm.Missing, ", "))
+		}
+		if len(mm.Unexpected) > 0 {
+			diffs = append(diffs, "unexpected "+strings.Join(mm.Unexpected, ", "))
+		}
+		warnings = append(warnings, fmt.Sprintf("candidates of %s differ from the manifest: %s", mm.ContestID, strings.Join(diffs, "; ")))
+	}
+	p.logger.WarnContext(ctx, "snapshot differs from manifest",
+		"county", req.CountyName,
+		"missing", len(check.Missing),
+		"unexpected", len(check.Unexpected),
+		"candidate_mismatches", len(check.Candidates),
+	)
+	return warnings
+}
+
+func contestIDs(refs []models.ManifestContestRef) string {
+	ids := make([]string, len(refs))
+	for i, ref := range refs {
+		ids[i] = ref.ID
+	}
+	return strings.Join(ids, ", ")
+}
+
+// checkDrift quarantines results if the drift policy rejects them,
+// returning the record holding them.
+func (p *Processor) checkDrift(ctx context.Context, req models.ProcessRequest, results *models.Results, sample *models.SourceSample, data []byte) (models.QuarantineRecord, bool) {
+	if p.drift == nil {
+		return models.QuarantineRecord{}, false
+	}
+	prev, err := p.store.ElectionResults(req.Election, req.CountyName)
+	if err != nil || prev.Hash == results.Hash {
+		return models.QuarantineRecord{}, false
+	}
+	reasons := p.drift.Check(prev, results)
+	if len(reasons) == 0 {
+		return models.QuarantineRecord{}, false
+	}
+	if rec, ok := p.store.HeldQuarantine(req.CountyName, results.Hash); ok {
+		return rec, true
+	}
+
+	id, err := models.NewID()
+	if err != nil {
+		p.logger.ErrorContext(ctx, "failed to quarantine snapshot, publishing it", "county", req.CountyName, "error", err)
+		return models.QuarantineRecord{}, false
+	}
+	rec := models.QuarantineRecord{
+		ID:           id,
+		County:       req.CountyName,
+		Status:       models.QuarantineHeld,
+		Reasons:      reasons,
+		CreatedAt:    p.clock.Now().UTC(),
+		PreviousHash: prev.Hash,
+		Suggestions:  drift.Suggest(p.store.Sample(req.CountyName), sample),
+		Results:      results,
+		Request:      req,
+		Source:       data,
+		Sample:       sample,
+	}
+	p.store.SaveQuarantine(rec)
+	p.archiveSource(ctx, req, results, data)
+	p.logger.WarnContext(ctx, "snapshot quarantined",
+		"id", id,
+		"county", req.CountyName,
+		"reasons", reasons,
+		"suggestions", len(rec.Suggestions),
+	)
+	p.alert(ctx, models.Alert{
+		Type:     models.AlertQuarantined,
+		Severity: models.SeverityError,
+		County:   req.CountyName,
+		Source:   req.FileLink,
+		Summary:  fmt.Sprintf("%s snapshot quarantined: %s", req.CountyName, strings.Join(reasons, "; ")),
+		Details: map[string]string{
+			"quarantine_id": id,
+			"suggestions":   strconv.Itoa(len(rec.Suggestions)),
+		},
+	})
+	return rec, true
+}
+
+// checkLayout compares the layout of a fetched source with the county's
+// previous fetch and records and reports whether it changed. Alerting
+// before totals go wrong gives operators a chance to fix mappings first.
+func (p *Processor) checkLayout(ctx context.Context, req models.ProcessRequest, layout *models.SourceLayout) bool {
+	if layout == nil {
+		// Nothing recognizable is a layout too: a source whose tables all
+		// vanished has changed.
+		layout = &models.SourceLayout{Features: []string{}}
+	}
+	prev := p.store.SwapLayout(req.CountyName, layout)
+	if prev == nil || prev.Hash == layout.Hash {
+		return false
+	}
+
+	c := models.LayoutChange{
+		County:       req.CountyName,
+		Source:       req.FileLink,
+		DetectedAt:   p.clock.Now().UTC(),
+		PreviousHash: prev.Hash,
+		Hash:         layout.Hash,
+		Added:        difference(layout.Features, prev.Features),
+		Removed:      difference(prev.Features, layout.Features),
+	}
+	p.store.AddLayoutChange(c)
+	p.logger.WarnContext(ctx, "source layout changed",
+		"county", req.CountyName,
+		"source", req.FileLink,
+		"previous_hash", c.PreviousHash,
+		"hash", c.Hash,
+		"added", c.Added,
+		"removed", c.Removed,
+	)
+	p.alert(ctx, models.Alert{
+		Type:     models.AlertLayoutChanged,
+		Severity: models.SeverityWarning,
+		County:   req.CountyName,
+		Source:   req.FileLink,
+		Summary:  fmt.Sprintf("%s source layout changed", req.CountyName),
+		Details: map[string]string{
+			"added":   strings.Join(c.Added, "; "),
+			"removed": strings.Join(c.Removed, "; "),
+		},
+	})
+	return true
+}
+
+// parseFallback re-extracts data with each of the county's earlier parser
+// configs, newest first, and returns the first that reads it.
+func (p *Processor) parseFallback(ctx context.Context, req models.ProcessRequest, data []byte) (models.ParserConfig, *models.Results, *models.SourceSample, bool) {
+	current := models.ParserConfigOf(req)
+	for _, c := range p.store.ParserConfigs(req.CountyName) {
+		if c.Same(current) {
+			continue
+		}
+		if results, sample, err := p.extract(ctx, c.Apply(req), data); err == nil {
+			return c, results, sample, true
+		}
+	}
+	return models.ParserConfig{}, nil, nil, false
+}
+
+// difference returns the features in a that aren't in b.
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, f := range b {
+		in[f] = true
+	}
+	out := []string{}
+	for _, f := range a {
+		if !in[f] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Extract parses a fetched source into the results that Process would
+// publish, without rendering, saving or notifying hooks.
+func (p *Processor) Extract(ctx context.Context, req models.ProcessRequest, data []byte) (*models.Results, error) {
+	results, _, err := p.extract(ctx, req, data)
+	return results, err
+}
+
+// extract is Extract, also returning the source's sample, which is set even
+// if parsing fails.
+func (p *Processor) extract(ctx context.Context, req models.ProcessRequest, data []byte) (*models.Results, *models.SourceSample, error) {
+	tr := parser.TraceFrom(ctx)
+	if tr == nil {
+		tr = parser.NewSampleTrace()
+		ctx = parser.WithTrace(ctx, tr)
+	}
+	parsed, err := parser.Parse(ctx, req.ParseMethod, data)
+	if err != nil {
+		return nil, tr.Sample(), err
+	}
+	return p.results(req, parsed), tr.Sample(), nil
+}
+
+// results assigns IDs to freshly parsed contests, runs transforms on them
+// and returns them as req's results.
+func (p *Processor) results(req models.ProcessRequest, parsed *models.Results) *models.Results {
+	contests := parsed.Contests
+
+	assignContestIDs(contests)
+	for _, t := range p.transforms {
+		contests = t(req, contests)
+	}
+	// Outcomes are computed last so they see the final candidates and any
+	// thresholds set by transforms.
+	contests = measures.Apply(req, contests)
+
+	results := &models.Results{
+		County:       req.CountyName,
+		Election:     req.Election,
+		Jurisdiction: req.Jurisdiction,
+		ContentType:  req.ContentType,
+		Source:       req.FileLink,
+		SourceLevel:  req.SourceLevel,
+		ParsedAt:     p.clock.Now().UTC(),
+		License:      req.License,
+		Turnout:      parsed.Turnout,
+		Contests:     contests,
+	}
+	if results.License == nil {
+		results.License = p.license
+	}
+	if results.SourceLevel == models.SourceLevelCounty {
+		results.SourceLevel = ""
+	}
+	results.Hash = models.SnapshotHash(results)
+	return results
+}
+
+// Replicate saves a snapshot another instance published and notifies hooks,
+// so this instance serves and streams it as if it had parsed it. Hooks that
+// forward snapshots elsewhere must recognise replicated ones, by a marker in
+// ctx, lest they echo them back. A snapshot already held, or older than the
+// county's current one, is ignored.
+func (p *Processor) Replicate(ctx context.Context, results *models.Results) {
+	if prev, err := p.store.ElectionResults(results.Election, results.County); err == nil {
+		if prev.Hash == results.Hash || prev.ParsedAt.After(results.ParsedAt) {
+			return
+		}
+	}
+	if err := p.store.SaveResults(results); err != nil {
+		p.logger.ErrorContext(ctx, "failed to save replicated snapshot", "county", results.County, "error", err)
+		return
+	}
+	p.runHooks(ctx, results)
+}
+
+func (p *Processor) alert(ctx context.Context, a models.Alert) {
+	if p.alerter != nil {
+		a.RaisedAt = p.clock.Now().UTC()
+		p.alerter.Raise(ctx, a)
+	}
+}
+
+// runHooks notifies snapshot hooks without tying them to the request's
+// lifetime, so a client disconnecting doesn't cancel them.
+func (p *Processor) runHooks(ctx context.Context, results *models.Results) {
+	ctx = context.WithoutCancel(ctx)
+	for _, h := range p.hooks {
+		go h(ctx, results)
+	}
+}
+
+// assignContestIDs gives every contest a stable, URL-safe ID derived from its
+// title, suffixing duplicates so IDs stay unique within a county.
+func assignContestIDs(contests []models.Contest) {
+	seen := make(map[string]int, len(contests))
+	for i := range contests {
+		id := models.Slug(contests[i].Title)
+		if id == "" {
+			id = "contest"
+		}
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			id = fmt.Sprintf("%s-%d", id, n)
+		}
+		contests[i].ID = id
+	}
+}
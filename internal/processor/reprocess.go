@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/many221/era_api_v1/internal/fetcher"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// ErrNoArchive is returned by Reprocess when the deployment doesn't archive
+// sources.
+var ErrNoArchive = errors.New("sources aren't archived")
+
+// Reprocess parses the archived source of one of req's county's snapshots
+// again, the one snapshot names as archive.Find takes it, and publishes the
+// results as Process would, without fetching anything, so a parser fixed
+// mid-count corrects what was published from the same file. req names the
+// county and election and may set DryRun; the rest comes from the county's
+// current registration, so a corrected parser config is picked up too, or
+// for a county that isn't registered from its published results and the
+// method the source was archived under.
+func (p *Processor) Reprocess(ctx context.Context, req models.ProcessRequest, snapshot string, progress ProgressFunc) (*models.ProcessResponse, error) {
+	if p.archive == nil {
+		return nil, ErrNoArchive
+	}
+	if snapshot == "" {
+		snapshot = "latest"
+	}
+	election, err := p.store.Election(req.Election)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownElection, req.Election)
+	}
+	art, err := p.archive.Find(ctx, req.CountyName, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", req.CountyName, snapshot, err)
+	}
+	data, err := p.archive.Read(ctx, art)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", req.CountyName, snapshot, err)
+	}
+
+	base := models.ProcessRequest{Election: election.ID}
+	if c, err := p.store.ElectionCounty(election.ID, req.CountyName); err == nil {
+		base = c.ProcessRequest()
+		base.Election = election.ID
+		base.Browser = nil
+	} else if prev, err := p.store.ElectionResults(election.ID, req.CountyName); err == nil {
+		base.CountyName = prev.County
+		base.ContentType = prev.ContentType
+		base.SourceLevel = prev.SourceLevel
+		base.License = prev.License
+		base.Jurisdiction = prev.Jurisdiction
+		base.ParseMethod = art.ParseMethod
+	} else {
+		return nil, fmt.Errorf("%w: %s has no registration or published results in election %s", store.ErrNotFound, req.CountyName, election.ID)
+	}
+	// Results name the file they were parsed from.
+	if art.FileLink != "" {
+		base.FileLink = art.FileLink
+	}
+	base.Debug, base.DryRun = req.Debug, req.DryRun
+
+	p.logger.InfoContext(ctx, "reprocessing archived source", "county", base.CountyName, "snapshot", art.SnapshotHash, "parse_method", base.ParseMethod, "dry_run", base.DryRun)
+	return p.process(ctx, base, &fetcher.Source{Data: data, Name: art.FileLink}, progress)
+}
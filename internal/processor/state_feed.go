@@ -0,0 +1,119 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/parser"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// ProcessState fetches a state's aggregate feed and publishes each county's
+// share of it as that county's results, unless a source that takes
+// precedence has published for the county. Counties are published one at a time, so one that
+// fails or is quarantined doesn't hold back the rest.
+func (p *Processor) ProcessState(ctx context.Context, req models.StateFeedRequest) (*models.StateFeedResponse, error) {
+	start := time.Now()
+	election, err := p.store.Election(req.Election)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownElection, req.Election)
+	}
+	if election.Archived() {
+		return nil, fmt.Errorf("%w: %s", store.ErrElectionArchived, election.ID)
+	}
+	req.Election = election.ID
+
+	fetchStart := p.clock.Now().UTC()
+	src, err := p.fetcher.FetchSource(ctx, req.FileLink)
+	if err != nil {
+		return nil, err
+	}
+	latency := models.Latency{SourceModifiedAt: src.ModifiedAt, FetchStartedAt: fetchStart, FetchedAt: p.clock.Now().UTC()}
+
+	counties, err := parser.ParseState(ctx, req.Format, src.Data)
+	if err != nil {
+		return nil, err
+	}
+	only := make(map[string]bool, len(req.Counties))
+	for _, c := range req.Counties {
+		only[store.CountyKey(c)] = true
+	}
+
+	resp := &models.StateFeedResponse{State: req.State, Election: req.Election, Source: req.FileLink, Counties: []models.StateFeedCounty{}}
+	published := 0
+	for _, parsed := range counties {
+		if len(only) > 0 && !only[store.CountyKey(parsed.County)] {
+			continue
+		}
+		c := p.publishStateCounty(ctx, req, parsed, src.Data, latency)
+		if c.Status == models.StateFeedPublished {
+			published++
+		}
+		resp.Counties = append(resp.Counties, c)
+	}
+
+	p.logger.InfoContext(ctx, "state feed processed",
+		"state", req.State,
+		"source", req.FileLink,
+		"bytes", len(src.Data),
+		"counties", len(resp.Counties),
+		"published", published,
+		"duration", time.Since(start),
+	)
+	return resp, nil
+}
+
+// publishStateCounty publishes one county's share of a state feed.
+func (p *Processor) publishStateCounty(ctx context.Context, feed models.StateFeedRequest, parsed *models.Results, data []byte, latency models.Latency) models.StateFeedCounty {
+	// A registered county keeps its own spelling.
+	name := parsed.County
+	reg, regErr := p.store.ElectionCounty(feed.Election, name)
+	if regErr == nil {
+		name = reg.Name
+	}
+	out := models.StateFeedCounty{County: name}
+	req := feed.ProcessRequest(name)
+	unlock, err := p.counties.lock(ctx, req.Election, name, func(string, int) {})
+	if err != nil {
+		out.Status = models.StateFeedFailed
+		out.Reason = err.Error()
+		return out
+	}
+	defer unlock()
+	if req.MeasureThreshold == "" && regErr == nil {
+		req.MeasureThreshold = reg.MeasureThreshold
+	}
+
+	results := p.results(req, parsed)
+	results.Latency = &latency
+	out.Hash = results.Hash
+	out.Contests = len(results.Contests)
+	p.store.SaveSourceResults(results)
+	if prev, err := p.store.ElectionResults(req.Election, name); err == nil && prev.Hash == results.Hash {
+		out.Status = models.StateFeedUnchanged
+		return out
+	}
+	if prev, ok := p.outranked(results); ok {
+		out.Status = models.StateFeedSkipped
+		out.Reason = fmt.Sprintf("the %s source takes precedence and has published results", prev.Level())
+		return out
+	}
+
+	out.Warnings = p.checkManifest(ctx, req, results)
+	if rec, held := p.checkDrift(ctx, req, results, nil, data); held {
+		out.Status = models.StateFeedQuarantined
+		out.Reason = fmt.Sprintf("quarantined as %s: %s", rec.ID, strings.Join(rec.Reasons, "; "))
+		return out
+	}
+	if _, err := p.publish(ctx, req, results, nil, data); err != nil {
+		p.logger.ErrorContext(ctx, "failed to publish state feed county", "state", feed.State, "county", name, "error", err)
+		out.Status = models.StateFeedFailed
+		out.Reason = err.Error()
+		return out
+	}
+	out.Status = models.StateFeedPublished
+	return out
+}
@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// ErrNoWatch is returned by ProcessWatched for a registration without a
+// watch.
+var ErrNoWatch = errors.New("county source isn't watched")
+
+// linkTarget matches the target of an anchor on a downloads page or a
+// server's directory listing.
+var linkTarget = regexp.MustCompile(`(?is)<a\s[^>]*?\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+
+// ProcessWatched reads c's watched page and processes the newest file
+// linked from it that matches c.Watch.Pattern and hasn't been seen before.
+// Files are ordered by name, reading runs of digits as numbers, so
+// "results_10.zip" follows "results_9.zip". Each file is taken to supersede
+// those before it, so older unseen ones are marked seen without being read;
+// the first read of a page ingests only its newest file. A file that can't
+// be fetched is left unseen to be tried again; one that is read is seen,
+// whether or not it published, so a bad file is quarantined or kept as a
+// parse error once rather than on every refresh. It returns a nil response
+// when there is nothing new.
+func (p *Processor) ProcessWatched(ctx context.Context, c models.CountySource) (*models.ProcessResponse, error) {
+	if c.Watch == nil {
+		return nil, ErrNoWatch
+	}
+	page, err := p.fetch(ctx, c.Name, c.FileLink)
+	if err != nil {
+		return nil, err
+	}
+	files, err := watchedLinks(c.FileLink, page.Data, c.Watch.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	seen := p.store.WatchedFiles(c.Election, c.Name)
+	var fresh []string
+	for _, f := range files {
+		if !seen[f] {
+			fresh = append(fresh, f)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+	newest, older := fresh[len(fresh)-1], fresh[:len(fresh)-1]
+
+	req := c.ProcessRequest()
+	req.FileLink = newest
+	src, err := p.fetch(ctx, c.Name, newest)
+	if err != nil {
+		// Superseded files needn't wait for this one to be fetched.
+		p.store.MarkWatched(c.Election, c.Name, "", older...)
+		return nil, err
+	}
+	p.store.MarkWatched(c.Election, c.Name, newest, older...)
+	p.logger.InfoContext(ctx, "watched file found",
+		"county", c.Name,
+		"page", c.FileLink,
+		"file", newest,
+		"superseded", len(older),
+	)
+	return p.process(ctx, req, src, nil)
+}
+
+// watchedLinks returns the absolute URLs of the files linked from page,
+// fetched from base, whose names match pattern, without repeats and in
+// name order.
+func watchedLinks(base string, page []byte, pattern string) ([]string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("watched page %q: %w", base, err)
+	}
+	pattern = strings.ToLower(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("watch pattern %q: %w", pattern, err)
+	}
+
+	var links []string
+	names := make(map[string]string)
+	for _, m := range linkTarget.FindAllSubmatch(page, -1) {
+		href := html.UnescapeString(strings.TrimSpace(string(m[1]) + string(m[2]) + string(m[3])))
+		ref, err := url.Parse(href)
+		if err != nil || href == "" || strings.HasPrefix(href, "#") {
+			continue
+		}
+		u := b.ResolveReference(ref)
+		u.Fragment = ""
+		name := path.Base(u.Path)
+		if ok, _ := path.Match(pattern, strings.ToLower(name)); !ok {
+			continue
+		}
+		link := u.String()
+		if _, dup := names[link]; !dup {
+			names[link] = name
+			links = append(links, link)
+		}
+	}
+	slices.SortStableFunc(links, func(a, b string) int {
+		return naturalCompare(names[a], names[b])
+	})
+	return links, nil
+}
+
+// naturalCompare orders a and b as strings, except that runs of digits are
+// compared by value.
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		da, db := digitRun(a), digitRun(b)
+		if da > 0 && db > 0 {
+			na := strings.TrimLeft(a[:da], "0")
+			nb := strings.TrimLeft(b[:db], "0")
+			if len(na) != len(nb) {
+				return len(na) - len(nb)
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) - len(b)
+}
+
+func digitRun(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,103 @@
+// Package rcv builds ranked-choice tabulations from the per-round tallies
+// counties publish.
+package rcv
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// ExhaustedLabel is the pseudo-candidate name used by most tabulators for
+// ballots with no continuing choices left.
+const ExhaustedLabel = "exhausted"
+
+// Round is the raw tally of one round: candidate name to votes. Exhausted
+// ballots may be included under ExhaustedLabel or passed separately.
+type Round struct {
+	Number    int
+	Tallies   map[string]int
+	Exhausted int
+}
+
+// Tabulate derives eliminations, transfers and the winner from rounds.
+// A candidate counts as eliminated in the last round they appear in with
+// votes if they are absent from the next. The winner is the candidate with a
+// majority of continuing ballots in the final round.
+func Tabulate(rounds []Round) *models.RCVTabulation {
+	if len(rounds) == 0 {
+		return nil
+	}
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i].Number < rounds[j].Number })
+
+	for i := range rounds {
+		if n, ok := rounds[i].Tallies[ExhaustedLabel]; ok {
+			rounds[i].Exhausted += n
+			delete(rounds[i].Tallies, ExhaustedLabel)
+		}
+	}
+
+	t := &models.RCVTabulation{}
+	for i, r := range rounds {
+		round := models.RCVRound{Round: r.Number, Exhausted: r.Exhausted}
+		if round.Round == 0 {
+			round.Round = i + 1
+		}
+
+		for _, name := range orderedNames(r.Tallies) {
+			tally := models.RCVTally{Candidate: name, Votes: r.Tallies[name], Status: models.RCVContinuing}
+			if i > 0 {
+				tally.Transfer = tally.Votes - rounds[i-1].Tallies[name]
+			}
+			if i+1 < len(rounds) {
+				if next, ok := rounds[i+1].Tallies[name]; !ok || next == 0 {
+					tally.Status = models.RCVEliminated
+					round.Eliminated = append(round.Eliminated, name)
+				}
+			}
+			round.Tallies = append(round.Tallies, tally)
+		}
+		t.Rounds = append(t.Rounds, round)
+	}
+
+	last := &t.Rounds[len(t.Rounds)-1]
+	continuing := 0
+	for _, tally := range last.Tallies {
+		continuing += tally.Votes
+	}
+	t.Threshold = continuing/2 + 1
+	if len(last.Tallies) > 0 && last.Tallies[0].Votes >= t.Threshold {
+		last.Tallies[0].Status = models.RCVElected
+		t.Winner = last.Tallies[0].Candidate
+		t.Final = true
+	}
+	return t
+}
+
+// FirstChoices returns the first-round tallies as candidates, for
+// Contest.Candidates.
+func FirstChoices(t *models.RCVTabulation) []models.Candidate {
+	if t == nil || len(t.Rounds) == 0 {
+		return nil
+	}
+	out := make([]models.Candidate, 0, len(t.Rounds[0].Tallies))
+	for _, tally := range t.Rounds[0].Tallies {
+		out = append(out, models.Candidate{Name: tally.Candidate, Votes: tally.Votes})
+	}
+	return out
+}
+
+// orderedNames sorts candidates by votes, highest first, then by name.
+func orderedNames(tallies map[string]int) []string {
+	names := make([]string, 0, len(tallies))
+	for name := range tallies {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if tallies[names[i]] != tallies[names[j]] {
+			return tallies[names[i]] > tallies[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
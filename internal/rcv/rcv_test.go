@@ -0,0 +1,157 @@
+package rcv
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+func TestTabulate(t *testing.T) {
+	tests := []struct {
+		name          string
+		rounds        []Round
+		wantWinner    string
+		wantThreshold int
+		wantFinal     bool
+		// per round: candidate order, statuses, transfers and eliminations
+		wantOrder      [][]string
+		wantStatus     [][]string
+		wantTransfer   [][]int
+		wantEliminated [][]string
+		wantExhausted  []int
+	}{
+		{
+			name: "winner after an elimination",
+			rounds: []Round{
+				{Number: 1, Tallies: map[string]int{"A": 40, "B": 35, "C": 25}},
+				{Number: 2, Tallies: map[string]int{"A": 50, "B": 45}, Exhausted: 5},
+			},
+			wantWinner:    "A",
+			wantThreshold: 48,
+			wantFinal:     true,
+			wantOrder:     [][]string{{"A", "B", "C"}, {"A", "B"}},
+			wantStatus: [][]string{
+				{models.RCVContinuing, models.RCVContinuing, models.RCVEliminated},
+				{models.RCVElected, models.RCVContinuing},
+			},
+			wantTransfer:   [][]int{{0, 0, 0}, {10, 10}},
+			wantEliminated: [][]string{{"C"}, nil},
+			wantExhausted:  []int{0, 5},
+		},
+		{
+			name: "rounds out of order, exhausted as a candidate",
+			rounds: []Round{
+				{Number: 2, Tallies: map[string]int{"A": 52, "B": 44, ExhaustedLabel: 4}},
+				{Number: 1, Tallies: map[string]int{"A": 40, "B": 35, "C": 25}},
+			},
+			wantWinner:    "A",
+			wantThreshold: 49,
+			wantFinal:     true,
+			wantOrder:     [][]string{{"A", "B", "C"}, {"A", "B"}},
+			wantStatus: [][]string{
+				{models.RCVContinuing, models.RCVContinuing, models.RCVEliminated},
+				{models.RCVElected, models.RCVContinuing},
+			},
+			wantTransfer:   [][]int{{0, 0, 0}, {12, 9}},
+			wantEliminated: [][]string{{"C"}, nil},
+			wantExhausted:  []int{0, 4},
+		},
+		{
+			name: "zero votes counts as eliminated",
+			rounds: []Round{
+				{Number: 1, Tallies: map[string]int{"A": 30, "B": 30, "C": 40}},
+				{Number: 2, Tallies: map[string]int{"A": 0, "B": 55, "C": 45}},
+			},
+			wantWinner:    "B",
+			wantThreshold: 51,
+			wantFinal:     true,
+			wantOrder:     [][]string{{"C", "A", "B"}, {"B", "C", "A"}},
+			wantStatus: [][]string{
+				{models.RCVContinuing, models.RCVEliminated, models.RCVContinuing},
+				{models.RCVElected, models.RCVContinuing, models.RCVContinuing},
+			},
+			wantTransfer:   [][]int{{0, 0, 0}, {25, 5, -30}},
+			wantEliminated: [][]string{{"A"}, nil},
+			wantExhausted:  []int{0, 0},
+		},
+		{
+			name: "no majority yet",
+			rounds: []Round{
+				{Number: 1, Tallies: map[string]int{"A": 40, "B": 40, "C": 20}},
+			},
+			wantThreshold:  51,
+			wantOrder:      [][]string{{"A", "B", "C"}},
+			wantStatus:     [][]string{{models.RCVContinuing, models.RCVContinuing, models.RCVContinuing}},
+			wantTransfer:   [][]int{{0, 0, 0}},
+			wantEliminated: [][]string{nil},
+			wantExhausted:  []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Tabulate(tt.rounds)
+			if got.Winner != tt.wantWinner || got.Threshold != tt.wantThreshold || got.Final != tt.wantFinal {
+				t.Errorf("winner %q, threshold %d, final %v; want %q, %d, %v",
+					got.Winner, got.Threshold, got.Final, tt.wantWinner, tt.wantThreshold, tt.wantFinal)
+			}
+			if len(got.Rounds) != len(tt.wantOrder) {
+				t.Fatalf("%d rounds, want %d", len(got.Rounds), len(tt.wantOrder))
+			}
+			for i, r := range got.Rounds {
+				if r.Round != i+1 {
+					t.Errorf("round %d numbered %d", i+1, r.Round)
+				}
+				var order, status []string
+				var transfer []int
+				for _, tally := range r.Tallies {
+					order = append(order, tally.Candidate)
+					status = append(status, tally.Status)
+					transfer = append(transfer, tally.Transfer)
+				}
+				if !reflect.DeepEqual(order, tt.wantOrder[i]) {
+					t.Errorf("round %d order = %v, want %v", r.Round, order, tt.wantOrder[i])
+				}
+				if !reflect.DeepEqual(status, tt.wantStatus[i]) {
+					t.Errorf("round %d statuses = %v, want %v", r.Round, status, tt.wantStatus[i])
+				}
+				if !reflect.DeepEqual(transfer, tt.wantTransfer[i]) {
+					t.Errorf("round %d transfers = %v, want %v", r.Round, transfer, tt.wantTransfer[i])
+				}
+				if !reflect.DeepEqual(r.Eliminated, tt.wantEliminated[i]) {
+					t.Errorf("round %d eliminated = %v, want %v", r.Round, r.Eliminated, tt.wantEliminated[i])
+				}
+				if r.Exhausted != tt.wantExhausted[i] {
+					t.Errorf("round %d exhausted = %d, want %d", r.Round, r.Exhausted, tt.wantExhausted[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTabulateNumbering(t *testing.T) {
+	got := Tabulate([]Round{
+		{Tallies: map[string]int{"A": 10, "B": 5}},
+		{Tallies: map[string]int{"A": 12}},
+	})
+	if got.Rounds[0].Round != 1 || got.Rounds[1].Round != 2 {
+		t.Errorf("unnumbered rounds numbered %d, %d; want 1, 2", got.Rounds[0].Round, got.Rounds[1].Round)
+	}
+	if Tabulate(nil) != nil {
+		t.Error("Tabulate(nil) is not nil")
+	}
+}
+
+func TestFirstChoices(t *testing.T) {
+	tab := Tabulate([]Round{
+		{Number: 1, Tallies: map[string]int{"B": 35, "A": 40, ExhaustedLabel: 3}},
+		{Number: 2, Tallies: map[string]int{"A": 45, "B": 30}},
+	})
+	want := []models.Candidate{{Name: "A", Votes: 40}, {Name: "B", Votes: 35}}
+	if got := FirstChoices(tab); !reflect.DeepEqual(got, want) {
+		t.Errorf("FirstChoices = %+v, want %+v", got, want)
+	}
+	if FirstChoices(nil) != nil {
+		t.Error("FirstChoices(nil) is not nil")
+	}
+}
@@ -0,0 +1,300 @@
+// Package redis is a minimal client for the parts of Redis the server uses
+// when several instances share state: string keys with expiry, counters and
+// pub/sub. It speaks RESP2 directly over pooled connections, so no client
+// library is needed.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout = 5 * time.Second
+	maxIdle        = 8
+)
+
+// ErrNil is returned for a key that doesn't exist.
+var ErrNil = errors.New("redis: nil reply")
+
+// Error is an error reply from the server.
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// Client is a pool of connections to one Redis server.
+type Client struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+
+	idle chan *conn
+}
+
+// Open returns a client for rawURL, of the form
+// redis://[:password@]host[:port][/db]. Connections are made when first
+// needed.
+func Open(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("parse redis url: scheme must be redis, not %q", u.Scheme)
+	}
+	c := &Client{addr: u.Host, timeout: defaultTimeout, idle: make(chan *conn, maxIdle)}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("parse redis url: invalid database %q", db)
+		}
+	}
+	return c, nil
+}
+
+// Addr returns the server address.
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+// Do sends one command and returns its reply: a string for simple and bulk
+// strings, an int64, a []any for arrays, or nil.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cn.deadline(ctx, c.timeout)
+	reply, err := cn.do(args...)
+	c.put(cn, err)
+	return reply, err
+}
+
+// Get returns the value of key, or ErrNil if it isn't set.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrNil
+	}
+	s, _ := reply.(string)
+	return []byte(s), nil
+}
+
+// Set sets key to value, expiring after ttl if it is positive.
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+// Incr increments the counter at key and returns its new value.
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	reply, err := c.Do(ctx, "INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected INCR reply %T", reply)
+	}
+	return n, nil
+}
+
+// Publish sends msg to the subscribers of channel.
+func (c *Client) Publish(ctx context.Context, channel string, msg []byte) error {
+	_, err := c.Do(ctx, "PUBLISH", channel, string(msg))
+	return err
+}
+
+// Subscribe calls fn with each message published to channel until ctx is
+// done or the connection fails. It holds a connection of its own for as
+// long as it runs; fn runs on the reading goroutine and should be quick.
+func (c *Client) Subscribe(ctx context.Context, channel string, fn func([]byte)) error {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+	stop := context.AfterFunc(ctx, func() { cn.Close() })
+	defer stop()
+
+	cn.deadline(ctx, c.timeout)
+	if err := cn.send("SUBSCRIBE", channel); err != nil {
+		return err
+	}
+	for {
+		// Subscribed connections are idle between messages; only
+		// cancellation ends the wait.
+		cn.SetDeadline(time.Time{})
+		reply, err := cn.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		msg, ok := reply.([]any)
+		if !ok || len(msg) != 3 {
+			continue
+		}
+		if kind, _ := msg[0].(string); kind == "message" {
+			payload, _ := msg[2].(string)
+			fn([]byte(payload))
+		}
+	}
+}
+
+// Close closes idle connections. Connections in use are closed when
+// returned.
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+		return c.dial(ctx)
+	}
+}
+
+// put returns cn to the pool unless the command failed in a way that may
+// have left the connection mid-reply.
+func (c *Client) put(cn *conn, err error) {
+	var reply Error
+	if err != nil && !errors.As(err, &reply) && !errors.Is(err, ErrNil) {
+		cn.Close()
+		return
+	}
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	d := net.Dialer{Timeout: c.timeout}
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis: dial %s: %w", c.addr, err)
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc)}
+	cn.deadline(ctx, c.timeout)
+	if c.password != "" {
+		if _, err := cn.do("AUTH", c.password); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.do("SELECT", strconv.Itoa(c.db)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// conn is one connection speaking RESP2.
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// deadline bounds the next exchange by ctx's deadline, or timeout if
+// sooner.
+func (cn *conn) deadline(ctx context.Context, timeout time.Duration) {
+	d := time.Now().Add(timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(d) {
+		d = dl
+	}
+	cn.SetDeadline(d)
+}
+
+func (cn *conn) do(args ...string) (any, error) {
+	if err := cn.send(args...); err != nil {
+		return nil, err
+	}
+	return cn.read()
+}
+
+func (cn *conn) send(args ...string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	_, err := io.WriteString(cn.Conn, b.String())
+	return err
+}
+
+func (cn *conn) read() (any, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch body := line[1:]; line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, Error(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		out := make([]any, n)
+		for i := range out {
+			if out[i], err = cn.read(); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
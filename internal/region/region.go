@@ -0,0 +1,57 @@
+// Package region identifies which deployment served a response. Behind a
+// geo-balanced deployment, consecutive requests from one client can land in
+// different regions whose stores are at different versions; stamping every
+// response with the region, the instance and the store's version lets
+// clients notice when that happens instead of seeing results move backwards
+// without explanation.
+package region
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Response headers set by Middleware.
+const (
+	HeaderRegion   = "X-ERA-Region"
+	HeaderInstance = "X-ERA-Instance"
+	HeaderVersion  = "X-ERA-Snapshot-Version"
+)
+
+// Identity names the region and instance serving requests.
+type Identity struct {
+	Region   string `json:"region,omitempty"`
+	Instance string `json:"instance"`
+}
+
+// New returns the identity of an instance in region. An empty instance is
+// replaced by a random ID, so every process is told apart even when no ID
+// is configured.
+func New(region, instance string) Identity {
+	if instance == "" {
+		id := make([]byte, 8)
+		rand.Read(id)
+		instance = hex.EncodeToString(id)
+	}
+	return Identity{Region: region, Instance: instance}
+}
+
+// Middleware stamps every response with id and the version of st as the
+// request arrived. Versions count publishes on one instance, so they order
+// responses from the same instance only; compare snapshot hashes and parse
+// times across instances.
+func (id Identity) Middleware(st store.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		if id.Region != "" {
+			h.Set(HeaderRegion, id.Region)
+		}
+		h.Set(HeaderInstance, id.Instance)
+		h.Set(HeaderVersion, strconv.FormatUint(st.Version(), 10))
+		next.ServeHTTP(w, r)
+	})
+}
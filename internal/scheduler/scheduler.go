@@ -0,0 +1,226 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/requestid"
+	"github.com/many221/era_api_v1/internal/store"
+	"github.com/many221/era_api_v1/internal/workerpool"
+)
+
+// tickInterval is how often the scheduler checks for counties that are due.
+const tickInterval = 15 * time.Second
+
+// ErrInFlight is returned by RefreshNow for a county whose refresh is
+// already queued or running.
+var ErrInFlight = errors.New("refresh already in progress")
+
+// ErrNoFileLink is returned by RefreshNow for a county that only emails its
+// results, so has nothing to fetch.
+var ErrNoFileLink = errors.New("county has no fileLink to refresh")
+
+// Scheduler periodically refreshes every registered county, fanning the work
+// out over a worker pool.
+type Scheduler struct {
+	store     store.Store
+	processor *processor.Processor
+	pool      *workerpool.Pool
+	logger    *slog.Logger
+	interval  time.Duration
+	timeout   time.Duration
+	alerter   processor.Alerter
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+	lastRun  map[string]time.Time
+}
+
+// New returns a Scheduler refreshing counties every interval by default, with
+// each refresh bounded by timeout.
+func New(st store.Store, p *processor.Processor, pool *workerpool.Pool, logger *slog.Logger, interval, timeout time.Duration) *Scheduler {
+	return &Scheduler{
+		store:     st,
+		processor: p,
+		pool:      pool,
+		logger:    logger,
+		interval:  interval,
+		timeout:   timeout,
+		inFlight:  make(map[string]bool),
+		lastRun:   make(map[string]time.Time),
+	}
+}
+
+// SetAlerter makes the scheduler raise an alert when a refresh fails.
+// Quarantined snapshots are left to the processor's own alert.
+func (s *Scheduler) SetAlerter(a processor.Alerter) {
+	s.alerter = a
+}
+
+// Run checks for due counties until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("scheduler started", "default_interval", s.interval)
+	for {
+		s.enqueueDue(time.Now())
+		select {
+		case <-ctx.Done():
+			s.logger.Info("scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueueDue submits every due county that isn't already being refreshed.
+func (s *Scheduler) enqueueDue(now time.Time) {
+	for _, c := range s.store.CountySources() {
+		if c.FileLink == "" {
+			continue
+		}
+		key := store.ElectionKey(c.Election, c.Name)
+
+		s.mu.Lock()
+		due := !s.inFlight[key] && now.Sub(s.lastRun[key]) >= c.RefreshInterval(now, s.interval)
+		if due {
+			s.inFlight[key] = true
+		}
+		s.mu.Unlock()
+		if !due {
+			continue
+		}
+
+		if err := s.submit(c); err != nil {
+			if errors.Is(err, workerpool.ErrQueueFull) {
+				s.logger.Warn("refresh deferred, worker queue full", "county", c.Name)
+				continue
+			}
+			s.logger.Error("failed to schedule refresh", "county", c.Name, "error", err)
+		}
+	}
+}
+
+// RefreshNow queues a refresh of county's registration in election, empty
+// for the default one, ahead of its schedule. It returns
+// store.ErrNotFound for unregistered counties, ErrNoFileLink for those fed
+// only by email, ErrInFlight when a refresh is already queued or running,
+// and workerpool.ErrQueueFull when the pool has no room.
+func (s *Scheduler) RefreshNow(election, county string) error {
+	c, err := s.store.ElectionCounty(election, county)
+	if err != nil {
+		return err
+	}
+	if c.FileLink == "" {
+		return ErrNoFileLink
+	}
+	key := store.ElectionKey(c.Election, c.Name)
+
+	s.mu.Lock()
+	if s.inFlight[key] {
+		s.mu.Unlock()
+		return ErrInFlight
+	}
+	s.inFlight[key] = true
+	s.mu.Unlock()
+
+	if err := s.submit(c); err != nil {
+		return err
+	}
+	s.logger.Info("manual refresh queued", "county", c.Name)
+	return nil
+}
+
+// submit queues a refresh of c, which the caller has marked in flight. The
+// mark is cleared if the pool refuses the task.
+func (s *Scheduler) submit(c models.CountySource) error {
+	err := s.pool.Submit(workerpool.Task{
+		Source: SourceKey(c.FileLink),
+		Run:    func(ctx context.Context) { s.refresh(ctx, c) },
+	})
+	if err != nil {
+		s.mu.Lock()
+		delete(s.inFlight, store.ElectionKey(c.Election, c.Name))
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *Scheduler) refresh(ctx context.Context, c models.CountySource) {
+	key := store.ElectionKey(c.Election, c.Name)
+	start := time.Now()
+	defer func() {
+		s.mu.Lock()
+		delete(s.inFlight, key)
+		s.lastRun[key] = start
+		s.mu.Unlock()
+	}()
+
+	// A refresh is traced like a request, its logs sharing an ID.
+	ctx, cancel := context.WithTimeout(requestid.With(ctx, requestid.New()), s.timeout)
+	defer cancel()
+
+	var err error
+	if c.Watch != nil {
+		_, err = s.processor.ProcessWatched(ctx, c)
+	} else {
+		_, err = s.processor.Process(ctx, c.ProcessRequest(), nil)
+	}
+	s.store.RecordFetch(c.Election, c.Name, start.UTC(), err)
+	if err != nil {
+		attrs := []any{"county", c.Name, "error", err}
+		if contact, cerr := s.store.Contact(c.Name); cerr == nil {
+			attrs = append(attrs, "escalate_to", escalation(contact))
+		}
+		s.logger.ErrorContext(ctx, "scheduled refresh failed", attrs...)
+		if s.alerter != nil && !errors.Is(err, processor.ErrQuarantined) {
+			s.alerter.Raise(ctx, models.Alert{
+				Type:     models.AlertFetchFailed,
+				Severity: models.SeverityError,
+				County:   c.Name,
+				Source:   c.FileLink,
+				Summary:  "Scheduled refresh of " + c.Name + " failed",
+				Details:  map[string]string{"error": err.Error()},
+				RaisedAt: start.UTC(),
+			})
+		}
+	}
+}
+
+// escalation summarizes who to call about c's outage for the log: IT staff
+// first, as a broken feed is usually theirs to fix, then the office.
+func escalation(c models.CountyContact) string {
+	var parts []string
+	for _, p := range [][]string{
+		{"IT", c.ITName, c.ITPhone, c.ITEmail},
+		{"office", c.OfficeName, c.OfficePhone, c.OfficeEmail},
+	} {
+		var fields []string
+		for _, f := range p[1:] {
+			if f != "" {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) > 0 {
+			parts = append(parts, p[0]+": "+strings.Join(fields, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// SourceKey groups sources by host, the workerpool.Task Source a fetch of
+// fileLink runs under, so per-source limits apply per county site.
+func SourceKey(fileLink string) string {
+	if u, err := url.Parse(fileLink); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return fileLink
+}
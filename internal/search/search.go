@@ -0,0 +1,134 @@
+// Package search finds contests, candidates and measures in counties'
+// results by approximate title or name, for search boxes.
+package search
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/normalize"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// MinScore is the lowest score of a match returned.
+const MinScore = 0.6
+
+// Search returns the contests, candidates and measures in election's
+// published results matching q, best first and at most limit of them.
+// Counties spelling a contest or candidate the same are one match.
+func Search(st store.Store, election, q string, limit int) *models.SearchResults {
+	snap := st.ElectionSnapshot(election)
+	res := &models.SearchResults{Query: q, Election: election, Matches: []models.SearchMatch{}, Version: snap.Version}
+	var aggregate bool
+	if e, err := st.Election(""); err == nil {
+		aggregate = e.ID == election
+	}
+
+	byKey := make(map[string]int)
+	add := func(m models.SearchMatch, key string, results *models.Results) {
+		i, ok := byKey[key]
+		if !ok {
+			if aggregate {
+				m.Aggregate = "/api/v1/aggregate?contest=" + url.QueryEscape(m.ContestID)
+			}
+			i = len(res.Matches)
+			byKey[key] = i
+			res.Matches = append(res.Matches, m)
+		}
+		// Counties are in key order, so each is added once per match.
+		if n := len(res.Matches[i].Counties); n > 0 && res.Matches[i].Counties[n-1].County == results.County {
+			return
+		}
+		res.Matches[i].Counties = append(res.Matches[i].Counties, models.SearchCounty{
+			County:  results.County,
+			Results: "/api/v1/elections/" + url.PathEscape(election) + "/results/" + store.CountyKey(results.County),
+		})
+	}
+	for _, results := range snap.Results {
+		for _, c := range results.Contests {
+			kind := models.SearchContest
+			if c.Measure != nil {
+				kind = models.SearchMeasure
+			}
+			if s := Score(q, c.Title); s >= MinScore {
+				add(models.SearchMatch{Kind: kind, Name: c.Title, ContestID: c.ID, Contest: c.Title, Score: s}, kind+" "+c.ID, results)
+			}
+			if c.Measure != nil {
+				// A measure's choices aren't worth finding.
+				continue
+			}
+			for _, cand := range c.Candidates {
+				if cand.WriteInAggregate {
+					continue
+				}
+				if s := Score(q, cand.Name); s >= MinScore {
+					m := models.SearchMatch{Kind: models.SearchCandidate, Name: cand.Name, Party: cand.Party, ContestID: c.ID, Contest: c.Title, Score: s}
+					add(m, "candidate "+c.ID+" "+fold(cand.Name), results)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(res.Matches, func(i, j int) bool {
+		a, b := res.Matches[i], res.Matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Counties) != len(b.Counties) {
+			return len(a.Counties) > len(b.Counties)
+		}
+		return a.Name < b.Name
+	})
+	if len(res.Matches) > limit {
+		res.Matches = res.Matches[:limit]
+	}
+	return res
+}
+
+// Score rates how well text matches the query q, from 0 to 1: 1 for all
+// of text, less for q starting one of text's words or found within one,
+// and otherwise, allowing for typos, how alike q's words are to text's or
+// to how they start.
+func Score(q, text string) float64 {
+	q, text = fold(q), fold(text)
+	if q == "" {
+		return 0
+	}
+	switch i := strings.Index(text, q); {
+	case text == q:
+		return 1
+	case i == 0 || (i > 0 && text[i-1] == ' '):
+		return 0.95
+	case i > 0:
+		return 0.85
+	}
+	words := strings.Fields(text)
+	qwords := strings.Fields(q)
+	var total float64
+	for _, qw := range qwords {
+		best := 0.0
+		for _, w := range words {
+			best = max(best, normalize.Similarity(qw, w))
+			if r := []rune(w); len(r) > len([]rune(qw)) {
+				best = max(best, normalize.Similarity(qw, string(r[:len([]rune(qw))])))
+			}
+		}
+		total += best
+	}
+	return math.Round(800*total/float64(len(qwords))) / 1000
+}
+
+// fold lower-cases s and turns its punctuation to spaces, one between
+// each word.
+func fold(s string) string {
+	return strings.Join(strings.Fields(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, s)), " ")
+}
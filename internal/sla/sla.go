@@ -0,0 +1,249 @@
+// Package sla tracks freshness SLAs, promises that a county's published data
+// is never more than so long behind what the county has posted. Data is
+// current as of its last successful fetch, so an SLA is breached once that
+// fetch is older than its limit, and the breach lasts until a fetch
+// succeeds again.
+package sla
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/processor"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// checkInterval is how often SLAs are checked. Breaches that open and close
+// between checks are still recorded, from the gap between fetches.
+const checkInterval = 15 * time.Second
+
+// Monitor records SLA breaches as counties go stale and recover.
+type Monitor struct {
+	store   store.Store
+	logger  *slog.Logger
+	alerter processor.Alerter
+
+	mu    sync.Mutex
+	fresh map[string]time.Time // county key → freshness at the last check
+}
+
+// NewMonitor returns a Monitor checking the SLAs in st against the fetch
+// status of its counties.
+func NewMonitor(st store.Store, logger *slog.Logger) *Monitor {
+	return &Monitor{store: st, logger: logger, fresh: make(map[string]time.Time)}
+}
+
+// SetAlerter makes the monitor raise an alert when a breach opens.
+func (m *Monitor) SetAlerter(a processor.Alerter) {
+	m.alerter = a
+}
+
+// Run checks SLAs until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		m.check(ctx, time.Now().UTC())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check opens breaches for counties that have gone stale and ends those of
+// counties fetched since. Open breaches no longer covered by an SLA, because
+// it or the county was removed, end now.
+func (m *Monitor) check(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	open := make(map[string]models.SLABreach)
+	for _, b := range m.store.SLABreaches("") {
+		if b.EndedAt == nil {
+			open[breachKey(b.SLAID, b.County)] = b
+		}
+	}
+
+	counties := m.store.CountySources()
+	for _, s := range m.store.SLAs() {
+		for _, c := range Covered(s, counties) {
+			fresh := freshAt(c)
+			if fresh.IsZero() {
+				continue
+			}
+			key := breachKey(s.ID, c.Name)
+			b, breached := open[key]
+			delete(open, key)
+			prev, seen := m.fresh[store.CountyKey(c.Name)]
+
+			switch stale := now.Sub(fresh) > s.MaxStaleness(); {
+			case stale && !breached:
+				m.open(ctx, s, c, fresh)
+			case !stale && breached:
+				m.store.EndSLABreach(b.ID, fresh)
+				m.logger.Info("sla breach ended", "sla", s.ID, "county", c.Name, "duration", fresh.Sub(b.StartedAt))
+			case !stale && seen && fresh.Sub(prev) > s.MaxStaleness():
+				// The county went stale and was fetched again between
+				// checks.
+				end := fresh
+				m.add(s, c, prev, &end)
+				m.logger.Info("sla breach recorded after recovery", "sla", s.ID, "county", c.Name, "duration", fresh.Sub(prev.Add(s.MaxStaleness())))
+			}
+		}
+	}
+	for _, b := range open {
+		m.store.EndSLABreach(b.ID, now)
+	}
+
+	clear(m.fresh)
+	for _, c := range counties {
+		m.fresh[store.CountyKey(c.Name)] = freshAt(c)
+	}
+}
+
+func (m *Monitor) open(ctx context.Context, s models.SLA, c models.CountySource, fresh time.Time) {
+	b := m.add(s, c, fresh, nil)
+	m.logger.Warn("sla breached", "sla", s.ID, "tenant", s.Tenant, "county", c.Name, "fresh_at", fresh, "max_staleness", s.MaxStaleness())
+	if m.alerter == nil {
+		return
+	}
+	details := map[string]string{
+		"sla":           s.Name,
+		"max_staleness": s.MaxStaleness().String(),
+		"fresh_at":      fresh.Format(time.RFC3339),
+	}
+	if s.Tenant != "" {
+		details["tenant"] = s.Tenant
+	}
+	m.alerter.Raise(ctx, models.Alert{
+		Type:     models.AlertSLABreached,
+		Severity: models.SeverityError,
+		County:   c.Name,
+		Source:   c.FileLink,
+		Summary:  c.Name + " data is staler than SLA \"" + s.Name + "\" allows",
+		Details:  details,
+		RaisedAt: b.StartedAt,
+	})
+}
+
+func (m *Monitor) add(s models.SLA, c models.CountySource, fresh time.Time, ended *time.Time) models.SLABreach {
+	id, err := models.NewID()
+	if err != nil {
+		m.logger.Error("failed to record sla breach", "sla", s.ID, "county", c.Name, "error", err)
+		return models.SLABreach{}
+	}
+	b := models.SLABreach{
+		ID:        id,
+		SLAID:     s.ID,
+		Tenant:    s.Tenant,
+		County:    c.Name,
+		FreshAt:   fresh,
+		StartedAt: fresh.Add(s.MaxStaleness()),
+		EndedAt:   ended,
+	}
+	m.store.AddSLABreach(b)
+	return b
+}
+
+// Report summarizes how s was kept from since until now. Counties count
+// from when they were registered if that was later.
+func (m *Monitor) Report(s models.SLA, since, now time.Time) models.SLAReport {
+	rep := models.SLAReport{SLA: s, From: since, To: now, Met: true, Counties: []models.SLACountyReport{}}
+	breaches := m.store.SLABreaches(s.ID)
+
+	var total, within time.Duration
+	for _, c := range Covered(s, m.store.CountySources()) {
+		from := since
+		if c.RegisteredAt.After(from) {
+			from = c.RegisteredAt
+		}
+		if !from.Before(now) {
+			continue
+		}
+		cr := models.SLACountyReport{County: c.Name}
+		if fresh := freshAt(c); !fresh.IsZero() {
+			cr.StalenessSeconds = int(now.Sub(fresh).Seconds())
+		}
+
+		var breached time.Duration
+		for _, b := range breaches {
+			if store.CountyKey(b.County) != store.CountyKey(c.Name) {
+				continue
+			}
+			start, end := b.StartedAt, now
+			if b.EndedAt != nil && b.EndedAt.Before(now) {
+				end = *b.EndedAt
+			} else if b.EndedAt == nil && b.StartedAt.Before(now) {
+				cr.Breached = true
+			}
+			if start.Before(from) {
+				start = from
+			}
+			if !end.After(start) {
+				continue
+			}
+			d := end.Sub(start)
+			cr.Breaches++
+			breached += d
+			cr.LongestBreachSeconds = max(cr.LongestBreachSeconds, int(d.Seconds()))
+		}
+		window := now.Sub(from)
+		cr.BreachedSeconds = int(breached.Seconds())
+		cr.CompliancePercent = percent(window-breached, window)
+		if cr.Breaches > 0 {
+			rep.Met = false
+		}
+		total += window
+		within += window - breached
+		rep.Counties = append(rep.Counties, cr)
+	}
+	rep.CompliancePercent = percent(within, total)
+	return rep
+}
+
+// Covered returns the counties of counties that s applies to.
+func Covered(s models.SLA, counties []models.CountySource) []models.CountySource {
+	if len(s.Counties) == 0 {
+		return counties
+	}
+	want := make(map[string]bool, len(s.Counties))
+	for _, name := range s.Counties {
+		want[store.CountyKey(name)] = true
+	}
+	var out []models.CountySource
+	for _, c := range counties {
+		if want[store.CountyKey(c.Name)] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// freshAt returns when c's data was last known current: its last successful
+// fetch, or its registration if it has never been fetched.
+func freshAt(c models.CountySource) time.Time {
+	if c.Status.LastSuccessAt != nil {
+		return *c.Status.LastSuccessAt
+	}
+	return c.RegisteredAt
+}
+
+func breachKey(slaID, county string) string {
+	return slaID + "/" + store.CountyKey(county)
+}
+
+// percent returns part as a percentage of whole to two decimal places, 100
+// when whole is zero.
+func percent(part, whole time.Duration) float64 {
+	if whole <= 0 {
+		return 100
+	}
+	return math.Round(float64(part)/float64(whole)*10000) / 100
+}
@@ -0,0 +1,139 @@
+package snippets
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/formatter"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// Service renders live-blog snippets and keeps the rendered output cached,
+// regenerating it whenever a county in the snippet gets a new snapshot.
+type Service struct {
+	store  store.Store
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	rendered map[string]*models.RenderedSnippet
+}
+
+// NewService returns a Service reading results from st.
+func NewService(st store.Store, logger *slog.Logger) *Service {
+	return &Service{
+		store:    st,
+		logger:   logger,
+		rendered: make(map[string]*models.RenderedSnippet),
+	}
+}
+
+// Rendered returns the current output of the snippet with the given ID,
+// rendering it on first use.
+func (s *Service) Rendered(id string) (*models.RenderedSnippet, error) {
+	s.mu.RLock()
+	r, ok := s.rendered[id]
+	s.mu.RUnlock()
+	if ok {
+		return r, nil
+	}
+	return s.Regenerate(id)
+}
+
+// Regenerate re-renders the snippet with the given ID from stored results.
+func (s *Service) Regenerate(id string) (*models.RenderedSnippet, error) {
+	r, err := s.Render(id, s.store.ElectionResults)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.rendered[id] = r
+	s.mu.Unlock()
+	return r, nil
+}
+
+// Render renders the snippet with the given ID from the results lookup
+// returns, such as those a delayed access tier sees, without caching it.
+func (s *Service) Render(id string, lookup func(election, county string) (*models.Results, error)) (*models.RenderedSnippet, error) {
+	sn, err := s.store.Snippet(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &models.RenderedSnippet{
+		ID:        sn.ID,
+		Name:      sn.Name,
+		Shortcode: fmt.Sprintf(`[era_results snippet="%s"]`, sn.ID),
+		UpdatedAt: time.Now().UTC(),
+		Contests:  make([]models.SnippetContest, 0, len(sn.Contests)),
+	}
+	for _, ref := range sn.Contests {
+		results, err := lookup(ref.Election, ref.County)
+		if err != nil {
+			continue
+		}
+		contest, ok := results.ContestByID(ref.ContestID)
+		if !ok {
+			continue
+		}
+		r.Contests = append(r.Contests, snippetContest(results, contest))
+	}
+
+	if r.HTML, err = formatter.AMPSnippet(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Forget drops the cached output of a deleted snippet.
+func (s *Service) Forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rendered, id)
+}
+
+// OnSnapshot regenerates every snippet that includes a contest from the
+// county and election in results. Register it with Processor.OnSnapshot.
+func (s *Service) OnSnapshot(_ context.Context, results *models.Results) {
+	county := store.CountyKey(results.County)
+	for _, sn := range s.store.Snippets() {
+		for _, ref := range sn.Contests {
+			if ref.Election != results.Election || store.CountyKey(ref.County) != county {
+				continue
+			}
+			if _, err := s.Regenerate(sn.ID); err != nil {
+				s.logger.Error("snippet regeneration failed", "snippet_id", sn.ID, "error", err)
+			}
+			break
+		}
+	}
+}
+
+func snippetContest(results *models.Results, c *models.Contest) models.SnippetContest {
+	total := c.TotalVotes()
+	out := models.SnippetContest{
+		County:     results.County,
+		ContestID:  c.ID,
+		Title:      c.Title,
+		AsOf:       results.ParsedAt,
+		License:    results.License,
+		Candidates: make([]models.SnippetCandidate, 0, len(c.Candidates)),
+	}
+	for _, cand := range c.Candidates {
+		pct := 0.0
+		if total > 0 {
+			pct = float64(cand.Votes) * 100 / float64(total)
+		}
+		out.Candidates = append(out.Candidates, models.SnippetCandidate{
+			Name:    cand.Name,
+			Votes:   cand.Votes,
+			Percent: pct,
+			WriteIn: cand.WriteIn,
+		})
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// maxAlerts bounds the alert history kept.
+const maxAlerts = 500
+
+func runbookKey(alertType, county string) string {
+	return alertType + "/" + CountyKey(county)
+}
+
+// SaveRunbook stores rb, replacing the runbook for the same alert type and
+// county.
+func (s *Memory) SaveRunbook(rb models.Runbook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runbooks[runbookKey(rb.AlertType, rb.County)] = rb
+}
+
+// DeleteRunbook removes the runbook for alertType and county, which is
+// empty for the one applying to every source.
+func (s *Memory) DeleteRunbook(alertType, county string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := runbookKey(alertType, county)
+	if _, ok := s.runbooks[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.runbooks, key)
+	return nil
+}
+
+// Runbooks returns every runbook ordered by alert type, the ones applying
+// to every source first.
+func (s *Memory) Runbooks() []models.Runbook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.Runbook, 0, len(s.runbooks))
+	for _, rb := range s.runbooks {
+		out = append(out, rb)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AlertType != out[j].AlertType {
+			return out[i].AlertType < out[j].AlertType
+		}
+		return out[i].County < out[j].County
+	})
+	return out
+}
+
+// RunbooksFor returns the runbooks for an alert of alertType about county:
+// the county's own first, then the one for every source.
+func (s *Memory) RunbooksFor(alertType, county string) []models.Runbook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []models.Runbook
+	if county != "" {
+		if rb, ok := s.runbooks[runbookKey(alertType, county)]; ok {
+			out = append(out, rb)
+		}
+	}
+	if rb, ok := s.runbooks[runbookKey(alertType, "")]; ok {
+		out = append(out, rb)
+	}
+	return out
+}
+
+// AddAlert appends a raised alert to the history, dropping the oldest past
+// maxAlerts.
+func (s *Memory) AddAlert(a models.Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.alerts = append(s.alerts, a)
+	if n := len(s.alerts) - maxAlerts; n > 0 {
+		s.alerts = append([]models.Alert(nil), s.alerts[n:]...)
+	}
+}
+
+// Alerts returns raised alerts newest first, only those about county when
+// it is set.
+func (s *Memory) Alerts(county string) []models.Alert {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []models.Alert{}
+	for i := len(s.alerts) - 1; i >= 0; i-- {
+		if county == "" || CountyKey(s.alerts[i].County) == CountyKey(county) {
+			out = append(out, s.alerts[i])
+		}
+	}
+	return out
+}
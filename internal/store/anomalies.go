@@ -0,0 +1,31 @@
+package store
+
+import "github.com/many221/era_api_v1/internal/models"
+
+// maxAnomalies bounds the anomaly history; the oldest are dropped first.
+const maxAnomalies = 1000
+
+// AddAnomalies appends flagged anomalies to the history.
+func (s *Memory) AddAnomalies(found []models.Anomaly) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.anomalies = append(s.anomalies, found...)
+	if n := len(s.anomalies) - maxAnomalies; n > 0 {
+		s.anomalies = append([]models.Anomaly(nil), s.anomalies[n:]...)
+	}
+}
+
+// Anomalies returns the recorded anomalies, newest first, of the given
+// county or, if county is empty, of every county.
+func (s *Memory) Anomalies(county string) []models.Anomaly {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []models.Anomaly{}
+	for i := len(s.anomalies) - 1; i >= 0; i-- {
+		a := s.anomalies[i]
+		if county == "" || CountyKey(a.County) == CountyKey(county) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
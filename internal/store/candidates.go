@@ -0,0 +1,51 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SaveCandidate adds or replaces a canonical candidate in the registry.
+func (s *Memory) SaveCandidate(c models.CanonicalCandidate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.candidates[c.ID] = c
+}
+
+// Candidate returns the canonical candidate with the given ID.
+func (s *Memory) Candidate(id string) (models.CanonicalCandidate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.candidates[id]
+	if !ok {
+		return models.CanonicalCandidate{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// Candidates returns the whole registry ordered by name.
+func (s *Memory) Candidates() []models.CanonicalCandidate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.CanonicalCandidate, 0, len(s.candidates))
+	for _, c := range s.candidates {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// DeleteCandidate removes a canonical candidate from the registry.
+func (s *Memory) DeleteCandidate(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.candidates[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.candidates, id)
+	return nil
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// ErrCertified is returned when publishing for, or certifying again, a
+// county whose results are certified.
+var ErrCertified = errors.New("county results certified")
+
+// ErrSnapshotChanged is returned when certifying a snapshot that is no
+// longer the county's live one.
+var ErrSnapshotChanged = errors.New("county results changed")
+
+// Certify records c, freezing the county's live results in c's election
+// as they are: nothing more is published for it, and its registration is
+// removed, as there is nothing left to refresh. It fails with ErrNotFound
+// if the county has no results, and with ErrSnapshotChanged if its live snapshot
+// isn't c's, as it would be had a run published since c was prepared.
+func (s *Memory) Certify(c models.Certification) (models.Certification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.Election = s.electionID(c.Election)
+	if s.archivedLocked(c.Election) {
+		return models.Certification{}, ErrElectionArchived
+	}
+	key := ElectionKey(c.Election, c.County)
+	if _, ok := s.certifications[key]; ok {
+		return models.Certification{}, ErrCertified
+	}
+	r, ok := s.projectionForLocked(c.Election).results[CountyKey(c.County)]
+	if !ok {
+		return models.Certification{}, ErrNotFound
+	}
+	if r.Hash != c.SnapshotHash {
+		return models.Certification{}, ErrSnapshotChanged
+	}
+	c.County = r.County
+	ev := &models.Event{Type: models.EventCountyCertified, At: c.CertifiedAt, County: c.County, Election: c.Election, Hash: c.SnapshotHash, ParsedAt: r.ParsedAt, Certification: &c}
+	if err := s.log.Append(ev); err != nil {
+		return models.Certification{}, err
+	}
+	s.applyLocked(*ev)
+	delete(s.counties, key)
+	delete(s.watched, key)
+	s.version++
+	return c, nil
+}
+
+// Certification returns the certification of county in election; an empty
+// election is the default one.
+func (s *Memory) Certification(election, county string) (models.Certification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.certifications[ElectionKey(s.electionID(election), county)]
+	if !ok {
+		return models.Certification{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// Certifications returns the certified counties of election, by county.
+func (s *Memory) Certifications(election string) []models.Certification {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id := s.electionID(election)
+	out := []models.Certification{}
+	for _, c := range s.certifications {
+		if c.Election == id {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].County < out[j].County })
+	return out
+}
+
+// certifiedLocked reports whether county's results in election are
+// certified.
+func (s *Memory) certifiedLocked(election, county string) bool {
+	_, ok := s.certifications[ElectionKey(s.electionID(election), county)]
+	return ok
+}
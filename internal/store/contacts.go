@@ -0,0 +1,52 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SaveContact stores c as the contact for its county, replacing any before.
+func (s *Memory) SaveContact(c models.CountyContact) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contacts[CountyKey(c.County)] = c
+}
+
+// Contact returns the contact for county.
+func (s *Memory) Contact(county string) (models.CountyContact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.contacts[CountyKey(county)]
+	if !ok {
+		return models.CountyContact{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// Contacts returns every county contact ordered by county.
+func (s *Memory) Contacts() []models.CountyContact {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.CountyContact, 0, len(s.contacts))
+	for _, c := range s.contacts {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].County < out[j].County })
+	return out
+}
+
+// DeleteContact removes the contact for county.
+func (s *Memory) DeleteContact(county string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := CountyKey(county)
+	if _, ok := s.contacts[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.contacts, key)
+	return nil
+}
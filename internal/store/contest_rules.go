@@ -0,0 +1,46 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SaveContestRule adds or replaces a contest mapping rule.
+func (s *Memory) SaveContestRule(r models.ContestRule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contestRules[r.ID] = r
+}
+
+// ContestRules returns the rules for election, highest priority first.
+func (s *Memory) ContestRules(election string) []models.ContestRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.ContestRule, 0)
+	for _, r := range s.contestRules {
+		if r.Election == election {
+			out = append(out, r)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Priority != out[j].Priority {
+			return out[i].Priority > out[j].Priority
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// DeleteContestRule removes the rule with the given ID.
+func (s *Memory) DeleteContestRule(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.contestRules[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.contestRules, id)
+	return nil
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SaveCounty registers c in its election, the default one if it has none,
+// or updates its configuration if already present. Existing fetch status
+// and registration time are kept.
+func (s *Memory) SaveCounty(c models.CountySource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.Election = s.electionID(c.Election)
+	key := ElectionKey(c.Election, c.Name)
+	if old, ok := s.counties[key]; ok {
+		c.Status = old.Status
+		c.RegisteredAt = old.RegisteredAt
+	}
+	s.counties[key] = c
+}
+
+// County returns the registration for county in the default election.
+func (s *Memory) County(county string) (models.CountySource, error) {
+	return s.ElectionCounty("", county)
+}
+
+// ElectionCounty returns the registration for county in election; an
+// empty election is the default one.
+func (s *Memory) ElectionCounty(election, county string) (models.CountySource, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.counties[ElectionKey(s.electionID(election), county)]
+	if !ok {
+		return models.CountySource{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// CountySources returns every registered county of every election ordered
+// by name, then election.
+func (s *Memory) CountySources() []models.CountySource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.CountySource, 0, len(s.counties))
+	for _, c := range s.counties {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Election < out[j].Election
+	})
+	return out
+}
+
+// ElectionCountySources returns the counties registered in election
+// ordered by name; an empty election is the default one.
+func (s *Memory) ElectionCountySources(election string) []models.CountySource {
+	s.mu.RLock()
+	election = s.electionID(election)
+	s.mu.RUnlock()
+
+	out := []models.CountySource{}
+	for _, c := range s.CountySources() {
+		if c.Election == election {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// DeleteCounty removes the registration for county in election; an empty
+// election is the default one. Stored results are kept.
+func (s *Memory) DeleteCounty(election, county string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := ElectionKey(s.electionID(election), county)
+	if _, ok := s.counties[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.counties, key)
+	delete(s.watched, key)
+	return nil
+}
+
+// RecordFetch updates the fetch status of a county registered in election.
+func (s *Memory) RecordFetch(election, county string, at time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := ElectionKey(s.electionID(election), county)
+	c, ok := s.counties[key]
+	if !ok {
+		return
+	}
+	c.Status.LastRunAt = &at
+	if err != nil {
+		c.Status.LastError = err.Error()
+	} else {
+		c.Status.LastSuccessAt = &at
+		c.Status.LastError = ""
+	}
+	s.counties[key] = c
+}
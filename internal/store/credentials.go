@@ -0,0 +1,36 @@
+package store
+
+import "github.com/many221/era_api_v1/internal/models"
+
+// SaveCredentials stores c as the credentials for its county's sources,
+// replacing any before.
+func (s *Memory) SaveCredentials(c models.SourceCredentials) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials[CountyKey(c.County)] = c
+}
+
+// Credentials returns the credentials for county's sources.
+func (s *Memory) Credentials(county string) (models.SourceCredentials, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.credentials[CountyKey(county)]
+	if !ok {
+		return models.SourceCredentials{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// DeleteCredentials removes the credentials for county's sources.
+func (s *Memory) DeleteCredentials(county string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := CountyKey(county)
+	if _, ok := s.credentials[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.credentials, key)
+	return nil
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// dataset is a labeled set of staged snapshots, by ElectionKey.
+type dataset struct {
+	createdAt time.Time
+	updatedAt time.Time
+	results   map[string]*models.Results
+}
+
+func (d *dataset) model(label string) models.Dataset {
+	out := models.Dataset{Label: label, Counties: []models.DatasetCounty{}, CreatedAt: d.createdAt, UpdatedAt: d.updatedAt}
+	for _, r := range d.results {
+		out.Counties = append(out.Counties, models.DatasetCounty{County: r.County, Election: r.Election, SnapshotHash: r.Hash, ParsedAt: r.ParsedAt})
+	}
+	sort.Slice(out.Counties, func(i, j int) bool {
+		if out.Counties[i].County != out.Counties[j].County {
+			return out.Counties[i].County < out.Counties[j].County
+		}
+		return out.Counties[i].Election < out.Counties[j].Election
+	})
+	return out
+}
+
+// StageResults adds r to the dataset labeled label as of at, replacing the
+// county's earlier staged snapshot in r's election. The dataset is created
+// if needed.
+func (s *Memory) StageResults(label string, r *models.Results, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.datasets[label]
+	if !ok {
+		d = &dataset{createdAt: at, results: make(map[string]*models.Results)}
+		s.datasets[label] = d
+	}
+	d.updatedAt = at
+	d.results[ElectionKey(s.electionID(r.Election), r.County)] = r
+}
+
+// Datasets returns every dataset, most recently updated first.
+func (s *Memory) Datasets() []models.Dataset {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.Dataset, 0, len(s.datasets))
+	for label, d := range s.datasets {
+		out = append(out, d.model(label))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	return out
+}
+
+// Dataset returns the dataset labeled label.
+func (s *Memory) Dataset(label string) (models.Dataset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.datasets[label]
+	if !ok {
+		return models.Dataset{}, ErrNotFound
+	}
+	return d.model(label), nil
+}
+
+// StagedResults returns a copy of county's snapshot for election in the
+// dataset labeled label; an empty election is the default one.
+func (s *Memory) StagedResults(label, election, county string) (*models.Results, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.datasets[label]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	r, ok := d.results[ElectionKey(s.electionID(election), county)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyResults(r), nil
+}
+
+// DeleteDataset discards the dataset labeled label, unless any of its
+// snapshots is under a legal hold.
+func (s *Memory) DeleteDataset(label string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.datasets[label]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, r := range d.results {
+		if s.heldLocked(r.Election, r.County) {
+			return ErrLegalHold
+		}
+	}
+	delete(s.datasets, label)
+	return nil
+}
+
+// ActivateDataset makes every snapshot in the dataset labeled label live at
+// once, as of at, and returns copies of them. Readers see either none of
+// them or all of them. The live snapshots they replace become the dataset
+// labeled models.DatasetPrevious, replacing any earlier one, and the
+// activated dataset is consumed. Nothing is activated if any of the
+// snapshots is for an archived election or a certified county.
+func (s *Memory) ActivateDataset(label string, at time.Time) ([]*models.Results, models.DatasetSwitch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.datasets[label]
+	if !ok {
+		return nil, models.DatasetSwitch{}, ErrNotFound
+	}
+	sw := models.DatasetSwitch{Label: label, Counties: []string{}, SwitchedAt: at}
+	displaced := &dataset{createdAt: at, updatedAt: at, results: make(map[string]*models.Results)}
+	keys := make([]string, 0, len(d.results))
+	for key := range d.results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if s.archivedLocked(d.results[key].Election) {
+			return nil, models.DatasetSwitch{}, ErrElectionArchived
+		}
+		if s.certifiedLocked(d.results[key].Election, d.results[key].County) {
+			return nil, models.DatasetSwitch{}, ErrCertified
+		}
+	}
+
+	var activated []*models.Results
+	var published []*models.Event
+	for _, key := range keys {
+		r := d.results[key]
+		if prev, ok := s.projectionForLocked(s.electionID(r.Election)).results[CountyKey(r.County)]; ok {
+			displaced.results[key] = prev
+		} else {
+			sw.Added = append(sw.Added, r.County)
+		}
+		published = append(published, s.publishedEventLocked(r, at))
+		sw.Counties = append(sw.Counties, r.County)
+		activated = append(activated, copyResults(r))
+	}
+	if err := s.log.Append(published...); err != nil {
+		return nil, models.DatasetSwitch{}, err
+	}
+	for _, e := range published {
+		s.applyLocked(*e)
+	}
+	s.version++
+
+	delete(s.datasets, label)
+	// An older set of displaced snapshots would revert counties from a
+	// different switch, so it goes even when this one displaced nothing.
+	delete(s.datasets, models.DatasetPrevious)
+	if len(displaced.results) > 0 {
+		s.datasets[models.DatasetPrevious] = displaced
+		sw.Previous = models.DatasetPrevious
+	}
+	return activated, sw, nil
+}
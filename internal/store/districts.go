@@ -0,0 +1,59 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SaveDistrict adds or replaces d.
+func (s *Memory) SaveDistrict(d models.District) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.districts[d.ID] = d
+	s.version++
+}
+
+// District returns the district with the given ID.
+func (s *Memory) District(id string) (models.District, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.districts[id]
+	if !ok {
+		return models.District{}, ErrNotFound
+	}
+	return d, nil
+}
+
+// Districts returns every district, by type and then name.
+func (s *Memory) Districts() []models.District {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.District, 0, len(s.districts))
+	for _, d := range s.districts {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Type != out[j].Type {
+			return out[i].Type < out[j].Type
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// DeleteDistrict removes the district with the given ID.
+func (s *Memory) DeleteDistrict(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.districts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.districts, id)
+	s.version++
+	return nil
+}
@@ -0,0 +1,217 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// DefaultElection is the ID of the default election until
+// SetDefaultElection names another.
+const DefaultElection = "default"
+
+// ErrElectionInUse is returned when deleting the default election or one
+// with registrations or published results, and when archiving the default
+// election.
+var ErrElectionInUse = errors.New("election in use")
+
+// ErrElectionArchived is returned when publishing for an archived election
+// or archiving one again.
+var ErrElectionArchived = errors.New("election archived")
+
+// SetDefaultElection makes id the election that requests naming none read
+// and write, registering it if needed. It is meant to be called before
+// anything is published; the previous default is dropped if nothing was.
+func (s *Memory) SetDefaultElection(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old := s.election; old != id && len(s.projectionForLocked(old).results) == 0 {
+		delete(s.elections, old)
+		delete(s.projections, old)
+	}
+	s.election = id
+	s.projection = s.projectionForLocked(id)
+	if _, ok := s.elections[id]; !ok {
+		s.elections[id] = models.Election{ID: id, CreatedAt: time.Now().UTC()}
+	}
+}
+
+// DefaultElection returns the ID of the default election.
+func (s *Memory) DefaultElection() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.election
+}
+
+// SaveElection adds e, or replaces the election with its ID. The time it
+// was first created is kept.
+func (s *Memory) SaveElection(e models.Election) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.elections[e.ID]; ok {
+		e.CreatedAt = old.CreatedAt
+		e.ArchivedAt = old.ArchivedAt
+	}
+	e.Default = false
+	s.elections[e.ID] = e
+}
+
+// Election returns the election id; an empty id is the default election.
+func (s *Memory) Election(id string) (models.Election, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id = s.electionID(id)
+	e, ok := s.elections[id]
+	if !ok {
+		return models.Election{}, ErrNotFound
+	}
+	e.Default = id == s.election
+	return e, nil
+}
+
+// Elections returns every election, the most recent date first and then
+// by ID. Elections without a date come last.
+func (s *Memory) Elections() []models.Election {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.Election, 0, len(s.elections))
+	for id, e := range s.elections {
+		e.Default = id == s.election
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Date != out[j].Date {
+			return out[i].Date > out[j].Date
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// DeleteElection removes the election id. The default election, and any
+// with registered counties or published results, can't be deleted, nor can
+// one under a legal hold.
+func (s *Memory) DeleteElection(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.elections[id]; !ok {
+		return ErrNotFound
+	}
+	if s.heldLocked(id, "") {
+		return ErrLegalHold
+	}
+	if id == s.election {
+		return ErrElectionInUse
+	}
+	if p, ok := s.projections[id]; ok && len(p.results) > 0 {
+		return ErrElectionInUse
+	}
+	for _, c := range s.counties {
+		if c.Election == id {
+			return ErrElectionInUse
+		}
+	}
+	delete(s.elections, id)
+	delete(s.projections, id)
+	for k, r := range s.sourceResults {
+		if r.Election == id {
+			delete(s.sourceResults, k)
+		}
+	}
+	return nil
+}
+
+// ArchiveElection makes the election id read-only as of at, recording it
+// in the event log. Its results stay as they were and nothing more can be
+// published for it; its county registrations are removed, as there is
+// nothing left to refresh. The default election can't be archived.
+func (s *Memory) ArchiveElection(id string, at time.Time) (models.Election, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.elections[id]
+	switch {
+	case !ok:
+		return models.Election{}, ErrNotFound
+	case id == s.election:
+		return models.Election{}, ErrElectionInUse
+	case e.Archived():
+		return models.Election{}, ErrElectionArchived
+	}
+	ev := &models.Event{Type: models.EventElectionArchived, At: at, Election: id}
+	if err := s.log.Append(ev); err != nil {
+		return models.Election{}, err
+	}
+	s.applyLocked(*ev)
+	for key, c := range s.counties {
+		if c.Election == id {
+			delete(s.counties, key)
+			delete(s.watched, key)
+		}
+	}
+	s.version++
+	return s.elections[id], nil
+}
+
+// ElectionSummary returns the counties with results in election, sorted;
+// an empty election is the default one.
+func (s *Memory) ElectionSummary(election string) (models.ElectionResults, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id := s.electionID(election)
+	e, ok := s.elections[id]
+	if !ok {
+		return models.ElectionResults{}, ErrNotFound
+	}
+	e.Default = id == s.election
+	out := models.ElectionResults{Election: e, Counties: []models.ElectionCounty{}}
+	if p := s.projections[id]; p != nil {
+		for _, r := range p.results {
+			out.Counties = append(out.Counties, models.ElectionCounty{County: r.County, SnapshotHash: r.Hash, ParsedAt: r.ParsedAt, Contests: len(r.Contests)})
+		}
+	}
+	sort.Slice(out.Counties, func(i, j int) bool { return out.Counties[i].County < out.Counties[j].County })
+	return out, nil
+}
+
+// archivedLocked reports whether election is archived.
+func (s *Memory) archivedLocked(election string) bool {
+	return s.elections[s.electionID(election)].Archived()
+}
+
+// ElectionKey returns the key a county's registration in election is
+// stored under.
+func ElectionKey(election, county string) string {
+	return election + "/" + CountyKey(county)
+}
+
+// electionID resolves an empty election ID to the default election.
+func (s *Memory) electionID(id string) string {
+	if id == "" {
+		return s.election
+	}
+	return id
+}
+
+// projectionForLocked returns the read models of election, creating them
+// if it has none; projectionOf returns nil instead.
+func (s *Memory) projectionForLocked(election string) *projection {
+	p, ok := s.projections[election]
+	if !ok {
+		p = newProjection()
+		s.projections[election] = p
+	}
+	return p
+}
+
+func (s *Memory) projectionOf(election string) *projection {
+	return s.projections[s.electionID(election)]
+}
@@ -0,0 +1,23 @@
+package store
+
+import "github.com/many221/era_api_v1/internal/models"
+
+// SetForecasts replaces the win-probability estimates stored for county.
+func (s *Memory) SetForecasts(county string, forecasts []models.ContestForecast) {
+	byContest := make(map[string]models.ContestForecast, len(forecasts))
+	for _, f := range forecasts {
+		byContest[f.ContestID] = f
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forecasts[CountyKey(county)] = byContest
+	s.version++
+}
+
+// Forecasts returns the latest estimates for county keyed by contest ID.
+func (s *Memory) Forecasts(county string) map[string]models.ContestForecast {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.forecasts[CountyKey(county)]
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// HistoryWindow is how far back superseded snapshots are kept, so callers
+// whose access is delayed can be served what was live a while ago.
+const HistoryWindow = time.Hour
+
+// appendHistory adds r, a snapshot with new content, to the county's
+// history and drops what has fallen out of the window. The newest snapshot
+// older than the window is kept, as it was still live at its start.
+func (p *projection) appendHistory(key string, r *models.Results) {
+	history := append(p.history[key], r)
+	cutoff := r.ParsedAt.Add(-HistoryWindow)
+	drop := 0
+	for drop+1 < len(history) && !history[drop+1].ParsedAt.After(cutoff) {
+		drop++
+	}
+	p.history[key] = history[drop:]
+}
+
+// ElectionResultsAt returns a copy of the county's results in election as
+// they were at: the newest snapshot parsed by then, looking back as far as
+// HistoryWindow. An empty election is the default one.
+func (s *Memory) ElectionResultsAt(election, county string, at time.Time) (*models.Results, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p := s.projectionOf(election)
+	if p == nil {
+		return nil, ErrNotFound
+	}
+	history := p.history[CountyKey(county)]
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].ParsedAt.After(at) {
+			return copyResults(history[i]), nil
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// ErrJurisdictionParent is returned when a jurisdiction's parent doesn't
+// exist or isn't above it in the tree, or a change would put one of its
+// children at or above its own level.
+var ErrJurisdictionParent = errors.New("parent must be an existing jurisdiction above it")
+
+// ErrJurisdictionInUse is returned when deleting a jurisdiction that has
+// children or contests attached.
+var ErrJurisdictionInUse = errors.New("jurisdiction has children or contests")
+
+// SaveJurisdiction adds or replaces j in the tree.
+func (s *Memory) SaveJurisdiction(j models.Jurisdiction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	level := models.JurisdictionLevel(j.Type)
+	if j.Parent != "" {
+		parent, ok := s.jurisdictions[j.Parent]
+		if !ok || models.JurisdictionLevel(parent.Type) >= level {
+			return ErrJurisdictionParent
+		}
+	}
+	for _, child := range s.jurisdictions {
+		if child.Parent == j.ID && models.JurisdictionLevel(child.Type) <= level {
+			return ErrJurisdictionParent
+		}
+	}
+	s.jurisdictions[j.ID] = j
+	return nil
+}
+
+// Jurisdiction returns the jurisdiction with the given ID.
+func (s *Memory) Jurisdiction(id string) (models.Jurisdiction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, ok := s.jurisdictions[id]
+	if !ok {
+		return models.Jurisdiction{}, ErrNotFound
+	}
+	return j, nil
+}
+
+// Jurisdictions returns every jurisdiction, top levels first and then by
+// name.
+func (s *Memory) Jurisdictions() []models.Jurisdiction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.Jurisdiction, 0, len(s.jurisdictions))
+	for _, j := range s.jurisdictions {
+		out = append(out, j)
+	}
+	sortJurisdictions(out)
+	return out
+}
+
+// JurisdictionView returns jurisdiction id with its ancestors, its
+// children and the contests attached to it in election.
+func (s *Memory) JurisdictionView(election, id string) (models.JurisdictionView, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, ok := s.jurisdictions[id]
+	if !ok {
+		return models.JurisdictionView{}, ErrNotFound
+	}
+	v := models.JurisdictionView{Jurisdiction: j, Path: []models.Jurisdiction{}, Children: []models.Jurisdiction{}, Contests: []string{}}
+	for p, ok := s.jurisdictions[j.Parent]; ok; p, ok = s.jurisdictions[p.Parent] {
+		v.Path = append([]models.Jurisdiction{p}, v.Path...)
+	}
+	for _, child := range s.jurisdictions {
+		if child.Parent == id {
+			v.Children = append(v.Children, child)
+		}
+	}
+	sortJurisdictions(v.Children)
+	prefix := s.electionID(election) + "/"
+	for key, at := range s.contestJurisdictions {
+		if contest, ok := strings.CutPrefix(key, prefix); ok && at == id {
+			v.Contests = append(v.Contests, contest)
+		}
+	}
+	sort.Strings(v.Contests)
+	return v, nil
+}
+
+// JurisdictionCounty returns the county jurisdiction id is, or is in.
+func (s *Memory) JurisdictionCounty(id string) (models.Jurisdiction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for j, ok := s.jurisdictions[id]; ok; j, ok = s.jurisdictions[j.Parent] {
+		if j.Type == models.JurisdictionCounty {
+			return j, nil
+		}
+	}
+	return models.Jurisdiction{}, ErrNotFound
+}
+
+// DeleteJurisdiction removes a jurisdiction without children or contests.
+func (s *Memory) DeleteJurisdiction(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jurisdictions[id]; !ok {
+		return ErrNotFound
+	}
+	for _, j := range s.jurisdictions {
+		if j.Parent == id {
+			return ErrJurisdictionInUse
+		}
+	}
+	for _, at := range s.contestJurisdictions {
+		if at == id {
+			return ErrJurisdictionInUse
+		}
+	}
+	delete(s.jurisdictions, id)
+	return nil
+}
+
+// AttachContest places contest in election at jurisdiction, moving it from
+// wherever it was attached before.
+func (s *Memory) AttachContest(election, contest, jurisdiction string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jurisdictions[jurisdiction]; !ok {
+		return ErrNotFound
+	}
+	s.contestJurisdictions[s.electionID(election)+"/"+contest] = jurisdiction
+	s.version++
+	return nil
+}
+
+// DetachContest removes contest in election from jurisdiction.
+func (s *Memory) DetachContest(election, contest, jurisdiction string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := s.electionID(election) + "/" + contest
+	if s.contestJurisdictions[key] != jurisdiction {
+		return ErrNotFound
+	}
+	delete(s.contestJurisdictions, key)
+	s.version++
+	return nil
+}
+
+// ContestJurisdictions returns the jurisdiction each attached contest of
+// election is held in, keyed by contest ID.
+func (s *Memory) ContestJurisdictions(election string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefix := s.electionID(election) + "/"
+	out := make(map[string]string)
+	for key, at := range s.contestJurisdictions {
+		if contest, ok := strings.CutPrefix(key, prefix); ok {
+			out[contest] = at
+		}
+	}
+	return out
+}
+
+func sortJurisdictions(list []models.Jurisdiction) {
+	sort.Slice(list, func(i, j int) bool {
+		li, lj := models.JurisdictionLevel(list[i].Type), models.JurisdictionLevel(list[j].Type)
+		if li != lj {
+			return li < lj
+		}
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].ID < list[j].ID
+	})
+}
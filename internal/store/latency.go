@@ -0,0 +1,27 @@
+package store
+
+import "github.com/many221/era_api_v1/internal/models"
+
+// maxLatencySamples bounds the latency history kept per county.
+const maxLatencySamples = 500
+
+func (p *projection) appendLatency(key string, sample models.LatencySample) {
+	history := append(p.latency[key], sample)
+	if len(history) > maxLatencySamples {
+		history = history[len(history)-maxLatencySamples:]
+	}
+	p.latency[key] = history
+}
+
+// LatencyHistory returns every county's latency samples, oldest first, keyed
+// by county key.
+func (s *Memory) LatencyHistory() map[string][]models.LatencySample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string][]models.LatencySample, len(s.latency))
+	for k, v := range s.latency {
+		out[k] = append([]models.LatencySample(nil), v...)
+	}
+	return out
+}
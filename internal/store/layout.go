@@ -0,0 +1,90 @@
+package store
+
+import "github.com/many221/era_api_v1/internal/models"
+
+const (
+	// maxLayoutChanges bounds the layout change history; the oldest changes
+	// are dropped first.
+	maxLayoutChanges = 1000
+
+	// maxParserConfigs is how many parser config revisions are kept per
+	// county.
+	maxParserConfigs = 5
+)
+
+// SwapLayout records layout as the county's latest source layout and
+// returns the one it replaces, or nil on the county's first fetch.
+func (s *Memory) SwapLayout(county string, layout *models.SourceLayout) *models.SourceLayout {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.layouts[CountyKey(county)]
+	s.layouts[CountyKey(county)] = layout
+	return prev
+}
+
+// Layout returns the layout of county's last fetched source, or nil.
+func (s *Memory) Layout(county string) *models.SourceLayout {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.layouts[CountyKey(county)]
+}
+
+// AddLayoutChange appends c to the layout change history.
+func (s *Memory) AddLayoutChange(c models.LayoutChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.layoutChanges = append(s.layoutChanges, c)
+	if n := len(s.layoutChanges) - maxLayoutChanges; n > 0 {
+		s.layoutChanges = append([]models.LayoutChange(nil), s.layoutChanges[n:]...)
+	}
+}
+
+// LayoutChanges returns the recorded layout changes, newest first, of the
+// given county or, if county is empty, of every county.
+func (s *Memory) LayoutChanges(county string) []models.LayoutChange {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []models.LayoutChange{}
+	for i := len(s.layoutChanges) - 1; i >= 0; i-- {
+		c := s.layoutChanges[i]
+		if county == "" || CountyKey(c.County) == CountyKey(county) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// RecordParserConfig notes that c published a snapshot of the county. A
+// config differing from the county's latest becomes a new revision; older
+// revisions beyond maxParserConfigs are forgotten.
+func (s *Memory) RecordParserConfig(county string, c models.ParserConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := CountyKey(county)
+	revs := s.parserConfigs[key]
+	if n := len(revs); n > 0 && revs[n-1].Same(c) {
+		revs[n-1].PublishedAt = c.PublishedAt
+		return
+	}
+	c.Revision = 1
+	if n := len(revs); n > 0 {
+		c.Revision = revs[n-1].Revision + 1
+	}
+	revs = append(revs, c)
+	if len(revs) > maxParserConfigs {
+		revs = revs[len(revs)-maxParserConfigs:]
+	}
+	s.parserConfigs[key] = revs
+}
+
+// ParserConfigs returns the county's parser config revisions, newest first.
+func (s *Memory) ParserConfigs(county string) []models.ParserConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	revs := s.parserConfigs[CountyKey(county)]
+	out := make([]models.ParserConfig, len(revs))
+	for i, c := range revs {
+		out[len(revs)-1-i] = c
+	}
+	return out
+}
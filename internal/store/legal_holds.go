@@ -0,0 +1,94 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// ErrLegalHold is returned when purging data a legal hold preserves.
+var ErrLegalHold = errors.New("under legal hold")
+
+// ErrHoldReleased is returned when releasing a hold that was released
+// already.
+var ErrHoldReleased = errors.New("legal hold already released")
+
+// SaveLegalHold places h, which must already have an ID, in h's election;
+// an empty election is the default one.
+func (s *Memory) SaveLegalHold(h models.LegalHold) models.LegalHold {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h.Election = s.electionID(h.Election)
+	s.legalHolds[h.ID] = h
+	return h
+}
+
+// LegalHold returns the hold with the given ID.
+func (s *Memory) LegalHold(id string) (models.LegalHold, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, ok := s.legalHolds[id]
+	if !ok {
+		return models.LegalHold{}, ErrNotFound
+	}
+	return h, nil
+}
+
+// LegalHolds returns every hold, released or not, most recently placed
+// first.
+func (s *Memory) LegalHolds() []models.LegalHold {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.LegalHold, 0, len(s.legalHolds))
+	for _, h := range s.legalHolds {
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PlacedAt.After(out[j].PlacedAt) })
+	return out
+}
+
+// ReleaseLegalHold lifts the hold with the given ID as of at, on behalf of
+// the API key by.
+func (s *Memory) ReleaseLegalHold(id, by string, at time.Time) (models.LegalHold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.legalHolds[id]
+	if !ok {
+		return models.LegalHold{}, ErrNotFound
+	}
+	if !h.Active() {
+		return models.LegalHold{}, ErrHoldReleased
+	}
+	h.ReleasedBy = by
+	h.ReleasedAt = &at
+	s.legalHolds[id] = h
+	return h, nil
+}
+
+// Held reports whether an active legal hold covers county in election or,
+// if county is empty, any of the election. An empty election is the
+// default one.
+func (s *Memory) Held(election, county string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.heldLocked(election, county)
+}
+
+func (s *Memory) heldLocked(election, county string) bool {
+	id := s.electionID(election)
+	for _, h := range s.legalHolds {
+		if !h.Active() || h.Election != id {
+			continue
+		}
+		if h.County == "" || county == "" || CountyKey(h.County) == CountyKey(county) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package store
+
+import "github.com/many221/era_api_v1/internal/models"
+
+// SaveManifest replaces the manifest of m's election.
+func (s *Memory) SaveManifest(m models.Manifest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.manifests[m.Election] = m
+}
+
+// Manifest returns the manifest of election.
+func (s *Memory) Manifest(election string) (models.Manifest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.manifests[election]
+	if !ok {
+		return models.Manifest{}, ErrNotFound
+	}
+	return m, nil
+}
+
+// DeleteManifest removes the manifest of election.
+func (s *Memory) DeleteManifest(election string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.manifests[election]; !ok {
+		return ErrNotFound
+	}
+	delete(s.manifests, election)
+	return nil
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SaveMeasureLink adds or replaces l in l's election; an empty election is
+// the default one.
+func (s *Memory) SaveMeasureLink(l models.MeasureLink) models.MeasureLink {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l.Election = s.electionID(l.Election)
+	s.measureLinks[l.Election+"/"+l.ID] = l
+	s.version++
+	return l
+}
+
+// MeasureLink returns the link with the given ID in election.
+func (s *Memory) MeasureLink(election, id string) (models.MeasureLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.measureLinks[s.electionID(election)+"/"+id]
+	if !ok {
+		return models.MeasureLink{}, ErrNotFound
+	}
+	return l, nil
+}
+
+// MeasureLinks returns the links of election ordered by ID.
+func (s *Memory) MeasureLinks(election string) []models.MeasureLink {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id := s.electionID(election)
+	out := []models.MeasureLink{}
+	for _, l := range s.measureLinks {
+		if l.Election == id {
+			out = append(out, l)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// DeleteMeasureLink removes the link with the given ID from election.
+func (s *Memory) DeleteMeasureLink(election, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := s.electionID(election) + "/" + id
+	if _, ok := s.measureLinks[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.measureLinks, key)
+	s.version++
+	return nil
+}
@@ -0,0 +1,18 @@
+package store
+
+import "github.com/many221/era_api_v1/internal/models"
+
+// SetOutcomeRules sets when contests are called from their count.
+func (s *Memory) SetOutcomeRules(rules models.OutcomeRules) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outcomeRules = rules
+	s.version++
+}
+
+// OutcomeRules returns when contests are called from their count.
+func (s *Memory) OutcomeRules() models.OutcomeRules {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.outcomeRules
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// AddOverlay stores o, which must already have an ID.
+func (s *Memory) AddOverlay(o models.Overlay) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o.Official = false
+	s.overlays[o.ID] = o
+	s.version++
+}
+
+// DeleteOverlay removes the overlay with the given ID.
+func (s *Memory) DeleteOverlay(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.overlays[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.overlays, id)
+	s.version++
+	return nil
+}
+
+// Overlays returns the overlays attached to a county's contests, keyed by
+// contest ID and ordered by creation time.
+func (s *Memory) Overlays(county string) map[string][]models.Overlay {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := CountyKey(county)
+	out := make(map[string][]models.Overlay)
+	for _, o := range s.overlays {
+		if CountyKey(o.County) == key {
+			out[o.ContestID] = append(out[o.ContestID], o)
+		}
+	}
+	for _, list := range out {
+		sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	}
+	return out
+}
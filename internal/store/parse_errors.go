@@ -0,0 +1,92 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// maxParseErrors bounds the parse errors kept, with their files.
+const maxParseErrors = 200
+
+// SaveParseError stores or replaces a parse error, dropping the least
+// recently seen past maxParseErrors. Records under a legal hold aren't
+// dropped.
+func (s *Memory) SaveParseError(rec models.ParseError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.parseErrors[rec.ID] = rec
+	if len(s.parseErrors) <= maxParseErrors {
+		return
+	}
+	var oldest models.ParseError
+	for _, e := range s.parseErrors {
+		if s.heldLocked(e.Request.Election, e.County) {
+			continue
+		}
+		if oldest.ID == "" || e.LastSeenAt.Before(oldest.LastSeenAt) {
+			oldest = e
+		}
+	}
+	if oldest.ID != "" {
+		delete(s.parseErrors, oldest.ID)
+	}
+}
+
+// ParseError returns the parse error with the given ID.
+func (s *Memory) ParseError(id string) (models.ParseError, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.parseErrors[id]
+	if !ok {
+		return models.ParseError{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+// OpenParseError returns the open parse error for county's file with the
+// given hash, so a file that keeps failing is recorded once.
+func (s *Memory) OpenParseError(county, sourceHash string) (models.ParseError, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := CountyKey(county)
+	for _, rec := range s.parseErrors {
+		if rec.Status == models.ParseErrorOpen && CountyKey(rec.County) == key && rec.SourceHash == sourceHash {
+			return rec, true
+		}
+	}
+	return models.ParseError{}, false
+}
+
+// ParseErrors returns every parse error, most recently seen first.
+func (s *Memory) ParseErrors() []models.ParseError {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.ParseError, 0, len(s.parseErrors))
+	for _, rec := range s.parseErrors {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeenAt.After(out[j].LastSeenAt) })
+	return out
+}
+
+// DeleteParseError removes the parse error with the given ID, unless it is
+// under a legal hold.
+func (s *Memory) DeleteParseError(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.parseErrors[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if s.heldLocked(rec.Request.Election, rec.County) {
+		return ErrLegalHold
+	}
+	delete(s.parseErrors, id)
+	return nil
+}
@@ -0,0 +1,337 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/events"
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// projection holds the read models of one election derived from the event
+// log.
+type projection struct {
+	results      map[string]*models.Results
+	snapshotLogs map[string]*snapshotLog
+	turnout      map[string][]models.TurnoutSample
+	latency      map[string][]models.LatencySample
+	// history holds each county's snapshots of distinct content within
+	// HistoryWindow, oldest first.
+	history map[string][]*models.Results
+
+	// seq is the sequence number of the last event applied.
+	seq uint64
+}
+
+func newProjection() *projection {
+	return &projection{
+		results:      make(map[string]*models.Results),
+		snapshotLogs: make(map[string]*snapshotLog),
+		turnout:      make(map[string][]models.TurnoutSample),
+		latency:      make(map[string][]models.LatencySample),
+		history:      make(map[string][]*models.Results),
+	}
+}
+
+// apply updates the read models with e. Events of unknown types are
+// skipped, so a log written by a newer version can still be replayed.
+func (p *projection) apply(e models.Event) {
+	p.seq = e.Seq
+	switch e.Type {
+	case models.EventPublished:
+		p.publish(e)
+	}
+}
+
+// publish makes the snapshot of e the county's live results, logging it
+// and recording its turnout and, if its content is new, its latency.
+func (p *projection) publish(e models.Event) {
+	key := CountyKey(e.County)
+	prev, had := p.results[key]
+	r := e.Results
+	if r == nil {
+		// A republished snapshot repeats the county's live one.
+		if !had || prev.Hash != e.Hash {
+			return
+		}
+		c := *prev
+		c.ParsedAt = e.ParsedAt
+		c.Latency = e.Latency
+		r = &c
+	}
+	if !had || prev.Hash != r.Hash {
+		p.appendHistory(key, r)
+		if r.Latency != nil {
+			p.appendLatency(key, models.LatencySample{County: r.County, SnapshotHash: r.Hash, Latency: *r.Latency})
+		}
+	}
+	p.results[key] = r
+	p.appendLog(key, r, e.At)
+	if r.Turnout != nil {
+		p.appendTurnout(key, models.TurnoutSample{
+			At:               r.ParsedAt,
+			RegisteredVoters: r.Turnout.RegisteredVoters,
+			BallotsCast:      r.Turnout.BallotsCast,
+			Percent:          r.Turnout.Percent,
+		})
+	}
+}
+
+// applyLocked updates the read models of e's election with e, registering
+// the election if it is new, as one replicated from another instance may
+// be.
+func (s *Memory) applyLocked(e models.Event) {
+	id := s.electionID(e.Election)
+	if _, ok := s.elections[id]; !ok {
+		s.elections[id] = models.Election{ID: id, CreatedAt: e.At.UTC()}
+	}
+	switch e.Type {
+	case models.EventElectionArchived:
+		el := s.elections[id]
+		at := e.At.UTC()
+		el.ArchivedAt = &at
+		s.elections[id] = el
+	case models.EventCountyCertified:
+		if e.Certification != nil {
+			c := *e.Certification
+			c.Election = id
+			s.certifications[ElectionKey(id, c.County)] = c
+		}
+	default:
+		s.projectionForLocked(id).apply(e)
+	}
+}
+
+// publishedEventLocked returns the event publishing r in its election as of
+// at. Only content new to the county is carried in full.
+func (s *Memory) publishedEventLocked(r *models.Results, at time.Time) *models.Event {
+	e := &models.Event{
+		Type:     models.EventPublished,
+		At:       at,
+		County:   r.County,
+		Election: s.electionID(r.Election),
+		Hash:     r.Hash,
+		ParsedAt: r.ParsedAt,
+	}
+	prev, ok := s.projectionForLocked(e.Election).results[CountyKey(r.County)]
+	if ok && prev.Hash == r.Hash {
+		e.Latency = r.Latency
+	} else {
+		e.Results = r
+	}
+	return e
+}
+
+// Open returns a Memory whose published data is projected from log, as
+// earlier runs recorded it, and which records new events there. Events
+// recorded without an election belong to election, the default one.
+func Open(log events.Log, election string) (*Memory, error) {
+	s := New()
+	s.SetDefaultElection(election)
+	s.log = log
+	for _, e := range s.electionsInLog() {
+		if _, ok := s.elections[e.ID]; !ok {
+			s.elections[e.ID] = e
+		}
+		if _, err := s.Rebuild(context.Background(), RebuildOptions{Election: e.ID}); err != nil {
+			return nil, err
+		}
+	}
+	// Elections are archived, and counties certified, outside the
+	// projections.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.log.Replay(0, func(e models.Event) error {
+		if e.Type == models.EventElectionArchived || e.Type == models.EventCountyCertified {
+			s.applyLocked(e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// electionsInLog returns the elections with events in the log, as created
+// by their first event, so elections created before a restart are known
+// again. Errors reading the log surface from Rebuild.
+func (s *Memory) electionsInLog() []models.Election {
+	seen := map[string]bool{s.election: true}
+	out := []models.Election{{ID: s.election}}
+	s.log.Replay(0, func(e models.Event) error {
+		if id := s.electionOf(e); !seen[id] {
+			seen[id] = true
+			out = append(out, models.Election{ID: id, CreatedAt: e.At.UTC()})
+		}
+		return nil
+	})
+	return out
+}
+
+// RebuildOptions narrow and report on a Rebuild.
+type RebuildOptions struct {
+	// Election is the election whose read models are rebuilt; empty is the
+	// default one.
+	Election string
+	// From limits the rebuild to counties with events at or after it.
+	// Their whole history is still replayed, since every read model
+	// depends on what came before. Zero rebuilds every county.
+	From time.Time
+	// Progress, if set, is called after each event replayed with the
+	// number replayed so far and the number to replay.
+	Progress func(done, total int)
+}
+
+// Rebuild derives the projections of an election again by replaying the
+// event log through the current projection code, so a fix to how read
+// models are derived applies to everything published before it. Readers
+// see the old projections until the new ones are complete, then all of them
+// at once; nothing changes if ctx is cancelled first.
+func (s *Memory) Rebuild(ctx context.Context, opts RebuildOptions) (models.RebuildReport, error) {
+	s.mu.RLock()
+	election := s.electionID(opts.Election)
+	s.mu.RUnlock()
+	report := models.RebuildReport{Election: election, StartedAt: time.Now().UTC(), Counties: []string{}}
+	if !opts.From.IsZero() {
+		from := opts.From.UTC()
+		report.From = &from
+	}
+
+	// A first pass finds the counties to rebuild and how many events they
+	// have, so progress has a total.
+	counts := make(map[string]int)
+	counties := make(map[string]bool)
+	err := s.log.Replay(0, func(e models.Event) error {
+		if e.Type != models.EventPublished || s.electionOf(e) != election {
+			return nil
+		}
+		key := CountyKey(e.County)
+		counts[key]++
+		if !e.At.Before(opts.From) {
+			counties[key] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	total := 0
+	for key := range counties {
+		total += counts[key]
+	}
+	selected := func(e models.Event) bool {
+		return s.electionOf(e) == election && counties[CountyKey(e.County)]
+	}
+
+	p := newProjection()
+	replay := func(e models.Event) error {
+		p.seq = e.Seq
+		if !selected(e) {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.apply(e)
+		report.Events++
+		if opts.Progress != nil {
+			opts.Progress(report.Events, max(total, report.Events))
+		}
+		return nil
+	}
+	if err := s.log.Replay(0, replay); err != nil {
+		return report, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Events are only appended under the lock, so this catches up with
+	// those appended during the replay.
+	if err := s.log.Replay(p.seq, replay); err != nil {
+		return report, err
+	}
+	for key := range p.results {
+		report.Counties = append(report.Counties, key)
+	}
+	sort.Strings(report.Counties)
+	s.projectionForLocked(election).copyExcept(p, counties)
+	s.projections[election] = p
+	if election == s.election {
+		s.projection = p
+	}
+	s.version++
+	report.FinishedAt = time.Now().UTC()
+	report.DurationMs = report.FinishedAt.Sub(report.StartedAt).Milliseconds()
+	return report, nil
+}
+
+// copyExcept copies the read models of every county not in skip into dst.
+func (p *projection) copyExcept(dst *projection, skip map[string]bool) {
+	for key, r := range p.results {
+		if !skip[key] {
+			dst.results[key] = r
+		}
+	}
+	for key, l := range p.snapshotLogs {
+		if !skip[key] {
+			dst.snapshotLogs[key] = l
+		}
+	}
+	for key, t := range p.turnout {
+		if !skip[key] {
+			dst.turnout[key] = t
+		}
+	}
+	for key, l := range p.latency {
+		if !skip[key] {
+			dst.latency[key] = l
+		}
+	}
+	for key, h := range p.history {
+		if !skip[key] {
+			dst.history[key] = h
+		}
+	}
+}
+
+var errEnough = errors.New("enough events")
+
+// electionOf returns the election of e. The default election is only set
+// before anything is published, so it is read without the lock.
+func (s *Memory) electionOf(e models.Event) string {
+	if e.Election == "" {
+		return s.election
+	}
+	return e.Election
+}
+
+// Events returns up to limit events after seq, oldest first, only those of
+// election and county if they are set.
+func (s *Memory) Events(after uint64, election, county string, limit int) ([]models.Event, error) {
+	out := []models.Event{}
+	err := s.log.Replay(after, func(e models.Event) error {
+		if election != "" && s.electionOf(e) != election {
+			return nil
+		}
+		if county != "" && CountyKey(e.County) != CountyKey(county) {
+			return nil
+		}
+		out = append(out, e)
+		if len(out) >= limit {
+			return errEnough
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnough) {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LastEvent returns the sequence number of the last event recorded.
+func (s *Memory) LastEvent() uint64 {
+	return s.log.Last()
+}
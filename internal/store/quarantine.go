@@ -0,0 +1,91 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SaveSample records the source sample of the county's published snapshot,
+// the baseline the next snapshot's layout is compared against.
+func (s *Memory) SaveSample(county string, sample *models.SourceSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sample == nil {
+		delete(s.samples, CountyKey(county))
+		return
+	}
+	s.samples[CountyKey(county)] = sample
+}
+
+// Sample returns the source sample of the county's published snapshot, or
+// nil if none was recorded.
+func (s *Memory) Sample(county string) *models.SourceSample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.samples[CountyKey(county)]
+}
+
+// SaveQuarantine stores or replaces a quarantine record.
+func (s *Memory) SaveQuarantine(rec models.QuarantineRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quarantine[rec.ID] = rec
+}
+
+// Quarantine returns the quarantine record with the given ID.
+func (s *Memory) Quarantine(id string) (models.QuarantineRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.quarantine[id]
+	if !ok {
+		return models.QuarantineRecord{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+// HeldQuarantine returns the held record for county whose snapshot has the
+// given hash, so a source that keeps failing the same way is held once.
+func (s *Memory) HeldQuarantine(county, hash string) (models.QuarantineRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := CountyKey(county)
+	for _, rec := range s.quarantine {
+		if rec.Status == models.QuarantineHeld && CountyKey(rec.County) == key && rec.Results.Hash == hash {
+			return rec, true
+		}
+	}
+	return models.QuarantineRecord{}, false
+}
+
+// Quarantines returns every quarantine record, newest first.
+func (s *Memory) Quarantines() []models.QuarantineRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.QuarantineRecord, 0, len(s.quarantine))
+	for _, rec := range s.quarantine {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+// DeleteQuarantine removes the quarantine record with the given ID, unless
+// it is under a legal hold.
+func (s *Memory) DeleteQuarantine(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.quarantine[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if s.heldLocked(rec.Request.Election, rec.County) {
+		return ErrLegalHold
+	}
+	delete(s.quarantine, id)
+	return nil
+}
@@ -0,0 +1,61 @@
+package store
+
+import "github.com/many221/era_api_v1/internal/models"
+
+// SaveRaceCall records c, replacing any earlier call of its contest; an
+// empty election is the default one.
+func (s *Memory) SaveRaceCall(c models.ManualCall) models.ManualCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.Election = s.electionID(c.Election)
+	s.raceCalls[raceCallKey(c.Election, c.ContestID)] = c
+	s.version++
+	return c
+}
+
+// RaceCall returns the manual call of contest in election.
+func (s *Memory) RaceCall(election, contest string) (models.ManualCall, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.raceCalls[raceCallKey(s.electionID(election), contest)]
+	if !ok {
+		return models.ManualCall{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// RaceCalls returns the manual calls of election keyed by contest ID.
+func (s *Memory) RaceCalls(election string) map[string]models.ManualCall {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id := s.electionID(election)
+	out := make(map[string]models.ManualCall)
+	for _, c := range s.raceCalls {
+		if c.Election == id {
+			out[c.ContestID] = c
+		}
+	}
+	return out
+}
+
+// DeleteRaceCall retracts the manual call of contest in election.
+func (s *Memory) DeleteRaceCall(election, contest string) (models.ManualCall, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := raceCallKey(s.electionID(election), contest)
+	c, ok := s.raceCalls[key]
+	if !ok {
+		return models.ManualCall{}, ErrNotFound
+	}
+	delete(s.raceCalls, key)
+	s.version++
+	return c, nil
+}
+
+func raceCallKey(election, contest string) string {
+	return election + "/" + contest
+}
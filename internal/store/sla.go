@@ -0,0 +1,97 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// maxSLABreaches bounds the breach history kept.
+const maxSLABreaches = 1000
+
+// SaveSLA stores or replaces an SLA.
+func (s *Memory) SaveSLA(sla models.SLA) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slas[sla.ID] = sla
+}
+
+// SLA returns the SLA with the given ID.
+func (s *Memory) SLA(id string) (models.SLA, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sla, ok := s.slas[id]
+	if !ok {
+		return models.SLA{}, ErrNotFound
+	}
+	return sla, nil
+}
+
+// SLAs returns every SLA, oldest first.
+func (s *Memory) SLAs() []models.SLA {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.SLA, 0, len(s.slas))
+	for _, sla := range s.slas {
+		out = append(out, sla)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// DeleteSLA removes the SLA with the given ID. Its breaches stay in the
+// history.
+func (s *Memory) DeleteSLA(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.slas[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.slas, id)
+	return nil
+}
+
+// AddSLABreach appends a breach to the history, dropping the oldest past
+// maxSLABreaches.
+func (s *Memory) AddSLABreach(b models.SLABreach) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.slaBreaches = append(s.slaBreaches, b)
+	if n := len(s.slaBreaches) - maxSLABreaches; n > 0 {
+		s.slaBreaches = append([]models.SLABreach(nil), s.slaBreaches[n:]...)
+	}
+}
+
+// EndSLABreach ends the open breach with the given ID at at.
+func (s *Memory) EndSLABreach(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.slaBreaches {
+		if b := &s.slaBreaches[i]; b.ID == id && b.EndedAt == nil {
+			b.EndedAt = &at
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// SLABreaches returns recorded breaches newest first, only those of the SLA
+// with slaID when it is set.
+func (s *Memory) SLABreaches(slaID string) []models.SLABreach {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []models.SLABreach{}
+	for i := len(s.slaBreaches) - 1; i >= 0; i-- {
+		if slaID == "" || s.slaBreaches[i].SLAID == slaID {
+			out = append(out, s.slaBreaches[i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/merkle"
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// snapshotLog is a county's append-only log of published snapshots. Entries
+// are never modified or removed, so every tree head ever served stays
+// provable.
+type snapshotLog struct {
+	entries []models.LogEntry
+	leaves  [][]byte
+}
+
+func (p *projection) appendLog(key string, r *models.Results, at time.Time) {
+	l := p.snapshotLogs[key]
+	if l == nil {
+		l = &snapshotLog{}
+		p.snapshotLogs[key] = l
+	}
+	e := models.LogEntry{
+		Index:        len(l.entries),
+		SnapshotHash: r.Hash,
+		PublishedAt:  at.UTC(),
+	}
+	leaf := merkle.LeafHash(models.LogEntryData(key, e))
+	e.LeafHash = hex.EncodeToString(leaf)
+	l.entries = append(l.entries, e)
+	l.leaves = append(l.leaves, leaf)
+}
+
+// SnapshotLog returns county's snapshot log and its current tree head.
+func (s *Memory) SnapshotLog(county string) (models.SnapshotLog, error) {
+	return s.ElectionSnapshotLog("", county)
+}
+
+// ElectionSnapshotLog returns county's snapshot log in election and its
+// current tree head; an empty election is the default one.
+func (s *Memory) ElectionSnapshotLog(election, county string) (models.SnapshotLog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := CountyKey(county)
+	l, ok := s.snapshotLogOf(election, key)
+	if !ok {
+		return models.SnapshotLog{}, ErrNotFound
+	}
+	return models.SnapshotLog{
+		LogHead: l.head(key, len(l.leaves)),
+		Entries: append([]models.LogEntry(nil), l.entries...),
+	}, nil
+}
+
+// InclusionProof proves entry index is in county's log as of the tree of
+// size entries; size 0 means the current tree. Proofs against an older size
+// let auditors check an entry against a tree head they recorded earlier.
+func (s *Memory) InclusionProof(county string, index, size int) (models.InclusionProof, error) {
+	return s.ElectionInclusionProof("", county, index, size)
+}
+
+// ElectionInclusionProof is InclusionProof for county's log in election; an
+// empty election is the default one.
+func (s *Memory) ElectionInclusionProof(election, county string, index, size int) (models.InclusionProof, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := CountyKey(county)
+	l, ok := s.snapshotLogOf(election, key)
+	if !ok {
+		return models.InclusionProof{}, ErrNotFound
+	}
+	if size == 0 {
+		size = len(l.leaves)
+	}
+	if size > len(l.leaves) || index < 0 || index >= size {
+		return models.InclusionProof{}, ErrNotFound
+	}
+
+	path, err := merkle.Proof(index, l.leaves[:size])
+	if err != nil {
+		return models.InclusionProof{}, ErrNotFound
+	}
+	p := models.InclusionProof{
+		LogHead: l.head(key, size),
+		Entry:   l.entries[index],
+		Path:    make([]string, len(path)),
+	}
+	for i, h := range path {
+		p.Path[i] = hex.EncodeToString(h)
+	}
+	return p, nil
+}
+
+func (s *Memory) snapshotLogOf(election, key string) (*snapshotLog, bool) {
+	p := s.projectionOf(election)
+	if p == nil {
+		return nil, false
+	}
+	l, ok := p.snapshotLogs[key]
+	return l, ok
+}
+
+func (l *snapshotLog) head(key string, size int) models.LogHead {
+	return models.LogHead{County: key, Size: size, Root: hex.EncodeToString(merkle.Root(l.leaves[:size]))}
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SaveSnippet stores or replaces a snippet definition.
+func (s *Memory) SaveSnippet(sn models.Snippet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snippets[sn.ID] = sn
+}
+
+// Snippet returns the snippet definition with the given ID.
+func (s *Memory) Snippet(id string) (models.Snippet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sn, ok := s.snippets[id]
+	if !ok {
+		return models.Snippet{}, ErrNotFound
+	}
+	return sn, nil
+}
+
+// Snippets returns every snippet definition, oldest first.
+func (s *Memory) Snippets() []models.Snippet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.Snippet, 0, len(s.snippets))
+	for _, sn := range s.snippets {
+		out = append(out, sn)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// DeleteSnippet removes the snippet definition with the given ID.
+func (s *Memory) DeleteSnippet(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.snippets[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.snippets, id)
+	return nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// SaveSourceResults keeps r as the latest snapshot its source level reported
+// for its county, whether or not it was published, so sources reporting the
+// same county can be compared.
+func (s *Memory) SaveSourceResults(r *models.Results) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := copyResults(r)
+	c.Election = s.electionID(r.Election)
+	s.sourceResults[sourceResultsKey(c.Election, c.County, c.Level())] = c
+}
+
+// SourceResults returns the latest snapshot of every source level that has
+// reported county in election, keyed by level.
+func (s *Memory) SourceResults(election, county string) map[string]*models.Results {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	election = s.electionID(election)
+	key := CountyKey(county)
+	out := make(map[string]*models.Results)
+	for _, r := range s.sourceResults {
+		if r.Election == election && CountyKey(r.County) == key {
+			out[r.Level()] = copyResults(r)
+		}
+	}
+	return out
+}
+
+// SourceCounties returns the keys of the counties of election reported by
+// more than one source level, sorted.
+func (s *Memory) SourceCounties(election string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	election = s.electionID(election)
+	levels := make(map[string]int)
+	for _, r := range s.sourceResults {
+		if r.Election == election {
+			levels[CountyKey(r.County)]++
+		}
+	}
+	var out []string
+	for county, n := range levels {
+		if n > 1 {
+			out = append(out, county)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sourceResultsKey(election, county, level string) string {
+	return election + "/" + CountyKey(county) + "/" + level
+}
@@ -0,0 +1,440 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/events"
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// ErrNotFound is returned when the requested county or record doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Store keeps the latest processed results per county, plus supplementary
+// data attached to them. Counties are keyed by models.Slug of their name so
+// they can be addressed from URLs.
+//
+// Results and county registrations belong to an election. Methods that
+// don't take one use the default election.
+//
+// Memory is the implementation the server runs with; the methods are
+// documented there. Another backend, such as a database, implements the
+// same methods, so the API, scheduler and renderers run unchanged on it.
+type Store interface {
+	// Published results
+	SaveResults(r *models.Results) error
+	Results(county string) (*models.Results, error)
+	ElectionResults(election, county string) (*models.Results, error)
+	ElectionSnapshot(election string) Snapshot
+	Version() uint64
+	Counties() []string
+	ElectionCounties(election string) []string
+	ElectionResultsAt(election, county string, at time.Time) (*models.Results, error)
+	SaveSourceResults(r *models.Results)
+	SourceResults(election, county string) map[string]*models.Results
+	SourceCounties(election string) []string
+
+	// Snapshot logs and proofs
+	SnapshotLog(county string) (models.SnapshotLog, error)
+	ElectionSnapshotLog(election, county string) (models.SnapshotLog, error)
+	InclusionProof(county string, index, size int) (models.InclusionProof, error)
+	ElectionInclusionProof(election, county string, index, size int) (models.InclusionProof, error)
+
+	// Histories
+	TurnoutHistory() map[string][]models.TurnoutSample
+	LatencyHistory() map[string][]models.LatencySample
+
+	// The event log and its projections
+	Rebuild(ctx context.Context, opts RebuildOptions) (models.RebuildReport, error)
+	Events(after uint64, election, county string, limit int) ([]models.Event, error)
+	LastEvent() uint64
+
+	// Elections
+	SetDefaultElection(id string)
+	DefaultElection() string
+	SaveElection(e models.Election)
+	Election(id string) (models.Election, error)
+	Elections() []models.Election
+	DeleteElection(id string) error
+	ArchiveElection(id string, at time.Time) (models.Election, error)
+	Certify(c models.Certification) (models.Certification, error)
+	Certification(election, county string) (models.Certification, error)
+	Certifications(election string) []models.Certification
+	ElectionSummary(election string) (models.ElectionResults, error)
+
+	// County registrations
+	SaveCounty(c models.CountySource)
+	County(county string) (models.CountySource, error)
+	ElectionCounty(election, county string) (models.CountySource, error)
+	CountySources() []models.CountySource
+	ElectionCountySources(election string) []models.CountySource
+	DeleteCounty(election, county string) error
+	RecordFetch(election, county string, at time.Time, err error)
+	SaveCredentials(c models.SourceCredentials)
+	Credentials(county string) (models.SourceCredentials, error)
+	DeleteCredentials(county string) error
+	SaveContact(c models.CountyContact)
+	Contact(county string) (models.CountyContact, error)
+	Contacts() []models.CountyContact
+	DeleteContact(county string) error
+	WatchedFiles(election, county string) map[string]bool
+	MarkWatched(election, county, ingested string, files ...string)
+
+	// Jurisdictions
+	SaveJurisdiction(j models.Jurisdiction) error
+	Jurisdiction(id string) (models.Jurisdiction, error)
+	Jurisdictions() []models.Jurisdiction
+	JurisdictionView(election, id string) (models.JurisdictionView, error)
+	JurisdictionCounty(id string) (models.Jurisdiction, error)
+	DeleteJurisdiction(id string) error
+	AttachContest(election, contest, jurisdiction string) error
+	DetachContest(election, contest, jurisdiction string) error
+	ContestJurisdictions(election string) map[string]string
+
+	// Districts
+	SaveDistrict(d models.District)
+	District(id string) (models.District, error)
+	Districts() []models.District
+	DeleteDistrict(id string) error
+
+	// Staged datasets
+	StageResults(label string, r *models.Results, at time.Time)
+	Datasets() []models.Dataset
+	Dataset(label string) (models.Dataset, error)
+	StagedResults(label, election, county string) (*models.Results, error)
+	DeleteDataset(label string) error
+	ActivateDataset(label string, at time.Time) ([]*models.Results, models.DatasetSwitch, error)
+
+	// Source samples and quarantine
+	SaveSample(county string, sample *models.SourceSample)
+	Sample(county string) *models.SourceSample
+	SaveQuarantine(rec models.QuarantineRecord)
+	Quarantine(id string) (models.QuarantineRecord, error)
+	HeldQuarantine(county, hash string) (models.QuarantineRecord, bool)
+	Quarantines() []models.QuarantineRecord
+	DeleteQuarantine(id string) error
+
+	// Parse errors
+	SaveParseError(rec models.ParseError)
+	ParseError(id string) (models.ParseError, error)
+	OpenParseError(county, sourceHash string) (models.ParseError, bool)
+	ParseErrors() []models.ParseError
+	DeleteParseError(id string) error
+
+	// Source layouts and parser configs
+	SwapLayout(county string, layout *models.SourceLayout) *models.SourceLayout
+	Layout(county string) *models.SourceLayout
+	AddLayoutChange(c models.LayoutChange)
+	LayoutChanges(county string) []models.LayoutChange
+	RecordParserConfig(county string, c models.ParserConfig)
+	ParserConfigs(county string) []models.ParserConfig
+
+	// Contest rules, candidates and measures
+	SaveContestRule(r models.ContestRule)
+	ContestRules(election string) []models.ContestRule
+	DeleteContestRule(id string) error
+	SaveCandidate(c models.CanonicalCandidate)
+	Candidate(id string) (models.CanonicalCandidate, error)
+	Candidates() []models.CanonicalCandidate
+	DeleteCandidate(id string) error
+	SaveMeasureLink(l models.MeasureLink) models.MeasureLink
+	MeasureLink(election, id string) (models.MeasureLink, error)
+	MeasureLinks(election string) []models.MeasureLink
+	DeleteMeasureLink(election, id string) error
+	SaveManifest(m models.Manifest)
+	Manifest(election string) (models.Manifest, error)
+	DeleteManifest(election string) error
+
+	// Outcomes and race calls
+	SetOutcomeRules(rules models.OutcomeRules)
+	OutcomeRules() models.OutcomeRules
+	SaveRaceCall(c models.ManualCall) models.ManualCall
+	RaceCall(election, contest string) (models.ManualCall, error)
+	RaceCalls(election string) map[string]models.ManualCall
+	DeleteRaceCall(election, contest string) (models.ManualCall, error)
+	SetForecasts(county string, forecasts []models.ContestForecast)
+	Forecasts(county string) map[string]models.ContestForecast
+
+	// Anomalies, alerts and SLAs
+	AddAnomalies(found []models.Anomaly)
+	Anomalies(county string) []models.Anomaly
+	SaveRunbook(rb models.Runbook)
+	DeleteRunbook(alertType, county string) error
+	Runbooks() []models.Runbook
+	RunbooksFor(alertType, county string) []models.Runbook
+	AddAlert(a models.Alert)
+	Alerts(county string) []models.Alert
+	SaveSLA(sla models.SLA)
+	SLA(id string) (models.SLA, error)
+	SLAs() []models.SLA
+	DeleteSLA(id string) error
+	AddSLABreach(b models.SLABreach)
+	EndSLABreach(id string, at time.Time) error
+	SLABreaches(slaID string) []models.SLABreach
+
+	// Overlays, snippets and legal holds
+	AddOverlay(o models.Overlay)
+	DeleteOverlay(id string) error
+	Overlays(county string) map[string][]models.Overlay
+	SaveSnippet(sn models.Snippet)
+	Snippet(id string) (models.Snippet, error)
+	Snippets() []models.Snippet
+	DeleteSnippet(id string) error
+	SaveLegalHold(h models.LegalHold) models.LegalHold
+	LegalHold(id string) (models.LegalHold, error)
+	LegalHolds() []models.LegalHold
+	ReleaseLegalHold(id, by string, at time.Time) (models.LegalHold, error)
+	Held(election, county string) bool
+}
+
+// Memory is a Store holding everything in memory, with nothing to set up,
+// for development, demos and single-node deployments.
+//
+// Published snapshots are recorded in an event log; the live results,
+// snapshot logs and turnout and latency histories are projections of it,
+// which Rebuild derives again from the log. Open gives it a persistent log,
+// from which a restarted server recovers what it published.
+//
+// The default election's read models are embedded.
+type Memory struct {
+	mu  sync.RWMutex
+	log events.Log
+	*projection
+
+	election    string
+	elections   map[string]models.Election
+	projections map[string]*projection
+
+	overlays  map[string]models.Overlay
+	forecasts map[string]map[string]models.ContestForecast
+	counties  map[string]models.CountySource
+	contacts  map[string]models.CountyContact
+	snippets  map[string]models.Snippet
+
+	credentials map[string]models.SourceCredentials
+
+	candidates   map[string]models.CanonicalCandidate
+	contestRules map[string]models.ContestRule
+	manifests    map[string]models.Manifest
+
+	samples     map[string]*models.SourceSample
+	quarantine  map[string]models.QuarantineRecord
+	parseErrors map[string]models.ParseError
+
+	layouts       map[string]*models.SourceLayout
+	layoutChanges []models.LayoutChange
+	parserConfigs map[string][]models.ParserConfig
+
+	runbooks map[string]models.Runbook
+	alerts   []models.Alert
+
+	slas        map[string]models.SLA
+	slaBreaches []models.SLABreach
+
+	anomalies []models.Anomaly
+
+	datasets map[string]*dataset
+
+	legalHolds map[string]models.LegalHold
+
+	certifications map[string]models.Certification // by election key
+
+	outcomeRules models.OutcomeRules
+	raceCalls    map[string]models.ManualCall  // by election and contest ID
+	measureLinks map[string]models.MeasureLink // by election and link ID
+
+	jurisdictions        map[string]models.Jurisdiction
+	districts            map[string]models.District
+	contestJurisdictions map[string]string // jurisdiction ID by election and contest ID
+
+	sourceResults map[string]*models.Results // by election, county and source level
+
+	watched map[string]map[string]bool // files seen on watched pages, by election key
+
+	// version counts changes to published data so derived views, like the
+	// bulk dump, know when to rebuild.
+	version uint64
+}
+
+// New returns an empty Memory with its event log in memory, publishing for
+// DefaultElection.
+func New() *Memory {
+	p := newProjection()
+	return &Memory{
+		log:        events.NewMemory(),
+		projection: p,
+
+		election:    DefaultElection,
+		elections:   map[string]models.Election{DefaultElection: {ID: DefaultElection, CreatedAt: time.Now().UTC()}},
+		projections: map[string]*projection{DefaultElection: p},
+
+		overlays:  make(map[string]models.Overlay),
+		forecasts: make(map[string]map[string]models.ContestForecast),
+		counties:  make(map[string]models.CountySource),
+		contacts:  make(map[string]models.CountyContact),
+		snippets:  make(map[string]models.Snippet),
+
+		credentials: make(map[string]models.SourceCredentials),
+
+		candidates:   make(map[string]models.CanonicalCandidate),
+		contestRules: make(map[string]models.ContestRule),
+		manifests:    make(map[string]models.Manifest),
+
+		samples:     make(map[string]*models.SourceSample),
+		quarantine:  make(map[string]models.QuarantineRecord),
+		parseErrors: make(map[string]models.ParseError),
+
+		layouts:       make(map[string]*models.SourceLayout),
+		parserConfigs: make(map[string][]models.ParserConfig),
+
+		runbooks: make(map[string]models.Runbook),
+
+		slas: make(map[string]models.SLA),
+
+		datasets: make(map[string]*dataset),
+
+		legalHolds: make(map[string]models.LegalHold),
+
+		certifications: make(map[string]models.Certification),
+
+		outcomeRules: models.DefaultOutcomeRules,
+		raceCalls:    make(map[string]models.ManualCall),
+		measureLinks: make(map[string]models.MeasureLink),
+
+		jurisdictions:        make(map[string]models.Jurisdiction),
+		districts:            make(map[string]models.District),
+		contestJurisdictions: make(map[string]string),
+
+		sourceResults: make(map[string]*models.Results),
+
+		watched: make(map[string]map[string]bool),
+	}
+}
+
+// CountyKey returns the key a county name is stored under.
+func CountyKey(name string) string {
+	return models.Slug(name)
+}
+
+// SaveResults publishes r as the county's results in its election, as of
+// when it was parsed. It is recorded in the event log before any read model
+// changes, and nothing is published if that fails, the election is archived
+// or the county's results are certified. The snapshot is one event, written
+// whole or dropped on replay if a crash cut it short, and readers see
+// either the county's previous results or all of r's contests, never some
+// of each.
+func (s *Memory) SaveResults(r *models.Results) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.archivedLocked(r.Election) {
+		return ErrElectionArchived
+	}
+	if s.certifiedLocked(r.Election, r.County) {
+		return ErrCertified
+	}
+	e := s.publishedEventLocked(r, r.ParsedAt)
+	if err := s.log.Append(e); err != nil {
+		return err
+	}
+	s.applyLocked(*e)
+	s.version++
+	return nil
+}
+
+// Results returns a copy of the latest results for county.
+func (s *Memory) Results(county string) (*models.Results, error) {
+	return s.ElectionResults("", county)
+}
+
+// ElectionResults returns a copy of the latest results for county in
+// election; an empty election is the default one.
+func (s *Memory) ElectionResults(election, county string) (*models.Results, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p := s.projectionOf(election)
+	if p == nil {
+		return nil, ErrNotFound
+	}
+	r, ok := p.results[CountyKey(county)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyResults(r), nil
+}
+
+// Snapshot is every county's published results in an election as of one
+// store version. A county's results are only ever replaced whole, all
+// contests at once; a Snapshot extends that to the election, being read in
+// one step so no publish lands between two counties.
+type Snapshot struct {
+	Version uint64
+	Results []*models.Results // copies, sorted by county key
+}
+
+// ElectionSnapshot returns the published results of every county in
+// election; an empty election is the default one.
+func (s *Memory) ElectionSnapshot(election string) Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap := Snapshot{Version: s.version, Results: []*models.Results{}}
+	if p := s.projectionOf(election); p != nil {
+		for _, r := range p.results {
+			snap.Results = append(snap.Results, copyResults(r))
+		}
+	}
+	sort.Slice(snap.Results, func(i, j int) bool {
+		return CountyKey(snap.Results[i].County) < CountyKey(snap.Results[j].County)
+	})
+	return snap
+}
+
+// Version returns a counter that increases whenever published results, or
+// the forecasts and overlays attached to them, change.
+func (s *Memory) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// Counties returns the keys of every county with stored results, sorted.
+func (s *Memory) Counties() []string {
+	return s.ElectionCounties("")
+}
+
+// ElectionCounties returns the keys of every county with stored results in
+// election, sorted; an empty election is the default one.
+func (s *Memory) ElectionCounties(election string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []string{}
+	if p := s.projectionOf(election); p != nil {
+		for k := range p.results {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// copyResults deep-copies r so callers can annotate it without racing writers.
+func copyResults(r *models.Results) *models.Results {
+	out := *r
+	out.Contests = make([]models.Contest, len(r.Contests))
+	for i, c := range r.Contests {
+		c.Candidates = append([]models.Candidate(nil), c.Candidates...)
+		c.Overlays = nil
+		c.Forecast = nil
+		c.Outcome = nil
+		c.Jurisdiction = ""
+		out.Contests[i] = c
+	}
+	return &out
+}
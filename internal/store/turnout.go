@@ -0,0 +1,32 @@
+package store
+
+import "github.com/many221/era_api_v1/internal/models"
+
+// maxTurnoutSamples bounds the turnout history kept per county.
+const maxTurnoutSamples = 2000
+
+func (p *projection) appendTurnout(key string, sample models.TurnoutSample) {
+	history := p.turnout[key]
+	if n := len(history); n > 0 && history[n-1].BallotsCast == sample.BallotsCast &&
+		history[n-1].RegisteredVoters == sample.RegisteredVoters {
+		return
+	}
+	history = append(history, sample)
+	if len(history) > maxTurnoutSamples {
+		history = history[len(history)-maxTurnoutSamples:]
+	}
+	p.turnout[key] = history
+}
+
+// TurnoutHistory returns every county's turnout samples, oldest first, keyed
+// by county key.
+func (s *Memory) TurnoutHistory() map[string][]models.TurnoutSample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string][]models.TurnoutSample, len(s.turnout))
+	for k, v := range s.turnout {
+		out[k] = append([]models.TurnoutSample(nil), v...)
+	}
+	return out
+}
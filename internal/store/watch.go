@@ -0,0 +1,41 @@
+package store
+
+// WatchedFiles returns the files already seen on the watched page of
+// county's registration in election.
+func (s *Memory) WatchedFiles(election, county string) map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := s.watched[ElectionKey(s.electionID(election), county)]
+	out := make(map[string]bool, len(seen))
+	for f := range seen {
+		out[f] = true
+	}
+	return out
+}
+
+// MarkWatched records files as seen on the watched page of county's
+// registration in election, and ingested as the one it last ingested if
+// it isn't empty.
+func (s *Memory) MarkWatched(election, county, ingested string, files ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := ElectionKey(s.electionID(election), county)
+	seen, ok := s.watched[key]
+	if !ok {
+		seen = make(map[string]bool)
+		s.watched[key] = seen
+	}
+	for _, f := range files {
+		seen[f] = true
+	}
+	if ingested == "" {
+		return
+	}
+	seen[ingested] = true
+	if c, ok := s.counties[key]; ok {
+		c.Status.LastFile = ingested
+		s.counties[key] = c
+	}
+}
@@ -0,0 +1,337 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"maps"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	parsetree "text/template/parse"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/formatter"
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// Kinds of Issue.
+const (
+	IssueSyntax    = "syntax"    // the template doesn't parse
+	IssueUndefined = "undefined" // a field or method results don't have
+	IssueEscaping  = "escaping"  // output html/template can't escape safely
+	IssueExecution = "execution" // anything else failing on the results
+)
+
+// Issue is a problem found in a template.
+type Issue struct {
+	Kind    string `json:"kind"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is a template rendered without being saved, with the problems
+// found in it. HTML is empty when it fails to render.
+type Report struct {
+	Valid  bool    `json:"valid"`
+	HTML   string  `json:"html,omitempty"`
+	Issues []Issue `json:"issues"`
+}
+
+// errorLine splits "template: name:line[:col]: message" errors, and the
+// "html/template:name:line:col: message" ones of the escaper.
+var errorLine = regexp.MustCompile(`^(?:html/)?template: ?[^:]*:(\d+)(?::\d+)?: (.*)$`)
+
+// Preview lints source and renders it with results and fragment, the
+// standard fragment of results, as Save would store it. Unlike Save, it
+// reports every field that doesn't exist rather than the first one
+// rendering reaches, and flags values html/template replaced as unsafe.
+func Preview(source string, results *models.Results, fragment string) Report {
+	rep := Report{Issues: []Issue{}}
+	tmpl, err := template.New("preview").Funcs(formatter.Funcs()).Parse(source)
+	if err != nil {
+		rep.Issues = append(rep.Issues, issueFrom(IssueSyntax, err))
+		return rep
+	}
+
+	// The tree is walked before rendering, which adds escaping functions
+	// to it.
+	if tmpl.Tree != nil {
+		l := &linter{tree: tmpl.Tree, root: reflect.TypeOf(Data{})}
+		l.walk(tmpl.Tree.Root, l.root, map[string]reflect.Type{"$": l.root})
+		rep.Issues = append(rep.Issues, l.issues...)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, Data{Title: results.County + " Results", Content: template.HTML(fragment), Results: results})
+	var escErr *template.Error
+	switch {
+	case errors.As(err, &escErr):
+		issue := issueFrom(IssueEscaping, err)
+		issue.Message = escErr.Description
+		rep.Issues = append(rep.Issues, issue)
+	case err != nil:
+		if issue := issueFrom(IssueExecution, err); !rep.reported(issue.Line) {
+			rep.Issues = append(rep.Issues, issue)
+		}
+	default:
+		rep.HTML = buf.String()
+		if n := strings.Count(rep.HTML, "ZgotmplZ"); n > 0 {
+			rep.Issues = append(rep.Issues, Issue{Kind: IssueEscaping, Message: fmt.Sprintf("%d unsafe URL or CSS values were replaced with ZgotmplZ; a value in an href, src or style attribute must be a safe URL or style", n)})
+		}
+	}
+	rep.Valid = len(rep.Issues) == 0
+	return rep
+}
+
+// reported reports whether an undefined field was already found on line,
+// which rendering then fails on too.
+func (r *Report) reported(line int) bool {
+	for _, issue := range r.Issues {
+		if issue.Kind == IssueUndefined && issue.Line == line {
+			return true
+		}
+	}
+	return false
+}
+
+func issueFrom(kind string, err error) Issue {
+	if m := errorLine.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return Issue{Kind: kind, Line: line, Message: m[2]}
+	}
+	return Issue{Kind: kind, Message: err.Error()}
+}
+
+// Sample is the results a template is previewed with when none are given:
+// a candidate contest and a measure, with turnout.
+func Sample() *models.Results {
+	return &models.Results{
+		County:      "Sample",
+		ContentType: models.ContentTypeCandidate,
+		Source:      "https://example.com/results.xml",
+		ParsedAt:    time.Date(2024, 11, 5, 21, 30, 0, 0, time.UTC),
+		Turnout:     &models.Turnout{RegisteredVoters: 12000, BallotsCast: 7650, Percent: models.TurnoutPercent(7650, 12000)},
+		Contests: []models.Contest{{
+			ID:                 "sample-governor",
+			Title:              "Governor",
+			PrecinctsReporting: 18,
+			PrecinctsTotal:     24,
+			Candidates: []models.Candidate{
+				{Name: "Candidate A", Party: "DEM", Votes: 3900},
+				{Name: "Candidate B", Party: "REP", Votes: 3550},
+				{Name: "Write-in", Votes: 40, WriteIn: true, WriteInAggregate: true},
+			},
+		}, {
+			ID:    "sample-measure-a",
+			Title: "Measure A",
+			Candidates: []models.Candidate{
+				{Name: "Yes", Votes: 4100},
+				{Name: "No", Votes: 3200},
+			},
+			Measure: &models.MeasureResult{Yes: 4100, No: 3200, YesPercent: 56.16, Threshold: "majority", Passing: true},
+		}},
+	}
+}
+
+// linter finds the fields a template uses that its data doesn't have,
+// following the type of dot through range and with.
+type linter struct {
+	tree   *parsetree.Tree
+	root   reflect.Type
+	issues []Issue
+}
+
+// walk checks n with dot of type dot; a nil type means it is unknown, as
+// for values functions return, and isn't checked.
+func (l *linter) walk(n parsetree.Node, dot reflect.Type, vars map[string]reflect.Type) {
+	switch n := n.(type) {
+	case *parsetree.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			l.walk(c, dot, vars)
+		}
+	case *parsetree.ActionNode:
+		// Variables declared by an action last until the end of the
+		// enclosing block, so the caller's map is updated.
+		l.pipe(n.Pipe, dot, vars, false)
+	case *parsetree.IfNode:
+		inner := maps.Clone(vars)
+		l.pipe(n.Pipe, dot, inner, false)
+		l.walk(n.List, dot, inner)
+		l.walk(n.ElseList, dot, maps.Clone(vars))
+	case *parsetree.WithNode:
+		inner := maps.Clone(vars)
+		t := l.pipe(n.Pipe, dot, inner, false)
+		l.walk(n.List, t, inner)
+		l.walk(n.ElseList, dot, maps.Clone(vars))
+	case *parsetree.RangeNode:
+		inner := maps.Clone(vars)
+		t := l.pipe(n.Pipe, dot, inner, true)
+		l.walk(n.List, elem(t), inner)
+		l.walk(n.ElseList, dot, maps.Clone(vars))
+	case *parsetree.TemplateNode:
+		l.pipe(n.Pipe, dot, maps.Clone(vars), false)
+	}
+}
+
+// pipe checks a pipeline and returns the type it yields, declaring its
+// variables in vars. For a range, they get the key and element types.
+func (l *linter) pipe(p *parsetree.PipeNode, dot reflect.Type, vars map[string]reflect.Type, ranged bool) reflect.Type {
+	if p == nil {
+		return nil
+	}
+	var t reflect.Type
+	for _, c := range p.Cmds {
+		t = l.command(c, dot, vars)
+	}
+	for i, v := range p.Decl {
+		vt := t
+		if ranged {
+			vt = elem(t)
+			if len(p.Decl) == 2 && i == 0 {
+				vt = key(t)
+			}
+		}
+		vars[v.Ident[0]] = vt
+	}
+	return t
+}
+
+func (l *linter) command(c *parsetree.CommandNode, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	if len(c.Args) == 0 {
+		return nil
+	}
+	for _, arg := range c.Args[1:] {
+		l.arg(arg, dot, vars)
+	}
+	if _, ok := c.Args[0].(*parsetree.IdentifierNode); ok {
+		return nil // a function, whose result isn't followed
+	}
+	return l.arg(c.Args[0], dot, vars)
+}
+
+func (l *linter) arg(n parsetree.Node, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	switch n := n.(type) {
+	case *parsetree.DotNode:
+		return dot
+	case *parsetree.FieldNode:
+		return l.resolve(dot, n.Ident, n)
+	case *parsetree.VariableNode:
+		t, known := vars[n.Ident[0]]
+		if !known {
+			return nil
+		}
+		return l.resolve(t, n.Ident[1:], n)
+	case *parsetree.ChainNode:
+		return l.resolve(l.arg(n.Node, dot, vars), n.Field, n)
+	case *parsetree.PipeNode:
+		return l.pipe(n, dot, maps.Clone(vars), false)
+	}
+	return nil
+}
+
+// resolve follows the field and method names from t, reporting the first
+// that doesn't exist.
+func (l *linter) resolve(t reflect.Type, names []string, n parsetree.Node) reflect.Type {
+	for _, name := range names {
+		if t == nil {
+			return nil
+		}
+		next, ok := member(t, name)
+		if !ok {
+			l.undefined(n, name, t)
+			return nil
+		}
+		t = next
+	}
+	return t
+}
+
+func (l *linter) undefined(n parsetree.Node, name string, t reflect.Type) {
+	location, _ := l.tree.ErrorContext(n)
+	line := 0
+	if parts := strings.Split(location, ":"); len(parts) >= 2 {
+		line, _ = strconv.Atoi(parts[1])
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	owner := t.String()
+	if t == l.root {
+		owner = "the template data"
+	}
+	l.issues = append(l.issues, Issue{Kind: IssueUndefined, Line: line, Message: fmt.Sprintf("%s has no field or method %s", owner, name)})
+}
+
+// member returns the type of t's field or method name. Map keys and
+// anything reached through an interface are taken on trust, with a nil
+// type.
+func member(t reflect.Type, name string) (reflect.Type, bool) {
+	if m, ok := t.MethodByName(name); ok {
+		return result(m.Type), true
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if m, ok := reflect.PointerTo(t).MethodByName(name); ok {
+		return result(m.Type), true
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if f, ok := t.FieldByName(name); ok && f.IsExported() {
+			return f.Type, true
+		}
+		return nil, false
+	case reflect.Map:
+		return t.Elem(), true
+	case reflect.Interface:
+		return nil, true
+	}
+	return nil, false
+}
+
+func result(method reflect.Type) reflect.Type {
+	if method.NumOut() == 0 {
+		return nil
+	}
+	return method.Out(0)
+}
+
+// elem returns the type of the values ranging over t yields.
+func elem(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return t.Elem()
+	case reflect.Int:
+		return t
+	}
+	return nil
+}
+
+// key returns the type of the keys ranging over t yields.
+func key(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.TypeOf(0)
+	case reflect.Map:
+		return t.Key()
+	}
+	return nil
+}
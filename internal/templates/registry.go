@@ -0,0 +1,388 @@
+// Package templates manages the named HTML templates results are rendered
+// with, and which template each county or content type uses. Templates live
+// as <name>.html files in a directory and are reloaded when they change on
+// disk, so they can be edited without a restart.
+package templates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/formatter"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/store"
+)
+
+// ErrNotFound is returned for templates that don't exist.
+var ErrNotFound = errors.New("template not found")
+
+// ErrInUse is returned when deleting a template that is still assigned.
+var ErrInUse = errors.New("template is assigned")
+
+const assignmentsFile = "assignments.json"
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Data is what templates are executed with. Content is the standard results
+// fragment, so page templates can simply wrap it; the embedded Results allow
+// templates to lay out contests themselves.
+type Data struct {
+	Title   string
+	Content template.HTML
+	*models.Results
+}
+
+// Info describes a stored template.
+type Info struct {
+	Name      string    `json:"name"`
+	Source    string    `json:"source,omitempty"`
+	Size      int       `json:"size"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Assignments selects the template used for a snapshot: a county assignment
+// wins over a content type one. Sources with neither get the plain fragment.
+type Assignments struct {
+	Counties     map[string]string `json:"counties"`     // county key → template
+	ContentTypes map[string]string `json:"contentTypes"` // content type → template
+}
+
+type entry struct {
+	info Info
+	tmpl *template.Template
+}
+
+// Registry is the set of templates in a directory.
+type Registry struct {
+	dir    string
+	logger *slog.Logger
+
+	mu          sync.RWMutex
+	templates   map[string]entry
+	assignments Assignments
+	fingerprint string
+}
+
+// Open loads every template and the assignments stored in dir, creating the
+// directory if needed.
+func Open(dir string, logger *slog.Logger) (*Registry, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create template directory: %w", err)
+	}
+	r := &Registry{dir: dir, logger: logger}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload rereads the directory. Templates that fail to parse are skipped
+// and logged, keeping the rest usable.
+func (r *Registry) Reload() error {
+	files, err := filepath.Glob(filepath.Join(r.dir, "*.html"))
+	if err != nil {
+		return fmt.Errorf("list templates: %w", err)
+	}
+
+	current := fingerprint(r.dir)
+	loaded := make(map[string]entry, len(files))
+	for _, path := range files {
+		name := strings.TrimSuffix(filepath.Base(path), ".html")
+		if !validName.MatchString(name) {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			r.logger.Error("failed to read template", "path", path, "error", err)
+			continue
+		}
+		tmpl, err := parse(name, string(src))
+		if err != nil {
+			r.logger.Error("failed to parse template", "path", path, "error", err)
+			continue
+		}
+		loaded[name] = entry{
+			info: Info{Name: name, Source: string(src), Size: len(src), UpdatedAt: fi.ModTime().UTC()},
+			tmpl: tmpl,
+		}
+	}
+
+	var a Assignments
+	path := filepath.Join(r.dir, assignmentsFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &a); err != nil {
+			return fmt.Errorf("decode template assignments: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("read template assignments: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates = loaded
+	r.assignments = a.normalized()
+	r.fingerprint = current
+	return nil
+}
+
+// Watch reloads the directory every interval if files have changed, until
+// ctx is done.
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.mu.RLock()
+			unchanged := r.fingerprint == fingerprint(r.dir)
+			r.mu.RUnlock()
+			if unchanged {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				r.logger.Error("failed to reload templates", "error", err)
+				continue
+			}
+			r.logger.Info("templates reloaded", "dir", r.dir)
+		}
+	}
+}
+
+// fingerprint summarizes the names, sizes and modification times of the
+// files in dir, so changes can be detected without reading them.
+func fingerprint(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.html"))
+	files = append(files, filepath.Join(dir, assignmentsFile))
+
+	var b strings.Builder
+	for _, path := range files {
+		if fi, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d;", fi.Name(), fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+	return b.String()
+}
+
+// List returns every template, without sources, sorted by name.
+func (r *Registry) List() []Info {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Info, 0, len(r.templates))
+	for _, e := range r.templates {
+		info := e.info
+		info.Source = ""
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Get returns a template with its source.
+func (r *Registry) Get(name string) (Info, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.templates[name]
+	if !ok {
+		return Info{}, ErrNotFound
+	}
+	return e.info, nil
+}
+
+// ValidName reports whether name can be used for a template.
+func ValidName(name string) bool {
+	return validName.MatchString(name)
+}
+
+// Save parses source and, if it is valid, writes it as template name. It
+// reports whether the template is new.
+func (r *Registry) Save(name, source string) (bool, error) {
+	if !validName.MatchString(name) {
+		return false, fmt.Errorf("invalid template name %q", name)
+	}
+	tmpl, err := parse(name, source)
+	if err != nil {
+		return false, err
+	}
+	if err := tmpl.Execute(io.Discard, sampleData()); err != nil {
+		return false, fmt.Errorf("template fails on sample results: %w", err)
+	}
+
+	_, err = r.Get(name)
+	created := errors.Is(err, ErrNotFound)
+	if err := writeFile(filepath.Join(r.dir, name+".html"), []byte(source)); err != nil {
+		return false, fmt.Errorf("write template: %w", err)
+	}
+	return created, r.Reload()
+}
+
+// Delete removes a template that is not assigned to anything.
+func (r *Registry) Delete(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.templates[name]; !ok {
+		return ErrNotFound
+	}
+	if r.assignments.uses(name) {
+		return ErrInUse
+	}
+	if err := os.Remove(filepath.Join(r.dir, name+".html")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete template: %w", err)
+	}
+	delete(r.templates, name)
+	return nil
+}
+
+// Assignments returns the current template assignments.
+func (r *Registry) Assignments() Assignments {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.assignments.normalized()
+}
+
+// SetAssignments replaces the assignments. Every referenced template must
+// exist.
+func (r *Registry) SetAssignments(a Assignments) error {
+	a = a.normalized()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range []map[string]string{a.Counties, a.ContentTypes} {
+		for _, name := range m {
+			if _, ok := r.templates[name]; !ok {
+				return fmt.Errorf("%w: %s", ErrNotFound, name)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.dir, assignmentsFile)
+	if err := writeFile(path, data); err != nil {
+		return fmt.Errorf("write template assignments: %w", err)
+	}
+	r.assignments = a
+	return nil
+}
+
+// Render renders results with the template assigned to its county or
+// content type. fragment is the standard results fragment; it is returned
+// unchanged when no template is assigned.
+func (r *Registry) Render(results *models.Results, fragment string) (string, error) {
+	r.mu.RLock()
+	name, ok := r.assignments.Counties[store.CountyKey(results.County)]
+	if !ok {
+		name, ok = r.assignments.ContentTypes[results.ContentType]
+	}
+	e, found := r.templates[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return fragment, nil
+	}
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return execute(e, results, fragment)
+}
+
+// RenderWith renders results with the template name, whatever is assigned.
+func (r *Registry) RenderWith(name string, results *models.Results, fragment string) (string, error) {
+	r.mu.RLock()
+	e, found := r.templates[name]
+	r.mu.RUnlock()
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return execute(e, results, fragment)
+}
+
+func execute(e entry, results *models.Results, fragment string) (string, error) {
+	var buf bytes.Buffer
+	if err := e.tmpl.Execute(&buf, Data{
+		Title:   results.County + " Results",
+		Content: template.HTML(fragment),
+		Results: results,
+	}); err != nil {
+		return "", fmt.Errorf("render template %s: %w", e.info.Name, err)
+	}
+	return buf.String(), nil
+}
+
+func parse(name, source string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(formatter.Funcs()).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// sampleData is used to catch templates that parse but fail at execution,
+// e.g. by referencing fields that don't exist.
+func sampleData() Data {
+	results := &models.Results{
+		County:      "Sample",
+		ContentType: models.ContentTypeCandidate,
+		Contests: []models.Contest{{
+			ID:         "sample",
+			Title:      "Sample Contest",
+			Candidates: []models.Candidate{{Name: "Candidate A", Votes: 10}},
+		}},
+	}
+	return Data{Title: "Sample Results", Content: "<div></div>", Results: results}
+}
+
+// writeFile replaces path atomically so the watcher never sees a partial
+// file.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (a Assignments) normalized() Assignments {
+	out := Assignments{Counties: make(map[string]string), ContentTypes: make(map[string]string)}
+	for county, name := range a.Counties {
+		out.Counties[store.CountyKey(county)] = name
+	}
+	for ct, name := range a.ContentTypes {
+		out.ContentTypes[ct] = name
+	}
+	return out
+}
+
+func (a Assignments) uses(name string) bool {
+	for _, m := range []map[string]string{a.Counties, a.ContentTypes} {
+		for _, n := range m {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
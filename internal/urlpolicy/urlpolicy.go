@@ -0,0 +1,205 @@
+// Package urlpolicy decides which source URLs the server may fetch. File
+// links come from API callers, so without a policy anyone holding a key
+// could have the server request internal addresses on their behalf: cloud
+// metadata endpoints, admin consoles, services on the private network.
+//
+// Hosts are checked against the URL before a request is made, and the
+// addresses they resolve to are checked again as each connection is dialed,
+// so a name that resolves to a public address at check time and a private
+// one at connect time is still refused.
+package urlpolicy
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// ErrBlocked is returned for a URL or address the policy refuses.
+var ErrBlocked = errors.New("blocked by fetch policy")
+
+// Config configures a Policy.
+type Config struct {
+	// Schemes are the URL schemes that may be fetched. Empty means https
+	// only.
+	Schemes []string
+	// Allow, when not empty, restricts fetching to these hosts. An entry is
+	// a host name, a "*.example.gov" pattern matching any subdomain, an IP
+	// address or a CIDR range. Allowed ranges may be fetched even when
+	// private.
+	Allow []string
+	// Deny lists hosts never fetched, in the same forms as Allow. Deny wins
+	// over Allow.
+	Deny []string
+	// AllowPrivate permits loopback, private, link-local and other
+	// non-public addresses, for development against local servers.
+	AllowPrivate bool
+}
+
+// Policy is a compiled Config. A nil Policy allows everything.
+type Policy struct {
+	schemes      []string
+	allowHosts   []string
+	allowNets    []netip.Prefix
+	denyHosts    []string
+	denyNets     []netip.Prefix
+	allowPrivate bool
+}
+
+// Default is the policy used when a deployment configures none: https to
+// public addresses only.
+func Default() *Policy {
+	return &Policy{schemes: []string{"https"}}
+}
+
+// New compiles c.
+func New(c Config) (*Policy, error) {
+	p := &Policy{allowPrivate: c.AllowPrivate}
+	for _, s := range c.Schemes {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			p.schemes = append(p.schemes, s)
+		}
+	}
+	if len(p.schemes) == 0 {
+		p.schemes = []string{"https"}
+	}
+	var err error
+	if p.allowHosts, p.allowNets, err = compile(c.Allow); err != nil {
+		return nil, fmt.Errorf("allow list: %w", err)
+	}
+	if p.denyHosts, p.denyNets, err = compile(c.Deny); err != nil {
+		return nil, fmt.Errorf("deny list: %w", err)
+	}
+	return p, nil
+}
+
+// compile splits entries into host patterns and address ranges.
+func compile(entries []string) ([]string, []netip.Prefix, error) {
+	var hosts []string
+	var nets []netip.Prefix
+	for _, e := range entries {
+		e = strings.ToLower(strings.TrimSpace(e))
+		switch {
+		case e == "":
+		case strings.Contains(e, "/"):
+			prefix, err := netip.ParsePrefix(e)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid range %q: %w", e, err)
+			}
+			nets = append(nets, prefix.Masked())
+		default:
+			if addr, err := netip.ParseAddr(e); err == nil {
+				nets = append(nets, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+				continue
+			}
+			if strings.Contains(strings.TrimPrefix(e, "*."), "*") {
+				return nil, nil, fmt.Errorf("invalid host pattern %q: only a leading *. is supported", e)
+			}
+			hosts = append(hosts, e)
+		}
+	}
+	return hosts, nets, nil
+}
+
+// CheckURL reports whether raw may be fetched, judging by its scheme and
+// host. Names are resolved only when dialing, where Control checks the
+// addresses.
+func (p *Policy) CheckURL(raw string) error {
+	if p == nil {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: invalid URL: %v", ErrBlocked, err)
+	}
+	if !slices.Contains(p.schemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("%w: scheme %q is not allowed (allowed: %s)", ErrBlocked, u.Scheme, strings.Join(p.schemes, ", "))
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: URL has no host", ErrBlocked)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if len(p.allowHosts) > 0 || len(p.allowNets) > 0 {
+			if !inNets(addr.Unmap(), p.allowNets) {
+				return fmt.Errorf("%w: address %s is not on the allow list", ErrBlocked, addr)
+			}
+		}
+		return p.CheckAddr(addr)
+	}
+	if matchHost(host, p.denyHosts) {
+		return fmt.Errorf("%w: host %s is denied", ErrBlocked, host)
+	}
+	if len(p.allowHosts) > 0 || len(p.allowNets) > 0 {
+		if !matchHost(host, p.allowHosts) {
+			return fmt.Errorf("%w: host %s is not on the allow list", ErrBlocked, host)
+		}
+	}
+	return nil
+}
+
+// CheckAddr reports whether a connection to addr is allowed.
+func (p *Policy) CheckAddr(addr netip.Addr) error {
+	if p == nil {
+		return nil
+	}
+	addr = addr.Unmap()
+	if inNets(addr, p.denyNets) {
+		return fmt.Errorf("%w: address %s is denied", ErrBlocked, addr)
+	}
+	if !p.allowPrivate && !Public(addr) && !inNets(addr, p.allowNets) {
+		return fmt.Errorf("%w: address %s is not public", ErrBlocked, addr)
+	}
+	return nil
+}
+
+// Control checks the address a connection is about to be made to. Set it
+// as a net.Dialer's Control so every dial, including those for redirects,
+// is checked after name resolution.
+func (p *Policy) Control(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unexpected dial address %q", ErrBlocked, address)
+	}
+	return p.CheckAddr(ap.Addr())
+}
+
+// sharedSpace is the carrier-grade NAT range, which netip doesn't count as
+// private but is no more reachable from the internet.
+var sharedSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Public reports whether addr is a globally routable unicast address.
+func Public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedSpace.Contains(addr)
+}
+
+func inNets(addr netip.Addr, nets []netip.Prefix) bool {
+	for _, n := range nets {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost reports whether host is one of patterns, or a subdomain of a
+// "*." pattern.
+func matchHost(host string, patterns []string) bool {
+	for _, pat := range patterns {
+		if suffix, ok := strings.CutPrefix(pat, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pat {
+			return true
+		}
+	}
+	return false
+}
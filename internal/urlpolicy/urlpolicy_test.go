@@ -0,0 +1,102 @@
+package urlpolicy
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		url     string
+		allowed bool
+	}{
+		{"https", Config{}, "https://results.example.gov/summary.xml", true},
+		{"http by default", Config{}, "http://results.example.gov/summary.xml", false},
+		{"http allowed", Config{Schemes: []string{"HTTPS", " http "}}, "http://results.example.gov/", true},
+		{"ftp", Config{}, "ftp://results.example.gov/summary.txt", false},
+		{"file", Config{}, "file:///etc/passwd", false},
+		{"no host", Config{}, "https:///summary.xml", false},
+		{"invalid", Config{}, "https://[::1", false},
+		{"public address", Config{}, "https://93.184.216.34/", true},
+		{"loopback", Config{}, "https://127.0.0.1/", false},
+		{"loopback v6", Config{}, "https://[::1]/", false},
+		{"mapped loopback", Config{}, "https://[::ffff:127.0.0.1]/", false},
+		{"private", Config{}, "https://10.1.2.3/", false},
+		{"private v6", Config{}, "https://[fd00::1]/", false},
+		{"link local metadata", Config{}, "http://169.254.169.254/latest/meta-data/", false},
+		{"shared address space", Config{}, "https://100.64.0.1/", false},
+		{"unspecified", Config{}, "https://0.0.0.0/", false},
+		{"private allowed", Config{AllowPrivate: true}, "https://10.1.2.3/", true},
+		{"allowed range", Config{Allow: []string{"10.1.0.0/16"}}, "https://10.1.2.3/", true},
+		{"outside allowed range", Config{Allow: []string{"10.1.0.0/16"}}, "https://10.2.0.1/", false},
+		{"allowed host", Config{Allow: []string{"results.example.gov"}}, "https://Results.Example.gov./", true},
+		{"host not allowed", Config{Allow: []string{"results.example.gov"}}, "https://other.example.gov/", false},
+		{"address with allowed hosts", Config{Allow: []string{"results.example.gov"}}, "https://93.184.216.34/", false},
+		{"allowed subdomain", Config{Allow: []string{"*.example.gov"}}, "https://adams.example.gov/", true},
+		{"wildcard needs a subdomain", Config{Allow: []string{"*.example.gov"}}, "https://example.gov/", false},
+		{"wildcard suffix only", Config{Allow: []string{"*.example.gov"}}, "https://badexample.gov/", false},
+		{"denied host", Config{Deny: []string{"results.example.gov"}}, "https://results.example.gov/", false},
+		{"deny wins", Config{Allow: []string{"*.example.gov"}, Deny: []string{"admin.example.gov"}}, "https://admin.example.gov/", false},
+		{"denied address", Config{Deny: []string{"93.184.216.0/24"}}, "https://93.184.216.34/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.CheckURL(tt.url)
+			if tt.allowed && err != nil {
+				t.Fatalf("CheckURL(%q): %v", tt.url, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrBlocked) {
+				t.Fatalf("CheckURL(%q) = %v, want ErrBlocked", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestControl(t *testing.T) {
+	p, err := New(Config{Allow: []string{"results.example.gov", "10.1.0.0/16"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"10.1.2.3:443", true},
+		{"10.2.0.1:443", false},
+		{"127.0.0.1:443", false},
+		{"[::ffff:169.254.169.254]:80", false},
+		{"results.example.gov:443", false},
+	}
+	for _, tt := range tests {
+		err := p.Control("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("Control(%q): %v", tt.address, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrBlocked) {
+			t.Errorf("Control(%q) = %v, want ErrBlocked", tt.address, err)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, entry := range []string{"10.0.0.0/33", "a.*.example.gov", "**.example.gov"} {
+		if _, err := New(Config{Allow: []string{entry}}); err == nil {
+			t.Errorf("New accepted allow entry %q", entry)
+		}
+	}
+	var p *Policy
+	if err := p.CheckURL("gopher://127.0.0.1/"); err != nil {
+		t.Errorf("nil policy refused a URL: %v", err)
+	}
+	if err := p.CheckAddr(netip.MustParseAddr("127.0.0.1")); err != nil {
+		t.Errorf("nil policy refused an address: %v", err)
+	}
+}
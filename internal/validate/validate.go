@@ -0,0 +1,583 @@
+// Package validate checks request bodies before the API acts on them. Each
+// check collects every invalid field rather than stopping at the first, so
+// a client sees everything it has to fix in one response.
+package validate
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/many221/era_api_v1/internal/measures"
+	"github.com/many221/era_api_v1/internal/models"
+	"github.com/many221/era_api_v1/internal/parser"
+)
+
+// parseMethods are the accepted values of a parseMethod field.
+func parseMethods() []string {
+	return append(parser.Methods(), models.ParseMethodAuto)
+}
+
+// ContentTypes are the accepted values of a contentType field. Empty means
+// candidate.
+var ContentTypes = []string{models.ContentTypeCandidate, models.ContentTypeMeasure}
+
+// Census codes of jurisdictions. Codes below the county, such as voting
+// districts', may have letters.
+var (
+	stateFIPS  = regexp.MustCompile(`^[0-9]{2}$`)
+	countyFIPS = regexp.MustCompile(`^[0-9]{5}$`)
+	geoID      = regexp.MustCompile(`^[0-9A-Za-z]+$`)
+)
+
+// Error reports the invalid fields of a request body.
+type Error struct {
+	Fields []models.FieldError
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// checker accumulates field errors.
+type checker struct {
+	fields []models.FieldError
+}
+
+func (c *checker) add(field, format string, args ...any) {
+	c.fields = append(c.fields, models.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// required reports whether value is non-blank, noting field if not.
+func (c *checker) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		c.add(field, "is required")
+		return false
+	}
+	return true
+}
+
+// oneOf notes field unless value, compared case-insensitively, is empty or
+// one of allowed.
+func (c *checker) oneOf(field, value string, allowed []string) {
+	if value != "" && !slices.Contains(allowed, strings.ToLower(value)) {
+		c.add(field, "must be one of %s", strings.Join(allowed, ", "))
+	}
+}
+
+// sourceURL notes field unless value is an absolute http, https, ftp, s3
+// or gs URL. sftp is refused with its own message, as counties ask for it
+// and the fetcher has no SSH client (see fetcher.ErrSFTP).
+func (c *checker) sourceURL(field, value string) {
+	if !c.required(field, value) {
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(value))
+	switch {
+	case err != nil:
+		c.add(field, "is not a valid URL")
+	case u.Scheme == "sftp":
+		c.add(field, "sftp isn't supported, as the server has no SSH client; use ftp or https")
+	case u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ftp" && u.Scheme != "s3" && u.Scheme != "gs":
+		c.add(field, "must be an http, https, ftp, s3 or gs URL")
+	case u.Host == "":
+		c.add(field, "must include a host")
+	case u.User != nil:
+		c.add(field, "must not contain credentials")
+	}
+}
+
+func (c *checker) threshold(field, value string) {
+	if err := measures.ValidThreshold(value); err != nil {
+		c.add(field, "%v", err)
+	}
+}
+
+func (c *checker) license(field string, l *models.License) {
+	if l == nil {
+		return
+	}
+	c.required(field+".name", l.Name)
+	if l.URL != "" {
+		if u, err := url.Parse(l.URL); err != nil || !u.IsAbs() {
+			c.add(field+".url", "must be an absolute URL")
+		}
+	}
+}
+
+// browser notes field if a headless browser is asked to load a link that
+// isn't a web page.
+func (c *checker) browser(field, link string, b *models.BrowserFetch) {
+	if b == nil {
+		return
+	}
+	if u, err := url.Parse(strings.TrimSpace(link)); err == nil && u.Scheme != "http" && u.Scheme != "https" {
+		c.add(field, "needs an http or https fileLink")
+	}
+}
+
+func (c *checker) emailRule(field string, r *models.EmailRule) {
+	if r == nil {
+		return
+	}
+	if len(r.From) == 0 {
+		c.add(field+".from", "is required")
+	}
+	for i, from := range r.From {
+		from = strings.TrimSpace(from)
+		if domain, ok := strings.CutPrefix(from, "@"); ok {
+			if !strings.Contains(domain, ".") || strings.ContainsAny(domain, "@ ") {
+				c.add(fmt.Sprintf("%s.from[%d]", field, i), "must be an address or a domain such as %q", "@example.gov")
+			}
+			continue
+		}
+		c.email(fmt.Sprintf("%s.from[%d]", field, i), from)
+		if from == "" {
+			c.add(fmt.Sprintf("%s.from[%d]", field, i), "must not be empty")
+		}
+	}
+	if _, err := path.Match(r.Attachment, ""); err != nil {
+		c.add(field+".attachment", "must be a glob such as %q", "results*.xml")
+	}
+}
+
+// email notes field unless value is empty or looks like an email address.
+func (c *checker) email(field, value string) {
+	if value == "" {
+		return
+	}
+	if local, domain, ok := strings.Cut(value, "@"); !ok || local == "" || !strings.Contains(domain, ".") {
+		c.add(field, "must be an email address")
+	}
+}
+
+// phone notes field unless value is empty or has enough digits to dial.
+func (c *checker) phone(field, value string) {
+	if value == "" {
+		return
+	}
+	digits := 0
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	if digits < 7 {
+		c.add(field, "must be a phone number")
+	}
+}
+
+// maxLabelLen bounds dataset labels, which appear in URLs.
+const maxLabelLen = 64
+
+// dataset notes field unless value is empty or a label results can be
+// staged under: lowercase letters, digits and dashes, and not the label
+// reserved for displaced snapshots.
+func (c *checker) dataset(field, value string) {
+	switch {
+	case value == "":
+	case value != models.Slug(value) || len(value) > maxLabelLen:
+		c.add(field, "must be at most %d lowercase letters, digits and dashes", maxLabelLen)
+	case value == models.DatasetPrevious:
+		c.add(field, "%q is reserved for the snapshots a switch displaced", value)
+	}
+}
+
+// sourceLevel notes field unless value is empty or a source level: a
+// short name of lowercase letters, digits and dashes, such as "state".
+func (c *checker) sourceLevel(field, value string) {
+	if value != "" && (value != models.Slug(value) || len(value) > maxLabelLen) {
+		c.add(field, "must be a source level such as %q or %q: at most %d lowercase letters, digits and dashes", models.SourceLevelCounty, models.SourceLevelState, maxLabelLen)
+	}
+}
+
+func (c *checker) err() error {
+	if len(c.fields) == 0 {
+		return nil
+	}
+	return &Error{Fields: c.fields}
+}
+
+// ProcessRequest checks a body of POST /api/v1/process.
+func ProcessRequest(req models.ProcessRequest) error {
+	var c checker
+	if req.Jurisdiction == "" {
+		c.required("countyName", req.CountyName)
+	}
+	c.sourceURL("fileLink", req.FileLink)
+	c.browser("browser", req.FileLink, req.Browser)
+	c.processRequest(req)
+	return c.err()
+}
+
+// Upload checks the fields of a POST /api/v1/upload form, whose source is
+// the uploaded file of size bytes rather than a fileLink.
+func Upload(req models.ProcessRequest, size int64) error {
+	var c checker
+	if req.Jurisdiction == "" {
+		c.required("countyName", req.CountyName)
+	}
+	if size == 0 {
+		c.add("file", "is required")
+	}
+	c.processRequest(req)
+	return c.err()
+}
+
+// processRequest checks the fields a process request has however its
+// source arrives.
+func (c *checker) processRequest(req models.ProcessRequest) {
+	if c.required("parseMethod", req.ParseMethod) {
+		c.oneOf("parseMethod", req.ParseMethod, parseMethods())
+	}
+	c.oneOf("contentType", req.ContentType, ContentTypes)
+	c.threshold("measureThreshold", req.MeasureThreshold)
+	c.license("license", req.License)
+	c.dataset("dataset", req.Dataset)
+	c.sourceLevel("sourceLevel", req.SourceLevel)
+}
+
+// CountySource checks a body of POST /api/v1/counties.
+func CountySource(s models.CountySource) error {
+	var c checker
+	c.required("name", s.Name)
+	// A county that only emails its results has no link to refresh.
+	if s.Email == nil || s.FileLink != "" || s.Watch != nil || s.Browser != nil {
+		c.sourceURL("fileLink", s.FileLink)
+	}
+	if c.required("parseMethod", s.ParseMethod) {
+		c.oneOf("parseMethod", s.ParseMethod, parseMethods())
+	}
+	c.oneOf("contentType", s.ContentType, ContentTypes)
+	if s.IntervalSeconds < 0 {
+		c.add("intervalSeconds", "must not be negative")
+	}
+	for i, w := range s.Schedule {
+		field := fmt.Sprintf("schedule[%d]", i)
+		if w.IntervalSeconds <= 0 {
+			c.add(field+".intervalSeconds", "must be positive")
+		}
+		if w.From.IsZero() || w.Until.IsZero() {
+			c.add(field, "needs from and until times such as %q", "2026-11-03T20:00:00-08:00")
+		} else if !w.Until.After(w.From) {
+			c.add(field+".until", "must be after from")
+		}
+	}
+	c.threshold("measureThreshold", s.MeasureThreshold)
+	c.license("license", s.License)
+	c.sourceLevel("precedence", s.Precedence)
+	if s.Watch != nil && c.required("watch.pattern", s.Watch.Pattern) {
+		if _, err := path.Match(s.Watch.Pattern, ""); err != nil {
+			c.add("watch.pattern", "must be a glob such as %q", "results_*.zip")
+		}
+	}
+	c.browser("browser", s.FileLink, s.Browser)
+	if s.Browser != nil && s.Watch != nil {
+		c.add("browser", "can't be combined with watch")
+	}
+	c.emailRule("email", s.Email)
+	return c.err()
+}
+
+// StateFeedRequest checks a body of POST /api/v1/state-feeds/process.
+func StateFeedRequest(req models.StateFeedRequest) error {
+	var c checker
+	c.sourceURL("fileLink", req.FileLink)
+	if c.required("format", req.Format) {
+		c.oneOf("format", req.Format, models.StateFeedFormats)
+	}
+	c.oneOf("contentType", req.ContentType, ContentTypes)
+	for i, county := range req.Counties {
+		if strings.TrimSpace(county) == "" {
+			c.add(fmt.Sprintf("counties[%d]", i), "must not be empty")
+		}
+	}
+	c.threshold("measureThreshold", req.MeasureThreshold)
+	c.license("license", req.License)
+	return c.err()
+}
+
+// Election checks a body of POST /api/v1/elections.
+func Election(e models.Election) error {
+	var c checker
+	if c.required("id", e.ID) && (e.ID != models.Slug(e.ID) || len(e.ID) > maxLabelLen) {
+		c.add("id", "must be at most %d lowercase letters, digits and dashes", maxLabelLen)
+	}
+	if e.Date != "" {
+		if _, err := time.Parse(time.DateOnly, e.Date); err != nil {
+			c.add("date", "must be a date such as 2026-11-03")
+		}
+	}
+	c.oneOf("type", e.Type, models.ElectionTypes)
+	return c.err()
+}
+
+// LegalHold checks a legal hold before it is placed.
+func LegalHold(h models.LegalHold) error {
+	var c checker
+	c.required("reason", h.Reason)
+	return c.err()
+}
+
+// Jurisdiction checks a jurisdiction before it is added to the tree.
+func Jurisdiction(j models.Jurisdiction) error {
+	var c checker
+	c.required("name", j.Name)
+	if c.required("type", j.Type) {
+		c.oneOf("type", j.Type, models.JurisdictionTypes)
+	}
+	if j.ID != "" && models.Slug(j.ID) != j.ID {
+		c.add("id", "must be lowercase letters, digits and hyphens")
+	}
+	if j.Type == models.JurisdictionCounty && j.ID != "" && j.ID != models.Slug(j.Name) {
+		c.add("id", "of a county must be its county key, %q", models.Slug(j.Name))
+	}
+	if j.FIPS != "" {
+		switch {
+		case j.Type == models.JurisdictionState && !stateFIPS.MatchString(j.FIPS):
+			c.add("fips", "of a state must be 2 digits")
+		case j.Type == models.JurisdictionCounty && !countyFIPS.MatchString(j.FIPS):
+			c.add("fips", "of a county must be 5 digits, its state's and its own")
+		case !geoID.MatchString(j.FIPS):
+			c.add("fips", "must be letters and digits")
+		}
+	}
+	return c.err()
+}
+
+// Districts checks imported districts: each needs a name, a type and at
+// least one precinct, each of those with its county and listed once.
+func Districts(list []models.District) error {
+	var c checker
+	for i, d := range list {
+		at := fmt.Sprintf("[%d].", i)
+		c.required(at+"name", d.Name)
+		if c.required(at+"type", d.Type) {
+			c.oneOf(at+"type", d.Type, models.DistrictTypes)
+		}
+		if d.ID != "" && models.Slug(d.ID) != d.ID {
+			c.add(at+"id", "must be lowercase letters, digits and hyphens")
+		}
+		if len(d.Precincts) == 0 {
+			c.add(at+"precincts", "must list at least one precinct")
+		}
+		seen := make(map[models.DistrictPrecinct]bool)
+		for j, p := range d.Precincts {
+			county := c.required(fmt.Sprintf("%sprecincts[%d].county", at, j), p.County)
+			precinct := c.required(fmt.Sprintf("%sprecincts[%d].precinct", at, j), p.Precinct)
+			if !county || !precinct {
+				continue
+			}
+			key := models.DistrictPrecinct{County: models.Slug(p.County), Precinct: strings.ToLower(strings.TrimSpace(p.Precinct))}
+			if seen[key] {
+				c.add(fmt.Sprintf("%sprecincts[%d]", at, j), "is listed more than once")
+			}
+			seen[key] = true
+		}
+	}
+	return c.err()
+}
+
+// MeasureLink checks a measure link: a title and the contests of at least
+// two different counties.
+func MeasureLink(l models.MeasureLink) error {
+	var c checker
+	c.required("title", l.Title)
+	if len(l.Members) < 2 {
+		c.add("members", "must list at least two counties' contests")
+	}
+	seen := make(map[string]bool)
+	for i, m := range l.Members {
+		if c.required(fmt.Sprintf("members[%d].county", i), m.County) {
+			if seen[models.Slug(m.County)] {
+				c.add(fmt.Sprintf("members[%d].county", i), "is listed more than once")
+			}
+			seen[models.Slug(m.County)] = true
+		}
+		c.required(fmt.Sprintf("members[%d].contestId", i), m.ContestID)
+	}
+	return c.err()
+}
+
+// ContestRule checks a contest rule, whether posted or loaded from a file.
+func ContestRule(r models.ContestRule) error {
+	var c checker
+	patternSet := c.required("pattern", r.Pattern)
+	c.required("canonicalId", r.CanonicalID)
+	switch r.Match {
+	case models.RuleExact, models.RulePrefix, models.RuleContains:
+	case models.RuleRegex:
+		if _, err := regexp.Compile(r.Pattern); err != nil && patternSet {
+			c.add("pattern", "is not a valid regular expression: %v", err)
+		}
+	default:
+		c.add("match", "must be one of exact, prefix, contains, regex")
+	}
+	if r.Threshold != "" {
+		c.threshold("threshold", r.Threshold)
+	}
+	return c.err()
+}
+
+// Overlay checks a body of POST .../overlays. An empty kind means other.
+func Overlay(o models.Overlay) error {
+	var c checker
+	switch o.Kind {
+	case "", models.OverlayExitPoll, models.OverlayForecast, models.OverlayOther:
+	default:
+		c.add("kind", "must be one of exit_poll, forecast, other")
+	}
+	c.required("label", o.Label)
+	c.required("source", o.Source)
+	if len(o.Entries) == 0 {
+		c.add("entries", "must have at least one entry")
+	}
+	for i, e := range o.Entries {
+		c.required(fmt.Sprintf("entries[%d].candidate", i), e.Candidate)
+		if e.Share < 0 || e.Share > 100 {
+			c.add(fmt.Sprintf("entries[%d].share", i), "must be a percentage between 0 and 100")
+		}
+		if e.MarginOfError != nil && *e.MarginOfError < 0 {
+			c.add(fmt.Sprintf("entries[%d].marginOfError", i), "must not be negative")
+		}
+	}
+	return c.err()
+}
+
+// Contact checks a body of PUT /api/v1/contacts/{county}. A contact is
+// only useful with some way to reach someone.
+func Contact(ct models.CountyContact) error {
+	var c checker
+	if ct.OfficePhone == "" && ct.OfficeEmail == "" && ct.ITPhone == "" && ct.ITEmail == "" {
+		c.add("officePhone", "or another phone or email is required")
+	}
+	c.phone("officePhone", ct.OfficePhone)
+	c.email("officeEmail", ct.OfficeEmail)
+	c.phone("itPhone", ct.ITPhone)
+	c.email("itEmail", ct.ITEmail)
+	return c.err()
+}
+
+// Credentials checks a body of PUT /api/v1/counties/{county}/credentials.
+// Line breaks are refused, as FTP sends the login as commands.
+func Credentials(cr models.SourceCredentials) error {
+	var c checker
+	c.required("username", cr.Username)
+	if strings.ContainsAny(cr.Username, "\r\n") {
+		c.add("username", "must not contain line breaks")
+	}
+	if strings.ContainsAny(cr.Password, "\r\n") {
+		c.add("password", "must not contain line breaks")
+	}
+	return c.err()
+}
+
+// Runbook checks a body of PUT /api/v1/runbooks/{type}, with the alert
+// type taken from the path.
+func Runbook(rb models.Runbook) error {
+	var c checker
+	c.oneOf("alertType", rb.AlertType, models.AlertTypes)
+	if rb.URL == "" && strings.TrimSpace(rb.Notes) == "" {
+		c.add("url", "or notes is required")
+	}
+	if rb.URL != "" {
+		if u, err := url.Parse(rb.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			c.add("url", "must be an http or https URL")
+		}
+	}
+	return c.err()
+}
+
+// SLA checks a body of POST /api/v1/slas.
+func SLA(s models.SLA) error {
+	var c checker
+	c.required("name", s.Name)
+	if s.MaxStalenessSeconds <= 0 {
+		c.add("maxStalenessSeconds", "must be positive")
+	}
+	for i, county := range s.Counties {
+		c.required(fmt.Sprintf("counties[%d]", i), county)
+	}
+	return c.err()
+}
+
+// Snippet checks a body of POST /api/v1/snippets.
+func Snippet(sn models.Snippet) error {
+	var c checker
+	c.required("name", sn.Name)
+	if len(sn.Contests) == 0 {
+		c.add("contests", "must have at least one contest")
+	}
+	for i, ref := range sn.Contests {
+		c.required(fmt.Sprintf("contests[%d].county", i), ref.County)
+		c.required(fmt.Sprintf("contests[%d].contestId", i), ref.ContestID)
+	}
+	return c.err()
+}
+
+// RenderRequest checks a body of POST /api/v1/render.
+func RenderRequest(req models.RenderRequest) error {
+	var c checker
+	c.required("county", req.County)
+	if len(req.Contests) == 0 {
+		c.add("contests", "must have at least one contest")
+	}
+	for i, ct := range req.Contests {
+		c.required(fmt.Sprintf("contests[%d].title", i), ct.Title)
+		for j, cand := range ct.Candidates {
+			c.required(fmt.Sprintf("contests[%d].candidates[%d].name", i, j), cand.Name)
+			if cand.Votes < 0 {
+				c.add(fmt.Sprintf("contests[%d].candidates[%d].votes", i, j), "must not be negative")
+			}
+		}
+	}
+	c.oneOf("contentType", req.ContentType, ContentTypes)
+	c.threshold("measureThreshold", req.MeasureThreshold)
+	c.license("license", req.License)
+	return c.err()
+}
+
+// Candidate checks a body of POST /api/v1/candidates.
+func Candidate(cand models.CanonicalCandidate) error {
+	var c checker
+	c.required("name", cand.Name)
+	return c.err()
+}
+
+// Aliases checks the aliases posted to POST /api/v1/candidates/{id}/aliases.
+func Aliases(aliases []string) error {
+	var c checker
+	if len(aliases) == 0 {
+		c.add("aliases", "must have at least one alias")
+	}
+	for i, a := range aliases {
+		c.required(fmt.Sprintf("aliases[%d]", i), a)
+	}
+	return c.err()
+}
+
+// Manifest checks an election manifest, whether imported through the API or
+// loaded from a file.
+func Manifest(m models.Manifest) error {
+	var c checker
+	if len(m.Contests) == 0 {
+		c.add("contests", "must have at least one contest")
+	}
+	for i, contest := range m.Contests {
+		field := fmt.Sprintf("contests[%d]", i)
+		c.required(field+".title", contest.Title)
+		c.oneOf(field+".type", contest.Type, ContentTypes)
+		for j, cand := range contest.Candidates {
+			c.required(fmt.Sprintf("%s.candidates[%d].name", field, j), cand.Name)
+		}
+	}
+	return c.err()
+}
@@ -0,0 +1,60 @@
+package visibility
+
+import "strings"
+
+// Select returns v with only the named fields kept. A field is a JSON
+// field name or a dotted path through nested objects and lists of them,
+// e.g. contests.candidates.votes; naming an object keeps all of it. Values
+// that can't be round-tripped through JSON are returned unchanged.
+func Select(v any, fields []string) any {
+	tree, ok := toTree(v)
+	if !ok {
+		return v
+	}
+	sel := make(selection)
+	for _, f := range fields {
+		sel.add(strings.Split(f, "."))
+	}
+	return sel.apply(tree)
+}
+
+// selection is the fields kept of an object, each with those kept of its
+// value; nil keeps all of it.
+type selection map[string]selection
+
+func (s selection) add(path []string) {
+	sub, seen := s[path[0]]
+	switch {
+	case len(path) == 1:
+		s[path[0]] = nil
+	case seen && sub == nil:
+		// Already kept whole.
+	default:
+		if sub == nil {
+			sub = make(selection)
+			s[path[0]] = sub
+		}
+		sub.add(path[1:])
+	}
+}
+
+func (s selection) apply(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			sub, ok := s[k]
+			if !ok {
+				delete(t, k)
+				continue
+			}
+			if sub != nil {
+				t[k] = sub.apply(child)
+			}
+		}
+	case []any:
+		for i := range t {
+			t[i] = s.apply(t[i])
+		}
+	}
+	return v
+}
@@ -0,0 +1,161 @@
+package visibility
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Profile shapes responses for a kind of consumer: which fields are
+// omitted, how floats are rounded and how candidate and contest lists are
+// ordered.
+type Profile struct {
+	Name string `json:"name"`
+
+	// Omit lists JSON field names dropped at any depth.
+	Omit []string `json:"omit,omitempty"`
+
+	// Decimals rounds every number to this many places when set.
+	Decimals *int `json:"decimals,omitempty"`
+
+	// CandidateOrder is "votes" (highest first), "name" or empty to keep
+	// ballot order. ContestOrder is "title" or empty.
+	CandidateOrder string `json:"candidateOrder,omitempty"`
+	ContestOrder   string `json:"contestOrder,omitempty"`
+}
+
+func intPtr(n int) *int { return &n }
+
+// builtinProfiles are available without configuration.
+var builtinProfiles = map[string]Profile{
+	"broadcast": {
+		Name:           "broadcast",
+		Omit:           []string{"overlays", "rawName", "rawTitle", "source", "html"},
+		Decimals:       intPtr(1),
+		CandidateOrder: "votes",
+	},
+	"web-embed": {
+		Name:           "web-embed",
+		Omit:           []string{"rawName", "rawTitle", "source"},
+		Decimals:       intPtr(1),
+		CandidateOrder: "votes",
+	},
+	"analyst": {
+		Name:         "analyst",
+		ContestOrder: "title",
+	},
+}
+
+// Profiles is the set of named response profiles.
+type Profiles struct {
+	byName map[string]Profile
+}
+
+// DefaultProfiles returns the built-in profiles.
+func DefaultProfiles() *Profiles {
+	p := &Profiles{byName: make(map[string]Profile, len(builtinProfiles))}
+	for name, prof := range builtinProfiles {
+		p.byName[name] = prof
+	}
+	return p
+}
+
+// LoadProfiles reads a JSON array of profiles from path, adding to or
+// overriding the built-in ones.
+func LoadProfiles(path string) (*Profiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read profiles: %w", err)
+	}
+	var list []Profile
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode profiles: %w", err)
+	}
+
+	p := DefaultProfiles()
+	for i, prof := range list {
+		if prof.Name == "" {
+			return nil, fmt.Errorf("profile %d: name is required", i)
+		}
+		p.byName[prof.Name] = prof
+	}
+	return p, nil
+}
+
+// Get returns the named profile.
+func (p *Profiles) Get(name string) (*Profile, bool) {
+	if p == nil {
+		return nil, false
+	}
+	prof, ok := p.byName[name]
+	if !ok {
+		return nil, false
+	}
+	return &prof, true
+}
+
+func (p *Profile) apply(v any) any {
+	omit := make(map[string]bool, len(p.Omit))
+	for _, f := range p.Omit {
+		omit[f] = true
+	}
+	return p.walk(v, "", omit)
+}
+
+func (p *Profile) walk(v any, key string, omit map[string]bool) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if omit[k] {
+				delete(t, k)
+				continue
+			}
+			t[k] = p.walk(child, k, omit)
+		}
+	case []any:
+		for i := range t {
+			t[i] = p.walk(t[i], "", omit)
+		}
+		p.order(key, t)
+	case float64:
+		if p.Decimals != nil {
+			scale := math.Pow(10, float64(*p.Decimals))
+			return math.Round(t*scale) / scale
+		}
+	}
+	return v
+}
+
+// order sorts candidate and contest lists in place according to the profile.
+func (p *Profile) order(key string, list []any) {
+	var by func(a, b map[string]any) bool
+	switch {
+	case key == "candidates" && p.CandidateOrder == "votes":
+		by = func(a, b map[string]any) bool { return num(a["votes"]) > num(b["votes"]) }
+	case key == "candidates" && p.CandidateOrder == "name":
+		by = func(a, b map[string]any) bool { return str(a["name"]) < str(b["name"]) }
+	case key == "contests" && p.ContestOrder == "title":
+		by = func(a, b map[string]any) bool { return str(a["title"]) < str(b["title"]) }
+	default:
+		return
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		a, aok := list[i].(map[string]any)
+		b, bok := list[j].(map[string]any)
+		return aok && bok && by(a, b)
+	})
+}
+
+func num(v any) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+func str(v any) string {
+	s, _ := v.(string)
+	return strings.ToUpper(s)
+}
@@ -0,0 +1,126 @@
+// Package visibility strips field groups from API responses and shapes them
+// with per-consumer profiles according to the caller's API key, so both are
+// enforced in one place at serialization time rather than in every handler.
+package visibility
+
+import (
+	"encoding/json"
+
+	"github.com/many221/era_api_v1/internal/models"
+)
+
+// Field groups that can be hidden, and the JSON fields each one covers.
+var groups = map[string][]string{
+	"precincts":  {"precinctsReporting", "precinctsTotal", "precincts", "reportingPercent"},
+	"provenance": {"source", "rawName", "rawTitle", "fileLink"},
+	"forecast":   {"forecast"},
+	"overlays":   {"overlays"},
+	"breakdown":  {"breakdown"},
+	"contacts":   {"contact"},
+	"latency":    {"latency"},
+	"anomalies":  {"anomalies"},
+}
+
+var presets = map[string][]string{
+	models.VisibilityFull:    nil,
+	models.VisibilityPartner: {"precincts", "contacts", "latency", "anomalies"},
+	models.VisibilityPublic:  {"precincts", "provenance", "contacts", "latency", "anomalies"},
+}
+
+// Policy is the set of JSON fields a caller must not see.
+type Policy struct {
+	hidden map[string]bool
+}
+
+// Known reports whether name is a valid preset.
+func Known(preset string) bool {
+	_, ok := presets[preset]
+	return ok
+}
+
+// KnownGroup reports whether name is a valid field group.
+func KnownGroup(name string) bool {
+	_, ok := groups[name]
+	return ok
+}
+
+// For builds the policy of an API key.
+func For(key models.APIKey) Policy {
+	return New(key.Visibility, key.Hide...)
+}
+
+// New builds a policy from a preset plus extra field groups.
+func New(preset string, extra ...string) Policy {
+	p := Policy{hidden: make(map[string]bool)}
+	for _, g := range append(append([]string(nil), presets[preset]...), extra...) {
+		for _, f := range groups[g] {
+			p.hidden[f] = true
+		}
+	}
+	return p
+}
+
+// Full reports whether the policy hides nothing.
+func (p Policy) Full() bool {
+	return len(p.hidden) == 0
+}
+
+// Hides reports whether the JSON field name is hidden by the policy, for
+// output formats that aren't produced from JSON.
+func (p Policy) Hides(field string) bool {
+	return p.hidden[field]
+}
+
+// Apply returns v with every hidden field removed at any depth. Values that
+// can't be round-tripped through JSON are returned unchanged.
+func (p Policy) Apply(v any) any {
+	return Shape(v, p, nil)
+}
+
+// Shape applies the visibility policy and then the optional profile to v.
+func Shape(v any, p Policy, prof *Profile) any {
+	if p.Full() && prof == nil {
+		return v
+	}
+	tree, ok := toTree(v)
+	if !ok {
+		return v
+	}
+
+	tree = p.prune(tree)
+	if prof != nil {
+		tree = prof.apply(tree)
+	}
+	return tree
+}
+
+// toTree round-trips v through JSON into maps, lists and scalars.
+func toTree(v any) (any, bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, false
+	}
+	return tree, true
+}
+
+func (p Policy) prune(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if p.hidden[k] {
+				delete(t, k)
+				continue
+			}
+			t[k] = p.prune(child)
+		}
+	case []any:
+		for i := range t {
+			t[i] = p.prune(t[i])
+		}
+	}
+	return v
+}
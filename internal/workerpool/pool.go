@@ -0,0 +1,195 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQueueFull is returned by Submit when the queue is at capacity. Callers
+// should treat it as backpressure and retry later rather than block.
+var ErrQueueFull = errors.New("worker pool queue is full")
+
+// ErrStopped is returned by Submit after Stop has been called.
+var ErrStopped = errors.New("worker pool stopped")
+
+// Task is a unit of work. Tasks with the same Source share that source's
+// concurrency limit, so a single county site is never hit by too many
+// fetches at once.
+type Task struct {
+	Source string
+	Run    func(ctx context.Context)
+}
+
+// Config controls pool sizing.
+type Config struct {
+	Workers        int // tasks run at once across all sources
+	QueueSize      int // tasks waiting before Submit reports ErrQueueFull
+	PerSourceLimit int // tasks run at once for a single source
+}
+
+// Stats is a point-in-time view of pool utilization.
+type Stats struct {
+	Workers   int `json:"workers"`
+	Active    int `json:"active"`
+	Queued    int `json:"queued"`
+	QueueSize int `json:"queueSize"`
+	// AverageRunSeconds is a moving average of how long tasks take.
+	AverageRunSeconds float64 `json:"averageRunSeconds"`
+}
+
+// Pool runs tasks on a fixed number of workers with a bounded queue.
+type Pool struct {
+	cfg    Config
+	logger *slog.Logger
+	queue  chan Task
+	active atomic.Int64
+	avgRun atomic.Int64 // nanoseconds
+
+	mu      sync.Mutex
+	sources map[string]chan struct{}
+	stopped bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a Pool configured by cfg. Call Start before submitting work.
+func New(cfg Config, logger *slog.Logger) *Pool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize < 0 {
+		cfg.QueueSize = 0
+	}
+	if cfg.PerSourceLimit <= 0 {
+		cfg.PerSourceLimit = cfg.Workers
+	}
+	return &Pool{
+		cfg:     cfg,
+		logger:  logger,
+		queue:   make(chan Task, cfg.QueueSize),
+		sources: make(map[string]chan struct{}),
+	}
+}
+
+// Start launches the workers. Tasks receive a context derived from ctx.
+func (p *Pool) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	for i := 0; i < p.cfg.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker(ctx)
+	}
+}
+
+// Submit queues t without blocking.
+func (p *Pool) Submit(t Task) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return ErrStopped
+	}
+	select {
+	case p.queue <- t:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Stop stops accepting work, lets queued tasks drain and waits for workers
+// to exit or ctx to expire, whichever comes first.
+func (p *Pool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+// Stats reports current utilization.
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Workers:           p.cfg.Workers,
+		Active:            int(p.active.Load()),
+		Queued:            len(p.queue),
+		QueueSize:         p.cfg.QueueSize,
+		AverageRunSeconds: time.Duration(p.avgRun.Load()).Seconds(),
+	}
+}
+
+func (p *Pool) worker(ctx context.Context) {
+	defer p.wg.Done()
+	for t := range p.queue {
+		p.run(ctx, t)
+	}
+}
+
+func (p *Pool) run(ctx context.Context, t Task) {
+	sem := p.sourceSem(t.Source)
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	defer func() { <-sem }()
+
+	p.active.Add(1)
+	defer p.active.Add(-1)
+	defer p.observe(time.Now())
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Error("worker task panicked", "source", t.Source, "panic", r)
+		}
+	}()
+	t.Run(ctx)
+}
+
+func (p *Pool) sourceSem(source string) chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sem, ok := p.sources[source]
+	if !ok {
+		sem = make(chan struct{}, p.cfg.PerSourceLimit)
+		p.sources[source] = sem
+	}
+	return sem
+}
+
+// observe folds the time since start into the moving average of run
+// times, weighting the latest run by an eighth.
+func (p *Pool) observe(start time.Time) {
+	d := int64(time.Since(start))
+	for {
+		old := p.avgRun.Load()
+		next := d
+		if old > 0 {
+			next = old + (d-old)/8
+		}
+		if p.avgRun.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
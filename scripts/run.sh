@@ -49,4 +49,4 @@ export PORT=${PORT:-8080}
 export GODEBUG=netdns=go # Force pure Go DNS resolution
 
 # Run the server with pure Go
-go run -tags netgo cmd/server/main.go
\ No newline at end of file
+go run -tags netgo ./cmd/server serve
\ No newline at end of file